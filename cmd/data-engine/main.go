@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"os/signal"
 	"quant-data-engine/internal/api"
 	"quant-data-engine/internal/config"
 	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/events"
 	"quant-data-engine/internal/kafka"
+	"quant-data-engine/internal/models"
+	"quant-data-engine/internal/pipeline"
 	"quant-data-engine/internal/schedule"
+	"quant-data-engine/internal/selfcheck"
+	"quant-data-engine/internal/shutdown"
 	"quant-data-engine/internal/storage"
 	"syscall"
 	"time"
@@ -22,7 +28,8 @@ func main() {
 
 	// 加载配置
 	if err := config.LoadConfig(); err != nil {
-		logrus.Fatalf("Failed to load config: %v", err)
+		// 配置校验一次列出全部问题，每行一个
+		logrus.Fatalf("Failed to load config:\n%v", err)
 	}
 
 	logrus.Info("Starting Quant Data Engine...")
@@ -31,6 +38,26 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// 初始化 Tushare 客户端
+	tushareClient, err := datasource.NewTushareClient()
+	if err != nil {
+		logrus.Fatalf("Failed to initialize Tushare client: %v", err)
+	}
+
+	// 启动自检：汇总所有配置错误后再退出，避免各子系统在不同位置报错
+	if config.AppConfig.SelfCheckEnabled {
+		cfg := config.AppConfig
+		report := selfcheck.RunSelfCheck(ctx, time.Duration(cfg.SelfCheckTimeout)*time.Second,
+			selfcheck.DatabaseCheck(cfg.DBHost, cfg.DBPort, cfg.DBName, storage.Ping),
+			selfcheck.KafkaCheck(cfg.KafkaBrokers),
+			selfcheck.TushareCheck(cfg.TushareAPIKey, cfg.SelfCheckTushareProbe, tushareClient),
+		)
+		if !report.OK() {
+			logrus.Fatal(report.String())
+		}
+		logrus.Info(report.String())
+	}
+
 	// 初始化存储
 	db, err := storage.NewPostgresStorage()
 	if err != nil {
@@ -45,22 +72,173 @@ func main() {
 	}
 	defer kafkaProducer.Close()
 
+	// 启用原始响应保存时，Tushare和交易所数据源每次请求成功后保存原始响应体
+	var rawSink datasource.RawResponseSink
+	if config.AppConfig.PersistRawResponses {
+		rawSink = db
+	}
+	tushareClient.SetRawResponseSink(rawSink)
+
 	// 初始化数据源
 	dataSourceFactory := datasource.NewDataSourceFactory()
-	dataSourceFactory.Register("binance", datasource.NewExchangeDataSource("binance", config.AppConfig.ExchangeAPIKey, config.AppConfig.ExchangeAPISecret))
-	dataSourceFactory.Register("okx", datasource.NewExchangeDataSource("okx", config.AppConfig.ExchangeAPIKey, config.AppConfig.ExchangeAPISecret))
+	exchangeClient := &http.Client{Timeout: time.Duration(config.AppConfig.DataSourceTimeout) * time.Second}
+	if config.AppConfig.BinanceKlinesEnabled {
+		binance, err := datasource.NewBinanceDataSource(config.AppConfig.BinanceBaseURL, config.AppConfig.BinanceKlineInterval, config.AppConfig.BinanceRateLimit)
+		if err != nil {
+			logrus.Fatalf("Failed to initialize Binance datasource: %v", err)
+		}
+		binance.SetRawResponseSink(rawSink)
+		dataSourceFactory.Register("binance", binance)
+	} else {
+		// 最新成交价来自24小时行情接口
+		exchange := datasource.NewExchangeDataSource("binance", config.AppConfig.BinanceBaseURL,
+			config.AppConfig.ExchangeAPIKey, config.AppConfig.ExchangeAPISecret, exchangeClient)
+		exchange.SetRawResponseSink(rawSink)
+		dataSourceFactory.Register("binance", exchange)
+	}
+	// OKX最新成交价来自行情接口，与Binance相互独立
+	okx := datasource.NewOKXDataSource(config.AppConfig.OKXBaseURL, exchangeClient)
+	okx.SetRawResponseSink(rawSink)
+	dataSourceFactory.Register("okx", okx)
 
-	// 初始化 Tushare 客户端
-	tushareClient := datasource.NewTushareClient()
+	// 新保存的日线发布到事件总线，供日线实时推送接口订阅
+	dailyEvents := events.NewBus[models.Daily]()
+	db.OnDailySaved(dailyEvents.Publish)
 
 	// 初始化定时任务调度器
-	scheduler := schedule.NewScheduler(tushareClient, db)
+	scheduler := schedule.NewScheduler(tushareClient, db, kafkaProducer, config.AppConfig.SchedulerSinkMode)
+	if err := scheduler.AddJob("stock_list", config.AppConfig.StockListCron, scheduler.FetchStockList); err != nil {
+		logrus.Fatalf("Failed to schedule stock list job: %v", err)
+	}
+	if config.AppConfig.DailyJobEnabled {
+		dailyJob := schedule.NewDailyJob(tushareClient, db, config.AppConfig.DailyChunkSize)
+		if err := scheduler.AddJob("daily", config.AppConfig.DailyCron, dailyJob.RunToday); err != nil {
+			logrus.Fatalf("Failed to schedule daily job: %v", err)
+		}
+	}
+	if config.AppConfig.PersistRawResponses {
+		cleanup := schedule.NewRawResponseCleanupJob(db, time.Duration(config.AppConfig.RawResponseRetentionHours)*time.Hour)
+		if err := scheduler.AddJob("raw_response_cleanup", config.AppConfig.RawResponseCleanupCron, cleanup.Run); err != nil {
+			logrus.Fatalf("Failed to schedule raw response cleanup job: %v", err)
+		}
+	}
 
 	// 启动定时任务
-	scheduler.Start()
+	scheduler.Start(ctx)
+
+	// 启动数据获取和处理
+	shedder := pipeline.NewLoadShedder(
+		time.Duration(config.AppConfig.LoadShedLatencyMs)*time.Millisecond,
+		config.AppConfig.LoadShedSlowSaves,
+		config.AppConfig.LoadShedMaxFactor,
+	)
+	// 启用事务性发件箱时，流水线只负责保存，由发件箱转发器发送到Kafka
+	var publisher pipeline.MarketDataPublisher = kafkaProducer
+	if config.AppConfig.OutboxEnabled {
+		publisher = nil
+		relay := pipeline.NewOutboxRelay(db, kafkaProducer,
+			time.Duration(config.AppConfig.OutboxRelayIntervalMs)*time.Millisecond, config.AppConfig.OutboxBatchSize)
+		go relay.Run(ctx)
+	}
+	var pipelineOpts []pipeline.Option
+	switch config.AppConfig.RejectSinkMode {
+	case pipeline.RejectSinkTable:
+		pipelineOpts = append(pipelineOpts, pipeline.WithRejectSink(pipeline.NewTableRejectSink(db)))
+	case pipeline.RejectSinkKafka:
+		pipelineOpts = append(pipelineOpts, pipeline.WithRejectSink(pipeline.NewKafkaRejectSink(kafkaProducer, config.AppConfig.RejectKafkaTopic)))
+	case pipeline.RejectSinkNone:
+	default:
+		logrus.Warnf("Unknown reject sink mode %q, rejected market data will only be logged", config.AppConfig.RejectSinkMode)
+	}
+	// 启用写缓冲时跨交易对和数据源累积数据后一次性保存，落库后才发送到Kafka
+	var writeBuffer *pipeline.WriteBuffer
+	if config.AppConfig.WriteBufferSize > 0 {
+		writeBuffer = pipeline.NewWriteBuffer(db, config.AppConfig.WriteBufferSize,
+			time.Duration(config.AppConfig.WriteBufferMaxAgeMs)*time.Millisecond)
+		pipelineOpts = append(pipelineOpts, pipeline.WithWriteBuffer(writeBuffer))
+	}
+	// 交易对持续失败、处理降速和写入死信时发送通知
+	if config.AppConfig.NotifyWebhookURL != "" {
+		notifier := pipeline.NewThrottledNotifier(pipeline.NewWebhookNotifier(config.AppConfig.NotifyWebhookURL),
+			config.AppConfig.NotifyMinLevel, time.Duration(config.AppConfig.NotifyThrottleSeconds)*time.Second)
+		pipelineOpts = append(pipelineOpts, pipeline.WithNotifier(notifier, config.AppConfig.NotifyFailureThreshold))
+	}
+	// 交易对映射：先写入种子文件中的映射，再从表中加载，获取数据时转换为各数据源的交易对名称
+	if config.AppConfig.SymbolMapFile != "" {
+		mappings, err := pipeline.ReadSymbolMapFile(config.AppConfig.SymbolMapFile)
+		if err != nil {
+			logrus.Fatalf("Failed to read symbol map: %v", err)
+		}
+		if err := db.SaveSymbolMappings(ctx, mappings); err != nil {
+			logrus.Fatalf("Failed to seed symbol map: %v", err)
+		}
+		logrus.Infof("Seeded %d symbol mappings from %s", len(mappings), config.AppConfig.SymbolMapFile)
+	}
+	symbolMap, err := pipeline.LoadSymbolMap(ctx, db)
+	if err != nil {
+		logrus.Fatalf("Failed to load symbol map: %v", err)
+	}
+	pipelineOpts = append(pipelineOpts, pipeline.WithSymbolMap(symbolMap))
+	dataPipeline := pipeline.NewPipeline(dataSourceFactory, db, publisher,
+		config.Symbols(), time.Duration(config.AppConfig.ProcessingInterval)*time.Second, shedder, pipelineOpts...)
+	if writeBuffer != nil {
+		go writeBuffer.Run(ctx)
+	}
 
 	// 初始化API服务器
-	apiServer := api.NewServer(tushareClient, db)
+	serverOpts := []api.ServerOption{
+		api.WithStalenessThreshold(time.Duration(config.AppConfig.DataStalenessSeconds) * time.Second),
+		api.WithAdminToken(config.AppConfig.AdminToken),
+		api.WithIngestToken(config.AppConfig.IngestToken),
+		// 与流水线相同，启用发件箱时推送的数据由转发器发送
+		api.WithMarketDataPublisher(publisher),
+		api.WithSymbolStatus(dataPipeline),
+		api.WithPipelineControl(dataPipeline),
+		api.WithMarketDataMaxLimit(config.AppConfig.MarketDataMaxLimit),
+		api.WithMaxDecompressedBody(int64(config.AppConfig.MaxDecompressedBody)),
+		api.WithMaxActiveJobs(config.AppConfig.MaxActiveJobs),
+		api.WithMaxHistoricalWindow(time.Duration(config.AppConfig.MaxHistoricalWindowDays) * 24 * time.Hour),
+		// 诊断接口复用启动自检的Kafka连通性检查
+		api.WithDatabaseHealth(db),
+		api.WithKafkaProbe(selfcheck.KafkaCheck(config.AppConfig.KafkaBrokers).Run),
+		api.WithJobStatus(scheduler),
+		api.WithDataSources(dataSourceFactory),
+		// 日志级别在config.Reload中生效，处理间隔需要同步到流水线
+		api.WithConfigReload(func() (config.Reloadable, error) {
+			reloaded, err := config.Reload()
+			if err != nil {
+				return reloaded, err
+			}
+			dataPipeline.SetInterval(time.Duration(reloaded.ProcessingInterval) * time.Second)
+			return reloaded, nil
+		}),
+	}
+	if config.AppConfig.GzipEnabled {
+		serverOpts = append(serverOpts, api.WithGzip(config.AppConfig.GzipMinSize))
+	}
+	serverOpts = append(serverOpts, api.WithDailyStream(dailyEvents))
+	// 双写一致性检查：独立消费组读取主题末尾，抽样检查已保存的数据是否发送到Kafka
+	if config.AppConfig.ConsistencyCheckEnabled {
+		consumer, err := kafka.NewKafkaTailConsumer(config.AppConfig.ConsistencyGroupID)
+		if err != nil {
+			logrus.Fatalf("Failed to create consistency check consumer: %v", err)
+		}
+		if err := consumer.Subscribe(config.AppConfig.KafkaTopic); err != nil {
+			logrus.Fatalf("Failed to subscribe consistency check consumer to topic %s: %v", config.AppConfig.KafkaTopic, err)
+		}
+		checker := pipeline.NewConsistencyChecker(db, consumer,
+			time.Duration(config.AppConfig.ConsistencyCheckIntervalSec)*time.Second,
+			time.Duration(config.AppConfig.ConsistencyGraceSec)*time.Second,
+			config.AppConfig.ConsistencySamplePercent, config.AppConfig.ConsistencySampleLimit)
+		go func() {
+			checker.Run(ctx)
+			if err := consumer.Close(); err != nil {
+				logrus.Errorf("Failed to close consistency check consumer: %v", err)
+			}
+		}()
+		serverOpts = append(serverOpts, api.WithConsistencyReporter(checker))
+	}
+	apiServer := api.NewServer(tushareClient, db, serverOpts...)
 
 	// 启动API服务器
 	go func() {
@@ -69,11 +247,24 @@ func main() {
 		}
 	}()
 
-	// 启动数据获取和处理
+	// 启动SLA看门狗
+	if config.AppConfig.SLAThresholdSeconds > 0 {
+		var hooks []pipeline.AlertHook
+		if config.AppConfig.SLAAlertWebhookURL != "" {
+			hooks = append(hooks, pipeline.NewWebhookHook(config.AppConfig.SLAAlertWebhookURL))
+		}
+		if config.AppConfig.SLAAlertKafkaTopic != "" {
+			hooks = append(hooks, pipeline.NewKafkaAlertHook(kafkaProducer, config.AppConfig.SLAAlertKafkaTopic))
+		}
+		watchdog := pipeline.NewWatchdog(dataPipeline.LastSuccess,
+			time.Duration(config.AppConfig.SLAThresholdSeconds)*time.Second, hooks...)
+		go watchdog.Run(ctx)
+	}
+
 	dataProcessingDone := make(chan struct{})
 	go func() {
 		defer close(dataProcessingDone)
-		startDataProcessing(ctx, dataSourceFactory, db, kafkaProducer)
+		dataPipeline.Run(ctx)
 	}()
 
 	// 等待中断信号
@@ -86,77 +277,24 @@ func main() {
 	// 取消上下文，通知所有goroutine停止
 	cancel()
 
-	// 等待数据处理完成
-	select {
-	case <-dataProcessingDone:
-		logrus.Info("Data processing stopped gracefully")
-	case <-time.After(5 * time.Second):
-		logrus.Warn("Data processing stopped forcefully after timeout")
+	// 依次等待定时任务和数据处理退出、写缓冲保存剩余数据、HTTP服务处理完请求、Kafka发送完队列中的消息
+	steps := []shutdown.Step{
+		{Name: "Scheduler", Stop: scheduler.Stop},
+		{Name: "Data processing", Stop: shutdown.WaitDone(dataProcessingDone)},
 	}
-
-	logrus.Info("Quant Data Engine stopped")
-}
-
-// startDataProcessing 启动数据处理
-func startDataProcessing(ctx context.Context, factory *datasource.DataSourceFactory, db *storage.PostgresStorage, kafkaProducer *kafka.KafkaProducer) {
-	symbols := []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
-	interval := 30 * time.Second
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	logrus.Infof("Starting data processing with interval %v", interval)
-
-	for {
-		select {
-		case <-ctx.Done():
-			logrus.Info("Data processing context canceled, exiting...")
-			return
-		case <-ticker.C:
-			processData(factory, db, kafkaProducer, symbols)
-		}
+	if writeBuffer != nil {
+		steps = append(steps, shutdown.Step{Name: "Write buffer", Stop: func(ctx context.Context) error {
+			return writeBuffer.Flush()
+		}})
 	}
-}
-
-// processData 处理数据
-func processData(factory *datasource.DataSourceFactory, db *storage.PostgresStorage, kafkaProducer *kafka.KafkaProducer, symbols []string) {
-	logrus.Info("Processing market data...")
-
-	for _, symbol := range symbols {
-		// 从各个数据源获取数据
-		for _, sourceName := range []string{"binance", "okx"} {
-			source := factory.GetDataSource(sourceName)
-			if source == nil {
-				logrus.Warnf("DataSource %s not found", sourceName)
-				continue
-			}
-
-			// 获取市场数据
-			data, err := source.GetMarketData(symbol)
-			if err != nil {
-				logrus.Errorf("Failed to get market data from %s for %s: %v", sourceName, symbol, err)
-				continue
-			}
-
-			if len(data) == 0 {
-				logrus.Infof("No market data received from %s for %s", sourceName, symbol)
-				continue
-			}
-
-			// 保存到数据库
-			if err := db.SaveMarketData(data); err != nil {
-				logrus.Errorf("Failed to save market data to database: %v", err)
-				continue
-			}
-
-			// 发送到Kafka
-			if err := kafkaProducer.SendMarketData(data); err != nil {
-				logrus.Errorf("Failed to send market data to Kafka: %v", err)
-				// 即使Kafka发送失败，也继续处理其他数据
-				// 可以考虑添加重试机制或死信队列
-			}
-		}
+	steps = append(steps,
+		shutdown.Step{Name: "API server", Stop: apiServer.Shutdown},
+		shutdown.Step{Name: "Kafka producer", Stop: kafkaProducer.Flush},
+	)
+	timedOut := shutdown.Run(time.Duration(config.AppConfig.ShutdownTimeout)*time.Second, steps...)
+	if len(timedOut) > 0 {
+		logrus.Warnf("Shutdown timed out for: %v", timedOut)
 	}
 
-	logrus.Info("Market data processing completed")
+	logrus.Info("Quant Data Engine stopped")
 }
@@ -9,15 +9,1974 @@ const docTemplate = `{
     "info": {
         "description": "{{escape .Description}}",
         "title": "{{.Title}}",
-        "contact": {},
+        "termsOfService": "http://swagger.io/terms/",
+        "contact": {
+            "name": "API Support",
+            "url": "http://www.example.com/support",
+            "email": "support@example.com"
+        },
         "version": "{{.Version}}"
     },
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/backtest/data": {
+        "/admin/backfill": {
+            "post": {
+                "description": "后台逐只股票回补前复权日线，进度写入backfill_progress表，可通过resume_id从检查点恢复；运行中的任务数达到MAX_ACTIVE_JOBS时返回429；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "启动全市场日线回补任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cADMIN_TOKEN\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "回补参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.BackfillRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/backfill/{id}": {
+            "get": {
+                "description": "返回回补任务已完成/总股票数、当前处理的股票和错误信息；已结束的任务超过一小时后从backfill_progress表读取；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "获取回补任务进度",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cADMIN_TOKEN\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "任务ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/backfill/{id}/cancel": {
+            "post": {
+                "description": "取消运行中的回补任务，已完成的股票保留在检查点中，可通过resume_id恢复；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "取消回补任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cADMIN_TOKEN\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "任务ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cache": {
+            "get": {
+                "description": "返回各内存缓存的条目数、命中次数、未命中次数和命中率；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "获取内存缓存统计",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cache/purge": {
+            "post": {
+                "description": "清空指定名称的内存缓存，不指定name时清空全部，缓存在下一次查询时重新加载；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "清空内存缓存",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "缓存名称，例如 stock_names",
+                        "name": "name",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/config/reload": {
+            "post": {
+                "description": "重新读取.env文件和环境变量，立即应用新的日志级别和数据处理间隔；数据库等需要重启的配置发生变化时拒绝重新加载，所有配置保持不变；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "重新加载配置",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/diagnostics": {
+            "get": {
+                "description": "一次返回数据库（连通性和连接池统计）、Kafka连通性、定时任务最近一次执行情况、数据处理流水线状态和各数据源数据新鲜度，所有检查项通过时ok为true，否则返回503。各检查项并发执行，每项最多等待2秒；未配置的子系统不检查。需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "诊断各子系统状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cADMIN_TOKEN\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.DiagnosticsReport"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.DiagnosticsReport"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/jobs/active": {
+            "get": {
+                "description": "返回运行中的回补任务及其进度，按启动时间排序；任务完成、失败或取消后不再出现在列表中；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "获取运行中的后台任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cADMIN_TOKEN\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/pipeline/pause": {
+            "post": {
+                "description": "暂停数据处理流水线，恢复前跳过每个处理周期（不再从数据源获取数据），正在进行的周期会完成；暂停期间不触发SLA告警。已暂停时同样返回200；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "暂停数据处理",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/pipeline/resume": {
+            "post": {
+                "description": "恢复已暂停的数据处理流水线，从下一个处理周期开始正常处理。未暂停时同样返回200；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "恢复数据处理",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tushare/limits": {
+            "get": {
+                "description": "返回各Tushare接口配置的每分钟调用上限、当前可用令牌数和距离下一个令牌的秒数，只包含已调用过的接口；需要请求头 Authorization: Bearer \u003cADMIN_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "管理"
+                ],
+                "summary": "获取Tushare接口限流状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/backtest/batch": {
+            "post": {
+                "description": "一次查询返回所有存在的回测数据，不存在的ID会被忽略，单次最多100个ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "回测"
+                ],
+                "summary": "按ID批量获取回测数据",
+                "parameters": [
+                    {
+                        "description": "回测ID列表",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.BacktestBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/backtest/data": {
+            "get": {
+                "description": "分页获取数据库中指定交易对的回测数据，可按策略过滤，按时间戳倒序；没有数据时返回空列表。meta中返回总数、has_more和下一页的next_cursor",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "回测"
+                ],
+                "summary": "获取回测数据",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易对符号，例如 BTCUSDT",
+                        "name": "symbol",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "策略名称，例如 MA Cross",
+                        "name": "strategy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数，缺省或0时为50，最大500，超过时截断",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "跳过的条数，默认0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "分页游标，优先于offset",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.BacktestData"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "插入或更新回测数据（按id），返回持久化后的数据，包括数据库生成的created_at",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "回测"
+                ],
+                "summary": "保存回测数据",
+                "parameters": [
+                    {
+                        "description": "回测数据",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BacktestData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/backtest/import": {
+            "post": {
+                "description": "上传CSV文件（multipart字段file，或直接作为请求体）批量导入回测数据。首行为表头，必须包含id、symbol、strategy、start_date、end_date、results、timestamp列，列顺序不限；日期为RFC3339或YYYY-MM-DD格式，results为JSON字符串。非法的行被拒绝并返回行号和原因，其余行在一个事务中保存（按id插入或更新）。单个文件最多10000行、32MB；按内容识别格式，Parquet文件暂不支持，返回415",
+                "consumes": [
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "回测"
+                ],
+                "summary": "导入回测数据",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV文件",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/backtest/parquet": {
+            "get": {
+                "description": "以Parquet文件（附件）返回指定交易对在日期范围内已保存的市场数据，列为symbol、price、volume和timestamp（毫秒精度的UTC时间），按时间升序。最多返回MAX_HISTORICAL_ROWS行，超过时响应头X-Truncated为true",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/vnd.apache.parquet"
+                ],
+                "tags": [
+                    "回测"
+                ],
+                "summary": "获取Parquet格式的回测数据",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易对符号，例如 BTCUSDT",
+                        "name": "symbol",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期，格式：YYYY-MM-DD",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期（包含当天），格式：YYYY-MM-DD",
+                        "name": "end_date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/datasource/freshness": {
+            "get": {
+                "description": "返回每个数据源最新一条市场数据的时间，超过阈值的数据源标记为stale",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "数据源"
+                ],
+                "summary": "获取各数据源的数据新鲜度",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/datasource/{name}/symbols": {
+            "get": {
+                "description": "返回数据源支持的交易对，使用数据源的交易对名称（如OKX为BTC-USDT）；交易所数据源的列表缓存1小时，数据处理流水线跳过列表之外的交易对",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "数据源"
+                ],
+                "summary": "获取数据源支持的交易对",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "数据源名称，如binance、okx",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "string"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "检查量化数据引擎API是否正常运行，挂载在根路径 /health，不带 /api/v1 前缀",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "系统"
+                ],
+                "summary": "健康检查",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/market/compare": {
+            "get": {
+                "description": "返回各数据源在指定时间附近距该时间最近的价格，最高价相对最低价偏离超过阈值时标记divergent",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "市场"
+                ],
+                "summary": "对比各数据源价格",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易对符号，例如 BTCUSDT",
+                        "name": "symbol",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "对比时间，RFC3339格式",
+                        "name": "at",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "查找范围（秒），默认60",
+                        "name": "tolerance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "价格偏离告警阈值（百分比），默认1",
+                        "name": "threshold",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/market/correlation": {
+            "get": {
+                "description": "两个交易对的价格按1分钟分桶对齐（每个桶取最晚的价格），计算相邻共同桶之间收益率的皮尔逊相关系数，返回相关系数和重叠的收益率点数；重叠点数不足3个或价格没有波动时返回404",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "市场"
+                ],
+                "summary": "获取收益率相关系数",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易对A，例如 BTCUSDT",
+                        "name": "a",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "交易对B，例如 ETHUSDT",
+                        "name": "b",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间，RFC3339格式",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，RFC3339格式",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/market/data": {
+            "get": {
+                "description": "获取指定交易对已保存的市场数据，按时间倒序，没有数据时返回空数组，meta中给出总数、has_more和next_cursor；指定bucket时按时间桶降采样[start, end]内的数据，每个桶返回最晚的一条",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "市场"
+                ],
+                "summary": "获取市场数据",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易对符号，例如 BTCUSDT",
+                        "name": "symbol",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "返回数据条数，缺省或0时为10，负数返回400，最大MARKET_DATA_MAX_LIMIT（默认1000）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "为true时返回允许的最大条数，优先于limit",
+                        "name": "all",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "偏移量，默认0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "分页游标，优先于offset",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "降采样时间桶，如5m、1h，至少1s",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "降采样开始时间，RFC3339格式，指定bucket时必填",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "降采样结束时间，RFC3339格式，指定bucket时必填",
+                        "name": "end",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/market/history": {
+            "get": {
+                "description": "按时间升序分页获取指定交易对在时间范围内的历史市场数据，后面还有数据时返回has_more和next_offset用于取下一页（truncated和next_start为兼容保留）；时间跨度超过MAX_HISTORICAL_WINDOW_DAYS（默认90天）时返回400",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "市场"
+                ],
+                "summary": "获取历史市场数据",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易对符号，例如 BTCUSDT",
+                        "name": "symbol",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间，RFC3339格式",
+                        "name": "start_time",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，RFC3339格式",
+                        "name": "end_time",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数，缺省或0时为MAX_HISTORICAL_ROWS，超过时截断",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "跳过的条数，默认0",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/market/ingest": {
+            "post": {
+                "description": "接收外部采集程序推送的市场数据数组，逐条校验后保存合法数据，并在配置了Kafka时发送；非法数据不影响同批的合法数据，响应中返回每条非法数据的下标和原因。单次最多10000条；请求体可以使用Content-Encoding: gzip压缩；配置了INGEST_TOKEN时需要请求头 Authorization: Bearer \u003cINGEST_TOKEN\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "市场"
+                ],
+                "summary": "推送市场数据",
+                "parameters": [
+                    {
+                        "description": "市场数据",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MarketData"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/market/volume-profile": {
+            "get": {
+                "description": "将交易对在[start, end]内的[最低价, 最高价]等分为buckets个价格区间，返回每个区间的成交量和数据条数，按价格升序；所有价格相同时只返回一个区间，没有数据时buckets为空数组",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "市场"
+                ],
+                "summary": "获取成交量分布",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易对符号，例如 BTCUSDT",
+                        "name": "symbol",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间，RFC3339格式",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，RFC3339格式",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "价格区间数，1到500，默认20",
+                        "name": "buckets",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/market/vwap": {
+            "get": {
+                "description": "计算交易对在[start, end]内市场数据的成交量加权平均价，范围内成交量为0时返回404",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "市场"
+                ],
+                "summary": "获取成交量加权平均价",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易对符号，例如 BTCUSDT",
+                        "name": "symbol",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间，RFC3339格式",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，RFC3339格式",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipeline/consistency": {
+            "get": {
+                "description": "返回一致性检查的累计次数、抽样记录数和已保存到数据库但Kafka中没有读到的记录数，以及最近一次检查缺失的记录ID（最多20个）；未启用CONSISTENCY_CHECK_ENABLED时返回503",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "数据源"
+                ],
+                "summary": "获取双写一致性检查结果",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipeline/status": {
+            "get": {
+                "description": "返回流水线是否已暂停及暂停时间、实际处理间隔、最近一次成功处理的时间和交易对数量",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "数据源"
+                ],
+                "summary": "获取流水线运行状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pipeline/symbols": {
+            "get": {
+                "description": "返回每个交易对最近一次成功保存的时间、最近一次获取或保存失败的错误和连续失败次数，用于定位持续失败的交易对",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "数据源"
+                ],
+                "summary": "获取各交易对的处理状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/daily/adjusted": {
+            "get": {
+                "description": "使用已保存的复权因子对日线价格进行前复权（以区间内最新因子为基准），拆股/送转前后价格连续，适合回测使用",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "股票"
+                ],
+                "summary": "获取复权调整后的日线行情",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "股票代码，例如 000001.SZ",
+                        "name": "ts_code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期，格式：YYYYMMDD",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期，格式：YYYYMMDD",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "附加字段，逗号分隔，目前支持 name（股票名称）",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/daily/columns": {
+            "get": {
+                "description": "返回列式结果（ts_code、trade_date与各数值列一一对应），按股票代码和交易日升序排列，适合回测批量拉取，单次最多500只股票",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "股票"
+                ],
+                "summary": "按列批量获取日线数据",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "股票代码，逗号分隔，例如 000001.SZ,600000.SH",
+                        "name": "ts_codes",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "数值列，逗号分隔，可选 open,high,low,close,pre_close,change,pct_chg,vol,amount",
+                        "name": "cols",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期，格式：YYYYMMDD",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期，格式：YYYYMMDD",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/daily/dates": {
+            "get": {
+                "description": "返回数据库中该股票已保存日线的交易日（去重、升序），可与交易日历对比找出缺失的日线；没有日线时dates为空数组",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "股票"
+                ],
+                "summary": "获取已保存日线的交易日",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "股票代码，例如 000001.SZ",
+                        "name": "ts_code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/daily/missing": {
+            "get": {
+                "description": "返回交易所在[start, end]内开市（按trade_cal）、但数据库中该股票没有日线的日期（YYYYMMDD，升序），回补时可以只拉取这些日期；没有缺失时dates为空数组",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "股票"
+                ],
+                "summary": "获取缺失日线的交易日",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "股票代码，例如 000001.SZ",
+                        "name": "ts_code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "交易所，默认SSE",
+                        "name": "exchange",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期，格式YYYYMMDD",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期，格式YYYYMMDD",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/daily/recompute": {
+            "post": {
+                "description": "按交易日顺序用前一交易日收盘价回填pre_close，并计算change和pct_chg，只更新这些字段为空或为0的行（如CSV导入的日线）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "股票"
+                ],
+                "summary": "重新计算日线涨跌幅",
+                "parameters": [
+                    {
+                        "description": "重新计算参数",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.RecomputeDailyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/daily/stream": {
+            "get": {
+                "description": "以Server-Sent Events（text/event-stream）推送该股票之后新保存到daily表的日线（例如日线任务运行时），每条日线为一个daily事件，data为日线JSON；空闲时每15秒发送一条心跳注释保持连接。客户端断开时停止推送。未启用时返回503",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "股票"
+                ],
+                "summary": "实时推送日线",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "股票代码，例如 000001.SZ",
+                        "name": "ts_code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event: daily",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/fetch-list": {
+            "post": {
+                "description": "手动触发从 Tushare API 获取股票列表并保存到数据库中",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "股票"
+                ],
+                "summary": "手动触发获取股票列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/highlow": {
+            "get": {
+                "description": "计算截至as_of（含）最近window个交易日日线的最高价和最低价，用于突破类策略；不足window个交易日时使用已有的全部日线，没有日线时返回404",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "股票"
+                ],
+                "summary": "获取N日最高最低价",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "股票代码，例如 000001.SZ",
+                        "name": "ts_code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "交易日窗口，默认20，必须大于0",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "截止日期，格式：YYYYMMDD，默认最新交易日",
+                        "name": "as_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stock/list": {
             "get": {
-                "description": "获取指定交易对的回测数据",
+                "description": "按ts_code升序分页返回数据库中已保存的股票基础信息，可按市场、交易所和上市状态过滤，没有数据时返回空数组；meta中返回总数、has_more和下一页的next_cursor",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,16 +1984,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "回测"
+                    "股票"
                 ],
-                "summary": "获取回测数据",
+                "summary": "获取已保存的股票列表",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "每页条数，缺省或0时为100，最大5000，超过时截断",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "跳过的条数，默认0",
+                        "name": "offset",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "交易对符号，例如 BTCUSDT",
-                        "name": "symbol",
-                        "in": "query",
-                        "required": true
+                        "description": "分页游标，优先于offset",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "市场类型，例如 主板、创业板",
+                        "name": "market",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "交易所，例如 SSE、SZSE",
+                        "name": "exchange",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "上市状态：L上市、D退市、P暂停上市",
+                        "name": "list_status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -49,13 +2037,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/backtest/parquet": {
+        "/stock/managers": {
             "get": {
-                "description": "获取指定交易对和日期范围的Parquet格式回测数据",
+                "description": "按公告日期倒序、姓名升序分页返回公司在公告日期范围内的管理层，后面还有数据时返回has_more、next_offset和next_cursor；没有数据时data为空数组",
                 "consumes": [
                     "application/json"
                 ],
@@ -63,27 +2057,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "回测"
+                    "股票"
                 ],
-                "summary": "获取Parquet格式的回测数据",
+                "summary": "获取上市公司管理层",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "交易对符号，例如 BTCUSDT",
-                        "name": "symbol",
+                        "description": "股票代码，例如 000001.SZ",
+                        "name": "ts_code",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "开始日期，格式：YYYY-MM-DD",
-                        "name": "start_date",
+                        "description": "公告日期起始（含），格式YYYYMMDD",
+                        "name": "start",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "结束日期，格式：YYYY-MM-DD",
-                        "name": "end_date",
+                        "description": "公告日期结束（含），格式YYYYMMDD",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数，缺省或0时为50，最大500，超过时截断",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "跳过的条数，默认0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "分页游标，优先于offset",
+                        "name": "cursor",
                         "in": "query"
                     }
                 ],
@@ -99,13 +2111,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/health": {
-            "get": {
-                "description": "检查量化数据引擎API是否正常运行",
+        "/sync/ohlcv/full": {
+            "post": {
+                "description": "按年分段同步A股日线前复权(OHLCV QFQ)数据，支持指定股票列表或全量同步",
                 "consumes": [
                     "application/json"
                 ],
@@ -113,22 +2131,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "系统"
+                    "同步"
+                ],
+                "summary": "全量OHLCV前复权数据同步",
+                "parameters": [
+                    {
+                        "description": "同步参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.SyncOHLCVFullRequest"
+                        }
+                    }
                 ],
-                "summary": "健康检查",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.APIResponse"
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/market/data": {
+        "/sync/ohlcv/status": {
             "get": {
-                "description": "获取指定交易对的市场数据",
+                "description": "查看各股票的OHLCV数据同步情况",
                 "consumes": [
                     "application/json"
                 ],
@@ -136,24 +2177,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "市场"
+                    "同步"
                 ],
-                "summary": "获取市场数据",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "交易对符号，例如 BTCUSDT",
-                        "name": "symbol",
-                        "in": "query",
-                        "required": true
+                "summary": "获取OHLCV同步状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIResponse"
+                        }
                     },
-                    {
-                        "type": "integer",
-                        "description": "返回数据条数，默认10",
-                        "name": "limit",
-                        "in": "query"
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
+                }
+            }
+        },
+        "/sync/trade-calendar": {
+            "post": {
+                "description": "从Tushare同步交易日历到trade_calendar表和按交易所区分的trade_cal表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "同步"
                 ],
+                "summary": "同步交易日历",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -161,8 +2216,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.APIResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -170,9 +2225,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/stock/fetch-list": {
-            "post": {
-                "description": "手动触发从 Tushare API 获取股票列表并保存到数据库中",
+        "/trade-cal/open": {
+            "get": {
+                "description": "返回交易所在[start, end]内开市的日期（YYYYMMDD），按日期升序，不包含周末和节假日",
                 "consumes": [
                     "application/json"
                 ],
@@ -180,9 +2235,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "股票"
+                    "交易日历"
+                ],
+                "summary": "获取交易日列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "交易所，默认SSE",
+                        "name": "exchange",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期，格式YYYYMMDD",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期，格式YYYYMMDD",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    }
                 ],
-                "summary": "手动触发获取股票列表",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -190,6 +2267,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.APIResponse"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -201,6 +2284,69 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "api.BackfillRequest": {
+            "type": "object",
+            "properties": {
+                "end_date": {
+                    "description": "结束日期，格式：YYYY-MM-DD",
+                    "type": "string"
+                },
+                "resume_id": {
+                    "description": "需要恢复的任务ID",
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "开始日期，格式：YYYY-MM-DD",
+                    "type": "string"
+                }
+            }
+        },
+        "api.BacktestBatchRequest": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.RecomputeDailyRequest": {
+            "type": "object",
+            "properties": {
+                "ts_codes": {
+                    "description": "指定股票列表，为空则处理所有股票",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.SyncOHLCVFullRequest": {
+            "type": "object",
+            "properties": {
+                "end_year": {
+                    "description": "结束年份，默认当前年份",
+                    "type": "integer"
+                },
+                "start_year": {
+                    "description": "起始年份，默认2000",
+                    "type": "integer"
+                },
+                "symbols": {
+                    "description": "指定股票列表，为空则同步所有",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "models.APIResponse": {
             "type": "object",
             "properties": {
@@ -208,30 +2354,145 @@ const docTemplate = `{
                 "message": {
                     "type": "string"
                 },
+                "meta": {
+                    "$ref": "#/definitions/models.PageMeta"
+                },
                 "success": {
                     "type": "boolean"
                 }
             }
         },
+        "models.BacktestData": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt 首次写入数据库的时间，由数据库生成，只在返回持久化结果时填充",
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "strategy": {
+                    "type": "string"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DiagnosticCheck": {
+            "type": "object",
+            "properties": {
+                "details": {},
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ok": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.DiagnosticsReport": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DiagnosticCheck"
+                    }
+                },
+                "ok": {
+                    "type": "boolean"
+                }
+            }
+        },
         "models.ErrorResponse": {
             "type": "object",
             "properties": {
+                "code": {
+                    "description": "Code 机器可读的错误码，如INVALID_BODY，未设置时省略",
+                    "type": "string"
+                },
                 "error": {
                     "type": "string"
                 }
             }
+        },
+        "models.MarketData": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "volume": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.PageMeta": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
-	Host:             "",
-	BasePath:         "",
-	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Version:          "1.0",
+	Host:             "localhost:8080",
+	BasePath:         "/api/v1",
+	Schemes:          []string{"http"},
+	Title:            "Quant Data Engine API",
+	Description:      "量化数据引擎API文档",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -7,17 +7,26 @@
 // @contact.url http://www.example.com/support
 // @contact.email support@example.com
 // @host localhost:8080
-// @BasePath /
+// @BasePath /api/v1
 // @schemes http
 package api
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"quant-data-engine/internal/backfill"
+	"quant-data-engine/internal/config"
 	"quant-data-engine/internal/datasource"
 	"quant-data-engine/internal/models"
+	"quant-data-engine/internal/parquet"
 	"quant-data-engine/internal/storage"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,10 +42,191 @@ type Server struct {
 	mutex         sync.RWMutex
 	tushareClient datasource.TushareClientInterface
 	storage       storage.StorageInterface
+	backfill      *backfill.Manager
+
+	// 数据源最新数据距今超过该时长时标记为停止更新
+	stalenessThreshold time.Duration
+	// 响应体达到该字节数时进行gzip压缩，0表示不压缩
+	gzipMinSize int
+	// 管理接口鉴权令牌，为空时需要鉴权的管理接口不可用
+	adminToken string
+	// httpServer Run启动的HTTP服务，供Shutdown优雅关闭
+	httpServer *http.Server
+	// stockNames 日线附带股票名称时使用的代码到名称缓存
+	stockNames *stockNameCache
+	// symbolStatus 数据处理流水线每个交易对的处理状态，为nil时状态接口不可用
+	symbolStatus SymbolStatusProvider
+	// pipeline 数据处理流水线的暂停、恢复和运行状态，为nil时相关接口不可用
+	pipeline PipelineController
+	// consistency 数据库与Kafka双写一致性检查的统计，为nil时表示未启用
+	consistency ConsistencyReporter
+	// reloadConfig 重新加载可运行时修改的配置并应用，为nil时配置重新加载接口不可用
+	reloadConfig ConfigReloader
+	// marketDataPaginator 市场数据接口的分页参数
+	marketDataPaginator Paginator
+	// ingestToken 市场数据推送接口鉴权令牌，为空时不鉴权
+	ingestToken string
+	// marketDataPublisher 推送的市场数据保存后发送到Kafka，为nil时只保存
+	marketDataPublisher MarketDataPublisher
+	// maxDecompressedBody gzip请求体解压后的最大字节数
+	maxDecompressedBody int64
+	// maxHistoricalWindow 历史数据单次查询的最大时间跨度，0表示不限制
+	maxHistoricalWindow time.Duration
+	// dailyStream 日线实时推送的事件来源，为nil时推送接口不可用；dailyStreamHeartbeat为空闲时的心跳间隔
+	dailyStream          DailySubscriber
+	dailyStreamHeartbeat time.Duration
+	// 诊断接口检查的子系统，为nil时不检查该子系统；diagnosticsTimeout为单个检查项的超时时间
+	databaseHealth     DatabaseHealth
+	kafkaProbe         KafkaProbe
+	jobStatus          JobStatusProvider
+	diagnosticsTimeout time.Duration
+	// dataSources 行情数据源，为nil时数据源交易对接口不可用
+	dataSources *datasource.DataSourceFactory
+}
+
+// MarketDataPublisher 发送市场数据到Kafka
+type MarketDataPublisher interface {
+	SendMarketData(data []models.MarketData) error
+}
+
+// ConfigReloader 重新加载可运行时修改的配置并应用到各子系统
+type ConfigReloader func() (config.Reloadable, error)
+
+// SymbolStatusProvider 提供数据处理流水线每个交易对的处理状态
+type SymbolStatusProvider interface {
+	SymbolStatuses() []models.SymbolStatus
+}
+
+// PipelineController 暂停、恢复数据处理流水线并提供其运行状态，Pause和Resume在状态未改变时返回false
+type PipelineController interface {
+	Pause() bool
+	Resume() bool
+	Status() models.PipelineStatus
+}
+
+// ConsistencyReporter 提供数据库与Kafka双写一致性检查的累计结果
+type ConsistencyReporter interface {
+	Stats() models.ConsistencyStats
+}
+
+// DefaultStalenessThreshold 默认的数据源停止更新判定阈值
+const DefaultStalenessThreshold = 5 * time.Minute
+
+// ServerOption API服务器可选配置
+type ServerOption func(*Server)
+
+// WithStalenessThreshold 设置数据源停止更新判定阈值
+func WithStalenessThreshold(threshold time.Duration) ServerOption {
+	return func(s *Server) {
+		if threshold > 0 {
+			s.stalenessThreshold = threshold
+		}
+	}
+}
+
+// WithGzip 启用gzip响应压缩，小于minSize字节的响应不压缩
+func WithGzip(minSize int) ServerOption {
+	return func(s *Server) {
+		if minSize < 1 {
+			minSize = 1
+		}
+		s.gzipMinSize = minSize
+	}
+}
+
+// WithMaxDecompressedBody 设置gzip请求体解压后的最大字节数，maxSize<1时忽略
+func WithMaxDecompressedBody(maxSize int64) ServerOption {
+	return func(s *Server) {
+		if maxSize > 0 {
+			s.maxDecompressedBody = maxSize
+		}
+	}
+}
+
+// WithMaxActiveJobs 设置同时运行的回补任务数上限，maxActive<1时忽略
+func WithMaxActiveJobs(maxActive int) ServerOption {
+	return func(s *Server) {
+		s.backfill.SetMaxActive(maxActive)
+	}
+}
+
+// DefaultMaxHistoricalWindow 历史数据单次查询的默认最大时间跨度
+const DefaultMaxHistoricalWindow = 90 * 24 * time.Hour
+
+// WithMaxHistoricalWindow 设置历史数据单次查询的最大时间跨度，window<=0时不限制
+func WithMaxHistoricalWindow(window time.Duration) ServerOption {
+	return func(s *Server) {
+		s.maxHistoricalWindow = max(window, 0)
+	}
+}
+
+// WithAdminToken 设置管理接口鉴权令牌
+func WithAdminToken(token string) ServerOption {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
+
+// WithIngestToken 设置市场数据推送接口鉴权令牌，为空时不鉴权
+func WithIngestToken(token string) ServerOption {
+	return func(s *Server) {
+		s.ingestToken = token
+	}
+}
+
+// WithMarketDataPublisher 设置推送的市场数据保存后的Kafka发送，启用事务性发件箱时不需要设置
+func WithMarketDataPublisher(publisher MarketDataPublisher) ServerOption {
+	return func(s *Server) {
+		s.marketDataPublisher = publisher
+	}
+}
+
+// WithMarketDataMaxLimit 设置市场数据接口单次返回的最大条数，maxLimit<1时忽略
+func WithMarketDataMaxLimit(maxLimit int) ServerOption {
+	return func(s *Server) {
+		if maxLimit > 0 {
+			s.marketDataPaginator = NewPaginator(DefaultMarketDataLimit, maxLimit)
+		}
+	}
+}
+
+// WithConfigReload 设置配置重新加载函数
+func WithConfigReload(reload ConfigReloader) ServerOption {
+	return func(s *Server) {
+		s.reloadConfig = reload
+	}
+}
+
+// WithDataSources 设置行情数据源，用于查询各数据源支持的交易对
+func WithDataSources(factory *datasource.DataSourceFactory) ServerOption {
+	return func(s *Server) {
+		s.dataSources = factory
+	}
+}
+
+// WithSymbolStatus 设置交易对处理状态来源，通常为数据处理流水线
+func WithSymbolStatus(provider SymbolStatusProvider) ServerOption {
+	return func(s *Server) {
+		s.symbolStatus = provider
+	}
+}
+
+// WithPipelineControl 设置可暂停和恢复的数据处理流水线
+func WithPipelineControl(pipeline PipelineController) ServerOption {
+	return func(s *Server) {
+		s.pipeline = pipeline
+	}
+}
+
+// WithConsistencyReporter 设置双写一致性检查，提供其累计结果
+func WithConsistencyReporter(reporter ConsistencyReporter) ServerOption {
+	return func(s *Server) {
+		s.consistency = reporter
+	}
 }
 
 // NewServer 创建API服务器
-func NewServer(tushareClient datasource.TushareClientInterface, storage storage.StorageInterface) *Server {
+func NewServer(tushareClient datasource.TushareClientInterface, storage storage.StorageInterface, opts ...ServerOption) *Server {
 	router := gin.Default()
 
 	// 配置CORS
@@ -53,6 +243,32 @@ func NewServer(tushareClient datasource.TushareClientInterface, storage storage.
 		c.Next()
 	})
 
+	server := &Server{
+		router:        router,
+		tushareClient: tushareClient,
+		storage:       storage,
+		backfill:      backfill.NewManager(tushareClient, storage, backfill.DefaultRateInterval),
+		stockNames:    newStockNameCache(DefaultStockNameTTL, storage.GetStockNames),
+
+		stalenessThreshold:  DefaultStalenessThreshold,
+		marketDataPaginator: NewPaginator(DefaultMarketDataLimit, DefaultMarketDataMaxLimit),
+		maxDecompressedBody: DefaultMaxDecompressedBody,
+		maxHistoricalWindow: DefaultMaxHistoricalWindow,
+
+		dailyStreamHeartbeat: DefaultDailyStreamHeartbeat,
+		diagnosticsTimeout:   DefaultDiagnosticsCheckTimeout,
+	}
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	// 配置响应压缩
+	if server.gzipMinSize > 0 {
+		router.Use(gzipMiddleware(server.gzipMinSize))
+	}
+	// 解压gzip请求体
+	router.Use(gunzipRequestMiddleware(server.maxDecompressedBody))
+
 	// 添加Swagger UI路由
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler,
 		ginSwagger.URL("/swagger.json"),
@@ -63,54 +279,109 @@ func NewServer(tushareClient datasource.TushareClientInterface, storage storage.
 		c.File("./docs/swagger.json")
 	})
 
-	server := &Server{
-		router:        router,
-		tushareClient: tushareClient,
-		storage:       storage,
-	}
-
 	// 注册路由
 	server.registerRoutes()
 
 	return server
 }
 
+// APIPrefix 业务接口的版本前缀，/health和/swagger等系统路由不带前缀
+const APIPrefix = "/api/v1"
+
 // registerRoutes 注册路由
 func (s *Server) registerRoutes() {
-	// 健康检查
+	// 健康检查等系统路由挂载在根路径
 	s.router.GET("/health", s.healthCheck)
 
+	// 业务路由统一挂载在版本前缀下
+	v1 := s.router.Group(APIPrefix)
+
 	// 回测数据相关
-	backtest := s.router.Group("/backtest")
+	backtest := v1.Group("/backtest")
 	{
 		backtest.GET("/data", s.getBacktestData)
+		backtest.POST("/data", s.saveBacktestData)
 		backtest.GET("/parquet", s.getParquetData)
+		backtest.POST("/batch", s.getBacktestBatch)
+		backtest.POST("/import", s.importBacktestData)
 	}
 
 	// 市场数据相关
-	market := s.router.Group("/market")
+	market := v1.Group("/market")
 	{
 		market.GET("/data", s.getMarketData)
+		market.GET("/history", s.getHistoricalData)
+		market.GET("/compare", s.compareMarketData)
+		market.GET("/vwap", s.getVWAP)
+		market.GET("/volume-profile", s.getVolumeProfile)
+		market.GET("/correlation", s.getReturnCorrelation)
+		market.POST("/ingest", s.requireIngestToken(), s.ingestMarketData)
 	}
 
 	// 股票数据相关
-	stock := s.router.Group("/stock")
+	stock := v1.Group("/stock")
 	{
 		stock.POST("/fetch-list", s.fetchStockList)
+		stock.GET("/list", s.getStockList)
+		stock.GET("/daily/adjusted", s.getSplitAdjustedDaily)
+		stock.POST("/daily/recompute", s.recomputeDailyChanges)
+		stock.GET("/daily/columns", s.getDailyColumns)
+		stock.GET("/daily/dates", s.getStoredTradeDates)
+		stock.GET("/daily/missing", s.getMissingTradingDays)
+		stock.GET("/daily/stream", s.streamDaily)
+		stock.GET("/highlow", s.getRollingHighLow)
+		stock.GET("/managers", s.getStkManagers)
 	}
 
 	// 同步相关
-	sync := s.router.Group("/sync")
+	sync := v1.Group("/sync")
 	{
 		sync.POST("/ohlcv/full", s.syncOHLCVFull)
 		sync.POST("/trade-calendar", s.syncTradeCalendar)
 		sync.GET("/ohlcv/status", s.getOHLCVStatus)
 	}
+
+	// 交易日历相关
+	tradeCal := v1.Group("/trade-cal")
+	{
+		tradeCal.GET("/open", s.getOpenTradeDates)
+	}
+
+	// 数据源相关
+	ds := v1.Group("/datasource")
+	{
+		ds.GET("/freshness", s.getSourceFreshness)
+		ds.GET("/:name/symbols", s.getDataSourceSymbols)
+	}
+
+	// 数据处理流水线相关
+	pipeline := v1.Group("/pipeline")
+	{
+		pipeline.GET("/symbols", s.getPipelineSymbols)
+		pipeline.GET("/status", s.getPipelineStatus)
+		pipeline.GET("/consistency", s.getConsistencyStats)
+	}
+
+	// 管理相关
+	admin := v1.Group("/admin")
+	{
+		admin.POST("/backfill", s.requireAdminToken(), s.startBackfill)
+		admin.GET("/backfill/:id", s.requireAdminToken(), s.getBackfillProgress)
+		admin.POST("/backfill/:id/cancel", s.requireAdminToken(), s.cancelBackfill)
+		admin.GET("/jobs/active", s.requireAdminToken(), s.getActiveJobs)
+		admin.GET("/diagnostics", s.requireAdminToken(), s.getDiagnostics)
+		admin.GET("/tushare/limits", s.requireAdminToken(), s.getTushareLimits)
+		admin.POST("/config/reload", s.requireAdminToken(), s.reloadConfigHandler)
+		admin.GET("/cache", s.requireAdminToken(), s.getCacheStats)
+		admin.POST("/cache/purge", s.requireAdminToken(), s.purgeCache)
+		admin.POST("/pipeline/pause", s.requireAdminToken(), s.pausePipeline)
+		admin.POST("/pipeline/resume", s.requireAdminToken(), s.resumePipeline)
+	}
 }
 
 // healthCheck 健康检查
 // @Summary 健康检查
-// @Description 检查量化数据引擎API是否正常运行
+// @Description 检查量化数据引擎API是否正常运行，挂载在根路径 /health，不带 /api/v1 前缀
 // @Tags 系统
 // @Accept json
 // @Produce json
@@ -127,15 +398,29 @@ func (s *Server) healthCheck(c *gin.Context) {
 	})
 }
 
+// 获取回测数据的默认条数和最大条数
+const (
+	DefaultBacktestDataLimit = 50
+	MaxBacktestDataLimit     = 500
+)
+
+// backtestDataPaginator 回测数据接口的分页参数
+var backtestDataPaginator = NewPaginator(DefaultBacktestDataLimit, MaxBacktestDataLimit)
+
 // getBacktestData 获取回测数据
 // @Summary 获取回测数据
-// @Description 获取指定交易对的回测数据
+// @Description 分页获取数据库中指定交易对的回测数据，可按策略过滤，按时间戳倒序；没有数据时返回空列表。meta中返回总数、has_more和下一页的next_cursor
 // @Tags 回测
 // @Accept json
 // @Produce json
 // @Param symbol query string true "交易对符号，例如 BTCUSDT"
-// @Success 200 {object} models.APIResponse
+// @Param strategy query string false "策略名称，例如 MA Cross"
+// @Param limit query int false "每页条数，缺省或0时为50，最大500，超过时截断"
+// @Param offset query int false "跳过的条数，默认0"
+// @Param cursor query string false "分页游标，优先于offset"
+// @Success 200 {object} models.APIResponse{data=[]models.BacktestData}
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
 // @Router /backtest/data [get]
 func (s *Server) getBacktestData(c *gin.Context) {
 	symbol := c.Query("symbol")
@@ -146,35 +431,132 @@ func (s *Server) getBacktestData(c *gin.Context) {
 		return
 	}
 
-	// 模拟回测数据
-	data := models.BacktestData{
-		ID:        fmt.Sprintf("backtest_%s_%d", symbol, time.Now().Unix()),
-		Symbol:    symbol,
-		Strategy:  "MA Cross",
-		StartDate: time.Now().AddDate(-1, 0, 0),
-		EndDate:   time.Now(),
-		Results:   `{"profit": 12.5, "drawdown": 5.2, "trades": 120}`,
-		Timestamp: time.Now(),
+	page, err := backtestDataPaginator.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	strategy := c.Query("strategy")
+	total, err := s.storage.CountBacktestData(symbol, strategy)
+	if err != nil {
+		logrus.Errorf("Failed to count backtest data for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get backtest data: " + err.Error()})
+		return
+	}
+	data, err := s.storage.GetBacktestData(symbol, strategy, page.Limit, page.Offset)
+	if err != nil {
+		logrus.Errorf("Failed to get backtest data for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get backtest data: " + err.Error()})
+		return
+	}
+	if data == nil {
+		data = []models.BacktestData{}
 	}
 
+	meta := page.Meta(total)
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Backtest data retrieved successfully",
 		Data:    data,
+		Meta:    &meta,
+	})
+}
+
+// saveBacktestData 保存回测数据
+// @Summary 保存回测数据
+// @Description 插入或更新回测数据（按id），返回持久化后的数据，包括数据库生成的created_at
+// @Tags 回测
+// @Accept json
+// @Produce json
+// @Param request body models.BacktestData true "回测数据"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /backtest/data [post]
+func (s *Server) saveBacktestData(c *gin.Context) {
+	var req models.BacktestData
+	if !bindJSON(c, &req) {
+		return
+	}
+	// created_at由数据库生成
+	req.CreatedAt = nil
+
+	stored, err := s.storage.UpsertBacktestDataReturning(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidBacktestData) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		logrus.Errorf("Failed to save backtest data %s: %v", req.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save backtest data: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Backtest data saved",
+		Data:    stored,
+	})
+}
+
+// MaxBacktestBatchIDs 批量获取回测数据时单次请求的最大ID数
+const MaxBacktestBatchIDs = 100
+
+// BacktestBatchRequest 批量获取回测数据请求
+type BacktestBatchRequest struct {
+	IDs []string `json:"ids" binding:"required"`
+}
+
+// getBacktestBatch 按ID批量获取回测数据
+// @Summary 按ID批量获取回测数据
+// @Description 一次查询返回所有存在的回测数据，不存在的ID会被忽略，单次最多100个ID
+// @Tags 回测
+// @Accept json
+// @Produce json
+// @Param request body BacktestBatchRequest true "回测ID列表"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /backtest/batch [post]
+func (s *Server) getBacktestBatch(c *gin.Context) {
+	var req BacktestBatchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !checkItemCount(c, "ids", len(req.IDs), MaxBacktestBatchIDs, false) {
+		return
+	}
+
+	data, err := s.storage.GetBacktestDataByIDs(c.Request.Context(), req.IDs)
+	if err != nil {
+		logrus.Errorf("Failed to get backtest data by ids: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get backtest data: " + err.Error()})
+		return
+	}
+	if data == nil {
+		data = []models.BacktestData{}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d of %d backtests", len(data), len(req.IDs)),
+		Data:    data,
 	})
 }
 
 // getParquetData 获取Parquet格式的回测数据
 // @Summary 获取Parquet格式的回测数据
-// @Description 获取指定交易对和日期范围的Parquet格式回测数据
+// @Description 以Parquet文件（附件）返回指定交易对在日期范围内已保存的市场数据，列为symbol、price、volume和timestamp（毫秒精度的UTC时间），按时间升序。最多返回MAX_HISTORICAL_ROWS行，超过时响应头X-Truncated为true
 // @Tags 回测
 // @Accept json
-// @Produce json
+// @Produce application/vnd.apache.parquet
 // @Param symbol query string true "交易对符号，例如 BTCUSDT"
 // @Param start_date query string false "开始日期，格式：YYYY-MM-DD"
-// @Param end_date query string false "结束日期，格式：YYYY-MM-DD"
-// @Success 200 {object} models.APIResponse
+// @Param end_date query string false "结束日期（包含当天），格式：YYYY-MM-DD"
+// @Success 200 {file} file
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
 // @Router /backtest/parquet [get]
 func (s *Server) getParquetData(c *gin.Context) {
 	symbol := c.Query("symbol")
@@ -189,7 +571,7 @@ func (s *Server) getParquetData(c *gin.Context) {
 	startDateStr := c.Query("start_date")
 	endDateStr := c.Query("end_date")
 
-	var startDate, endDate time.Time
+	var startDate, endDate, endTime time.Time
 	var err error
 
 	if startDateStr != "" {
@@ -212,8 +594,11 @@ func (s *Server) getParquetData(c *gin.Context) {
 			})
 			return
 		}
+		// 结束日期包含当天的数据
+		endTime = endDate.AddDate(0, 0, 1).Add(-time.Millisecond)
 	} else {
 		endDate = time.Now()
+		endTime = endDate
 	}
 
 	// 验证日期范围
@@ -232,36 +617,55 @@ func (s *Server) getParquetData(c *gin.Context) {
 		return
 	}
 
-	// 生成模拟Parquet数据
-	var data []map[string]interface{}
-	current := startDate
-	for current.Before(endDate) {
-		data = append(data, map[string]interface{}{
-			"timestamp": current.Unix(),
-			"symbol":    symbol,
-			"price":     1000 + float64(current.Day())*10,
-			"volume":    10000 + float64(current.Hour())*1000,
+	result, err := s.storage.GetHistoricalData(symbol, startDate.Format(time.RFC3339Nano), endTime.Format(time.RFC3339Nano), 0, 0)
+	if err != nil {
+		logrus.Errorf("Failed to get parquet data for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to get historical data: %v", err),
 		})
-		current = current.Add(1 * time.Hour)
+		return
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Parquet data retrieved successfully",
-		Data:    data,
-	})
+	var buf bytes.Buffer
+	if err := parquet.WriteMarketData(&buf, result.Data); err != nil {
+		logrus.Errorf("Failed to encode parquet data for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to encode parquet data: %v", err),
+		})
+		return
+	}
+
+	if result.HasMore {
+		c.Header("X-Truncated", "true")
+	}
+	filename := fmt.Sprintf("%s_%s_%s.parquet", symbol, startDate.Format("20060102"), endDate.Format("20060102"))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Data(http.StatusOK, parquet.ContentType, buf.Bytes())
 }
 
+// 市场数据接口的默认返回条数和默认最大返回条数
+const (
+	DefaultMarketDataLimit    = 10
+	DefaultMarketDataMaxLimit = 1000
+)
+
 // getMarketData 获取市场数据
 // @Summary 获取市场数据
-// @Description 获取指定交易对的市场数据
+// @Description 获取指定交易对已保存的市场数据，按时间倒序，没有数据时返回空数组，meta中给出总数、has_more和next_cursor；指定bucket时按时间桶降采样[start, end]内的数据，每个桶返回最晚的一条
 // @Tags 市场
 // @Accept json
 // @Produce json
 // @Param symbol query string true "交易对符号，例如 BTCUSDT"
-// @Param limit query int false "返回数据条数，默认10"
+// @Param limit query int false "返回数据条数，缺省或0时为10，负数返回400，最大MARKET_DATA_MAX_LIMIT（默认1000）"
+// @Param all query bool false "为true时返回允许的最大条数，优先于limit"
+// @Param offset query int false "偏移量，默认0"
+// @Param cursor query string false "分页游标，优先于offset"
+// @Param bucket query string false "降采样时间桶，如5m、1h，至少1s"
+// @Param start query string false "降采样开始时间，RFC3339格式，指定bucket时必填"
+// @Param end query string false "降采样结束时间，RFC3339格式，指定bucket时必填"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
 // @Router /market/data [get]
 func (s *Server) getMarketData(c *gin.Context) {
 	symbol := c.Query("symbol")
@@ -272,592 +676,1957 @@ func (s *Server) getMarketData(c *gin.Context) {
 		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
+	if c.Query("bucket") != "" {
+		s.getDownsampledMarketData(c, symbol)
+		return
 	}
 
-	// 模拟市场数据
-	var data []models.MarketData
-	for i := 0; i < limit; i++ {
-		data = append(data, models.MarketData{
-			ID:        fmt.Sprintf("market_%s_%d", symbol, time.Now().Unix()-int64(i)),
-			Symbol:    symbol,
-			Price:     1000 + float64(i)*10,
-			Volume:    10000 + float64(i)*1000,
-			Timestamp: time.Now().Add(-time.Duration(i) * time.Hour),
-			Source:    "Binance",
-		})
+	page, err := s.marketDataPaginator.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// 按时间倒序跳过前offset条后取limit条，偏移在数据库中完成，内存中最多limit条
+	query := storage.MarketDataQuery{
+		Symbol: symbol,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	total, err := s.storage.CountMarketData(c.Request.Context(), query)
+	if err != nil {
+		logrus.Errorf("Failed to count market data for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get market data: " + err.Error()})
+		return
+	}
+	data, err := s.storage.QueryMarketData(c.Request.Context(), query)
+	if err != nil {
+		logrus.Errorf("Failed to get market data for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get market data: " + err.Error()})
+		return
+	}
+	if data == nil {
+		data = []models.MarketData{}
 	}
 
+	meta := page.Meta(total)
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Market data retrieved successfully",
 		Data:    data,
+		Meta:    &meta,
 	})
 }
 
-// Run 运行API服务器
-func (s *Server) Run(port string) error {
-	logrus.Infof("Starting API server on port %s", port)
-	return s.router.Run(":" + port)
-}
-
-// fetchStockList 手动触发获取股票列表
-// @Summary 手动触发获取股票列表
-// @Description 手动触发从 Tushare API 获取股票列表并保存到数据库中
-// @Tags 股票
-// @Accept json
-// @Produce json
-// @Success 200 {object} models.APIResponse
-// @Failure 500 {object} models.ErrorResponse
-// @Router /stock/fetch-list [post]
-func (s *Server) fetchStockList(c *gin.Context) {
-	logrus.Info("Manually triggering stock list fetch")
+// getDownsampledMarketData 按时间桶降采样市场数据，每个桶返回最晚的一条
+func (s *Server) getDownsampledMarketData(c *gin.Context, symbol string) {
+	bucket, err := time.ParseDuration(c.Query("bucket"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid bucket, use a duration such as 5m or 1h"})
+		return
+	}
 
-	// 准备请求参数
-	req := &datasource.StockBasicRequest{
-		ListStatus: "L", // 只获取上市的股票
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid start format, use RFC3339"})
+		return
 	}
-	fields := []string{
-		"ts_code", "symbol", "name", "area", "industry", "fullname", "enname", "cnspell",
-		"market", "exchange", "curr_type", "list_status", "list_date", "delist_date", "is_hs",
-		"act_name", "act_ent_type",
+
+	end, err := time.Parse(time.RFC3339, c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid end format, use RFC3339"})
+		return
 	}
 
-	// 输出请求详细信息
-	logrus.Debugf("Requesting stock basic info with params: %+v", req)
-	logrus.Debugf("Requesting fields: %+v", fields)
+	data, err := s.storage.GetDownsampledMarketData(c.Request.Context(), symbol, bucket, start, end)
+	if errors.Is(err, storage.ErrInvalidMarketDataQuery) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to get downsampled market data for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get downsampled market data: " + err.Error()})
+		return
+	}
+	if data == nil {
+		data = []models.MarketData{}
+	}
 
-	// 调用 Tushare API 获取股票基础信息
-	resp, err := s.tushareClient.GetStockBasic(req, fields)
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Market data downsampled to %d buckets of %v", len(data), bucket),
+		Data:    data,
+	})
+}
 
-	// 输出响应详细信息
-	if resp != nil {
-		logrus.Debugf("Tushare API response code: %d", resp.Code)
-		logrus.Debugf("Tushare API response message: %s", resp.Message)
-		if resp.Data != nil {
-			logrus.Debugf("Tushare API response fields: %+v", resp.Data.Fields)
-			logrus.Debugf("Tushare API response items count: %d", len(resp.Data.Items))
-			if len(resp.Data.Items) > 0 {
-				logrus.Debugf("First item: %+v", resp.Data.Items[0])
-			}
-		}
+// getHistoricalData 获取历史市场数据
+// @Summary 获取历史市场数据
+// @Description 按时间升序分页获取指定交易对在时间范围内的历史市场数据，后面还有数据时返回has_more和next_offset用于取下一页（truncated和next_start为兼容保留）；时间跨度超过MAX_HISTORICAL_WINDOW_DAYS（默认90天）时返回400
+// @Tags 市场
+// @Accept json
+// @Produce json
+// @Param symbol query string true "交易对符号，例如 BTCUSDT"
+// @Param start_time query string true "开始时间，RFC3339格式"
+// @Param end_time query string true "结束时间，RFC3339格式"
+// @Param limit query int false "每页条数，缺省或0时为MAX_HISTORICAL_ROWS，超过时截断"
+// @Param offset query int false "跳过的条数，默认0"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /market/history [get]
+func (s *Server) getHistoricalData(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Symbol is required",
+		})
+		return
 	}
 
+	startTime, err := time.Parse(time.RFC3339, c.Query("start_time"))
 	if err != nil {
-		logrus.Errorf("Failed to fetch stock list: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: fmt.Sprintf("Failed to fetch stock list: %v", err),
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid start_time format, use RFC3339",
 		})
 		return
 	}
 
-	// 解析响应数据
-	var stockList []models.StockBasic
-	if resp.Data != nil && len(resp.Data.Items) > 0 {
-		for _, item := range resp.Data.Items {
-			stock := models.StockBasic{}
-
-			// 解析字段
-			for i, field := range resp.Data.Fields {
-				if i < len(item) {
-					switch field {
-					case "ts_code":
-						if v, ok := item[i].(string); ok {
-							stock.TSCode = v
-						}
-					case "symbol":
-						if v, ok := item[i].(string); ok {
-							stock.Symbol = v
-						}
-					case "name":
-						if v, ok := item[i].(string); ok {
-							stock.Name = v
-						}
-					case "area":
-						if v, ok := item[i].(string); ok {
-							stock.Area = v
-						}
-					case "industry":
-						if v, ok := item[i].(string); ok {
-							stock.Industry = v
-						}
-					case "fullname":
-						if v, ok := item[i].(string); ok {
-							stock.Fullname = v
-						}
-					case "enname":
-						if v, ok := item[i].(string); ok {
-							stock.Enname = v
-						}
-					case "cnspell":
-						if v, ok := item[i].(string); ok {
-							stock.Cnspell = v
-						}
-					case "market":
-						if v, ok := item[i].(string); ok {
-							stock.Market = v
-						}
-					case "exchange":
-						if v, ok := item[i].(string); ok {
-							stock.Exchange = v
-						}
-					case "curr_type":
-						if v, ok := item[i].(string); ok {
-							stock.CurrType = v
-						}
-					case "list_status":
-						if v, ok := item[i].(string); ok {
-							stock.ListStatus = v
-						}
-					case "list_date":
-						if v, ok := item[i].(string); ok {
-							stock.ListDate = v
-						}
-					case "delist_date":
-						if v, ok := item[i].(string); ok {
-							stock.DelistDate = v
-						}
-					case "is_hs":
-						if v, ok := item[i].(string); ok {
-							stock.IsHS = v
-						}
-					case "act_name":
-						if v, ok := item[i].(string); ok {
-							stock.ActName = v
-						}
-					case "act_ent_type":
-						if v, ok := item[i].(string); ok {
-							stock.ActEntType = v
-						}
-					}
-				}
-			}
+	endTime, err := time.Parse(time.RFC3339, c.Query("end_time"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid end_time format, use RFC3339",
+		})
+		return
+	}
 
-			// 添加到列表
-			stockList = append(stockList, stock)
-		}
+	if endTime.Before(startTime) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "end_time must be after start_time",
+		})
+		return
 	}
 
-	logrus.Infof("Fetched %d stocks from Tushare API", len(stockList))
-	if len(stockList) > 0 {
-		logrus.Debugf("First 5 stocks: %+v", stockList[:min(5, len(stockList))])
+	if s.maxHistoricalWindow > 0 && endTime.Sub(startTime) > s.maxHistoricalWindow {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: fmt.Sprintf("Time range exceeds the maximum of %d days, split the query or raise MAX_HISTORICAL_WINDOW_DAYS",
+				int(s.maxHistoricalWindow/(24*time.Hour))),
+		})
+		return
 	}
 
-	// 保存到数据库
-	if len(stockList) > 0 {
-		logrus.Debugf("Saving %d stocks to database", len(stockList))
-		if err := s.storage.SaveStockBasic(stockList); err != nil {
-			logrus.Errorf("Failed to save stock list: %v", err)
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error: fmt.Sprintf("Failed to save stock list: %v", err),
-			})
-			return
-		}
-		logrus.Infof("Successfully saved %d stocks to database", len(stockList))
+	limit, err := nonNegativeQuery(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	offset, err := nonNegativeQuery(c, "offset")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := s.storage.GetHistoricalData(symbol, startTime.Format(time.RFC3339), endTime.Format(time.RFC3339), limit, offset)
+	if err != nil {
+		logrus.Errorf("Failed to get historical data for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to get historical data: %v", err),
+		})
+		return
+	}
+
+	if result.HasMore {
+		logrus.Debugf("Historical data for %s has more rows after offset %d", symbol, offset+len(result.Data))
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Stock list fetched and saved successfully",
-		Data: map[string]interface{}{
-			"count": len(stockList),
-		},
+		Message: "Historical data retrieved successfully",
+		Data:    result,
 	})
 }
 
-// SyncOHLCVFullRequest 全量OHLCV同步请求
-type SyncOHLCVFullRequest struct {
-	Symbols   []string `json:"symbols"`   // 指定股票列表，为空则同步所有
-	StartYear int      `json:"start_year"` // 起始年份，默认2000
-	EndYear   int      `json:"end_year"`   // 结束年份，默认当前年份
+// nonNegativeQuery 解析非负整数查询参数，缺省时返回0
+func nonNegativeQuery(c *gin.Context, name string) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
 }
 
-// syncOHLCVFull 全量OHLCV前复权数据同步
-// @Summary 全量OHLCV前复权数据同步
-// @Description 按年分段同步A股日线前复权(OHLCV QFQ)数据，支持指定股票列表或全量同步
-// @Tags 同步
+// 跨数据源价格对比的默认参数
+const (
+	// DefaultCompareToleranceSeconds 默认在at前后60秒内查找各数据源的价格
+	DefaultCompareToleranceSeconds = 60
+	// DefaultDivergenceThresholdPct 默认价格偏离超过1%时标记为divergent
+	DefaultDivergenceThresholdPct = 1.0
+)
+
+// compareMarketData 对比同一交易对在各数据源的价格
+// @Summary 对比各数据源价格
+// @Description 返回各数据源在指定时间附近距该时间最近的价格，最高价相对最低价偏离超过阈值时标记divergent
+// @Tags 市场
 // @Accept json
 // @Produce json
-// @Param request body SyncOHLCVFullRequest true "同步参数"
+// @Param symbol query string true "交易对符号，例如 BTCUSDT"
+// @Param at query string true "对比时间，RFC3339格式"
+// @Param tolerance query int false "查找范围（秒），默认60"
+// @Param threshold query number false "价格偏离告警阈值（百分比），默认1"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /sync/ohlcv/full [post]
-func (s *Server) syncOHLCVFull(c *gin.Context) {
-	var req SyncOHLCVFullRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request: " + err.Error()})
+// @Router /market/compare [get]
+func (s *Server) compareMarketData(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Symbol is required"})
 		return
 	}
 
-	// 默认参数
-	if req.StartYear == 0 {
-		req.StartYear = 2000
-	}
-	if req.EndYear == 0 {
-		req.EndYear = time.Now().Year()
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid at format, use RFC3339"})
+		return
 	}
 
-	// 获取股票列表
-	var tsCodes []string
-	if len(req.Symbols) > 0 {
-		tsCodes = req.Symbols
-	} else {
-		codes, err := s.storage.GetAllStockCodes()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stock codes: " + err.Error()})
-			return
-		}
-		tsCodes = codes
+	toleranceSeconds, err := strconv.Atoi(c.DefaultQuery("tolerance", strconv.Itoa(DefaultCompareToleranceSeconds)))
+	if err != nil || toleranceSeconds <= 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "tolerance must be a positive number of seconds"})
+		return
 	}
 
-	logrus.Infof("Starting OHLCV QFQ sync for %d stocks, years %d-%d", len(tsCodes), req.StartYear, req.EndYear)
-
-	// 同步字段
-	fields := []string{"ts_code", "trade_date", "open", "high", "low", "close", "vol", "amount"}
-
-	totalSynced := 0
-	totalSkipped := 0
-	totalErrors := 0
-
-	// 遍历每只股票
-	for _, tsCode := range tsCodes {
-		logrus.Infof("Syncing %s", tsCode)
-
-		// 检查已存在的日期范围
-		existingMin, existingMax, _ := s.storage.GetExistingDateRangeForSymbol(tsCode)
-		if existingMin != "" && existingMax != "" {
-			logrus.Debugf("%s already has data from %s to %s, will fill gaps", tsCode, existingMin, existingMax)
-		}
-
-		// 按年分段同步
-		for year := req.StartYear; year <= req.EndYear; year++ {
-			startDate := fmt.Sprintf("%d0101", year)
-			endDate := fmt.Sprintf("%d1231", year)
-
-			// 获取日线数据（未复权）
-			dailyResp, err := s.tushareClient.GetDaily(&datasource.DailyRequest{
-				TSCode:    tsCode,
-				StartDate: startDate,
-				EndDate:   endDate,
-			}, fields)
-			if err != nil {
-				logrus.Errorf("Failed to fetch daily for %s year %d: %v", tsCode, year, err)
-				totalErrors++
-				continue
-			}
-			if dailyResp == nil || dailyResp.Data == nil || len(dailyResp.Data.Items) == 0 {
-				logrus.Debugf("No daily data for %s year %d", tsCode, year)
-				totalSkipped++
-				time.Sleep(250 * time.Millisecond)
-				continue
-			}
-
-			// 获取复权因子
-			adjResp, err := s.tushareClient.GetAdjFactor(&datasource.AdjFactorRequest{
-				TSCode:    tsCode,
-				StartDate: startDate,
-				EndDate:   endDate,
-			}, []string{"ts_code", "trade_date", "adj_factor"})
-			if err != nil {
-				logrus.Errorf("Failed to fetch adj_factor for %s year %d: %v", tsCode, year, err)
-				totalErrors++
-				time.Sleep(250 * time.Millisecond)
-				continue
-			}
-
-			// 构建复权因子映射
-			adjMap := make(map[string]float64)
-			var lastAdjFactor float64 = 1.0
-			if adjResp != nil && adjResp.Data != nil {
-				for _, item := range adjResp.Data.Items {
-					tradeDate := ""
-					var adjFactor float64 = 1.0
-					for i, field := range adjResp.Data.Fields {
-						if i < len(item) {
-							switch field {
-							case "trade_date":
-								if v, ok := item[i].(string); ok {
-									tradeDate = v
-								}
-							case "adj_factor":
-								if v, ok := item[i].(float64); ok {
-									adjFactor = v
-								} else if v, ok := item[i].(int); ok {
-									adjFactor = float64(v)
-								}
-							}
-						}
-					}
-					if tradeDate != "" {
-						adjMap[tradeDate] = adjFactor
-						lastAdjFactor = adjFactor
-					}
-				}
-			}
-
-			// 解析并应用复权
-			var ohlcvList []models.OHLCVDailyQFQ
-			for _, item := range dailyResp.Data.Items {
-				ohlcv := models.OHLCVDailyQFQ{}
-				var tradeDate string
-				var unadjClose float64
-
-				for i, field := range dailyResp.Data.Fields {
-					if i < len(item) {
-						switch field {
-						case "ts_code":
-							if v, ok := item[i].(string); ok {
-								ohlcv.Symbol = v
-							}
-						case "trade_date":
-							if v, ok := item[i].(string); ok {
-								tradeDate = v
-								ohlcv.TradeDate = v
-							}
-						case "open":
-							if v, ok := item[i].(float64); ok {
-								ohlcv.Open = v
-							} else if v, ok := item[i].(int); ok {
-								ohlcv.Open = float64(v)
-							}
-						case "high":
-							if v, ok := item[i].(float64); ok {
-								ohlcv.High = v
-							} else if v, ok := item[i].(int); ok {
-								ohlcv.High = float64(v)
-							}
-						case "low":
-							if v, ok := item[i].(float64); ok {
-								ohlcv.Low = v
-							} else if v, ok := item[i].(int); ok {
-								ohlcv.Low = float64(v)
-							}
-						case "close":
-							if v, ok := item[i].(float64); ok {
-								unadjClose = v
-								ohlcv.Close = v
-							} else if v, ok := item[i].(int); ok {
-								unadjClose = float64(v)
-								ohlcv.Close = float64(v)
-							}
-						case "vol":
-							if v, ok := item[i].(float64); ok {
-								ohlcv.Volume = v
-							} else if v, ok := item[i].(int); ok {
-								ohlcv.Volume = float64(v)
-							}
-						case "amount":
-							if v, ok := item[i].(float64); ok {
-								ohlcv.Turnover = v
-							} else if v, ok := item[i].(int); ok {
-								ohlcv.Turnover = float64(v)
-							}
-						}
-					}
-				}
-
-				// 应用前复权调整因子
-				if tradeDate != "" && ohlcv.Symbol != "" {
-					adjFactor := adjMap[tradeDate]
-					if adjFactor == 0 {
-						adjFactor = lastAdjFactor
-					}
-					if adjFactor > 0 && adjFactor != 1.0 {
-						ohlcv.Open = ohlcv.Open * adjFactor
-						ohlcv.High = ohlcv.High * adjFactor
-						ohlcv.Low = ohlcv.Low * adjFactor
-						ohlcv.Close = unadjClose * adjFactor
-					}
-					ohlcvList = append(ohlcvList, ohlcv)
-				}
-			}
-
-			if len(ohlcvList) > 0 {
-				if err := s.storage.SaveOHLCVDailyQFQ(ohlcvList); err != nil {
-					logrus.Errorf("Failed to save OHLCV for %s year %d: %v", tsCode, year, err)
-					totalErrors++
-				} else {
-					totalSynced += len(ohlcvList)
-					logrus.Infof("Saved %d QFQ records for %s year %d", len(ohlcvList), tsCode, year)
-				}
-			} else {
-				totalSkipped++
-			}
+	threshold, err := strconv.ParseFloat(c.DefaultQuery("threshold", strconv.FormatFloat(DefaultDivergenceThresholdPct, 'f', -1, 64)), 64)
+	if err != nil || threshold < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "threshold must be a non-negative percentage"})
+		return
+	}
 
-			// 速率限制：250ms
-			time.Sleep(250 * time.Millisecond)
-		}
+	tolerance := time.Duration(toleranceSeconds) * time.Second
+	prices, err := s.storage.GetCrossSourcePrices(c.Request.Context(), symbol, at, tolerance)
+	if err != nil {
+		logrus.Errorf("Failed to get cross-source prices for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get cross-source prices: " + err.Error()})
+		return
 	}
 
-	logrus.Infof("OHLCV sync completed: %d records synced, %d years skipped, %d errors",
-		totalSynced, totalSkipped, totalErrors)
+	comparison := compareSourcePrices(prices, threshold)
+	comparison.Symbol = symbol
+	comparison.At = at
+	comparison.ToleranceSeconds = tolerance.Seconds()
+	if comparison.Divergent {
+		logrus.Warnf("Price divergence for %s at %s: %s %.4f vs %s %.4f (%.2f%%)", symbol, at.Format(time.RFC3339),
+			comparison.MinSource, prices[comparison.MinSource], comparison.MaxSource, prices[comparison.MaxSource], comparison.DivergencePct)
+	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "OHLCV QFQ sync completed",
-		Data: map[string]interface{}{
-			"total_synced":  totalSynced,
-			"total_skipped": totalSkipped,
-			"total_errors":  totalErrors,
-			"stocks_count":  len(tsCodes),
-			"start_year":    req.StartYear,
-			"end_year":      req.EndYear,
-		},
+		Message: "Cross-source prices compared",
+		Data:    comparison,
 	})
 }
 
-// syncTradeCalendar 同步交易日历
-// @Summary 同步交易日历
-// @Description 从Tushare同步交易日历到trade_calendar表
-// @Tags 同步
+// getVWAP 获取成交量加权平均价
+// @Summary 获取成交量加权平均价
+// @Description 计算交易对在[start, end]内市场数据的成交量加权平均价，范围内成交量为0时返回404
+// @Tags 市场
 // @Accept json
 // @Produce json
+// @Param symbol query string true "交易对符号，例如 BTCUSDT"
+// @Param start query string true "开始时间，RFC3339格式"
+// @Param end query string true "结束时间，RFC3339格式"
 // @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /sync/trade-calendar [post]
-func (s *Server) syncTradeCalendar(c *gin.Context) {
-	logrus.Info("Starting trade calendar sync")
-
-	// Tushare trade_cal API字段
-	fields := []string{"exchange", "cal_date", "is_open", "pre_trade_date"}
-
-	// 同步最近20年的数据
-	endDate := time.Now().Format("20060102")
-	startDate := fmt.Sprintf("%d0101", time.Now().Year()-20)
-
-	req := &datasource.TradeCalRequest{
-		Exchange:  "",
-		StartDate: startDate,
-		EndDate:   endDate,
-		IsOpen:    "",
+// @Router /market/vwap [get]
+func (s *Server) getVWAP(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Symbol is required"})
+		return
 	}
 
-	resp, err := s.tushareClient.GetTradeCal(req, fields)
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch trade cal: " + err.Error()})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid start format, use RFC3339"})
 		return
 	}
 
-	if resp == nil || resp.Data == nil || len(resp.Data.Items) == 0 {
-		c.JSON(http.StatusOK, models.APIResponse{
-			Success: true,
-			Message: "No trade calendar data returned",
-			Data:    map[string]interface{}{"count": 0},
-		})
+	end, err := time.Parse(time.RFC3339, c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid end format, use RFC3339"})
 		return
 	}
 
-	// 解析并保存
-	var tradeCalList []models.TradeCal
-	for _, item := range resp.Data.Items {
-		tc := models.TradeCal{}
-		for i, field := range resp.Data.Fields {
-			if i < len(item) {
-				switch field {
-				case "exchange":
-					if v, ok := item[i].(string); ok {
-						tc.Exchange = v
-					}
-				case "cal_date":
-					if v, ok := item[i].(string); ok {
-						tc.CalDate = v
-					}
-				case "is_open":
-					if v, ok := item[i].(string); ok {
-						tc.IsOpen = v
-					}
-				case "pre_trade_date":
-					if v, ok := item[i].(string); ok {
-						tc.PreTradeDate = v
-					}
-				}
-			}
-		}
-		if tc.CalDate != "" {
-			tradeCalList = append(tradeCalList, tc)
-		}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end must be after start"})
+		return
 	}
 
-	if err := s.storage.SaveTradeCalendar(tradeCalList); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save trade calendar: " + err.Error()})
+	vwap, err := s.storage.GetVWAP(c.Request.Context(), symbol, start, end)
+	if errors.Is(err, storage.ErrZeroVolume) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: fmt.Sprintf("No volume for %s between %s and %s", symbol, start.Format(time.RFC3339), end.Format(time.RFC3339))})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to get vwap for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get vwap: " + err.Error()})
 		return
 	}
-
-	logrus.Infof("Saved %d trade calendar records", len(tradeCalList))
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Trade calendar synced successfully",
-		Data: map[string]interface{}{
-			"count": len(tradeCalList),
-		},
+		Message: "VWAP calculated",
+		Data:    models.VWAPResult{Symbol: symbol, Start: start, End: end, VWAP: vwap},
 	})
 }
 
-// getOHLCVStatus 获取OHLCV同步状态
-// @Summary 获取OHLCV同步状态
-// @Description 查看各股票的OHLCV数据同步情况
-// @Tags 同步
+// DefaultVolumeProfileBuckets 成交量分布默认的价格区间数
+const DefaultVolumeProfileBuckets = 20
+
+// getVolumeProfile 获取成交量分布
+// @Summary 获取成交量分布
+// @Description 将交易对在[start, end]内的[最低价, 最高价]等分为buckets个价格区间，返回每个区间的成交量和数据条数，按价格升序；所有价格相同时只返回一个区间，没有数据时buckets为空数组
+// @Tags 市场
 // @Accept json
 // @Produce json
+// @Param symbol query string true "交易对符号，例如 BTCUSDT"
+// @Param start query string true "开始时间，RFC3339格式"
+// @Param end query string true "结束时间，RFC3339格式"
+// @Param buckets query int false "价格区间数，1到500，默认20"
 // @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /sync/ohlcv/status [get]
-func (s *Server) getOHLCVStatus(c *gin.Context) {
-	tsCode := c.Query("ts_code")
+// @Router /market/volume-profile [get]
+func (s *Server) getVolumeProfile(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Symbol is required"})
+		return
+	}
 
-	if tsCode != "" {
-		count, _ := s.storage.GetOHLCVCountBySymbol(tsCode)
-		minDate, maxDate, _ := s.storage.GetExistingDateRangeForSymbol(tsCode)
-		c.JSON(http.StatusOK, models.APIResponse{
-			Success: true,
-			Message: "OHLCV status retrieved",
-			Data: map[string]interface{}{
-				"ts_code":   tsCode,
-				"count":     count,
-				"min_date":  minDate,
-				"max_date":  maxDate,
-			},
-		})
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid start format, use RFC3339"})
 		return
 	}
 
-	// 返回总体统计
-	codes, err := s.storage.GetAllStockCodes()
+	end, err := time.Parse(time.RFC3339, c.Query("end"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid end format, use RFC3339"})
 		return
 	}
 
-	stats := make([]map[string]interface{}, 0, min(100, len(codes)))
-	for _, code := range codes {
-		if len(stats) >= 100 {
-			break
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end must be after start"})
+		return
+	}
+
+	buckets := DefaultVolumeProfileBuckets
+	if raw := c.Query("buckets"); raw != "" {
+		buckets, err = strconv.Atoi(raw)
+		if err != nil || buckets < storage.MinVolumeProfileBuckets || buckets > storage.MaxVolumeProfileBuckets {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: fmt.Sprintf("buckets must be an integer between %d and %d", storage.MinVolumeProfileBuckets, storage.MaxVolumeProfileBuckets),
+			})
+			return
 		}
-		count, _ := s.storage.GetOHLCVCountBySymbol(code)
-		minDate, maxDate, _ := s.storage.GetExistingDateRangeForSymbol(code)
-		stats = append(stats, map[string]interface{}{
-			"ts_code":  code,
-			"count":    count,
-			"min_date": minDate,
-			"max_date": maxDate,
+	}
+
+	profile, err := s.storage.GetVolumeProfile(c.Request.Context(), symbol, start, end, buckets)
+	if err != nil {
+		logrus.Errorf("Failed to get volume profile for %s: %v", symbol, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get volume profile: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Volume profile calculated over %d price buckets", len(profile)),
+		Data:    models.VolumeProfileResult{Symbol: symbol, Start: start, End: end, Buckets: profile},
+	})
+}
+
+// getReturnCorrelation 获取两个交易对收益率的相关系数
+// @Summary 获取收益率相关系数
+// @Description 两个交易对的价格按1分钟分桶对齐（每个桶取最晚的价格），计算相邻共同桶之间收益率的皮尔逊相关系数，返回相关系数和重叠的收益率点数；重叠点数不足3个或价格没有波动时返回404
+// @Tags 市场
+// @Accept json
+// @Produce json
+// @Param a query string true "交易对A，例如 BTCUSDT"
+// @Param b query string true "交易对B，例如 ETHUSDT"
+// @Param start query string true "开始时间，RFC3339格式"
+// @Param end query string true "结束时间，RFC3339格式"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /market/correlation [get]
+func (s *Server) getReturnCorrelation(c *gin.Context) {
+	symbolA, symbolB := c.Query("a"), c.Query("b")
+	if symbolA == "" || symbolB == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Both a and b symbols are required"})
+		return
+	}
+	if symbolA == symbolB {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "a and b must be different symbols"})
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid start format, use RFC3339"})
+		return
+	}
+
+	end, err := time.Parse(time.RFC3339, c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid end format, use RFC3339"})
+		return
+	}
+
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end must be after start"})
+		return
+	}
+
+	correlation, points, err := s.storage.GetReturnCorrelation(c.Request.Context(), symbolA, symbolB, start, end)
+	if errors.Is(err, storage.ErrInsufficientOverlap) || errors.Is(err, storage.ErrZeroVariance) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: fmt.Sprintf("Cannot correlate %s and %s: %v", symbolA, symbolB, err)})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to get correlation for %s and %s: %v", symbolA, symbolB, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get correlation: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Correlation calculated over %d returns", points),
+		Data: models.CorrelationResult{
+			SymbolA:     symbolA,
+			SymbolB:     symbolB,
+			Start:       start,
+			End:         end,
+			Bucket:      storage.CorrelationBucket.String(),
+			Correlation: correlation,
+			Points:      points,
+		},
+	})
+}
+
+// MaxIngestRecords 推送市场数据时单次请求的最大条数
+const MaxIngestRecords = 10000
+
+// ingestMarketData 接收外部采集程序推送的市场数据
+// @Summary 推送市场数据
+// @Description 接收外部采集程序推送的市场数据数组，逐条校验后保存合法数据，并在配置了Kafka时发送；非法数据不影响同批的合法数据，响应中返回每条非法数据的下标和原因。单次最多10000条；请求体可以使用Content-Encoding: gzip压缩；配置了INGEST_TOKEN时需要请求头 Authorization: Bearer <INGEST_TOKEN>
+// @Tags 市场
+// @Accept json
+// @Produce json
+// @Param request body []models.MarketData true "市场数据"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /market/ingest [post]
+func (s *Server) ingestMarketData(c *gin.Context) {
+	var records []models.MarketData
+	if !bindJSON(c, &records) {
+		return
+	}
+	if !checkItemCount(c, "records", len(records), MaxIngestRecords, false) {
+		return
+	}
+
+	result := models.IngestResult{Rejections: []models.IngestRejection{}}
+	accepted := make([]models.MarketData, 0, len(records))
+	for i, d := range records {
+		d.Source = models.CanonicalSource(d.Source)
+		if err := storage.ValidateMarketData(d); err != nil {
+			result.Rejections = append(result.Rejections, models.IngestRejection{Index: i, Reason: err.Error()})
+			continue
+		}
+		accepted = append(accepted, d)
+	}
+	result.Accepted = len(accepted)
+	result.Rejected = len(result.Rejections)
+
+	if len(accepted) > 0 {
+		if err := s.storage.SaveMarketData(accepted); err != nil {
+			logrus.Errorf("Failed to save %d ingested market data records: %v", len(accepted), err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save market data: " + err.Error()})
+			return
+		}
+
+		// 发送失败时数据已保存，只在结果中标记未发送
+		if s.marketDataPublisher != nil {
+			if err := s.marketDataPublisher.SendMarketData(accepted); err != nil {
+				logrus.Errorf("Failed to send %d ingested market data records to Kafka: %v", len(accepted), err)
+			} else {
+				result.Published = true
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Accepted %d of %d records", result.Accepted, len(records)),
+		Data:    result,
+	})
+}
+
+// compareSourcePrices 计算各数据源价格的最大偏离，少于两个数据源时不计算偏离
+func compareSourcePrices(prices map[string]float64, thresholdPct float64) models.CrossSourceComparison {
+	comparison := models.CrossSourceComparison{Prices: prices, ThresholdPct: thresholdPct}
+	if len(prices) < 2 {
+		return comparison
+	}
+
+	// 按数据源名称遍历，价格相同时结果稳定
+	sources := make([]string, 0, len(prices))
+	for source := range prices {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	comparison.MinSource, comparison.MaxSource = sources[0], sources[0]
+	for _, source := range sources[1:] {
+		if prices[source] < prices[comparison.MinSource] {
+			comparison.MinSource = source
+		}
+		if prices[source] > prices[comparison.MaxSource] {
+			comparison.MaxSource = source
+		}
+	}
+
+	if low := prices[comparison.MinSource]; low > 0 {
+		comparison.DivergencePct = (prices[comparison.MaxSource] - low) / low * 100
+	}
+	comparison.Divergent = comparison.DivergencePct > thresholdPct
+	return comparison
+}
+
+// Run 运行API服务器
+func (s *Server) Run(port string) error {
+	logrus.Infof("Starting API server on port %s", port)
+	srv := &http.Server{Addr: ":" + port, Handler: s.router}
+	s.mutex.Lock()
+	s.httpServer = srv
+	s.mutex.Unlock()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 停止接受新连接并等待处理中的请求完成，ctx到期时返回ctx的错误
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mutex.RLock()
+	srv := s.httpServer
+	s.mutex.RUnlock()
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+// 股票列表接口的默认返回条数和最大返回条数
+const (
+	DefaultStockListLimit = 100
+	MaxStockListLimit     = 5000
+)
+
+// stockListPaginator 股票列表接口的分页参数
+var stockListPaginator = NewPaginator(DefaultStockListLimit, MaxStockListLimit)
+
+// getStockList 获取已保存的股票列表
+// @Summary 获取已保存的股票列表
+// @Description 按ts_code升序分页返回数据库中已保存的股票基础信息，可按市场、交易所和上市状态过滤，没有数据时返回空数组；meta中返回总数、has_more和下一页的next_cursor
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Param limit query int false "每页条数，缺省或0时为100，最大5000，超过时截断"
+// @Param offset query int false "跳过的条数，默认0"
+// @Param cursor query string false "分页游标，优先于offset"
+// @Param market query string false "市场类型，例如 主板、创业板"
+// @Param exchange query string false "交易所，例如 SSE、SZSE"
+// @Param list_status query string false "上市状态：L上市、D退市、P暂停上市"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/list [get]
+func (s *Server) getStockList(c *gin.Context) {
+	page, err := stockListPaginator.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	query := storage.StockBasicQuery{
+		Market:     c.Query("market"),
+		Exchange:   c.Query("exchange"),
+		ListStatus: c.Query("list_status"),
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+	}
+
+	total, err := s.storage.CountStockBasic(c.Request.Context(), query)
+	if err != nil {
+		logrus.Errorf("Failed to count stock list: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to get stock list: %v", err),
 		})
+		return
+	}
+	stocks, err := s.storage.QueryStockBasic(c.Request.Context(), query)
+	if err != nil {
+		logrus.Errorf("Failed to get stock list: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to get stock list: %v", err),
+		})
+		return
+	}
+	if stocks == nil {
+		stocks = []models.StockBasic{}
 	}
 
+	meta := page.Meta(total)
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "OHLCV status retrieved",
+		Message: fmt.Sprintf("Retrieved %d stocks", len(stocks)),
+		Data:    stocks,
+		Meta:    &meta,
+	})
+}
+
+// fetchStockList 手动触发获取股票列表
+// @Summary 手动触发获取股票列表
+// @Description 手动触发从 Tushare API 获取股票列表并保存到数据库中
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/fetch-list [post]
+func (s *Server) fetchStockList(c *gin.Context) {
+	logrus.Info("Manually triggering stock list fetch")
+
+	// 准备请求参数
+	req := &datasource.StockBasicRequest{
+		ListStatus: "L", // 只获取上市的股票
+	}
+	fields := []string{
+		"ts_code", "symbol", "name", "area", "industry", "fullname", "enname", "cnspell",
+		"market", "exchange", "curr_type", "list_status", "list_date", "delist_date", "is_hs",
+		"act_name", "act_ent_type",
+	}
+
+	// 输出请求详细信息
+	logrus.Debugf("Requesting stock basic info with params: %+v", req)
+	logrus.Debugf("Requesting fields: %+v", fields)
+
+	// 调用 Tushare API 获取股票基础信息
+	resp, err := s.tushareClient.GetStockBasic(c.Request.Context(), req, fields)
+
+	// 输出响应详细信息
+	if resp != nil {
+		logrus.Debugf("Tushare API response code: %d", resp.Code)
+		logrus.Debugf("Tushare API response message: %s", resp.Message)
+		if resp.Data != nil {
+			logrus.Debugf("Tushare API response fields: %+v", resp.Data.Fields)
+			logrus.Debugf("Tushare API response items count: %d", len(resp.Data.Items))
+			if len(resp.Data.Items) > 0 {
+				logrus.Debugf("First item: %+v", resp.Data.Items[0])
+			}
+		}
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to fetch stock list: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to fetch stock list: %v", err),
+		})
+		return
+	}
+
+	// 解析响应数据
+	var stockList []models.StockBasic
+	if resp != nil && resp.Data != nil {
+		for _, item := range resp.Data.Items {
+			stockList = append(stockList, models.StockBasicFromRow(resp.Data.Fields, item))
+		}
+	}
+
+	logrus.Infof("Fetched %d stocks from Tushare API", len(stockList))
+	if len(stockList) > 0 {
+		logrus.Debugf("First 5 stocks: %+v", stockList[:min(5, len(stockList))])
+	}
+
+	// 保存到数据库
+	if len(stockList) > 0 {
+		logrus.Debugf("Saving %d stocks to database", len(stockList))
+		if err := s.storage.SaveStockBasic(stockList); err != nil {
+			logrus.Errorf("Failed to save stock list: %v", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: fmt.Sprintf("Failed to save stock list: %v", err),
+			})
+			return
+		}
+		logrus.Infof("Successfully saved %d stocks to database", len(stockList))
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Stock list fetched and saved successfully",
 		Data: map[string]interface{}{
-			"total_stocks": len(codes),
-			"sample":       stats,
+			"count": len(stockList),
 		},
 	})
 }
 
+// SyncOHLCVFullRequest 全量OHLCV同步请求
+type SyncOHLCVFullRequest struct {
+	Symbols   []string `json:"symbols"`    // 指定股票列表，为空则同步所有
+	StartYear int      `json:"start_year"` // 起始年份，默认2000
+	EndYear   int      `json:"end_year"`   // 结束年份，默认当前年份
+}
+
+// syncOHLCVFull 全量OHLCV前复权数据同步
+// @Summary 全量OHLCV前复权数据同步
+// @Description 按年分段同步A股日线前复权(OHLCV QFQ)数据，支持指定股票列表或全量同步
+// @Tags 同步
+// @Accept json
+// @Produce json
+// @Param request body SyncOHLCVFullRequest true "同步参数"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sync/ohlcv/full [post]
+func (s *Server) syncOHLCVFull(c *gin.Context) {
+	var req SyncOHLCVFullRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !checkItemCount(c, "symbols", len(req.Symbols), MaxRequestItems, true) {
+		return
+	}
+
+	// 默认参数
+	if req.StartYear == 0 {
+		req.StartYear = 2000
+	}
+	if req.EndYear == 0 {
+		req.EndYear = time.Now().Year()
+	}
+
+	// 获取股票列表
+	var tsCodes []string
+	if len(req.Symbols) > 0 {
+		tsCodes = req.Symbols
+	} else {
+		codes, err := s.storage.GetAllStockCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stock codes: " + err.Error()})
+			return
+		}
+		tsCodes = codes
+	}
+
+	logrus.Infof("Starting OHLCV QFQ sync for %d stocks, years %d-%d", len(tsCodes), req.StartYear, req.EndYear)
+
+	// 同步字段
+	fields := []string{"ts_code", "trade_date", "open", "high", "low", "close", "vol", "amount"}
+
+	totalSynced := 0
+	totalSkipped := 0
+	totalErrors := 0
+
+	// 遍历每只股票
+	for _, tsCode := range tsCodes {
+		logrus.Infof("Syncing %s", tsCode)
+
+		// 检查已存在的日期范围
+		existingMin, existingMax, _ := s.storage.GetExistingDateRangeForSymbol(tsCode)
+		if existingMin != "" && existingMax != "" {
+			logrus.Debugf("%s already has data from %s to %s, will fill gaps", tsCode, existingMin, existingMax)
+		}
+
+		// 按年分段同步
+		for year := req.StartYear; year <= req.EndYear; year++ {
+			startDate := fmt.Sprintf("%d0101", year)
+			endDate := fmt.Sprintf("%d1231", year)
+
+			// 获取日线数据（未复权）
+			dailyResp, err := s.tushareClient.GetDaily(c.Request.Context(), &datasource.DailyRequest{
+				TSCode:    tsCode,
+				StartDate: startDate,
+				EndDate:   endDate,
+			}, fields)
+			if err != nil {
+				logrus.Errorf("Failed to fetch daily for %s year %d: %v", tsCode, year, err)
+				totalErrors++
+				continue
+			}
+			if dailyResp == nil || dailyResp.Data == nil || len(dailyResp.Data.Items) == 0 {
+				logrus.Debugf("No daily data for %s year %d", tsCode, year)
+				totalSkipped++
+				time.Sleep(250 * time.Millisecond)
+				continue
+			}
+
+			// 获取复权因子
+			adjResp, err := s.tushareClient.GetAdjFactor(c.Request.Context(), &datasource.AdjFactorRequest{
+				TSCode:    tsCode,
+				StartDate: startDate,
+				EndDate:   endDate,
+			}, []string{"ts_code", "trade_date", "adj_factor"})
+			if err != nil {
+				logrus.Errorf("Failed to fetch adj_factor for %s year %d: %v", tsCode, year, err)
+				totalErrors++
+				time.Sleep(250 * time.Millisecond)
+				continue
+			}
+
+			// 解析并应用前复权
+			ohlcvList := datasource.BuildQFQBars(dailyResp, adjResp)
+
+			if len(ohlcvList) > 0 {
+				if err := s.storage.SaveOHLCVDailyQFQ(ohlcvList); err != nil {
+					logrus.Errorf("Failed to save OHLCV for %s year %d: %v", tsCode, year, err)
+					totalErrors++
+				} else {
+					totalSynced += len(ohlcvList)
+					logrus.Infof("Saved %d QFQ records for %s year %d", len(ohlcvList), tsCode, year)
+				}
+			} else {
+				totalSkipped++
+			}
+
+			// 速率限制：250ms
+			time.Sleep(250 * time.Millisecond)
+		}
+	}
+
+	logrus.Infof("OHLCV sync completed: %d records synced, %d years skipped, %d errors",
+		totalSynced, totalSkipped, totalErrors)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "OHLCV QFQ sync completed",
+		Data: map[string]interface{}{
+			"total_synced":  totalSynced,
+			"total_skipped": totalSkipped,
+			"total_errors":  totalErrors,
+			"stocks_count":  len(tsCodes),
+			"start_year":    req.StartYear,
+			"end_year":      req.EndYear,
+		},
+	})
+}
+
+// syncTradeCalendar 同步交易日历
+// @Summary 同步交易日历
+// @Description 从Tushare同步交易日历到trade_calendar表和按交易所区分的trade_cal表
+// @Tags 同步
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sync/trade-calendar [post]
+func (s *Server) syncTradeCalendar(c *gin.Context) {
+	logrus.Info("Starting trade calendar sync")
+
+	// Tushare trade_cal API字段
+	fields := []string{"exchange", "cal_date", "is_open", "pretrade_date"}
+
+	// 同步最近20年的数据
+	endDate := time.Now().Format("20060102")
+	startDate := fmt.Sprintf("%d0101", time.Now().Year()-20)
+
+	req := &datasource.TradeCalRequest{
+		Exchange:  "",
+		StartDate: startDate,
+		EndDate:   endDate,
+		IsOpen:    "",
+	}
+
+	resp, err := s.tushareClient.GetTradeCal(c.Request.Context(), req, fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch trade cal: " + err.Error()})
+		return
+	}
+
+	if resp == nil || resp.Data == nil || len(resp.Data.Items) == 0 {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "No trade calendar data returned",
+			Data:    map[string]interface{}{"count": 0},
+		})
+		return
+	}
+
+	// 解析并保存：is_open可能是数字，由DecodeItems统一转为字符串；Tushare的pretrade_date与TradeCal的json标签不同，单独读取
+	var decoded []models.TradeCal
+	if err := datasource.DecodeItems(resp, &decoded); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to decode trade cal: " + err.Error()})
+		return
+	}
+	preTradeDateIndex := -1
+	for i, field := range resp.Data.Fields {
+		if field == "pretrade_date" {
+			preTradeDateIndex = i
+		}
+	}
+	tradeCalList := make([]models.TradeCal, 0, len(decoded))
+	for row, tc := range decoded {
+		if preTradeDateIndex >= 0 && preTradeDateIndex < len(resp.Data.Items[row]) {
+			tc.PreTradeDate = datasource.AsString(resp.Data.Items[row][preTradeDateIndex])
+		}
+		if tc.CalDate != "" {
+			tradeCalList = append(tradeCalList, tc)
+		}
+	}
+
+	if err := s.storage.SaveTradeCalendar(tradeCalList); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save trade calendar: " + err.Error()})
+		return
+	}
+
+	logrus.Infof("Saved %d trade calendar records", len(tradeCalList))
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Trade calendar synced successfully",
+		Data: map[string]interface{}{
+			"count": len(tradeCalList),
+		},
+	})
+}
+
+// getOHLCVStatus 获取OHLCV同步状态
+// @Summary 获取OHLCV同步状态
+// @Description 查看各股票的OHLCV数据同步情况
+// @Tags 同步
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sync/ohlcv/status [get]
+func (s *Server) getOHLCVStatus(c *gin.Context) {
+	tsCode := c.Query("ts_code")
+
+	if tsCode != "" {
+		count, _ := s.storage.GetOHLCVCountBySymbol(tsCode)
+		minDate, maxDate, _ := s.storage.GetExistingDateRangeForSymbol(tsCode)
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "OHLCV status retrieved",
+			Data: map[string]interface{}{
+				"ts_code":  tsCode,
+				"count":    count,
+				"min_date": minDate,
+				"max_date": maxDate,
+			},
+		})
+		return
+	}
+
+	// 返回总体统计
+	codes, err := s.storage.GetAllStockCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	stats := make([]map[string]interface{}, 0, min(100, len(codes)))
+	for _, code := range codes {
+		if len(stats) >= 100 {
+			break
+		}
+		count, _ := s.storage.GetOHLCVCountBySymbol(code)
+		minDate, maxDate, _ := s.storage.GetExistingDateRangeForSymbol(code)
+		stats = append(stats, map[string]interface{}{
+			"ts_code":  code,
+			"count":    count,
+			"min_date": minDate,
+			"max_date": maxDate,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "OHLCV status retrieved",
+		Data: map[string]interface{}{
+			"total_stocks": len(codes),
+			"sample":       stats,
+		},
+	})
+}
+
+// 管理层接口的默认每页条数和最大每页条数
+const (
+	DefaultStkManagersLimit = 50
+	MaxStkManagersLimit     = 500
+)
+
+// stkManagersPaginator 管理层接口的分页参数
+var stkManagersPaginator = NewPaginator(DefaultStkManagersLimit, MaxStkManagersLimit)
+
+// getStkManagers 分页获取上市公司管理层
+// @Summary 获取上市公司管理层
+// @Description 按公告日期倒序、姓名升序分页返回公司在公告日期范围内的管理层，后面还有数据时返回has_more、next_offset和next_cursor；没有数据时data为空数组
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Param ts_code query string true "股票代码，例如 000001.SZ"
+// @Param start query string false "公告日期起始（含），格式YYYYMMDD"
+// @Param end query string false "公告日期结束（含），格式YYYYMMDD"
+// @Param limit query int false "每页条数，缺省或0时为50，最大500，超过时截断"
+// @Param offset query int false "跳过的条数，默认0"
+// @Param cursor query string false "分页游标，优先于offset"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/managers [get]
+func (s *Server) getStkManagers(c *gin.Context) {
+	tsCode := c.Query("ts_code")
+	if tsCode == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "ts_code is required"})
+		return
+	}
+	pagination, err := stkManagersPaginator.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	limit, offset := pagination.Limit, pagination.Offset
+	start, end := c.Query("start"), c.Query("end")
+
+	// 多取一条判断是否还有下一页
+	managers, err := s.storage.GetStkManagersFiltered(c.Request.Context(), tsCode, start, end, limit+1, offset)
+	if errors.Is(err, storage.ErrInvalidDateRange) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to get stk managers for %s: %v", tsCode, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stk managers: " + err.Error()})
+		return
+	}
+
+	page := models.StkManagersPage{TSCode: tsCode, Start: start, End: end, Limit: limit, Offset: offset, Data: managers}
+	if len(managers) > limit {
+		next := offset + limit
+		page.Data = managers[:limit]
+		page.HasMore = true
+		page.NextOffset = &next
+		page.NextCursor = encodeCursor(next)
+	}
+	if page.Data == nil {
+		page.Data = []models.StkManagers{}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Retrieved %d managers", len(page.Data)),
+		Data:    page,
+	})
+}
+
+// DefaultHighLowWindow 滚动最高最低价默认的交易日窗口
+const DefaultHighLowWindow = 20
+
+// getStoredTradeDates 获取股票已保存日线的交易日
+// @Summary 获取已保存日线的交易日
+// @Description 返回数据库中该股票已保存日线的交易日（去重、升序），可与交易日历对比找出缺失的日线；没有日线时dates为空数组
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Param ts_code query string true "股票代码，例如 000001.SZ"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/daily/dates [get]
+func (s *Server) getStoredTradeDates(c *gin.Context) {
+	tsCode := c.Query("ts_code")
+	if tsCode == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "ts_code is required"})
+		return
+	}
+
+	dates, err := s.storage.GetStoredTradeDates(c.Request.Context(), tsCode)
+	if err != nil {
+		logrus.Errorf("Failed to get stored trade dates for %s: %v", tsCode, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stored trade dates: " + err.Error()})
+		return
+	}
+	if dates == nil {
+		dates = []string{}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Stored trade dates retrieved successfully",
+		Data:    models.StoredTradeDates{TSCode: tsCode, Dates: dates},
+	})
+}
+
+// getMissingTradingDays 获取股票缺失日线的交易日
+// @Summary 获取缺失日线的交易日
+// @Description 返回交易所在[start, end]内开市（按trade_cal）、但数据库中该股票没有日线的日期（YYYYMMDD，升序），回补时可以只拉取这些日期；没有缺失时dates为空数组
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Param ts_code query string true "股票代码，例如 000001.SZ"
+// @Param exchange query string false "交易所，默认SSE"
+// @Param start query string true "开始日期，格式YYYYMMDD"
+// @Param end query string true "结束日期，格式YYYYMMDD"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/daily/missing [get]
+func (s *Server) getMissingTradingDays(c *gin.Context) {
+	tsCode := c.Query("ts_code")
+	if tsCode == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "ts_code is required"})
+		return
+	}
+	exchange := c.DefaultQuery("exchange", storage.DefaultExchange)
+	start, end := c.Query("start"), c.Query("end")
+
+	dates, err := s.storage.FindMissingTradingDays(c.Request.Context(), tsCode, exchange, start, end)
+	if errors.Is(err, storage.ErrInvalidDateRange) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to find missing trading days for %s: %v", tsCode, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to find missing trading days: " + err.Error()})
+		return
+	}
+	if dates == nil {
+		dates = []string{}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d missing trading days", len(dates)),
+		Data:    models.MissingTradeDates{TSCode: tsCode, Exchange: exchange, Start: start, End: end, Dates: dates},
+	})
+}
+
+// getRollingHighLow 获取股票N日最高价和最低价
+// @Summary 获取N日最高最低价
+// @Description 计算截至as_of（含）最近window个交易日日线的最高价和最低价，用于突破类策略；不足window个交易日时使用已有的全部日线，没有日线时返回404
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Param ts_code query string true "股票代码，例如 000001.SZ"
+// @Param window query int false "交易日窗口，默认20，必须大于0"
+// @Param as_of query string false "截止日期，格式：YYYYMMDD，默认最新交易日"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/highlow [get]
+func (s *Server) getRollingHighLow(c *gin.Context) {
+	tsCode := c.Query("ts_code")
+	if tsCode == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "ts_code is required"})
+		return
+	}
+
+	window := DefaultHighLowWindow
+	if raw := c.Query("window"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "window must be a positive integer"})
+			return
+		}
+		window = parsed
+	}
+
+	asOf := c.Query("as_of")
+	if asOf != "" {
+		if _, err := time.Parse("20060102", asOf); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid as_of format, use YYYYMMDD"})
+			return
+		}
+	}
+
+	high, low, err := s.storage.GetRollingHighLow(c.Request.Context(), tsCode, window, asOf)
+	if errors.Is(err, storage.ErrNoDailyData) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: fmt.Sprintf("No daily data for %s", tsCode)})
+		return
+	}
+	if errors.Is(err, storage.ErrInvalidWindow) || errors.Is(err, storage.ErrInvalidDateRange) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to get rolling high/low for %s: %v", tsCode, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get rolling high/low: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Rolling high/low calculated",
+		Data:    models.RollingHighLow{TSCode: tsCode, Window: window, AsOf: asOf, High: high, Low: low},
+	})
+}
+
+// getSplitAdjustedDaily 获取复权调整后的日线行情
+// @Summary 获取复权调整后的日线行情
+// @Description 使用已保存的复权因子对日线价格进行前复权（以区间内最新因子为基准），拆股/送转前后价格连续，适合回测使用
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Param ts_code query string true "股票代码，例如 000001.SZ"
+// @Param start_date query string true "开始日期，格式：YYYYMMDD"
+// @Param end_date query string true "结束日期，格式：YYYYMMDD"
+// @Param include query string false "附加字段，逗号分隔，目前支持 name（股票名称）"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/daily/adjusted [get]
+func (s *Server) getSplitAdjustedDaily(c *gin.Context) {
+	tsCode := c.Query("ts_code")
+	if tsCode == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "ts_code is required"})
+		return
+	}
+
+	startDate := c.Query("start_date")
+	start, err := time.Parse("20060102", startDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid start_date format, use YYYYMMDD"})
+		return
+	}
+	endDate := c.Query("end_date")
+	end, err := time.Parse("20060102", endDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid end_date format, use YYYYMMDD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end_date must be after start_date"})
+		return
+	}
+
+	includeName := false
+	for _, field := range splitQueryList(c.Query("include")) {
+		if field != "name" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("unsupported include field %q", field)})
+			return
+		}
+		includeName = true
+	}
+
+	data, err := s.storage.GetSplitAdjustedDaily(c.Request.Context(), tsCode, startDate, endDate)
+	if err != nil {
+		logrus.Errorf("Failed to get split adjusted daily for %s: %v", tsCode, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get adjusted daily data: " + err.Error()})
+		return
+	}
+
+	if !includeName {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Adjusted daily data retrieved successfully",
+			Data:    data,
+		})
+		return
+	}
+
+	names, err := s.stockNames.Names(c.Request.Context())
+	if err != nil {
+		logrus.Errorf("Failed to load stock names: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to load stock names: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Adjusted daily data retrieved successfully",
+		Data:    withStockNames(data, names),
+	})
+}
+
+// withStockNames 为日线附加股票名称，名称未知时为空字符串
+func withStockNames(data []models.Daily, names map[string]string) []models.DailyWithName {
+	enriched := make([]models.DailyWithName, len(data))
+	for i, d := range data {
+		enriched[i] = models.DailyWithName{Daily: d, Name: names[d.TSCode]}
+	}
+	return enriched
+}
+
+// MaxDailyColumnsCodes 按列批量获取日线时单次请求的最大股票数
+const MaxDailyColumnsCodes = 500
+
+// getDailyColumns 按列批量获取多只股票的日线数据
+// @Summary 按列批量获取日线数据
+// @Description 返回列式结果（ts_code、trade_date与各数值列一一对应），按股票代码和交易日升序排列，适合回测批量拉取，单次最多500只股票
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Param ts_codes query string true "股票代码，逗号分隔，例如 000001.SZ,600000.SH"
+// @Param cols query string true "数值列，逗号分隔，可选 open,high,low,close,pre_close,change,pct_chg,vol,amount"
+// @Param start_date query string true "开始日期，格式：YYYYMMDD"
+// @Param end_date query string true "结束日期，格式：YYYYMMDD"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/daily/columns [get]
+func (s *Server) getDailyColumns(c *gin.Context) {
+	tsCodes := splitQueryList(c.Query("ts_codes"))
+	if len(tsCodes) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "ts_codes is required"})
+		return
+	}
+	if len(tsCodes) > MaxDailyColumnsCodes {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: fmt.Sprintf("too many ts_codes: %d, maximum is %d", len(tsCodes), MaxDailyColumnsCodes),
+		})
+		return
+	}
+
+	cols, err := storage.ValidateDailyColumns(splitQueryList(c.Query("cols")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	startDate := c.Query("start_date")
+	start, err := time.Parse("20060102", startDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid start_date format, use YYYYMMDD"})
+		return
+	}
+	endDate := c.Query("end_date")
+	end, err := time.Parse("20060102", endDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid end_date format, use YYYYMMDD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end_date must be after start_date"})
+		return
+	}
+
+	data, err := s.storage.GetDailyColumns(c.Request.Context(), tsCodes, cols, startDate, endDate)
+	if err != nil {
+		logrus.Errorf("Failed to get daily columns: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get daily columns: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Daily columns retrieved successfully",
+		Data:    data,
+	})
+}
+
+// splitQueryList 解析逗号分隔的查询参数，去除空白和空项
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RecomputeDailyRequest 重新计算日线涨跌幅请求
+type RecomputeDailyRequest struct {
+	TSCodes []string `json:"ts_codes"` // 指定股票列表，为空则处理所有股票
+}
+
+// recomputeDailyChanges 重新计算日线的昨收、涨跌额和涨跌幅
+// @Summary 重新计算日线涨跌幅
+// @Description 按交易日顺序用前一交易日收盘价回填pre_close，并计算change和pct_chg，只更新这些字段为空或为0的行（如CSV导入的日线）
+// @Tags 股票
+// @Accept json
+// @Produce json
+// @Param request body RecomputeDailyRequest false "重新计算参数"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stock/daily/recompute [post]
+func (s *Server) recomputeDailyChanges(c *gin.Context) {
+	var req RecomputeDailyRequest
+	if c.Request.ContentLength > 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+	if !checkItemCount(c, "ts_codes", len(req.TSCodes), MaxRequestItems, true) {
+		return
+	}
+
+	tsCodes := req.TSCodes
+	if len(tsCodes) == 0 {
+		codes, err := s.storage.GetAllStockCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stock codes: " + err.Error()})
+			return
+		}
+		tsCodes = codes
+	}
+
+	var updated int64
+	var failed []string
+	for _, tsCode := range tsCodes {
+		n, err := s.storage.RecomputeDailyChanges(c.Request.Context(), tsCode)
+		if err != nil {
+			logrus.Errorf("Failed to recompute daily changes for %s: %v", tsCode, err)
+			failed = append(failed, tsCode)
+			continue
+		}
+		updated += n
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: len(failed) == 0,
+		Message: fmt.Sprintf("Recomputed daily changes for %d stocks, %d rows updated", len(tsCodes)-len(failed), updated),
+		Data: map[string]interface{}{
+			"total":   len(tsCodes),
+			"updated": updated,
+			"failed":  failed,
+		},
+	})
+}
+
+// getOpenTradeDates 获取交易日列表
+// @Summary 获取交易日列表
+// @Description 返回交易所在[start, end]内开市的日期（YYYYMMDD），按日期升序，不包含周末和节假日
+// @Tags 交易日历
+// @Accept json
+// @Produce json
+// @Param exchange query string false "交易所，默认SSE"
+// @Param start query string true "开始日期，格式YYYYMMDD"
+// @Param end query string true "结束日期，格式YYYYMMDD"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /trade-cal/open [get]
+func (s *Server) getOpenTradeDates(c *gin.Context) {
+	exchange := c.DefaultQuery("exchange", storage.DefaultExchange)
+	dates, err := s.storage.GetOpenTradeDates(c.Request.Context(), exchange, c.Query("start"), c.Query("end"))
+	if errors.Is(err, storage.ErrInvalidDateRange) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to get open trade dates for %s: %v", exchange, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get open trade dates: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d open trade dates", len(dates)),
+		Data:    dates,
+	})
+}
+
+// getPipelineSymbols 获取数据处理流水线每个交易对的处理状态
+// @Summary 获取各交易对的处理状态
+// @Description 返回每个交易对最近一次成功保存的时间、最近一次获取或保存失败的错误和连续失败次数，用于定位持续失败的交易对
+// @Tags 数据源
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /pipeline/symbols [get]
+func (s *Server) getPipelineSymbols(c *gin.Context) {
+	if s.symbolStatus == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Pipeline status is not available"})
+		return
+	}
+
+	statuses := s.symbolStatus.SymbolStatuses()
+	failing := 0
+	for _, status := range statuses {
+		if status.Failing {
+			failing++
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d of %d symbols failing", failing, len(statuses)),
+		Data:    statuses,
+	})
+}
+
+// getPipelineStatus 获取数据处理流水线的运行状态
+// @Summary 获取流水线运行状态
+// @Description 返回流水线是否已暂停及暂停时间、实际处理间隔、最近一次成功处理的时间和交易对数量
+// @Tags 数据源
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /pipeline/status [get]
+func (s *Server) getPipelineStatus(c *gin.Context) {
+	if s.pipeline == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Pipeline status is not available"})
+		return
+	}
+
+	status := s.pipeline.Status()
+	message := "Pipeline is running"
+	if status.Paused {
+		message = "Pipeline is paused"
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    status,
+	})
+}
+
+// getConsistencyStats 获取数据库与Kafka双写一致性检查结果
+// @Summary 获取双写一致性检查结果
+// @Description 返回一致性检查的累计次数、抽样记录数和已保存到数据库但Kafka中没有读到的记录数，以及最近一次检查缺失的记录ID（最多20个）；未启用CONSISTENCY_CHECK_ENABLED时返回503
+// @Tags 数据源
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /pipeline/consistency [get]
+func (s *Server) getConsistencyStats(c *gin.Context) {
+	if s.consistency == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Consistency check is not enabled"})
+		return
+	}
+
+	stats := s.consistency.Stats()
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d of %d sampled records missing from Kafka", stats.Missing, stats.Sampled),
+		Data:    stats,
+	})
+}
+
+// pausePipeline 暂停数据处理流水线
+// @Summary 暂停数据处理
+// @Description 暂停数据处理流水线，恢复前跳过每个处理周期（不再从数据源获取数据），正在进行的周期会完成；暂停期间不触发SLA告警。已暂停时同样返回200；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/pipeline/pause [post]
+func (s *Server) pausePipeline(c *gin.Context) {
+	if s.pipeline == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Pipeline control is not available"})
+		return
+	}
+
+	message := "Pipeline paused"
+	if !s.pipeline.Pause() {
+		message = "Pipeline was already paused"
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    s.pipeline.Status(),
+	})
+}
+
+// resumePipeline 恢复数据处理流水线
+// @Summary 恢复数据处理
+// @Description 恢复已暂停的数据处理流水线，从下一个处理周期开始正常处理。未暂停时同样返回200；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/pipeline/resume [post]
+func (s *Server) resumePipeline(c *gin.Context) {
+	if s.pipeline == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Pipeline control is not available"})
+		return
+	}
+
+	message := "Pipeline resumed"
+	if !s.pipeline.Resume() {
+		message = "Pipeline was not paused"
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    s.pipeline.Status(),
+	})
+}
+
+// getSourceFreshness 获取各数据源的数据新鲜度
+// @Summary 获取各数据源的数据新鲜度
+// @Description 返回每个数据源最新一条市场数据的时间，超过阈值的数据源标记为stale
+// @Tags 数据源
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /datasource/freshness [get]
+func (s *Server) getSourceFreshness(c *gin.Context) {
+	latest, err := s.storage.GetSourceFreshness(c.Request.Context())
+	if err != nil {
+		logrus.Errorf("Failed to get source freshness: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get source freshness: " + err.Error()})
+		return
+	}
+
+	freshness := evaluateFreshness(latest, s.stalenessThreshold, time.Now())
+	for _, f := range freshness {
+		if f.Stale {
+			logrus.Warnf("Data source %s is stale, last data at %s", f.Source, f.LastSeen.Format(time.RFC3339))
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Source freshness retrieved",
+		Data:    freshness,
+	})
+}
+
+// getDataSourceSymbols 获取数据源支持的交易对
+// @Summary 获取数据源支持的交易对
+// @Description 返回数据源支持的交易对，使用数据源的交易对名称（如OKX为BTC-USDT）；交易所数据源的列表缓存1小时，数据处理流水线跳过列表之外的交易对
+// @Tags 数据源
+// @Accept json
+// @Produce json
+// @Param name path string true "数据源名称，如binance、okx"
+// @Success 200 {object} models.APIResponse{data=[]string}
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /datasource/{name}/symbols [get]
+func (s *Server) getDataSourceSymbols(c *gin.Context) {
+	if s.dataSources == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Data sources are not available"})
+		return
+	}
+
+	name := models.CanonicalSource(c.Param("name"))
+	source := s.dataSources.GetDataSource(name)
+	if source == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: fmt.Sprintf("Data source not found: %s", name)})
+		return
+	}
+
+	symbols, err := source.SupportedSymbols(c.Request.Context())
+	if err != nil {
+		logrus.Errorf("Failed to get supported symbols of %s: %v", name, err)
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{Error: fmt.Sprintf("Failed to get supported symbols of %s: %v", name, err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d symbols supported by %s", len(symbols), name),
+		Data:    symbols,
+	})
+}
+
+// evaluateFreshness 计算各数据源的数据延迟并标记停止更新的数据源，结果按数据源名称排序
+func evaluateFreshness(latest map[string]time.Time, threshold time.Duration, now time.Time) []models.SourceFreshness {
+	freshness := make([]models.SourceFreshness, 0, len(latest))
+	for source, lastSeen := range latest {
+		age := now.Sub(lastSeen)
+		freshness = append(freshness, models.SourceFreshness{
+			Source:     source,
+			LastSeen:   lastSeen,
+			AgeSeconds: age.Seconds(),
+			Stale:      age > threshold,
+		})
+	}
+	sort.Slice(freshness, func(i, j int) bool { return freshness[i].Source < freshness[j].Source })
+	return freshness
+}
+
+// BackfillRequest 全市场回补请求，提供ResumeID时从该任务的检查点恢复，忽略日期参数
+type BackfillRequest struct {
+	StartDate string `json:"start_date"` // 开始日期，格式：YYYY-MM-DD
+	EndDate   string `json:"end_date"`   // 结束日期，格式：YYYY-MM-DD
+	ResumeID  string `json:"resume_id"`  // 需要恢复的任务ID
+}
+
+// CodeTooManyJobs 运行中的后台任务数达到上限时的错误码
+const CodeTooManyJobs = "TOO_MANY_JOBS"
+
+// startBackfill 启动全市场日线回补任务
+// @Summary 启动全市场日线回补任务
+// @Description 后台逐只股票回补前复权日线，进度写入backfill_progress表，可通过resume_id从检查点恢复；运行中的任务数达到MAX_ACTIVE_JOBS时返回429；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <ADMIN_TOKEN>"
+// @Param request body BackfillRequest true "回补参数"
+// @Success 202 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/backfill [post]
+func (s *Server) startBackfill(c *gin.Context) {
+	var req BackfillRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var progress models.BackfillProgress
+	var err error
+	if req.ResumeID != "" {
+		progress, err = s.backfill.Resume(req.ResumeID)
+	} else {
+		startDate, parseErr := time.Parse("2006-01-02", req.StartDate)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid start_date format, use YYYY-MM-DD"})
+			return
+		}
+		endDate, parseErr := time.Parse("2006-01-02", req.EndDate)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid end_date format, use YYYY-MM-DD"})
+			return
+		}
+		if endDate.Before(startDate) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end_date must be after start_date"})
+			return
+		}
+		progress, err = s.backfill.Start(startDate.Format("20060102"), endDate.Format("20060102"))
+	}
+
+	switch {
+	case errors.Is(err, backfill.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, backfill.ErrJobNotResumable):
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, backfill.ErrTooManyJobs):
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{Error: err.Error(), Code: CodeTooManyJobs})
+		return
+	case err != nil:
+		logrus.Errorf("Failed to start backfill: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to start backfill: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.APIResponse{
+		Success: true,
+		Message: "Backfill job started",
+		Data:    progress,
+	})
+}
+
+// getBackfillProgress 获取回补任务进度
+// @Summary 获取回补任务进度
+// @Description 返回回补任务已完成/总股票数、当前处理的股票和错误信息；已结束的任务超过一小时后从backfill_progress表读取；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <ADMIN_TOKEN>"
+// @Param id path string true "任务ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/backfill/{id} [get]
+func (s *Server) getBackfillProgress(c *gin.Context) {
+	progress, err := s.backfill.Get(c.Param("id"))
+	if errors.Is(err, backfill.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Backfill progress retrieved",
+		Data:    progress,
+	})
+}
+
+// cancelBackfill 取消回补任务
+// @Summary 取消回补任务
+// @Description 取消运行中的回补任务，已完成的股票保留在检查点中，可通过resume_id恢复；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <ADMIN_TOKEN>"
+// @Param id path string true "任务ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/backfill/{id}/cancel [post]
+func (s *Server) cancelBackfill(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.backfill.Cancel(id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Backfill job cancellation requested",
+		Data:    map[string]interface{}{"id": id},
+	})
+}
+
+// getActiveJobs 获取运行中的后台任务
+// @Summary 获取运行中的后台任务
+// @Description 返回运行中的回补任务及其进度，按启动时间排序；任务完成、失败或取消后不再出现在列表中；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <ADMIN_TOKEN>"
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/jobs/active [get]
+func (s *Server) getActiveJobs(c *gin.Context) {
+	active := s.backfill.Active()
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d active jobs", len(active)),
+		Data:    active,
+	})
+}
+
+// getTushareLimits 获取Tushare接口限流状态
+// @Summary 获取Tushare接口限流状态
+// @Description 返回各Tushare接口配置的每分钟调用上限、当前可用令牌数和距离下一个令牌的秒数，只包含已调用过的接口；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/tushare/limits [get]
+func (s *Server) getTushareLimits(c *gin.Context) {
+	limits := []models.RateLimitState{}
+	if reporter, ok := s.tushareClient.(datasource.RateLimitReporter); ok {
+		if state := reporter.RateLimits(); state != nil {
+			limits = state
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Tushare rate limits retrieved successfully",
+		Data:    limits,
+	})
+}
+
+// getCacheStats 获取内存缓存统计
+// @Summary 获取内存缓存统计
+// @Description 返回各内存缓存的条目数、命中次数、未命中次数和命中率；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/cache [get]
+func (s *Server) getCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Cache stats retrieved successfully",
+		Data:    s.cacheStats(),
+	})
+}
+
+// purgeCache 清空内存缓存
+// @Summary 清空内存缓存
+// @Description 清空指定名称的内存缓存，不指定name时清空全部，缓存在下一次查询时重新加载；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Param name query string false "缓存名称，例如 stock_names"
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/cache/purge [post]
+func (s *Server) purgeCache(c *gin.Context) {
+	name := c.Query("name")
+	purged, err := s.purgeCaches(name)
+	if errors.Is(err, ErrUnknownCache) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: fmt.Sprintf("%v: %s", err, name)})
+		return
+	}
+
+	logrus.Infof("Purged caches: %v", purged)
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Cache purged successfully",
+		Data:    map[string]interface{}{"purged": purged},
+	})
+}
+
+// reloadConfigHandler 重新加载运行时可修改的配置
+// @Summary 重新加载配置
+// @Description 重新读取.env文件和环境变量，立即应用新的日志级别和数据处理间隔；数据库等需要重启的配置发生变化时拒绝重新加载，所有配置保持不变；需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/config/reload [post]
+func (s *Server) reloadConfigHandler(c *gin.Context) {
+	if s.reloadConfig == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Config reload is not available"})
+		return
+	}
+
+	reloaded, err := s.reloadConfig()
+	if errors.Is(err, config.ErrNonReloadableChange) || errors.Is(err, config.ErrInvalidReload) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to reload config: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to reload config: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Config reloaded successfully",
+		Data:    reloaded,
+	})
+}
+
 // min returns the smaller of x or y
 func min(x, y int) int {
 	if x < y {
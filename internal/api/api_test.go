@@ -1,12 +1,25 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"quant-data-engine/internal/backfill"
+	"quant-data-engine/internal/config"
 	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/events"
 	"quant-data-engine/internal/models"
+	"quant-data-engine/internal/parquet"
+	"quant-data-engine/internal/storage"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -15,10 +28,12 @@ import (
 // MockTushareClient 模拟 Tushare 客户端
 type MockTushareClient struct {
 	GetStockBasicFunc func(req *datasource.StockBasicRequest, fields []string) (*datasource.TushareResponse, error)
+	GetDailyFunc      func(req *datasource.DailyRequest, fields []string) (*datasource.TushareResponse, error)
+	GetTradeCalFunc   func(req *datasource.TradeCalRequest, fields []string) (*datasource.TushareResponse, error)
 }
 
 // GetStockBasic 模拟获取股票基础信息
-func (m *MockTushareClient) GetStockBasic(req *datasource.StockBasicRequest, fields []string) (*datasource.TushareResponse, error) {
+func (m *MockTushareClient) GetStockBasic(ctx context.Context, req *datasource.StockBasicRequest, fields []string) (*datasource.TushareResponse, error) {
 	if m.GetStockBasicFunc != nil {
 		return m.GetStockBasicFunc(req, fields)
 	}
@@ -26,39 +41,93 @@ func (m *MockTushareClient) GetStockBasic(req *datasource.StockBasicRequest, fie
 }
 
 // GetTradeCal 模拟获取交易日历
-func (m *MockTushareClient) GetTradeCal(req *datasource.TradeCalRequest, fields []string) (*datasource.TushareResponse, error) {
+func (m *MockTushareClient) GetTradeCal(ctx context.Context, req *datasource.TradeCalRequest, fields []string) (*datasource.TushareResponse, error) {
+	if m.GetTradeCalFunc != nil {
+		return m.GetTradeCalFunc(req, fields)
+	}
 	return nil, nil
 }
 
 // GetNewShare 模拟获取新股上市列表
-func (m *MockTushareClient) GetNewShare(req *datasource.NewShareRequest, fields []string) (*datasource.TushareResponse, error) {
+func (m *MockTushareClient) GetNewShare(ctx context.Context, req *datasource.NewShareRequest, fields []string) (*datasource.TushareResponse, error) {
 	return nil, nil
 }
 
 // GetStockCompany 模拟获取上市公司基础信息
-func (m *MockTushareClient) GetStockCompany(req *datasource.StockCompanyRequest, fields []string) (*datasource.TushareResponse, error) {
+func (m *MockTushareClient) GetStockCompany(ctx context.Context, req *datasource.StockCompanyRequest, fields []string) (*datasource.TushareResponse, error) {
 	return nil, nil
 }
 
 // GetStkManagers 模拟获取上市公司管理层
-func (m *MockTushareClient) GetStkManagers(req *datasource.StkManagersRequest, fields []string) (*datasource.TushareResponse, error) {
+func (m *MockTushareClient) GetStkManagers(ctx context.Context, req *datasource.StkManagersRequest, fields []string) (*datasource.TushareResponse, error) {
 	return nil, nil
 }
 
 // GetStkRewards 模拟获取管理层薪酬和持股
-func (m *MockTushareClient) GetStkRewards(req *datasource.StkRewardsRequest, fields []string) (*datasource.TushareResponse, error) {
+func (m *MockTushareClient) GetStkRewards(ctx context.Context, req *datasource.StkRewardsRequest, fields []string) (*datasource.TushareResponse, error) {
 	return nil, nil
 }
 
 // GetDaily 模拟获取A股日线行情
-func (m *MockTushareClient) GetDaily(req *datasource.DailyRequest, fields []string) (*datasource.TushareResponse, error) {
+func (m *MockTushareClient) GetDaily(ctx context.Context, req *datasource.DailyRequest, fields []string) (*datasource.TushareResponse, error) {
+	if m.GetDailyFunc != nil {
+		return m.GetDailyFunc(req, fields)
+	}
+	return nil, nil
+}
+
+// GetProBar 模拟获取行情数据
+func (m *MockTushareClient) GetProBar(ctx context.Context, req *datasource.ProBarRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+// GetAdjFactor 模拟获取复权因子
+func (m *MockTushareClient) GetAdjFactor(ctx context.Context, req *datasource.AdjFactorRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+// GetMoneyflow 模拟获取个股资金流向
+func (m *MockTushareClient) GetMoneyflow(ctx context.Context, req *datasource.MoneyflowRequest, fields []string) (*datasource.TushareResponse, error) {
 	return nil, nil
 }
 
 // MockStorage 模拟存储实例
 type MockStorage struct {
-	SaveStockBasicFunc func(data []models.StockBasic) error
-	GetStockBasicFunc  func(limit int) ([]models.StockBasic, error)
+	SaveStockBasicFunc         func(data []models.StockBasic) error
+	SaveTradeCalendarFunc      func(data []models.TradeCal) error
+	GetStockBasicFunc          func(limit int) ([]models.StockBasic, error)
+	QueryStockBasicFunc        func(ctx context.Context, q storage.StockBasicQuery) ([]models.StockBasic, error)
+	CountStockBasicFunc        func(ctx context.Context, q storage.StockBasicQuery) (int64, error)
+	GetStockNamesFunc          func(ctx context.Context) (map[string]string, error)
+	GetMarketDataFunc          func(ctx context.Context, symbol string, limit int) ([]models.MarketData, error)
+	QueryMarketDataFunc        func(ctx context.Context, q storage.MarketDataQuery) ([]models.MarketData, error)
+	CountMarketDataFunc        func(ctx context.Context, q storage.MarketDataQuery) (int64, error)
+	GetHistoricalDataFunc      func(symbol string, startTime, endTime string, limit, offset int) (*models.HistoricalDataResult, error)
+	GetAllStockCodesFunc       func() ([]string, error)
+	GetSourceFreshnessFunc     func(ctx context.Context) (map[string]time.Time, error)
+	GetCrossSourcePricesFunc   func(ctx context.Context, symbol string, at time.Time, tolerance time.Duration) (map[string]float64, error)
+	GetVWAPFunc                func(ctx context.Context, symbol string, start, end time.Time) (float64, error)
+	GetReturnCorrelationFunc   func(ctx context.Context, symbolA, symbolB string, start, end time.Time) (float64, int, error)
+	GetVolumeProfileFunc       func(ctx context.Context, symbol string, start, end time.Time, buckets int) ([]models.PriceVolumeBucket, error)
+	SaveMarketDataFunc         func(data []models.MarketData) error
+	SaveBacktestDataBatchFunc  func(data []models.BacktestData) error
+	GetRollingHighLowFunc      func(ctx context.Context, tsCode string, window int, asOf string) (float64, float64, error)
+	GetStoredTradeDatesFunc    func(ctx context.Context, tsCode string) ([]string, error)
+	FindMissingDaysFunc        func(ctx context.Context, tsCode, exchange, start, end string) ([]string, error)
+	GetDownsampledFunc         func(ctx context.Context, symbol string, bucket time.Duration, start, end time.Time) ([]models.MarketData, error)
+	GetOpenTradeDatesFunc      func(ctx context.Context, exchange, start, end string) ([]string, error)
+	GetSplitAdjustedDailyFunc  func(ctx context.Context, tsCode, start, end string) ([]models.Daily, error)
+	GetBacktestDataByIDsFunc   func(ctx context.Context, ids []string) ([]models.BacktestData, error)
+	GetBacktestDataFunc        func(symbol, strategy string, limit, offset int) ([]models.BacktestData, error)
+	CountBacktestDataFunc      func(symbol, strategy string) (int64, error)
+	UpsertBacktestFunc         func(ctx context.Context, data models.BacktestData) (models.BacktestData, error)
+	RecomputeDailyChangesFunc  func(ctx context.Context, tsCode string) (int64, error)
+	GetDailyColumnsFunc        func(ctx context.Context, tsCodes []string, cols []string, start, end string) (*models.DailyColumns, error)
+	GetMoneyflowFunc           func(ctx context.Context, tsCode, start, end string) ([]models.Moneyflow, error)
+	GetStkManagersFilteredFunc func(ctx context.Context, tsCode string, startAnnDate, endAnnDate string, limit, offset int) ([]models.StkManagers, error)
+
+	mutex            sync.Mutex
+	backfillProgress map[string]models.BackfillProgress
 }
 
 // SaveStockBasic 模拟保存股票基础信息
@@ -77,26 +146,328 @@ func (m *MockStorage) GetStockBasic(limit int) ([]models.StockBasic, error) {
 	return nil, nil
 }
 
+// QueryStockBasic 模拟按条件获取股票基础信息
+func (m *MockStorage) QueryStockBasic(ctx context.Context, q storage.StockBasicQuery) ([]models.StockBasic, error) {
+	if m.QueryStockBasicFunc != nil {
+		return m.QueryStockBasicFunc(ctx, q)
+	}
+	return nil, nil
+}
+
+// CountStockBasic 模拟统计符合条件的股票数
+func (m *MockStorage) CountStockBasic(ctx context.Context, q storage.StockBasicQuery) (int64, error) {
+	if m.CountStockBasicFunc != nil {
+		return m.CountStockBasicFunc(ctx, q)
+	}
+	return 0, nil
+}
+
 // SaveMarketData 模拟保存市场数据
 func (m *MockStorage) SaveMarketData(data []models.MarketData) error {
+	if m.SaveMarketDataFunc != nil {
+		return m.SaveMarketDataFunc(data)
+	}
 	return nil
 }
 
+// GetStockNames 模拟获取股票名称映射
+func (m *MockStorage) GetStockNames(ctx context.Context) (map[string]string, error) {
+	if m.GetStockNamesFunc != nil {
+		return m.GetStockNamesFunc(ctx)
+	}
+	return map[string]string{}, nil
+}
+
 // SaveBacktestData 模拟保存回测数据
 func (m *MockStorage) SaveBacktestData(data models.BacktestData) error {
 	return nil
 }
 
+// SaveBacktestDataBatch 模拟批量保存回测数据
+func (m *MockStorage) SaveBacktestDataBatch(data []models.BacktestData) error {
+	if m.SaveBacktestDataBatchFunc != nil {
+		return m.SaveBacktestDataBatchFunc(data)
+	}
+	return nil
+}
+
+// UpsertBacktestDataReturning 模拟插入或更新回测数据
+func (m *MockStorage) UpsertBacktestDataReturning(ctx context.Context, data models.BacktestData) (models.BacktestData, error) {
+	if m.UpsertBacktestFunc != nil {
+		return m.UpsertBacktestFunc(ctx, data)
+	}
+	return data, nil
+}
+
 // GetMarketData 模拟获取市场数据
-func (m *MockStorage) GetMarketData(symbol string, limit int) ([]models.MarketData, error) {
+func (m *MockStorage) GetMarketData(ctx context.Context, symbol string, limit int) ([]models.MarketData, error) {
+	if m.GetMarketDataFunc != nil {
+		return m.GetMarketDataFunc(ctx, symbol, limit)
+	}
+	return nil, nil
+}
+
+// QueryMarketData 模拟按查询选项获取市场数据
+func (m *MockStorage) QueryMarketData(ctx context.Context, q storage.MarketDataQuery) ([]models.MarketData, error) {
+	if m.QueryMarketDataFunc != nil {
+		return m.QueryMarketDataFunc(ctx, q)
+	}
 	return nil, nil
 }
 
+// CountMarketData 模拟统计符合条件的市场数据条数
+func (m *MockStorage) CountMarketData(ctx context.Context, q storage.MarketDataQuery) (int64, error) {
+	if m.CountMarketDataFunc != nil {
+		return m.CountMarketDataFunc(ctx, q)
+	}
+	return 0, nil
+}
+
 // GetHistoricalData 模拟获取历史数据
-func (m *MockStorage) GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error) {
+func (m *MockStorage) GetHistoricalData(symbol string, startTime, endTime string, limit, offset int) (*models.HistoricalDataResult, error) {
+	if m.GetHistoricalDataFunc != nil {
+		return m.GetHistoricalDataFunc(symbol, startTime, endTime, limit, offset)
+	}
+	return &models.HistoricalDataResult{}, nil
+}
+
+// SaveOHLCVDailyQFQ 模拟保存前复权日线行情
+func (m *MockStorage) SaveOHLCVDailyQFQ(data []models.OHLCVDailyQFQ) error {
+	return nil
+}
+
+// GetOHLCVCountBySymbol 模拟获取OHLCV记录数
+func (m *MockStorage) GetOHLCVCountBySymbol(tsCode string) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockStorage) GetDailyColumns(ctx context.Context, tsCodes []string, cols []string, start, end string) (*models.DailyColumns, error) {
+	if m.GetDailyColumnsFunc != nil {
+		return m.GetDailyColumnsFunc(ctx, tsCodes, cols, start, end)
+	}
+	return &models.DailyColumns{}, nil
+}
+
+func (m *MockStorage) SaveMoneyflow(data []models.Moneyflow) error {
+	return nil
+}
+
+func (m *MockStorage) GetMoneyflow(ctx context.Context, tsCode, start, end string) ([]models.Moneyflow, error) {
+	if m.GetMoneyflowFunc != nil {
+		return m.GetMoneyflowFunc(ctx, tsCode, start, end)
+	}
+	return []models.Moneyflow{}, nil
+}
+
+// GetStkManagersFiltered 模拟分页获取上市公司管理层
+func (m *MockStorage) GetStkManagersFiltered(ctx context.Context, tsCode string, startAnnDate, endAnnDate string, limit, offset int) ([]models.StkManagers, error) {
+	if m.GetStkManagersFilteredFunc != nil {
+		return m.GetStkManagersFilteredFunc(ctx, tsCode, startAnnDate, endAnnDate, limit, offset)
+	}
+	return []models.StkManagers{}, nil
+}
+
+// GetExistingDateRangeForSymbol 模拟获取已存在的日期范围
+func (m *MockStorage) GetExistingDateRangeForSymbol(tsCode string) (string, string, error) {
+	return "", "", nil
+}
+
+// GetAllStockCodes 模拟获取所有股票代码
+func (m *MockStorage) GetAllStockCodes() ([]string, error) {
+	if m.GetAllStockCodesFunc != nil {
+		return m.GetAllStockCodesFunc()
+	}
+	return nil, nil
+}
+
+// GetStockListDate 模拟获取股票上市日期
+func (m *MockStorage) GetStockListDate(symbol string) (string, error) {
+	return "", nil
+}
+
+// SaveTradeCalendar 模拟保存交易日历
+func (m *MockStorage) SaveTradeCalendar(data []models.TradeCal) error {
+	if m.SaveTradeCalendarFunc != nil {
+		return m.SaveTradeCalendarFunc(data)
+	}
+	return nil
+}
+
+// SaveTradeCal 模拟保存按交易所区分的交易日历
+func (m *MockStorage) SaveTradeCal(data []models.TradeCal) error {
+	return nil
+}
+
+// GetTradeCal 模拟获取交易日历
+func (m *MockStorage) GetTradeCal(exchange string, startDate, endDate string) ([]models.TradeCal, error) {
+	return []models.TradeCal{}, nil
+}
+
+// UpsertTradeCal 模拟保存或更新交易日历
+func (m *MockStorage) UpsertTradeCal(exchange, calDate, isOpen, preTradeDate string) error {
+	return nil
+}
+
+// SaveBackfillProgress 模拟保存回补任务进度
+func (m *MockStorage) SaveBackfillProgress(progress models.BackfillProgress) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.backfillProgress == nil {
+		m.backfillProgress = make(map[string]models.BackfillProgress)
+	}
+	m.backfillProgress[progress.ID] = progress
+	return nil
+}
+
+// GetBackfillProgress 模拟获取回补任务进度
+func (m *MockStorage) GetBackfillProgress(id string) (*models.BackfillProgress, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if p, ok := m.backfillProgress[id]; ok {
+		return &p, nil
+	}
+	return nil, nil
+}
+
+// GetSourceFreshness 模拟获取数据源新鲜度
+func (m *MockStorage) GetSourceFreshness(ctx context.Context) (map[string]time.Time, error) {
+	if m.GetSourceFreshnessFunc != nil {
+		return m.GetSourceFreshnessFunc(ctx)
+	}
+	return map[string]time.Time{}, nil
+}
+
+// GetCrossSourcePrices 模拟获取各数据源最近价格
+func (m *MockStorage) GetCrossSourcePrices(ctx context.Context, symbol string, at time.Time, tolerance time.Duration) (map[string]float64, error) {
+	if m.GetCrossSourcePricesFunc != nil {
+		return m.GetCrossSourcePricesFunc(ctx, symbol, at, tolerance)
+	}
+	return map[string]float64{}, nil
+}
+
+// GetOHLCV 模拟聚合K线
+func (m *MockStorage) GetOHLCV(symbol string, interval time.Duration, startTime, endTime time.Time) ([]models.Candle, error) {
+	return []models.Candle{}, nil
+}
+
+// GetReturnCorrelation 模拟计算收益率相关系数
+func (m *MockStorage) GetReturnCorrelation(ctx context.Context, symbolA, symbolB string, start, end time.Time) (float64, int, error) {
+	if m.GetReturnCorrelationFunc != nil {
+		return m.GetReturnCorrelationFunc(ctx, symbolA, symbolB, start, end)
+	}
+	return 0, 0, storage.ErrInsufficientOverlap
+}
+
+// GetVolumeProfile 模拟统计成交量分布
+func (m *MockStorage) GetVolumeProfile(ctx context.Context, symbol string, start, end time.Time, buckets int) ([]models.PriceVolumeBucket, error) {
+	if m.GetVolumeProfileFunc != nil {
+		return m.GetVolumeProfileFunc(ctx, symbol, start, end, buckets)
+	}
+	return []models.PriceVolumeBucket{}, nil
+}
+
+// GetVWAP 模拟计算成交量加权平均价
+func (m *MockStorage) GetVWAP(ctx context.Context, symbol string, start, end time.Time) (float64, error) {
+	if m.GetVWAPFunc != nil {
+		return m.GetVWAPFunc(ctx, symbol, start, end)
+	}
+	return 0, storage.ErrZeroVolume
+}
+
+// GetDownsampledMarketData 模拟降采样查询市场数据
+func (m *MockStorage) GetDownsampledMarketData(ctx context.Context, symbol string, bucket time.Duration, start, end time.Time) ([]models.MarketData, error) {
+	if m.GetDownsampledFunc != nil {
+		return m.GetDownsampledFunc(ctx, symbol, bucket, start, end)
+	}
+	return nil, nil
+}
+
+// GetOpenTradeDates 模拟获取交易日列表
+func (m *MockStorage) GetOpenTradeDates(ctx context.Context, exchange, start, end string) ([]string, error) {
+	if m.GetOpenTradeDatesFunc != nil {
+		return m.GetOpenTradeDatesFunc(ctx, exchange, start, end)
+	}
+	return []string{}, nil
+}
+
+// SaveAdjFactors 模拟保存复权因子
+func (m *MockStorage) SaveAdjFactors(data []models.AdjFactor) error {
+	return nil
+}
+
+// SaveDaily 模拟保存A股日线行情
+func (m *MockStorage) SaveDaily(data []models.Daily) error {
+	return nil
+}
+
+// GetDaily 模拟获取A股日线行情
+func (m *MockStorage) GetDaily(tsCode, startDate, endDate string) ([]models.Daily, error) {
+	return []models.Daily{}, nil
+}
+
+// FindMissingTradingDays 模拟获取缺失日线的交易日
+func (m *MockStorage) FindMissingTradingDays(ctx context.Context, tsCode, exchange, start, end string) ([]string, error) {
+	if m.FindMissingDaysFunc != nil {
+		return m.FindMissingDaysFunc(ctx, tsCode, exchange, start, end)
+	}
+	return []string{}, nil
+}
+
+// GetStoredTradeDates 模拟获取已保存日线的交易日
+func (m *MockStorage) GetStoredTradeDates(ctx context.Context, tsCode string) ([]string, error) {
+	if m.GetStoredTradeDatesFunc != nil {
+		return m.GetStoredTradeDatesFunc(ctx, tsCode)
+	}
+	return []string{}, nil
+}
+
+// GetRollingHighLow 模拟获取N日最高最低价
+func (m *MockStorage) GetRollingHighLow(ctx context.Context, tsCode string, window int, asOf string) (float64, float64, error) {
+	if m.GetRollingHighLowFunc != nil {
+		return m.GetRollingHighLowFunc(ctx, tsCode, window, asOf)
+	}
+	return 0, 0, storage.ErrNoDailyData
+}
+
+// GetSplitAdjustedDaily 模拟获取复权调整后的日线行情
+func (m *MockStorage) GetSplitAdjustedDaily(ctx context.Context, tsCode, start, end string) ([]models.Daily, error) {
+	if m.GetSplitAdjustedDailyFunc != nil {
+		return m.GetSplitAdjustedDailyFunc(ctx, tsCode, start, end)
+	}
+	return []models.Daily{}, nil
+}
+
+func (m *MockStorage) RecomputeDailyChanges(ctx context.Context, tsCode string) (int64, error) {
+	if m.RecomputeDailyChangesFunc != nil {
+		return m.RecomputeDailyChangesFunc(ctx, tsCode)
+	}
+	return 0, nil
+}
+
+// GetBacktestDataByIDs 模拟按ID批量获取回测数据
+func (m *MockStorage) GetBacktestDataByIDs(ctx context.Context, ids []string) ([]models.BacktestData, error) {
+	if m.GetBacktestDataByIDsFunc != nil {
+		return m.GetBacktestDataByIDsFunc(ctx, ids)
+	}
 	return nil, nil
 }
 
+// GetBacktestData 模拟获取交易对的回测数据
+func (m *MockStorage) GetBacktestData(symbol, strategy string, limit, offset int) ([]models.BacktestData, error) {
+	if m.GetBacktestDataFunc != nil {
+		return m.GetBacktestDataFunc(symbol, strategy, limit, offset)
+	}
+	return nil, nil
+}
+
+// CountBacktestData 模拟统计交易对的回测数据条数
+func (m *MockStorage) CountBacktestData(symbol, strategy string) (int64, error) {
+	if m.CountBacktestDataFunc != nil {
+		return m.CountBacktestDataFunc(symbol, strategy)
+	}
+	return 0, nil
+}
+
 // Close 模拟关闭存储
 func (m *MockStorage) Close() {
 }
@@ -113,7 +484,7 @@ func TestServer_HealthCheck(t *testing.T) {
 	// 创建测试请求
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodGet, "/api/health", nil)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/health", nil)
 
 	// 调用健康检查方法
 	server.healthCheck(c)
@@ -135,52 +506,336 @@ func TestServer_GetBacktestData(t *testing.T) {
 	// 测试缺少symbol参数
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodGet, "/api/backtest/data", nil)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/data", nil)
 	server.getBacktestData(c)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "Symbol is required")
 
-	// 测试有symbol参数
+	// 没有回测数据时返回空列表
 	w = httptest.NewRecorder()
 	c, _ = gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodGet, "/api/backtest/data?symbol=BTCUSDT", nil)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/data?symbol=BTCUSDT", nil)
 	server.getBacktestData(c)
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Backtest data retrieved successfully")
+	assert.Contains(t, w.Body.String(), `"data":[]`)
+
+	// 返回存储中的回测数据，传入策略和条数
+	ts := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	stored := []models.BacktestData{
+		{ID: "bt-2", Symbol: "BTCUSDT", Strategy: "MA Cross", StartDate: ts.AddDate(-1, 0, 0), EndDate: ts, Results: `{"profit":12.5}`, Timestamp: ts.Add(time.Hour)},
+		{ID: "bt-1", Symbol: "BTCUSDT", Strategy: "MA Cross", StartDate: ts.AddDate(-1, 0, 0), EndDate: ts, Results: `{"profit":8}`, Timestamp: ts},
+	}
+	var gotSymbol, gotStrategy string
+	var gotLimit, gotOffset int
+	mockStorage.GetBacktestDataFunc = func(symbol, strategy string, limit, offset int) ([]models.BacktestData, error) {
+		gotSymbol, gotStrategy, gotLimit, gotOffset = symbol, strategy, limit, offset
+		return stored, nil
+	}
+	mockStorage.CountBacktestDataFunc = func(symbol, strategy string) (int64, error) {
+		return 25, nil
+	}
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/data?symbol=BTCUSDT&strategy=MA+Cross&limit=10", nil)
+	server.getBacktestData(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "BTCUSDT", gotSymbol)
+	assert.Equal(t, "MA Cross", gotStrategy)
+	assert.Equal(t, 10, gotLimit)
+	assert.Equal(t, 0, gotOffset)
+	var resp struct {
+		Data []models.BacktestData `json:"data"`
+		Meta models.PageMeta       `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(25), resp.Meta.Total)
+	assert.Equal(t, 10, resp.Meta.Limit)
+	assert.True(t, resp.Meta.HasMore)
+	if assert.Len(t, resp.Data, 2) {
+		assert.Equal(t, "bt-2", resp.Data[0].ID)
+		assert.Equal(t, `{"profit":12.5}`, resp.Data[0].Results)
+		assert.True(t, ts.Add(time.Hour).Equal(resp.Data[0].Timestamp))
+		assert.Equal(t, "bt-1", resp.Data[1].ID)
+	}
+
+	// 条数使用默认值并限制最大值
+	for query, want := range map[string]int{"": DefaultBacktestDataLimit, "&limit=100000": MaxBacktestDataLimit} {
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/data?symbol=BTCUSDT"+query, nil)
+		server.getBacktestData(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, want, gotLimit)
+		assert.Empty(t, gotStrategy)
+	}
+
+	// 按游标取下一页，最后一页没有更多数据
+	var next struct {
+		Meta models.PageMeta `json:"meta"`
+	}
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/data?symbol=BTCUSDT&limit=10&cursor="+resp.Meta.NextCursor, nil)
+	server.getBacktestData(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 10, gotOffset)
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/data?symbol=BTCUSDT&limit=10&offset=20", nil)
+	server.getBacktestData(c)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &next))
+	assert.False(t, next.Meta.HasMore)
+	assert.Empty(t, next.Meta.NextCursor)
+
+	// 非法的条数和偏移量
+	for _, query := range []string{"&limit=-1", "&limit=abc", "&offset=abc"} {
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/data?symbol=BTCUSDT"+query, nil)
+		server.getBacktestData(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// 存储错误
+	mockStorage.GetBacktestDataFunc = func(string, string, int, int) ([]models.BacktestData, error) {
+		return nil, fmt.Errorf("connection refused")
+	}
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/data?symbol=BTCUSDT", nil)
+	server.getBacktestData(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
 // TestServer_GetMarketData 测试获取市场数据接口
 func TestServer_GetMarketData(t *testing.T) {
-	// 创建模拟的 Tushare 客户端和存储实例
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stored := []models.MarketData{
+		{ID: "md-3", Symbol: "BTCUSDT", Price: 42100, Volume: 3, Timestamp: ts.Add(2 * time.Minute), Source: models.SourceBinance},
+		{ID: "md-2", Symbol: "BTCUSDT", Price: 42050, Volume: 2, Timestamp: ts.Add(time.Minute), Source: models.SourceBinance},
+		{ID: "md-1", Symbol: "BTCUSDT", Price: 42000, Volume: 1, Timestamp: ts, Source: models.SourceBinance},
+	}
+	var lastLimit, lastOffset int
 	mockTushareClient := &MockTushareClient{}
-	mockStorage := &MockStorage{}
+	mockStorage := &MockStorage{
+		QueryMarketDataFunc: func(ctx context.Context, q storage.MarketDataQuery) ([]models.MarketData, error) {
+			lastLimit, lastOffset = q.Limit, q.Offset
+			if q.Symbol != "BTCUSDT" || q.Offset >= len(stored) {
+				return nil, nil
+			}
+			return stored[q.Offset:min(q.Offset+q.Limit, len(stored))], nil
+		},
+		CountMarketDataFunc: func(ctx context.Context, q storage.MarketDataQuery) (int64, error) {
+			if q.Symbol != "BTCUSDT" {
+				return 0, nil
+			}
+			return int64(len(stored)), nil
+		},
+	}
 
 	// 创建API服务器
-	server := NewServer(mockTushareClient, mockStorage)
+	server := NewServer(mockTushareClient, mockStorage, WithMarketDataMaxLimit(500))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/market/data"+query, nil)
+		server.getMarketData(c)
+		return w
+	}
+	decodeMeta := func(w *httptest.ResponseRecorder) models.PageMeta {
+		var resp struct {
+			Meta models.PageMeta `json:"meta"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Meta
+	}
+	decode := func(w *httptest.ResponseRecorder) []models.MarketData {
+		var resp struct {
+			Data []models.MarketData `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
 
 	// 测试缺少symbol参数
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodGet, "/api/market/data", nil)
-	server.getMarketData(c)
+	w := get("")
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "Symbol is required")
 
-	// 测试有symbol参数
-	w = httptest.NewRecorder()
-	c, _ = gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodGet, "/api/market/data?symbol=BTCUSDT", nil)
-	server.getMarketData(c)
+	// 测试返回已保存的数据，默认limit为10
+	w = get("?symbol=BTCUSDT")
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Market data retrieved successfully")
+	assert.Equal(t, DefaultMarketDataLimit, lastLimit)
+	assert.Equal(t, stored, decode(w))
+
+	assert.Equal(t, models.PageMeta{Limit: DefaultMarketDataLimit, Total: 3}, decodeMeta(w))
+
+	// 测试有symbol和limit参数，还有下一页时按next_cursor继续
+	w = get("?symbol=BTCUSDT&limit=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, stored[:2], decode(w))
+	meta := decodeMeta(w)
+	assert.True(t, meta.HasMore)
+	assert.Equal(t, int64(3), meta.Total)
+	w = get("?symbol=BTCUSDT&limit=2&cursor=" + meta.NextCursor)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, lastOffset)
+	assert.Equal(t, stored[2:], decode(w))
+	assert.False(t, decodeMeta(w).HasMore)
 
-	// 测试有symbol和limit参数
+	// 测试offset跳过最新的数据，偏移交给数据库处理
+	w = get("?symbol=BTCUSDT&limit=1&offset=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, lastLimit)
+	assert.Equal(t, 1, lastOffset)
+	assert.Equal(t, stored[1:2], decode(w))
+
+	// 测试很大的offset不会放大查询条数
+	w = get("?symbol=BTCUSDT&limit=10&offset=9223372036854775807")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 10, lastLimit)
+	assert.Contains(t, w.Body.String(), `"data":[]`)
+
+	// 测试limit超过上限时被截断
+	w = get("?symbol=BTCUSDT&limit=5000")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 500, lastLimit)
+
+	// 测试limit=0使用默认值，负数返回400
+	w = get("?symbol=BTCUSDT&limit=0")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DefaultMarketDataLimit, lastLimit)
+
+	lastLimit = 0
+	w = get("?symbol=BTCUSDT&limit=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "limit must be a non-negative integer")
+	assert.Equal(t, 0, lastLimit)
+
+	// 测试all=true取上限
+	w = get("?symbol=BTCUSDT&all=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 500, lastLimit)
+	assert.Equal(t, stored, decode(w))
+
+	// 测试没有数据时返回空数组而不是模拟数据
+	w = get("?symbol=ETHUSDT")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"data":[]`)
+
+	// 测试非法cursor
+	w = get("?symbol=BTCUSDT&cursor=bad")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 测试存储错误
+	server = NewServer(mockTushareClient, &MockStorage{
+		QueryMarketDataFunc: func(ctx context.Context, q storage.MarketDataQuery) ([]models.MarketData, error) {
+			return nil, fmt.Errorf("connection refused")
+		},
+	})
+	w = get("?symbol=BTCUSDT")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	server = NewServer(mockTushareClient, &MockStorage{
+		CountMarketDataFunc: func(ctx context.Context, q storage.MarketDataQuery) (int64, error) {
+			return 0, fmt.Errorf("connection refused")
+		},
+	})
+	w = get("?symbol=BTCUSDT")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// TestServer_GetHistoricalData 测试获取历史数据接口
+func TestServer_GetHistoricalData(t *testing.T) {
+	nextStart := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	mockTushareClient := &MockTushareClient{}
+	mockStorage := &MockStorage{
+		GetHistoricalDataFunc: func(symbol string, startTime, endTime string, limit, offset int) (*models.HistoricalDataResult, error) {
+			assert.Equal(t, "BTCUSDT", symbol)
+			assert.Equal(t, "2024-01-01T00:00:00Z", startTime)
+			nextOffset := offset + 2
+			return &models.HistoricalDataResult{
+				Data: []models.MarketData{
+					{ID: "1", Symbol: "BTCUSDT", Price: 100, Volume: 1, Timestamp: nextStart.Add(-2 * time.Hour), Source: "binance"},
+					{ID: "2", Symbol: "BTCUSDT", Price: 101, Volume: 1, Timestamp: nextStart.Add(-time.Hour), Source: "binance"},
+				},
+				HasMore:    true,
+				NextOffset: &nextOffset,
+				Truncated:  true,
+				NextStart:  &nextStart,
+			}, nil
+		},
+	}
+
+	server := NewServer(mockTushareClient, mockStorage)
+
+	// 测试缺少时间参数
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/market/history?symbol=BTCUSDT", nil)
+	server.getHistoricalData(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid start_time format")
+
+	// 测试结果被截断
 	w = httptest.NewRecorder()
 	c, _ = gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodGet, "/api/market/data?symbol=BTCUSDT&limit=5", nil)
-	server.getMarketData(c)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/market/history?symbol=BTCUSDT&start_time=2024-01-01T00:00:00Z&end_time=2024-01-02T00:00:00Z", nil)
+	server.getHistoricalData(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"truncated":true`)
+	assert.Contains(t, w.Body.String(), `"next_start":"2024-01-01T02:00:00Z"`)
+	assert.Contains(t, w.Body.String(), `"has_more":true`)
+	assert.Contains(t, w.Body.String(), `"next_offset":2`)
+}
+
+// TestServer_GetHistoricalDataPaging 测试历史数据的分页参数传递、非法参数和超出最大时间跨度
+func TestServer_GetHistoricalDataPaging(t *testing.T) {
+	var gotLimit, gotOffset int
+	mockStorage := &MockStorage{
+		GetHistoricalDataFunc: func(symbol string, startTime, endTime string, limit, offset int) (*models.HistoricalDataResult, error) {
+			gotLimit, gotOffset = limit, offset
+			return &models.HistoricalDataResult{Data: []models.MarketData{}}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage, WithMaxHistoricalWindow(7*24*time.Hour))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/market/history?symbol=BTCUSDT&"+query, nil)
+		server.getHistoricalData(c)
+		return w
+	}
+
+	// limit和offset传给存储
+	w := get("start_time=2024-01-01T00:00:00Z&end_time=2024-01-02T00:00:00Z&limit=50&offset=100")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 50, gotLimit)
+	assert.Equal(t, 100, gotOffset)
+	assert.Contains(t, w.Body.String(), `"has_more":false`)
+
+	// 非法的分页参数
+	w = get("start_time=2024-01-01T00:00:00Z&end_time=2024-01-02T00:00:00Z&limit=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "limit must be a non-negative integer")
+	w = get("start_time=2024-01-01T00:00:00Z&end_time=2024-01-02T00:00:00Z&offset=abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "offset must be a non-negative integer")
+
+	// 超出最大时间跨度，不查询存储
+	gotLimit = -1
+	w = get("start_time=2024-01-01T00:00:00Z&end_time=2024-01-09T00:00:00Z")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Time range exceeds the maximum of 7 days")
+	assert.Equal(t, -1, gotLimit)
+
+	// 不限制时间跨度
+	server = NewServer(&MockTushareClient{}, mockStorage, WithMaxHistoricalWindow(0))
+	w = get("start_time=2020-01-01T00:00:00Z&end_time=2024-01-01T00:00:00Z")
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Contains(t, w.Body.String(), "Market data retrieved successfully")
 }
 
 // TestServer_GetParquetData 测试获取Parquet数据接口
@@ -195,7 +850,7 @@ func TestServer_GetParquetData(t *testing.T) {
 	// 测试缺少symbol参数
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodGet, "/api/backtest/parquet", nil)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/parquet", nil)
 	server.getParquetData(c)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "Symbol is required")
@@ -203,24 +858,80 @@ func TestServer_GetParquetData(t *testing.T) {
 	// 测试日期范围无效
 	w = httptest.NewRecorder()
 	c, _ = gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodGet, "/api/backtest/parquet?symbol=BTCUSDT&start_date=2023-01-01&end_date=2022-01-01", nil)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/parquet?symbol=BTCUSDT&start_date=2023-01-01&end_date=2022-01-01", nil)
 	server.getParquetData(c)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "end_date must be after start_date")
 }
 
-// TestServer_FetchStockList 测试获取股票列表接口
-func TestServer_FetchStockList(t *testing.T) {
-	// 创建模拟的 Tushare 客户端和存储实例
-	mockTushareClient := &MockTushareClient{
-		GetStockBasicFunc: func(req *datasource.StockBasicRequest, fields []string) (*datasource.TushareResponse, error) {
-			// 返回模拟的响应数据
-			return &datasource.TushareResponse{
-				Code:    0,
-				Message: "success",
-				Data: &datasource.DataResult{
-					Fields: []string{"ts_code", "symbol", "name", "area", "industry", "fullname", "enname", "cnspell", "market", "exchange", "curr_type", "list_status", "list_date", "delist_date", "is_hs", "act_name", "act_ent_type"},
-					Items: [][]interface{}{
+// TestServer_GetParquetDataFile 测试返回的Parquet文件包含存储中的全部数据，结束日期包含当天
+func TestServer_GetParquetDataFile(t *testing.T) {
+	base := time.Date(2023, 1, 31, 12, 0, 0, 0, time.UTC)
+	stored := []models.MarketData{
+		{Symbol: "BTCUSDT", Price: 42000.5, Volume: 1.5, Timestamp: base},
+		{Symbol: "BTCUSDT", Price: 42010, Volume: 2, Timestamp: base.Add(time.Minute)},
+		{Symbol: "BTCUSDT", Price: 41990.25, Volume: 0.5, Timestamp: base.Add(2 * time.Minute)},
+	}
+	var gotStart, gotEnd string
+	hasMore := false
+	mockStorage := &MockStorage{
+		GetHistoricalDataFunc: func(symbol string, startTime, endTime string, limit, offset int) (*models.HistoricalDataResult, error) {
+			gotStart, gotEnd = startTime, endTime
+			return &models.HistoricalDataResult{Data: stored, HasMore: hasMore}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/backtest/parquet?symbol=BTCUSDT&start_date=2023-01-01&end_date=2023-01-31", nil)
+		server.getParquetData(c)
+		return w
+	}
+
+	w := get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/vnd.apache.parquet", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=BTCUSDT_20230101_20230131.parquet`, w.Header().Get("Content-Disposition"))
+	assert.Empty(t, w.Header().Get("X-Truncated"))
+	assert.Equal(t, "2023-01-01T00:00:00Z", gotStart)
+	assert.Equal(t, "2023-01-31T23:59:59.999Z", gotEnd)
+
+	data, err := parquet.ReadMarketData(w.Body.Bytes())
+	assert.NoError(t, err)
+	if assert.Len(t, data, len(stored)) {
+		assert.Equal(t, "BTCUSDT", data[0].Symbol)
+		assert.Equal(t, 41990.25, data[2].Price)
+		assert.True(t, stored[1].Timestamp.Equal(data[1].Timestamp))
+	}
+
+	// 超过最大行数时标记截断
+	hasMore = true
+	w = get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Truncated"))
+
+	// 存储错误返回500
+	mockStorage.GetHistoricalDataFunc = func(symbol string, startTime, endTime string, limit, offset int) (*models.HistoricalDataResult, error) {
+		return nil, fmt.Errorf("connection refused")
+	}
+	w = get()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// TestServer_FetchStockList 测试获取股票列表接口
+func TestServer_FetchStockList(t *testing.T) {
+	// 创建模拟的 Tushare 客户端和存储实例
+	mockTushareClient := &MockTushareClient{
+		GetStockBasicFunc: func(req *datasource.StockBasicRequest, fields []string) (*datasource.TushareResponse, error) {
+			// 返回模拟的响应数据
+			return &datasource.TushareResponse{
+				Code:    0,
+				Message: "success",
+				Data: &datasource.DataResult{
+					Fields: []string{"ts_code", "symbol", "name", "area", "industry", "fullname", "enname", "cnspell", "market", "exchange", "curr_type", "list_status", "list_date", "delist_date", "is_hs", "act_name", "act_ent_type"},
+					Items: [][]interface{}{
 						{"600000.SH", "600000", "浦发银行", "上海", "银行", "上海浦东发展银行股份有限公司", "Shanghai Pudong Development Bank Co., Ltd.", "pfyh", "SSE", "SSE", "CNY", "L", "19990114", "", "H", "上海市国有资产监督管理委员会", "地方国有企业"},
 						{"600001.SH", "600001", "邯郸钢铁", "河北", "钢铁", "河北钢铁股份有限公司", "Hebei Iron & Steel Co., Ltd.", "hdgt", "SSE", "SSE", "CNY", "L", "19961119", "", "", "河北省国有资产监督管理委员会", "地方国有企业"},
 					},
@@ -247,7 +958,7 @@ func TestServer_FetchStockList(t *testing.T) {
 	// 创建测试请求
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodPost, "/api/stock/fetch-list", nil)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/stock/fetch-list", nil)
 
 	// 调用获取股票列表方法
 	server.fetchStockList(c)
@@ -276,7 +987,7 @@ func TestServer_FetchStockList_Error(t *testing.T) {
 	// 创建测试请求
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodPost, "/api/stock/fetch-list", nil)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/stock/fetch-list", nil)
 
 	// 调用获取股票列表方法
 	server.fetchStockList(c)
@@ -318,7 +1029,7 @@ func TestServer_FetchStockList_StorageError(t *testing.T) {
 	// 创建测试请求
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Request, _ = http.NewRequest(http.MethodPost, "/api/stock/fetch-list", nil)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/stock/fetch-list", nil)
 
 	// 调用获取股票列表方法
 	server.fetchStockList(c)
@@ -327,3 +1038,1904 @@ func TestServer_FetchStockList_StorageError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "Storage error")
 }
+
+// TestServer_SyncTradeCalendar 测试同步交易日历时数字形式的is_open和pretrade_date都能正确保存
+func TestServer_SyncTradeCalendar(t *testing.T) {
+	mockTushareClient := &MockTushareClient{
+		GetTradeCalFunc: func(req *datasource.TradeCalRequest, fields []string) (*datasource.TushareResponse, error) {
+			assert.Contains(t, fields, "pretrade_date")
+			return &datasource.TushareResponse{
+				Data: &datasource.DataResult{
+					Fields: []string{"exchange", "cal_date", "is_open", "pretrade_date"},
+					Items: [][]interface{}{
+						{"SSE", "20240101", float64(0), "20231229"},
+						{"SSE", "20240102", float64(1), "20231229"},
+						{"SSE", "20240103", "1", "20240102"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	var saved []models.TradeCal
+	mockStorage := &MockStorage{
+		SaveTradeCalendarFunc: func(data []models.TradeCal) error {
+			saved = data
+			return nil
+		},
+	}
+
+	server := NewServer(mockTushareClient, mockStorage)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/sync/trade-calendar", nil)
+	server.syncTradeCalendar(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.TradeCal{
+		{Exchange: "SSE", CalDate: "20240101", IsOpen: "0", PreTradeDate: "20231229"},
+		{Exchange: "SSE", CalDate: "20240102", IsOpen: "1", PreTradeDate: "20231229"},
+		{Exchange: "SSE", CalDate: "20240103", IsOpen: "1", PreTradeDate: "20240102"},
+	}, saved)
+}
+
+// TestServer_GetStockList 测试读取已保存的股票列表接口
+func TestServer_GetStockList(t *testing.T) {
+	stocks := []models.StockBasic{
+		{TSCode: "000001.SZ", Symbol: "000001", Name: "平安银行", Market: "主板", Exchange: "SZSE", ListStatus: "L", ListDate: "19910403"},
+		{TSCode: "600000.SH", Symbol: "600000", Name: "浦发银行", Market: "主板", Exchange: "SSE", ListStatus: "L", ListDate: "19991110"},
+	}
+	var gotQuery, gotCount storage.StockBasicQuery
+	mockStorage := &MockStorage{
+		QueryStockBasicFunc: func(ctx context.Context, q storage.StockBasicQuery) ([]models.StockBasic, error) {
+			gotQuery = q
+			if q.Exchange == "SSE" {
+				return stocks[1:], nil
+			}
+			return stocks, nil
+		},
+		CountStockBasicFunc: func(ctx context.Context, q storage.StockBasicQuery) (int64, error) {
+			gotCount = q
+			return 250, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/stock/list?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 默认条数，数据原样序列化，meta包含总数和下一页游标
+	w := request("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, storage.StockBasicQuery{Limit: DefaultStockListLimit}, gotQuery)
+	var resp struct {
+		Success bool                `json:"success"`
+		Data    []models.StockBasic `json:"data"`
+		Meta    models.PageMeta     `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Success)
+	assert.Equal(t, stocks, resp.Data)
+	assert.Equal(t, int64(250), resp.Meta.Total)
+	assert.True(t, resp.Meta.HasMore)
+	assert.Contains(t, w.Body.String(), `"ts_code":"000001.SZ"`)
+	assert.Contains(t, w.Body.String(), `"name":"平安银行"`)
+	assert.Contains(t, w.Body.String(), `"list_status":"L"`)
+
+	// 按游标取下一页
+	assert.Equal(t, http.StatusOK, request("cursor="+resp.Meta.NextCursor).Code)
+	assert.Equal(t, DefaultStockListLimit, gotQuery.Offset)
+
+	// limit超过上限时截断
+	assert.Equal(t, http.StatusOK, request("limit=100000").Code)
+	assert.Equal(t, MaxStockListLimit, gotQuery.Limit)
+
+	// 过滤条件和分页参数传给存储查询，总数使用相同的过滤条件
+	w = request("limit=10&offset=20&exchange=SSE&market=%E4%B8%BB%E6%9D%BF&list_status=L")
+	assert.Equal(t, http.StatusOK, w.Code)
+	want := storage.StockBasicQuery{Market: "主板", Exchange: "SSE", ListStatus: "L", Limit: 10, Offset: 20}
+	assert.Equal(t, want, gotQuery)
+	assert.Equal(t, want, gotCount)
+	assert.Contains(t, w.Body.String(), `"ts_code":"600000.SH"`)
+	assert.NotContains(t, w.Body.String(), "000001.SZ")
+
+	// 非法limit和offset
+	for _, query := range []string{"limit=-5", "limit=abc", "offset=abc"} {
+		assert.Equal(t, http.StatusBadRequest, request(query).Code, query)
+	}
+
+	// 没有数据时返回空数组
+	mockStorage.QueryStockBasicFunc = nil
+	w = request("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"data":[]`)
+
+	// 存储错误
+	mockStorage.QueryStockBasicFunc = func(ctx context.Context, q storage.StockBasicQuery) ([]models.StockBasic, error) {
+		return nil, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, request("").Code)
+	mockStorage.CountStockBasicFunc = func(ctx context.Context, q storage.StockBasicQuery) (int64, error) {
+		return 0, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, request("").Code)
+}
+
+// TestServer_Backfill 测试启动回补任务并查询进度
+func TestServer_Backfill(t *testing.T) {
+	mockTushareClient := &MockTushareClient{
+		GetDailyFunc: func(req *datasource.DailyRequest, fields []string) (*datasource.TushareResponse, error) {
+			return &datasource.TushareResponse{
+				Data: &datasource.DataResult{
+					Fields: []string{"ts_code", "trade_date", "close"},
+					Items:  [][]interface{}{{req.TSCode, req.StartDate, 10.0}},
+				},
+			}, nil
+		},
+	}
+	mockStorage := &MockStorage{
+		GetAllStockCodesFunc: func() ([]string, error) {
+			return []string{"000001.SZ", "600000.SH"}, nil
+		},
+	}
+
+	server := NewServer(mockTushareClient, mockStorage)
+	server.backfill = backfill.NewManager(mockTushareClient, mockStorage, 0)
+
+	// 测试日期格式错误
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/admin/backfill", strings.NewReader(`{"start_date":"20240101","end_date":"2024-01-31"}`))
+	server.startBackfill(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 启动任务
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/admin/backfill", strings.NewReader(`{"start_date":"2024-01-01","end_date":"2024-01-31"}`))
+	server.startBackfill(c)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var resp struct {
+		Data models.BackfillProgress `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Data.Total)
+
+	// 轮询进度直到完成
+	var progress models.BackfillProgress
+	assert.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: resp.Data.ID}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/admin/backfill/"+resp.Data.ID, nil)
+		server.getBackfillProgress(c)
+		var body struct {
+			Data models.BackfillProgress `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		progress = body.Data
+		return progress.Status == backfill.StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, progress.Done)
+	assert.Equal(t, "600000.SH", progress.LastCode)
+
+	// 查询不存在的任务
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/admin/backfill/missing", nil)
+	server.getBackfillProgress(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestServer_BackfillMaxActive 测试运行中的回补任务达到上限时返回429，并在运行中任务列表中列出
+func TestServer_BackfillMaxActive(t *testing.T) {
+	mockStorage := &MockStorage{
+		GetAllStockCodesFunc: func() ([]string, error) {
+			return []string{"000001.SZ"}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage, WithAdminToken("secret"))
+	// 速率间隔很长，任务一直运行直到被取消
+	server.backfill = backfill.NewManager(&MockTushareClient{}, mockStorage, time.Hour)
+	server.backfill.SetMaxActive(1)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 回补和运行中任务接口需要管理令牌
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/admin/backfill"},
+		{http.MethodGet, "/api/v1/admin/backfill/any"},
+		{http.MethodPost, "/api/v1/admin/backfill/any/cancel"},
+		{http.MethodGet, "/api/v1/admin/jobs/active"},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(route.method, route.path, strings.NewReader(`{"start_date":"2024-01-01","end_date":"2024-01-31"}`))
+		req.Header.Set("Content-Type", "application/json")
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, route.path)
+	}
+	assert.Empty(t, server.backfill.Active())
+
+	w := request(http.MethodPost, "/api/v1/admin/backfill", `{"start_date":"2024-01-01","end_date":"2024-01-31"}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var started struct {
+		Data models.BackfillProgress `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+
+	w = request(http.MethodPost, "/api/v1/admin/backfill", `{"start_date":"2024-02-01","end_date":"2024-02-29"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), CodeTooManyJobs)
+
+	w = request(http.MethodGet, "/api/v1/admin/jobs/active", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var active struct {
+		Data []models.BackfillProgress `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &active))
+	if assert.Len(t, active.Data, 1) {
+		assert.Equal(t, started.Data.ID, active.Data[0].ID)
+	}
+
+	// 取消后任务从列表中移除，可以启动新任务
+	w = request(http.MethodPost, "/api/v1/admin/backfill/"+started.Data.ID+"/cancel", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Eventually(t, func() bool { return len(server.backfill.Active()) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	w = request(http.MethodPost, "/api/v1/admin/backfill", `{"start_date":"2024-02-01","end_date":"2024-02-29"}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	for _, job := range server.backfill.Active() {
+		assert.NoError(t, server.backfill.Cancel(job.ID))
+	}
+}
+
+// TestServer_GetDataSourceSymbols 测试获取数据源支持的交易对
+func TestServer_GetDataSourceSymbols(t *testing.T) {
+	get := func(server *Server, name string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/datasource/"+name+"/symbols", nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未配置数据源
+	server := NewServer(&MockTushareClient{}, &MockStorage{})
+	assert.Equal(t, http.StatusServiceUnavailable, get(server, "binance").Code)
+
+	factory := datasource.NewDataSourceFactory()
+	factory.Register("binance", datasource.NewMockDataSource("binance"))
+	factory.Register("kraken", datasource.NewExchangeDataSource("kraken", "", "", "", nil))
+	server = NewServer(&MockTushareClient{}, &MockStorage{}, WithDataSources(factory))
+
+	w := get(server, "Binance")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []string `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, datasource.MockSupportedSymbols, resp.Data)
+
+	assert.Equal(t, http.StatusNotFound, get(server, "huobi").Code)
+	// 数据源获取失败
+	assert.Equal(t, http.StatusBadGateway, get(server, "kraken").Code)
+}
+
+// TestServer_GetSourceFreshness 测试数据源新鲜度接口标记停止更新的数据源
+func TestServer_GetSourceFreshness(t *testing.T) {
+	now := time.Now()
+	mockStorage := &MockStorage{
+		GetSourceFreshnessFunc: func(ctx context.Context) (map[string]time.Time, error) {
+			return map[string]time.Time{
+				"okx":     now.Add(-time.Hour),
+				"binance": now.Add(-10 * time.Second),
+			}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage, WithStalenessThreshold(time.Minute))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/datasource/freshness", nil)
+	server.getSourceFreshness(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data []models.SourceFreshness `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Data, 2) {
+		assert.Equal(t, "binance", resp.Data[0].Source)
+		assert.False(t, resp.Data[0].Stale)
+		assert.Equal(t, "okx", resp.Data[1].Source)
+		assert.True(t, resp.Data[1].Stale)
+		assert.InDelta(t, 3600, resp.Data[1].AgeSeconds, 5)
+	}
+
+	// 存储错误
+	mockStorage.GetSourceFreshnessFunc = func(ctx context.Context) (map[string]time.Time, error) {
+		return nil, fmt.Errorf("database error")
+	}
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/datasource/freshness", nil)
+	server.getSourceFreshness(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// staticSymbolStatus 返回固定交易对状态的模拟流水线
+type staticSymbolStatus []models.SymbolStatus
+
+func (s staticSymbolStatus) SymbolStatuses() []models.SymbolStatus {
+	return s
+}
+
+// TestServer_GetPipelineSymbols 测试交易对处理状态接口
+func TestServer_GetPipelineSymbols(t *testing.T) {
+	request := func(server *Server) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/pipeline/symbols", nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未配置流水线时不可用
+	assert.Equal(t, http.StatusServiceUnavailable, request(NewServer(&MockTushareClient{}, &MockStorage{})).Code)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	server := NewServer(&MockTushareClient{}, &MockStorage{}, WithSymbolStatus(staticSymbolStatus{
+		{Symbol: "BTCUSDT", LastSuccess: &now},
+		{Symbol: "LUNAUSDT", LastError: "binance: symbol delisted", LastErrorAt: &now, ConsecutiveFailures: 3, Failing: true},
+	}))
+	w := request(server)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Message string                `json:"message"`
+		Data    []models.SymbolStatus `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "1 of 2 symbols failing", resp.Message)
+	if assert.Len(t, resp.Data, 2) {
+		assert.False(t, resp.Data[0].Failing)
+		assert.Equal(t, now, resp.Data[0].LastSuccess.UTC())
+		assert.True(t, resp.Data[1].Failing)
+		assert.Equal(t, "binance: symbol delisted", resp.Data[1].LastError)
+		assert.Equal(t, 3, resp.Data[1].ConsecutiveFailures)
+	}
+}
+
+// fakePipeline 记录暂停状态的流水线
+type fakePipeline struct {
+	paused      bool
+	lastSuccess time.Time
+}
+
+func (f *fakePipeline) Pause() bool {
+	changed := !f.paused
+	f.paused = true
+	return changed
+}
+
+func (f *fakePipeline) Resume() bool {
+	changed := f.paused
+	f.paused = false
+	return changed
+}
+
+func (f *fakePipeline) Status() models.PipelineStatus {
+	return models.PipelineStatus{Paused: f.paused, Interval: "30s", Symbols: 3, LastSuccess: f.lastSuccess}
+}
+
+// fakeDatabaseHealth 返回固定连接池统计的数据库，block为true时连通性检查一直等到ctx取消
+type fakeDatabaseHealth struct {
+	err   error
+	block bool
+}
+
+func (f fakeDatabaseHealth) PingPool(ctx context.Context) error {
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return f.err
+}
+
+func (f fakeDatabaseHealth) PoolStats() models.DBPoolStats {
+	return models.DBPoolStats{TotalConns: 4, IdleConns: 3, AcquiredConns: 1, MaxConns: 10}
+}
+
+// fakeJobStatus 返回固定的定时任务执行情况
+type fakeJobStatus []models.ScheduledJobStatus
+
+func (f fakeJobStatus) JobStatuses() []models.ScheduledJobStatus {
+	return f
+}
+
+// TestServer_GetDiagnostics 测试诊断接口汇总各子系统状态，任一子系统异常时ok为false并返回503
+func TestServer_GetDiagnostics(t *testing.T) {
+	now := time.Now()
+	lastRun := now.Add(-time.Hour)
+	type diagnosticsResponse struct {
+		Success bool                     `json:"success"`
+		Message string                   `json:"message"`
+		Data    models.DiagnosticsReport `json:"data"`
+	}
+	request := func(server *Server, token string) (int, diagnosticsResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/admin/diagnostics", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.router.ServeHTTP(w, req)
+		var resp diagnosticsResponse
+		if w.Code == http.StatusOK || w.Code == http.StatusServiceUnavailable {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+	checks := func(resp diagnosticsResponse) map[string]models.DiagnosticCheck {
+		byName := make(map[string]models.DiagnosticCheck)
+		for _, check := range resp.Data.Checks {
+			byName[check.Name] = check
+		}
+		return byName
+	}
+	freshStorage := &MockStorage{GetSourceFreshnessFunc: func(ctx context.Context) (map[string]time.Time, error) {
+		return map[string]time.Time{"binance": now.Add(-time.Minute)}, nil
+	}}
+
+	healthy := NewServer(&MockTushareClient{}, freshStorage,
+		WithAdminToken("secret"),
+		WithDatabaseHealth(fakeDatabaseHealth{}),
+		WithKafkaProbe(func(ctx context.Context) error { return nil }),
+		WithJobStatus(fakeJobStatus{{Name: "stock_list", Spec: "@daily", LastRun: &lastRun}}),
+		WithPipelineControl(&fakePipeline{lastSuccess: now}),
+	)
+
+	// 需要管理令牌
+	code, _ := request(healthy, "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, resp := request(healthy, "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Success)
+	assert.True(t, resp.Data.OK)
+	byName := checks(resp)
+	assert.Len(t, byName, 5)
+	for name, check := range byName {
+		assert.True(t, check.OK, "check %s: %s", name, check.Error)
+	}
+	assert.Contains(t, fmt.Sprint(byName["database"].Details), "max_conns")
+
+	// 数据库超时、Kafka不可连接、定时任务失败、流水线长时间没有成功、数据源停止更新
+	unhealthy := NewServer(&MockTushareClient{}, &MockStorage{GetSourceFreshnessFunc: func(ctx context.Context) (map[string]time.Time, error) {
+		return map[string]time.Time{"binance": now.Add(-time.Minute), "okx": now.Add(-time.Hour)}, nil
+	}},
+		WithAdminToken("secret"),
+		WithDatabaseHealth(fakeDatabaseHealth{block: true}),
+		WithKafkaProbe(func(ctx context.Context) error { return fmt.Errorf("connection refused") }),
+		WithJobStatus(fakeJobStatus{{Name: "daily", Spec: "0 18 * * 1-5", LastRun: &lastRun, LastError: "tushare unavailable"}}),
+		WithPipelineControl(&fakePipeline{lastSuccess: now.Add(-time.Hour)}),
+	)
+	unhealthy.diagnosticsTimeout = 50 * time.Millisecond
+	start := time.Now()
+	code, resp = request(unhealthy, "secret")
+	assert.Less(t, time.Since(start), time.Second, "checks should be bounded by the timeout")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, resp.Success)
+	assert.False(t, resp.Data.OK)
+	byName = checks(resp)
+	assert.Contains(t, byName["database"].Error, "timed out")
+	assert.Equal(t, "connection refused", byName["kafka"].Error)
+	assert.Contains(t, byName["scheduler"].Error, "daily")
+	assert.Contains(t, byName["pipeline"].Error, "no successful cycle")
+	assert.Contains(t, byName["freshness"].Error, "okx")
+	for name, check := range byName {
+		assert.False(t, check.OK, "check %s", name)
+	}
+
+	// 主动暂停的流水线和未配置的子系统不影响结果
+	paused := NewServer(&MockTushareClient{}, freshStorage, WithAdminToken("secret"),
+		WithPipelineControl(&fakePipeline{paused: true, lastSuccess: now.Add(-time.Hour)}))
+	code, resp = request(paused, "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Data.OK)
+	assert.Len(t, resp.Data.Checks, 2)
+}
+
+// fakeConsistencyReporter 返回固定的一致性检查结果
+type fakeConsistencyReporter struct {
+	stats models.ConsistencyStats
+}
+
+func (r fakeConsistencyReporter) Stats() models.ConsistencyStats {
+	return r.stats
+}
+
+// TestServer_GetConsistencyStats 测试一致性检查结果接口，未启用时返回503
+func TestServer_GetConsistencyStats(t *testing.T) {
+	get := func(server *Server) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/pipeline/consistency", nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, get(NewServer(&MockTushareClient{}, &MockStorage{})).Code)
+
+	stats := models.ConsistencyStats{Checks: 3, Sampled: 120, Missing: 2, LastSampled: 40, LastMissing: []string{"a", "b"}}
+	w := get(NewServer(&MockTushareClient{}, &MockStorage{}, WithConsistencyReporter(fakeConsistencyReporter{stats})))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Message string                  `json:"message"`
+		Data    models.ConsistencyStats `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, stats, resp.Data)
+	assert.Equal(t, "2 of 120 sampled records missing from Kafka", resp.Message)
+}
+
+// TestServer_PipelinePauseResume 测试通过管理接口暂停和恢复流水线，状态接口反映暂停状态
+func TestServer_PipelinePauseResume(t *testing.T) {
+	request := func(server *Server, method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/v1"+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	type statusResponse struct {
+		Message string                `json:"message"`
+		Data    models.PipelineStatus `json:"data"`
+	}
+	status := func(w *httptest.ResponseRecorder) statusResponse {
+		var resp statusResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// 未配置流水线时不可用
+	unconfigured := NewServer(&MockTushareClient{}, &MockStorage{}, WithAdminToken("secret"))
+	assert.Equal(t, http.StatusServiceUnavailable, request(unconfigured, http.MethodGet, "/pipeline/status", "").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, request(unconfigured, http.MethodPost, "/admin/pipeline/pause", "secret").Code)
+
+	p := &fakePipeline{}
+	server := NewServer(&MockTushareClient{}, &MockStorage{}, WithAdminToken("secret"), WithPipelineControl(p))
+
+	// 需要管理令牌
+	assert.Equal(t, http.StatusUnauthorized, request(server, http.MethodPost, "/admin/pipeline/pause", "").Code)
+	assert.False(t, p.paused)
+
+	w := request(server, http.MethodPost, "/admin/pipeline/pause", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Pipeline paused", status(w).Message)
+	assert.True(t, p.paused)
+	assert.Equal(t, "Pipeline was already paused", status(request(server, http.MethodPost, "/admin/pipeline/pause", "secret")).Message)
+
+	w = request(server, http.MethodGet, "/pipeline/status", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp := status(w)
+	assert.Equal(t, "Pipeline is paused", resp.Message)
+	assert.True(t, resp.Data.Paused)
+	assert.Equal(t, 3, resp.Data.Symbols)
+
+	w = request(server, http.MethodPost, "/admin/pipeline/resume", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Pipeline resumed", status(w).Message)
+	assert.False(t, p.paused)
+	assert.Equal(t, "Pipeline was not paused", status(request(server, http.MethodPost, "/admin/pipeline/resume", "secret")).Message)
+	assert.False(t, status(request(server, http.MethodGet, "/pipeline/status", "")).Data.Paused)
+}
+
+// TestServer_CompareMarketData 测试跨数据源价格对比和偏离标记
+func TestServer_CompareMarketData(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockStorage := &MockStorage{
+		GetCrossSourcePricesFunc: func(ctx context.Context, symbol string, gotAt time.Time, tolerance time.Duration) (map[string]float64, error) {
+			assert.Equal(t, "BTCUSDT", symbol)
+			assert.True(t, at.Equal(gotAt))
+			assert.Equal(t, 30*time.Second, tolerance)
+			return map[string]float64{"binance": 100, "okx": 102, "bybit": 101}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	compare := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/market/compare?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := compare("symbol=BTCUSDT&at=2024-01-02T03:04:05Z&tolerance=30&threshold=1.5")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.CrossSourceComparison `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "binance", resp.Data.MinSource)
+	assert.Equal(t, "okx", resp.Data.MaxSource)
+	assert.InDelta(t, 2.0, resp.Data.DivergencePct, 1e-9)
+	assert.True(t, resp.Data.Divergent)
+	assert.Equal(t, 30.0, resp.Data.ToleranceSeconds)
+	assert.Len(t, resp.Data.Prices, 3)
+
+	// 偏离未超过阈值
+	w = compare("symbol=BTCUSDT&at=2024-01-02T03:04:05Z&tolerance=30&threshold=5")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Data.Divergent)
+
+	// 参数校验
+	assert.Equal(t, http.StatusBadRequest, compare("at=2024-01-02T03:04:05Z").Code)
+	assert.Equal(t, http.StatusBadRequest, compare("symbol=BTCUSDT&at=2024-01-02").Code)
+	assert.Equal(t, http.StatusBadRequest, compare("symbol=BTCUSDT&at=2024-01-02T03:04:05Z&tolerance=0").Code)
+	assert.Equal(t, http.StatusBadRequest, compare("symbol=BTCUSDT&at=2024-01-02T03:04:05Z&threshold=abc").Code)
+
+	// 存储错误
+	mockStorage.GetCrossSourcePricesFunc = func(ctx context.Context, symbol string, at time.Time, tolerance time.Duration) (map[string]float64, error) {
+		return nil, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, compare("symbol=BTCUSDT&at=2024-01-02T03:04:05Z").Code)
+}
+
+// TestServer_GetReturnCorrelation 测试收益率相关系数接口
+func TestServer_GetReturnCorrelation(t *testing.T) {
+	mockStorage := &MockStorage{
+		GetReturnCorrelationFunc: func(ctx context.Context, symbolA, symbolB string, start, end time.Time) (float64, int, error) {
+			assert.Equal(t, "BTCUSDT", symbolA)
+			assert.Equal(t, "ETHUSDT", symbolB)
+			assert.Equal(t, time.Hour, end.Sub(start))
+			return 0.85, 60, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/market/correlation?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("a=BTCUSDT&b=ETHUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.CorrelationResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 0.85, resp.Data.Correlation)
+	assert.Equal(t, 60, resp.Data.Points)
+	assert.Equal(t, "1m0s", resp.Data.Bucket)
+
+	// 参数校验
+	assert.Equal(t, http.StatusBadRequest, request("a=BTCUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z").Code)
+	assert.Equal(t, http.StatusBadRequest, request("a=BTCUSDT&b=BTCUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z").Code)
+	assert.Equal(t, http.StatusBadRequest, request("a=BTCUSDT&b=ETHUSDT&start=2024-01-02&end=2024-01-02T01:00:00Z").Code)
+	assert.Equal(t, http.StatusBadRequest, request("a=BTCUSDT&b=ETHUSDT&start=2024-01-02T01:00:00Z&end=2024-01-02T00:00:00Z").Code)
+
+	// 重叠数据不足
+	mockStorage.GetReturnCorrelationFunc = nil
+	w = request("a=BTCUSDT&b=ETHUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient overlapping data")
+
+	// 存储错误
+	mockStorage.GetReturnCorrelationFunc = func(ctx context.Context, symbolA, symbolB string, start, end time.Time) (float64, int, error) {
+		return 0, 0, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, request("a=BTCUSDT&b=ETHUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z").Code)
+}
+
+// TestServer_GetVWAP 测试成交量加权平均价接口
+func TestServer_GetVWAP(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	mockStorage := &MockStorage{
+		GetVWAPFunc: func(ctx context.Context, symbol string, gotStart, gotEnd time.Time) (float64, error) {
+			assert.Equal(t, "BTCUSDT", symbol)
+			assert.True(t, start.Equal(gotStart))
+			assert.True(t, end.Equal(gotEnd))
+			return 101.5, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/market/vwap?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("symbol=BTCUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.VWAPResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "BTCUSDT", resp.Data.Symbol)
+	assert.Equal(t, 101.5, resp.Data.VWAP)
+
+	// 参数校验
+	assert.Equal(t, http.StatusBadRequest, request("start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z").Code)
+	assert.Equal(t, http.StatusBadRequest, request("symbol=BTCUSDT&start=2024-01-02&end=2024-01-02T01:00:00Z").Code)
+	assert.Equal(t, http.StatusBadRequest, request("symbol=BTCUSDT&start=2024-01-02T00:00:00Z").Code)
+	assert.Equal(t, http.StatusBadRequest, request("symbol=BTCUSDT&start=2024-01-02T01:00:00Z&end=2024-01-02T00:00:00Z").Code)
+
+	// 成交量为0
+	mockStorage.GetVWAPFunc = func(ctx context.Context, symbol string, start, end time.Time) (float64, error) {
+		return 0, fmt.Errorf("BTCUSDT: %w", storage.ErrZeroVolume)
+	}
+	assert.Equal(t, http.StatusNotFound, request("symbol=BTCUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z").Code)
+
+	// 存储错误
+	mockStorage.GetVWAPFunc = func(ctx context.Context, symbol string, start, end time.Time) (float64, error) {
+		return 0, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, request("symbol=BTCUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z").Code)
+}
+
+// TestServer_GetVolumeProfile 测试成交量分布接口的区间数默认值和校验
+func TestServer_GetVolumeProfile(t *testing.T) {
+	var gotBuckets int
+	mockStorage := &MockStorage{
+		GetVolumeProfileFunc: func(ctx context.Context, symbol string, start, end time.Time, buckets int) ([]models.PriceVolumeBucket, error) {
+			gotBuckets = buckets
+			return []models.PriceVolumeBucket{
+				{PriceLow: 100, PriceHigh: 105, Volume: 3, Ticks: 2},
+				{PriceLow: 105, PriceHigh: 110, Volume: 7, Ticks: 1},
+			}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/market/volume-profile?symbol=BTCUSDT&start=2024-01-02T00:00:00Z&end=2024-01-02T01:00:00Z"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DefaultVolumeProfileBuckets, gotBuckets)
+	var resp struct {
+		Data models.VolumeProfileResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "BTCUSDT", resp.Data.Symbol)
+	assert.Len(t, resp.Data.Buckets, 2)
+	assert.Equal(t, 7.0, resp.Data.Buckets[1].Volume)
+
+	assert.Equal(t, http.StatusOK, request("&buckets=2").Code)
+	assert.Equal(t, 2, gotBuckets)
+
+	// 区间数校验
+	for _, buckets := range []string{"0", "501", "-3", "ten"} {
+		w = request("&buckets=" + buckets)
+		assert.Equal(t, http.StatusBadRequest, w.Code, buckets)
+		assert.Contains(t, w.Body.String(), "buckets must be an integer between 1 and 500")
+	}
+
+	// 存储错误
+	mockStorage.GetVolumeProfileFunc = func(ctx context.Context, symbol string, start, end time.Time, buckets int) ([]models.PriceVolumeBucket, error) {
+		return nil, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, request("").Code)
+}
+
+// mockMarketDataPublisher 记录发送的市场数据
+type mockMarketDataPublisher struct {
+	sent []models.MarketData
+	err  error
+}
+
+func (m *mockMarketDataPublisher) SendMarketData(data []models.MarketData) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, data...)
+	return nil
+}
+
+// TestServer_IngestMarketData 测试推送市场数据时逐条校验、只保存和发送合法数据，并返回非法数据的下标和原因
+func TestServer_IngestMarketData(t *testing.T) {
+	var saved []models.MarketData
+	mockStorage := &MockStorage{
+		SaveMarketDataFunc: func(data []models.MarketData) error {
+			saved = append(saved, data...)
+			return nil
+		},
+	}
+	publisher := &mockMarketDataPublisher{}
+	server := NewServer(&MockTushareClient{}, mockStorage, WithMarketDataPublisher(publisher))
+
+	request := func(server *Server, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/market/ingest", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) models.IngestResult {
+		var resp struct {
+			Data models.IngestResult `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	// 全部合法，数据源名称规范化为小写
+	w := request(server, `[
+		{"id":"a","symbol":"BTCUSDT","price":100,"volume":1,"timestamp":"2024-01-02T00:00:00Z","source":"Binance"},
+		{"id":"b","symbol":"ETHUSDT","price":10,"volume":2,"timestamp":"2024-01-02T00:00:00Z","source":"okx"}
+	]`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := decode(w)
+	assert.Equal(t, 2, result.Accepted)
+	assert.Equal(t, 0, result.Rejected)
+	assert.True(t, result.Published)
+	assert.Empty(t, result.Rejections)
+	if assert.Len(t, saved, 2) {
+		assert.Equal(t, "binance", saved[0].Source)
+	}
+	assert.Len(t, publisher.sent, 2)
+
+	// 部分非法，只保存合法数据
+	saved, publisher.sent = nil, nil
+	w = request(server, `[
+		{"id":"c","symbol":"BTCUSDT","price":0,"volume":1,"timestamp":"2024-01-02T00:00:00Z","source":"binance"},
+		{"id":"d","symbol":"BTCUSDT","price":101,"volume":1,"timestamp":"2024-01-02T00:01:00Z","source":"binance"},
+		{"id":"e","symbol":"BTCUSDT","price":102,"volume":1,"timestamp":"2024-01-02T00:02:00Z","source":"coinbase"}
+	]`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	result = decode(w)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, 2, result.Rejected)
+	if assert.Len(t, result.Rejections, 2) {
+		assert.Equal(t, 0, result.Rejections[0].Index)
+		assert.Contains(t, result.Rejections[0].Reason, "price")
+		assert.Equal(t, 2, result.Rejections[1].Index)
+		assert.Contains(t, result.Rejections[1].Reason, "unknown source")
+	}
+	if assert.Len(t, saved, 1) {
+		assert.Equal(t, "d", saved[0].ID)
+	}
+	assert.Len(t, publisher.sent, 1)
+
+	// 全部非法时不保存
+	saved = nil
+	w = request(server, `[{"id":"f","symbol":"","price":1,"timestamp":"2024-01-02T00:00:00Z","source":"binance"}]`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	result = decode(w)
+	assert.Equal(t, 0, result.Accepted)
+	assert.Equal(t, 1, result.Rejected)
+	assert.False(t, result.Published)
+	assert.Empty(t, saved)
+
+	// Kafka发送失败时数据已保存
+	publisher.err = fmt.Errorf("broker unavailable")
+	w = request(server, `[{"id":"g","symbol":"BTCUSDT","price":1,"timestamp":"2024-01-02T00:00:00Z","source":"binance"}]`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, decode(w).Published)
+	assert.Len(t, saved, 1)
+
+	// 请求体校验
+	assert.Equal(t, http.StatusBadRequest, request(server, `[]`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, request(server, `{"id":"a"}`, "").Code)
+
+	// 存储错误
+	mockStorage.SaveMarketDataFunc = func(data []models.MarketData) error {
+		return fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError,
+		request(server, `[{"id":"h","symbol":"BTCUSDT","price":1,"timestamp":"2024-01-02T00:00:00Z","source":"binance"}]`, "").Code)
+
+	// 配置了令牌时需要鉴权
+	secured := NewServer(&MockTushareClient{}, &MockStorage{}, WithIngestToken("secret"))
+	body := `[{"id":"i","symbol":"BTCUSDT","price":1,"timestamp":"2024-01-02T00:00:00Z","source":"binance"}]`
+	assert.Equal(t, http.StatusUnauthorized, request(secured, body, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(secured, body, "wrong").Code)
+	w = request(secured, body, "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, decode(w).Published)
+}
+
+// TestServer_ImportBacktestData 测试CSV导入回测数据时拒绝非法的行并报告行号，只保存合法的行
+func TestServer_ImportBacktestData(t *testing.T) {
+	var saved []models.BacktestData
+	mockStorage := &MockStorage{
+		SaveBacktestDataBatchFunc: func(data []models.BacktestData) error {
+			saved = append(saved, data...)
+			return nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	request := func(body []byte, asForm bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		var req *http.Request
+		if asForm {
+			var buf bytes.Buffer
+			mw := multipart.NewWriter(&buf)
+			fw, _ := mw.CreateFormFile("file", "backtests.csv")
+			fw.Write(body)
+			mw.Close()
+			req, _ = http.NewRequest(http.MethodPost, "/api/v1/backtest/import", &buf)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+		} else {
+			req, _ = http.NewRequest(http.MethodPost, "/api/v1/backtest/import", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "text/csv")
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	csvData := `id,symbol,strategy,start_date,end_date,results,timestamp
+bt-1,BTCUSDT,MA Cross,2023-01-01,2023-12-31,"{""profit"": 0.12}",2024-01-01T00:00:00Z
+bt-2,ETHUSDT,RSI,2023-01-01,2023-12-31,"{profit: 0.3",2024-01-01T00:00:00Z
+bt-3,ETHUSDT,RSI,2023-01-01T00:00:00Z,2023-06-30T00:00:00Z,"[1, 2, 3]",2024-01-01T00:00:00Z
+`
+	w := request([]byte(csvData), true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.BacktestImportResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Data.Parsed)
+	assert.Equal(t, 2, resp.Data.Inserted)
+	assert.Equal(t, 1, resp.Data.Rejected)
+	if assert.Len(t, resp.Data.Rejections, 1) {
+		assert.Equal(t, 3, resp.Data.Rejections[0].Line)
+		assert.Contains(t, resp.Data.Rejections[0].Reason, "results is not valid JSON")
+	}
+	if assert.Len(t, saved, 2) {
+		assert.Equal(t, "bt-1", saved[0].ID)
+		assert.Equal(t, `{"profit": 0.12}`, saved[0].Results)
+		assert.Equal(t, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), saved[0].EndDate)
+		assert.Equal(t, "bt-3", saved[1].ID)
+	}
+
+	// 请求体直接为CSV，列顺序不限
+	saved = nil
+	w = request([]byte("symbol,id,strategy,start_date,end_date,results,timestamp\nBTCUSDT,bt-4,MA,2023-01-01,2023-02-01,{},2023-02-02\n"), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, saved, 1) {
+		assert.Equal(t, "bt-4", saved[0].ID)
+		assert.Equal(t, "BTCUSDT", saved[0].Symbol)
+	}
+
+	// 表头缺少列
+	w = request([]byte("id,symbol\nbt-5,BTCUSDT\n"), false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "strategy, start_date, end_date, results, timestamp")
+
+	// Parquet文件不支持
+	w = request([]byte("PAR1\x15\x04\x15\x10"), true)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), CodeUnsupportedFormat)
+
+	// 保存失败
+	mockStorage.SaveBacktestDataBatchFunc = func(data []models.BacktestData) error {
+		return fmt.Errorf("connection refused")
+	}
+	w = request([]byte(csvData), false)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// TestServer_GetMarketDataDownsampled 测试market/data接口的bucket降采样参数
+func TestServer_GetMarketDataDownsampled(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockStorage := &MockStorage{
+		GetDownsampledFunc: func(ctx context.Context, symbol string, bucket time.Duration, gotStart, end time.Time) ([]models.MarketData, error) {
+			assert.Equal(t, "BTCUSDT", symbol)
+			assert.Equal(t, 5*time.Minute, bucket)
+			assert.True(t, start.Equal(gotStart))
+			return []models.MarketData{
+				{ID: "a", Symbol: symbol, Price: 100, Timestamp: start.Add(4 * time.Minute)},
+				{ID: "b", Symbol: symbol, Price: 101, Timestamp: start.Add(9 * time.Minute)},
+			}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/market/data?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("symbol=BTCUSDT&bucket=5m&start=2024-01-01T00:00:00Z&end=2024-01-01T00:10:00Z")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []models.MarketData `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Data, 2) {
+		assert.Equal(t, "b", resp.Data[1].ID)
+	}
+
+	// 参数校验
+	assert.Equal(t, http.StatusBadRequest, request("symbol=BTCUSDT&bucket=abc&start=2024-01-01T00:00:00Z&end=2024-01-01T00:10:00Z").Code)
+	assert.Equal(t, http.StatusBadRequest, request("symbol=BTCUSDT&bucket=5m&end=2024-01-01T00:10:00Z").Code)
+	assert.Equal(t, http.StatusBadRequest, request("symbol=BTCUSDT&bucket=5m&start=2024-01-01T00:00:00Z").Code)
+
+	mockStorage.GetDownsampledFunc = func(ctx context.Context, symbol string, bucket time.Duration, start, end time.Time) ([]models.MarketData, error) {
+		return nil, fmt.Errorf("%w: bucket must be at least 1s", storage.ErrInvalidMarketDataQuery)
+	}
+	assert.Equal(t, http.StatusBadRequest, request("symbol=BTCUSDT&bucket=1ms&start=2024-01-01T00:00:00Z&end=2024-01-01T00:10:00Z").Code)
+
+	mockStorage.GetDownsampledFunc = func(ctx context.Context, symbol string, bucket time.Duration, start, end time.Time) ([]models.MarketData, error) {
+		return nil, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, request("symbol=BTCUSDT&bucket=5m&start=2024-01-01T00:00:00Z&end=2024-01-01T00:10:00Z").Code)
+}
+
+// TestServer_ReloadConfig 测试配置重新加载接口的鉴权和错误映射
+func TestServer_ReloadConfig(t *testing.T) {
+	request := func(server *Server, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/admin/config/reload", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未设置重新加载函数时不可用
+	assert.Equal(t, http.StatusServiceUnavailable, request(NewServer(&MockTushareClient{}, &MockStorage{}, WithAdminToken("secret")), "secret").Code)
+
+	var reloadErr error
+	calls := 0
+	server := NewServer(&MockTushareClient{}, &MockStorage{}, WithAdminToken("secret"),
+		WithConfigReload(func() (config.Reloadable, error) {
+			calls++
+			return config.Reloadable{LogLevel: "debug", ProcessingInterval: 10}, reloadErr
+		}))
+
+	assert.Equal(t, http.StatusUnauthorized, request(server, "").Code)
+	assert.Equal(t, 0, calls)
+
+	w := request(server, "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data config.Reloadable `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, config.Reloadable{LogLevel: "debug", ProcessingInterval: 10}, resp.Data)
+
+	reloadErr = fmt.Errorf("%w: DBHost", config.ErrNonReloadableChange)
+	w = request(server, "secret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "DBHost")
+
+	reloadErr = fmt.Errorf("failed to read DB_PASSWORD_FILE")
+	assert.Equal(t, http.StatusInternalServerError, request(server, "secret").Code)
+}
+
+// TestServer_Cache 测试缓存统计反映命中情况，清空指定缓存后条目数为0
+func TestServer_Cache(t *testing.T) {
+	mockStorage := &MockStorage{
+		GetStockNamesFunc: func(ctx context.Context) (map[string]string, error) {
+			return map[string]string{"000001.SZ": "平安银行"}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage, WithAdminToken("secret"))
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	stats := func() map[string]models.CacheStats {
+		w := request(http.MethodGet, "/api/v1/admin/cache", "secret")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []models.CacheStats `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		byName := make(map[string]models.CacheStats)
+		for _, s := range resp.Data {
+			byName[s.Name] = s
+		}
+		return byName
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/admin/cache", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/api/v1/admin/cache/purge", "").Code)
+
+	// 一次加载、两次命中
+	for i := 0; i < 3; i++ {
+		_, err := server.stockNames.Names(context.Background())
+		assert.NoError(t, err)
+	}
+	got := stats()[CacheStockNames]
+	assert.Equal(t, 1, got.Size)
+	assert.Equal(t, int64(2), got.Hits)
+	assert.Equal(t, int64(1), got.Misses)
+	assert.InDelta(t, 2.0/3, got.HitRate, 1e-9)
+
+	// 清空不存在的缓存
+	w := request(http.MethodPost, "/api/v1/admin/cache/purge?name=missing", "secret")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, 1, stats()[CacheStockNames].Size)
+
+	// 清空指定缓存
+	w = request(http.MethodPost, "/api/v1/admin/cache/purge?name="+CacheStockNames, "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), CacheStockNames)
+	assert.Equal(t, 0, stats()[CacheStockNames].Size)
+
+	// 不指定名称时清空全部
+	_, _ = server.stockNames.Names(context.Background())
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/v1/admin/cache/purge", "secret").Code)
+	assert.Equal(t, 0, stats()[CacheStockNames].Size)
+}
+
+// TestServer_GetStoredTradeDates 测试已保存交易日接口
+func TestServer_GetStoredTradeDates(t *testing.T) {
+	var storeErr error
+	mockStorage := &MockStorage{
+		GetStoredTradeDatesFunc: func(ctx context.Context, tsCode string) ([]string, error) {
+			if tsCode != "000001.SZ" {
+				return []string{}, storeErr
+			}
+			return []string{"20240102", "20240103", "20240105"}, storeErr
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/stock/daily/dates"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, get("").Code)
+
+	w := get("?ts_code=000001.SZ")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.StoredTradeDates `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "000001.SZ", resp.Data.TSCode)
+	assert.Equal(t, []string{"20240102", "20240103", "20240105"}, resp.Data.Dates)
+
+	// 没有日线时返回空数组
+	w = get("?ts_code=600000.SH")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"dates":[]`)
+
+	storeErr = fmt.Errorf("connection refused")
+	assert.Equal(t, http.StatusInternalServerError, get("?ts_code=000001.SZ").Code)
+}
+
+// TestServer_GetMissingTradingDays 测试缺失交易日接口的参数传递和错误映射
+func TestServer_GetMissingTradingDays(t *testing.T) {
+	var gotExchange string
+	mockStorage := &MockStorage{
+		FindMissingDaysFunc: func(ctx context.Context, tsCode, exchange, start, end string) ([]string, error) {
+			gotExchange = exchange
+			if start > end {
+				return nil, fmt.Errorf("%w: start after end", storage.ErrInvalidDateRange)
+			}
+			if tsCode == "FAIL.SZ" {
+				return nil, fmt.Errorf("connection refused")
+			}
+			return []string{"20240103", "20240105"}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/stock/daily/missing?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("ts_code=000001.SZ&start=20240101&end=20240131")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.MissingTradeDates `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, models.MissingTradeDates{
+		TSCode: "000001.SZ", Exchange: storage.DefaultExchange, Start: "20240101", End: "20240131",
+		Dates: []string{"20240103", "20240105"},
+	}, resp.Data)
+
+	get("ts_code=000001.SZ&exchange=SZSE&start=20240101&end=20240131")
+	assert.Equal(t, "SZSE", gotExchange)
+
+	assert.Equal(t, http.StatusBadRequest, get("start=20240101&end=20240131").Code)
+	assert.Equal(t, http.StatusBadRequest, get("ts_code=000001.SZ&start=20240131&end=20240101").Code)
+	assert.Equal(t, http.StatusInternalServerError, get("ts_code=FAIL.SZ&start=20240101&end=20240131").Code)
+}
+
+// TestServer_StreamDaily 测试日线实时推送：发布的日线以SSE事件推送，只推送请求的股票，空闲时发送心跳
+func TestServer_StreamDaily(t *testing.T) {
+	bus := events.NewBus[models.Daily]()
+	server := NewServer(&MockTushareClient{}, &MockStorage{}, WithDailyStream(bus))
+	server.dailyStreamHeartbeat = 20 * time.Millisecond
+	ts := httptest.NewServer(server.router)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/stock/daily/stream?ts_code=000001.SZ", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readLine := func() string {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		return strings.TrimRight(line, "\n")
+	}
+	// 连接建立后先收到注释，之后订阅已生效
+	assert.True(t, strings.HasPrefix(readLine(), ": streaming daily bars for 000001.SZ"))
+	assert.Equal(t, 1, bus.Subscribers())
+
+	bus.Publish([]models.Daily{
+		{TSCode: "600000.SH", TradeDate: "20240102", Close: 7.1},
+		{TSCode: "000001.SZ", TradeDate: "20240102", Close: 10.5},
+	})
+
+	// 跳过空行和心跳，读到日线事件
+	var lines []string
+	for len(lines) < 3 {
+		line := readLine()
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	assert.Equal(t, "event: daily", lines[0])
+	assert.Equal(t, "id: 20240102", lines[1])
+	var bar models.Daily
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &bar))
+	assert.Equal(t, "000001.SZ", bar.TSCode)
+	assert.Equal(t, 10.5, bar.Close)
+
+	// 空闲时收到心跳
+	for line := readLine(); line != ": heartbeat"; line = readLine() {
+		assert.True(t, line == "" || strings.HasPrefix(line, ":"), "unexpected line %q", line)
+	}
+
+	// 客户端断开后取消订阅
+	cancel()
+	assert.Eventually(t, func() bool { return bus.Subscribers() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+// TestServer_StreamDailyErrors 测试日线实时推送未启用和缺少参数时的错误
+func TestServer_StreamDailyErrors(t *testing.T) {
+	get := func(server *Server, query string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/stock/daily/stream?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, get(NewServer(&MockTushareClient{}, &MockStorage{}), "ts_code=000001.SZ"))
+	assert.Equal(t, http.StatusBadRequest, get(NewServer(&MockTushareClient{}, &MockStorage{}, WithDailyStream(events.NewBus[models.Daily]())), ""))
+}
+
+// TestServer_GetStkManagers 测试管理层接口在模拟数据集上的日期过滤、分页和参数校验
+func TestServer_GetStkManagers(t *testing.T) {
+	// 已按公告日期倒序、姓名升序排列
+	seeded := []models.StkManagers{
+		{TSCode: "000001.SZ", AnnDate: "20240301", Name: "王五", Title: "董事长"},
+		{TSCode: "000001.SZ", AnnDate: "20240301", Name: "赵六", Title: "董事"},
+		{TSCode: "000001.SZ", AnnDate: "20230601", Name: "张三", Title: "总经理"},
+		{TSCode: "000001.SZ", AnnDate: "20220101", Name: "李四", Title: "监事"},
+	}
+	var gotLimit int
+	mockStorage := &MockStorage{
+		GetStkManagersFilteredFunc: func(ctx context.Context, tsCode string, startAnnDate, endAnnDate string, limit, offset int) ([]models.StkManagers, error) {
+			if tsCode == "FAIL.SZ" {
+				return nil, fmt.Errorf("connection refused")
+			}
+			if startAnnDate == "bad" {
+				return nil, fmt.Errorf("%w: start must be YYYYMMDD", storage.ErrInvalidDateRange)
+			}
+			gotLimit = limit
+			var matched []models.StkManagers
+			for _, m := range seeded {
+				if m.TSCode == tsCode && (startAnnDate == "" || m.AnnDate >= startAnnDate) && (endAnnDate == "" || m.AnnDate <= endAnnDate) {
+					matched = append(matched, m)
+				}
+			}
+			if offset >= len(matched) {
+				return nil, nil
+			}
+			matched = matched[offset:]
+			return matched[:min(limit, len(matched))], nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+	get := func(query string) (*httptest.ResponseRecorder, models.StkManagersPage) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/stock/managers?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		var resp struct {
+			Data models.StkManagersPage `json:"data"`
+		}
+		if w.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp.Data
+	}
+
+	// 默认每页条数，多取一条判断是否有下一页
+	w, page := get("ts_code=000001.SZ")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DefaultStkManagersLimit+1, gotLimit)
+	assert.Len(t, page.Data, 4)
+	assert.False(t, page.HasMore)
+	assert.Nil(t, page.NextOffset)
+
+	// 第一页
+	_, page = get("ts_code=000001.SZ&limit=2")
+	assert.Equal(t, []string{"王五", "赵六"}, []string{page.Data[0].Name, page.Data[1].Name})
+	assert.True(t, page.HasMore)
+	if assert.NotNil(t, page.NextOffset) {
+		assert.Equal(t, 2, *page.NextOffset)
+	}
+	cursor := page.NextCursor
+
+	// 最后一页
+	_, page = get("ts_code=000001.SZ&limit=2&offset=2")
+	assert.Equal(t, []string{"张三", "李四"}, []string{page.Data[0].Name, page.Data[1].Name})
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, 2, page.Offset)
+
+	// 游标与next_offset取到同一页
+	_, page = get("ts_code=000001.SZ&limit=2&cursor=" + cursor)
+	assert.Equal(t, 2, page.Offset)
+	assert.Equal(t, []string{"张三", "李四"}, []string{page.Data[0].Name, page.Data[1].Name})
+
+	// 公告日期过滤
+	_, page = get("ts_code=000001.SZ&start=20230101&end=20231231")
+	if assert.Len(t, page.Data, 1) {
+		assert.Equal(t, "张三", page.Data[0].Name)
+	}
+	assert.Equal(t, "20230101", page.Start)
+
+	// 超出范围的页和没有数据的公司返回空数组
+	w, page = get("ts_code=000001.SZ&offset=10")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, page.Data)
+	assert.Empty(t, page.Data)
+
+	// 每页条数超过上限时截断
+	_, page = get("ts_code=000001.SZ&limit=100000")
+	assert.Equal(t, MaxStkManagersLimit, page.Limit)
+
+	w, _ = get("limit=2")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("ts_code=000001.SZ&limit=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("ts_code=000001.SZ&offset=x")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("ts_code=000001.SZ&cursor=bad")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("ts_code=000001.SZ&start=bad")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("ts_code=FAIL.SZ")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// TestServer_GetRollingHighLow 测试N日最高最低价接口的参数校验和错误映射
+func TestServer_GetRollingHighLow(t *testing.T) {
+	mockStorage := &MockStorage{
+		GetRollingHighLowFunc: func(ctx context.Context, tsCode string, window int, asOf string) (float64, float64, error) {
+			if tsCode == "NODATA.SZ" {
+				return 0, 0, storage.ErrNoDailyData
+			}
+			assert.Equal(t, "20240131", asOf)
+			return 12.5, 9.8, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/stock/highlow?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("ts_code=000001.SZ&as_of=20240131")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.RollingHighLow `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, models.RollingHighLow{TSCode: "000001.SZ", Window: DefaultHighLowWindow, AsOf: "20240131", High: 12.5, Low: 9.8}, resp.Data)
+
+	assert.Equal(t, http.StatusBadRequest, request("as_of=20240131").Code)
+	assert.Equal(t, http.StatusBadRequest, request("ts_code=000001.SZ&window=0&as_of=20240131").Code)
+	assert.Equal(t, http.StatusBadRequest, request("ts_code=000001.SZ&window=abc&as_of=20240131").Code)
+	assert.Equal(t, http.StatusBadRequest, request("ts_code=000001.SZ&as_of=2024-01-31").Code)
+	assert.Equal(t, http.StatusNotFound, request("ts_code=NODATA.SZ&window=5&as_of=20240131").Code)
+}
+
+// TestServer_GetOpenTradeDates 测试交易日列表接口
+func TestServer_GetOpenTradeDates(t *testing.T) {
+	var gotExchange string
+	mockStorage := &MockStorage{
+		GetOpenTradeDatesFunc: func(ctx context.Context, exchange, start, end string) ([]string, error) {
+			gotExchange = exchange
+			if start == "bad" {
+				return nil, fmt.Errorf("%w: start must be YYYYMMDD", storage.ErrInvalidDateRange)
+			}
+			return []string{"20240102", "20240103"}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/trade-cal/open?"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("start=20240101&end=20240105")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "SSE", gotExchange)
+	var resp struct {
+		Data []string `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"20240102", "20240103"}, resp.Data)
+
+	request("exchange=SZSE&start=20240101&end=20240105")
+	assert.Equal(t, "SZSE", gotExchange)
+
+	// 日期范围不合法
+	assert.Equal(t, http.StatusBadRequest, request("start=bad&end=20240105").Code)
+
+	// 存储错误
+	mockStorage.GetOpenTradeDatesFunc = func(ctx context.Context, exchange, start, end string) ([]string, error) {
+		return nil, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, request("start=20240101&end=20240105").Code)
+}
+
+// TestCompareSourcePrices 测试少于两个数据源时不计算偏离
+func TestCompareSourcePrices(t *testing.T) {
+	comparison := compareSourcePrices(map[string]float64{"binance": 100}, 1)
+	assert.False(t, comparison.Divergent)
+	assert.Empty(t, comparison.MinSource)
+
+	comparison = compareSourcePrices(map[string]float64{"binance": 100, "okx": 100}, 0)
+	assert.Equal(t, "binance", comparison.MinSource)
+	assert.Equal(t, "binance", comparison.MaxSource)
+	assert.Zero(t, comparison.DivergencePct)
+	assert.False(t, comparison.Divergent)
+}
+
+// TestServer_GetSplitAdjustedDaily 测试复权日线接口参数校验
+func TestServer_GetSplitAdjustedDaily(t *testing.T) {
+	mockStorage := &MockStorage{
+		GetSplitAdjustedDailyFunc: func(ctx context.Context, tsCode, start, end string) ([]models.Daily, error) {
+			assert.Equal(t, "000001.SZ", tsCode)
+			assert.Equal(t, "20240101", start)
+			assert.Equal(t, "20240131", end)
+			return []models.Daily{{TSCode: tsCode, TradeDate: "20240102", Close: 10}}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"missing ts_code", "start_date=20240101&end_date=20240131", http.StatusBadRequest},
+		{"invalid start_date", "ts_code=000001.SZ&start_date=2024-01-01&end_date=20240131", http.StatusBadRequest},
+		{"end before start", "ts_code=000001.SZ&start_date=20240131&end_date=20240101", http.StatusBadRequest},
+		{"success", "ts_code=000001.SZ&start_date=20240101&end_date=20240131", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/stock/daily/adjusted?"+tt.query, nil)
+			server.getSplitAdjustedDaily(c)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+// TestServer_GetSplitAdjustedDailyIncludeName 测试include=name时附带股票名称，否则不返回名称
+func TestServer_GetSplitAdjustedDailyIncludeName(t *testing.T) {
+	loads := 0
+	mockStorage := &MockStorage{
+		GetSplitAdjustedDailyFunc: func(ctx context.Context, tsCode, start, end string) ([]models.Daily, error) {
+			return []models.Daily{{TSCode: tsCode, TradeDate: "20240102", Close: 10}, {TSCode: tsCode, TradeDate: "20240103", Close: 11}}, nil
+		},
+		GetStockNamesFunc: func(ctx context.Context) (map[string]string, error) {
+			loads++
+			return map[string]string{"000001.SZ": "平安银行"}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/stock/daily/adjusted?start_date=20240101&end_date=20240131&"+query, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	w := get("ts_code=000001.SZ&include=name")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Data, 2) {
+		assert.Equal(t, "平安银行", resp.Data[0]["name"])
+		assert.Equal(t, "平安银行", resp.Data[1]["name"])
+		assert.Equal(t, 10.0, resp.Data[0]["close"])
+	}
+
+	// 未知股票名称为空，名称映射使用缓存
+	w = get("ts_code=000002.SZ&include=name")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "", resp.Data[0]["name"])
+	assert.Equal(t, 1, loads)
+
+	// 未请求时不返回名称，也不加载名称映射
+	server = NewServer(&MockTushareClient{}, mockStorage)
+	loads = 0
+	w = get("ts_code=000001.SZ")
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp.Data = nil
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Data, 2) {
+		assert.NotContains(t, resp.Data[0], "name")
+	}
+	assert.Zero(t, loads)
+
+	assert.Equal(t, http.StatusBadRequest, get("ts_code=000001.SZ&include=industry").Code)
+}
+
+// TestServer_RecomputeDailyChanges 测试指定股票和全部股票的涨跌幅重新计算
+func TestServer_RecomputeDailyChanges(t *testing.T) {
+	var recomputed []string
+	mockStorage := &MockStorage{
+		GetAllStockCodesFunc: func() ([]string, error) {
+			return []string{"000001.SZ", "000002.SZ", "600000.SH"}, nil
+		},
+		RecomputeDailyChangesFunc: func(ctx context.Context, tsCode string) (int64, error) {
+			recomputed = append(recomputed, tsCode)
+			if tsCode == "600000.SH" {
+				return 0, fmt.Errorf("db error")
+			}
+			return 2, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	// 指定股票
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/stock/daily/recompute", strings.NewReader(`{"ts_codes":["000001.SZ"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	server.recomputeDailyChanges(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response models.APIResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Success)
+	data := response.Data.(map[string]interface{})
+	assert.Equal(t, float64(2), data["updated"])
+	assert.Equal(t, []string{"000001.SZ"}, recomputed)
+
+	// 未指定股票时处理全部股票，失败的股票单独列出
+	recomputed = nil
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/stock/daily/recompute", nil)
+	server.recomputeDailyChanges(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.False(t, response.Success)
+	data = response.Data.(map[string]interface{})
+	assert.Equal(t, float64(3), data["total"])
+	assert.Equal(t, float64(4), data["updated"])
+	assert.Equal(t, []interface{}{"600000.SH"}, data["failed"])
+	assert.Len(t, recomputed, 3)
+
+	// 非法请求体
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/stock/daily/recompute", strings.NewReader(`{"ts_codes":`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	server.recomputeDailyChanges(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestServer_GetDailyColumns 测试按列批量获取日线数据的参数校验和列式返回结构
+func TestServer_GetDailyColumns(t *testing.T) {
+	mockStorage := &MockStorage{
+		GetDailyColumnsFunc: func(ctx context.Context, tsCodes []string, cols []string, start, end string) (*models.DailyColumns, error) {
+			assert.Equal(t, []string{"000001.SZ", "600000.SH"}, tsCodes)
+			assert.Equal(t, []string{"close", "vol"}, cols)
+			return &models.DailyColumns{
+				TSCode:    []string{"000001.SZ", "000001.SZ", "600000.SH"},
+				TradeDate: []string{"20240102", "20240103", "20240102"},
+				Columns: map[string][]float64{
+					"close": {10.0, 10.5, 7.2},
+					"vol":   {1000, 1200, 800},
+				},
+			}, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"missing ts_codes", "cols=close&start_date=20240101&end_date=20240131", http.StatusBadRequest},
+		{"missing cols", "ts_codes=000001.SZ&start_date=20240101&end_date=20240131", http.StatusBadRequest},
+		{"invalid col", "ts_codes=000001.SZ&cols=close,name&start_date=20240101&end_date=20240131", http.StatusBadRequest},
+		{"invalid end_date", "ts_codes=000001.SZ&cols=close&start_date=20240101&end_date=2024-01-31", http.StatusBadRequest},
+		{"success", "ts_codes=000001.SZ,%20600000.SH&cols=close,vol,close&start_date=20240101&end_date=20240131", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/stock/daily/columns?"+tt.query, nil)
+			server.getDailyColumns(c)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+
+	// 列式结构：每列是与ts_code、trade_date等长的数组
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/stock/daily/columns?ts_codes=000001.SZ,600000.SH&cols=close,vol&start_date=20240101&end_date=20240131", nil)
+	server.getDailyColumns(c)
+
+	var response struct {
+		Data models.DailyColumns `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Data.TradeDate, 3)
+	assert.Equal(t, []float64{10.0, 10.5, 7.2}, response.Data.Columns["close"])
+	assert.Equal(t, []float64{1000, 1200, 800}, response.Data.Columns["vol"])
+}
+
+// TestServer_SaveBacktestData 测试保存回测数据返回持久化后的行
+func TestServer_SaveBacktestData(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockStorage := &MockStorage{
+		UpsertBacktestFunc: func(ctx context.Context, data models.BacktestData) (models.BacktestData, error) {
+			if data.Symbol == "" {
+				return models.BacktestData{}, fmt.Errorf("%w: symbol is required", storage.ErrInvalidBacktestData)
+			}
+			assert.Nil(t, data.CreatedAt)
+			data.CreatedAt = &createdAt
+			return data, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/backtest/data", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 客户端传入的created_at被忽略
+	w := post(`{"id":"bt-1","symbol":"BTCUSDT","strategy":"MA Cross","start_date":"2024-01-01T00:00:00Z","end_date":"2024-01-31T00:00:00Z","results":"{}","timestamp":"2024-02-01T00:00:00Z","created_at":"1999-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.BacktestData `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "bt-1", resp.Data.ID)
+	if assert.NotNil(t, resp.Data.CreatedAt) {
+		assert.True(t, createdAt.Equal(*resp.Data.CreatedAt))
+	}
+
+	// 校验失败
+	w = post(`{"id":"bt-1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "symbol is required")
+
+	// 请求体不是JSON
+	assert.Equal(t, http.StatusBadRequest, post(`not json`).Code)
+
+	// 存储错误
+	mockStorage.UpsertBacktestFunc = func(ctx context.Context, data models.BacktestData) (models.BacktestData, error) {
+		return models.BacktestData{}, fmt.Errorf("database error")
+	}
+	assert.Equal(t, http.StatusInternalServerError, post(`{"id":"bt-1","symbol":"BTCUSDT"}`).Code)
+}
+
+// TestServer_GetBacktestBatch 测试按ID批量获取回测数据，不存在的ID被忽略
+func TestServer_GetBacktestBatch(t *testing.T) {
+	stored := map[string]models.BacktestData{
+		"bt-1": {ID: "bt-1", Symbol: "BTCUSDT", Strategy: "MA Cross"},
+		"bt-2": {ID: "bt-2", Symbol: "ETHUSDT", Strategy: "RSI"},
+	}
+	mockStorage := &MockStorage{
+		GetBacktestDataByIDsFunc: func(ctx context.Context, ids []string) ([]models.BacktestData, error) {
+			var data []models.BacktestData
+			for _, id := range ids {
+				if d, ok := stored[id]; ok {
+					data = append(data, d)
+				}
+			}
+			return data, nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/backtest/batch", strings.NewReader(`{"ids":["bt-1","missing","bt-2"]}`))
+	server.getBacktestBatch(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data []models.BacktestData `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var ids []string
+	for _, d := range resp.Data {
+		ids = append(ids, d.ID)
+	}
+	assert.ElementsMatch(t, []string{"bt-1", "bt-2"}, ids)
+
+	// 空列表
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/backtest/batch", strings.NewReader(`{"ids":[]}`))
+	server.getBacktestBatch(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 超过ID数量上限
+	tooMany := make([]string, MaxBacktestBatchIDs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("bt-%d", i)
+	}
+	body, _ := json.Marshal(BacktestBatchRequest{IDs: tooMany})
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/backtest/batch", strings.NewReader(string(body)))
+	server.getBacktestBatch(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "too many ids")
+}
+
+// rateLimitedTushareClient 带限流器的模拟Tushare客户端
+type rateLimitedTushareClient struct {
+	MockTushareClient
+	limiter *datasource.RateLimiter
+}
+
+func (m *rateLimitedTushareClient) RateLimits() []models.RateLimitState {
+	return m.limiter.State()
+}
+
+// TestServer_GetTushareLimits 测试限流状态接口的鉴权和消耗令牌后的状态
+func TestServer_GetTushareLimits(t *testing.T) {
+	client := &rateLimitedTushareClient{limiter: datasource.NewRateLimiter(120)}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, client.limiter.Wait(context.Background(), "daily"))
+	}
+
+	request := func(server *Server, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/admin/tushare/limits", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未配置管理令牌时不可用
+	assert.Equal(t, http.StatusForbidden, request(NewServer(client, &MockStorage{}), "secret").Code)
+
+	server := NewServer(client, &MockStorage{}, WithAdminToken("secret"))
+	assert.Equal(t, http.StatusUnauthorized, request(server, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(server, "wrong").Code)
+
+	w := request(server, "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []models.RateLimitState `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, "daily", response.Data[0].API)
+		assert.Equal(t, 120, response.Data[0].RatePerMinute)
+		assert.Equal(t, int64(3), response.Data[0].Calls)
+		assert.InDelta(t, 117, response.Data[0].AvailableTokens, 0.5)
+		assert.Greater(t, response.Data[0].NextTokenSeconds, 0.0)
+	}
+
+	// 不支持限流状态的客户端返回空列表
+	w = request(NewServer(&MockTushareClient{}, &MockStorage{}, WithAdminToken("secret")), "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"data":[]`)
+}
+
+// TestServer_VersionedRoutes 测试业务接口挂载在/api/v1下，系统路由仍在根路径
+func TestServer_VersionedRoutes(t *testing.T) {
+	server := NewServer(&MockTushareClient{}, &MockStorage{})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/api/v1/market/data?symbol=BTCUSDT", http.StatusOK},
+		{http.MethodGet, "/api/v1/backtest/data?symbol=BTCUSDT", http.StatusOK},
+		{http.MethodGet, "/api/v1/datasource/freshness", http.StatusOK},
+		{http.MethodGet, "/health", http.StatusOK},
+		// 业务接口不再挂载在根路径，系统路由不带版本前缀
+		{http.MethodGet, "/market/data?symbol=BTCUSDT", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/health", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			server.router.ServeHTTP(w, req)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+
+	// Swagger路由挂载在根路径
+	paths := make(map[string]bool)
+	for _, route := range server.router.Routes() {
+		paths[route.Path] = true
+	}
+	assert.True(t, paths["/swagger/*any"])
+	assert.True(t, paths["/swagger.json"])
+}
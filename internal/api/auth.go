@@ -0,0 +1,46 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"quant-data-engine/internal/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdminToken 管理接口鉴权中间件，要求请求头 Authorization: Bearer <ADMIN_TOKEN>
+// 未配置管理令牌时拒绝所有请求，避免管理接口在默认配置下对外开放
+func (s *Server) requireAdminToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.adminToken == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{Error: "Admin API is disabled: ADMIN_TOKEN is not configured"})
+			return
+		}
+
+		if !hasBearerToken(c, s.adminToken) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or missing admin token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireIngestToken 市场数据推送接口鉴权中间件，配置了INGEST_TOKEN时要求请求头 Authorization: Bearer <INGEST_TOKEN>，未配置时不鉴权
+func (s *Server) requireIngestToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.ingestToken != "" && !hasBearerToken(c, s.ingestToken) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or missing ingest token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// hasBearerToken 请求头 Authorization: Bearer <token> 是否与want一致
+func hasBearerToken(c *gin.Context, want string) bool {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
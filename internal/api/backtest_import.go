@@ -0,0 +1,242 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"quant-data-engine/internal/models"
+	"quant-data-engine/internal/storage"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 导入回测数据时单个文件的最大字节数和最大数据行数
+const (
+	MaxBacktestImportBytes = 32 << 20
+	MaxBacktestImportRows  = 10000
+)
+
+// CodeUnsupportedFormat 导入文件的格式无法解析时的错误码
+const CodeUnsupportedFormat = "UNSUPPORTED_FORMAT"
+
+// parquetMagic Parquet文件以"PAR1"开头
+var parquetMagic = []byte("PAR1")
+
+// backtestImportColumns 导入文件必须包含的列，与models.BacktestData的JSON字段一致
+var backtestImportColumns = []string{"id", "symbol", "strategy", "start_date", "end_date", "results", "timestamp"}
+
+// importBacktestData 批量导入回测数据
+// @Summary 导入回测数据
+// @Description 上传CSV文件（multipart字段file，或直接作为请求体）批量导入回测数据。首行为表头，必须包含id、symbol、strategy、start_date、end_date、results、timestamp列，列顺序不限；日期为RFC3339或YYYY-MM-DD格式，results为JSON字符串。非法的行被拒绝并返回行号和原因，其余行在一个事务中保存（按id插入或更新）。单个文件最多10000行、32MB；按内容识别格式，Parquet文件暂不支持，返回415
+// @Tags 回测
+// @Accept text/csv,multipart/form-data
+// @Produce json
+// @Param file formData file false "CSV文件"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /backtest/import [post]
+func (s *Server) importBacktestData(c *gin.Context) {
+	content, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+
+	// 按文件内容识别格式，不依赖Content-Type和文件扩展名
+	if bytes.HasPrefix(content, parquetMagic) {
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+			Error: "Parquet import is not supported, upload the backtest data as CSV",
+			Code:  CodeUnsupportedFormat,
+		})
+		return
+	}
+	if !strings.HasPrefix(http.DetectContentType(content), "text/") {
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+			Error: "Unrecognized file format, upload the backtest data as CSV",
+			Code:  CodeUnsupportedFormat,
+		})
+		return
+	}
+
+	data, rejections, err := parseBacktestCSV(bytes.NewReader(content), MaxBacktestImportRows)
+	if err != nil {
+		invalidBody(c, err.Error())
+		return
+	}
+
+	result := models.BacktestImportResult{
+		Parsed:     len(data) + len(rejections),
+		Rejected:   len(rejections),
+		Rejections: rejections,
+	}
+	if len(data) > 0 {
+		if err := s.storage.SaveBacktestDataBatch(data); err != nil {
+			logrus.Errorf("Failed to import %d backtest data records: %v", len(data), err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save backtest data: " + err.Error()})
+			return
+		}
+		result.Inserted = len(data)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Imported %d of %d backtest rows", result.Inserted, result.Parsed),
+		Data:    result,
+	})
+}
+
+// readImportFile 读取上传的文件：multipart请求读取file字段，否则读取整个请求体
+// 返回false表示已写入错误响应，调用方应直接返回
+func readImportFile(c *gin.Context) ([]byte, bool) {
+	var r io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			invalidBody(c, "multipart field file is required")
+			return nil, false
+		}
+		f, err := header.Open()
+		if err != nil {
+			invalidBody(c, "failed to open uploaded file: "+err.Error())
+			return nil, false
+		}
+		defer f.Close()
+		r = f
+	}
+
+	// 多读一个字节判断是否超过上限
+	content, err := io.ReadAll(io.LimitReader(r, MaxBacktestImportBytes+1))
+	if err != nil {
+		invalidBody(c, "failed to read file: "+err.Error())
+		return nil, false
+	}
+	if len(content) > MaxBacktestImportBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error: fmt.Sprintf("Import file exceeds %d bytes", MaxBacktestImportBytes),
+			Code:  CodeBodyTooLarge,
+		})
+		return nil, false
+	}
+	if len(content) == 0 {
+		invalidBody(c, "file is empty")
+		return nil, false
+	}
+	return content, true
+}
+
+// parseBacktestCSV 解析CSV格式的回测数据，逐行校验，非法的行放入rejections，不影响其他行
+// 表头缺少列、CSV格式错误或数据行超过maxRows时返回错误
+func parseBacktestCSV(r io.Reader, maxRows int) ([]models.BacktestData, []models.BacktestImportRejection, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("CSV header is missing")
+		}
+		return nil, nil, fmt.Errorf("malformed CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	var missing []string
+	for _, name := range backtestImportColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("CSV header is missing columns: %s", strings.Join(missing, ", "))
+	}
+
+	data := []models.BacktestData{}
+	rejections := []models.BacktestImportRejection{}
+	for rows := 0; ; rows++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if rows >= maxRows {
+			return nil, nil, fmt.Errorf("too many rows, maximum is %d", maxRows)
+		}
+		// 列数不一致只影响当前行，其他格式错误无法继续解析
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			rejections = append(rejections, models.BacktestImportRejection{
+				Line:   parseErr.StartLine,
+				Reason: fmt.Sprintf("expected %d fields, got %d", len(header), len(record)),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("malformed CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		d, err := parseBacktestRecord(record, columns)
+		if err == nil {
+			err = storage.ValidateBacktestData(d)
+		}
+		if err != nil {
+			rejections = append(rejections, models.BacktestImportRejection{Line: line, Reason: err.Error()})
+			continue
+		}
+		data = append(data, d)
+	}
+	return data, rejections, nil
+}
+
+// parseBacktestRecord 按表头列位置将一行CSV转换为回测数据
+func parseBacktestRecord(record []string, columns map[string]int) (models.BacktestData, error) {
+	field := func(name string) string {
+		return strings.TrimSpace(record[columns[name]])
+	}
+
+	d := models.BacktestData{
+		ID:       field("id"),
+		Symbol:   field("symbol"),
+		Strategy: field("strategy"),
+		Results:  field("results"),
+	}
+	if d.Results != "" && !json.Valid([]byte(d.Results)) {
+		return d, fmt.Errorf("results is not valid JSON")
+	}
+
+	var err error
+	if d.StartDate, err = parseImportTime(field("start_date")); err != nil {
+		return d, fmt.Errorf("invalid start_date: %w", err)
+	}
+	if d.EndDate, err = parseImportTime(field("end_date")); err != nil {
+		return d, fmt.Errorf("invalid end_date: %w", err)
+	}
+	if d.Timestamp, err = parseImportTime(field("timestamp")); err != nil {
+		return d, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	return d, nil
+}
+
+// parseImportTime 解析RFC3339或YYYY-MM-DD格式的时间，空字符串返回零值，由校验报告缺失
+func parseImportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not RFC3339 or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"quant-data-engine/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CodeInvalidBody 请求体不是合法的JSON、字段类型不匹配或数组元素数不合法时的错误码
+const CodeInvalidBody = "INVALID_BODY"
+
+// MaxRequestItems POST请求体中单个数组的最大元素数，不小于全市场股票数
+const MaxRequestItems = 10000
+
+// bindJSON 解析JSON请求体到req，失败时返回400 INVALID_BODY并附带出错位置
+// 返回false表示已写入错误响应，调用方应直接返回
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		invalidBody(c, describeBindError(err))
+		return false
+	}
+	return true
+}
+
+// checkItemCount 检查数组字段的元素数，allowEmpty为false时空数组同样返回400
+// 返回false表示已写入错误响应，调用方应直接返回
+func checkItemCount(c *gin.Context, field string, n, max int, allowEmpty bool) bool {
+	if n == 0 && !allowEmpty {
+		invalidBody(c, fmt.Sprintf("%s must not be empty", field))
+		return false
+	}
+	if n > max {
+		invalidBody(c, fmt.Sprintf("too many %s: %d, maximum is %d", field, n, max))
+		return false
+	}
+	return true
+}
+
+// invalidBody 写入400 INVALID_BODY响应
+func invalidBody(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body: " + message, Code: CodeInvalidBody})
+}
+
+// describeBindError 将JSON解析错误转换为包含出错位置（字节偏移和字段）的说明
+func describeBindError(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "unexpected end of JSON input"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at offset %d: %v", syntaxErr.Offset, syntaxErr)
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "(root)"
+		}
+		return fmt.Sprintf("field %s at offset %d: expected %s, got JSON %s", field, typeErr.Offset, typeErr.Type, typeErr.Value)
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jsonArray 生成包含n个字符串元素的JSON数组
+func jsonArray(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = `"000001.SZ"`
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+// TestServer_PostBodyValidation 测试各POST接口对非法JSON、空数组和超出上限数组返回400 INVALID_BODY
+func TestServer_PostBodyValidation(t *testing.T) {
+	server := NewServer(&MockTushareClient{}, &MockStorage{}, WithAdminToken("secret"))
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		contains string
+	}{
+		{"backtest data malformed", "/api/v1/backtest/data", `{"id":"bt-1",`, "unexpected end of JSON input"},
+		{"backtest data syntax", "/api/v1/backtest/data", `{"id":"bt-1" "symbol":"BTCUSDT"}`, "malformed JSON at offset 14"},
+		{"backtest data wrong type", "/api/v1/backtest/data", `{"id":123}`, "field id at offset 9: expected string, got JSON number"},
+		{"backtest batch empty body", "/api/v1/backtest/batch", ``, "body is empty"},
+		{"backtest batch malformed", "/api/v1/backtest/batch", `{"ids":[1,}`, "malformed JSON"},
+		{"backtest batch empty array", "/api/v1/backtest/batch", `{"ids":[]}`, "ids must not be empty"},
+		{"backtest batch over limit", "/api/v1/backtest/batch", `{"ids":` + jsonArray(MaxBacktestBatchIDs+1) + `}`, "too many ids"},
+		{"backtest batch root array", "/api/v1/backtest/batch", `["bt-1"]`, "field (root)"},
+		{"ohlcv sync malformed", "/api/v1/sync/ohlcv/full", `{"symbols":`, "unexpected end of JSON input"},
+		{"ohlcv sync wrong type", "/api/v1/sync/ohlcv/full", `{"start_year":"2020"}`, "field start_year"},
+		{"ohlcv sync over limit", "/api/v1/sync/ohlcv/full", `{"symbols":` + jsonArray(MaxRequestItems+1) + `}`, "too many symbols"},
+		{"recompute malformed", "/api/v1/stock/daily/recompute", `{"ts_codes":"000001.SZ"}`, "field ts_codes"},
+		{"recompute over limit", "/api/v1/stock/daily/recompute", `{"ts_codes":` + jsonArray(MaxRequestItems+1) + `}`, "too many ts_codes"},
+		{"backfill malformed", "/api/v1/admin/backfill", `{"start_date":}`, "malformed JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer secret")
+			server.router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var resp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, CodeInvalidBody, resp.Code)
+			assert.Contains(t, resp.Error, tt.contains)
+		})
+	}
+}
+
+// TestCheckItemCount 测试允许空数组的接口不拒绝空数组
+func TestCheckItemCount(t *testing.T) {
+	w := httptest.NewRecorder()
+	server := NewServer(&MockTushareClient{}, &MockStorage{})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/stock/daily/recompute", strings.NewReader(`{"ts_codes":[]}`))
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
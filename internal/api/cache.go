@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"quant-data-engine/internal/models"
+	"sort"
+)
+
+// 内存缓存名称
+const (
+	CacheStockNames = "stock_names"
+)
+
+// ErrUnknownCache 缓存名称不存在
+var ErrUnknownCache = errors.New("unknown cache")
+
+// cache 可在管理接口查看统计和清空的内存缓存
+type cache interface {
+	Stats() models.CacheStats
+	Purge()
+}
+
+// newCacheStats 根据命中和未命中次数生成缓存统计
+func newCacheStats(name string, size int, hits, misses int64) models.CacheStats {
+	stats := models.CacheStats{Name: name, Size: size, Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		stats.HitRate = float64(hits) / float64(total)
+	}
+	return stats
+}
+
+// caches 返回服务器持有的所有内存缓存
+func (s *Server) caches() map[string]cache {
+	return map[string]cache{
+		CacheStockNames: s.stockNames,
+	}
+}
+
+// cacheStats 返回所有缓存的统计，按名称排序
+func (s *Server) cacheStats() []models.CacheStats {
+	caches := s.caches()
+	stats := make([]models.CacheStats, 0, len(caches))
+	for _, c := range caches {
+		stats = append(stats, c.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// purgeCaches 清空指定名称的缓存，name为空时清空全部，返回被清空的缓存名称
+func (s *Server) purgeCaches(name string) ([]string, error) {
+	caches := s.caches()
+	if name != "" {
+		c, ok := caches[name]
+		if !ok {
+			return nil, ErrUnknownCache
+		}
+		c.Purge()
+		return []string{name}, nil
+	}
+
+	names := make([]string, 0, len(caches))
+	for n, c := range caches {
+		c.Purge()
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"quant-data-engine/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxDecompressedBody gzip请求体解压后的默认最大字节数
+const DefaultMaxDecompressedBody = 32 << 20
+
+// CodeBodyTooLarge 请求体解压后超过上限时的错误码
+const CodeBodyTooLarge = "BODY_TOO_LARGE"
+
+// gunzipRequestMiddleware 解压 Content-Encoding: gzip 的请求体，处理函数按未压缩的请求体读取
+// 解压后超过maxSize字节时返回413，防止压缩炸弹耗尽内存；不是合法gzip数据时返回400
+func gunzipRequestMiddleware(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.EqualFold(strings.TrimSpace(c.GetHeader("Content-Encoding")), "gzip") || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		gz, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body: malformed gzip data", Code: CodeInvalidBody})
+			return
+		}
+		defer gz.Close()
+
+		// 多读一个字节判断是否超过上限
+		body, err := io.ReadAll(io.LimitReader(gz, maxSize+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body: malformed gzip data", Code: CodeInvalidBody})
+			return
+		}
+		if int64(len(body)) > maxSize {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+				Error: fmt.Sprintf("Request body exceeds %d bytes after decompression", maxSize),
+				Code:  CodeBodyTooLarge,
+			})
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		c.Next()
+	}
+}
+
+// gzipMiddleware gzip响应压缩中间件
+// 客户端声明Accept-Encoding: gzip时，先缓冲响应体，达到minSize字节才开始压缩，小响应按原样返回；
+// 处理函数调用Flush（流式响应）时立即开始压缩并逐块输出
+func gzipMiddleware(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || !acceptsGzip(c.Request.Header.Get("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		w := &gzipResponseWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = w
+		defer w.finish()
+
+		c.Header("Vary", "Accept-Encoding")
+		c.Next()
+	}
+}
+
+// acceptsGzip 判断Accept-Encoding是否接受gzip（q=0表示明确拒绝）
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		encoding := strings.TrimSpace(fields[0])
+		if encoding != "gzip" && encoding != "*" {
+			continue
+		}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if q, ok := strings.CutPrefix(param, "q="); ok {
+				if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter 延迟决定是否压缩的ResponseWriter
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minSize int
+	buf     bytes.Buffer
+	gz      *gzip.Writer
+	decided bool
+}
+
+// Write 缓冲响应体直到确定是否压缩
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf.Write(data)
+	if w.buf.Len() >= w.minSize {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteString 实现gin.ResponseWriter
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 流式响应：立即开始压缩并输出已写入的数据
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if err := w.startGzip(); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// startGzip 设置压缩响应头，并将已缓冲的数据写入gzip流
+func (w *gzipResponseWriter) startGzip() error {
+	w.decided = true
+	// 处理函数自行设置了编码时不再压缩
+	if w.Header().Get("Content-Encoding") != "" {
+		return w.flushBuffer()
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	if _, err := w.gz.Write(w.buf.Bytes()); err != nil {
+		return err
+	}
+	w.buf.Reset()
+	return nil
+}
+
+// flushBuffer 将缓冲数据原样写出
+func (w *gzipResponseWriter) flushBuffer() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// finish 请求处理结束：未达到压缩阈值的响应原样输出，已压缩的关闭gzip流
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		w.decided = true
+		w.flushBuffer()
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGzipTestRouter 创建启用gzip压缩的测试路由
+func newGzipTestRouter(minSize int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gzipMiddleware(minSize))
+	router.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": strings.Repeat("x", 4096)})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		for i := 0; i < 3; i++ {
+			c.Writer.WriteString("{\"line\":1}\n")
+			c.Writer.Flush()
+		}
+	})
+	return router
+}
+
+// gunzip 解压响应体
+func gunzip(t *testing.T, body io.Reader) string {
+	reader, err := gzip.NewReader(body)
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// TestGzipMiddleware 测试大响应压缩、小响应不压缩
+func TestGzipMiddleware(t *testing.T) {
+	router := newGzipTestRouter(1024)
+
+	// 大响应被压缩
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Less(t, w.Body.Len(), 4096)
+	assert.Contains(t, gunzip(t, w.Body), strings.Repeat("x", 4096))
+
+	// 小响应不压缩
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.JSONEq(t, `{"ok":true}`, w.Body.String())
+
+	// 客户端不接受gzip时不压缩
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/large", nil)
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Body.String(), strings.Repeat("x", 4096))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0, identity")
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+// TestGzipMiddleware_Streaming 测试流式响应在Flush时压缩输出
+func TestGzipMiddleware_Streaming(t *testing.T) {
+	router := newGzipTestRouter(1024)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.True(t, w.Flushed)
+	assert.Equal(t, strings.Repeat("{\"line\":1}\n", 3), gunzip(t, w.Body))
+}
+
+// TestAcceptsGzip 测试Accept-Encoding解析
+func TestAcceptsGzip(t *testing.T) {
+	assert.True(t, acceptsGzip("gzip"))
+	assert.True(t, acceptsGzip("deflate, gzip;q=0.8"))
+	assert.True(t, acceptsGzip("*"))
+	assert.False(t, acceptsGzip(""))
+	assert.False(t, acceptsGzip("deflate, br"))
+	assert.False(t, acceptsGzip("gzip;q=0"))
+}
+
+// gzipBody 压缩请求体
+func gzipBody(t *testing.T, body string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+// TestGunzipRequestMiddleware 测试gzip请求体被解压后交给处理函数，解压后超过上限返回413
+func TestGunzipRequestMiddleware(t *testing.T) {
+	var saved []models.MarketData
+	mockStorage := &MockStorage{
+		SaveMarketDataFunc: func(data []models.MarketData) error {
+			saved = append(saved, data...)
+			return nil
+		},
+	}
+	server := NewServer(&MockTushareClient{}, mockStorage, WithMaxDecompressedBody(1024))
+
+	request := func(body io.Reader, encoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/market/ingest", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 压缩的请求体被解压
+	record := `{"id":"a","symbol":"BTCUSDT","price":100,"volume":1,"timestamp":"2024-01-02T00:00:00Z","source":"binance"}`
+	w := request(gzipBody(t, "["+record+"]"), "gzip")
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, saved, 1) {
+		assert.Equal(t, "BTCUSDT", saved[0].Symbol)
+	}
+
+	// 解压后超过上限，压缩后的大小远小于上限
+	large := "[" + strings.Repeat(record+",", 20) + record + "]"
+	compressed := gzipBody(t, large)
+	assert.Less(t, compressed.Len(), 1024)
+	w = request(compressed, "gzip")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), CodeBodyTooLarge)
+	assert.Len(t, saved, 1)
+
+	// 不是合法的gzip数据
+	w = request(strings.NewReader("["+record+"]"), "gzip")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), CodeInvalidBody)
+
+	// 未压缩的请求不受影响，上限只作用于gzip请求体
+	w = request(strings.NewReader(large), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, saved, 22)
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"quant-data-engine/internal/models"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultDiagnosticsCheckTimeout 诊断接口单个检查项的超时时间，各检查项并发执行
+const DefaultDiagnosticsCheckTimeout = 2 * time.Second
+
+// DatabaseHealth 提供数据库连通性检查和连接池统计
+type DatabaseHealth interface {
+	PingPool(ctx context.Context) error
+	PoolStats() models.DBPoolStats
+}
+
+// JobStatusProvider 提供定时任务的执行情况
+type JobStatusProvider interface {
+	JobStatuses() []models.ScheduledJobStatus
+}
+
+// KafkaProbe 检查Kafka是否可以连接
+type KafkaProbe func(ctx context.Context) error
+
+// WithDatabaseHealth 设置诊断接口使用的数据库连通性检查和连接池统计
+func WithDatabaseHealth(db DatabaseHealth) ServerOption {
+	return func(s *Server) {
+		s.databaseHealth = db
+	}
+}
+
+// WithKafkaProbe 设置诊断接口使用的Kafka连通性检查
+func WithKafkaProbe(probe KafkaProbe) ServerOption {
+	return func(s *Server) {
+		s.kafkaProbe = probe
+	}
+}
+
+// WithJobStatus 设置诊断接口使用的定时任务执行情况来源，通常为定时任务调度器
+func WithJobStatus(provider JobStatusProvider) ServerOption {
+	return func(s *Server) {
+		s.jobStatus = provider
+	}
+}
+
+// diagnosticCheck 一个诊断检查项，返回该子系统的状态；返回错误表示检查未通过，状态仍会返回
+type diagnosticCheck struct {
+	name string
+	run  func(ctx context.Context) (interface{}, error)
+}
+
+// getDiagnostics 汇总各子系统状态
+// @Summary 诊断各子系统状态
+// @Description 一次返回数据库（连通性和连接池统计）、Kafka连通性、定时任务最近一次执行情况、数据处理流水线状态和各数据源数据新鲜度，所有检查项通过时ok为true，否则返回503。各检查项并发执行，每项最多等待2秒；未配置的子系统不检查。需要请求头 Authorization: Bearer <ADMIN_TOKEN>
+// @Tags 管理
+// @Produce json
+// @Param Authorization header string true "Bearer <ADMIN_TOKEN>"
+// @Success 200 {object} models.APIResponse{data=models.DiagnosticsReport}
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 503 {object} models.APIResponse{data=models.DiagnosticsReport}
+// @Router /admin/diagnostics [get]
+func (s *Server) getDiagnostics(c *gin.Context) {
+	report := s.runDiagnostics(c.Request.Context(), time.Now())
+
+	status, message := http.StatusOK, "All subsystems are healthy"
+	if !report.OK {
+		var failed []string
+		for _, check := range report.Checks {
+			if !check.OK {
+				failed = append(failed, check.Name)
+			}
+		}
+		status, message = http.StatusServiceUnavailable, fmt.Sprintf("Unhealthy subsystems: %v", failed)
+		logrus.Warn(message)
+	}
+	c.JSON(status, models.APIResponse{
+		Success: report.OK,
+		Message: message,
+		Data:    report,
+	})
+}
+
+// diagnosticChecks 返回已配置子系统的检查项
+func (s *Server) diagnosticChecks(now time.Time) []diagnosticCheck {
+	var checks []diagnosticCheck
+	if s.databaseHealth != nil {
+		checks = append(checks, diagnosticCheck{"database", func(ctx context.Context) (interface{}, error) {
+			stats := s.databaseHealth.PoolStats()
+			return stats, s.databaseHealth.PingPool(ctx)
+		}})
+	}
+	if s.kafkaProbe != nil {
+		checks = append(checks, diagnosticCheck{"kafka", func(ctx context.Context) (interface{}, error) {
+			return nil, s.kafkaProbe(ctx)
+		}})
+	}
+	if s.jobStatus != nil {
+		checks = append(checks, diagnosticCheck{"scheduler", func(ctx context.Context) (interface{}, error) {
+			jobs := s.jobStatus.JobStatuses()
+			var failed []string
+			for _, job := range jobs {
+				if job.LastError != "" {
+					failed = append(failed, job.Name)
+				}
+			}
+			if len(failed) > 0 {
+				return jobs, fmt.Errorf("last run failed for jobs %v", failed)
+			}
+			return jobs, nil
+		}})
+	}
+	if s.pipeline != nil {
+		checks = append(checks, diagnosticCheck{"pipeline", func(ctx context.Context) (interface{}, error) {
+			status := s.pipeline.Status()
+			// 主动暂停不视为故障
+			if age := now.Sub(status.LastSuccess); !status.Paused && age > s.stalenessThreshold {
+				return status, fmt.Errorf("no successful cycle for %v", age.Round(time.Second))
+			}
+			return status, nil
+		}})
+	}
+	checks = append(checks, diagnosticCheck{"freshness", func(ctx context.Context) (interface{}, error) {
+		latest, err := s.storage.GetSourceFreshness(ctx)
+		if err != nil {
+			return nil, err
+		}
+		freshness := evaluateFreshness(latest, s.stalenessThreshold, now)
+		var stale []string
+		for _, f := range freshness {
+			if f.Stale {
+				stale = append(stale, f.Source)
+			}
+		}
+		if len(stale) > 0 {
+			return freshness, fmt.Errorf("stale data sources %v", stale)
+		}
+		return freshness, nil
+	}})
+	return checks
+}
+
+// runDiagnostics 并发执行所有检查项，每项最多等待diagnosticsTimeout，超时的检查项视为未通过
+func (s *Server) runDiagnostics(ctx context.Context, now time.Time) models.DiagnosticsReport {
+	checks := s.diagnosticChecks(now)
+	results := make([]models.DiagnosticCheck, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runDiagnosticCheck(ctx, s.diagnosticsTimeout, check)
+		}()
+	}
+	wg.Wait()
+
+	report := models.DiagnosticsReport{OK: true, CheckedAt: now, Checks: results}
+	for _, result := range results {
+		report.OK = report.OK && result.OK
+	}
+	return report
+}
+
+// runDiagnosticCheck 执行一个检查项，检查项不响应ctx取消时也在超时后返回，不等待其结束
+func runDiagnosticCheck(ctx context.Context, timeout time.Duration, check diagnosticCheck) models.DiagnosticCheck {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		details interface{}
+		err     error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		details, err := check.run(ctx)
+		done <- outcome{details, err}
+	}()
+
+	result := models.DiagnosticCheck{Name: check.name}
+	select {
+	case o := <-done:
+		result.Details = o.details
+		if o.err != nil {
+			result.Error = o.err.Error()
+		}
+	case <-ctx.Done():
+		result.Error = fmt.Sprintf("check timed out after %v", timeout)
+	}
+	result.OK = result.Error == ""
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"quant-data-engine/internal/models"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultStockNameTTL 股票代码到名称映射的缓存时间
+const DefaultStockNameTTL = 10 * time.Minute
+
+// stockNameCache 缓存股票代码到名称的映射，过期后在下一次查询时整体重新加载
+type stockNameCache struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	load     func(ctx context.Context) (map[string]string, error)
+	names    map[string]string
+	loadedAt time.Time
+	now      func() time.Time
+	// hits 使用未过期缓存的次数，misses 需要从存储加载的次数
+	hits   int64
+	misses int64
+}
+
+// newStockNameCache 创建股票名称缓存
+func newStockNameCache(ttl time.Duration, load func(ctx context.Context) (map[string]string, error)) *stockNameCache {
+	return &stockNameCache{ttl: ttl, load: load, now: time.Now}
+}
+
+// Names 返回股票代码到名称的映射，缓存过期或未加载时从存储加载
+// 加载失败时如有旧数据则继续使用旧数据
+func (c *stockNameCache) Names(ctx context.Context) (map[string]string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.names != nil && c.now().Sub(c.loadedAt) < c.ttl {
+		c.hits++
+		return c.names, nil
+	}
+	c.misses++
+
+	names, err := c.load(ctx)
+	if err != nil {
+		if c.names != nil {
+			logrus.Warnf("Failed to reload stock names, using cached names: %v", err)
+			return c.names, nil
+		}
+		return nil, err
+	}
+	c.names = names
+	c.loadedAt = c.now()
+	return names, nil
+}
+
+// Stats 返回缓存的条目数和命中统计
+func (c *stockNameCache) Stats() models.CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return newCacheStats(CacheStockNames, len(c.names), c.hits, c.misses)
+}
+
+// Purge 清空缓存，下一次查询时重新加载；命中统计保留
+func (c *stockNameCache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.names = nil
+	c.loadedAt = time.Time{}
+}
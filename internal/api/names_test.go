@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStockNameCache 测试名称映射在过期前使用缓存，过期后重新加载，加载失败时沿用旧数据
+func TestStockNameCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loads := 0
+	var loadErr error
+	cache := newStockNameCache(time.Minute, func(ctx context.Context) (map[string]string, error) {
+		loads++
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return map[string]string{"000001.SZ": fmt.Sprintf("v%d", loads)}, nil
+	})
+	cache.now = func() time.Time { return now }
+
+	names, err := cache.Names(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", names["000001.SZ"])
+
+	now = now.Add(30 * time.Second)
+	names, _ = cache.Names(context.Background())
+	assert.Equal(t, "v1", names["000001.SZ"])
+	assert.Equal(t, 1, loads)
+
+	now = now.Add(time.Minute)
+	names, _ = cache.Names(context.Background())
+	assert.Equal(t, "v2", names["000001.SZ"])
+
+	// 过期后加载失败时沿用旧数据
+	now = now.Add(time.Minute)
+	loadErr = fmt.Errorf("database error")
+	names, err = cache.Names(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", names["000001.SZ"])
+
+	// 从未加载成功时返回错误
+	empty := newStockNameCache(time.Minute, func(ctx context.Context) (map[string]string, error) {
+		return nil, loadErr
+	})
+	_, err = empty.Names(context.Background())
+	assert.Error(t, err)
+}
+
+// TestStockNameCache_StatsAndPurge 测试命中统计和清空缓存后重新加载
+func TestStockNameCache_StatsAndPurge(t *testing.T) {
+	loads := 0
+	cache := newStockNameCache(time.Minute, func(ctx context.Context) (map[string]string, error) {
+		loads++
+		return map[string]string{"000001.SZ": "平安银行", "600000.SH": "浦发银行"}, nil
+	})
+
+	assert.Equal(t, CacheStockNames, cache.Stats().Name)
+	assert.Equal(t, 0.0, cache.Stats().HitRate)
+
+	for i := 0; i < 4; i++ {
+		_, err := cache.Names(context.Background())
+		assert.NoError(t, err)
+	}
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, 0.75, stats.HitRate)
+
+	// 清空后条目数为0，下一次查询重新加载
+	cache.Purge()
+	assert.Equal(t, 0, cache.Stats().Size)
+	_, err := cache.Names(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loads)
+	assert.Equal(t, int64(2), cache.Stats().Misses)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"quant-data-engine/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cursorPrefix 游标编码前缀，游标对客户端不透明
+const cursorPrefix = "offset:"
+
+// ErrInvalidPage 分页参数不合法
+var ErrInvalidPage = errors.New("invalid pagination")
+
+// Paginator 列表接口的分页参数解析器
+// limit的含义：缺省或0时使用DefaultLimit，不是整数或为负数时返回错误，超过MaxLimit时截断为MaxLimit；
+// all=true表示返回允许的最大条数，即limit取MaxLimit
+type Paginator struct {
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// Page 解析后的分页参数
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// NewPaginator 创建分页参数解析器
+func NewPaginator(defaultLimit, maxLimit int) Paginator {
+	if defaultLimit < 1 {
+		defaultLimit = 1
+	}
+	if maxLimit < defaultLimit {
+		maxLimit = defaultLimit
+	}
+	return Paginator{DefaultLimit: defaultLimit, MaxLimit: maxLimit}
+}
+
+// Parse 解析分页参数，cursor优先于offset；limit或offset不是非负整数、all不是布尔值或cursor格式错误时返回错误
+func (p Paginator) Parse(c *gin.Context) (Page, error) {
+	page := Page{Limit: p.DefaultLimit}
+
+	limit, err := nonNegativeQuery(c, "limit")
+	if err != nil {
+		return Page{}, fmt.Errorf("%w: %v", ErrInvalidPage, err)
+	}
+	if limit > 0 {
+		page.Limit = min(limit, p.MaxLimit)
+	}
+
+	if all := c.Query("all"); all != "" {
+		fetchAll, err := strconv.ParseBool(all)
+		if err != nil {
+			return Page{}, fmt.Errorf("%w: all must be true or false", ErrInvalidPage)
+		}
+		if fetchAll {
+			page.Limit = p.MaxLimit
+		}
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		page.Offset = offset
+		return page, nil
+	}
+
+	offset, err := nonNegativeQuery(c, "offset")
+	if err != nil {
+		return Page{}, fmt.Errorf("%w: %v", ErrInvalidPage, err)
+	}
+	page.Offset = offset
+	return page, nil
+}
+
+// Meta 根据总数生成分页元数据，还有下一页时给出next_cursor
+func (p Page) Meta(total int64) models.PageMeta {
+	meta := models.PageMeta{
+		Limit:   p.Limit,
+		Offset:  p.Offset,
+		Total:   total,
+		HasMore: int64(p.Offset+p.Limit) < total,
+	}
+	if meta.HasMore {
+		meta.NextCursor = encodeCursor(p.Offset + p.Limit)
+	}
+	return meta
+}
+
+// encodeCursor 将偏移量编码为游标
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// decodeCursor 解码游标得到偏移量
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, fmt.Errorf("%w: invalid cursor", ErrInvalidPage)
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: invalid cursor", ErrInvalidPage)
+	}
+	return offset, nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPaginationContext 创建带查询参数的测试上下文
+func newPaginationContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest(http.MethodGet, "/list?"+query, nil)
+	return c
+}
+
+// TestPaginator_Parse 测试分页参数默认值和截断
+func TestPaginator_Parse(t *testing.T) {
+	p := NewPaginator(10, 100)
+
+	// 默认值
+	page, err := p.Parse(newPaginationContext(""))
+	require.NoError(t, err)
+	assert.Equal(t, Page{Limit: 10, Offset: 0}, page)
+
+	// 正常值
+	page, err = p.Parse(newPaginationContext("limit=20&offset=40"))
+	require.NoError(t, err)
+	assert.Equal(t, Page{Limit: 20, Offset: 40}, page)
+
+	// 超过最大值时截断
+	page, err = p.Parse(newPaginationContext("limit=5000"))
+	require.NoError(t, err)
+	assert.Equal(t, 100, page.Limit)
+
+	// 非整数或负数返回错误
+	for _, query := range []string{"limit=abc", "offset=abc", "offset=-5", "limit=1.5"} {
+		_, err = p.Parse(newPaginationContext(query))
+		assert.ErrorIs(t, err, ErrInvalidPage, query)
+	}
+
+	// 0表示使用默认值
+	page, err = p.Parse(newPaginationContext("limit=0"))
+	require.NoError(t, err)
+	assert.Equal(t, 10, page.Limit)
+
+	// 负数返回错误
+	_, err = p.Parse(newPaginationContext("limit=-1"))
+	assert.ErrorIs(t, err, ErrInvalidPage)
+
+	// all=true取最大值，优先于limit
+	page, err = p.Parse(newPaginationContext("all=true&limit=5&offset=20"))
+	require.NoError(t, err)
+	assert.Equal(t, Page{Limit: 100, Offset: 20}, page)
+
+	page, err = p.Parse(newPaginationContext("all=false&limit=5"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, page.Limit)
+
+	_, err = p.Parse(newPaginationContext("all=yes"))
+	assert.ErrorIs(t, err, ErrInvalidPage)
+
+	// cursor优先于offset
+	page, err = p.Parse(newPaginationContext("offset=5&cursor=" + encodeCursor(30)))
+	require.NoError(t, err)
+	assert.Equal(t, 30, page.Offset)
+
+	// 非法cursor
+	_, err = p.Parse(newPaginationContext("cursor=not-a-cursor"))
+	assert.ErrorIs(t, err, ErrInvalidPage)
+
+	// 构造参数校验
+	assert.Equal(t, Paginator{DefaultLimit: 1, MaxLimit: 1}, NewPaginator(0, 0))
+}
+
+// TestPage_Meta 测试分页元数据计算
+func TestPage_Meta(t *testing.T) {
+	meta := Page{Limit: 10, Offset: 0}.Meta(25)
+	assert.Equal(t, int64(25), meta.Total)
+	assert.True(t, meta.HasMore)
+	offset, err := decodeCursor(meta.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, 10, offset)
+
+	meta = Page{Limit: 10, Offset: 20}.Meta(25)
+	assert.False(t, meta.HasMore)
+	assert.Empty(t, meta.NextCursor)
+
+	meta = Page{Limit: 10, Offset: 15}.Meta(25)
+	assert.False(t, meta.HasMore)
+
+	meta = Page{Limit: 10, Offset: 0}.Meta(0)
+	assert.False(t, meta.HasMore)
+}
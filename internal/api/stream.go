@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"quant-data-engine/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// DailySubscriber 订阅新保存的日线，返回的函数取消订阅
+type DailySubscriber interface {
+	Subscribe(filter func(models.Daily) bool, buffer int) (<-chan models.Daily, func())
+}
+
+// 日线实时推送的心跳间隔和每个连接缓冲的日线条数，缓冲区满时丢弃新日线
+const (
+	DefaultDailyStreamHeartbeat = 15 * time.Second
+	dailyStreamBuffer           = 256
+)
+
+// WithDailyStream 设置日线事件来源，启用日线实时推送接口
+func WithDailyStream(subscriber DailySubscriber) ServerOption {
+	return func(s *Server) {
+		s.dailyStream = subscriber
+	}
+}
+
+// streamDaily 通过Server-Sent Events推送新保存的日线
+// @Summary 实时推送日线
+// @Description 以Server-Sent Events（text/event-stream）推送该股票之后新保存到daily表的日线（例如日线任务运行时），每条日线为一个daily事件，data为日线JSON；空闲时每15秒发送一条心跳注释保持连接。客户端断开时停止推送。未启用时返回503
+// @Tags 股票
+// @Produce text/event-stream
+// @Param ts_code query string true "股票代码，例如 000001.SZ"
+// @Success 200 {string} string "event: daily"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /stock/daily/stream [get]
+func (s *Server) streamDaily(c *gin.Context) {
+	if s.dailyStream == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Daily stream is not available"})
+		return
+	}
+	tsCode := c.Query("ts_code")
+	if tsCode == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "ts_code is required"})
+		return
+	}
+
+	bars, unsubscribe := s.dailyStream.Subscribe(func(d models.Daily) bool { return d.TSCode == tsCode }, dailyStreamBuffer)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// 关闭反向代理（nginx）的响应缓冲
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, ": streaming daily bars for %s\n\n", tsCode)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(s.dailyStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	logrus.Debugf("Daily stream opened for %s", tsCode)
+	defer logrus.Debugf("Daily stream closed for %s", tsCode)
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case bar, ok := <-bars:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(bar)
+			if err != nil {
+				logrus.Errorf("Failed to marshal daily bar %s %s: %v", bar.TSCode, bar.TradeDate, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: daily\nid: %s\ndata: %s\n\n", bar.TradeDate, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// 回补任务状态
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// DefaultRateInterval 两次Tushare调用之间的默认间隔
+const DefaultRateInterval = 250 * time.Millisecond
+
+// ErrJobNotFound 回补任务不存在
+var ErrJobNotFound = errors.New("backfill job not found")
+
+// ErrJobNotResumable 回补任务正在运行或已完成，不能恢复
+var ErrJobNotResumable = errors.New("backfill job is running or completed")
+
+// ErrTooManyJobs 运行中的回补任务数已达到上限
+var ErrTooManyJobs = errors.New("too many active backfill jobs")
+
+// DefaultMaxActive 默认同时运行的回补任务数上限
+const DefaultMaxActive = 2
+
+// DefaultFinishedTTL 已结束的任务在内存中保留的默认时间，超过后从内存移除，进度仍可从存储查询
+const DefaultFinishedTTL = time.Hour
+
+// Store 回补任务依赖的存储接口
+type Store interface {
+	GetAllStockCodes() ([]string, error)
+	SaveOHLCVDailyQFQ(data []models.OHLCVDailyQFQ) error
+	SaveAdjFactors(data []models.AdjFactor) error
+	SaveBackfillProgress(progress models.BackfillProgress) error
+	GetBackfillProgress(id string) (*models.BackfillProgress, error)
+}
+
+// job 运行中的回补任务
+type job struct {
+	mutex    sync.Mutex
+	progress models.BackfillProgress
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// snapshot 返回当前进度的副本
+func (j *job) snapshot() models.BackfillProgress {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.progress
+}
+
+// update 在锁保护下修改进度并返回修改后的副本
+func (j *job) update(fn func(p *models.BackfillProgress)) models.BackfillProgress {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	fn(&j.progress)
+	j.progress.UpdatedAt = time.Now()
+	return j.progress
+}
+
+// Manager 全市场日线回补任务管理器
+// 任务逐只股票按年分段拉取日线和复权因子，保存前复权数据，每完成一只股票写入一次检查点
+type Manager struct {
+	client       datasource.TushareClientInterface
+	store        Store
+	rateInterval time.Duration
+	mutex        sync.RWMutex
+	jobs         map[string]*job
+	// active 运行中的任务，任务结束（完成、失败或取消）时移除；maxActive为同时运行的任务数上限
+	active    map[string]*job
+	maxActive int
+	// finishedTTL 已结束的任务在jobs中保留的时间，启动任务或查询进度时清理过期的任务
+	finishedTTL time.Duration
+}
+
+// NewManager 创建回补任务管理器
+func NewManager(client datasource.TushareClientInterface, store Store, rateInterval time.Duration) *Manager {
+	return &Manager{
+		client:       client,
+		store:        store,
+		rateInterval: rateInterval,
+		jobs:         make(map[string]*job),
+		active:       make(map[string]*job),
+		maxActive:    DefaultMaxActive,
+		finishedTTL:  DefaultFinishedTTL,
+	}
+}
+
+// SetFinishedTTL 设置已结束的任务在内存中保留的时间，ttl<=0时忽略
+func (m *Manager) SetFinishedTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.finishedTTL = ttl
+}
+
+// prune 移除结束时间早于finishedTTL的任务，运行中的任务不受影响
+func (m *Manager) prune() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for id, j := range m.jobs {
+		if _, running := m.active[id]; running {
+			continue
+		}
+		progress := j.snapshot()
+		if progress.Status != StatusRunning && time.Since(progress.UpdatedAt) > m.finishedTTL {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// SetMaxActive 设置同时运行的任务数上限，n<1时忽略；已运行的任务不受影响
+func (m *Manager) SetMaxActive(n int) {
+	if n < 1 {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.maxActive = n
+}
+
+// Active 返回运行中任务的进度，按创建时间排序
+func (m *Manager) Active() []models.BackfillProgress {
+	m.mutex.RLock()
+	active := make([]models.BackfillProgress, 0, len(m.active))
+	for _, j := range m.active {
+		active = append(active, j.snapshot())
+	}
+	m.mutex.RUnlock()
+
+	sort.Slice(active, func(a, b int) bool {
+		return active[a].CreatedAt.Before(active[b].CreatedAt)
+	})
+	return active
+}
+
+// register 占用一个运行名额，任务已在运行时返回ErrJobNotResumable，达到上限时返回ErrTooManyJobs
+func (m *Manager) register(j *job) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	id := j.progress.ID
+	if _, ok := m.active[id]; ok {
+		return ErrJobNotResumable
+	}
+	if len(m.active) >= m.maxActive {
+		return fmt.Errorf("%w: limit is %d", ErrTooManyJobs, m.maxActive)
+	}
+	m.active[id] = j
+	return nil
+}
+
+// deregister 释放任务占用的运行名额
+func (m *Manager) deregister(j *job) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.active, j.progress.ID)
+}
+
+// Start 启动新的回补任务，日期格式为YYYYMMDD
+func (m *Manager) Start(startDate, endDate string) (models.BackfillProgress, error) {
+	if _, err := yearSegments(startDate, endDate); err != nil {
+		return models.BackfillProgress{}, err
+	}
+
+	progress := models.BackfillProgress{
+		ID:        uuid.New().String(),
+		StartDate: startDate,
+		EndDate:   endDate,
+		Status:    StatusRunning,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	return m.launch(progress)
+}
+
+// Resume 从检查点恢复已取消或失败的回补任务
+func (m *Manager) Resume(id string) (models.BackfillProgress, error) {
+	m.mutex.RLock()
+	j, running := m.jobs[id]
+	m.mutex.RUnlock()
+	if running && j.snapshot().Status == StatusRunning {
+		return models.BackfillProgress{}, ErrJobNotResumable
+	}
+
+	progress, err := m.store.GetBackfillProgress(id)
+	if err != nil {
+		return models.BackfillProgress{}, err
+	}
+	if progress == nil {
+		return models.BackfillProgress{}, ErrJobNotFound
+	}
+	if progress.Status == StatusCompleted {
+		return models.BackfillProgress{}, ErrJobNotResumable
+	}
+
+	progress.Status = StatusRunning
+	progress.CurrentCode = ""
+	return m.launch(*progress)
+}
+
+// launch 获取股票列表并在后台运行任务
+func (m *Manager) launch(progress models.BackfillProgress) (models.BackfillProgress, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		progress: progress,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	// 先占用运行名额再访问存储，并发启动时运行中的任务数不会超过上限
+	if err := m.register(j); err != nil {
+		cancel()
+		return models.BackfillProgress{}, err
+	}
+
+	codes, err := m.store.GetAllStockCodes()
+	if err != nil {
+		m.deregister(j)
+		cancel()
+		return models.BackfillProgress{}, fmt.Errorf("failed to get stock codes: %w", err)
+	}
+	sort.Strings(codes)
+	progress = j.update(func(p *models.BackfillProgress) { p.Total = len(codes) })
+
+	if err := m.store.SaveBackfillProgress(progress); err != nil {
+		m.deregister(j)
+		cancel()
+		return models.BackfillProgress{}, err
+	}
+
+	m.prune()
+	m.mutex.Lock()
+	m.jobs[progress.ID] = j
+	m.mutex.Unlock()
+
+	go m.run(ctx, j, codes)
+
+	logrus.Infof("Backfill job %s started for %d stocks from %s to %s (resume after %q)",
+		progress.ID, len(codes), progress.StartDate, progress.EndDate, progress.LastCode)
+	return progress, nil
+}
+
+// Get 获取任务进度，优先返回内存中的实时进度
+func (m *Manager) Get(id string) (*models.BackfillProgress, error) {
+	m.prune()
+	m.mutex.RLock()
+	j, ok := m.jobs[id]
+	m.mutex.RUnlock()
+	if ok {
+		progress := j.snapshot()
+		return &progress, nil
+	}
+
+	progress, err := m.store.GetBackfillProgress(id)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		return nil, ErrJobNotFound
+	}
+	return progress, nil
+}
+
+// Cancel 取消运行中的任务，任务保存检查点后退出，未处理完的股票在恢复时重新回补
+func (m *Manager) Cancel(id string) error {
+	m.mutex.RLock()
+	j, ok := m.jobs[id]
+	m.mutex.RUnlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+	j.cancel()
+	return nil
+}
+
+// run 逐只股票执行回补
+func (m *Manager) run(ctx context.Context, j *job, codes []string) {
+	defer close(j.done)
+	defer j.cancel()
+	// 保存最终状态后释放运行名额，done关闭时任务已不在运行列表中
+	defer m.deregister(j)
+
+	start := j.snapshot()
+	lastCode := start.LastCode
+	segments, err := yearSegments(start.StartDate, start.EndDate)
+	if err != nil {
+		j.update(func(p *models.BackfillProgress) { p.LastError = err.Error() })
+		m.finish(j, StatusFailed)
+		return
+	}
+
+	for _, code := range codes {
+		// 断点续传：跳过检查点之前已完成的股票
+		if lastCode != "" && code <= lastCode {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			m.finish(j, StatusCancelled)
+			return
+		}
+
+		j.update(func(p *models.BackfillProgress) { p.CurrentCode = code })
+
+		err := m.backfillCode(ctx, code, segments)
+		if ctx.Err() != nil {
+			// 被取消的股票不计入完成，下次从该股票重新开始
+			m.finish(j, StatusCancelled)
+			return
+		}
+
+		progress := j.update(func(p *models.BackfillProgress) {
+			p.Done++
+			p.LastCode = code
+			if err != nil {
+				p.Errors++
+				p.LastError = fmt.Sprintf("%s: %v", code, err)
+			}
+		})
+		if err != nil {
+			logrus.Errorf("Backfill job %s failed for %s: %v", progress.ID, code, err)
+		}
+
+		if err := m.store.SaveBackfillProgress(progress); err != nil {
+			logrus.Errorf("Failed to save backfill checkpoint for job %s: %v", progress.ID, err)
+			m.finish(j, StatusFailed)
+			return
+		}
+	}
+
+	m.finish(j, StatusCompleted)
+}
+
+// finish 设置任务最终状态并保存
+func (m *Manager) finish(j *job, status string) {
+	progress := j.update(func(p *models.BackfillProgress) {
+		p.Status = status
+		p.CurrentCode = ""
+	})
+	if err := m.store.SaveBackfillProgress(progress); err != nil {
+		logrus.Errorf("Failed to save backfill progress for job %s: %v", progress.ID, err)
+	}
+	logrus.Infof("Backfill job %s %s: %d/%d stocks done, %d errors",
+		progress.ID, status, progress.Done, progress.Total, progress.Errors)
+}
+
+// backfillCode 按年分段回补单只股票的前复权日线
+func (m *Manager) backfillCode(ctx context.Context, code string, segments [][2]string) error {
+	var errs []error
+	for _, segment := range segments {
+		if err := m.backfillSegment(ctx, code, segment[0], segment[1]); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// backfillSegment 回补单只股票一个日期分段的数据
+func (m *Manager) backfillSegment(ctx context.Context, code, startDate, endDate string) error {
+	if err := m.wait(ctx); err != nil {
+		return err
+	}
+	dailyResp, err := m.client.GetDaily(ctx, &datasource.DailyRequest{
+		TSCode:    code,
+		StartDate: startDate,
+		EndDate:   endDate,
+	}, []string{"ts_code", "trade_date", "open", "high", "low", "close", "vol", "amount"})
+	if err != nil {
+		return fmt.Errorf("failed to fetch daily %s-%s: %w", startDate, endDate, err)
+	}
+	if dailyResp == nil || dailyResp.Data == nil || len(dailyResp.Data.Items) == 0 {
+		return nil
+	}
+
+	if err := m.wait(ctx); err != nil {
+		return err
+	}
+	adjResp, err := m.client.GetAdjFactor(ctx, &datasource.AdjFactorRequest{
+		TSCode:    code,
+		StartDate: startDate,
+		EndDate:   endDate,
+	}, []string{"ts_code", "trade_date", "adj_factor"})
+	if err != nil {
+		return fmt.Errorf("failed to fetch adj_factor %s-%s: %w", startDate, endDate, err)
+	}
+	if err := m.store.SaveAdjFactors(datasource.ParseAdjFactors(adjResp)); err != nil {
+		return fmt.Errorf("failed to save adj_factor %s-%s: %w", startDate, endDate, err)
+	}
+
+	bars := datasource.BuildQFQBars(dailyResp, adjResp)
+	if len(bars) == 0 {
+		return nil
+	}
+	if err := m.store.SaveOHLCVDailyQFQ(bars); err != nil {
+		return fmt.Errorf("failed to save %s-%s: %w", startDate, endDate, err)
+	}
+	return nil
+}
+
+// wait 速率限制：在两次API调用之间等待rateInterval，可被ctx取消
+func (m *Manager) wait(ctx context.Context) error {
+	if m.rateInterval <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(m.rateInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// yearSegments 将YYYYMMDD日期范围按自然年切分，避免单次请求超出Tushare返回行数上限
+func yearSegments(startDate, endDate string) ([][2]string, error) {
+	start, err := time.Parse("20060102", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start_date %q: %w", startDate, err)
+	}
+	end, err := time.Parse("20060102", endDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end_date %q: %w", endDate, err)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end_date must be after start_date")
+	}
+
+	var segments [][2]string
+	for year := start.Year(); year <= end.Year(); year++ {
+		segStart := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		if segStart.Before(start) {
+			segStart = start
+		}
+		segEnd := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+		if segEnd.After(end) {
+			segEnd = end
+		}
+		segments = append(segments, [2]string{segStart.Format("20060102"), segEnd.Format("20060102")})
+	}
+	return segments, nil
+}
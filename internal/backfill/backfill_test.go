@@ -0,0 +1,329 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTushareClient 模拟Tushare客户端，为每只股票返回一根日线
+type mockTushareClient struct {
+	mutex      sync.Mutex
+	dailyCalls []string
+	failCode   string
+}
+
+func (m *mockTushareClient) GetStockBasic(ctx context.Context, req *datasource.StockBasicRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetTradeCal(ctx context.Context, req *datasource.TradeCalRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetNewShare(ctx context.Context, req *datasource.NewShareRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetStockCompany(ctx context.Context, req *datasource.StockCompanyRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetStkManagers(ctx context.Context, req *datasource.StkManagersRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetStkRewards(ctx context.Context, req *datasource.StkRewardsRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetProBar(ctx context.Context, req *datasource.ProBarRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetDaily(ctx context.Context, req *datasource.DailyRequest, fields []string) (*datasource.TushareResponse, error) {
+	m.mutex.Lock()
+	m.dailyCalls = append(m.dailyCalls, req.TSCode)
+	m.mutex.Unlock()
+
+	if req.TSCode == m.failCode {
+		return nil, fmt.Errorf("API error")
+	}
+	return &datasource.TushareResponse{
+		Data: &datasource.DataResult{
+			Fields: []string{"ts_code", "trade_date", "open", "high", "low", "close", "vol", "amount"},
+			Items:  [][]interface{}{{req.TSCode, req.StartDate, 10.0, 11.0, 9.0, 10.5, 1000.0, 10000.0}},
+		},
+	}, nil
+}
+
+func (m *mockTushareClient) GetAdjFactor(ctx context.Context, req *datasource.AdjFactorRequest, fields []string) (*datasource.TushareResponse, error) {
+	return &datasource.TushareResponse{
+		Data: &datasource.DataResult{
+			Fields: []string{"ts_code", "trade_date", "adj_factor"},
+			Items:  [][]interface{}{{req.TSCode, req.StartDate, 2.0}},
+		},
+	}, nil
+}
+
+func (m *mockTushareClient) GetMoneyflow(ctx context.Context, req *datasource.MoneyflowRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) calls() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]string(nil), m.dailyCalls...)
+}
+
+// memoryStore 内存存储，记录每次保存的检查点
+type memoryStore struct {
+	mutex       sync.Mutex
+	codes       []string
+	bars        []models.OHLCVDailyQFQ
+	factors     []models.AdjFactor
+	progress    map[string]models.BackfillProgress
+	checkpoints []models.BackfillProgress
+}
+
+func newMemoryStore(codes ...string) *memoryStore {
+	return &memoryStore{codes: codes, progress: make(map[string]models.BackfillProgress)}
+}
+
+func (s *memoryStore) GetAllStockCodes() ([]string, error) {
+	return s.codes, nil
+}
+
+func (s *memoryStore) SaveOHLCVDailyQFQ(data []models.OHLCVDailyQFQ) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bars = append(s.bars, data...)
+	return nil
+}
+
+func (s *memoryStore) SaveAdjFactors(data []models.AdjFactor) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.factors = append(s.factors, data...)
+	return nil
+}
+
+func (s *memoryStore) SaveBackfillProgress(progress models.BackfillProgress) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.progress[progress.ID] = progress
+	s.checkpoints = append(s.checkpoints, progress)
+	return nil
+}
+
+func (s *memoryStore) GetBackfillProgress(id string) (*models.BackfillProgress, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p, ok := s.progress[id]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// waitJob 等待任务结束
+func waitJob(t *testing.T, m *Manager, id string) {
+	m.mutex.RLock()
+	j := m.jobs[id]
+	m.mutex.RUnlock()
+	select {
+	case <-j.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("backfill job did not finish")
+	}
+}
+
+// TestManager_Progress 测试任务进度汇报和每只股票的检查点保存
+func TestManager_Progress(t *testing.T) {
+	client := &mockTushareClient{failCode: "000002.SZ"}
+	store := newMemoryStore("000003.SZ", "000001.SZ", "000002.SZ")
+	m := NewManager(client, store, 0)
+
+	progress, err := m.Start("20230601", "20240131")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, progress.Status)
+	assert.Equal(t, 3, progress.Total)
+
+	waitJob(t, m, progress.ID)
+
+	got, err := m.Get(progress.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, got.Status)
+	assert.Equal(t, 3, got.Done)
+	assert.Equal(t, 1, got.Errors)
+	assert.Contains(t, got.LastError, "000002.SZ")
+	assert.Equal(t, "000003.SZ", got.LastCode)
+
+	// 按代码顺序处理，每只股票按年分两段
+	assert.Equal(t, []string{"000001.SZ", "000001.SZ", "000002.SZ", "000002.SZ", "000003.SZ", "000003.SZ"}, client.calls())
+	assert.Len(t, store.bars, 4)
+	assert.Len(t, store.factors, 4)
+	assert.Equal(t, 21.0, store.bars[0].Close)
+
+	// 初始记录 + 每只股票一个检查点 + 最终状态
+	require.Len(t, store.checkpoints, 5)
+	assert.Equal(t, "000001.SZ", store.checkpoints[1].LastCode)
+	assert.Equal(t, 1, store.checkpoints[1].Done)
+	assert.Equal(t, "000002.SZ", store.checkpoints[2].LastCode)
+	assert.Equal(t, StatusCompleted, store.checkpoints[4].Status)
+}
+
+// TestManager_Resume 测试从持久化的检查点恢复任务
+func TestManager_Resume(t *testing.T) {
+	client := &mockTushareClient{}
+	store := newMemoryStore("000001.SZ", "000002.SZ", "000003.SZ")
+	store.progress["job-1"] = models.BackfillProgress{
+		ID:        "job-1",
+		StartDate: "20240101",
+		EndDate:   "20240131",
+		Status:    StatusCancelled,
+		Total:     3,
+		Done:      2,
+		LastCode:  "000002.SZ",
+	}
+	m := NewManager(client, store, 0)
+
+	_, err := m.Resume("job-1")
+	require.NoError(t, err)
+	waitJob(t, m, "job-1")
+
+	// 只回补检查点之后的股票
+	assert.Equal(t, []string{"000003.SZ"}, client.calls())
+	got, err := m.Get("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, got.Status)
+	assert.Equal(t, 3, got.Done)
+
+	// 已完成的任务不能再次恢复
+	_, err = m.Resume("job-1")
+	assert.ErrorIs(t, err, ErrJobNotResumable)
+
+	_, err = m.Resume("missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+// TestManager_Cancel 测试取消任务后保存检查点
+func TestManager_Cancel(t *testing.T) {
+	client := &mockTushareClient{}
+	store := newMemoryStore("000001.SZ", "000002.SZ")
+	m := NewManager(client, store, time.Hour)
+
+	progress, err := m.Start("20240101", "20240131")
+	require.NoError(t, err)
+	require.NoError(t, m.Cancel(progress.ID))
+	waitJob(t, m, progress.ID)
+
+	got, err := m.Get(progress.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, got.Status)
+	assert.Equal(t, 0, got.Done)
+	assert.Empty(t, client.calls())
+
+	stored, err := store.GetBackfillProgress(progress.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, stored.Status)
+
+	assert.ErrorIs(t, m.Cancel("missing"), ErrJobNotFound)
+}
+
+// TestManager_MaxActive 测试运行中的任务数达到上限时拒绝新任务，任务结束后释放名额
+func TestManager_MaxActive(t *testing.T) {
+	client := &mockTushareClient{}
+	store := newMemoryStore("000001.SZ")
+	// 速率间隔很长，任务启动后一直运行直到被取消
+	m := NewManager(client, store, time.Hour)
+	m.SetMaxActive(2)
+
+	first, err := m.Start("20240101", "20240131")
+	require.NoError(t, err)
+	second, err := m.Start("20240101", "20240131")
+	require.NoError(t, err)
+
+	_, err = m.Start("20240101", "20240131")
+	assert.ErrorIs(t, err, ErrTooManyJobs)
+
+	active := m.Active()
+	require.Len(t, active, 2)
+	assert.ElementsMatch(t, []string{first.ID, second.ID}, []string{active[0].ID, active[1].ID})
+
+	// 运行中的任务不能重复恢复
+	_, err = m.Resume(first.ID)
+	assert.ErrorIs(t, err, ErrJobNotResumable)
+
+	// 取消后释放名额
+	require.NoError(t, m.Cancel(first.ID))
+	waitJob(t, m, first.ID)
+	active = m.Active()
+	require.Len(t, active, 1)
+	assert.Equal(t, second.ID, active[0].ID)
+
+	third, err := m.Start("20240101", "20240131")
+	require.NoError(t, err)
+	assert.Len(t, m.Active(), 2)
+
+	require.NoError(t, m.Cancel(second.ID))
+	require.NoError(t, m.Cancel(third.ID))
+	waitJob(t, m, second.ID)
+	waitJob(t, m, third.ID)
+	assert.Empty(t, m.Active())
+}
+
+// TestManager_FinishedTTL 测试已结束的任务超过保留时间后从内存移除，进度仍可从存储查询
+func TestManager_FinishedTTL(t *testing.T) {
+	client := &mockTushareClient{}
+	store := newMemoryStore("000001.SZ")
+	m := NewManager(client, store, time.Hour)
+	m.SetFinishedTTL(10 * time.Millisecond)
+
+	finished, err := m.Start("20240101", "20240131")
+	require.NoError(t, err)
+	require.NoError(t, m.Cancel(finished.ID))
+	waitJob(t, m, finished.ID)
+
+	running, err := m.Start("20240101", "20240131")
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+
+	got, err := m.Get(finished.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, got.Status)
+
+	m.mutex.RLock()
+	_, finishedKept := m.jobs[finished.ID]
+	_, runningKept := m.jobs[running.ID]
+	m.mutex.RUnlock()
+	assert.False(t, finishedKept)
+	assert.True(t, runningKept)
+
+	require.NoError(t, m.Cancel(running.ID))
+	waitJob(t, m, running.ID)
+}
+
+// TestYearSegments 测试按年切分日期范围
+func TestYearSegments(t *testing.T) {
+	segments, err := yearSegments("20220615", "20240110")
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{
+		{"20220615", "20221231"},
+		{"20230101", "20231231"},
+		{"20240101", "20240110"},
+	}, segments)
+
+	_, err = yearSegments("20240110", "20220615")
+	assert.Error(t, err)
+
+	_, err = yearSegments("2024-01-01", "20240110")
+	assert.Error(t, err)
+}
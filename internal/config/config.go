@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -21,83 +23,364 @@ type Config struct {
 	KafkaBrokers string
 	KafkaTopic   string
 	KafkaRetries int
+	// 并发序列化和提交消息的worker数，相同Key的消息由同一个worker按顺序处理
+	KafkaProduceWorkers int
+	// 分区策略：any（默认，由客户端分区器决定）或consistent（按Key哈希对KafkaPartitions取模）
+	KafkaPartitioner string
+	KafkaPartitions  int
+	// Kafka client.id，用于在broker的指标和日志中区分实例，默认为 quant-data-engine-<主机名>
+	KafkaClientID string
+	// 投递失败的市场数据消息转发到的死信主题，为空时不转发
+	KafkaDLQTopic string
+	// 严格保序：启用幂等生产者并限制每个连接只有1个在途请求，保证同一交易对的消息按发送顺序写入，要求consistent分区策略
+	KafkaStrictOrdering bool
 
 	// API配置
 	APIPort    string
 	APITimeout int
+	// 响应压缩：GzipEnabled为false时不压缩，小于GzipMinSize字节的响应不压缩
+	GzipEnabled bool
+	GzipMinSize int
+	// gzip请求体解压后的最大字节数
+	MaxDecompressedBody int
+	// 市场数据接口单次最多返回的条数
+	MarketDataMaxLimit int
+	// 同时运行的后台任务（回补任务）数上限，达到上限时启动新任务返回429
+	MaxActiveJobs int
 
 	// 数据源配置
 	ExchangeAPIKey    string
 	ExchangeAPISecret string
 	TushareAPIKey     string
+	// Tushare接口地址，可设置为镜像或代理地址（可包含路径），TUSHARE_API_URL为别名
+	TushareBaseURL    string
 	DataSourceTimeout int
+	// 每个Tushare接口每分钟最多调用次数，0表示不限流
+	TushareRateLimit int
+	// Tushare请求遇到网络错误或限流时的最多重试次数和首次重试前的等待毫秒数（之后指数增长）
+	TushareRetries     int
+	TushareRetryBaseMs int
+	// 分页拉取Tushare数据时每页的条数
+	TusharePageSize int
+	// Binance K线数据源：BinanceKlinesEnabled为false时binance使用模拟数据
+	BinanceKlinesEnabled bool
+	BinanceBaseURL       string
+	BinanceKlineInterval string
+	// 每分钟最多请求Binance K线接口的次数，0表示不限流
+	BinanceRateLimit int
+	// OKX REST接口地址，okx数据源从 /api/v5/market/ticker 获取最新成交价
+	OKXBaseURL string
+	// 数据源最新数据距今超过该秒数时视为停止更新
+	DataStalenessSeconds int
 
 	// 数据处理配置
 	ProcessingInterval int
-	MaxSymbols         int
+	// 数据处理的交易对，逗号分隔，为空时使用DefaultSymbols；最多处理MaxSymbols个，通过Symbols()读取
+	SymbolList string
+	MaxSymbols int
+	// 交易对映射种子文件（JSON），启动时写入symbol_map表，为空时只使用表中已有的映射
+	SymbolMapFile string
+	// 定时任务数据输出方式：db（默认）、kafka、both
+	SchedulerSinkMode string
+	// 定时任务的cron表达式：获取股票列表、获取当天日线
+	StockListCron string
+	DailyCron     string
+	// 是否启用日线定时任务，以及日线批量写入的每批条数
+	DailyJobEnabled bool
+	DailyChunkSize  int
+	// 是否保存数据源的原始响应（gzip压缩），保留RawResponseRetentionHours小时，按RawResponseCleanupCron清理
+	PersistRawResponses       bool
+	RawResponseRetentionHours int
+	RawResponseCleanupCron    string
+	// 校验失败的市场数据输出方式：table（默认）、kafka、none
+	RejectSinkMode   string
+	RejectKafkaTopic string
+
+	// 负载削减配置：连续LoadShedSlowSaves次保存耗时超过LoadShedLatencyMs时放慢处理节奏
+	LoadShedLatencyMs int
+	LoadShedSlowSaves int
+	LoadShedMaxFactor int
+
+	// SLA告警配置：超过SLAThresholdSeconds没有成功的数据处理周期时告警，0表示不启用
+	SLAThresholdSeconds int
+	SLAAlertWebhookURL  string
+	SLAAlertKafkaTopic  string
+
+	// 流水线错误通知配置：NotifyWebhookURL为空时不发送通知，低于NotifyMinLevel的通知被忽略，
+	// 相同通知在NotifyThrottleSeconds内只发送一次，交易对连续失败NotifyFailureThreshold次时通知
+	NotifyWebhookURL       string
+	NotifyMinLevel         string
+	NotifyThrottleSeconds  int
+	NotifyFailureThreshold int
+
+	// 事务性发件箱配置：启用后市场数据与发件箱在同一事务中写入，由转发器发送到Kafka
+	OutboxEnabled         bool
+	OutboxRelayIntervalMs int
+	OutboxBatchSize       int
+
+	// 市场数据去重键：id（默认，按id去重）或natural（按symbol、timestamp、source去重，启动时创建唯一索引）
+	// MarketDataKeyDropIndex 为id时是否删除已有的natural唯一索引，为false时存在该索引则启动失败
+	MarketDataKey          string
+	MarketDataKeyDropIndex bool
+
+	// 数据库与Kafka双写一致性检查：每ConsistencyCheckIntervalSec秒抽样ConsistencySamplePercent%最近保存的市场数据（最多ConsistencySampleLimit条），
+	// 检查独立消费组ConsistencyGroupID是否从Kafka读到；保存不到ConsistencyGraceSec秒的数据留到下次检查，等待发送完成
+	ConsistencyCheckEnabled     bool
+	ConsistencyCheckIntervalSec int
+	ConsistencySamplePercent    int
+	ConsistencySampleLimit      int
+	ConsistencyGraceSec         int
+	ConsistencyGroupID          string
+
+	// 写缓冲配置：累积WriteBufferSize条或最早的数据超过WriteBufferMaxAgeMs毫秒时一次性保存，WriteBufferSize为0时不缓冲
+	WriteBufferSize     int
+	WriteBufferMaxAgeMs int
+
+	// 查询配置：MaxHistoricalWindowDays为历史数据单次查询的最大时间跨度（天），0表示不限制
+	MaxHistoricalRows       int
+	MaxHistoricalWindowDays int
+
+	// 管理接口鉴权令牌，为空时需要鉴权的管理接口不可用
+	AdminToken string
+	// 市场数据推送接口鉴权令牌，为空时不鉴权
+	IngestToken string
+
+	// 优雅关闭时每个子系统（数据处理、HTTP服务、Kafka）的最长等待秒数
+	ShutdownTimeout int
+
+	// 启动自检配置：SelfCheckEnabled为true时启动前检查数据库、Kafka和Tushare token，任一失败则退出
+	SelfCheckEnabled bool
+	// 单个检查项的最长等待秒数
+	SelfCheckTimeout int
+	// 是否调用一次Tushare接口验证token有效，会消耗调用额度
+	SelfCheckTushareProbe bool
 
 	// 日志配置
 	LogLevel string
+
+	// 配置冲突检查：.env文件与环境变量取值不一致时记录日志，ConfigStrict为true时
+	// ConfigCriticalKeys（逗号分隔）中的配置不一致则启动失败
+	ConfigStrict       bool
+	ConfigCriticalKeys string
 }
 
 var AppConfig *Config
 
 func LoadConfig() error {
-	// 加载.env文件
-	if err := godotenv.Load(); err != nil {
-		// 尝试从项目根目录加载
-		if err := godotenv.Load(".env"); err != nil {
-			// 尝试从上级目录加载
-			if err := godotenv.Load("../.env"); err != nil {
-				// 尝试从两级上级目录加载
-				if err := godotenv.Load("../../.env"); err != nil {
-					logrus.Warn("No .env file found, using environment variables")
+	conflicts := loadDotEnv()
+
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+	if err := reportConflicts(conflicts, cfg.ConfigStrict, cfg.ConfigCriticalKeys); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	AppConfig = cfg
+	mutex.Unlock()
+
+	// 设置日志级别
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logrus.Warn("Invalid log level, using info")
+		level = logrus.InfoLevel
+	}
+	logrus.SetLevel(level)
+
+	return nil
+}
+
+// envFiles 依次尝试加载的.env文件，使用第一个存在的文件
+var envFiles = []string{".env", "../.env", "../../.env"}
+
+// dotenvValues 由.env文件写入的环境变量及其值
+// 重新加载时只覆盖仍为该值的变量，进程启动时已设置的环境变量始终优先于.env文件
+var dotenvValues = map[string]string{}
+
+// loadDotEnv 从项目根目录或上级目录的.env文件加载环境变量
+// 返回.env文件与已设置的环境变量取值不一致的配置，这些配置使用环境变量的值
+func loadDotEnv() []Conflict {
+	for _, path := range envFiles {
+		values, err := godotenv.Read(path)
+		if err != nil {
+			continue
+		}
+		var conflicts []Conflict
+		for key, value := range values {
+			current, set := os.LookupEnv(key)
+			if previous, fromFile := dotenvValues[key]; set && !(fromFile && current == previous) {
+				if current != value {
+					conflicts = append(conflicts, Conflict{Key: key, FileValue: value, EnvValue: current})
 				}
+				continue
 			}
+			os.Setenv(key, value)
+			dotenvValues[key] = value
 		}
+		sortConflicts(conflicts)
+		return conflicts
+	}
+	logrus.Warn("No .env file found, using environment variables")
+	return nil
+}
+
+// load 从环境变量读取配置
+func load() (*Config, error) {
+	// 密钥配置：*_FILE 指向挂载的密钥文件（Kubernetes/Docker secrets），优先于同名环境变量
+	dbPassword, err := getSecret("DB_PASSWORD", "DB_PASSWORD_FILE", "password")
+	if err != nil {
+		return nil, err
+	}
+	exchangeAPISecret, err := getSecret("EXCHANGE_API_SECRET", "EXCHANGE_API_SECRET_FILE", "")
+	if err != nil {
+		return nil, err
+	}
+	// TUSHARE_API_KEY和TUSHARE_TOKEN是TUSHARE_API_TOKEN的别名，同时设置时按此顺序优先
+	tushareAPIKey, err := getSecret("TUSHARE_API_TOKEN", "TUSHARE_API_KEY_FILE", getEnv("TUSHARE_API_KEY", getEnv("TUSHARE_TOKEN", "")))
+	if err != nil {
+		return nil, err
+	}
+	adminToken, err := getSecret("ADMIN_TOKEN", "ADMIN_TOKEN_FILE", "")
+	if err != nil {
+		return nil, err
+	}
+	ingestToken, err := getSecret("INGEST_TOKEN", "INGEST_TOKEN_FILE", "")
+	if err != nil {
+		return nil, err
 	}
 
-	AppConfig = &Config{
+	return &Config{
 		// 数据库配置
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "password"),
+		DBPassword: dbPassword,
 		DBName:     getEnv("DB_NAME", "quant_data"),
 		DBMaxConns: getEnvAsInt("DB_MAX_CONNS", 10),
 
 		// Kafka配置
-		KafkaBrokers: getEnv("KAFKA_BROKERS", "localhost:9092"),
-		KafkaTopic:   getEnv("KAFKA_TOPIC", "quant_data"),
-		KafkaRetries: getEnvAsInt("KAFKA_RETRIES", 3),
+		KafkaBrokers:        getEnv("KAFKA_BROKERS", "localhost:9092"),
+		KafkaTopic:          getEnv("KAFKA_TOPIC", "quant_data"),
+		KafkaRetries:        getEnvAsInt("KAFKA_RETRIES", 3),
+		KafkaProduceWorkers: getEnvAsInt("KAFKA_PRODUCE_WORKERS", 4),
+		KafkaPartitioner:    getEnv("KAFKA_PARTITIONER", "any"),
+		KafkaPartitions:     getEnvAsInt("KAFKA_PARTITIONS", 0),
+		KafkaClientID:       getEnv("KAFKA_CLIENT_ID", defaultKafkaClientID()),
+		KafkaDLQTopic:       getEnv("KAFKA_DLQ_TOPIC", ""),
+		KafkaStrictOrdering: getEnvAsBool("KAFKA_STRICT_ORDERING", false),
 
 		// API配置
-		APIPort:    getEnv("API_PORT", "8080"),
-		APITimeout: getEnvAsInt("API_TIMEOUT", 30),
+		APIPort:     getEnv("API_PORT", "8080"),
+		APITimeout:  getEnvAsInt("API_TIMEOUT", 30),
+		GzipEnabled: getEnvAsBool("GZIP_ENABLED", true),
+		GzipMinSize: getEnvAsInt("GZIP_MIN_SIZE", 1024),
+
+		MaxDecompressedBody: getEnvAsInt("MAX_DECOMPRESSED_BODY", 32<<20),
+
+		MarketDataMaxLimit: getEnvAsInt("MARKET_DATA_MAX_LIMIT", 1000),
+
+		MaxActiveJobs: getEnvAsInt("MAX_ACTIVE_JOBS", 2),
 
 		// 数据源配置
-		ExchangeAPIKey:    getEnv("EXCHANGE_API_KEY", ""),
-		ExchangeAPISecret: getEnv("EXCHANGE_API_SECRET", ""),
-		TushareAPIKey:     getEnv("TUSHARE_API_TOKEN", ""),
-		DataSourceTimeout: getEnvAsInt("DATA_SOURCE_TIMEOUT", 10),
+		ExchangeAPIKey:       getEnv("EXCHANGE_API_KEY", ""),
+		ExchangeAPISecret:    exchangeAPISecret,
+		TushareAPIKey:        tushareAPIKey,
+		TushareBaseURL:       getEnv("TUSHARE_BASE_URL", getEnv("TUSHARE_API_URL", "https://api.tushare.pro")),
+		DataSourceTimeout:    getEnvAsInt("DATA_SOURCE_TIMEOUT", 10),
+		TushareRateLimit:     getEnvAsInt("TUSHARE_RATE_LIMIT", 120),
+		TushareRetries:       getEnvAsInt("TUSHARE_RETRIES", 3),
+		TushareRetryBaseMs:   getEnvAsInt("TUSHARE_RETRY_BASE_MS", 500),
+		TusharePageSize:      getEnvAsInt("TUSHARE_PAGE_SIZE", 5000),
+		BinanceKlinesEnabled: getEnvAsBool("BINANCE_KLINES_ENABLED", false),
+		BinanceBaseURL:       getEnv("BINANCE_BASE_URL", "https://api.binance.com"),
+		BinanceKlineInterval: getEnv("BINANCE_KLINE_INTERVAL", "1h"),
+		BinanceRateLimit:     getEnvAsInt("BINANCE_RATE_LIMIT", 300),
+		OKXBaseURL:           getEnv("OKX_BASE_URL", "https://www.okx.com"),
+		DataStalenessSeconds: getEnvAsInt("DATA_STALENESS_SECONDS", 300),
 
 		// 数据处理配置
 		ProcessingInterval: getEnvAsInt("PROCESSING_INTERVAL", 30),
+		SymbolList:         getEnv("SYMBOLS", ""),
 		MaxSymbols:         getEnvAsInt("MAX_SYMBOLS", 10),
+		SymbolMapFile:      getEnv("SYMBOL_MAP_FILE", ""),
+		SchedulerSinkMode:  getEnv("SCHEDULER_SINK_MODE", "db"),
+		StockListCron:      getEnv("STOCK_LIST_CRON", "*/30 * * * *"),
+		DailyCron:          getEnv("DAILY_CRON", "0 17 * * 1-5"),
+		DailyJobEnabled:    getEnvAsBool("DAILY_JOB_ENABLED", false),
+		DailyChunkSize:     getEnvAsInt("DAILY_CHUNK_SIZE", 5000),
+		RejectSinkMode:     getEnv("REJECT_SINK_MODE", "table"),
+		RejectKafkaTopic:   getEnv("REJECT_KAFKA_TOPIC", "quant_data_rejected"),
+
+		// 原始响应配置
+		PersistRawResponses:       getEnvAsBool("PERSIST_RAW_RESPONSES", false),
+		RawResponseRetentionHours: getEnvAsInt("RAW_RESPONSE_RETENTION_HOURS", 72),
+		RawResponseCleanupCron:    getEnv("RAW_RESPONSE_CLEANUP_CRON", "@hourly"),
+
+		// 负载削减配置
+		LoadShedLatencyMs: getEnvAsInt("LOAD_SHED_LATENCY_MS", 2000),
+		LoadShedSlowSaves: getEnvAsInt("LOAD_SHED_SLOW_SAVES", 3),
+		LoadShedMaxFactor: getEnvAsInt("LOAD_SHED_MAX_FACTOR", 8),
+
+		// SLA告警配置
+		SLAThresholdSeconds: getEnvAsInt("SLA_THRESHOLD_SECONDS", 300),
+		SLAAlertWebhookURL:  getEnv("SLA_ALERT_WEBHOOK_URL", ""),
+		SLAAlertKafkaTopic:  getEnv("SLA_ALERT_KAFKA_TOPIC", ""),
+
+		// 流水线错误通知配置
+		NotifyWebhookURL:       getEnv("NOTIFY_WEBHOOK_URL", ""),
+		NotifyMinLevel:         getEnv("NOTIFY_MIN_LEVEL", "warning"),
+		NotifyThrottleSeconds:  getEnvAsInt("NOTIFY_THROTTLE_SECONDS", 300),
+		NotifyFailureThreshold: getEnvAsInt("NOTIFY_FAILURE_THRESHOLD", 3),
+
+		// 事务性发件箱配置
+		OutboxEnabled:         getEnvAsBool("OUTBOX_ENABLED", false),
+		OutboxRelayIntervalMs: getEnvAsInt("OUTBOX_RELAY_INTERVAL_MS", 1000),
+		OutboxBatchSize:       getEnvAsInt("OUTBOX_BATCH_SIZE", 100),
+
+		// 市场数据去重键
+		MarketDataKey:          getEnv("MARKET_DATA_KEY", "id"),
+		MarketDataKeyDropIndex: getEnvAsBool("MARKET_DATA_KEY_DROP_INDEX", false),
+
+		// 双写一致性检查配置
+		ConsistencyCheckEnabled:     getEnvAsBool("CONSISTENCY_CHECK_ENABLED", false),
+		ConsistencyCheckIntervalSec: getEnvAsInt("CONSISTENCY_CHECK_INTERVAL_SECONDS", 300),
+		ConsistencySamplePercent:    getEnvAsInt("CONSISTENCY_SAMPLE_PERCENT", 10),
+		ConsistencySampleLimit:      getEnvAsInt("CONSISTENCY_SAMPLE_LIMIT", 500),
+		ConsistencyGraceSec:         getEnvAsInt("CONSISTENCY_GRACE_SECONDS", 60),
+		ConsistencyGroupID:          getEnv("CONSISTENCY_GROUP_ID", "quant-data-engine-consistency"),
+
+		// 写缓冲配置
+		WriteBufferSize:     getEnvAsInt("WRITE_BUFFER_SIZE", 0),
+		WriteBufferMaxAgeMs: getEnvAsInt("WRITE_BUFFER_MAX_AGE_MS", 5000),
+
+		// 查询配置
+		MaxHistoricalRows:       getEnvAsInt("MAX_HISTORICAL_ROWS", 100000),
+		MaxHistoricalWindowDays: getEnvAsInt("MAX_HISTORICAL_WINDOW_DAYS", 90),
+
+		// 接口鉴权
+		AdminToken:  adminToken,
+		IngestToken: ingestToken,
+
+		// 优雅关闭配置
+		ShutdownTimeout: getEnvAsInt("SHUTDOWN_TIMEOUT", 5),
+
+		// 启动自检配置
+		SelfCheckEnabled:      getEnvAsBool("SELF_CHECK_ENABLED", true),
+		SelfCheckTimeout:      getEnvAsInt("SELF_CHECK_TIMEOUT", 5),
+		SelfCheckTushareProbe: getEnvAsBool("SELF_CHECK_TUSHARE_PROBE", false),
 
 		// 日志配置
 		LogLevel: getEnv("LOG_LEVEL", "info"),
-	}
 
-	// 设置日志级别
-	level, err := logrus.ParseLevel(AppConfig.LogLevel)
-	if err != nil {
-		logrus.Warn("Invalid log level, using info")
-		level = logrus.InfoLevel
-	}
-	logrus.SetLevel(level)
-
-	return nil
+		// 配置冲突检查
+		ConfigStrict:       getEnvAsBool("CONFIG_STRICT", false),
+		ConfigCriticalKeys: getEnv("CONFIG_CRITICAL_KEYS", DefaultCriticalKeys),
+	}, nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -107,6 +390,27 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// defaultKafkaClientID 默认的Kafka client.id，包含主机名以区分实例，获取主机名失败时不带后缀
+func defaultKafkaClientID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "quant-data-engine"
+	}
+	return "quant-data-engine-" + hostname
+}
+
+// getSecret 读取密钥：设置了fileKey时从该文件读取（去除首尾空白），否则读取环境变量key
+func getSecret(key, fileKey, defaultValue string) (string, error) {
+	if path := os.Getenv(fileKey); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", fileKey, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	return getEnv(key, defaultValue), nil
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSecret 在临时目录写入密钥文件
+func writeSecret(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// TestLoadConfig_SecretFiles 测试从密钥文件读取凭证并覆盖环境变量
+func TestLoadConfig_SecretFiles(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "inline-password")
+	t.Setenv("DB_PASSWORD_FILE", writeSecret(t, "db_password", "file-password\n"))
+	t.Setenv("EXCHANGE_API_SECRET", "inline-secret")
+	t.Setenv("EXCHANGE_API_SECRET_FILE", writeSecret(t, "exchange_secret", "  file-secret  "))
+	t.Setenv("TUSHARE_API_TOKEN", "inline-token")
+	t.Setenv("TUSHARE_API_KEY_FILE", writeSecret(t, "tushare_token", "file-token\r\n"))
+
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, "file-password", AppConfig.DBPassword)
+	assert.Equal(t, "file-secret", AppConfig.ExchangeAPISecret)
+	assert.Equal(t, "file-token", AppConfig.TushareAPIKey)
+}
+
+// TestLoadConfig_InlineSecrets 测试未设置密钥文件时使用环境变量
+func TestLoadConfig_InlineSecrets(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "inline-password")
+	t.Setenv("DB_PASSWORD_FILE", "")
+	t.Setenv("TUSHARE_API_TOKEN", "inline-token")
+	t.Setenv("TUSHARE_API_KEY_FILE", "")
+
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, "inline-password", AppConfig.DBPassword)
+	assert.Equal(t, "inline-token", AppConfig.TushareAPIKey)
+}
+
+// TestLoadConfig_TushareAliases 测试Tushare token和接口地址可以通过别名环境变量设置
+func TestLoadConfig_TushareAliases(t *testing.T) {
+	t.Setenv("TUSHARE_API_TOKEN", "")
+	t.Setenv("TUSHARE_API_KEY_FILE", "")
+	t.Setenv("TUSHARE_API_KEY", "")
+	t.Setenv("TUSHARE_TOKEN", "alias-token")
+	t.Setenv("TUSHARE_BASE_URL", "")
+	t.Setenv("TUSHARE_API_URL", "http://127.0.0.1:9000/tushare")
+
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, "alias-token", AppConfig.TushareAPIKey)
+	assert.Equal(t, "http://127.0.0.1:9000/tushare", AppConfig.TushareBaseURL)
+
+	// TUSHARE_API_KEY优先于TUSHARE_TOKEN，TUSHARE_API_TOKEN和TUSHARE_BASE_URL优先于别名
+	t.Setenv("TUSHARE_API_KEY", "key-token")
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, "key-token", AppConfig.TushareAPIKey)
+
+	t.Setenv("TUSHARE_API_TOKEN", "primary-token")
+	t.Setenv("TUSHARE_BASE_URL", "https://tushare.example.com")
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, "primary-token", AppConfig.TushareAPIKey)
+	assert.Equal(t, "https://tushare.example.com", AppConfig.TushareBaseURL)
+}
+
+// TestLoadConfig_MissingSecretFile 测试密钥文件不存在时返回错误
+func TestLoadConfig_MissingSecretFile(t *testing.T) {
+	t.Setenv("DB_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	err := LoadConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "DB_PASSWORD_FILE")
+}
+
+// TestReload_LogLevel 测试重新加载时立即应用新的日志级别
+func TestReload_LogLevel(t *testing.T) {
+	defer logrus.SetLevel(logrus.GetLevel())
+
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("PROCESSING_INTERVAL", "30")
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, logrus.InfoLevel, logrus.GetLevel())
+
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("PROCESSING_INTERVAL", "10")
+	reloaded, err := Reload()
+	require.NoError(t, err)
+	assert.Equal(t, Reloadable{LogLevel: "debug", ProcessingInterval: 10}, reloaded)
+	assert.Equal(t, reloaded, Current())
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+	// 非法值不生效
+	t.Setenv("LOG_LEVEL", "verbose")
+	_, err = Reload()
+	assert.ErrorIs(t, err, ErrInvalidReload)
+	assert.Equal(t, "debug", Current().LogLevel)
+}
+
+// TestReload_RejectsDBChange 测试修改数据库配置时拒绝重新加载，可重新加载的字段也保持不变
+func TestReload_RejectsDBChange(t *testing.T) {
+	defer logrus.SetLevel(logrus.GetLevel())
+
+	t.Setenv("DB_HOST", "db-1")
+	t.Setenv("LOG_LEVEL", "info")
+	require.NoError(t, LoadConfig())
+
+	t.Setenv("DB_HOST", "db-2")
+	t.Setenv("LOG_LEVEL", "debug")
+	_, err := Reload()
+	assert.ErrorIs(t, err, ErrNonReloadableChange)
+	assert.Contains(t, err.Error(), "DBHost")
+	assert.Equal(t, "db-1", AppConfig.DBHost)
+	assert.Equal(t, "info", Current().LogLevel)
+	assert.Equal(t, logrus.InfoLevel, logrus.GetLevel())
+}
+
+// TestLoadConfig_KafkaClientID 测试Kafka client.id默认包含主机名，设置后使用指定值
+func TestLoadConfig_KafkaClientID(t *testing.T) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	t.Setenv("KAFKA_CLIENT_ID", "")
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, "quant-data-engine-"+hostname, AppConfig.KafkaClientID)
+
+	t.Setenv("KAFKA_CLIENT_ID", "ingest-1")
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, "ingest-1", AppConfig.KafkaClientID)
+}
+
+// useEnvFile 让loadDotEnv只读取临时目录中内容为content的.env文件，并忘记之前由.env文件写入的环境变量
+func useEnvFile(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	previousFiles, previousValues := envFiles, dotenvValues
+	envFiles, dotenvValues = []string{path}, map[string]string{}
+	t.Cleanup(func() { envFiles, dotenvValues = previousFiles, previousValues })
+}
+
+// TestLoadConfig_EnvFileConflicts 测试.env文件与环境变量不一致时报告冲突并使用环境变量，严格模式下关键配置冲突返回错误
+func TestLoadConfig_EnvFileConflicts(t *testing.T) {
+	useEnvFile(t, "DB_NAME=prod\nKAFKA_BROKERS=kafka-prod:9092\nDB_HOST=db-1\nDB_PASSWORD=file-password\n")
+	t.Setenv("DB_NAME", "dev")
+	t.Setenv("KAFKA_BROKERS", "localhost:9092")
+	t.Setenv("DB_HOST", "db-1")
+	t.Setenv("DB_PASSWORD", "env-password")
+	t.Setenv("DB_PASSWORD_FILE", "")
+	t.Setenv("TUSHARE_API_TOKEN", "token")
+	t.Setenv("TUSHARE_API_KEY_FILE", "")
+	t.Setenv("CONFIG_STRICT", "false")
+	t.Setenv("CONFIG_CRITICAL_KEYS", "")
+
+	// 取值相同的DB_HOST不算冲突
+	assert.Equal(t, []Conflict{
+		{Key: "DB_NAME", FileValue: "prod", EnvValue: "dev"},
+		{Key: "DB_PASSWORD", FileValue: "file-password", EnvValue: "env-password"},
+		{Key: "KAFKA_BROKERS", FileValue: "kafka-prod:9092", EnvValue: "localhost:9092"},
+	}, loadDotEnv())
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, "dev", AppConfig.DBName)
+	assert.Equal(t, "localhost:9092", AppConfig.KafkaBrokers)
+	var messages []string
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Contains(t, messages, `Config DB_NAME differs: .env file has "prod", environment has "dev", using the environment value`)
+	assert.Contains(t, messages, `Config DB_PASSWORD differs: .env file has ***, environment has ***, using the environment value`)
+
+	// 严格模式：默认关键配置冲突时启动失败
+	t.Setenv("CONFIG_STRICT", "true")
+	t.Setenv("CONFIG_CRITICAL_KEYS", DefaultCriticalKeys)
+	err := LoadConfig()
+	assert.ErrorIs(t, err, ErrConfigConflict)
+	assert.Contains(t, err.Error(), "DB_NAME, KAFKA_BROKERS")
+	assert.NotContains(t, err.Error(), "DB_PASSWORD")
+
+	// 关键配置不包含冲突的配置时只记录日志
+	t.Setenv("CONFIG_CRITICAL_KEYS", "DB_HOST")
+	assert.NoError(t, LoadConfig())
+}
+
+// TestSymbols 测试交易对列表的解析、去除空白、默认值和数量上限
+func TestSymbols(t *testing.T) {
+	assert.Equal(t, []string{"BTCUSDT", "ETHUSDT", "SOLUSDT"}, parseSymbols(" btcusdt, ETHUSDT ,,solusdt,BTCUSDT ", 10))
+	assert.Equal(t, DefaultSymbols, parseSymbols("", 10))
+	assert.Equal(t, DefaultSymbols, parseSymbols(" , ", 10))
+
+	// 超过上限时只保留前面的交易对，上限<=0不限制
+	assert.Equal(t, []string{"A", "B"}, parseSymbols("a,b,c,d", 2))
+	assert.Equal(t, []string{"BTCUSDT"}, parseSymbols("", 1))
+	assert.Len(t, parseSymbols("a,b,c,d", 0), 4)
+
+	t.Setenv("SYMBOLS", "solusdt, xrpusdt, dogeusdt")
+	t.Setenv("MAX_SYMBOLS", "2")
+	require.NoError(t, LoadConfig())
+	assert.Equal(t, []string{"SOLUSDT", "XRPUSDT"}, Symbols())
+}
+
+// validConfig 通过校验的最小配置
+func validConfig() *Config {
+	return &Config{
+		DBHost:             "localhost",
+		DBPort:             "5432",
+		DBUser:             "postgres",
+		DBName:             "quant_data",
+		DBMaxConns:         10,
+		KafkaBrokers:       "localhost:9092",
+		APIPort:            "8080",
+		APITimeout:         30,
+		TushareAPIKey:      "token",
+		DataSourceTimeout:  10,
+		ProcessingInterval: 30,
+	}
+}
+
+// TestConfig_Validate 测试每个必填配置和取值范围的校验错误
+func TestConfig_Validate(t *testing.T) {
+	require.NoError(t, validConfig().Validate())
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"missing db host", func(c *Config) { c.DBHost = " " }, "DB_HOST is required"},
+		{"missing db user", func(c *Config) { c.DBUser = "" }, "DB_USER is required"},
+		{"missing db name", func(c *Config) { c.DBName = "" }, "DB_NAME is required"},
+		{"non-numeric db port", func(c *Config) { c.DBPort = "pg" }, `DB_PORT must be a port number between 1 and 65535, got "pg"`},
+		{"db port out of range", func(c *Config) { c.DBPort = "70000" }, "DB_PORT must be a port number"},
+		{"zero max conns", func(c *Config) { c.DBMaxConns = 0 }, "DB_MAX_CONNS must be at least 1, got 0"},
+		{"missing kafka brokers", func(c *Config) { c.KafkaBrokers = "" }, "KAFKA_BROKERS is required"},
+		{"non-numeric api port", func(c *Config) { c.APIPort = "http" }, "API_PORT must be a port number"},
+		{"zero api timeout", func(c *Config) { c.APITimeout = 0 }, "API_TIMEOUT must be at least 1"},
+		{"missing tushare key", func(c *Config) { c.TushareAPIKey = "" }, "TUSHARE_API_TOKEN (or TUSHARE_API_KEY, TUSHARE_TOKEN) is required"},
+		{"zero data source timeout", func(c *Config) { c.DataSourceTimeout = 0 }, "DATA_SOURCE_TIMEOUT must be at least 1"},
+		{"zero processing interval", func(c *Config) { c.ProcessingInterval = 0 }, "PROCESSING_INTERVAL must be at least 1, got 0"},
+		{"zero outbox interval", func(c *Config) { c.OutboxEnabled = true }, "OUTBOX_RELAY_INTERVAL_MS must be at least 1"},
+		{"zero raw response retention", func(c *Config) { c.PersistRawResponses = true }, "RAW_RESPONSE_RETENTION_HOURS must be at least 1"},
+		{"consistency sample percent out of range", func(c *Config) {
+			c.ConsistencyCheckEnabled, c.ConsistencyCheckIntervalSec, c.ConsistencySampleLimit, c.ConsistencyGroupID = true, 300, 500, "group"
+			c.ConsistencySamplePercent = 101
+		}, "CONSISTENCY_SAMPLE_PERCENT must be between 1 and 100, got 101"},
+		{"zero consistency interval", func(c *Config) {
+			c.ConsistencyCheckEnabled, c.ConsistencySamplePercent, c.ConsistencySampleLimit, c.ConsistencyGroupID = true, 10, 500, "group"
+		}, "CONSISTENCY_CHECK_INTERVAL_SECONDS must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+			err := c.Validate()
+			assert.ErrorIs(t, err, ErrInvalidConfig)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+
+	// 一次返回全部问题
+	c := validConfig()
+	c.DBHost, c.DBMaxConns, c.TushareAPIKey = "", 0, ""
+	err := c.Validate()
+	assert.ErrorContains(t, err, "DB_HOST is required")
+	assert.ErrorContains(t, err, "DB_MAX_CONNS must be at least 1")
+	assert.ErrorContains(t, err, "TUSHARE_API_TOKEN (or TUSHARE_API_KEY, TUSHARE_TOKEN) is required")
+	assert.Len(t, strings.Split(err.Error(), "\n"), 3)
+}
+
+// TestLoadConfig_Invalid 测试LoadConfig校验失败时返回错误并保留之前的配置
+func TestLoadConfig_Invalid(t *testing.T) {
+	t.Setenv("DB_MAX_CONNS", "10")
+	require.NoError(t, LoadConfig())
+	previous := AppConfig
+
+	t.Setenv("DB_MAX_CONNS", "0")
+	err := LoadConfig()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, "DB_MAX_CONNS")
+	assert.Same(t, previous, AppConfig)
+}
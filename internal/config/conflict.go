@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrConfigConflict 严格模式下.env文件与环境变量对关键配置的取值不一致
+var ErrConfigConflict = errors.New("critical config differs between .env file and environment")
+
+// DefaultCriticalKeys 严格模式默认检查的关键配置
+const DefaultCriticalKeys = "DB_NAME,KAFKA_BROKERS"
+
+// Conflict .env文件与进程环境变量对同一配置的取值不一致，环境变量优先
+type Conflict struct {
+	Key       string
+	FileValue string
+	EnvValue  string
+}
+
+// secretKeyMarkers 名称包含这些片段的配置在日志和错误中隐藏取值
+var secretKeyMarkers = []string{"PASSWORD", "SECRET", "TOKEN"}
+
+// displayValue 返回可以写入日志的配置值，密钥类配置只显示是否为空
+func displayValue(key, value string) string {
+	for _, marker := range secretKeyMarkers {
+		if strings.Contains(key, marker) && value != "" {
+			return "***"
+		}
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// sortConflicts 按配置名排序，使日志和错误信息稳定
+func sortConflicts(conflicts []Conflict) {
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Key < conflicts[j].Key })
+}
+
+// reportConflicts 记录每个取值不一致的配置及生效的值
+// strict为true时，冲突的配置在criticalKeys（逗号分隔）中则返回ErrConfigConflict
+func reportConflicts(conflicts []Conflict, strict bool, criticalKeys string) error {
+	critical := make(map[string]bool)
+	for _, key := range strings.Split(criticalKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			critical[key] = true
+		}
+	}
+
+	var rejected []string
+	for _, c := range conflicts {
+		logrus.Warnf("Config %s differs: .env file has %s, environment has %s, using the environment value",
+			c.Key, displayValue(c.Key, c.FileValue), displayValue(c.Key, c.EnvValue))
+		if strict && critical[c.Key] {
+			rejected = append(rejected, c.Key)
+		}
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("%w: %s (unset one of them or disable CONFIG_STRICT)", ErrConfigConflict, strings.Join(rejected, ", "))
+	}
+	return nil
+}
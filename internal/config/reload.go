@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNonReloadableChange 重新加载时修改了需要重启才能生效的配置
+var ErrNonReloadableChange = errors.New("non-reloadable config changed, restart required")
+
+// ErrInvalidReload 重新加载的配置值不合法
+var ErrInvalidReload = errors.New("invalid reloadable config")
+
+// mutex 保护AppConfig的替换和可重新加载字段的修改
+var mutex sync.RWMutex
+
+// Reloadable 运行时无需重启即可修改的配置，字段名与Config中的同名字段对应
+type Reloadable struct {
+	LogLevel string `json:"log_level"`
+	// 数据处理间隔（秒）
+	ProcessingInterval int `json:"processing_interval"`
+}
+
+// reloadableFields Reloadable包含的Config字段名
+var reloadableFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(Reloadable{})
+	for i := 0; i < t.NumField(); i++ {
+		fields[t.Field(i).Name] = true
+	}
+	return fields
+}()
+
+// reloadable 返回cfg中可重新加载的配置
+func (c *Config) reloadable() Reloadable {
+	return Reloadable{
+		LogLevel:           c.LogLevel,
+		ProcessingInterval: c.ProcessingInterval,
+	}
+}
+
+// Validate 校验可重新加载的配置值
+func (r Reloadable) Validate() error {
+	if _, err := logrus.ParseLevel(r.LogLevel); err != nil {
+		return fmt.Errorf("%w: LOG_LEVEL %q", ErrInvalidReload, r.LogLevel)
+	}
+	if r.ProcessingInterval < 1 {
+		return fmt.Errorf("%w: PROCESSING_INTERVAL must be at least 1 second", ErrInvalidReload)
+	}
+	return nil
+}
+
+// Current 返回当前生效的可重新加载配置
+func Current() Reloadable {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return AppConfig.reloadable()
+}
+
+// Reload 重新读取.env文件和环境变量，替换可重新加载的配置并立即应用新的日志级别
+// 其他配置（如数据库连接）发生变化时不做任何修改，返回ErrNonReloadableChange
+func Reload() (Reloadable, error) {
+	conflicts := loadDotEnv()
+
+	next, err := load()
+	if err != nil {
+		return Reloadable{}, err
+	}
+	if err := reportConflicts(conflicts, next.ConfigStrict, next.ConfigCriticalKeys); err != nil {
+		return Reloadable{}, err
+	}
+	reloaded := next.reloadable()
+	if err := reloaded.Validate(); err != nil {
+		return Reloadable{}, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if changed := nonReloadableChanges(AppConfig, next); len(changed) > 0 {
+		return Reloadable{}, fmt.Errorf("%w: %s", ErrNonReloadableChange, strings.Join(changed, ", "))
+	}
+
+	previous := AppConfig.reloadable()
+	AppConfig.LogLevel = reloaded.LogLevel
+	AppConfig.ProcessingInterval = reloaded.ProcessingInterval
+
+	level, _ := logrus.ParseLevel(reloaded.LogLevel)
+	logrus.SetLevel(level)
+
+	if previous != reloaded {
+		logrus.Infof("Config reloaded: %+v -> %+v", previous, reloaded)
+	}
+	return reloaded, nil
+}
+
+// nonReloadableChanges 返回current和next之间不可重新加载且值不同的字段名，不包含字段值以免泄露密钥
+func nonReloadableChanges(current, next *Config) []string {
+	var changed []string
+	cv, nv := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		name := cv.Type().Field(i).Name
+		if reloadableFields[name] {
+			continue
+		}
+		if !reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSymbols 未配置SYMBOLS时数据处理的交易对
+var DefaultSymbols = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
+
+// Symbols 返回数据处理的交易对，由SYMBOLS解析，最多MaxSymbols个
+func Symbols() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return parseSymbols(AppConfig.SymbolList, AppConfig.MaxSymbols)
+}
+
+// parseSymbols 解析逗号分隔的交易对：去除空白、转为大写、忽略空项和重复项，没有交易对时使用DefaultSymbols
+// 超过maxSymbols个时只保留前maxSymbols个并记录警告，maxSymbols<=0表示不限制
+func parseSymbols(raw string, maxSymbols int) []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, symbol := range strings.Split(raw, ",") {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	if len(symbols) == 0 {
+		symbols = append([]string(nil), DefaultSymbols...)
+	}
+	if maxSymbols > 0 && len(symbols) > maxSymbols {
+		logrus.Warnf("SYMBOLS lists %d symbols, only the first %d (MAX_SYMBOLS) are processed", len(symbols), maxSymbols)
+		symbols = symbols[:maxSymbols]
+	}
+	return symbols
+}
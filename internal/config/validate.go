@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidConfig 必填配置缺失或配置值超出范围
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Validate 检查必填配置和取值范围，一次返回全部问题（errors.Join），每个问题都可以用errors.Is判断为ErrInvalidConfig
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, args...)))
+	}
+	required := func(key, value string) {
+		if strings.TrimSpace(value) == "" {
+			invalid("%s is required", key)
+		}
+	}
+	port := func(key, value string) {
+		if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+			invalid("%s must be a port number between 1 and 65535, got %q", key, value)
+		}
+	}
+	atLeast := func(key string, value, min int) {
+		if value < min {
+			invalid("%s must be at least %d, got %d", key, min, value)
+		}
+	}
+
+	required("DB_HOST", c.DBHost)
+	required("DB_USER", c.DBUser)
+	required("DB_NAME", c.DBName)
+	port("DB_PORT", c.DBPort)
+	atLeast("DB_MAX_CONNS", c.DBMaxConns, 1)
+
+	required("KAFKA_BROKERS", c.KafkaBrokers)
+
+	port("API_PORT", c.APIPort)
+	atLeast("API_TIMEOUT", c.APITimeout, 1)
+
+	// 定时任务启动时和按STOCK_LIST_CRON从Tushare获取股票列表，日线任务同样依赖Tushare
+	required("TUSHARE_API_TOKEN (or TUSHARE_API_KEY, TUSHARE_TOKEN)", c.TushareAPIKey)
+	atLeast("DATA_SOURCE_TIMEOUT", c.DataSourceTimeout, 1)
+
+	atLeast("PROCESSING_INTERVAL", c.ProcessingInterval, 1)
+	if c.OutboxEnabled {
+		atLeast("OUTBOX_RELAY_INTERVAL_MS", c.OutboxRelayIntervalMs, 1)
+	}
+	if c.ConsistencyCheckEnabled {
+		atLeast("CONSISTENCY_CHECK_INTERVAL_SECONDS", c.ConsistencyCheckIntervalSec, 1)
+		if c.ConsistencySamplePercent < 1 || c.ConsistencySamplePercent > 100 {
+			invalid("CONSISTENCY_SAMPLE_PERCENT must be between 1 and 100, got %d", c.ConsistencySamplePercent)
+		}
+		atLeast("CONSISTENCY_SAMPLE_LIMIT", c.ConsistencySampleLimit, 1)
+		atLeast("CONSISTENCY_GRACE_SECONDS", c.ConsistencyGraceSec, 0)
+		required("CONSISTENCY_GROUP_ID", c.ConsistencyGroupID)
+	}
+	if c.PersistRawResponses {
+		atLeast("RAW_RESPONSE_RETENTION_HOURS", c.RawResponseRetentionHours, 1)
+	}
+
+	return errors.Join(errs...)
+}
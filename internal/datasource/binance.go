@@ -0,0 +1,217 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBinanceBaseURL Binance现货REST接口地址
+const DefaultBinanceBaseURL = "https://api.binance.com"
+
+// MaxKlinesPerRequest Binance /api/v3/klines 单次请求最多返回的K线数
+const MaxKlinesPerRequest = 1000
+
+// binanceIntervals 支持的K线周期
+var binanceIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// BinanceDataSource 基于Binance K线接口的数据源
+// 历史数据按最多1000根K线的时间窗口分页拉取，每次请求前经过限流器
+type BinanceDataSource struct {
+	baseURL    string
+	interval   string
+	step       time.Duration
+	httpClient *http.Client
+	limiter    *RateLimiter
+	// rawSink 保存成功响应的原始响应体，为nil时不保存
+	rawSink RawResponseSink
+	// symbols 缓存支持的交易对列表
+	symbols symbolCache
+}
+
+// NewBinanceDataSource 创建Binance数据源，interval为K线周期（如1m、1h），perMinute<=0时不限流
+func NewBinanceDataSource(baseURL, interval string, perMinute int) (*BinanceDataSource, error) {
+	step, ok := binanceIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("unsupported binance kline interval %q", interval)
+	}
+	if baseURL == "" {
+		baseURL = DefaultBinanceBaseURL
+	}
+	return &BinanceDataSource{
+		baseURL:    baseURL,
+		interval:   interval,
+		step:       step,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		limiter:    NewRateLimiter(perMinute),
+	}, nil
+}
+
+// SetRawResponseSink 设置原始响应的保存输出，每次请求成功后保存响应体，sink为nil时不保存
+func (b *BinanceDataSource) SetRawResponseSink(sink RawResponseSink) {
+	b.rawSink = sink
+}
+
+// Name 获取数据源名称
+func (b *BinanceDataSource) Name() string {
+	return models.SourceBinance
+}
+
+// SupportedSymbols 获取状态为TRADING的交易对，结果缓存DefaultSymbolCacheTTL
+func (b *BinanceDataSource) SupportedSymbols(ctx context.Context) ([]string, error) {
+	return b.symbols.get(ctx, models.SourceBinance, func(ctx context.Context) ([]string, error) {
+		if err := b.limiter.Wait(ctx, "exchangeInfo"); err != nil {
+			return nil, err
+		}
+		return fetchBinanceSymbols(ctx, b.httpClient, b.baseURL, "")
+	})
+}
+
+// GetMarketData 获取最新一根K线
+func (b *BinanceDataSource) GetMarketData(symbol string) ([]models.MarketData, error) {
+	return b.fetchKlines(context.Background(), symbol, url.Values{"limit": {"1"}})
+}
+
+// GetHistoricalData 获取[startTime, endTime]内的K线，时间为RFC3339格式
+func (b *BinanceDataSource) GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error) {
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return nil, errx.WrapF(err, "invalid start time %q", startTime)
+	}
+	end, err := time.Parse(time.RFC3339, endTime)
+	if err != nil {
+		return nil, errx.WrapF(err, "invalid end time %q", endTime)
+	}
+
+	var data []models.MarketData
+	err = b.FetchKlines(context.Background(), symbol, start, end, func(page []models.MarketData) error {
+		data = append(data, page...)
+		return nil
+	})
+	return data, err
+}
+
+// FetchKlines 按时间窗口分页拉取[start, end]内的K线，每个窗口最多1000根，拉取后调用onPage
+// onPage返回错误时停止拉取；调用方可在onPage中保存数据并记录最后的时间，中断后从该时间继续拉取
+// K线ID由交易对、周期和开盘时间生成，重复拉取同一区间得到相同的ID
+func (b *BinanceDataSource) FetchKlines(ctx context.Context, symbol string, start, end time.Time, onPage func([]models.MarketData) error) error {
+	if end.Before(start) {
+		return fmt.Errorf("end time must be after start time")
+	}
+
+	window := b.step * MaxKlinesPerRequest
+	for windowStart := start; !windowStart.After(end); windowStart = windowStart.Add(window) {
+		windowEnd := windowStart.Add(window - time.Millisecond)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+
+		page, err := b.fetchKlines(ctx, symbol, url.Values{
+			"startTime": {strconv.FormatInt(windowStart.UnixMilli(), 10)},
+			"endTime":   {strconv.FormatInt(windowEnd.UnixMilli(), 10)},
+			"limit":     {strconv.Itoa(MaxKlinesPerRequest)},
+		})
+		if err != nil {
+			return errx.WrapF(err, "failed to fetch klines %s-%s", windowStart.Format(time.RFC3339), windowEnd.Format(time.RFC3339))
+		}
+		if len(page) == 0 {
+			continue
+		}
+		if err := onPage(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchKlines 请求一次 /api/v3/klines 并转换为市场数据
+func (b *BinanceDataSource) fetchKlines(ctx context.Context, symbol string, params url.Values) ([]models.MarketData, error) {
+	if err := b.limiter.Wait(ctx, "klines"); err != nil {
+		return nil, err
+	}
+
+	params.Set("symbol", symbol)
+	params.Set("interval", b.interval)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/api/v3/klines?"+params.Encode(), nil)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to create klines request")
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to request klines")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("klines request returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to read klines")
+	}
+	var rows [][]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, errx.Wrap(err, "failed to decode klines")
+	}
+	recordRawResponse(ctx, b.rawSink, models.SourceBinance, "/api/v3/klines?"+params.Encode(), body)
+
+	data := make([]models.MarketData, 0, len(rows))
+	for _, row := range rows {
+		d, err := b.parseKline(symbol, row)
+		if err != nil {
+			logrus.Warnf("Skipping invalid %s kline: %v", symbol, err)
+			continue
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
+
+// parseKline 将一行K线 [开盘时间, 开, 高, 低, 收, 成交量, ...] 转换为市场数据，价格取收盘价
+func (b *BinanceDataSource) parseKline(symbol string, row []interface{}) (models.MarketData, error) {
+	if len(row) < 6 {
+		return models.MarketData{}, fmt.Errorf("expected at least 6 fields, got %d", len(row))
+	}
+	openTime, ok := row[0].(float64)
+	if !ok {
+		return models.MarketData{}, fmt.Errorf("invalid open time %v", row[0])
+	}
+	closePrice, err := strconv.ParseFloat(AsString(row[4]), 64)
+	if err != nil {
+		return models.MarketData{}, errx.WrapF(err, "invalid close price %v", row[4])
+	}
+	volume, err := strconv.ParseFloat(AsString(row[5]), 64)
+	if err != nil {
+		return models.MarketData{}, errx.WrapF(err, "invalid volume %v", row[5])
+	}
+
+	timestamp := time.UnixMilli(int64(openTime)).UTC()
+	return models.MarketData{
+		ID:        models.MarketDataID(symbol, models.SourceBinance, timestamp),
+		Symbol:    symbol,
+		Price:     closePrice,
+		Volume:    volume,
+		Timestamp: timestamp,
+		Source:    models.SourceBinance,
+	}, nil
+}
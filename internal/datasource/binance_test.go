@@ -0,0 +1,151 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/models"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// klinesStub 模拟Binance K线接口：在请求的时间窗口内每分钟返回一根K线，收盘价为距纪元的分钟数
+type klinesStub struct {
+	mutex   sync.Mutex
+	windows [][2]int64
+}
+
+func (s *klinesStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v3/klines" || r.URL.Query().Get("interval") != "1m" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	startMs, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+	endMs, _ := strconv.ParseInt(q.Get("endTime"), 10, 64)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	s.mutex.Lock()
+	s.windows = append(s.windows, [2]int64{startMs, endMs})
+	s.mutex.Unlock()
+
+	minute := time.Minute.Milliseconds()
+	var rows [][]interface{}
+	for t := (startMs + minute - 1) / minute * minute; t <= endMs && len(rows) < limit; t += minute {
+		price := strconv.FormatInt(t/minute, 10)
+		rows = append(rows, []interface{}{t, price, price, price, price, "1.5", t + minute - 1, "0", 1, "0", "0", "0"})
+	}
+	_ = json.NewEncoder(w).Encode(rows)
+}
+
+// TestBinanceDataSource_FetchKlinesPaging 测试按1000根K线的窗口分页拉取，各窗口拼接后连续且不重复
+func TestBinanceDataSource_FetchKlinesPaging(t *testing.T) {
+	stub := &klinesStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	source, err := NewBinanceDataSource(server.URL, "1m", 0)
+	if err != nil {
+		t.Fatalf("Failed to create binance datasource: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(2500 * time.Minute)
+	data, err := source.GetHistoricalData("BTCUSDT", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	if err != nil {
+		t.Fatalf("GetHistoricalData failed: %v", err)
+	}
+
+	// 3个窗口：[0, 1000)、[1000, 2000)、[2000, 2500]分钟
+	if len(stub.windows) != 3 {
+		t.Fatalf("Expected 3 requests, got %d: %v", len(stub.windows), stub.windows)
+	}
+	for i, window := range stub.windows {
+		expectedStart := start.Add(time.Duration(i*1000) * time.Minute).UnixMilli()
+		if window[0] != expectedStart {
+			t.Errorf("Window %d: expected start %d, got %d", i, expectedStart, window[0])
+		}
+		if i > 0 && window[0] != stub.windows[i-1][1]+1 {
+			t.Errorf("Window %d does not continue previous window: %v", i, stub.windows)
+		}
+	}
+	if stub.windows[2][1] != end.UnixMilli() {
+		t.Errorf("Expected last window to end at %d, got %d", end.UnixMilli(), stub.windows[2][1])
+	}
+
+	if len(data) != 2501 {
+		t.Fatalf("Expected 2501 klines, got %d", len(data))
+	}
+	ids := make(map[string]bool)
+	for i, d := range data {
+		expected := start.Add(time.Duration(i) * time.Minute)
+		if !d.Timestamp.Equal(expected) {
+			t.Fatalf("Kline %d: expected timestamp %v, got %v", i, expected, d.Timestamp)
+		}
+		if d.Price != float64(expected.Unix()/60) || d.Volume != 1.5 {
+			t.Errorf("Kline %d: unexpected price/volume %v/%v", i, d.Price, d.Volume)
+		}
+		if d.Source != models.SourceBinance || d.Symbol != "BTCUSDT" {
+			t.Errorf("Kline %d: unexpected source/symbol %s/%s", i, d.Source, d.Symbol)
+		}
+		if ids[d.ID] {
+			t.Fatalf("Duplicate kline id %s at %d", d.ID, i)
+		}
+		ids[d.ID] = true
+	}
+
+	// 重复拉取同一区间得到相同的ID，断点续传时可以安全地重新写入
+	again, err := source.GetHistoricalData("BTCUSDT", start.Format(time.RFC3339), start.Format(time.RFC3339))
+	if err != nil {
+		t.Fatalf("GetHistoricalData failed: %v", err)
+	}
+	if len(again) != 1 || again[0].ID != data[0].ID {
+		t.Errorf("Expected deterministic id %s, got %v", data[0].ID, again)
+	}
+}
+
+// TestBinanceDataSource_FetchKlinesStop 测试onPage返回错误时停止拉取，接口错误时返回错误
+func TestBinanceDataSource_FetchKlinesStop(t *testing.T) {
+	stub := &klinesStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	source, err := NewBinanceDataSource(server.URL, "1m", 0)
+	if err != nil {
+		t.Fatalf("Failed to create binance datasource: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	errStop := errors.New("stop")
+	var last time.Time
+	err = source.FetchKlines(context.Background(), "BTCUSDT", start, start.Add(5000*time.Minute), func(page []models.MarketData) error {
+		last = page[len(page)-1].Timestamp
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expected stop error, got %v", err)
+	}
+	if len(stub.windows) != 1 {
+		t.Errorf("Expected fetching to stop after first window, got %d requests", len(stub.windows))
+	}
+	if !last.Equal(start.Add(999 * time.Minute)) {
+		t.Errorf("Unexpected last kline time %v", last)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":-1003,"msg":"Too many requests"}`, http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+	source, _ = NewBinanceDataSource(failing.URL, "1m", 0)
+	if _, err := source.GetMarketData("BTCUSDT"); err == nil {
+		t.Error("Expected error for HTTP 429")
+	}
+
+	if _, err := NewBinanceDataSource(server.URL, "7m", 0); err == nil {
+		t.Error("Expected error for unsupported interval")
+	}
+}
@@ -1,6 +1,7 @@
 package datasource
 
 import (
+	"context"
 	"quant-data-engine/internal/models"
 	"sync"
 )
@@ -15,6 +16,9 @@ type DataSource interface {
 
 	// Name 获取数据源名称
 	Name() string
+
+	// SupportedSymbols 获取数据源支持的交易对，使用数据源的交易对名称
+	SupportedSymbols(ctx context.Context) ([]string, error)
 }
 
 // DataSourceFactory 数据源工厂
@@ -1,6 +1,7 @@
 package datasource
 
 import (
+	"quant-data-engine/internal/models"
 	"testing"
 )
 
@@ -9,8 +10,8 @@ func TestDataSourceFactory(t *testing.T) {
 	factory := NewDataSourceFactory()
 
 	// 注册数据源
-	factory.Register("binance", NewExchangeDataSource("binance", "key", "secret"))
-	factory.Register("okx", NewExchangeDataSource("okx", "key", "secret"))
+	factory.Register("binance", NewExchangeDataSource("binance", "", "key", "secret", nil))
+	factory.Register("okx", NewMockDataSource("okx"))
 
 	// 测试获取数据源
 	binance := factory.GetDataSource("binance")
@@ -30,9 +31,9 @@ func TestDataSourceFactory(t *testing.T) {
 	}
 }
 
-func TestExchangeDataSource(t *testing.T) {
-	// 创建交易所数据源
-	source := NewExchangeDataSource("binance", "key", "secret")
+func TestMockDataSource(t *testing.T) {
+	// 创建模拟数据源
+	source := NewMockDataSource("binance")
 
 	// 测试数据源名称
 	if source.Name() != "binance" {
@@ -59,3 +60,34 @@ func TestExchangeDataSource(t *testing.T) {
 		t.Error("Expected at least one historical data record")
 	}
 }
+
+func TestMockDataSource_CanonicalSource(t *testing.T) {
+	// 数据源名称规范化为小写
+	if name := NewExchangeDataSource(" Binance ", "", "key", "secret", nil).Name(); name != "binance" {
+		t.Errorf("Expected exchange datasource name to be 'binance', got '%s'", name)
+	}
+	source := NewMockDataSource(" Binance ")
+	if source.Name() != "binance" {
+		t.Errorf("Expected datasource name to be 'binance', got '%s'", source.Name())
+	}
+
+	data, err := source.GetMarketData("BTCUSDT")
+	if err != nil {
+		t.Fatalf("Expected no error when getting market data, got '%v'", err)
+	}
+	for _, d := range data {
+		if d.Source != "binance" {
+			t.Errorf("Expected source to be 'binance', got '%s'", d.Source)
+		}
+	}
+
+	if !models.IsKnownSource(models.CanonicalSource("OKX")) {
+		t.Error("Expected OKX to be a known source after normalization")
+	}
+	if models.IsKnownSource("Binance") {
+		t.Error("Expected non-canonical source name to be rejected")
+	}
+	if models.IsKnownSource("coinbase") {
+		t.Error("Expected unknown source to be rejected")
+	}
+}
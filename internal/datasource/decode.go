@@ -0,0 +1,250 @@
+package datasource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Row Tushare响应中的一行数据，按字段名访问
+// Tushare的数值字段有时以字符串形式返回（如 "15.3"），Float/Int会统一转换为目标类型
+type Row struct {
+	index  map[string]int
+	values []interface{}
+}
+
+// Rows 将Tushare响应数据转换为按字段名访问的行
+func Rows(resp *TushareResponse) []Row {
+	if resp == nil || resp.Data == nil {
+		return nil
+	}
+
+	index := make(map[string]int, len(resp.Data.Fields))
+	for i, field := range resp.Data.Fields {
+		index[field] = i
+	}
+
+	rows := make([]Row, 0, len(resp.Data.Items))
+	for _, item := range resp.Data.Items {
+		rows = append(rows, Row{index: index, values: item})
+	}
+	return rows
+}
+
+// value 返回字段的原始值，字段不存在时返回nil
+func (r Row) value(field string) interface{} {
+	i, ok := r.index[field]
+	if !ok || i >= len(r.values) {
+		return nil
+	}
+	return r.values[i]
+}
+
+// Has 判断字段是否存在且不为null
+func (r Row) Has(field string) bool {
+	return r.value(field) != nil
+}
+
+// String 获取字符串字段，数值会被格式化为字符串
+func (r Row) String(field string) string {
+	return AsString(r.value(field))
+}
+
+// Float 获取浮点数字段
+func (r Row) Float(field string) float64 {
+	return AsFloat64(field, r.value(field))
+}
+
+// Int 获取整数字段
+func (r Row) Int(field string) int {
+	return AsInt(field, r.value(field))
+}
+
+// ErrInvalidDecodeTarget DecodeItems的解码目标不是结构体切片指针，或匹配到的字段类型不受支持
+var ErrInvalidDecodeTarget = errors.New("invalid decode target")
+
+// DecodeItems 将Tushare响应的每一行解码为结构体，写入out（指向结构体切片的指针）
+// 响应字段名按结构体字段的json标签匹配，没有json标签时使用db标签；响应中多余的字段被忽略，缺失的字段保持零值
+// 支持string、float64和int类型的字段，转换规则与AsString、AsFloat64、AsInt相同；resp没有数据时out被置为nil
+func DecodeItems(resp *TushareResponse, out interface{}) error {
+	ptr := reflect.ValueOf(out)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() || ptr.Elem().Kind() != reflect.Slice || ptr.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: expected pointer to slice of structs, got %T", ErrInvalidDecodeTarget, out)
+	}
+	slice := ptr.Elem()
+	elemType := slice.Type().Elem()
+
+	if resp == nil || resp.Data == nil || len(resp.Data.Items) == 0 {
+		slice.Set(reflect.Zero(slice.Type()))
+		return nil
+	}
+
+	// 响应字段下标到结构体字段下标的映射，-1表示忽略该字段
+	tags := tagFieldIndex(elemType)
+	columns := make([]int, len(resp.Data.Fields))
+	for i, name := range resp.Data.Fields {
+		columns[i] = -1
+		index, ok := tags[name]
+		if !ok {
+			continue
+		}
+		switch kind := elemType.Field(index).Type.Kind(); kind {
+		case reflect.String, reflect.Float64, reflect.Int:
+			columns[i] = index
+		default:
+			return fmt.Errorf("%w: field %s maps to %s.%s of unsupported type %s",
+				ErrInvalidDecodeTarget, name, elemType.Name(), elemType.Field(index).Name, kind)
+		}
+	}
+
+	items := reflect.MakeSlice(slice.Type(), len(resp.Data.Items), len(resp.Data.Items))
+	for row, item := range resp.Data.Items {
+		elem := items.Index(row)
+		for i, index := range columns {
+			if index < 0 || i >= len(item) {
+				continue
+			}
+			field := elem.Field(index)
+			name := resp.Data.Fields[i]
+			switch field.Kind() {
+			case reflect.String:
+				field.SetString(AsString(item[i]))
+			case reflect.Float64:
+				field.SetFloat(AsFloat64(name, item[i]))
+			case reflect.Int:
+				field.SetInt(int64(AsInt(name, item[i])))
+			}
+		}
+	}
+	slice.Set(items)
+	return nil
+}
+
+// tagFieldIndex 返回结构体中导出字段的标签名（json优先，其次db）到字段下标的映射，同名标签取第一个字段
+func tagFieldIndex(t reflect.Type) map[string]int {
+	index := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := tagName(field.Tag.Get("json"))
+		if name == "" {
+			name = tagName(field.Tag.Get("db"))
+		}
+		if name == "" {
+			continue
+		}
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+	return index
+}
+
+// tagName 返回结构体标签中的名称部分，"-"表示忽略
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// AsString 将Tushare字段值转换为字符串，null返回空字符串
+func AsString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case json.Number:
+		return val.String()
+	default:
+		return ""
+	}
+}
+
+// AsFloat64 将Tushare字段值转换为float64，兼容以字符串编码的数值
+// null和空字符串返回0，无法解析的值记录警告日志并返回0
+func AsFloat64(field string, v interface{}) float64 {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case float64:
+		return val
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case json.Number:
+		return parseFloat(field, val.String())
+	case string:
+		return parseFloat(field, val)
+	default:
+		logrus.Warnf("Unexpected type %T for numeric field %s", v, field)
+		return 0
+	}
+}
+
+// AsInt 将Tushare字段值转换为int，兼容以字符串编码的数值，小数部分被截断
+// null和空字符串返回0，无法解析的值记录警告日志并返回0
+func AsInt(field string, v interface{}) int {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case int:
+		return val
+	case int64:
+		return int(val)
+	case float64:
+		return int(val)
+	case json.Number:
+		return parseInt(field, val.String())
+	case string:
+		return parseInt(field, val)
+	default:
+		logrus.Warnf("Unexpected type %T for numeric field %s", v, field)
+		return 0
+	}
+}
+
+// parseFloat 解析字符串编码的浮点数
+func parseFloat(field, s string) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		logrus.Warnf("Unparseable numeric value %q for field %s", s, field)
+		return 0
+	}
+	return f
+}
+
+// parseInt 解析字符串编码的整数，兼容 "42.0" 形式
+func parseInt(field, s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return i
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		logrus.Warnf("Unparseable numeric value %q for field %s", s, field)
+		return 0
+	}
+	return int(f)
+}
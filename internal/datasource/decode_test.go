@@ -0,0 +1,198 @@
+package datasource
+
+import (
+	"encoding/json"
+	"errors"
+	"quant-data-engine/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestRows_NumericFieldAsStringOrNumber(t *testing.T) {
+	bodies := map[string]string{
+		"string": `{"code":0,"data":{"fields":["ts_code","pe","issue_date"],"items":[["688001.SH","15.3","20240105"]]}}`,
+		"number": `{"code":0,"data":{"fields":["ts_code","pe","issue_date"],"items":[["688001.SH",15.3,"20240105"]]}}`,
+	}
+
+	for name, body := range bodies {
+		var resp TushareResponse
+		if err := json.Unmarshal([]byte(body), &resp); err != nil {
+			t.Fatalf("%s: failed to unmarshal response: %v", name, err)
+		}
+
+		rows := Rows(&resp)
+		if len(rows) != 1 {
+			t.Fatalf("%s: expected 1 row, got %d", name, len(rows))
+		}
+		if pe := rows[0].Float("pe"); pe != 15.3 {
+			t.Errorf("%s: expected pe 15.3, got %v", name, pe)
+		}
+		if code := rows[0].String("ts_code"); code != "688001.SH" {
+			t.Errorf("%s: expected ts_code 688001.SH, got '%s'", name, code)
+		}
+	}
+}
+
+func TestAsFloat64_EdgeCases(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected float64
+	}{
+		{nil, 0},
+		{"", 0},
+		{"  ", 0},
+		{" 2.5 ", 2.5},
+		{"n/a", 0},
+		{json.Number("7.25"), 7.25},
+		{3, 3},
+		{true, 0},
+	}
+
+	for _, c := range cases {
+		if got := AsFloat64("pe", c.value); got != c.expected {
+			t.Errorf("AsFloat64(%#v): expected %v, got %v", c.value, c.expected, got)
+		}
+	}
+}
+
+func TestAsInt_EdgeCases(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected int
+	}{
+		{nil, 0},
+		{"", 0},
+		{"42", 42},
+		{"42.0", 42},
+		{42.9, 42},
+		{"abc", 0},
+	}
+
+	for _, c := range cases {
+		if got := AsInt("list_days", c.value); got != c.expected {
+			t.Errorf("AsInt(%#v): expected %v, got %v", c.value, c.expected, got)
+		}
+	}
+}
+
+func TestRows_MissingField(t *testing.T) {
+	resp := &TushareResponse{Data: &DataResult{
+		Fields: []string{"ts_code"},
+		Items:  [][]interface{}{{"000001.SZ"}},
+	}}
+
+	row := Rows(resp)[0]
+	if row.Has("pe") {
+		t.Errorf("Expected pe to be missing")
+	}
+	if pe := row.Float("pe"); pe != 0 {
+		t.Errorf("Expected missing pe to be 0, got %v", pe)
+	}
+	if Rows(nil) != nil {
+		t.Errorf("Expected nil rows for nil response")
+	}
+}
+
+// decodeRow 测试用结构体，Count只有db标签，Hidden的json标签为"-"
+type decodeRow struct {
+	Name   string  `json:"name"`
+	Count  int     `db:"count"`
+	Ratio  float64 `json:"ratio"`
+	Hidden string  `json:"-"`
+}
+
+func TestDecodeItems(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		out  func() interface{}
+		want interface{}
+	}{
+		{
+			name: "stock_basic",
+			body: `{"code":0,"data":{"fields":["ts_code","symbol","name","list_date","is_hs"],"items":[["000001.SZ","000001","平安银行","19910403","S"],["600000.SH","600000","浦发银行","19991110",null]]}}`,
+			out:  func() interface{} { return &[]models.StockBasic{} },
+			want: &[]models.StockBasic{
+				{TSCode: "000001.SZ", Symbol: "000001", Name: "平安银行", ListDate: "19910403", IsHS: "S"},
+				{TSCode: "600000.SH", Symbol: "600000", Name: "浦发银行", ListDate: "19991110"},
+			},
+		},
+		{
+			name: "daily with numbers encoded as strings",
+			body: `{"code":0,"data":{"fields":["ts_code","trade_date","open","high","low","close","vol"],"items":[["000001.SZ","20240102",9.39,"9.42",9.21,9.21,"1158366.45"]]}}`,
+			out:  func() interface{} { return &[]models.Daily{} },
+			want: &[]models.Daily{
+				{TSCode: "000001.SZ", TradeDate: "20240102", Open: 9.39, High: 9.42, Low: 9.21, Close: 9.21, Vol: 1158366.45},
+			},
+		},
+		{
+			name: "trade_cal with numeric is_open",
+			body: `{"code":0,"data":{"fields":["exchange","cal_date","is_open","pretrade_date"],"items":[["SSE","20240101",0,"20231229"],["SSE","20240102",1,"20240101"]]}}`,
+			out:  func() interface{} { return &[]models.TradeCal{} },
+			want: &[]models.TradeCal{
+				{Exchange: "SSE", CalDate: "20240101", IsOpen: "0"},
+				{Exchange: "SSE", CalDate: "20240102", IsOpen: "1"},
+			},
+		},
+		{
+			name: "missing and extra fields",
+			body: `{"code":0,"data":{"fields":["ts_code","unknown","close"],"items":[["000001.SZ","x",10.5],["000002.SZ"]]}}`,
+			out:  func() interface{} { return &[]models.Daily{} },
+			want: &[]models.Daily{
+				{TSCode: "000001.SZ", Close: 10.5},
+				{TSCode: "000002.SZ"},
+			},
+		},
+		{
+			name: "int fields and db tags",
+			body: `{"code":0,"data":{"fields":["name","count","ratio","hidden"],"items":[["a","120",0.5,"x"],["b",7.9,"1.5",null]]}}`,
+			out:  func() interface{} { return &[]decodeRow{} },
+			want: &[]decodeRow{{Name: "a", Count: 120, Ratio: 0.5}, {Name: "b", Count: 7, Ratio: 1.5}},
+		},
+		{
+			name: "no data",
+			body: `{"code":0,"data":{"fields":["ts_code"],"items":[]}}`,
+			out:  func() interface{} { return &[]models.StockBasic{{TSCode: "stale"}} },
+			want: new([]models.StockBasic),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var resp TushareResponse
+			if err := json.Unmarshal([]byte(tc.body), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			out := tc.out()
+			if err := DecodeItems(&resp, out); err != nil {
+				t.Fatalf("Expected no error, got '%v'", err)
+			}
+			if !reflect.DeepEqual(out, tc.want) {
+				t.Errorf("Expected %+v, got %+v", tc.want, out)
+			}
+		})
+	}
+}
+
+func TestDecodeItems_InvalidTarget(t *testing.T) {
+	resp := &TushareResponse{Data: &DataResult{
+		Fields: []string{"ts_code", "created_at"},
+		Items:  [][]interface{}{{"000001.SZ", "2024-01-01"}},
+	}}
+
+	var stocks []models.StockBasic
+	for name, out := range map[string]interface{}{
+		"not a pointer":      stocks,
+		"nil pointer":        (*[]models.StockBasic)(nil),
+		"slice of strings":   &[]string{},
+		"unsupported fields": &stocks,
+	} {
+		if err := DecodeItems(resp, out); !errors.Is(err, ErrInvalidDecodeTarget) {
+			t.Errorf("%s: expected ErrInvalidDecodeTarget, got '%v'", name, err)
+		}
+	}
+
+	if err := DecodeItems(nil, &stocks); err != nil || stocks != nil {
+		t.Errorf("Expected nil response to decode to nil slice, got %v, %v", stocks, err)
+	}
+}
@@ -1,84 +1,157 @@
 package datasource
 
 import (
-	"math/rand"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"quant-data-engine/internal/errx"
 	"quant-data-engine/internal/models"
+	"strconv"
 	"time"
-
-	"github.com/google/uuid"
 )
 
-// ExchangeDataSource 交易所数据源实现
+// DefaultExchangeTimeout 未注入HTTP客户端时的请求超时
+const DefaultExchangeTimeout = 10 * time.Second
+
+// ExchangeDataSource 交易所REST行情数据源，目前只接入了Binance
+// 最新行情来自 /api/v3/ticker/24hr，历史数据来自1小时K线
 type ExchangeDataSource struct {
-	name      string
-	apiKey    string
-	apiSecret string
+	name       string
+	baseURL    string
+	apiKey     string
+	apiSecret  string
+	httpClient *http.Client
+	// klines 历史数据使用的K线数据源，与行情共用HTTP客户端
+	klines *BinanceDataSource
+	// rawSink 保存成功响应的原始响应体，为nil时不保存
+	rawSink RawResponseSink
+	// symbols 缓存支持的交易对列表
+	symbols symbolCache
 }
 
-// NewExchangeDataSource 创建交易所数据源
-func NewExchangeDataSource(name, apiKey, apiSecret string) *ExchangeDataSource {
-	return &ExchangeDataSource{
-		name:      name,
-		apiKey:    apiKey,
-		apiSecret: apiSecret,
+// NewExchangeDataSource 创建交易所数据源，名称会被规范化为小写形式
+// baseURL为空时使用交易所默认地址；httpClient为nil时使用超时为DefaultExchangeTimeout的客户端
+func NewExchangeDataSource(name, baseURL, apiKey, apiSecret string, httpClient *http.Client) *ExchangeDataSource {
+	name = models.CanonicalSource(name)
+	if baseURL == "" && name == models.SourceBinance {
+		baseURL = DefaultBinanceBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultExchangeTimeout}
 	}
-}
 
-// GetMarketData 获取市场数据
-func (e *ExchangeDataSource) GetMarketData(symbol string) ([]models.MarketData, error) {
-	// 模拟获取市场数据
-	rand.Seed(time.Now().UnixNano())
+	e := &ExchangeDataSource{
+		name:       name,
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		apiSecret:  apiSecret,
+		httpClient: httpClient,
+	}
+	if name == models.SourceBinance {
+		// 1h为支持的周期，不会返回错误
+		e.klines, _ = NewBinanceDataSource(baseURL, "1h", 0)
+		e.klines.httpClient = httpClient
+	}
+	return e
+}
 
-	data := []models.MarketData{
-		{
-			ID:        uuid.New().String(),
-			Symbol:    symbol,
-			Price:     1000 + rand.Float64()*100,
-			Volume:    10000 + rand.Float64()*1000,
-			Timestamp: time.Now(),
-			Source:    e.name,
-		},
+// SetRawResponseSink 设置原始响应的保存输出，行情和K线请求成功后保存响应体，sink为nil时不保存
+func (e *ExchangeDataSource) SetRawResponseSink(sink RawResponseSink) {
+	e.rawSink = sink
+	if e.klines != nil {
+		e.klines.SetRawResponseSink(sink)
 	}
+}
 
-	return data, nil
+// binanceTicker /api/v3/ticker/24hr 响应中使用的字段
+type binanceTicker struct {
+	Symbol    string `json:"symbol"`
+	LastPrice string `json:"lastPrice"`
+	Volume    string `json:"volume"`
+	CloseTime int64  `json:"closeTime"`
 }
 
-// GetHistoricalData 获取历史数据
-func (e *ExchangeDataSource) GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error) {
-	// 模拟获取历史数据
-	rand.Seed(time.Now().UnixNano())
+// GetMarketData 获取交易对最新成交价和24小时成交量
+func (e *ExchangeDataSource) GetMarketData(symbol string) ([]models.MarketData, error) {
+	if e.name != models.SourceBinance {
+		return nil, fmt.Errorf("exchange %s is not supported", e.name)
+	}
 
-	var data []models.MarketData
+	endpoint := "/api/v3/ticker/24hr?" + url.Values{"symbol": {symbol}}.Encode()
+	req, err := http.NewRequest(http.MethodGet, e.baseURL+endpoint, nil)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to create ticker request")
+	}
+	if e.apiKey != "" {
+		req.Header.Set("X-MBX-APIKEY", e.apiKey)
+	}
 
-	// 解析时间
-	start, err := time.Parse(time.RFC3339, startTime)
+	resp, err := e.httpClient.Do(req)
 	if err != nil {
-		start = time.Now().Add(-24 * time.Hour)
+		return nil, errx.WrapF(err, "failed to request %s ticker", symbol)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s ticker request returned status %d: %s", symbol, resp.StatusCode, string(body))
 	}
 
-	end, err := time.Parse(time.RFC3339, endTime)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errx.WrapF(err, "failed to read %s ticker", symbol)
+	}
+	var ticker binanceTicker
+	if err := json.Unmarshal(body, &ticker); err != nil {
+		return nil, errx.WrapF(err, "failed to decode %s ticker", symbol)
+	}
+	price, err := strconv.ParseFloat(ticker.LastPrice, 64)
+	if err != nil {
+		return nil, errx.WrapF(err, "invalid %s lastPrice %q", symbol, ticker.LastPrice)
+	}
+	volume, err := strconv.ParseFloat(ticker.Volume, 64)
 	if err != nil {
-		end = time.Now()
+		return nil, errx.WrapF(err, "invalid %s volume %q", symbol, ticker.Volume)
 	}
+	recordRawResponse(context.Background(), e.rawSink, e.name, endpoint, body)
 
-	// 生成模拟数据
-	current := start
-	for current.Before(end) {
-		data = append(data, models.MarketData{
-			ID:        uuid.New().String(),
-			Symbol:    symbol,
-			Price:     1000 + rand.Float64()*100,
-			Volume:    10000 + rand.Float64()*1000,
-			Timestamp: current,
-			Source:    e.name,
-		})
-		current = current.Add(1 * time.Hour)
+	timestamp := time.Now().UTC()
+	if ticker.CloseTime > 0 {
+		timestamp = time.UnixMilli(ticker.CloseTime).UTC()
 	}
+	// ID由交易对和行情时间生成，两次请求之间行情未更新时得到相同的ID
+	return []models.MarketData{{
+		ID:        models.MarketDataID(symbol, e.name, timestamp),
+		Symbol:    symbol,
+		Price:     price,
+		Volume:    volume,
+		Timestamp: timestamp,
+		Source:    e.name,
+	}}, nil
+}
 
-	return data, nil
+// GetHistoricalData 获取[startTime, endTime]内的1小时K线，时间为RFC3339格式
+func (e *ExchangeDataSource) GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error) {
+	if e.klines == nil {
+		return nil, fmt.Errorf("exchange %s is not supported", e.name)
+	}
+	return e.klines.GetHistoricalData(symbol, startTime, endTime)
 }
 
 // Name 获取数据源名称
 func (e *ExchangeDataSource) Name() string {
 	return e.name
 }
+
+// SupportedSymbols 获取状态为TRADING的交易对，结果缓存DefaultSymbolCacheTTL
+func (e *ExchangeDataSource) SupportedSymbols(ctx context.Context) ([]string, error) {
+	if e.name != models.SourceBinance {
+		return nil, fmt.Errorf("exchange %s is not supported", e.name)
+	}
+	return e.symbols.get(ctx, e.name, func(ctx context.Context) ([]string, error) {
+		return fetchBinanceSymbols(ctx, e.httpClient, e.baseURL, e.apiKey)
+	})
+}
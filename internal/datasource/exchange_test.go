@@ -0,0 +1,93 @@
+package datasource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExchangeDataSource_BinanceTicker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/ticker/24hr" || r.URL.Query().Get("symbol") != "BTCUSDT" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("X-MBX-APIKEY") != "key" {
+			t.Errorf("Expected api key header, got '%s'", r.Header.Get("X-MBX-APIKEY"))
+		}
+		w.Write([]byte(`{"symbol":"BTCUSDT","lastPrice":"43210.50000000","volume":"12345.67800000","closeTime":1704067200000}`))
+	}))
+	defer server.Close()
+
+	source := NewExchangeDataSource("binance", server.URL, "key", "secret", server.Client())
+	data, err := source.GetMarketData("BTCUSDT")
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if len(data) != 1 {
+		t.Fatalf("Expected one record, got %d", len(data))
+	}
+	d := data[0]
+	if d.Price != 43210.5 || d.Volume != 12345.678 {
+		t.Errorf("Unexpected price/volume %v/%v", d.Price, d.Volume)
+	}
+	if d.Source != "binance" || d.Symbol != "BTCUSDT" {
+		t.Errorf("Unexpected source/symbol %s/%s", d.Source, d.Symbol)
+	}
+	if !d.Timestamp.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected timestamp from closeTime, got %v", d.Timestamp)
+	}
+
+	// 行情未更新时ID相同
+	again, err := source.GetMarketData("BTCUSDT")
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if again[0].ID != d.ID {
+		t.Errorf("Expected stable id, got %s and %s", d.ID, again[0].ID)
+	}
+	if want := models.MarketDataID("BTCUSDT", "binance", d.Timestamp); d.ID != want {
+		t.Errorf("Expected id derived from symbol, source and timestamp %s, got %s", want, d.ID)
+	}
+}
+
+func TestExchangeDataSource_BinanceTickerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("symbol") {
+		case "SLOWUSDT":
+			time.Sleep(200 * time.Millisecond)
+		case "BADUSDT":
+			w.Write([]byte(`{"symbol":"BADUSDT","lastPrice":"n/a","volume":"1"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+		}
+	}))
+	defer server.Close()
+
+	source := NewExchangeDataSource("binance", server.URL, "", "", &http.Client{Timeout: 50 * time.Millisecond})
+
+	_, err := source.GetMarketData("NOPEUSDT")
+	if err == nil || !strings.Contains(err.Error(), "status 400") || !strings.Contains(err.Error(), "Invalid symbol") {
+		t.Errorf("Expected status error with body, got '%v'", err)
+	}
+
+	_, err = source.GetMarketData("BADUSDT")
+	if err == nil || !strings.Contains(err.Error(), "lastPrice") {
+		t.Errorf("Expected lastPrice parse error, got '%v'", err)
+	}
+
+	// 超过客户端超时
+	_, err = source.GetMarketData("SLOWUSDT")
+	if err == nil {
+		t.Error("Expected timeout error")
+	}
+
+	// 未接入的交易所
+	if _, err := NewExchangeDataSource("okx", "", "", "", nil).GetMarketData("BTCUSDT"); err == nil {
+		t.Error("Expected unsupported exchange error")
+	}
+}
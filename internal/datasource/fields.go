@@ -0,0 +1,98 @@
+package datasource
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidFields 请求字段无法解析
+var ErrInvalidFields = errors.New("invalid tushare fields")
+
+// defaultFields 各Tushare接口的默认字段集，请求字段使用"+field"/"-field"时以此为基础
+var defaultFields = map[string][]string{
+	"stock_basic": {
+		"ts_code", "symbol", "name", "area", "industry", "fullname", "enname", "cnspell",
+		"market", "exchange", "curr_type", "list_status", "list_date", "delist_date", "is_hs",
+		"act_name", "act_ent_type",
+	},
+	"trade_cal":  {"exchange", "cal_date", "is_open", "pretrade_date"},
+	"daily":      {"ts_code", "trade_date", "open", "high", "low", "close", "vol", "amount"},
+	"adj_factor": {"ts_code", "trade_date", "adj_factor"},
+}
+
+// defaultFieldsMutex 保护defaultFields
+var defaultFieldsMutex sync.RWMutex
+
+// RegisterDefaultFields 注册或替换接口的默认字段集
+func RegisterDefaultFields(apiName string, fields []string) {
+	defaultFieldsMutex.Lock()
+	defer defaultFieldsMutex.Unlock()
+	defaultFields[apiName] = append([]string(nil), fields...)
+}
+
+// DefaultFields 返回接口默认字段集的副本，未注册时返回nil
+func DefaultFields(apiName string) []string {
+	defaultFieldsMutex.RLock()
+	defer defaultFieldsMutex.RUnlock()
+	if fields, ok := defaultFields[apiName]; ok {
+		return append([]string(nil), fields...)
+	}
+	return nil
+}
+
+// ResolveFields 解析请求字段：不含"+"/"-"前缀时原样返回；
+// 否则以接口默认字段集为基础，"+field"追加字段（已存在时忽略），"-field"移除字段，不能与无前缀的字段混用
+func ResolveFields(apiName string, fields []string) ([]string, error) {
+	var additions, removals []string
+	plain := 0
+	for _, field := range fields {
+		switch {
+		case strings.HasPrefix(field, "+"):
+			additions = append(additions, strings.TrimPrefix(field, "+"))
+		case strings.HasPrefix(field, "-"):
+			removals = append(removals, strings.TrimPrefix(field, "-"))
+		default:
+			plain++
+		}
+	}
+	if len(additions) == 0 && len(removals) == 0 {
+		return fields, nil
+	}
+	if plain > 0 {
+		return nil, fmt.Errorf("%w: cannot mix +/- modifiers with explicit fields for %s", ErrInvalidFields, apiName)
+	}
+
+	resolved := DefaultFields(apiName)
+	if resolved == nil {
+		return nil, fmt.Errorf("%w: no default fields registered for %s", ErrInvalidFields, apiName)
+	}
+
+	for _, removal := range removals {
+		index := indexOf(resolved, removal)
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %q is not a default field of %s", ErrInvalidFields, removal, apiName)
+		}
+		resolved = append(resolved[:index], resolved[index+1:]...)
+	}
+	for _, addition := range additions {
+		if addition == "" {
+			return nil, fmt.Errorf("%w: empty field name for %s", ErrInvalidFields, apiName)
+		}
+		if indexOf(resolved, addition) < 0 {
+			resolved = append(resolved, addition)
+		}
+	}
+	return resolved, nil
+}
+
+// indexOf 返回field在fields中的下标，不存在时返回-1
+func indexOf(fields []string, field string) int {
+	for i, f := range fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
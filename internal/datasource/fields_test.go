@@ -0,0 +1,86 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestResolveFields(t *testing.T) {
+	RegisterDefaultFields("test_api", []string{"ts_code", "trade_date", "close", "vol"})
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   []string
+	}{
+		{"explicit fields unchanged", []string{"ts_code", "open"}, []string{"ts_code", "open"}},
+		{"empty fields unchanged", nil, nil},
+		{"additive", []string{"+amount"}, []string{"ts_code", "trade_date", "close", "vol", "amount"}},
+		{"additive existing field ignored", []string{"+close", "+amount"}, []string{"ts_code", "trade_date", "close", "vol", "amount"}},
+		{"subtractive", []string{"-vol", "-trade_date"}, []string{"ts_code", "close"}},
+		{"additive and subtractive", []string{"-vol", "+amount"}, []string{"ts_code", "trade_date", "close", "amount"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveFields("test_api", tt.fields)
+			if err != nil {
+				t.Fatalf("Expected no error, got '%v'", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// 修饰不能修改已注册的默认字段集
+	if got := DefaultFields("test_api"); len(got) != 4 {
+		t.Errorf("Expected default fields unchanged, got %v", got)
+	}
+
+	for name, fields := range map[string][]string{
+		"mixed with explicit fields": {"ts_code", "+amount"},
+		"remove unknown field":       {"-open"},
+		"empty addition":             {"+"},
+	} {
+		if _, err := ResolveFields("test_api", fields); !errors.Is(err, ErrInvalidFields) {
+			t.Errorf("%s: expected ErrInvalidFields, got '%v'", name, err)
+		}
+	}
+	if _, err := ResolveFields("unregistered_api", []string{"+amount"}); !errors.Is(err, ErrInvalidFields) {
+		t.Errorf("Expected ErrInvalidFields for unregistered api, got '%v'", err)
+	}
+}
+
+func TestTushareClient_ResolvesFieldModifiers(t *testing.T) {
+	var request TushareRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"code":0,"message":"","data":{"fields":[],"items":[]}}`))
+	}))
+	defer server.Close()
+
+	client := &TushareClient{apiURL: server.URL, apiKey: "token", httpClient: server.Client()}
+	if _, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"+pct_chg", "-amount"}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if want := "ts_code,trade_date,open,high,low,close,vol,pct_chg"; request.Fields != want {
+		t.Errorf("Expected fields '%s', got '%s'", want, request.Fields)
+	}
+
+	// 无法解析的字段不发送请求
+	request = TushareRequest{}
+	_, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"-unknown"})
+	if !errors.Is(err, ErrInvalidFields) {
+		t.Errorf("Expected ErrInvalidFields, got '%v'", err)
+	}
+	if request.APIName != "" {
+		t.Errorf("Expected no request to be sent, got %+v", request)
+	}
+}
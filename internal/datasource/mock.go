@@ -0,0 +1,83 @@
+package datasource
+
+import (
+	"context"
+	"math/rand"
+	"quant-data-engine/internal/models"
+	"time"
+)
+
+// MockDataSource 生成随机价格的模拟数据源，用于测试和尚未接入真实行情的交易所
+type MockDataSource struct {
+	name string
+}
+
+// NewMockDataSource 创建模拟数据源，名称会被规范化为小写形式
+func NewMockDataSource(name string) *MockDataSource {
+	return &MockDataSource{name: models.CanonicalSource(name)}
+}
+
+// GetMarketData 获取市场数据
+func (e *MockDataSource) GetMarketData(symbol string) ([]models.MarketData, error) {
+	// 模拟获取市场数据
+	rand.Seed(time.Now().UnixNano())
+
+	now := time.Now()
+	data := []models.MarketData{
+		{
+			ID:        models.MarketDataID(symbol, e.name, now),
+			Symbol:    symbol,
+			Price:     1000 + rand.Float64()*100,
+			Volume:    10000 + rand.Float64()*1000,
+			Timestamp: now,
+			Source:    e.name,
+		},
+	}
+
+	return data, nil
+}
+
+// GetHistoricalData 获取历史数据
+func (e *MockDataSource) GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error) {
+	// 模拟获取历史数据
+	rand.Seed(time.Now().UnixNano())
+
+	var data []models.MarketData
+
+	// 解析时间
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		start = time.Now().Add(-24 * time.Hour)
+	}
+
+	end, err := time.Parse(time.RFC3339, endTime)
+	if err != nil {
+		end = time.Now()
+	}
+
+	// 生成模拟数据
+	current := start
+	for current.Before(end) {
+		data = append(data, models.MarketData{
+			ID:        models.MarketDataID(symbol, e.name, current),
+			Symbol:    symbol,
+			Price:     1000 + rand.Float64()*100,
+			Volume:    10000 + rand.Float64()*1000,
+			Timestamp: current,
+			Source:    e.name,
+		})
+		current = current.Add(1 * time.Hour)
+	}
+
+	return data, nil
+}
+
+// Name 获取数据源名称
+func (e *MockDataSource) Name() string {
+	return e.name
+}
+
+// SupportedSymbols 返回MockSupportedSymbols的副本
+func (e *MockDataSource) SupportedSymbols(ctx context.Context) ([]string, error) {
+	return append([]string(nil), MockSupportedSymbols...), nil
+}
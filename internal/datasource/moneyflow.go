@@ -0,0 +1,48 @@
+package datasource
+
+import (
+	"quant-data-engine/internal/models"
+)
+
+// MoneyflowFields Tushare moneyflow接口的全部字段
+var MoneyflowFields = []string{
+	"ts_code", "trade_date",
+	"buy_sm_vol", "buy_sm_amount", "sell_sm_vol", "sell_sm_amount",
+	"buy_md_vol", "buy_md_amount", "sell_md_vol", "sell_md_amount",
+	"buy_lg_vol", "buy_lg_amount", "sell_lg_vol", "sell_lg_amount",
+	"buy_elg_vol", "buy_elg_amount", "sell_elg_vol", "sell_elg_amount",
+	"net_mf_vol", "net_mf_amount",
+}
+
+// ParseMoneyflow 解析Tushare moneyflow接口返回的个股资金流向，跳过缺少股票代码或交易日期的记录
+func ParseMoneyflow(resp *TushareResponse) []models.Moneyflow {
+	var flows []models.Moneyflow
+	for _, row := range Rows(resp) {
+		flow := models.Moneyflow{
+			TSCode:        row.String("ts_code"),
+			TradeDate:     row.String("trade_date"),
+			BuySmVol:      row.Int("buy_sm_vol"),
+			BuySmAmount:   row.Float("buy_sm_amount"),
+			SellSmVol:     row.Int("sell_sm_vol"),
+			SellSmAmount:  row.Float("sell_sm_amount"),
+			BuyMdVol:      row.Int("buy_md_vol"),
+			BuyMdAmount:   row.Float("buy_md_amount"),
+			SellMdVol:     row.Int("sell_md_vol"),
+			SellMdAmount:  row.Float("sell_md_amount"),
+			BuyLgVol:      row.Int("buy_lg_vol"),
+			BuyLgAmount:   row.Float("buy_lg_amount"),
+			SellLgVol:     row.Int("sell_lg_vol"),
+			SellLgAmount:  row.Float("sell_lg_amount"),
+			BuyElgVol:     row.Int("buy_elg_vol"),
+			BuyElgAmount:  row.Float("buy_elg_amount"),
+			SellElgVol:    row.Int("sell_elg_vol"),
+			SellElgAmount: row.Float("sell_elg_amount"),
+			NetMfVol:      row.Int("net_mf_vol"),
+			NetMfAmount:   row.Float("net_mf_amount"),
+		}
+		if flow.TSCode != "" && flow.TradeDate != "" {
+			flows = append(flows, flow)
+		}
+	}
+	return flows
+}
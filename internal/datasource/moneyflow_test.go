@@ -0,0 +1,60 @@
+package datasource
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoneyflow(t *testing.T) {
+	// 数值字段混合数字和字符串编码，第二条缺少交易日期
+	body := `{"code":0,"data":{
+		"fields":["ts_code","trade_date","buy_sm_vol","buy_sm_amount","sell_lg_vol","sell_lg_amount","net_mf_vol","net_mf_amount"],
+		"items":[
+			["000001.SZ","20240102",12345,"678.9","2100",345.6,-150,"-12.34"],
+			["000002.SZ",null,1,1,1,1,1,1],
+			["600000.SH","20240102","",null,0,0,0,0]
+		]}}`
+
+	var resp TushareResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	flows := ParseMoneyflow(&resp)
+	if len(flows) != 2 {
+		t.Fatalf("Expected 2 moneyflow records, got %d", len(flows))
+	}
+
+	flow := flows[0]
+	if flow.TSCode != "000001.SZ" || flow.TradeDate != "20240102" {
+		t.Errorf("Unexpected key %s %s", flow.TSCode, flow.TradeDate)
+	}
+	if flow.BuySmVol != 12345 {
+		t.Errorf("Expected buy_sm_vol 12345, got %d", flow.BuySmVol)
+	}
+	if flow.BuySmAmount != 678.9 {
+		t.Errorf("Expected buy_sm_amount 678.9, got %v", flow.BuySmAmount)
+	}
+	if flow.SellLgVol != 2100 {
+		t.Errorf("Expected sell_lg_vol 2100, got %d", flow.SellLgVol)
+	}
+	if flow.SellLgAmount != 345.6 {
+		t.Errorf("Expected sell_lg_amount 345.6, got %v", flow.SellLgAmount)
+	}
+	if flow.NetMfVol != -150 || flow.NetMfAmount != -12.34 {
+		t.Errorf("Expected net_mf -150/-12.34, got %d/%v", flow.NetMfVol, flow.NetMfAmount)
+	}
+	// 未请求的字段为0
+	if flow.BuyElgAmount != 0 {
+		t.Errorf("Expected missing buy_elg_amount to be 0, got %v", flow.BuyElgAmount)
+	}
+
+	// 空字符串和null按0处理
+	if flows[1].BuySmVol != 0 || flows[1].BuySmAmount != 0 {
+		t.Errorf("Expected empty values to be 0, got %+v", flows[1])
+	}
+
+	if ParseMoneyflow(nil) != nil {
+		t.Errorf("Expected nil for nil response")
+	}
+}
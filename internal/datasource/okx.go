@@ -0,0 +1,156 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultOKXBaseURL OKX REST接口地址
+const DefaultOKXBaseURL = "https://www.okx.com"
+
+// okxQuoteCurrencies 将BTCUSDT形式的交易对转换为OKX的BTC-USDT时识别的计价币种，USDT、USDC需在USD之前匹配
+var okxQuoteCurrencies = []string{"USDT", "USDC", "USD", "BTC", "ETH", "EUR"}
+
+// OKXDataSource OKX现货行情数据源，最新成交价和24小时成交量来自 /api/v5/market/ticker
+type OKXDataSource struct {
+	baseURL    string
+	httpClient *http.Client
+	// rawSink 保存成功响应的原始响应体，为nil时不保存
+	rawSink RawResponseSink
+	// symbols 缓存支持的交易对列表
+	symbols symbolCache
+}
+
+// NewOKXDataSource 创建OKX数据源，baseURL为空时使用DefaultOKXBaseURL，httpClient为nil时使用超时为DefaultExchangeTimeout的客户端
+func NewOKXDataSource(baseURL string, httpClient *http.Client) *OKXDataSource {
+	if baseURL == "" {
+		baseURL = DefaultOKXBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultExchangeTimeout}
+	}
+	return &OKXDataSource{baseURL: baseURL, httpClient: httpClient}
+}
+
+// SetRawResponseSink 设置原始响应的保存输出，行情请求成功后保存响应体，sink为nil时不保存
+func (o *OKXDataSource) SetRawResponseSink(sink RawResponseSink) {
+	o.rawSink = sink
+}
+
+// Name 获取数据源名称
+func (o *OKXDataSource) Name() string {
+	return models.SourceOKX
+}
+
+// SupportedSymbols 获取状态为live的现货产品ID（如BTC-USDT），结果缓存DefaultSymbolCacheTTL
+func (o *OKXDataSource) SupportedSymbols(ctx context.Context) ([]string, error) {
+	return o.symbols.get(ctx, models.SourceOKX, func(ctx context.Context) ([]string, error) {
+		return fetchOKXSymbols(ctx, o.httpClient, o.baseURL)
+	})
+}
+
+// OKXInstID 将交易对转换为OKX的产品ID，例如BTCUSDT转换为BTC-USDT；已包含"-"时原样返回
+func OKXInstID(symbol string) (string, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if strings.Contains(symbol, "-") {
+		return symbol, nil
+	}
+	for _, quote := range okxQuoteCurrencies {
+		if base, ok := strings.CutSuffix(symbol, quote); ok && base != "" {
+			return base + "-" + quote, nil
+		}
+	}
+	return "", fmt.Errorf("cannot convert symbol %q to an OKX instrument id", symbol)
+}
+
+// okxResponse OKX接口的响应，code为"0"表示成功
+type okxResponse struct {
+	Code string          `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// okxTicker /api/v5/market/ticker 响应中使用的字段，ts为毫秒时间戳
+type okxTicker struct {
+	InstID string `json:"instId"`
+	Last   string `json:"last"`
+	Vol24h string `json:"vol24h"`
+	TS     string `json:"ts"`
+}
+
+// GetMarketData 获取交易对最新成交价和24小时成交量（以交易币种计），返回的数据使用传入的交易对名称
+func (o *OKXDataSource) GetMarketData(symbol string) ([]models.MarketData, error) {
+	instID, err := OKXInstID(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := "/api/v5/market/ticker?" + url.Values{"instId": {instID}}.Encode()
+	resp, err := o.httpClient.Get(o.baseURL + endpoint)
+	if err != nil {
+		return nil, errx.WrapF(err, "failed to request %s ticker", instID)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s ticker request returned status %d: %s", instID, resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errx.WrapF(err, "failed to read %s ticker", instID)
+	}
+	var result okxResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errx.WrapF(err, "failed to decode %s ticker", instID)
+	}
+	if result.Code != "0" {
+		return nil, fmt.Errorf("%s ticker request failed with code %s: %s", instID, result.Code, result.Msg)
+	}
+	var tickers []okxTicker
+	if err := json.Unmarshal(result.Data, &tickers); err != nil {
+		return nil, errx.WrapF(err, "failed to decode %s ticker", instID)
+	}
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("no ticker returned for %s", instID)
+	}
+	ticker := tickers[0]
+
+	price, err := strconv.ParseFloat(ticker.Last, 64)
+	if err != nil {
+		return nil, errx.WrapF(err, "invalid %s last %q", instID, ticker.Last)
+	}
+	volume, err := strconv.ParseFloat(ticker.Vol24h, 64)
+	if err != nil {
+		return nil, errx.WrapF(err, "invalid %s vol24h %q", instID, ticker.Vol24h)
+	}
+	recordRawResponse(context.Background(), o.rawSink, models.SourceOKX, endpoint, body)
+
+	timestamp := time.Now().UTC()
+	if ms, err := strconv.ParseInt(ticker.TS, 10, 64); err == nil && ms > 0 {
+		timestamp = time.UnixMilli(ms).UTC()
+	}
+	return []models.MarketData{{
+		ID:        models.MarketDataID(symbol, models.SourceOKX, timestamp),
+		Symbol:    symbol,
+		Price:     price,
+		Volume:    volume,
+		Timestamp: timestamp,
+		Source:    models.SourceOKX,
+	}}, nil
+}
+
+// GetHistoricalData OKX数据源暂不支持历史数据
+func (o *OKXDataSource) GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error) {
+	return nil, fmt.Errorf("historical data is not supported by %s", models.SourceOKX)
+}
@@ -0,0 +1,112 @@
+package datasource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOKXInstID(t *testing.T) {
+	tests := map[string]string{
+		"BTCUSDT":  "BTC-USDT",
+		"ethusdc":  "ETH-USDC",
+		"BTCUSD":   "BTC-USD",
+		"ETHBTC":   "ETH-BTC",
+		"BTC-USDT": "BTC-USDT",
+	}
+	for symbol, want := range tests {
+		got, err := OKXInstID(symbol)
+		if err != nil || got != want {
+			t.Errorf("OKXInstID(%q) = %q, %v; want %q", symbol, got, err, want)
+		}
+	}
+	for _, symbol := range []string{"USDT", "BTCXYZ", ""} {
+		if _, err := OKXInstID(symbol); err == nil {
+			t.Errorf("Expected error for symbol %q", symbol)
+		}
+	}
+}
+
+func TestOKXDataSource_GetMarketData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v5/market/ticker" || r.URL.Query().Get("instId") != "BTC-USDT" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"code":"0","msg":"","data":[{"instType":"SPOT","instId":"BTC-USDT","last":"43210.5","lastSz":"0.01",` +
+			`"open24h":"42000","high24h":"43500","low24h":"41800","volCcy24h":"533456789.1","vol24h":"12345.678","ts":"1704067200000"}]}`))
+	}))
+	defer server.Close()
+
+	source := NewOKXDataSource(server.URL, nil)
+	if source.Name() != models.SourceOKX {
+		t.Errorf("Expected name okx, got %s", source.Name())
+	}
+
+	data, err := source.GetMarketData("BTCUSDT")
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if len(data) != 1 {
+		t.Fatalf("Expected one record, got %d", len(data))
+	}
+	d := data[0]
+	if d.Price != 43210.5 || d.Volume != 12345.678 {
+		t.Errorf("Unexpected price/volume %v/%v", d.Price, d.Volume)
+	}
+	// 保存的数据使用传入的交易对名称
+	if d.Source != models.SourceOKX || d.Symbol != "BTCUSDT" {
+		t.Errorf("Unexpected source/symbol %s/%s", d.Source, d.Symbol)
+	}
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !d.Timestamp.Equal(ts) {
+		t.Errorf("Expected timestamp from ts, got %v", d.Timestamp)
+	}
+	if want := models.MarketDataID("BTCUSDT", models.SourceOKX, ts); d.ID != want {
+		t.Errorf("Expected id %s, got %s", want, d.ID)
+	}
+
+	// 已经是OKX格式的交易对原样请求
+	data, err = source.GetMarketData("BTC-USDT")
+	if err != nil || len(data) != 1 || data[0].Symbol != "BTC-USDT" {
+		t.Errorf("Expected ticker for BTC-USDT, got %v, %v", data, err)
+	}
+}
+
+func TestOKXDataSource_GetMarketDataErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("instId") {
+		case "DOGE-USDT":
+			w.Write([]byte(`{"code":"51001","msg":"Instrument ID does not exist","data":[]}`))
+		case "ETH-USDT":
+			w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+		case "SOL-USDT":
+			w.Write([]byte(`{"code":"0","msg":"","data":[{"instId":"SOL-USDT","last":"abc","vol24h":"1","ts":"1704067200000"}]}`))
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	source := NewOKXDataSource(server.URL, nil)
+	tests := map[string]string{
+		"DOGEUSDT": "code 51001",
+		"ETHUSDT":  "no ticker",
+		"SOLUSDT":  "invalid SOL-USDT last",
+		"BNBUSDT":  "status 500",
+		"BTCXYZ":   "cannot convert",
+	}
+	for symbol, want := range tests {
+		_, err := source.GetMarketData(symbol)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("GetMarketData(%q): expected error containing %q, got %v", symbol, want, err)
+		}
+	}
+
+	if _, err := source.GetHistoricalData("BTCUSDT", "2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z"); err == nil {
+		t.Error("Expected historical data to be unsupported")
+	}
+}
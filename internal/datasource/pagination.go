@@ -0,0 +1,73 @@
+package datasource
+
+import "context"
+
+// DefaultTusharePageSize 分页拉取时每页的默认条数，不超过Tushare单次调用的返回上限
+const DefaultTusharePageSize = 5000
+
+// MaxTushareDailyPageSize Tushare daily接口单次调用的返回上限；每页条数超过上限时第一页就不满页，分页会提前停止并丢失数据
+const MaxTushareDailyPageSize = 6000
+
+// SetPageSize 设置GetDailyAll等分页拉取方法每页的条数，size<1时使用DefaultTusharePageSize，超过MaxTushareDailyPageSize时按上限处理
+func (c *TushareClient) SetPageSize(size int) {
+	if size < 1 {
+		size = DefaultTusharePageSize
+	}
+	c.pageSize = min(size, MaxTushareDailyPageSize)
+}
+
+// setPageParams limit、offset大于0时写入请求参数
+func setPageParams(params map[string]interface{}, limit, offset int) {
+	if limit > 0 {
+		params["limit"] = limit
+	}
+	if offset > 0 {
+		params["offset"] = offset
+	}
+}
+
+// GetDailyAll 分页获取A股日线行情，从req.Offset开始每次请求一页，直到某页返回的条数少于每页条数，所有数据合并到一个响应中
+// req.Limit大于0时作为每页条数，否则使用SetPageSize设置的值，每页条数不超过MaxTushareDailyPageSize；任一页失败时返回错误，不返回部分数据
+func (c *TushareClient) GetDailyAll(ctx context.Context, req *DailyRequest, fields []string) (*TushareResponse, error) {
+	page := *req
+	if page.Limit < 1 {
+		page.Limit = c.pageSize
+	}
+	if page.Limit < 1 {
+		page.Limit = DefaultTusharePageSize
+	}
+	page.Limit = min(page.Limit, MaxTushareDailyPageSize)
+	return fetchAllPages(page.Offset, page.Limit, func(offset int) (*TushareResponse, error) {
+		page.Offset = offset
+		return c.GetDaily(ctx, &page, fields)
+	})
+}
+
+// fetchAllPages 从offset开始按limit条一页调用fetch，合并各页的items
+func fetchAllPages(offset, limit int, fetch func(offset int) (*TushareResponse, error)) (*TushareResponse, error) {
+	var all *TushareResponse
+	for {
+		resp, err := fetch(offset)
+		if err != nil {
+			return nil, err
+		}
+		if all == nil {
+			all = resp
+		} else if resp.Data != nil {
+			if all.Data == nil {
+				all.Data = resp.Data
+			} else {
+				all.Data.Items = append(all.Data.Items, resp.Data.Items...)
+			}
+		}
+
+		n := 0
+		if resp.Data != nil {
+			n = len(resp.Data.Items)
+		}
+		if n < limit {
+			return all, nil
+		}
+		offset += n
+	}
+}
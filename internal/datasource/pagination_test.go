@@ -0,0 +1,122 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTushareClient_GetDailyAll(t *testing.T) {
+	var offsets []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params struct {
+				Limit  int `json:"limit"`
+				Offset int `json:"offset"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if req.Params.Limit != 2 {
+			t.Errorf("Expected limit 2, got %d", req.Params.Limit)
+		}
+		offsets = append(offsets, req.Params.Offset)
+
+		// 两页满页，第三页为空
+		items := "[]"
+		if req.Params.Offset < 4 {
+			items = fmt.Sprintf(`[["%06d.SZ"],["%06d.SZ"]]`, req.Params.Offset, req.Params.Offset+1)
+		}
+		fmt.Fprintf(w, `{"code":0,"message":"","data":{"fields":["ts_code"],"items":%s}}`, items)
+	}))
+	defer server.Close()
+
+	client, err := NewTushareClientWithURL(server.URL, "token", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	client.SetPageSize(2)
+
+	resp, err := client.GetDailyAll(context.Background(), &DailyRequest{TradeDate: "20240102"}, []string{"ts_code"})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if fmt.Sprint(offsets) != "[0 2 4]" {
+		t.Errorf("Expected offsets [0 2 4], got %v", offsets)
+	}
+	if resp.Data == nil || len(resp.Data.Items) != 4 {
+		t.Fatalf("Expected 4 combined items, got %+v", resp.Data)
+	}
+	for i, item := range resp.Data.Items {
+		if want := fmt.Sprintf("%06d.SZ", i); item[0] != want {
+			t.Errorf("Item %d: expected %s, got %v", i, want, item[0])
+		}
+	}
+}
+
+func TestTushareClient_GetDailyAllStopsOnShortPage(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"code":0,"message":"","data":{"fields":["ts_code"],"items":[["000001.SZ"]]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewTushareClientWithURL(server.URL, "token", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	// 请求中的Limit优先于客户端的每页条数，返回条数少于Limit时不再请求下一页
+	resp, err := client.GetDailyAll(context.Background(), &DailyRequest{TSCode: "000001.SZ", Limit: 3}, []string{"ts_code"})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+	if resp.Data == nil || len(resp.Data.Items) != 1 {
+		t.Errorf("Expected 1 item, got %+v", resp.Data)
+	}
+}
+
+func TestTushareClient_GetDailyAllClampsPageSize(t *testing.T) {
+	var limits []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params struct {
+				Limit int `json:"limit"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		limits = append(limits, req.Params.Limit)
+		w.Write([]byte(`{"code":0,"message":"","data":{"fields":["ts_code"],"items":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewTushareClientWithURL(server.URL, "token", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	// 超过daily单次返回上限的每页条数按上限请求，否则上限处的短页会被误判为最后一页
+	client.SetPageSize(10000)
+	if client.pageSize != MaxTushareDailyPageSize {
+		t.Errorf("Expected page size %d, got %d", MaxTushareDailyPageSize, client.pageSize)
+	}
+	if _, err := client.GetDailyAll(context.Background(), &DailyRequest{TradeDate: "20240102"}, []string{"ts_code"}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if _, err := client.GetDailyAll(context.Background(), &DailyRequest{TradeDate: "20240102", Limit: 8000}, []string{"ts_code"}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if fmt.Sprint(limits) != "[6000 6000]" {
+		t.Errorf("Expected limits [6000 6000], got %v", limits)
+	}
+}
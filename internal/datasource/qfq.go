@@ -0,0 +1,72 @@
+package datasource
+
+import (
+	"quant-data-engine/internal/models"
+)
+
+// BuildQFQBars 将日线行情与复权因子合并为前复权日线数据
+// 缺失复权因子的交易日使用复权因子列表中最后一条记录的因子
+func BuildQFQBars(dailyResp, adjResp *TushareResponse) []models.OHLCVDailyQFQ {
+	if dailyResp == nil || dailyResp.Data == nil {
+		return nil
+	}
+
+	// 构建复权因子映射
+	adjMap := make(map[string]float64)
+	var lastAdjFactor float64 = 1.0
+	for _, f := range ParseAdjFactors(adjResp) {
+		adjMap[f.TradeDate] = f.AdjFactor
+		lastAdjFactor = f.AdjFactor
+	}
+
+	// 解析并应用复权
+	var ohlcvList []models.OHLCVDailyQFQ
+	for _, row := range Rows(dailyResp) {
+		ohlcv := models.OHLCVDailyQFQ{
+			Symbol:    row.String("ts_code"),
+			TradeDate: row.String("trade_date"),
+			Open:      row.Float("open"),
+			High:      row.Float("high"),
+			Low:       row.Float("low"),
+			Close:     row.Float("close"),
+			Volume:    row.Float("vol"),
+			Turnover:  row.Float("amount"),
+		}
+
+		// 应用前复权调整因子
+		if ohlcv.TradeDate != "" && ohlcv.Symbol != "" {
+			adjFactor := adjMap[ohlcv.TradeDate]
+			if adjFactor == 0 {
+				adjFactor = lastAdjFactor
+			}
+			if adjFactor > 0 && adjFactor != 1.0 {
+				ohlcv.Open = ohlcv.Open * adjFactor
+				ohlcv.High = ohlcv.High * adjFactor
+				ohlcv.Low = ohlcv.Low * adjFactor
+				ohlcv.Close = ohlcv.Close * adjFactor
+			}
+			ohlcvList = append(ohlcvList, ohlcv)
+		}
+	}
+
+	return ohlcvList
+}
+
+// ParseAdjFactors 解析Tushare adj_factor接口返回的复权因子，跳过缺少交易日期的记录
+func ParseAdjFactors(adjResp *TushareResponse) []models.AdjFactor {
+	var factors []models.AdjFactor
+	for _, row := range Rows(adjResp) {
+		factor := models.AdjFactor{
+			TSCode:    row.String("ts_code"),
+			TradeDate: row.String("trade_date"),
+			AdjFactor: 1.0,
+		}
+		if row.Has("adj_factor") {
+			factor.AdjFactor = row.Float("adj_factor")
+		}
+		if factor.TradeDate != "" {
+			factors = append(factors, factor)
+		}
+	}
+	return factors
+}
@@ -0,0 +1,124 @@
+package datasource
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"quant-data-engine/internal/models"
+)
+
+// RateLimitReporter 可以报告限流状态的客户端
+type RateLimitReporter interface {
+	RateLimits() []models.RateLimitState
+}
+
+// RateLimiter 按Tushare接口分别限流的令牌桶，每个接口每分钟最多perMinute次调用
+// 桶容量为perMinute，空闲后允许短时突发；令牌按 60s/perMinute 的间隔匀速补充
+type RateLimiter struct {
+	mutex     sync.Mutex
+	perMinute int
+	interval  time.Duration
+	buckets   map[string]*tokenBucket
+	now       func() time.Time
+}
+
+// tokenBucket 单个接口的令牌桶
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	calls  int64
+}
+
+// NewRateLimiter 创建限流器，perMinute<=0时返回nil，表示不限流
+func NewRateLimiter(perMinute int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		perMinute: perMinute,
+		interval:  time.Minute / time.Duration(perMinute),
+		buckets:   make(map[string]*tokenBucket),
+		now:       time.Now,
+	}
+}
+
+// Wait 获取api的一个令牌，令牌不足时阻塞等待，ctx取消时返回ctx.Err()
+func (l *RateLimiter) Wait(ctx context.Context, api string) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	for {
+		l.mutex.Lock()
+		b := l.refill(api)
+		if b.tokens >= 1 {
+			b.tokens--
+			b.calls++
+			l.mutex.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) * float64(l.interval))
+		l.mutex.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// refill 按距上次补充的时间补充令牌，调用方需持有锁
+func (l *RateLimiter) refill(api string) *tokenBucket {
+	now := l.now()
+	b, ok := l.buckets[api]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.perMinute), last: now}
+		l.buckets[api] = b
+		return b
+	}
+
+	elapsed := now.Sub(b.last)
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(l.perMinute), b.tokens+float64(elapsed)/float64(l.interval))
+		b.last = now
+	}
+	return b
+}
+
+// State 返回已调用过的各接口的限流状态，按接口名排序
+func (l *RateLimiter) State() []models.RateLimitState {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	states := make([]models.RateLimitState, 0, len(l.buckets))
+	for api := range l.buckets {
+		b := l.refill(api)
+
+		// 桶满时不再补充令牌；否则为距离下一个完整令牌的时间
+		var next time.Duration
+		if b.tokens < float64(l.perMinute) {
+			next = time.Duration((math.Floor(b.tokens) + 1 - b.tokens) * float64(l.interval))
+		}
+
+		states = append(states, models.RateLimitState{
+			API:              api,
+			RatePerMinute:    l.perMinute,
+			AvailableTokens:  b.tokens,
+			NextTokenSeconds: next.Seconds(),
+			Calls:            b.calls,
+		})
+	}
+
+	sort.Slice(states, func(i, j int) bool { return states[i].API < states[j].API })
+	return states
+}
@@ -0,0 +1,158 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_State(t *testing.T) {
+	now := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	limiter := NewRateLimiter(60)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background(), "daily"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := limiter.Wait(context.Background(), "adj_factor"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	states := limiter.State()
+	if len(states) != 2 {
+		t.Fatalf("Expected 2 APIs, got %d", len(states))
+	}
+	if states[0].API != "adj_factor" || states[1].API != "daily" {
+		t.Errorf("Expected APIs sorted by name, got %s, %s", states[0].API, states[1].API)
+	}
+
+	daily := states[1]
+	if daily.RatePerMinute != 60 {
+		t.Errorf("Expected rate 60, got %d", daily.RatePerMinute)
+	}
+	if daily.AvailableTokens != 57 {
+		t.Errorf("Expected 57 available tokens, got %v", daily.AvailableTokens)
+	}
+	if daily.Calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", daily.Calls)
+	}
+	if daily.NextTokenSeconds != 1 {
+		t.Errorf("Expected next token in 1s, got %v", daily.NextTokenSeconds)
+	}
+
+	// 1.5秒后补充1.5个令牌，距离下一个完整令牌0.5秒
+	now = now.Add(1500 * time.Millisecond)
+	daily = limiter.State()[1]
+	if math.Abs(daily.AvailableTokens-58.5) > 1e-9 {
+		t.Errorf("Expected 58.5 available tokens, got %v", daily.AvailableTokens)
+	}
+	if math.Abs(daily.NextTokenSeconds-0.5) > 1e-9 {
+		t.Errorf("Expected next token in 0.5s, got %v", daily.NextTokenSeconds)
+	}
+
+	// 令牌不超过桶容量，桶满时没有待补充的令牌
+	now = now.Add(time.Hour)
+	daily = limiter.State()[1]
+	if daily.AvailableTokens != 60 || daily.NextTokenSeconds != 0 {
+		t.Errorf("Expected full bucket, got %v tokens, next in %vs", daily.AvailableTokens, daily.NextTokenSeconds)
+	}
+}
+
+func TestRateLimiter_WaitBlocksUntilContextDone(t *testing.T) {
+	limiter := NewRateLimiter(1)
+	if err := limiter.Wait(context.Background(), "daily"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 令牌已用完，下一个令牌要一分钟后才补充
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := limiter.Wait(ctx, "daily")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Wait to return promptly after cancellation, took %v", elapsed)
+	}
+
+	// 不同接口分别限流
+	if err := limiter.Wait(context.Background(), "adj_factor"); err != nil {
+		t.Errorf("Expected separate bucket for adj_factor, got %v", err)
+	}
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	limiter := NewRateLimiter(0)
+	if limiter != nil {
+		t.Fatalf("Expected nil limiter when rate is 0")
+	}
+	if err := limiter.Wait(context.Background(), "daily"); err != nil {
+		t.Errorf("Expected no error from disabled limiter, got %v", err)
+	}
+	if states := limiter.State(); states != nil {
+		t.Errorf("Expected no state from disabled limiter, got %+v", states)
+	}
+}
+
+func TestTushareClient_RateLimitSpacesCalls(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, time.Now())
+		mutex.Unlock()
+		w.Write([]byte(`{"code":0,"message":"","data":{"fields":["ts_code"],"items":[]}}`))
+	}))
+	defer server.Close()
+
+	// 每分钟1200次，即每50ms一个令牌
+	client, err := NewTushareClientWithLimit(1200)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	client.apiURL = server.URL
+	client.httpClient = server.Client()
+	// 模拟本分钟的额度只剩一次，后续调用需要排队等待补充
+	client.limiter.buckets["daily"] = &tokenBucket{tokens: 1, last: time.Now()}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	elapsed := time.Since(start)
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Before(requests[j]) })
+
+	// 额度不足时排队而不是失败
+	for err := range errs {
+		t.Errorf("Expected queued call to succeed, got '%v'", err)
+	}
+	if len(requests) != 10 {
+		t.Fatalf("Expected 10 requests, got %d", len(requests))
+	}
+	// 9次排队的调用每次至少间隔一个令牌补充周期，留出计时误差
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("Expected 9 queued calls to take at least 400ms, took %v", elapsed)
+	}
+	if span := requests[len(requests)-1].Sub(requests[0]); span < 400*time.Millisecond {
+		t.Errorf("Expected requests to be spread over at least 400ms, got %v", span)
+	}
+}
@@ -0,0 +1,53 @@
+package datasource
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"quant-data-engine/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RawResponseSink 保存数据源原始响应的输出，用于审计和回放
+type RawResponseSink interface {
+	SaveRawResponse(ctx context.Context, r models.RawResponse) error
+}
+
+// rawSaveTimeout 保存一条原始响应的最长时间，超时不影响数据获取
+const rawSaveTimeout = 5 * time.Second
+
+// SourceTushare 原始响应中Tushare数据源的名称
+const SourceTushare = "tushare"
+
+// recordRawResponse 将成功获取的原始响应体gzip压缩后保存，sink为nil时不保存
+// 保存是尽力而为的：失败只记录日志；ctx被取消时仍会保存，最多等待rawSaveTimeout
+func recordRawResponse(ctx context.Context, sink RawResponseSink, source, endpoint string, body []byte) {
+	if sink == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		logrus.Warnf("Failed to compress raw %s response from %s: %v", endpoint, source, err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		logrus.Warnf("Failed to compress raw %s response from %s: %v", endpoint, source, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rawSaveTimeout)
+	defer cancel()
+	err := sink.SaveRawResponse(ctx, models.RawResponse{
+		Source:    source,
+		Endpoint:  endpoint,
+		Body:      buf.Bytes(),
+		FetchedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		logrus.Warnf("Failed to persist raw %s response from %s: %v", endpoint, source, err)
+	}
+}
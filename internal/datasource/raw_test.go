@@ -0,0 +1,128 @@
+package datasource
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/models"
+	"sync"
+	"testing"
+)
+
+// recordingRawSink 记录保存的原始响应，err不为nil时保存失败
+type recordingRawSink struct {
+	mutex     sync.Mutex
+	responses []models.RawResponse
+	err       error
+}
+
+func (s *recordingRawSink) SaveRawResponse(ctx context.Context, r models.RawResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.responses = append(s.responses, r)
+	return s.err
+}
+
+// gunzipBody 解压保存的原始响应体
+func gunzipBody(t *testing.T, body []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Expected gzipped body, got '%v'", err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to gunzip body: %v", err)
+	}
+	return string(raw)
+}
+
+// TestTushareClient_RawResponse 测试设置了输出时保存成功响应的原始响应体，未设置或调用失败时不保存
+func TestTushareClient_RawResponse(t *testing.T) {
+	body := `{"code":0,"message":"","data":{"fields":["ts_code"],"items":[["000001.SZ"]]}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sink := &recordingRawSink{}
+	client := &TushareClient{apiURL: server.URL, apiKey: "token", httpClient: server.Client()}
+	client.SetRawResponseSink(sink)
+	if _, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if len(sink.responses) != 1 {
+		t.Fatalf("Expected one raw response, got %d", len(sink.responses))
+	}
+	r := sink.responses[0]
+	if r.Source != SourceTushare || r.Endpoint != "daily" || r.FetchedAt.IsZero() {
+		t.Errorf("Unexpected raw response metadata %s/%s/%v", r.Source, r.Endpoint, r.FetchedAt)
+	}
+	if got := gunzipBody(t, r.Body); got != body {
+		t.Errorf("Expected raw body %s, got %s", body, got)
+	}
+
+	// 保存失败不影响数据获取
+	sink.err = errors.New("database down")
+	if _, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"}); err != nil {
+		t.Fatalf("Expected no error when raw save fails, got '%v'", err)
+	}
+
+	// 未启用时不保存
+	disabled := &TushareClient{apiURL: server.URL, apiKey: "token", httpClient: server.Client()}
+	disabled.SetRawResponseSink(nil)
+	if _, err := disabled.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if len(sink.responses) != 2 {
+		t.Errorf("Expected no raw response when disabled, got %d in total", len(sink.responses))
+	}
+}
+
+// TestTushareClient_RawResponseAPIError 测试Tushare返回错误码时不保存原始响应
+func TestTushareClient_RawResponseAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":40001,"message":"invalid token"}`))
+	}))
+	defer server.Close()
+
+	sink := &recordingRawSink{}
+	client := &TushareClient{apiURL: server.URL, apiKey: "token", httpClient: server.Client()}
+	client.SetRawResponseSink(sink)
+	if _, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"}); err == nil {
+		t.Fatal("Expected error")
+	}
+	if len(sink.responses) != 0 {
+		t.Errorf("Expected no raw response for failed call, got %d", len(sink.responses))
+	}
+}
+
+// TestExchangeDataSource_RawResponse 测试交易所行情请求成功后保存原始响应
+func TestExchangeDataSource_RawResponse(t *testing.T) {
+	body := `{"symbol":"BTCUSDT","lastPrice":"43210.5","volume":"1.5","closeTime":1704067200000}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sink := &recordingRawSink{}
+	source := NewExchangeDataSource("binance", server.URL, "", "", server.Client())
+	source.SetRawResponseSink(sink)
+	if _, err := source.GetMarketData("BTCUSDT"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if len(sink.responses) != 1 {
+		t.Fatalf("Expected one raw response, got %d", len(sink.responses))
+	}
+	r := sink.responses[0]
+	if r.Source != models.SourceBinance || r.Endpoint != "/api/v3/ticker/24hr?symbol=BTCUSDT" {
+		t.Errorf("Unexpected raw response metadata %s/%s", r.Source, r.Endpoint)
+	}
+	if got := gunzipBody(t, r.Body); got != body {
+		t.Errorf("Expected raw body %s, got %s", body, got)
+	}
+}
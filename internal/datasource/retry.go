@@ -0,0 +1,64 @@
+package datasource
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy Tushare请求失败时的重试策略
+// 网络错误、HTTP 429/5xx和表示调用过于频繁的Tushare错误码会重试，第n次重试前等待 BaseDelay*2^n（不超过MaxDelay）的一半到全部之间的随机时间
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// tushareThrottleCodes 表示调用过于频繁的Tushare错误码，稍后重试可以成功
+var tushareThrottleCodes = map[int]bool{
+	40203: true,
+}
+
+// retryableStatus HTTP状态码是否为可重试的临时错误
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// backoff 返回第attempt次重试（从0开始）前的等待时间，带随机抖动避免多个请求同时重试
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// sleepContext 等待d，ctx取消时提前返回ctx.Err()
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
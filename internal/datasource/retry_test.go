@@ -0,0 +1,121 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRetryTestClient 创建请求server的客户端，重试等待很短
+func newRetryTestClient(t *testing.T, server *httptest.Server, maxRetries int) *TushareClient {
+	client, err := NewTushareClientWithURL(server.URL, "token", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	client.SetRetryPolicy(RetryPolicy{MaxRetries: maxRetries, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	return client
+}
+
+func TestTushareClient_RetriesTransientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("bad gateway"))
+		case 2:
+			w.Write([]byte(`{"code":40203,"message":"抱歉，您每分钟最多访问该接口120次"}`))
+		default:
+			w.Write([]byte(`{"code":0,"message":"","data":{"fields":["ts_code"],"items":[["000001.SZ"]]}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := newRetryTestClient(t, server, 3)
+	resp, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"})
+	if err != nil {
+		t.Fatalf("Expected success after retries, got '%v'", err)
+	}
+	if resp.Data == nil || len(resp.Data.Items) != 1 {
+		t.Errorf("Expected one item, got %+v", resp.Data)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Expected 3 calls, got %d", got)
+	}
+}
+
+func TestTushareClient_RetryLimits(t *testing.T) {
+	var calls atomic.Int32
+	code := `{"code":40203,"message":"too many requests"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(code))
+	}))
+	defer server.Close()
+
+	// 重试次数用完后返回最后一次的错误
+	client := newRetryTestClient(t, server, 2)
+	_, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"})
+	if !errors.Is(err, ErrTushareAPI) {
+		t.Fatalf("Expected ErrTushareAPI, got '%v'", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Expected 1 call plus 2 retries, got %d", got)
+	}
+
+	// 非限流的错误码不重试
+	calls.Store(0)
+	code = `{"code":40101,"message":"token invalid"}`
+	if _, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"}); err == nil {
+		t.Fatal("Expected error for invalid token")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected no retry for code 40101, got %d calls", got)
+	}
+}
+
+func TestTushareClient_RetryCanceledByContext(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newRetryTestClient(t, server, 5)
+	client.SetRetryPolicy(RetryPolicy{MaxRetries: 5, BaseDelay: time.Minute, MaxDelay: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetDaily(ctx, &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got '%v'", err)
+	}
+	var statusErr *ErrHTTPStatus
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected last HTTP error to be kept, got '%v'", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected pending retry to be canceled promptly, took %v", elapsed)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected 1 call before cancellation, got %d", got)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for i := 0; i < 20; i++ {
+			got := policy.backoff(attempt)
+			if got < want/2 || got > want {
+				t.Errorf("attempt %d: expected backoff in [%v, %v], got %v", attempt, want/2, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"quant-data-engine/internal/errx"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSymbolCacheTTL 数据源支持的交易对列表的缓存时间，交易所上下架交易对的频率很低
+const DefaultSymbolCacheTTL = time.Hour
+
+// MockSupportedSymbols 模拟数据源支持的交易对
+var MockSupportedSymbols = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "SOLUSDT", "XRPUSDT"}
+
+// symbolCache 缓存数据源支持的交易对列表，零值可用
+// 过期后重新获取，重新获取失败时继续返回过期的列表，避免交易所接口短暂不可用时所有交易对被跳过
+type symbolCache struct {
+	mu        sync.Mutex
+	symbols   []string
+	fetchedAt time.Time
+}
+
+// get 返回缓存的交易对列表，缓存为空或超过DefaultSymbolCacheTTL时调用fetch重新获取
+func (c *symbolCache) get(ctx context.Context, source string, fetch func(ctx context.Context) ([]string, error)) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.symbols != nil && time.Since(c.fetchedAt) < DefaultSymbolCacheTTL {
+		return c.symbols, nil
+	}
+	symbols, err := fetch(ctx)
+	if err != nil {
+		if c.symbols != nil {
+			logrus.Warnf("Failed to refresh supported symbols of %s, using list fetched at %s: %v",
+				source, c.fetchedAt.Format(time.RFC3339), err)
+			return c.symbols, nil
+		}
+		return nil, err
+	}
+	sort.Strings(symbols)
+	c.symbols = symbols
+	c.fetchedAt = time.Now()
+	return symbols, nil
+}
+
+// binanceExchangeInfo /api/v3/exchangeInfo 响应中使用的字段
+type binanceExchangeInfo struct {
+	Symbols []struct {
+		Symbol string `json:"symbol"`
+		Status string `json:"status"`
+	} `json:"symbols"`
+}
+
+// fetchBinanceSymbols 从 /api/v3/exchangeInfo 获取状态为TRADING的交易对，apiKey为空时不发送API Key
+func fetchBinanceSymbols(ctx context.Context, client *http.Client, baseURL, apiKey string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/v3/exchangeInfo", nil)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to create exchangeInfo request")
+	}
+	if apiKey != "" {
+		req.Header.Set("X-MBX-APIKEY", apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to request exchangeInfo")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("exchangeInfo request returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var info binanceExchangeInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, errx.Wrap(err, "failed to decode exchangeInfo")
+	}
+	symbols := make([]string, 0, len(info.Symbols))
+	for _, s := range info.Symbols {
+		if s.Status == "TRADING" {
+			symbols = append(symbols, s.Symbol)
+		}
+	}
+	return symbols, nil
+}
+
+// okxInstrument /api/v5/public/instruments 响应中使用的字段
+type okxInstrument struct {
+	InstID string `json:"instId"`
+	State  string `json:"state"`
+}
+
+// fetchOKXSymbols 从 /api/v5/public/instruments 获取状态为live的现货产品ID
+func fetchOKXSymbols(ctx context.Context, client *http.Client, baseURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/v5/public/instruments?instType=SPOT", nil)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to create instruments request")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to request instruments")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("instruments request returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result okxResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errx.Wrap(err, "failed to decode instruments")
+	}
+	if result.Code != "0" {
+		return nil, fmt.Errorf("instruments request failed with code %s: %s", result.Code, result.Msg)
+	}
+	var instruments []okxInstrument
+	if err := json.Unmarshal(result.Data, &instruments); err != nil {
+		return nil, errx.Wrap(err, "failed to decode instruments")
+	}
+	symbols := make([]string, 0, len(instruments))
+	for _, inst := range instruments {
+		if inst.State == "live" {
+			symbols = append(symbols, inst.InstID)
+		}
+	}
+	return symbols, nil
+}
@@ -0,0 +1,112 @@
+package datasource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMockDataSource_SupportedSymbols(t *testing.T) {
+	source := NewMockDataSource("binance")
+
+	symbols, err := source.SupportedSymbols(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if !reflect.DeepEqual(symbols, MockSupportedSymbols) {
+		t.Errorf("Expected %v, got %v", MockSupportedSymbols, symbols)
+	}
+
+	// 返回副本，修改结果不影响后续调用
+	symbols[0] = "CHANGED"
+	symbols, _ = source.SupportedSymbols(context.Background())
+	if symbols[0] != MockSupportedSymbols[0] {
+		t.Errorf("Expected canned list to be unchanged, got %v", symbols)
+	}
+}
+
+func TestExchangeDataSource_SupportedSymbols(t *testing.T) {
+	var requests atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/exchangeInfo" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		requests.Add(1)
+		if failing.Load() {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("X-MBX-APIKEY") != "key" {
+			t.Errorf("Expected api key header, got '%s'", r.Header.Get("X-MBX-APIKEY"))
+		}
+		w.Write([]byte(`{"timezone":"UTC","symbols":[` +
+			`{"symbol":"ETHUSDT","status":"TRADING","baseAsset":"ETH","quoteAsset":"USDT"},` +
+			`{"symbol":"LUNAUSDT","status":"BREAK","baseAsset":"LUNA","quoteAsset":"USDT"},` +
+			`{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT"}]}`))
+	}))
+	defer server.Close()
+
+	source := NewExchangeDataSource("binance", server.URL, "key", "secret", nil)
+	want := []string{"BTCUSDT", "ETHUSDT"}
+	for i := 0; i < 2; i++ {
+		symbols, err := source.SupportedSymbols(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got '%v'", err)
+		}
+		if !reflect.DeepEqual(symbols, want) {
+			t.Errorf("Expected %v, got %v", want, symbols)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected cached list to be reused, got %d requests", requests.Load())
+	}
+
+	// 缓存过期后重新获取失败时继续使用过期的列表
+	source.symbols.fetchedAt = time.Now().Add(-2 * DefaultSymbolCacheTTL)
+	failing.Store(true)
+	symbols, err := source.SupportedSymbols(context.Background())
+	if err != nil || !reflect.DeepEqual(symbols, want) {
+		t.Errorf("Expected stale list %v, got %v, %v", want, symbols, err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected expired list to be refreshed, got %d requests", requests.Load())
+	}
+
+	// 从未获取成功时返回错误
+	fresh := NewExchangeDataSource("binance", server.URL, "key", "secret", nil)
+	if _, err := fresh.SupportedSymbols(context.Background()); err == nil {
+		t.Error("Expected error when exchangeInfo is unavailable")
+	}
+
+	if _, err := NewExchangeDataSource("kraken", server.URL, "", "", nil).SupportedSymbols(context.Background()); err == nil {
+		t.Error("Expected error for unsupported exchange")
+	}
+}
+
+func TestOKXDataSource_SupportedSymbols(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v5/public/instruments" || r.URL.Query().Get("instType") != "SPOT" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"code":"0","msg":"","data":[` +
+			`{"instType":"SPOT","instId":"ETH-USDT","state":"live"},` +
+			`{"instType":"SPOT","instId":"NEW-USDT","state":"preopen"},` +
+			`{"instType":"SPOT","instId":"BTC-USDT","state":"live"}]}`))
+	}))
+	defer server.Close()
+
+	symbols, err := NewOKXDataSource(server.URL, nil).SupportedSymbols(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if want := []string{"BTC-USDT", "ETH-USDT"}; !reflect.DeepEqual(symbols, want) {
+		t.Errorf("Expected %v, got %v", want, symbols)
+	}
+}
@@ -2,29 +2,35 @@ package datasource
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"quant-data-engine/internal/config"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
 
 	"github.com/sirupsen/logrus"
 )
 
 // TushareClientInterface Tushare 客户端接口
 type TushareClientInterface interface {
-	GetStockBasic(req *StockBasicRequest, fields []string) (*TushareResponse, error)
-	GetTradeCal(req *TradeCalRequest, fields []string) (*TushareResponse, error)
-	GetNewShare(req *NewShareRequest, fields []string) (*TushareResponse, error)
-	GetStockCompany(req *StockCompanyRequest, fields []string) (*TushareResponse, error)
-	GetStkManagers(req *StkManagersRequest, fields []string) (*TushareResponse, error)
-	GetStkRewards(req *StkRewardsRequest, fields []string) (*TushareResponse, error)
-	GetDaily(req *DailyRequest, fields []string) (*TushareResponse, error)
-	GetProBar(req *ProBarRequest, fields []string) (*TushareResponse, error)
-	GetAdjFactor(req *AdjFactorRequest, fields []string) (*TushareResponse, error)
+	GetStockBasic(ctx context.Context, req *StockBasicRequest, fields []string) (*TushareResponse, error)
+	GetTradeCal(ctx context.Context, req *TradeCalRequest, fields []string) (*TushareResponse, error)
+	GetNewShare(ctx context.Context, req *NewShareRequest, fields []string) (*TushareResponse, error)
+	GetStockCompany(ctx context.Context, req *StockCompanyRequest, fields []string) (*TushareResponse, error)
+	GetStkManagers(ctx context.Context, req *StkManagersRequest, fields []string) (*TushareResponse, error)
+	GetStkRewards(ctx context.Context, req *StkRewardsRequest, fields []string) (*TushareResponse, error)
+	GetDaily(ctx context.Context, req *DailyRequest, fields []string) (*TushareResponse, error)
+	GetProBar(ctx context.Context, req *ProBarRequest, fields []string) (*TushareResponse, error)
+	GetAdjFactor(ctx context.Context, req *AdjFactorRequest, fields []string) (*TushareResponse, error)
+	GetMoneyflow(ctx context.Context, req *MoneyflowRequest, fields []string) (*TushareResponse, error)
 }
 
 // TushareClient Tushare API客户端
@@ -32,20 +38,111 @@ type TushareClient struct {
 	apiURL     string
 	apiKey     string
 	httpClient *http.Client
+	limiter    *RateLimiter
+	retry      RetryPolicy
+	pageSize   int
+	// rawSink 保存成功响应的原始响应体，为nil时不保存
+	rawSink RawResponseSink
 }
 
-// NewTushareClient 创建Tushare API客户端
-func NewTushareClient() *TushareClient {
+// DefaultTushareBaseURL Tushare Pro接口地址
+const DefaultTushareBaseURL = "https://api.tushare.pro"
+
+// NewTushareClient 使用配置创建Tushare API客户端，TUSHARE_BASE_URL不是合法的http(s)地址时返回错误
+func NewTushareClient() (*TushareClient, error) {
 	cfg := config.AppConfig
+	client, err := NewTushareClientWithURL(cfg.TushareBaseURL, cfg.TushareAPIKey, cfg.TushareRateLimit)
+	if err != nil {
+		return nil, err
+	}
+	client.SetRetryPolicy(RetryPolicy{
+		MaxRetries: cfg.TushareRetries,
+		BaseDelay:  time.Duration(cfg.TushareRetryBaseMs) * time.Millisecond,
+		MaxDelay:   DefaultRetryPolicy.MaxDelay,
+	})
+	client.SetPageSize(cfg.TusharePageSize)
+	return client, nil
+}
+
+// NewTushareClientWithLimit 使用配置创建Tushare API客户端，每个接口每分钟最多调用rate次，覆盖TUSHARE_RATE_LIMIT
+// 配置未加载时使用默认地址和空token，便于测试中替换地址
+func NewTushareClientWithLimit(rate int) (*TushareClient, error) {
+	if cfg := config.AppConfig; cfg != nil {
+		return NewTushareClientWithURL(cfg.TushareBaseURL, cfg.TushareAPIKey, rate)
+	}
+	return NewTushareClientWithURL(DefaultTushareBaseURL, "", rate)
+}
+
+// NewTushareClientWithURL 创建请求baseURL的Tushare API客户端，baseURL可包含路径（如镜像或代理的前缀），为空时使用默认地址
+// perMinute为每个接口每分钟最多调用次数，<=0时不限流
+func NewTushareClientWithURL(baseURL, apiKey string, perMinute int) (*TushareClient, error) {
+	if baseURL == "" {
+		baseURL = DefaultTushareBaseURL
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errx.WrapF(err, "invalid tushare base url %q", baseURL)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid tushare base url %q: must be an absolute http or https url", baseURL)
+	}
+	if parsed.Scheme == "http" {
+		logrus.Warnf("Tushare base url %s uses plain HTTP, the API token is sent unencrypted", baseURL)
+	}
+
+	client := NewTushareClientWithConfig(baseURL, apiKey, nil)
+	client.limiter = NewRateLimiter(perMinute)
+	return client, nil
+}
+
+// NewTushareClientWithConfig 创建请求apiURL的Tushare API客户端，不校验地址、不限流，httpClient为nil时使用30秒超时的默认客户端
+// 用于在测试中指向httptest.Server或注入自定义的http.Client
+func NewTushareClientWithConfig(apiURL, apiKey string, httpClient *http.Client) *TushareClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
 	return &TushareClient{
-		apiURL: "http://api.tushare.pro",
-		apiKey: cfg.TushareAPIKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiURL:     apiURL,
+		apiKey:     apiKey,
+		httpClient: httpClient,
+		limiter:    NewRateLimiter(0),
+		retry:      DefaultRetryPolicy,
+		pageSize:   DefaultTusharePageSize,
 	}
 }
 
+// SetRetryPolicy 设置请求失败时的重试策略，MaxRetries<=0时不重试
+func (c *TushareClient) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// SetRawResponseSink 设置原始响应的保存输出，每次调用成功后保存响应体，sink为nil时不保存
+func (c *TushareClient) SetRawResponseSink(sink RawResponseSink) {
+	c.rawSink = sink
+}
+
+// RateLimits 返回各接口的限流状态，未启用限流时返回nil
+func (c *TushareClient) RateLimits() []models.RateLimitState {
+	return c.limiter.State()
+}
+
+// maxErrorBodyLen ErrHTTPStatus中保留的响应体最大长度
+const maxErrorBodyLen = 512
+
+// ErrTushareAPI Tushare API返回非0的错误码（如token无效、权限不足、超出调用额度）
+var ErrTushareAPI = errors.New("Tushare API error")
+
+// ErrHTTPStatus Tushare API返回非2xx的HTTP状态码，通常来自网关（502/503等）而不是Tushare本身
+type ErrHTTPStatus struct {
+	Code int
+	Body string
+}
+
+// Error 实现error接口
+func (e *ErrHTTPStatus) Error() string {
+	return fmt.Sprintf("Tushare API HTTP status %d: %s", e.Code, e.Body)
+}
+
 // TushareRequest Tushare API请求参数
 type TushareRequest struct {
 	Token   string                 `json:"token"`
@@ -75,6 +172,8 @@ type StockBasicRequest struct {
 	ListStatus string `json:"list_status,omitempty"`
 	Exchange   string `json:"exchange,omitempty"`
 	IsHS       string `json:"is_hs,omitempty"`
+	Limit      int    `json:"limit,omitempty"`  // 单次最多返回条数，0时使用接口默认值
+	Offset     int    `json:"offset,omitempty"` // 跳过的条数
 }
 
 // TradeCalRequest 交易日历请求参数
@@ -117,6 +216,8 @@ type DailyRequest struct {
 	TradeDate string `json:"trade_date,omitempty"`
 	StartDate string `json:"start_date,omitempty"`
 	EndDate   string `json:"end_date,omitempty"`
+	Limit     int    `json:"limit,omitempty"`  // 单次最多返回条数，0时使用接口默认值
+	Offset    int    `json:"offset,omitempty"` // 跳过的条数
 }
 
 // ProBarRequest 行情数据请求参数（支持复权）
@@ -133,7 +234,7 @@ type ProBarRequest struct {
 }
 
 // GetStockBasic 获取股票基础信息
-func (c *TushareClient) GetStockBasic(req *StockBasicRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetStockBasic(ctx context.Context, req *StockBasicRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.TSCode != "" {
 		params["ts_code"] = req.TSCode
@@ -153,12 +254,13 @@ func (c *TushareClient) GetStockBasic(req *StockBasicRequest, fields []string) (
 	if req.IsHS != "" {
 		params["is_hs"] = req.IsHS
 	}
+	setPageParams(params, req.Limit, req.Offset)
 
-	return c.callAPI("stock_basic", params, fields)
+	return c.callAPI(ctx, "stock_basic", params, fields)
 }
 
 // GetTradeCal 获取交易日历
-func (c *TushareClient) GetTradeCal(req *TradeCalRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetTradeCal(ctx context.Context, req *TradeCalRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.Exchange != "" {
 		params["exchange"] = req.Exchange
@@ -173,11 +275,11 @@ func (c *TushareClient) GetTradeCal(req *TradeCalRequest, fields []string) (*Tus
 		params["is_open"] = req.IsOpen
 	}
 
-	return c.callAPI("trade_cal", params, fields)
+	return c.callAPI(ctx, "trade_cal", params, fields)
 }
 
 // GetNewShare 获取新股上市列表
-func (c *TushareClient) GetNewShare(req *NewShareRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetNewShare(ctx context.Context, req *NewShareRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.StartDate != "" {
 		params["start_date"] = req.StartDate
@@ -186,11 +288,11 @@ func (c *TushareClient) GetNewShare(req *NewShareRequest, fields []string) (*Tus
 		params["end_date"] = req.EndDate
 	}
 
-	return c.callAPI("new_share", params, fields)
+	return c.callAPI(ctx, "new_share", params, fields)
 }
 
 // GetStockCompany 获取上市公司基础信息
-func (c *TushareClient) GetStockCompany(req *StockCompanyRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetStockCompany(ctx context.Context, req *StockCompanyRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.TSCode != "" {
 		params["ts_code"] = req.TSCode
@@ -199,11 +301,11 @@ func (c *TushareClient) GetStockCompany(req *StockCompanyRequest, fields []strin
 		params["exchange"] = req.Exchange
 	}
 
-	return c.callAPI("stock_company", params, fields)
+	return c.callAPI(ctx, "stock_company", params, fields)
 }
 
 // GetStkManagers 获取上市公司管理层
-func (c *TushareClient) GetStkManagers(req *StkManagersRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetStkManagers(ctx context.Context, req *StkManagersRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.TSCode != "" {
 		params["ts_code"] = req.TSCode
@@ -218,11 +320,11 @@ func (c *TushareClient) GetStkManagers(req *StkManagersRequest, fields []string)
 		params["end_date"] = req.EndDate
 	}
 
-	return c.callAPI("stk_managers", params, fields)
+	return c.callAPI(ctx, "stk_managers", params, fields)
 }
 
 // GetStkRewards 获取管理层薪酬和持股
-func (c *TushareClient) GetStkRewards(req *StkRewardsRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetStkRewards(ctx context.Context, req *StkRewardsRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.TSCode != "" {
 		params["ts_code"] = req.TSCode
@@ -231,12 +333,12 @@ func (c *TushareClient) GetStkRewards(req *StkRewardsRequest, fields []string) (
 		params["end_date"] = req.EndDate
 	}
 
-	return c.callAPI("stk_rewards", params, fields)
+	return c.callAPI(ctx, "stk_rewards", params, fields)
 }
 
 // GetProBar 获取行情数据（支持复权）
 // 注意：此方法仅在Python SDK中可用，HTTP API使用GetDaily+GetAdjFactor组合实现复权
-func (c *TushareClient) GetProBar(req *ProBarRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetProBar(ctx context.Context, req *ProBarRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.TSCode != "" {
 		params["ts_code"] = req.TSCode
@@ -272,7 +374,7 @@ func (c *TushareClient) GetProBar(req *ProBarRequest, fields []string) (*Tushare
 		params["factor"] = req.Factor
 	}
 
-	return c.callAPI("pro_bar", params, fields)
+	return c.callAPI(ctx, "pro_bar", params, fields)
 }
 
 // AdjFactorRequest 复权因子请求参数
@@ -284,7 +386,34 @@ type AdjFactorRequest struct {
 }
 
 // GetAdjFactor 获取复权因子
-func (c *TushareClient) GetAdjFactor(req *AdjFactorRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetAdjFactor(ctx context.Context, req *AdjFactorRequest, fields []string) (*TushareResponse, error) {
+	params := make(map[string]interface{})
+	if req.TSCode != "" {
+		params["ts_code"] = req.TSCode
+	}
+	if req.TradeDate != "" {
+		params["trade_date"] = req.TradeDate
+	}
+	if req.StartDate != "" {
+		params["start_date"] = req.StartDate
+	}
+	if req.EndDate != "" {
+		params["end_date"] = req.EndDate
+	}
+
+	return c.callAPI(ctx, "adj_factor", params, fields)
+}
+
+// MoneyflowRequest 个股资金流向请求参数
+type MoneyflowRequest struct {
+	TSCode    string `json:"ts_code,omitempty"`
+	TradeDate string `json:"trade_date,omitempty"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+// GetMoneyflow 获取个股资金流向
+func (c *TushareClient) GetMoneyflow(ctx context.Context, req *MoneyflowRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.TSCode != "" {
 		params["ts_code"] = req.TSCode
@@ -299,11 +428,11 @@ func (c *TushareClient) GetAdjFactor(req *AdjFactorRequest, fields []string) (*T
 		params["end_date"] = req.EndDate
 	}
 
-	return c.callAPI("adj_factor", params, fields)
+	return c.callAPI(ctx, "moneyflow", params, fields)
 }
 
 // GetDaily 获取A股日线行情
-func (c *TushareClient) GetDaily(req *DailyRequest, fields []string) (*TushareResponse, error) {
+func (c *TushareClient) GetDaily(ctx context.Context, req *DailyRequest, fields []string) (*TushareResponse, error) {
 	params := make(map[string]interface{})
 	if req.TSCode != "" {
 		params["ts_code"] = req.TSCode
@@ -317,12 +446,19 @@ func (c *TushareClient) GetDaily(req *DailyRequest, fields []string) (*TushareRe
 	if req.EndDate != "" {
 		params["end_date"] = req.EndDate
 	}
+	setPageParams(params, req.Limit, req.Offset)
 
-	return c.callAPI("daily", params, fields)
+	return c.callAPI(ctx, "daily", params, fields)
 }
 
-// callAPI 调用Tushare API
-func (c *TushareClient) callAPI(apiName string, params map[string]interface{}, fields []string) (*TushareResponse, error) {
+// callAPI 调用Tushare API，ctx取消时中断限流排队、进行中的HTTP请求和重试等待
+func (c *TushareClient) callAPI(ctx context.Context, apiName string, params map[string]interface{}, fields []string) (*TushareResponse, error) {
+	// 展开"+field"/"-field"形式的字段修饰
+	fields, err := ResolveFields(apiName, fields)
+	if err != nil {
+		return nil, err
+	}
+
 	// 将fields数组转换为逗号分隔的字符串
 	fieldsStr := strings.Join(fields, ",")
 
@@ -342,16 +478,45 @@ func (c *TushareClient) callAPI(apiName string, params map[string]interface{}, f
 
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		logrus.Errorf("Failed to marshal request: %v", err)
-		return nil, err
+		return nil, errx.WrapF(err, "failed to marshal %s request", apiName)
 	}
 
 	logrus.Debugf("Request JSON: %s", string(jsonData))
 
-	resp, err := c.httpClient.Post(c.apiURL, "application/json", bytes.NewBuffer(jsonData))
+	// 网络错误和限流类错误按退避策略重试，每次尝试都经过限流器
+	for attempt := 0; ; attempt++ {
+		// 按接口限流，令牌不足时排队等待
+		if err := c.limiter.Wait(ctx, apiName); err != nil {
+			return nil, err
+		}
+
+		resp, retryable, err := c.doCall(ctx, apiName, jsonData)
+		if err == nil {
+			return resp, nil
+		}
+		if !retryable || attempt >= c.retry.MaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := c.retry.backoff(attempt)
+		logrus.Warnf("Tushare API %s failed (attempt %d/%d), retrying in %v: %v", apiName, attempt+1, c.retry.MaxRetries+1, delay, err)
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return nil, errors.Join(err, sleepErr)
+		}
+	}
+}
+
+// doCall 发送一次Tushare请求，返回的bool表示错误是否可以重试
+func (c *TushareClient) doCall(ctx context.Context, apiName string, jsonData []byte) (*TushareResponse, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, bytes.NewReader(jsonData))
 	if err != nil {
-		logrus.Errorf("Failed to call Tushare API: %v", err)
-		return nil, err
+		return nil, false, errx.WrapF(err, "failed to create %s request", apiName)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, true, errx.WrapF(err, "failed to call Tushare API %s", apiName)
 	}
 	defer resp.Body.Close()
 
@@ -360,25 +525,31 @@ func (c *TushareClient) callAPI(apiName string, params map[string]interface{}, f
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		logrus.Errorf("Failed to read response body: %v", err)
-		return nil, err
+		return nil, true, errx.WrapF(err, "failed to read %s response body", apiName)
 	}
 
 	logrus.Debugf("Response body: %s", string(body))
 
+	// 非2xx响应的body可能是网关错误页，不能按Tushare响应解析
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(body) > maxErrorBodyLen {
+			body = body[:maxErrorBodyLen]
+		}
+		return nil, retryableStatus(resp.StatusCode), errx.WrapF(&ErrHTTPStatus{Code: resp.StatusCode, Body: string(body)}, "Tushare API %s", apiName)
+	}
+
 	var tushareResp TushareResponse
 	if err := json.Unmarshal(body, &tushareResp); err != nil {
-		logrus.Errorf("Failed to unmarshal response: %v", err)
-		return nil, err
+		return nil, false, errx.WrapF(err, "failed to unmarshal %s response", apiName)
 	}
 
 	logrus.Debugf("Parsed response: %+v", tushareResp)
 
 	if tushareResp.Code != 0 {
-		logrus.Errorf("Tushare API error: code=%d, message=%s", tushareResp.Code, tushareResp.Message)
-		return nil, fmt.Errorf("Tushare API error: %s", tushareResp.Message)
+		return nil, tushareThrottleCodes[tushareResp.Code], fmt.Errorf("%w: %s (api=%s, code=%d)", ErrTushareAPI, tushareResp.Message, apiName, tushareResp.Code)
 	}
 
 	logrus.Debugf("Tushare API call successful")
-	return &tushareResp, nil
+	recordRawResponse(ctx, c.rawSink, SourceTushare, apiName, body)
+	return &tushareResp, false, nil
 }
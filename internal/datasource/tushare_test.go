@@ -0,0 +1,305 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTushareClient_HTTPStatusError(t *testing.T) {
+	// 模拟网关返回503，响应体是可以被解析为零值TushareResponse的JSON
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"message":"upstream unavailable"}`))
+	}))
+	defer server.Close()
+
+	client := &TushareClient{apiURL: server.URL, apiKey: "token", httpClient: server.Client()}
+	resp, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"})
+	if resp != nil {
+		t.Errorf("Expected nil response, got %+v", resp)
+	}
+
+	var statusErr *ErrHTTPStatus
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected ErrHTTPStatus, got '%v'", err)
+	}
+	if statusErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code 503, got %d", statusErr.Code)
+	}
+	if statusErr.Body != `{"message":"upstream unavailable"}` {
+		t.Errorf("Unexpected error body '%s'", statusErr.Body)
+	}
+}
+
+func TestTushareClient_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":40203,"message":"抱歉，您每分钟最多访问该接口500次"}`))
+	}))
+	defer server.Close()
+
+	client := &TushareClient{apiURL: server.URL, apiKey: "token", httpClient: server.Client()}
+	_, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"})
+	if !errors.Is(err, ErrTushareAPI) {
+		t.Fatalf("Expected ErrTushareAPI, got '%v'", err)
+	}
+	if !strings.Contains(err.Error(), "api=daily") || !strings.Contains(err.Error(), "code=40203") {
+		t.Errorf("Expected api name and code in error, got '%v'", err)
+	}
+}
+
+func TestTushareClient_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"message":"","data":{"fields":["ts_code"],"items":[["000001.SZ"]]}}`))
+	}))
+	defer server.Close()
+
+	client := &TushareClient{apiURL: server.URL, apiKey: "token", httpClient: server.Client()}
+	resp, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if resp.Data == nil || len(resp.Data.Items) != 1 {
+		t.Errorf("Expected one item, got %+v", resp.Data)
+	}
+}
+
+// TestTushareClient_CallAPI 测试callAPI对成功、错误码和无法解析的响应的处理，后两种错误不重试
+func TestTushareClient_CallAPI(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr func(t *testing.T, err error)
+	}{
+		{
+			name: "success",
+			body: `{"code":0,"message":"","data":{"fields":["ts_code","close"],"items":[["000001.SZ",10.5],["600000.SH",7.2]]}}`,
+		},
+		{
+			name: "error code",
+			body: `{"code":2002,"message":"您没有访问该接口的权限"}`,
+			wantErr: func(t *testing.T, err error) {
+				if !errors.Is(err, ErrTushareAPI) {
+					t.Fatalf("Expected ErrTushareAPI, got '%v'", err)
+				}
+				if !strings.Contains(err.Error(), "您没有访问该接口的权限") || !strings.Contains(err.Error(), "code=2002") {
+					t.Errorf("Expected message and code in error, got '%v'", err)
+				}
+			},
+		},
+		{
+			name: "malformed json",
+			body: `{"code":0,"data":`,
+			wantErr: func(t *testing.T, err error) {
+				var syntaxErr *json.SyntaxError
+				if !errors.As(err, &syntaxErr) {
+					t.Fatalf("Expected json.SyntaxError, got '%v'", err)
+				}
+				if !strings.Contains(err.Error(), "failed to unmarshal daily response") {
+					t.Errorf("Expected unmarshal error, got '%v'", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var request TushareRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				json.NewDecoder(r.Body).Decode(&request)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewTushareClientWithConfig(server.URL, "token", server.Client())
+			resp, err := client.callAPI(context.Background(), "daily", map[string]interface{}{"ts_code": "000001.SZ"}, []string{"ts_code", "close"})
+			if calls != 1 {
+				t.Errorf("Expected 1 request, got %d", calls)
+			}
+			if request.Token != "token" || request.APIName != "daily" || request.Fields != "ts_code,close" {
+				t.Errorf("Unexpected request %+v", request)
+			}
+
+			if tt.wantErr != nil {
+				if resp != nil {
+					t.Errorf("Expected nil response, got %+v", resp)
+				}
+				tt.wantErr(t, err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got '%v'", err)
+			}
+			if resp.Data == nil || len(resp.Data.Items) != 2 || resp.Data.Fields[1] != "close" {
+				t.Errorf("Expected two items with fields, got %+v", resp.Data)
+			}
+		})
+	}
+}
+
+// TestNewTushareClientWithConfig 测试注入的地址和http.Client，未传入http.Client时使用默认客户端
+func TestNewTushareClientWithConfig(t *testing.T) {
+	httpClient := &http.Client{Timeout: time.Second}
+	client := NewTushareClientWithConfig("http://127.0.0.1:1/tushare", "token", httpClient)
+	if client.apiURL != "http://127.0.0.1:1/tushare" || client.apiKey != "token" || client.httpClient != httpClient {
+		t.Errorf("Unexpected client %+v", client)
+	}
+	if client.pageSize != DefaultTusharePageSize {
+		t.Errorf("Expected default page size, got %d", client.pageSize)
+	}
+
+	client = NewTushareClientWithConfig(DefaultTushareBaseURL, "", nil)
+	if client.httpClient == nil || client.httpClient.Timeout != 30*time.Second {
+		t.Errorf("Expected default http client, got %+v", client.httpClient)
+	}
+}
+
+func TestTushareClient_GetMoneyflowRequest(t *testing.T) {
+	var request TushareRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"code":0,"message":"","data":{"fields":["ts_code"],"items":[]}}`))
+	}))
+	defer server.Close()
+
+	client := &TushareClient{apiURL: server.URL, apiKey: "token", httpClient: server.Client()}
+	_, err := client.GetMoneyflow(context.Background(), &MoneyflowRequest{
+		TSCode:    "000001.SZ",
+		StartDate: "20240101",
+		EndDate:   "20240131",
+	}, []string{"ts_code", "trade_date", "net_mf_amount"})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if request.APIName != "moneyflow" {
+		t.Errorf("Expected api_name moneyflow, got '%s'", request.APIName)
+	}
+	if request.Token != "token" {
+		t.Errorf("Expected token to be sent, got '%s'", request.Token)
+	}
+	if request.Fields != "ts_code,trade_date,net_mf_amount" {
+		t.Errorf("Unexpected fields '%s'", request.Fields)
+	}
+	expected := map[string]interface{}{"ts_code": "000001.SZ", "start_date": "20240101", "end_date": "20240131"}
+	if len(request.Params) != len(expected) {
+		t.Errorf("Expected params %v, got %v", expected, request.Params)
+	}
+	for key, value := range expected {
+		if request.Params[key] != value {
+			t.Errorf("Expected param %s=%v, got %v", key, value, request.Params[key])
+		}
+	}
+}
+
+// TestNewTushareClient_Config 测试NewTushareClient使用配置中的token和接口地址
+func TestNewTushareClient_Config(t *testing.T) {
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		token = request.Token
+		w.Write([]byte(`{"code":0,"message":"","data":{"fields":["ts_code"],"items":[["000001.SZ"]]}}`))
+	}))
+	defer server.Close()
+
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{TushareAPIKey: "config-token", TushareBaseURL: server.URL, TusharePageSize: 5000}
+	defer func() { config.AppConfig = previous }()
+
+	client, err := NewTushareClient()
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if _, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if token != "config-token" {
+		t.Errorf("Expected token config-token, got '%s'", token)
+	}
+}
+
+func TestNewTushareClientWithURL(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"code":0,"message":"","data":{"fields":["ts_code"],"items":[["000001.SZ"]]}}`))
+	}))
+	defer server.Close()
+
+	// 镜像地址可包含路径前缀
+	client, err := NewTushareClientWithURL(server.URL+"/tushare", "token", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	resp, err := client.GetDaily(context.Background(), &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if path != "/tushare" {
+		t.Errorf("Expected request to /tushare, got '%s'", path)
+	}
+	if resp.Data == nil || len(resp.Data.Items) != 1 {
+		t.Errorf("Expected one item, got %+v", resp.Data)
+	}
+
+	client, err = NewTushareClientWithURL("", "token", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if client.apiURL != DefaultTushareBaseURL {
+		t.Errorf("Expected default url %s, got %s", DefaultTushareBaseURL, client.apiURL)
+	}
+
+	for _, invalid := range []string{"api.tushare.pro", "ftp://api.tushare.pro", "https://", "http://[::1"} {
+		if _, err := NewTushareClientWithURL(invalid, "token", 0); err == nil {
+			t.Errorf("Expected error for base url %q", invalid)
+		}
+	}
+}
+
+func TestTushareClient_CancelInFlight(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewTushareClientWithURL(server.URL, "token", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err = client.GetDaily(ctx, &DailyRequest{TSCode: "000001.SZ"}, []string{"ts_code"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled, got '%v'", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected in-flight request to be canceled promptly, took %v", elapsed)
+	}
+}
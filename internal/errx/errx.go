@@ -0,0 +1,25 @@
+// Package errx 提供统一的错误包装
+//
+// 约定：存储层和数据源层只包装并返回错误，不记录错误日志；
+// 错误只在最上层（API处理函数、定时任务、数据处理流水线、main）记录一次，避免同一个错误被多次记录。
+// 包装后的错误保留原始错误链，调用方可以继续使用errors.Is/errors.As判断哨兵错误和错误类型。
+// 为已有错误添加上下文时使用Wrap/WrapF；基于哨兵错误创建新错误时仍使用fmt.Errorf("%w: ...", ErrXxx, ...)。
+package errx
+
+import "fmt"
+
+// Wrap 为err添加上下文信息 "msg: err"，err为nil时返回nil
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// WrapF 按格式为err添加上下文信息，err为nil时返回nil
+func WrapF(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), err)
+}
@@ -0,0 +1,44 @@
+package errx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errSentinel = errors.New("sentinel")
+
+// statusError 带状态码的错误类型
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+// TestWrap_PreservesChain 测试多层包装后errors.Is/errors.As仍能识别原始错误
+func TestWrap_PreservesChain(t *testing.T) {
+	err := Wrap(WrapF(errSentinel, "query %s", "daily"), "failed to load")
+	assert.EqualError(t, err, "failed to load: query daily: sentinel")
+	assert.ErrorIs(t, err, errSentinel)
+
+	err = WrapF(Wrap(&statusError{code: 503}, "call api"), "sync %d", 2)
+	assert.EqualError(t, err, "sync 2: call api: status 503")
+	var status *statusError
+	if assert.ErrorAs(t, err, &status) {
+		assert.Equal(t, 503, status.code)
+	}
+
+	// 与fmt.Errorf的%w包装混用
+	err = Wrap(fmt.Errorf("%w: extra", errSentinel), "outer")
+	assert.ErrorIs(t, err, errSentinel)
+}
+
+// TestWrap_Nil 测试包装nil返回nil
+func TestWrap_Nil(t *testing.T) {
+	assert.NoError(t, Wrap(nil, "ignored"))
+	assert.NoError(t, WrapF(nil, "ignored %d", 1))
+}
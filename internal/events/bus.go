@@ -0,0 +1,79 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Bus 进程内发布订阅，Publish不阻塞：订阅者的缓冲区已满时丢弃该订阅者的事件
+type Bus[T any] struct {
+	mutex  sync.RWMutex
+	nextID int
+	subs   map[int]*subscription[T]
+}
+
+// subscription 一个订阅者，filter为nil时接收所有事件
+type subscription[T any] struct {
+	ch      chan T
+	filter  func(T) bool
+	dropped int
+}
+
+// NewBus 创建事件总线
+func NewBus[T any]() *Bus[T] {
+	return &Bus[T]{subs: make(map[int]*subscription[T])}
+}
+
+// Subscribe 订阅filter返回true的事件，buffer为通道缓冲区大小（<1时为1）
+// 返回的函数取消订阅并关闭通道，可以重复调用
+func (b *Bus[T]) Subscribe(filter func(T) bool, buffer int) (<-chan T, func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	sub := &subscription[T]{ch: make(chan T, buffer), filter: filter}
+
+	b.mutex.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.mutex.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			delete(b.subs, id)
+			b.mutex.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// Publish 按顺序将事件发送给匹配的订阅者
+func (b *Bus[T]) Publish(events []T) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, sub := range b.subs {
+		for _, event := range events {
+			if sub.filter != nil && !sub.filter(event) {
+				continue
+			}
+			select {
+			case sub.ch <- event:
+			default:
+				sub.dropped++
+				if sub.dropped == 1 || sub.dropped%100 == 0 {
+					logrus.Warnf("Event subscriber is not keeping up, dropped %d events", sub.dropped)
+				}
+			}
+		}
+	}
+}
+
+// Subscribers 返回当前订阅者数量
+func (b *Bus[T]) Subscribers() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.subs)
+}
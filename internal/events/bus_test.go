@@ -0,0 +1,55 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBus_PublishSubscribe 测试订阅者只收到匹配的事件，取消订阅后关闭通道
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus[int]()
+	even, cancelEven := bus.Subscribe(func(n int) bool { return n%2 == 0 }, 10)
+	all, cancelAll := bus.Subscribe(nil, 10)
+	assert.Equal(t, 2, bus.Subscribers())
+
+	bus.Publish([]int{1, 2, 3, 4})
+	assert.Equal(t, []int{2, 4}, drain(even))
+	assert.Equal(t, []int{1, 2, 3, 4}, drain(all))
+
+	cancelEven()
+	cancelEven()
+	_, ok := <-even
+	assert.False(t, ok)
+	assert.Equal(t, 1, bus.Subscribers())
+
+	bus.Publish([]int{6})
+	assert.Equal(t, []int{6}, drain(all))
+	cancelAll()
+	assert.Equal(t, 0, bus.Subscribers())
+}
+
+// TestBus_SlowSubscriber 测试缓冲区已满时丢弃事件而不阻塞发布
+func TestBus_SlowSubscriber(t *testing.T) {
+	bus := NewBus[string]()
+	ch, cancel := bus.Subscribe(nil, 2)
+	defer cancel()
+
+	bus.Publish([]string{"a", "b", "c"})
+	require.Len(t, ch, 2)
+	assert.Equal(t, []string{"a", "b"}, drain(ch))
+}
+
+// drain 读出通道中已有的事件
+func drain[T any](ch <-chan T) []T {
+	var out []T
+	for {
+		select {
+		case v := <-ch:
+			out = append(out, v)
+		default:
+			return out
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"quant-data-engine/internal/config"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+// consumerClient 抽象confluent-kafka-go消费者，便于测试时注入模拟实现
+type consumerClient interface {
+	SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	CommitMessage(m *kafka.Message) ([]kafka.TopicPartition, error)
+	Close() error
+}
+
+// consumerPollTimeout 每次等待消息的最长时间，超时后检查ctx是否取消
+const consumerPollTimeout = 500 * time.Millisecond
+
+// KafkaConsumer Kafka消费者，用于回放主题中的市场数据
+type KafkaConsumer struct {
+	consumer consumerClient
+}
+
+// NewKafkaConsumer 创建属于groupID消费组的Kafka消费者，新消费组从最早的消息开始消费，处理成功后手动提交位移
+func NewKafkaConsumer(groupID string) (*KafkaConsumer, error) {
+	return newKafkaConsumer(groupID, "earliest")
+}
+
+// NewKafkaTailConsumer 创建属于groupID消费组的Kafka消费者，新消费组只消费创建后写入的消息，用于读取主题末尾
+func NewKafkaTailConsumer(groupID string) (*KafkaConsumer, error) {
+	return newKafkaConsumer(groupID, "latest")
+}
+
+func newKafkaConsumer(groupID, offsetReset string) (*KafkaConsumer, error) {
+	cfg := config.AppConfig
+
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  cfg.KafkaBrokers,
+		"client.id":          cfg.KafkaClientID,
+		"group.id":           groupID,
+		"auto.offset.reset":  offsetReset,
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to create Kafka consumer")
+	}
+
+	logrus.Infof("Kafka consumer created for group %s", groupID)
+	return &KafkaConsumer{consumer: consumer}, nil
+}
+
+// Subscribe 订阅主题
+func (c *KafkaConsumer) Subscribe(topic string) error {
+	if err := c.consumer.SubscribeTopics([]string{topic}, nil); err != nil {
+		return errx.WrapF(err, "failed to subscribe to %s", topic)
+	}
+	return nil
+}
+
+// Consume 逐条消费市场数据并调用handler，直到ctx被取消（返回nil）
+// handler成功后提交该消息的位移；无法解析的消息记录日志后跳过并提交位移，避免反复消费；
+// handler返回错误时不提交位移并返回错误，重新消费时从该消息开始
+func (c *KafkaConsumer) Consume(ctx context.Context, handler func(models.MarketData) error) error {
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		msg, err := c.consumer.ReadMessage(consumerPollTimeout)
+		if err != nil {
+			var kafkaErr kafka.Error
+			if errors.As(err, &kafkaErr) {
+				if kafkaErr.Code() == kafka.ErrTimedOut {
+					continue
+				}
+				if kafkaErr.IsFatal() {
+					return errx.Wrap(err, "fatal kafka consumer error")
+				}
+			}
+			logrus.Warnf("Kafka consumer error: %v", err)
+			continue
+		}
+
+		var data models.MarketData
+		if err := json.Unmarshal(msg.Value, &data); err != nil {
+			logrus.Warnf("Skipping undecodable market data message at %s: %v", msg.TopicPartition, err)
+			c.commit(msg)
+			continue
+		}
+
+		if err := handler(data); err != nil {
+			return errx.WrapF(err, "failed to handle market data %s at %s", data.ID, msg.TopicPartition)
+		}
+		c.commit(msg)
+	}
+}
+
+// commit 提交消息位移，失败时只记录日志，未提交的位移在下一条消息提交时一并推进
+func (c *KafkaConsumer) commit(msg *kafka.Message) {
+	if _, err := c.consumer.CommitMessage(msg); err != nil {
+		logrus.Errorf("Failed to commit offset %s: %v", msg.TopicPartition, err)
+	}
+}
+
+// Close 关闭消费者并离开消费组
+func (c *KafkaConsumer) Close() error {
+	return c.consumer.Close()
+}
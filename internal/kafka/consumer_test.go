@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"quant-data-engine/internal/models"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockConsumer 模拟Kafka消费者，依次返回messages，读完后调用onDrained并返回超时错误
+type mockConsumer struct {
+	messages  []*kafka.Message
+	onDrained func()
+	committed []kafka.Offset
+	topics    []string
+	closed    bool
+}
+
+func (m *mockConsumer) SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error {
+	m.topics = append(m.topics, topics...)
+	return nil
+}
+
+func (m *mockConsumer) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	if len(m.messages) == 0 {
+		if m.onDrained != nil {
+			m.onDrained()
+		}
+		return nil, kafka.NewError(kafka.ErrTimedOut, "timed out", false)
+	}
+	msg := m.messages[0]
+	m.messages = m.messages[1:]
+	return msg, nil
+}
+
+func (m *mockConsumer) CommitMessage(msg *kafka.Message) ([]kafka.TopicPartition, error) {
+	m.committed = append(m.committed, msg.TopicPartition.Offset)
+	return nil, nil
+}
+
+func (m *mockConsumer) Close() error {
+	m.closed = true
+	return nil
+}
+
+// newTestMessage 创建位于offset的消息
+func newTestMessage(t *testing.T, offset int64, value interface{}) *kafka.Message {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		data, err = json.Marshal(v)
+		require.NoError(t, err)
+	}
+	topic := "quant_data"
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(offset)},
+		Value:          data,
+	}
+}
+
+// TestKafkaConsumer_Consume 测试消息解析后交给handler并提交位移，无法解析的消息跳过
+func TestKafkaConsumer_Consume(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mock := &mockConsumer{
+		messages: []*kafka.Message{
+			newTestMessage(t, 0, models.MarketData{ID: "a", Symbol: "BTCUSDT", Price: 100, Timestamp: ts, Source: "binance"}),
+			newTestMessage(t, 1, "not json"),
+			newTestMessage(t, 2, models.MarketData{ID: "b", Symbol: "ETHUSDT", Price: 10, Timestamp: ts, Source: "okx"}),
+		},
+		onDrained: cancel,
+	}
+	consumer := &KafkaConsumer{consumer: mock}
+	require.NoError(t, consumer.Subscribe("quant_data"))
+	assert.Equal(t, []string{"quant_data"}, mock.topics)
+
+	var handled []models.MarketData
+	err := consumer.Consume(ctx, func(d models.MarketData) error {
+		handled = append(handled, d)
+		return nil
+	})
+	require.NoError(t, err)
+	if assert.Len(t, handled, 2) {
+		assert.Equal(t, "a", handled[0].ID)
+		assert.True(t, ts.Equal(handled[0].Timestamp))
+		assert.Equal(t, "ETHUSDT", handled[1].Symbol)
+	}
+	assert.Equal(t, []kafka.Offset{0, 1, 2}, mock.committed)
+
+	require.NoError(t, consumer.Close())
+	assert.True(t, mock.closed)
+}
+
+// TestKafkaConsumer_HandlerError 测试handler失败时不提交该消息的位移并停止消费
+func TestKafkaConsumer_HandlerError(t *testing.T) {
+	mock := &mockConsumer{
+		messages: []*kafka.Message{
+			newTestMessage(t, 5, models.MarketData{ID: "a", Symbol: "BTCUSDT"}),
+			newTestMessage(t, 6, models.MarketData{ID: "b", Symbol: "BTCUSDT"}),
+			newTestMessage(t, 7, models.MarketData{ID: "c", Symbol: "BTCUSDT"}),
+		},
+	}
+	consumer := &KafkaConsumer{consumer: mock}
+
+	err := consumer.Consume(context.Background(), func(d models.MarketData) error {
+		if d.ID == "b" {
+			return fmt.Errorf("database unavailable")
+		}
+		return nil
+	})
+	assert.ErrorContains(t, err, "database unavailable")
+	assert.Equal(t, []kafka.Offset{5}, mock.committed)
+	assert.Len(t, mock.messages, 1)
+}
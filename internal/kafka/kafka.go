@@ -1,50 +1,136 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"quant-data-engine/internal/config"
+	"quant-data-engine/internal/errx"
 	"quant-data-engine/internal/models"
+	"sync"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/sirupsen/logrus"
 )
 
+// producerClient 抽象confluent-kafka-go生产者，便于测试时注入模拟实现
+type producerClient interface {
+	Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error
+	Flush(timeoutMs int) int
+	Events() chan kafka.Event
+	Close()
+}
+
+// 分区策略
+const (
+	// PartitionerAny 由librdkafka默认分区器决定分区
+	PartitionerAny = "any"
+	// PartitionerConsistent 按Key哈希对分区数取模，同一Key总是写入同一分区
+	PartitionerConsistent = "consistent"
+)
+
 // KafkaProducer Kafka生产者
 type KafkaProducer struct {
-	producer *kafka.Producer
+	producer producerClient
 	topic    string
+	workers  int
+	// partitions 大于0时按Key计算主题分区（consistent分区策略），否则使用PartitionAny
+	partitions int32
+	// dlqTopic 投递报告为失败的市场数据消息转发到的死信主题，为空时不转发
+	dlqTopic string
 }
 
 // NewKafkaProducer 创建Kafka生产者
 func NewKafkaProducer() (*KafkaProducer, error) {
 	cfg := config.AppConfig
 
+	partitions, err := partitionCount(cfg.KafkaPartitioner, cfg.KafkaPartitions)
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := producerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// 配置Kafka生产者
-	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+	producer, err := kafka.NewProducer(configMap)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to create Kafka producer")
+	}
+
+	// 启动消息发送结果处理
+	go handleDeliveryReports(producer)
+
+	logrus.Info("Connected to Kafka successfully")
+	return &KafkaProducer{
+		producer:   producer,
+		topic:      cfg.KafkaTopic,
+		workers:    cfg.KafkaProduceWorkers,
+		partitions: partitions,
+		dlqTopic:   cfg.KafkaDLQTopic,
+	}, nil
+}
+
+// producerConfig 根据配置生成生产者参数
+// 严格保序模式下启用幂等生产者并限制每个连接只有1个在途请求，重试不会打乱同一分区内的顺序；
+// 同一交易对还必须总是写入同一分区，因此要求consistent分区策略
+func producerConfig(cfg *config.Config) (*kafka.ConfigMap, error) {
+	configMap := &kafka.ConfigMap{
 		"bootstrap.servers": cfg.KafkaBrokers,
-		"client.id":         "quant-data-engine",
+		"client.id":         cfg.KafkaClientID,
 		"acks":              "all",
 		"retries":           3,
 		"retry.backoff.ms":  1000,
 		"linger.ms":         100,
 		"batch.size":        16384,
 		"compression.type":  "gzip",
-	})
-	if err != nil {
-		logrus.Errorf("Failed to create Kafka producer: %v", err)
-		return nil, err
+	}
+	if !cfg.KafkaStrictOrdering {
+		return configMap, nil
 	}
 
-	// 启动消息发送结果处理
-	go handleDeliveryReports(producer)
+	if cfg.KafkaPartitioner != PartitionerConsistent {
+		return nil, fmt.Errorf("KAFKA_STRICT_ORDERING requires KAFKA_PARTITIONER=%q so that each symbol always goes to the same partition, got %q",
+			PartitionerConsistent, cfg.KafkaPartitioner)
+	}
+	(*configMap)["enable.idempotence"] = true
+	(*configMap)["max.in.flight.requests.per.connection"] = 1
+	logrus.Info("Kafka strict ordering enabled: idempotent producer with 1 in-flight request per connection")
+	return configMap, nil
+}
 
-	logrus.Info("Connected to Kafka successfully")
-	return &KafkaProducer{
-		producer: producer,
-		topic:    cfg.KafkaTopic,
-	}, nil
+// partitionCount 根据分区策略返回按Key计算分区时使用的分区数，0表示使用PartitionAny
+// consistent策略要求分区数与预先创建的主题分区数一致，未知策略回退到any
+func partitionCount(partitioner string, partitions int) (int32, error) {
+	switch partitioner {
+	case PartitionerConsistent:
+		if partitions < 1 {
+			return 0, fmt.Errorf("KAFKA_PARTITIONS must be at least 1 when KAFKA_PARTITIONER is %q, got %d", PartitionerConsistent, partitions)
+		}
+		logrus.Infof("Kafka messages are partitioned by key over %d partitions", partitions)
+		return int32(partitions), nil
+	case PartitionerAny, "":
+		return 0, nil
+	default:
+		logrus.Warnf("Unknown Kafka partitioner %q, falling back to %q", partitioner, PartitionerAny)
+		return 0, nil
+	}
+}
+
+// partitionFor 返回主题消息的目标分区
+func (p *KafkaProducer) partitionFor(key string) int32 {
+	if p.partitions <= 0 {
+		return kafka.PartitionAny
+	}
+	return partitionForKey(key, p.partitions)
+}
+
+// partitionForKey 按Key哈希对分区数取模
+func partitionForKey(key string, partitions int32) int32 {
+	return int32(keyHash(key) % uint32(partitions))
 }
 
 // handleDeliveryReports 处理消息发送结果
@@ -61,7 +147,8 @@ func handleDeliveryReports(producer *kafka.Producer) {
 	}
 }
 
-// SendMarketData 发送市场数据到Kafka
+// SendMarketData 发送市场数据到Kafka，等待投递报告
+// 有消息未能确认投递时返回*DeliveryError，确认失败的消息同时转发到死信主题（配置了KAFKA_DLQ_TOPIC时）
 func (p *KafkaProducer) SendMarketData(data []models.MarketData) error {
 	if len(data) == 0 {
 		return nil
@@ -70,15 +157,42 @@ func (p *KafkaProducer) SendMarketData(data []models.MarketData) error {
 	// 验证数据
 	for i, d := range data {
 		if err := validateMarketDataForKafka(d); err != nil {
-			return fmt.Errorf("invalid market data at index %d: %w", i, err)
+			return errx.WrapF(err, "invalid market data at index %d", i)
 		}
 	}
 
-	// 记录日志但不实际发送消息
-	logrus.Infof("[Kafka disabled] Would send %d market data messages to Kafka", len(data))
-	logrus.Debugf("[Kafka disabled] First market data: %+v", data[0])
+	if p.producer == nil {
+		return fmt.Errorf("kafka producer is not initialized")
+	}
+	return p.sendMarketDataToKafka(data)
+}
 
-	return nil
+// DeliveryFailure 确认投递失败的消息
+type DeliveryFailure struct {
+	ID     string
+	Symbol string
+	Err    error
+	// message 投递报告中的原始消息，提交到队列前就失败时为nil
+	message *kafka.Message
+}
+
+// DeliveryError 批量发送结果中未能确认成功的消息
+// Failed 为确认失败（可安全重发）的消息，Unknown 为Flush超时时仍未收到投递报告的消息，
+// 这些消息可能已经写入Kafka，下游重发前需要按ID去重
+type DeliveryError struct {
+	Total   int
+	Failed  []DeliveryFailure
+	Unknown []string
+}
+
+// Error 实现error接口
+func (e *DeliveryError) Error() string {
+	failed := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		failed = append(failed, fmt.Sprintf("%s (%v)", f.ID, f.Err))
+	}
+	return fmt.Sprintf("failed to confirm %d out of %d messages to Kafka: %d failed %v, %d unknown delivery status %v",
+		len(e.Failed)+len(e.Unknown), e.Total, len(e.Failed), failed, len(e.Unknown), e.Unknown)
 }
 
 // sendMarketDataToKafka 实际发送市场数据到Kafka
@@ -87,52 +201,190 @@ func (p *KafkaProducer) sendMarketDataToKafka(data []models.MarketData) error {
 		return nil
 	}
 
-	// 用于跟踪发送失败的消息
-	var failedMessages int
-
-	for _, d := range data {
-		// 将数据转换为JSON
-		jsonData, err := json.Marshal(d)
-		if err != nil {
-			logrus.Errorf("Failed to marshal market data: %v", err)
-			failedMessages++
-			continue
-		}
+	deliveryErr := &DeliveryError{Total: len(data)}
 
-		// 创建消息
-		message := &kafka.Message{
-			TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
-			Value:          jsonData,
-			Key:            []byte(d.Symbol),
-			Headers: []kafka.Header{
-				{Key: "source", Value: []byte(d.Source)},
-				{Key: "timestamp", Value: []byte(d.Timestamp.Format(time.RFC3339))},
-			},
-		}
+	// 每批消息使用独立的投递报告通道，通过Opaque关联回原始记录
+	deliveryChan := make(chan kafka.Event, len(data))
+	produceErrs := p.produceConcurrently(data, deliveryChan)
 
-		// 发送消息
-		if err := p.producer.Produce(message, nil); err != nil {
-			logrus.Errorf("Failed to produce message: %v", err)
-			failedMessages++
+	pending := make(map[int]bool, len(data))
+	for i, err := range produceErrs {
+		if err != nil {
+			deliveryErr.Failed = append(deliveryErr.Failed, DeliveryFailure{ID: data[i].ID, Symbol: data[i].Symbol, Err: err})
 			continue
 		}
+		pending[i] = true
 	}
 
 	// 等待所有消息发送完成
 	remaining := p.producer.Flush(10 * 1000)
 	if remaining > 0 {
-		logrus.Warnf("Failed to send %d messages to Kafka", remaining)
-		failedMessages += remaining
+		logrus.Warnf("Kafka flush timed out with %d messages still in queue", remaining)
+	}
+
+	// 收集已到达的投递报告，剩余未报告的消息投递状态未知
+	collectDeliveryReports(deliveryChan, pending, data, deliveryErr)
+	for i := range data {
+		if pending[i] {
+			deliveryErr.Unknown = append(deliveryErr.Unknown, data[i].ID)
+		}
 	}
 
-	if failedMessages > 0 {
-		return fmt.Errorf("failed to send %d out of %d messages to Kafka", failedMessages, len(data))
+	if len(deliveryErr.Failed) > 0 {
+		p.sendToDLQ(deliveryErr.Failed)
+	}
+	if len(deliveryErr.Failed) > 0 || len(deliveryErr.Unknown) > 0 {
+		return deliveryErr
 	}
 
 	logrus.Infof("Sent %d market data messages to Kafka", len(data))
 	return nil
 }
 
+// produceConcurrently 使用工作池并发序列化并提交消息，返回每条记录的提交错误
+// 相同Key的消息总是路由到同一个worker并按原始顺序提交，保证分区内顺序
+func (p *KafkaProducer) produceConcurrently(data []models.MarketData, deliveryChan chan kafka.Event) []error {
+	workers := p.workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(data) {
+		workers = len(data)
+	}
+
+	// 每条记录只由一个worker写入对应位置，无需加锁
+	errs := make([]error, len(data))
+
+	queues := make([]chan int, workers)
+	var wg sync.WaitGroup
+	for w := range queues {
+		queues[w] = make(chan int, len(data))
+		wg.Add(1)
+		go func(queue chan int) {
+			defer wg.Done()
+			for i := range queue {
+				errs[i] = p.produceMarketData(data[i], i, deliveryChan)
+			}
+		}(queues[w])
+	}
+
+	for i, d := range data {
+		queues[workerForKey(d.Symbol, workers)] <- i
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// produceMarketData 序列化单条市场数据并提交到生产者队列
+func (p *KafkaProducer) produceMarketData(d models.MarketData, index int, deliveryChan chan kafka.Event) error {
+	// 将数据转换为JSON
+	jsonData, err := json.Marshal(d)
+	if err != nil {
+		return errx.Wrap(err, "failed to marshal market data")
+	}
+
+	// 创建消息
+	message := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: p.partitionFor(d.Symbol)},
+		Value:          jsonData,
+		Key:            []byte(d.Symbol),
+		Opaque:         index,
+		Headers: []kafka.Header{
+			{Key: "source", Value: []byte(d.Source)},
+			{Key: "timestamp", Value: []byte(d.Timestamp.Format(time.RFC3339))},
+		},
+	}
+
+	// 发送消息
+	if err := p.producer.Produce(message, deliveryChan); err != nil {
+		return errx.Wrap(err, "failed to produce message")
+	}
+	return nil
+}
+
+// workerForKey 按Key哈希选择worker
+func workerForKey(key string, workers int) int {
+	return int(keyHash(key) % uint32(workers))
+}
+
+// keyHash 计算消息Key的FNV-1a哈希
+func keyHash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// collectDeliveryReports 非阻塞地读取投递报告，确认成功的从pending中移除，失败的记入deliveryErr
+func collectDeliveryReports(deliveryChan chan kafka.Event, pending map[int]bool, data []models.MarketData, deliveryErr *DeliveryError) {
+	for {
+		select {
+		case e := <-deliveryChan:
+			msg, ok := e.(*kafka.Message)
+			if !ok {
+				continue
+			}
+			i, ok := msg.Opaque.(int)
+			if !ok || !pending[i] {
+				continue
+			}
+			delete(pending, i)
+			if msg.TopicPartition.Error != nil {
+				deliveryErr.Failed = append(deliveryErr.Failed, DeliveryFailure{
+					ID:      data[i].ID,
+					Symbol:  data[i].Symbol,
+					Err:     msg.TopicPartition.Error,
+					message: msg,
+				})
+			}
+		default:
+			return
+		}
+	}
+}
+
+// sendToDLQ 将投递报告为失败的消息原样转发到死信主题，并附加描述失败原因的error消息头
+// 未配置死信主题时不转发；转发失败只记录日志，调用方仍按DeliveryError处理原始的投递失败
+func (p *KafkaProducer) sendToDLQ(failures []DeliveryFailure) {
+	if p.dlqTopic == "" {
+		return
+	}
+
+	sent := 0
+	for _, f := range failures {
+		if f.message == nil {
+			continue
+		}
+		headers := make([]kafka.Header, 0, len(f.message.Headers)+1)
+		headers = append(headers, f.message.Headers...)
+		headers = append(headers, kafka.Header{Key: "error", Value: []byte(f.Err.Error())})
+
+		message := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &p.dlqTopic, Partition: kafka.PartitionAny},
+			Value:          f.message.Value,
+			Key:            f.message.Key,
+			Headers:        headers,
+		}
+		if err := p.producer.Produce(message, nil); err != nil {
+			logrus.Errorf("Failed to produce market data %s to DLQ topic %s: %v", f.ID, p.dlqTopic, err)
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return
+	}
+
+	if remaining := p.producer.Flush(5 * 1000); remaining > 0 {
+		logrus.Errorf("Failed to flush %d market data messages to DLQ topic %s", remaining, p.dlqTopic)
+		return
+	}
+	logrus.Warnf("Routed %d undeliverable market data messages to DLQ topic %s", sent, p.dlqTopic)
+}
+
 // validateMarketDataForKafka 验证Kafka消息数据
 func validateMarketDataForKafka(data models.MarketData) error {
 	if data.Symbol == "" {
@@ -144,6 +396,9 @@ func validateMarketDataForKafka(data models.MarketData) error {
 	if data.Source == "" {
 		return fmt.Errorf("source is required")
 	}
+	if !models.IsKnownSource(data.Source) {
+		return fmt.Errorf("unknown source %q", data.Source)
+	}
 	return nil
 }
 
@@ -161,13 +416,12 @@ func (p *KafkaProducer) sendBacktestDataToKafka(data models.BacktestData) error
 	// 将数据转换为JSON
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		logrus.Errorf("Failed to marshal backtest data: %v", err)
-		return fmt.Errorf("failed to marshal backtest data: %w", err)
+		return errx.Wrap(err, "failed to marshal backtest data")
 	}
 
 	// 创建消息
 	message := &kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: p.partitionFor(data.Symbol)},
 		Value:          jsonData,
 		Key:            []byte(data.Symbol),
 		Headers: []kafka.Header{
@@ -179,8 +433,7 @@ func (p *KafkaProducer) sendBacktestDataToKafka(data models.BacktestData) error
 
 	// 发送消息
 	if err := p.producer.Produce(message, nil); err != nil {
-		logrus.Errorf("Failed to produce backtest data message: %v", err)
-		return fmt.Errorf("failed to produce backtest data message: %w", err)
+		return errx.Wrap(err, "failed to produce backtest data message")
 	}
 
 	// 等待消息发送完成
@@ -190,6 +443,111 @@ func (p *KafkaProducer) sendBacktestDataToKafka(data models.BacktestData) error
 	return nil
 }
 
+// SendStockBasic 发送股票基础信息到Kafka，以ts_code作为消息Key
+func (p *KafkaProducer) SendStockBasic(data []models.StockBasic) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	for _, d := range data {
+		jsonData, err := json.Marshal(d)
+		if err != nil {
+			return errx.WrapF(err, "failed to marshal stock basic %s", d.TSCode)
+		}
+
+		message := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: p.partitionFor(d.TSCode)},
+			Value:          jsonData,
+			Key:            []byte(d.TSCode),
+			Headers: []kafka.Header{
+				{Key: "type", Value: []byte("stock_basic")},
+			},
+		}
+
+		if err := p.producer.Produce(message, nil); err != nil {
+			return errx.WrapF(err, "failed to produce stock basic message for %s", d.TSCode)
+		}
+	}
+
+	// 等待所有消息发送完成
+	if remaining := p.producer.Flush(10 * 1000); remaining > 0 {
+		return fmt.Errorf("kafka flush timed out with %d stock basic messages still in queue", remaining)
+	}
+
+	logrus.Infof("Sent %d stock basic messages to Kafka", len(data))
+	return nil
+}
+
+// SendRejectedMarketData 发送被拒绝的市场数据到死信主题
+func (p *KafkaProducer) SendRejectedMarketData(topic string, records []models.RejectedMarketData) error {
+	for _, r := range records {
+		jsonData, err := json.Marshal(r)
+		if err != nil {
+			return errx.Wrap(err, "failed to marshal rejected market data")
+		}
+
+		message := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+			Value:          jsonData,
+			Key:            []byte(r.Data.Symbol),
+			Headers: []kafka.Header{
+				{Key: "type", Value: []byte("rejected_market_data")},
+				{Key: "timestamp", Value: []byte(r.RejectedAt.Format(time.RFC3339))},
+			},
+		}
+
+		if err := p.producer.Produce(message, nil); err != nil {
+			return errx.Wrap(err, "failed to produce rejected market data message")
+		}
+	}
+
+	if remaining := p.producer.Flush(5 * 1000); remaining > 0 {
+		return fmt.Errorf("failed to flush %d rejected market data messages", remaining)
+	}
+	return nil
+}
+
+// SendAlert 发送告警消息到指定主题
+func (p *KafkaProducer) SendAlert(topic string, alert models.SLAAlert) error {
+	jsonData, err := json.Marshal(alert)
+	if err != nil {
+		return errx.Wrap(err, "failed to marshal alert")
+	}
+
+	message := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          jsonData,
+		Key:            []byte(alert.Type),
+		Headers: []kafka.Header{
+			{Key: "type", Value: []byte("alert")},
+			{Key: "timestamp", Value: []byte(alert.Timestamp.Format(time.RFC3339))},
+		},
+	}
+
+	if err := p.producer.Produce(message, nil); err != nil {
+		return errx.Wrap(err, "failed to produce alert message")
+	}
+
+	// 告警需要尽快送达
+	p.producer.Flush(5 * 1000)
+	return nil
+}
+
+// Flush 等待队列中的消息发送完成，最长等待到ctx的截止时间，超时仍有未发送的消息时返回错误
+func (p *KafkaProducer) Flush(ctx context.Context) error {
+	if p.producer == nil {
+		return nil
+	}
+	timeout := 10 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = max(time.Until(deadline), 0)
+	}
+	if remaining := p.producer.Flush(int(timeout.Milliseconds())); remaining > 0 {
+		return errx.WrapF(context.DeadlineExceeded, "%d messages still in queue", remaining)
+	}
+	return nil
+}
+
 // Close 关闭Kafka生产者
 func (p *KafkaProducer) Close() {
 	if p.producer != nil {
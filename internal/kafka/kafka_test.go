@@ -1,14 +1,62 @@
 package kafka
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"quant-data-engine/internal/config"
 	"quant-data-engine/internal/models"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
+// mockProducer 模拟Kafka生产者，deliver决定每条消息的投递报告：
+// reported为false表示不产生报告（消息一直停留在队列中），否则err为nil表示投递成功
+// delay模拟每次提交的耗时
+type mockProducer struct {
+	deliver  func(msg *kafka.Message) (reported bool, err error)
+	delay    time.Duration
+	mutex    sync.Mutex
+	messages []*kafka.Message
+	pending  int
+}
+
+func (m *mockProducer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	time.Sleep(m.delay)
+	reported, err := m.deliver(msg)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.messages = append(m.messages, msg)
+	if !reported {
+		m.pending++
+		return nil
+	}
+	msg.TopicPartition.Error = err
+	if deliveryChan != nil {
+		deliveryChan <- msg
+	}
+	return nil
+}
+
+func (m *mockProducer) Flush(timeoutMs int) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.pending
+}
+
+func (m *mockProducer) Events() chan kafka.Event {
+	return nil
+}
+
+func (m *mockProducer) Close() {
+}
+
 // TestValidateMarketDataForKafka 测试Kafka市场数据验证
 func TestValidateMarketDataForKafka(t *testing.T) {
 	// 测试有效数据
@@ -52,6 +100,12 @@ func TestValidateMarketDataForKafka(t *testing.T) {
 	noSourceData.Source = ""
 	err = validateMarketDataForKafka(noSourceData)
 	assert.Error(t, err)
+
+	// 测试未知数据源
+	unknownSourceData := validData
+	unknownSourceData.Source = "Binance"
+	err = validateMarketDataForKafka(unknownSourceData)
+	assert.Error(t, err)
 }
 
 // TestSendMarketData 测试发送市场数据
@@ -78,3 +132,304 @@ func TestSendMarketData(t *testing.T) {
 	err = (&KafkaProducer{}).SendMarketData(invalidData)
 	assert.Error(t, err)
 }
+
+// TestSendMarketData_Delivery 测试SendMarketData实际提交消息，投递失败时返回DeliveryError并转发到死信主题
+func TestSendMarketData_Delivery(t *testing.T) {
+	data := []models.MarketData{
+		{ID: "delivered", Symbol: "BTCUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: "binance"},
+		{ID: "failed", Symbol: "ETHUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: "okx"},
+	}
+	producer := &mockProducer{
+		deliver: func(msg *kafka.Message) (bool, error) {
+			if *msg.TopicPartition.Topic == "test" && data[msg.Opaque.(int)].ID == "failed" {
+				return true, kafka.NewError(kafka.ErrMsgTimedOut, "message timed out", false)
+			}
+			return true, nil
+		},
+	}
+	p := &KafkaProducer{producer: producer, topic: "test", workers: 2, partitions: 4, dlqTopic: "test_dlq"}
+
+	err := p.SendMarketData(data)
+	var deliveryErr *DeliveryError
+	if assert.True(t, errors.As(err, &deliveryErr)) {
+		assert.Len(t, deliveryErr.Failed, 1)
+		assert.Empty(t, deliveryErr.Unknown)
+	}
+	topics := make(map[string]int)
+	for _, msg := range producer.messages {
+		topics[*msg.TopicPartition.Topic]++
+		if *msg.TopicPartition.Topic == "test" {
+			assert.Equal(t, partitionForKey(string(msg.Key), 4), msg.TopicPartition.Partition)
+		}
+	}
+	assert.Equal(t, map[string]int{"test": 2, "test_dlq": 1}, topics)
+
+	// 全部投递成功
+	producer = &mockProducer{deliver: func(msg *kafka.Message) (bool, error) { return true, nil }}
+	p = &KafkaProducer{producer: producer, topic: "test"}
+	assert.NoError(t, p.SendMarketData(data))
+	assert.Len(t, producer.messages, 2)
+
+	// 未初始化的生产者不能发送
+	assert.Error(t, (&KafkaProducer{}).SendMarketData(data))
+}
+
+// TestSendMarketDataToKafka_DeliveryStatus 测试Flush超时后区分投递失败和投递状态未知的消息
+func TestSendMarketDataToKafka_DeliveryStatus(t *testing.T) {
+	data := []models.MarketData{
+		{ID: "delivered", Symbol: "BTCUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: "binance"},
+		{ID: "failed", Symbol: "ETHUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: "binance"},
+		{ID: "pending-1", Symbol: "BNBUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: "binance"},
+		{ID: "pending-2", Symbol: "BNBUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: "okx"},
+	}
+
+	producer := &mockProducer{
+		deliver: func(msg *kafka.Message) (bool, error) {
+			switch data[msg.Opaque.(int)].ID {
+			case "delivered":
+				return true, nil
+			case "failed":
+				return true, kafka.NewError(kafka.ErrMsgTimedOut, "message timed out", false)
+			default:
+				return false, nil
+			}
+		},
+	}
+	p := &KafkaProducer{producer: producer, topic: "test"}
+
+	err := p.sendMarketDataToKafka(data)
+	assert.Error(t, err)
+	assert.Len(t, producer.messages, 4)
+
+	var deliveryErr *DeliveryError
+	assert.True(t, errors.As(err, &deliveryErr))
+	assert.Equal(t, 4, deliveryErr.Total)
+	assert.Equal(t, []string{"pending-1", "pending-2"}, deliveryErr.Unknown)
+	if assert.Len(t, deliveryErr.Failed, 1) {
+		assert.Equal(t, "failed", deliveryErr.Failed[0].ID)
+		assert.Equal(t, "ETHUSDT", deliveryErr.Failed[0].Symbol)
+	}
+	assert.Contains(t, err.Error(), "pending-1")
+
+	// 全部投递成功时不返回错误
+	producer = &mockProducer{deliver: func(msg *kafka.Message) (bool, error) { return true, nil }}
+	p = &KafkaProducer{producer: producer, topic: "test"}
+	assert.NoError(t, p.sendMarketDataToKafka(data))
+}
+
+// TestSendMarketDataToKafka_DLQ 测试投递失败的消息带error消息头转发到死信主题，投递成功的消息不转发
+func TestSendMarketDataToKafka_DLQ(t *testing.T) {
+	data := []models.MarketData{
+		{ID: "delivered", Symbol: "BTCUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: "binance"},
+		{ID: "failed", Symbol: "ETHUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: "okx"},
+	}
+	producer := &mockProducer{
+		deliver: func(msg *kafka.Message) (bool, error) {
+			if *msg.TopicPartition.Topic == "test" && data[msg.Opaque.(int)].ID == "failed" {
+				return true, kafka.NewError(kafka.ErrMsgTimedOut, "message timed out", false)
+			}
+			return true, nil
+		},
+	}
+	p := &KafkaProducer{producer: producer, topic: "test", dlqTopic: "test_dlq"}
+
+	err := p.sendMarketDataToKafka(data)
+	var deliveryErr *DeliveryError
+	if assert.True(t, errors.As(err, &deliveryErr)) {
+		assert.Len(t, deliveryErr.Failed, 1)
+	}
+
+	var dlq []*kafka.Message
+	for _, msg := range producer.messages {
+		if *msg.TopicPartition.Topic == "test_dlq" {
+			dlq = append(dlq, msg)
+		}
+	}
+	if assert.Len(t, dlq, 1) {
+		msg := dlq[0]
+		assert.Equal(t, "ETHUSDT", string(msg.Key))
+		assert.Contains(t, string(msg.Value), `"id":"failed"`)
+		headers := make(map[string]string)
+		for _, h := range msg.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		assert.Equal(t, "okx", headers["source"])
+		assert.Contains(t, headers["error"], "message timed out")
+	}
+
+	// 未配置死信主题时只返回投递错误
+	producer.messages = nil
+	p.dlqTopic = ""
+	assert.Error(t, p.sendMarketDataToKafka(data))
+	assert.Len(t, producer.messages, 2)
+}
+
+// newKeyedMarketData 生成symbols个Key、每个Key perKey条按时间递增的数据
+func newKeyedMarketData(symbols, perKey int) []models.MarketData {
+	base := time.Now()
+	data := make([]models.MarketData, 0, symbols*perKey)
+	for i := 0; i < perKey; i++ {
+		for s := 0; s < symbols; s++ {
+			data = append(data, models.MarketData{
+				ID:        fmt.Sprintf("SYM%d-%d", s, i),
+				Symbol:    fmt.Sprintf("SYM%d", s),
+				Price:     float64(i),
+				Volume:    1,
+				Timestamp: base.Add(time.Duration(i) * time.Second),
+				Source:    "binance",
+			})
+		}
+	}
+	return data
+}
+
+// TestSendMarketDataToKafka_WorkerPool 测试并发发送时保持同一Key内的顺序，且多个worker提高吞吐
+func TestSendMarketDataToKafka_WorkerPool(t *testing.T) {
+	data := newKeyedMarketData(8, 10)
+	delivered := func(msg *kafka.Message) (bool, error) { return true, nil }
+
+	producer := &mockProducer{deliver: delivered, delay: time.Millisecond}
+	p := &KafkaProducer{producer: producer, topic: "test", workers: 4}
+
+	start := time.Now()
+	assert.NoError(t, p.sendMarketDataToKafka(data))
+	concurrent := time.Since(start)
+	assert.Len(t, producer.messages, len(data))
+
+	// 同一Key的消息按原始顺序提交
+	lastIndex := make(map[string]int)
+	for _, msg := range producer.messages {
+		key := string(msg.Key)
+		index := msg.Opaque.(int)
+		if last, ok := lastIndex[key]; ok {
+			assert.Greater(t, index, last, "messages for key %s produced out of order", key)
+		}
+		lastIndex[key] = index
+	}
+	assert.Len(t, lastIndex, 8)
+
+	// 单个worker顺序发送作为对比
+	sequentialProducer := &mockProducer{deliver: delivered, delay: time.Millisecond}
+	p = &KafkaProducer{producer: sequentialProducer, topic: "test", workers: 1}
+	start = time.Now()
+	assert.NoError(t, p.sendMarketDataToKafka(data))
+	sequential := time.Since(start)
+
+	assert.Less(t, concurrent, sequential)
+}
+
+// BenchmarkSendMarketDataToKafka 对比不同worker数的发送吞吐
+func BenchmarkSendMarketDataToKafka(b *testing.B) {
+	data := newKeyedMarketData(16, 64)
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			producer := &mockProducer{deliver: func(msg *kafka.Message) (bool, error) { return true, nil }}
+			p := &KafkaProducer{producer: producer, topic: "test", workers: workers}
+			for i := 0; i < b.N; i++ {
+				producer.messages = producer.messages[:0]
+				if err := p.sendMarketDataToKafka(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestSendStockBasic 测试发送股票基础信息，以ts_code作为消息Key
+func TestSendStockBasic(t *testing.T) {
+	producer := &mockProducer{deliver: func(msg *kafka.Message) (bool, error) { return true, nil }}
+	p := &KafkaProducer{producer: producer, topic: "test"}
+
+	err := p.SendStockBasic([]models.StockBasic{
+		{TSCode: "000001.SZ", Name: "平安银行"},
+		{TSCode: "600000.SH", Name: "浦发银行"},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, producer.messages, 2) {
+		assert.Equal(t, "000001.SZ", string(producer.messages[0].Key))
+		assert.Equal(t, "stock_basic", string(producer.messages[0].Headers[0].Value))
+		assert.Contains(t, string(producer.messages[1].Value), "浦发银行")
+	}
+
+	// Flush超时返回错误
+	producer = &mockProducer{deliver: func(msg *kafka.Message) (bool, error) { return false, nil }}
+	p = &KafkaProducer{producer: producer, topic: "test"}
+	assert.Error(t, p.SendStockBasic([]models.StockBasic{{TSCode: "000001.SZ"}}))
+}
+
+// TestPartitionForKey 测试consistent分区策略下同一Key总是映射到同一分区
+func TestPartitionForKey(t *testing.T) {
+	seen := make(map[int32]bool)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("SYM%d", i)
+		partition := partitionForKey(key, 6)
+		assert.Equal(t, partition, partitionForKey(key, 6), "key %s", key)
+		assert.GreaterOrEqual(t, partition, int32(0))
+		assert.Less(t, partition, int32(6))
+		seen[partition] = true
+	}
+	// 不同Key分散到多个分区
+	assert.Greater(t, len(seen), 1)
+
+	// 未配置分区数时使用PartitionAny
+	p := &KafkaProducer{}
+	assert.Equal(t, kafka.PartitionAny, p.partitionFor("BTCUSDT"))
+
+	// 发送的消息带有计算出的分区
+	producer := &mockProducer{deliver: func(msg *kafka.Message) (bool, error) { return true, nil }}
+	p = &KafkaProducer{producer: producer, topic: "test", workers: 2, partitions: 6}
+	assert.NoError(t, p.sendMarketDataToKafka(newKeyedMarketData(4, 3)))
+	for _, msg := range producer.messages {
+		assert.Equal(t, partitionForKey(string(msg.Key), 6), msg.TopicPartition.Partition)
+	}
+}
+
+// TestPartitionCount 测试分区策略配置校验
+func TestPartitionCount(t *testing.T) {
+	partitions, err := partitionCount(PartitionerConsistent, 12)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(12), partitions)
+
+	_, err = partitionCount(PartitionerConsistent, 0)
+	assert.Error(t, err)
+
+	for _, partitioner := range []string{PartitionerAny, "", "random"} {
+		partitions, err = partitionCount(partitioner, 12)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(0), partitions, "partitioner %q", partitioner)
+	}
+}
+
+// TestProducerConfig_StrictOrdering 测试严格保序模式的生产者参数和不兼容的分区策略
+func TestProducerConfig_StrictOrdering(t *testing.T) {
+	cfg := &config.Config{KafkaBrokers: "localhost:9092", KafkaPartitioner: PartitionerAny}
+	configMap, err := producerConfig(cfg)
+	assert.NoError(t, err)
+	assert.NotContains(t, *configMap, "enable.idempotence")
+	assert.NotContains(t, *configMap, "max.in.flight.requests.per.connection")
+
+	cfg.KafkaStrictOrdering = true
+	configMap, err = producerConfig(cfg)
+	assert.Error(t, err)
+	assert.Nil(t, configMap)
+	assert.Contains(t, err.Error(), "KAFKA_PARTITIONER")
+
+	cfg.KafkaPartitioner = PartitionerConsistent
+	configMap, err = producerConfig(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, true, (*configMap)["enable.idempotence"])
+	assert.Equal(t, 1, (*configMap)["max.in.flight.requests.per.connection"])
+	assert.Equal(t, "all", (*configMap)["acks"])
+	assert.Equal(t, "localhost:9092", (*configMap)["bootstrap.servers"])
+}
+
+// TestFlush 测试刷新超时后仍有未发送消息时返回超时错误
+func TestFlush(t *testing.T) {
+	producer := &KafkaProducer{producer: &mockProducer{pending: 2}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, producer.Flush(ctx), context.DeadlineExceeded)
+
+	producer = &KafkaProducer{producer: &mockProducer{}}
+	assert.NoError(t, producer.Flush(ctx))
+}
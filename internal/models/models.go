@@ -1,7 +1,13 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // 市场数据模型
@@ -14,6 +20,44 @@ type MarketData struct {
 	Source    string    `json:"source" db:"source"`
 }
 
+// MarketDataID 由交易对、数据源和时间生成确定性的市场数据ID（UUIDv5），同一数据源同一时刻的数据重复获取时得到相同的ID，保存时按id去重
+func MarketDataID(symbol, source string, timestamp time.Time) string {
+	key := fmt.Sprintf("%s|%s|%s", symbol, source, timestamp.UTC().Format(time.RFC3339Nano))
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+}
+
+// 市场数据来源的规范名称，所有写入数据库和Kafka的Source都使用小写形式
+const (
+	SourceBinance = "binance"
+	SourceOKX     = "okx"
+)
+
+// knownSources 已知的规范数据源名称
+var knownSources = map[string]bool{
+	SourceBinance: true,
+	SourceOKX:     true,
+}
+
+// CanonicalSource 将数据源名称规范化为小写形式，例如 "Binance" -> "binance"
+func CanonicalSource(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// IsKnownSource 判断是否为已知的规范数据源名称
+func IsKnownSource(name string) bool {
+	return knownSources[name]
+}
+
+// 历史数据查询结果（一页），HasMore为true时表示后面还有数据，可用NextOffset取下一页，或从NextStart开始重新查询
+// Truncated与HasMore相同，为兼容旧客户端保留
+type HistoricalDataResult struct {
+	Data       []MarketData `json:"data"`
+	HasMore    bool         `json:"has_more"`
+	NextOffset *int         `json:"next_offset,omitempty"`
+	Truncated  bool         `json:"truncated"`
+	NextStart  *time.Time   `json:"next_start,omitempty"`
+}
+
 // 回测数据模型
 type BacktestData struct {
 	ID        string    `json:"id" db:"id"`
@@ -23,6 +67,8 @@ type BacktestData struct {
 	EndDate   time.Time `json:"end_date" db:"end_date"`
 	Results   string    `json:"results" db:"results"`
 	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	// CreatedAt 首次写入数据库的时间，由数据库生成，只在返回持久化结果时填充
+	CreatedAt *time.Time `json:"created_at,omitempty" db:"created_at"`
 }
 
 // 股票基础信息模型
@@ -48,6 +94,82 @@ type StockBasic struct {
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// StockBasicFromRow 将Tushare stock_basic响应的一行按字段名转换为StockBasic，API和定时任务共用
+// fields与item按下标对应，顺序不限；未请求、缺失或为null的字段保持零值，不认识的字段被忽略
+// 数值（如以float64返回的日期或人数）格式化为不带多余小数和指数的字符串
+func StockBasicFromRow(fields []string, item []interface{}) StockBasic {
+	var s StockBasic
+	targets := map[string]*string{
+		"ts_code":      &s.TSCode,
+		"symbol":       &s.Symbol,
+		"name":         &s.Name,
+		"area":         &s.Area,
+		"industry":     &s.Industry,
+		"fullname":     &s.Fullname,
+		"enname":       &s.Enname,
+		"cnspell":      &s.Cnspell,
+		"market":       &s.Market,
+		"exchange":     &s.Exchange,
+		"curr_type":    &s.CurrType,
+		"list_status":  &s.ListStatus,
+		"list_date":    &s.ListDate,
+		"delist_date":  &s.DelistDate,
+		"is_hs":        &s.IsHS,
+		"act_name":     &s.ActName,
+		"act_ent_type": &s.ActEntType,
+	}
+	for i, field := range fields {
+		if i >= len(item) {
+			break
+		}
+		if target, ok := targets[field]; ok {
+			*target = cellString(item[i])
+		}
+	}
+	return s
+}
+
+// IsListed 判断是否正常上市：list_status为"L"（不区分大小写）时返回true，退市（D）、暂停上市（P）、空值和无法识别的值均返回false
+func (s StockBasic) IsListed() bool {
+	return strings.EqualFold(strings.TrimSpace(s.ListStatus), "L")
+}
+
+// IsHSConnect 判断是否为沪深港通标的：is_hs为"H"（沪股通）或"S"（深股通）时返回true，"N"、空值和无法识别的值均返回false
+func (s StockBasic) IsHSConnect() bool {
+	switch strings.ToUpper(strings.TrimSpace(s.IsHS)) {
+	case "H", "S":
+		return true
+	default:
+		return false
+	}
+}
+
+// MarshalJSON 在原有字段外输出布尔类型的is_listed和is_hs_bool，list_status和is_hs保持Tushare的原始字符串
+func (s StockBasic) MarshalJSON() ([]byte, error) {
+	type stockBasic StockBasic
+	return json.Marshal(struct {
+		stockBasic
+		IsListed bool `json:"is_listed"`
+		IsHSBool bool `json:"is_hs_bool"`
+	}{stockBasic(s), s.IsListed(), s.IsHSConnect()})
+}
+
+// cellString 将Tushare响应中的单元格转换为字符串，null返回空字符串
+func cellString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return val.String()
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
 // 交易日历模型
 type TradeCal struct {
 	Exchange     string    `json:"exchange" db:"exchange"`
@@ -58,6 +180,25 @@ type TradeCal struct {
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Open 判断是否为交易日：is_open为"1"或"true"（不区分大小写）时返回true，"0"、空值和无法识别的值均视为休市
+func (t TradeCal) Open() bool {
+	switch strings.ToLower(strings.TrimSpace(t.IsOpen)) {
+	case "1", "true":
+		return true
+	default:
+		return false
+	}
+}
+
+// MarshalJSON 在原有字段外输出布尔类型的is_open_bool，is_open保持与数据库一致的"0"/"1"字符串
+func (t TradeCal) MarshalJSON() ([]byte, error) {
+	type tradeCal TradeCal
+	return json.Marshal(struct {
+		tradeCal
+		IsOpenBool bool `json:"is_open_bool"`
+	}{tradeCal(t), t.Open()})
+}
+
 // 新股上市列表模型
 type NewShare struct {
 	TSCode       string    `json:"ts_code" db:"ts_code"`
@@ -118,6 +259,19 @@ type StkManagers struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// 上市公司管理层分页结果，按公告日期倒序、姓名升序；HasMore为true时用NextOffset或NextCursor取下一页
+type StkManagersPage struct {
+	TSCode     string        `json:"ts_code"`
+	Start      string        `json:"start,omitempty"`
+	End        string        `json:"end,omitempty"`
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+	HasMore    bool          `json:"has_more"`
+	NextOffset *int          `json:"next_offset,omitempty"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Data       []StkManagers `json:"data"`
+}
+
 // 管理层薪酬和持股模型
 type StkRewards struct {
 	TSCode    string    `json:"ts_code" db:"ts_code"`
@@ -148,6 +302,53 @@ type Daily struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// 附带股票名称的A股日线模型
+type DailyWithName struct {
+	Daily
+	Name string `json:"name"`
+}
+
+// A股日线列式数据模型，TSCode、TradeDate与Columns中每一列的元素一一对应，用于减少批量拉取时的JSON体积
+type DailyColumns struct {
+	TSCode    []string             `json:"ts_code"`
+	TradeDate []string             `json:"trade_date"`
+	Columns   map[string][]float64 `json:"columns"`
+}
+
+// A股复权因子模型
+type AdjFactor struct {
+	TSCode    string    `json:"ts_code" db:"ts_code"`
+	TradeDate string    `json:"trade_date" db:"trade_date"`
+	AdjFactor float64   `json:"adj_factor" db:"adj_factor"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// A股个股资金流向模型，成交量单位为手，成交额单位为万元
+// sm/md/lg/elg分别为小单、中单、大单、特大单
+type Moneyflow struct {
+	TSCode        string    `json:"ts_code" db:"ts_code"`
+	TradeDate     string    `json:"trade_date" db:"trade_date"`
+	BuySmVol      int       `json:"buy_sm_vol" db:"buy_sm_vol"`
+	BuySmAmount   float64   `json:"buy_sm_amount" db:"buy_sm_amount"`
+	SellSmVol     int       `json:"sell_sm_vol" db:"sell_sm_vol"`
+	SellSmAmount  float64   `json:"sell_sm_amount" db:"sell_sm_amount"`
+	BuyMdVol      int       `json:"buy_md_vol" db:"buy_md_vol"`
+	BuyMdAmount   float64   `json:"buy_md_amount" db:"buy_md_amount"`
+	SellMdVol     int       `json:"sell_md_vol" db:"sell_md_vol"`
+	SellMdAmount  float64   `json:"sell_md_amount" db:"sell_md_amount"`
+	BuyLgVol      int       `json:"buy_lg_vol" db:"buy_lg_vol"`
+	BuyLgAmount   float64   `json:"buy_lg_amount" db:"buy_lg_amount"`
+	SellLgVol     int       `json:"sell_lg_vol" db:"sell_lg_vol"`
+	SellLgAmount  float64   `json:"sell_lg_amount" db:"sell_lg_amount"`
+	BuyElgVol     int       `json:"buy_elg_vol" db:"buy_elg_vol"`
+	BuyElgAmount  float64   `json:"buy_elg_amount" db:"buy_elg_amount"`
+	SellElgVol    int       `json:"sell_elg_vol" db:"sell_elg_vol"`
+	SellElgAmount float64   `json:"sell_elg_amount" db:"sell_elg_amount"`
+	NetMfVol      int       `json:"net_mf_vol" db:"net_mf_vol"`
+	NetMfAmount   float64   `json:"net_mf_amount" db:"net_mf_amount"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
 // A股日线前复权行情模型（匹配现有数据库schema）
 type OHLCVDailyQFQ struct {
 	Symbol    string    `json:"symbol" db:"symbol"`
@@ -162,16 +363,305 @@ type OHLCVDailyQFQ struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
-// API响应模型
+// 全市场回补任务进度模型，LastCode为最后一个已完成的股票代码，用于断点续传
+type BackfillProgress struct {
+	ID          string    `json:"id" db:"id"`
+	StartDate   string    `json:"start_date" db:"start_date"`
+	EndDate     string    `json:"end_date" db:"end_date"`
+	Status      string    `json:"status" db:"status"`
+	Total       int       `json:"total" db:"total"`
+	Done        int       `json:"done" db:"done"`
+	CurrentCode string    `json:"current_code" db:"current_code"`
+	LastCode    string    `json:"last_code" db:"last_code"`
+	Errors      int       `json:"errors" db:"errors"`
+	LastError   string    `json:"last_error" db:"last_error"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// 被拒绝的市场数据模型，Reason为校验失败原因，写入死信表或死信主题供排查
+type RejectedMarketData struct {
+	Data       MarketData `json:"data"`
+	Reason     string     `json:"reason"`
+	RejectedAt time.Time  `json:"rejected_at"`
+}
+
+// 市场数据推送结果，Rejections为每条非法数据在请求数组中的下标和原因；Published表示合法数据已发送到Kafka
+type IngestResult struct {
+	Accepted   int               `json:"accepted"`
+	Rejected   int               `json:"rejected"`
+	Published  bool              `json:"published"`
+	Rejections []IngestRejection `json:"rejections"`
+}
+
+// 推送的非法市场数据
+type IngestRejection struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// 回测数据导入结果
+type BacktestImportResult struct {
+	Parsed     int                       `json:"parsed"`
+	Inserted   int                       `json:"inserted"`
+	Rejected   int                       `json:"rejected"`
+	Rejections []BacktestImportRejection `json:"rejections"`
+}
+
+// 导入时被拒绝的回测数据行，Line为文件中的行号（表头为第1行）
+type BacktestImportRejection struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// 事务性发件箱消息模型，与业务数据在同一事务中写入，由转发器发送到Kafka后设置SentAt
+type OutboxMessage struct {
+	ID        int64      `json:"id" db:"id"`
+	EventType string     `json:"event_type" db:"event_type"`
+	Key       string     `json:"key" db:"key"`
+	Payload   []byte     `json:"payload" db:"payload"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+}
+
+// 发件箱事件类型
+const (
+	OutboxEventMarketData = "market_data"
+)
+
+// 数据源新鲜度模型，Stale表示最新数据距今超过阈值，数据源可能已停止更新
+type SourceFreshness struct {
+	Source     string    `json:"source"`
+	LastSeen   time.Time `json:"last_seen"`
+	AgeSeconds float64   `json:"age_seconds"`
+	Stale      bool      `json:"stale"`
+}
+
+// SLA告警模型，数据处理超过阈值时间没有成功周期时发送
+type SLAAlert struct {
+	Type             string    `json:"type"`
+	LastSuccess      time.Time `json:"last_success"`
+	GapSeconds       float64   `json:"gap_seconds"`
+	ThresholdSeconds float64   `json:"threshold_seconds"`
+	Message          string    `json:"message"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+// 流水线单个交易对的处理状态，LastError为最近一次获取或保存失败的错误（含数据源名称），
+// ConsecutiveFailures为最近一次成功之后连续失败的次数，Failing表示最近一次处理失败
+type SymbolStatus struct {
+	Symbol              string     `json:"symbol"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Failing             bool       `json:"failing"`
+}
+
+// 数据处理流水线的运行状态，Interval为考虑负载削减后的实际处理间隔，
+// Paused为true时流水线跳过处理周期，PausedAt为暂停的时间
+type PipelineStatus struct {
+	Paused      bool       `json:"paused"`
+	PausedAt    *time.Time `json:"paused_at,omitempty"`
+	Interval    string     `json:"interval"`
+	LastSuccess time.Time  `json:"last_success"`
+	Symbols     int        `json:"symbols"`
+}
+
+// 数据库与Kafka双写一致性检查的累计结果，Missing为抽样中已保存到数据库但Kafka中没有读到的记录数
+type ConsistencyStats struct {
+	Checks      int64      `json:"checks"`
+	Sampled     int64      `json:"sampled"`
+	Missing     int64      `json:"missing"`
+	LastCheckAt *time.Time `json:"last_check_at,omitempty"`
+	LastSampled int        `json:"last_sampled"`
+	LastMissing []string   `json:"last_missing"`
+}
+
+// 定时任务的执行情况，LastRun为nil表示尚未执行，LastError为最近一次执行返回的错误，NextRun为下一次计划执行的时间
+type ScheduledJobStatus struct {
+	Name         string     `json:"name"`
+	Spec         string     `json:"spec"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	NextRun      *time.Time `json:"next_run,omitempty"`
+}
+
+// 数据库连接池统计，AcquiredConns为正在使用的连接数，EmptyAcquireCount为等待空闲连接的获取次数
+type DBPoolStats struct {
+	TotalConns        int32  `json:"total_conns"`
+	IdleConns         int32  `json:"idle_conns"`
+	AcquiredConns     int32  `json:"acquired_conns"`
+	MaxConns          int32  `json:"max_conns"`
+	AcquireCount      int64  `json:"acquire_count"`
+	EmptyAcquireCount int64  `json:"empty_acquire_count"`
+	AcquireDuration   string `json:"acquire_duration"`
+}
+
+// 诊断接口的单个检查项，Details为该子系统的状态，检查失败或超时时Error为原因
+type DiagnosticCheck struct {
+	Name       string      `json:"name"`
+	OK         bool        `json:"ok"`
+	Error      string      `json:"error,omitempty"`
+	DurationMs int64       `json:"duration_ms"`
+	Details    interface{} `json:"details,omitempty"`
+}
+
+// 各子系统状态汇总，所有检查项通过时OK为true
+type DiagnosticsReport struct {
+	OK        bool              `json:"ok"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    []DiagnosticCheck `json:"checks"`
+}
+
+// 单只股票已保存日线的交易日（YYYYMMDD，升序）
+type StoredTradeDates struct {
+	TSCode string   `json:"ts_code"`
+	Dates  []string `json:"dates"`
+}
+
+// 单只股票在[Start, End]内开市但没有日线的交易日（YYYYMMDD，升序）
+type MissingTradeDates struct {
+	TSCode   string   `json:"ts_code"`
+	Exchange string   `json:"exchange"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Dates    []string `json:"dates"`
+}
+
+// 内存缓存统计，HitRate为Hits/(Hits+Misses)，没有查询时为0
+type CacheStats struct {
+	Name    string  `json:"name"`
+	Size    int     `json:"size"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// 成交量加权平均价模型，VWAP为[Start, End]内 SUM(price*volume)/SUM(volume)
+type VWAPResult struct {
+	Symbol string    `json:"symbol"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	VWAP   float64   `json:"vwap"`
+}
+
+// 成交量分布的一个价格区间[PriceLow, PriceHigh)，最后一个区间包含最高价，Ticks为区间内的数据条数
+type PriceVolumeBucket struct {
+	PriceLow  float64 `json:"price_low"`
+	PriceHigh float64 `json:"price_high"`
+	Volume    float64 `json:"volume"`
+	Ticks     int     `json:"ticks"`
+}
+
+// 交易对在[Start, End]内的成交量分布，Buckets按价格升序
+type VolumeProfileResult struct {
+	Symbol  string              `json:"symbol"`
+	Start   time.Time           `json:"start"`
+	End     time.Time           `json:"end"`
+	Buckets []PriceVolumeBucket `json:"buckets"`
+}
+
+// 两个交易对收益率相关系数，Points为参与计算的重叠收益率点数
+type CorrelationResult struct {
+	SymbolA     string    `json:"symbol_a"`
+	SymbolB     string    `json:"symbol_b"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Bucket      string    `json:"bucket"`
+	Correlation float64   `json:"correlation"`
+	Points      int       `json:"points"`
+}
+
+// 数据源原始响应，Body为gzip压缩的响应体，Endpoint为接口名称或请求路径
+type RawResponse struct {
+	ID        int64     `json:"id"`
+	Source    string    `json:"source"`
+	Endpoint  string    `json:"endpoint"`
+	Body      []byte    `json:"body"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// 交易对映射，Canonical为系统内统一的交易对名称（如BTCUSDT），ExchangeSymbol为Source数据源使用的名称（如BTC-USDT）
+type SymbolMapping struct {
+	Canonical      string `json:"canonical"`
+	Source         string `json:"source"`
+	ExchangeSymbol string `json:"exchange_symbol"`
+}
+
+// K线模型，由OpenTime开始的一个时间窗口内的市场数据聚合而成
+type Candle struct {
+	OpenTime time.Time `json:"open_time"`
+	Open     float64   `json:"open"`
+	High     float64   `json:"high"`
+	Low      float64   `json:"low"`
+	Close    float64   `json:"close"`
+	Volume   float64   `json:"volume"`
+}
+
+// 滚动最高最低价模型，High/Low为截至AsOf最近Window个交易日日线的最高价和最低价，AsOf为空表示截至最新交易日
+type RollingHighLow struct {
+	TSCode string  `json:"ts_code"`
+	Window int     `json:"window"`
+	AsOf   string  `json:"as_of,omitempty"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+}
+
+// 流水线错误通知模型，Text为带级别前缀的消息，兼容Slack Incoming Webhook
+type Notification struct {
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	Text      string    `json:"text"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// 跨数据源价格对比模型，Prices为各数据源距At最近的价格，
+// DivergencePct为最高价相对最低价的偏离百分比，超过ThresholdPct时Divergent为true
+type CrossSourceComparison struct {
+	Symbol           string             `json:"symbol"`
+	At               time.Time          `json:"at"`
+	ToleranceSeconds float64            `json:"tolerance_seconds"`
+	Prices           map[string]float64 `json:"prices"`
+	MinSource        string             `json:"min_source,omitempty"`
+	MaxSource        string             `json:"max_source,omitempty"`
+	DivergencePct    float64            `json:"divergence_pct"`
+	ThresholdPct     float64            `json:"threshold_pct"`
+	Divergent        bool               `json:"divergent"`
+}
+
+// Tushare接口限流状态，NextTokenSeconds为距离下一个令牌补充的秒数，桶满时为0
+type RateLimitState struct {
+	API              string  `json:"api"`
+	RatePerMinute    int     `json:"rate_per_minute"`
+	AvailableTokens  float64 `json:"available_tokens"`
+	NextTokenSeconds float64 `json:"next_token_seconds"`
+	Calls            int64   `json:"calls"`
+}
+
+// 分页元数据，HasMore为true时可使用NextCursor获取下一页
+type PageMeta struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      int64  `json:"total"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// API响应模型，分页列表接口在Meta中返回分页元数据
 type APIResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    *PageMeta   `json:"meta,omitempty"`
 }
 
 // 错误响应模型
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code 机器可读的错误码，如INVALID_BODY，未设置时省略
+	Code string `json:"code,omitempty"`
 }
 
 // Parquet数据请求模型
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMarketDataID 测试相同交易对、数据源和时间得到相同的ID，任一字段不同时ID不同
+func TestMarketDataID(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	id := MarketDataID("BTCUSDT", SourceBinance, ts)
+	assert.Len(t, id, 36)
+	// 同一时刻的不同时区表示得到相同的ID
+	assert.Equal(t, id, MarketDataID("BTCUSDT", SourceBinance, ts.In(time.FixedZone("CST", 8*3600))))
+
+	assert.NotEqual(t, id, MarketDataID("ETHUSDT", SourceBinance, ts))
+	assert.NotEqual(t, id, MarketDataID("BTCUSDT", SourceOKX, ts))
+	assert.NotEqual(t, id, MarketDataID("BTCUSDT", SourceBinance, ts.Add(time.Nanosecond)))
+}
+
+// TestStockBasicFromRow 测试字段顺序打乱、缺少列和数值以float64返回时按字段名转换
+func TestStockBasicFromRow(t *testing.T) {
+	fields := []string{"list_date", "name", "employees", "ts_code", "market", "symbol", "is_hs"}
+	item := []interface{}{float64(19991110), "平安银行", float64(36000), "000001.SZ", nil, json.Number("000001"), "S"}
+
+	s := StockBasicFromRow(fields, item)
+	assert.Equal(t, StockBasic{
+		TSCode:   "000001.SZ",
+		Symbol:   "000001",
+		Name:     "平安银行",
+		ListDate: "19991110",
+		IsHS:     "S",
+	}, s)
+	// 未返回的列和null保持零值
+	assert.Empty(t, s.Industry)
+	assert.Empty(t, s.Market)
+
+	// 行比字段短时忽略缺失的值
+	s = StockBasicFromRow([]string{"ts_code", "name"}, []interface{}{"600000.SH"})
+	assert.Equal(t, StockBasic{TSCode: "600000.SH"}, s)
+}
+
+// TestTradeCal_Open 测试is_open各种取值的转换，无法识别的值视为休市
+func TestTradeCal_Open(t *testing.T) {
+	tests := map[string]bool{
+		"1": true, "true": true, "TRUE": true, " 1 ": true,
+		"0": false, "false": false, "": false, "2": false, "yes": false, "Y": false,
+	}
+	for value, want := range tests {
+		assert.Equal(t, want, TradeCal{IsOpen: value}.Open(), "is_open %q", value)
+	}
+}
+
+// TestStockBasic_Flags 测试list_status和is_hs各种取值的转换，无法识别的值返回false
+func TestStockBasic_Flags(t *testing.T) {
+	listed := map[string]bool{"L": true, "l": true, " L ": true, "D": false, "P": false, "G": false, "": false, "Listed": false}
+	for value, want := range listed {
+		assert.Equal(t, want, StockBasic{ListStatus: value}.IsListed(), "list_status %q", value)
+	}
+
+	hs := map[string]bool{"H": true, "S": true, "s": true, "N": false, "": false, "HS": false, "1": false}
+	for value, want := range hs {
+		assert.Equal(t, want, StockBasic{IsHS: value}.IsHSConnect(), "is_hs %q", value)
+	}
+}
+
+// TestTypedFlagsJSON 测试JSON输出保留原始字符串并增加布尔字段，反序列化不受影响
+func TestTypedFlagsJSON(t *testing.T) {
+	body, err := json.Marshal(TradeCal{Exchange: "SSE", CalDate: "20240102", IsOpen: "1"})
+	assert.NoError(t, err)
+	var cal map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &cal))
+	assert.Equal(t, "1", cal["is_open"])
+	assert.Equal(t, true, cal["is_open_bool"])
+	assert.Equal(t, "SSE", cal["exchange"])
+
+	var decodedCal TradeCal
+	assert.NoError(t, json.Unmarshal(body, &decodedCal))
+	assert.Equal(t, TradeCal{Exchange: "SSE", CalDate: "20240102", IsOpen: "1"}, decodedCal)
+
+	body, err = json.Marshal([]StockBasic{{TSCode: "000001.SZ", ListStatus: "D", IsHS: "S"}})
+	assert.NoError(t, err)
+	var stocks []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &stocks))
+	assert.Equal(t, "D", stocks[0]["list_status"])
+	assert.Equal(t, false, stocks[0]["is_listed"])
+	assert.Equal(t, "S", stocks[0]["is_hs"])
+	assert.Equal(t, true, stocks[0]["is_hs_bool"])
+}
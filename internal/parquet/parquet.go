@@ -0,0 +1,88 @@
+// Package parquet 读写市场数据的Parquet文件
+// 编码和解码由github.com/xitongsys/parquet-go完成，生成的文件可以被pyarrow、Spark等标准实现读取
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"quant-data-engine/internal/models"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// ContentType Parquet文件的MIME类型
+const ContentType = "application/vnd.apache.parquet"
+
+// ErrInvalidFile 不是Parquet文件或与市场数据的schema不匹配
+var ErrInvalidFile = errors.New("invalid parquet file")
+
+// marketDataRow 市场数据文件的一行，字段顺序即列顺序
+type marketDataRow struct {
+	Symbol    string  `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Price     float64 `parquet:"name=price, type=DOUBLE"`
+	Volume    float64 `parquet:"name=volume, type=DOUBLE"`
+	Timestamp int64   `parquet:"name=timestamp, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+}
+
+// WriteMarketData 将市场数据写为Parquet文件，列为symbol、price、volume和timestamp（毫秒精度的UTC时间），使用默认的SNAPPY压缩
+func WriteMarketData(w io.Writer, data []models.MarketData) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(marketDataRow), 1)
+	if err != nil {
+		return fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	for _, d := range data {
+		row := marketDataRow{
+			Symbol:    d.Symbol,
+			Price:     d.Price,
+			Volume:    d.Volume,
+			Timestamp: d.Timestamp.UnixMilli(),
+		}
+		if err := pw.Write(row); err != nil {
+			return fmt.Errorf("failed to write parquet row: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	return nil
+}
+
+// ReadMarketData 读取WriteMarketData写出的Parquet文件，文件损坏或schema不匹配时返回ErrInvalidFile
+func ReadMarketData(file []byte) (data []models.MarketData, err error) {
+	// parquet-go遇到损坏的文件时可能panic，转换为ErrInvalidFile
+	defer func() {
+		if r := recover(); r != nil {
+			data, err = nil, fmt.Errorf("%w: %v", ErrInvalidFile, r)
+		}
+	}()
+
+	source, err := buffer.NewBufferFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	pr, err := reader.NewParquetReader(source, new(marketDataRow), 1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	defer pr.ReadStop()
+
+	rows := make([]marketDataRow, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	data = make([]models.MarketData, len(rows))
+	for i, row := range rows {
+		data[i] = models.MarketData{
+			Symbol:    row.Symbol,
+			Price:     row.Price,
+			Volume:    row.Volume,
+			Timestamp: time.UnixMilli(row.Timestamp).UTC(),
+		}
+	}
+	return data, nil
+}
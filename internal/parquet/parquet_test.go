@@ -0,0 +1,101 @@
+package parquet
+
+import (
+	"bytes"
+	"fmt"
+	"quant-data-engine/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// TestWriteMarketData_LibraryReader 测试写出的文件可以被parquet-go的reader按列名和类型解码
+func TestWriteMarketData_LibraryReader(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var data []models.MarketData
+	for i := 0; i < 20; i++ {
+		data = append(data, models.MarketData{
+			Symbol:    fmt.Sprintf("SYM%d", i%3),
+			Price:     100.5 + float64(i),
+			Volume:    float64(i) * 0.25,
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMarketData(&buf, data))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("PAR1")))
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("PAR1")))
+
+	// 不依赖包内的行类型，按schema独立声明
+	type row struct {
+		Symbol    string  `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+		Price     float64 `parquet:"name=price, type=DOUBLE"`
+		Volume    float64 `parquet:"name=volume, type=DOUBLE"`
+		Timestamp int64   `parquet:"name=timestamp, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	}
+	source, err := buffer.NewBufferFile(buf.Bytes())
+	require.NoError(t, err)
+	pr, err := reader.NewParquetReader(source, new(row), 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+	require.Equal(t, int64(len(data)), pr.GetNumRows())
+
+	rows := make([]row, pr.GetNumRows())
+	require.NoError(t, pr.Read(&rows))
+	for i := range data {
+		assert.Equal(t, data[i].Symbol, rows[i].Symbol)
+		assert.Equal(t, data[i].Price, rows[i].Price)
+		assert.Equal(t, data[i].Volume, rows[i].Volume)
+		assert.Equal(t, data[i].Timestamp.UnixMilli(), rows[i].Timestamp)
+	}
+}
+
+// TestWriteMarketData_RoundTrip 测试写出的Parquet文件可以读回相同的数据
+func TestWriteMarketData_RoundTrip(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := []models.MarketData{
+		{Symbol: "BTCUSDT", Price: 42000.5, Volume: 1.5, Timestamp: base},
+		{Symbol: "ETHUSDT", Price: 2300.25, Volume: 10, Timestamp: base.Add(time.Second)},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMarketData(&buf, data))
+
+	got, err := ReadMarketData(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, got, len(data))
+	for i := range data {
+		assert.Equal(t, data[i].Symbol, got[i].Symbol)
+		assert.Equal(t, data[i].Price, got[i].Price)
+		assert.Equal(t, data[i].Volume, got[i].Volume)
+		assert.True(t, data[i].Timestamp.Equal(got[i].Timestamp))
+	}
+}
+
+// TestWriteMarketData_Empty 测试没有数据时写出只有schema的文件
+func TestWriteMarketData_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMarketData(&buf, nil))
+
+	got, err := ReadMarketData(buf.Bytes())
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+// TestReadMarketData_Invalid 测试非Parquet文件和被截断的文件返回ErrInvalidFile
+func TestReadMarketData_Invalid(t *testing.T) {
+	_, err := ReadMarketData([]byte("symbol,price\nBTCUSDT,1\n"))
+	assert.ErrorIs(t, err, ErrInvalidFile)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMarketData(&buf, []models.MarketData{{Symbol: "BTCUSDT", Price: 1, Timestamp: time.Now()}}))
+	file := buf.Bytes()
+	// 去掉文件尾部的元数据
+	_, err = ReadMarketData(file[:len(file)/2])
+	assert.ErrorIs(t, err, ErrInvalidFile)
+}
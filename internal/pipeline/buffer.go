@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"quant-data-engine/internal/models"
+	"quant-data-engine/internal/storage"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxRetainedBatches 临时性数据库错误时缓冲区最多保留maxSize的多少倍数据，超过时丢弃，避免数据库长时间不可用时无限增长
+const maxRetainedBatches = 10
+
+// FlushHandler 接收每次刷新的结果：batch为本次保存的数据，elapsed为保存耗时，err为nil表示已落库
+type FlushHandler func(batch []models.MarketData, elapsed time.Duration, err error)
+
+// WriteBuffer 市场数据写缓冲：跨交易对和数据源累积数据，达到maxSize条或最早的数据超过maxAge时一次性保存，
+// 减少每个周期大量只含一两条数据的小事务
+// Add只把数据放入缓冲区，数据真正落库（或保存失败）时才通过FlushHandler通知，
+// 流水线据此记录保存成功、观测保存耗时并在落库后发送到Kafka，见WithWriteBuffer
+type WriteBuffer struct {
+	store   MarketDataStore
+	maxSize int
+	maxAge  time.Duration
+	onFlush FlushHandler
+
+	// mutex 保护缓冲区，刷新期间持有锁，保证各批数据按写入顺序保存
+	mutex    sync.Mutex
+	pending  []models.MarketData
+	oldestAt time.Time
+}
+
+// NewWriteBuffer 创建写缓冲，maxSize<1时按1处理（即不缓冲）
+func NewWriteBuffer(store MarketDataStore, maxSize int, maxAge time.Duration) *WriteBuffer {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	return &WriteBuffer{
+		store:   store,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}
+}
+
+// attach 替换保存使用的存储并设置刷新结果的接收方
+func (b *WriteBuffer) attach(store MarketDataStore, handler FlushHandler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.store = store
+	b.onFlush = handler
+}
+
+// Add 将数据加入缓冲区，达到maxSize条时同步刷新并返回保存结果；返回nil不表示数据已落库
+func (b *WriteBuffer) Add(data []models.MarketData) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.pending) == 0 {
+		b.oldestAt = time.Now()
+	}
+	b.pending = append(b.pending, data...)
+	if len(b.pending) < b.maxSize {
+		return nil
+	}
+	return b.flushLocked()
+}
+
+// Flush 立即保存缓冲区中的所有数据，用于优雅关闭
+func (b *WriteBuffer) Flush() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.flushLocked()
+}
+
+// Pending 返回缓冲区中尚未保存的数据条数
+func (b *WriteBuffer) Pending() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.pending)
+}
+
+// Run 定期检查缓冲区，最早的数据超过maxAge时刷新，直到ctx被取消
+// 退出时不刷新，关闭时应在数据处理停止后调用Flush，避免丢失最后一批数据
+func (b *WriteBuffer) Run(ctx context.Context) {
+	if b.maxAge <= 0 {
+		return
+	}
+	ticker := time.NewTicker(b.checkInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := b.flushExpired(now); err != nil {
+				logrus.Errorf("Failed to flush market data write buffer: %v", err)
+			}
+		}
+	}
+}
+
+// checkInterval 检查间隔为maxAge的四分之一，数据最多比maxAge晚四分之一落库
+func (b *WriteBuffer) checkInterval() time.Duration {
+	interval := b.maxAge / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval
+}
+
+// flushExpired 最早的数据在now时已超过maxAge时刷新
+func (b *WriteBuffer) flushExpired(now time.Time) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.pending) == 0 || now.Sub(b.oldestAt) < b.maxAge {
+		return nil
+	}
+	return b.flushLocked()
+}
+
+// flushLocked 保存并清空缓冲区，调用方需持有锁
+// 临时性数据库错误（storage.ErrDBTransient）时保留这批数据，下次刷新时与新数据一起重试，
+// 保留的数据超过maxRetainedBatches*maxSize条或遇到其他错误时丢弃，避免数据库故障时缓冲区无限增长
+func (b *WriteBuffer) flushLocked() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	batch := b.pending
+	b.pending = nil
+	start := time.Now()
+	err := b.store.SaveMarketData(batch)
+	if b.onFlush != nil {
+		b.onFlush(batch, time.Since(start), err)
+	}
+	if err == nil {
+		logrus.Debugf("Flushed %d buffered market data records", len(batch))
+		return nil
+	}
+
+	if errors.Is(err, storage.ErrDBTransient) && len(batch) <= maxRetainedBatches*b.maxSize {
+		logrus.Warnf("Keeping %d buffered market data records after transient save error: %v", len(batch), err)
+		b.pending = batch
+		return err
+	}
+	logrus.Errorf("Dropping %d buffered market data records after failed save: %v", len(batch), err)
+	return err
+}
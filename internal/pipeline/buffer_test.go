@@ -0,0 +1,151 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quant-data-engine/internal/models"
+	"quant-data-engine/internal/storage"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchStore 记录每次保存的批次
+type batchStore struct {
+	mutex   sync.Mutex
+	batches [][]models.MarketData
+	err     error
+}
+
+func (s *batchStore) SaveMarketData(data []models.MarketData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, data)
+	return nil
+}
+
+func (s *batchStore) batchSizes() []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sizes := make([]int, 0, len(s.batches))
+	for _, batch := range s.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func tick(symbol string) []models.MarketData {
+	return []models.MarketData{{Symbol: symbol, Price: 1, Timestamp: time.Now(), Source: "binance"}}
+}
+
+// TestWriteBuffer_SizeFlush 测试达到条数上限时一次性保存
+func TestWriteBuffer_SizeFlush(t *testing.T) {
+	store := &batchStore{}
+	buffer := NewWriteBuffer(store, 3, time.Hour)
+
+	require.NoError(t, buffer.Add(tick("BTCUSDT")))
+	require.NoError(t, buffer.Add(tick("ETHUSDT")))
+	assert.Empty(t, store.batchSizes())
+	assert.Equal(t, 2, buffer.Pending())
+
+	require.NoError(t, buffer.Add(tick("BNBUSDT")))
+	assert.Equal(t, []int{3}, store.batchSizes())
+	assert.Equal(t, 0, buffer.Pending())
+
+	// 保存失败时返回错误并丢弃这批数据
+	store.err = errors.New("database error")
+	require.NoError(t, buffer.Add(tick("BTCUSDT")))
+	require.NoError(t, buffer.Add(tick("ETHUSDT")))
+	assert.Error(t, buffer.Add(tick("BNBUSDT")))
+	assert.Equal(t, 0, buffer.Pending())
+}
+
+// TestWriteBuffer_TransientError 测试临时性数据库错误时保留这批数据，下次刷新时一起保存，刷新结果通知接收方
+func TestWriteBuffer_TransientError(t *testing.T) {
+	store := &batchStore{err: fmt.Errorf("%w: connection reset", storage.ErrDBTransient)}
+	buffer := NewWriteBuffer(store, 2, time.Hour)
+	var flushes []error
+	buffer.attach(store, func(batch []models.MarketData, elapsed time.Duration, err error) {
+		flushes = append(flushes, err)
+	})
+
+	require.NoError(t, buffer.Add(tick("BTCUSDT")))
+	assert.ErrorIs(t, buffer.Add(tick("ETHUSDT")), storage.ErrDBTransient)
+	assert.Equal(t, 2, buffer.Pending())
+
+	store.err = nil
+	require.NoError(t, buffer.Add(tick("BNBUSDT")))
+	assert.Equal(t, []int{3}, store.batchSizes())
+	assert.Equal(t, 0, buffer.Pending())
+	require.Len(t, flushes, 2)
+	assert.Error(t, flushes[0])
+	assert.NoError(t, flushes[1])
+
+	// 保留的数据超过上限时丢弃
+	store.err = fmt.Errorf("%w: connection reset", storage.ErrDBTransient)
+	for i := 0; i < maxRetainedBatches*2; i++ {
+		buffer.Add(tick("BTCUSDT"))
+	}
+	assert.Equal(t, maxRetainedBatches*2, buffer.Pending())
+	buffer.Add(tick("BTCUSDT"))
+	assert.Equal(t, 0, buffer.Pending())
+}
+
+// TestWriteBuffer_AgeFlush 测试最早的数据超过最长缓冲时间后刷新
+func TestWriteBuffer_AgeFlush(t *testing.T) {
+	store := &batchStore{}
+	buffer := NewWriteBuffer(store, 100, time.Minute)
+
+	require.NoError(t, buffer.Add(tick("BTCUSDT")))
+	require.NoError(t, buffer.Add(tick("ETHUSDT")))
+
+	require.NoError(t, buffer.flushExpired(time.Now().Add(30*time.Second)))
+	assert.Empty(t, store.batchSizes())
+
+	require.NoError(t, buffer.flushExpired(time.Now().Add(2*time.Minute)))
+	assert.Equal(t, []int{2}, store.batchSizes())
+
+	// 后台定时检查
+	buffer = NewWriteBuffer(store, 100, 20*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go buffer.Run(ctx)
+
+	require.NoError(t, buffer.Add(tick("BNBUSDT")))
+	assert.Eventually(t, func() bool { return buffer.Pending() == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{2, 1}, store.batchSizes())
+}
+
+// TestWriteBuffer_ShutdownFlush 测试关闭时保存缓冲区中剩余的数据
+func TestWriteBuffer_ShutdownFlush(t *testing.T) {
+	store := &batchStore{}
+	buffer := NewWriteBuffer(store, 100, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buffer.Run(ctx)
+	}()
+
+	require.NoError(t, buffer.Add(tick("BTCUSDT")))
+	require.NoError(t, buffer.Add(tick("ETHUSDT")))
+
+	cancel()
+	<-done
+	assert.Empty(t, store.batchSizes())
+
+	require.NoError(t, buffer.Flush())
+	assert.Equal(t, []int{2}, store.batchSizes())
+	assert.Equal(t, 0, buffer.Pending())
+
+	// 缓冲区为空时不保存
+	require.NoError(t, buffer.Flush())
+	assert.Equal(t, []int{2}, store.batchSizes())
+}
@@ -0,0 +1,164 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"quant-data-engine/internal/models"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MarketDataSampler 双写一致性检查依赖的存储接口，抽样保存时间在from之前到to之前之间的市场数据
+type MarketDataSampler interface {
+	SampleRecentMarketData(ctx context.Context, from, to time.Duration, percent, limit int) ([]models.MarketData, error)
+}
+
+// MarketDataConsumer 双写一致性检查读取Kafka市场数据的接口，Consume直到ctx被取消
+type MarketDataConsumer interface {
+	Consume(ctx context.Context, handler func(models.MarketData) error) error
+}
+
+// maxReportedMissing 统计结果中最多保留的缺失记录ID数
+const maxReportedMissing = 20
+
+// ConsistencyChecker 数据库与Kafka双写一致性检查
+// 独立消费组持续读取主题末尾并记录读到的市场数据ID，每个检查间隔抽样上一个间隔内保存到数据库的数据，
+// 没有在Kafka中读到的记录写入警告日志并计入统计；保存不到grace的数据留到下次检查，等待发送完成
+// 检查的前提是保存的数据都会发送到Kafka（流水线和推送接口的发送方，或发件箱转发器），只保存不发送的数据都会被标记为缺失
+type ConsistencyChecker struct {
+	sampler  MarketDataSampler
+	consumer MarketDataConsumer
+	interval time.Duration
+	grace    time.Duration
+	percent  int
+	limit    int
+
+	mutex sync.Mutex
+	// seen 从Kafka读到的市场数据ID和读到的时间
+	seen map[string]time.Time
+	// startedAt 开始消费的时间，之前保存的数据可能在消费开始前已经发送，不做检查
+	startedAt time.Time
+	stats     models.ConsistencyStats
+}
+
+// NewConsistencyChecker 创建双写一致性检查，每interval抽样percent%（最多limit条）的数据
+func NewConsistencyChecker(sampler MarketDataSampler, consumer MarketDataConsumer, interval, grace time.Duration, percent, limit int) *ConsistencyChecker {
+	return &ConsistencyChecker{
+		sampler:  sampler,
+		consumer: consumer,
+		interval: interval,
+		grace:    grace,
+		percent:  percent,
+		limit:    limit,
+		seen:     make(map[string]time.Time),
+		stats:    models.ConsistencyStats{LastMissing: []string{}},
+	}
+}
+
+// Run 在后台消费Kafka并定期检查，直到ctx被取消，返回前等待消费结束，之后可以关闭消费者
+func (c *ConsistencyChecker) Run(ctx context.Context) {
+	c.mutex.Lock()
+	c.startedAt = time.Now()
+	c.mutex.Unlock()
+
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		if err := c.consumer.Consume(ctx, c.observe); err != nil {
+			logrus.Errorf("Consistency check consumer stopped: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	logrus.Infof("Starting DB/Kafka consistency check with interval %v, sampling %d%%", c.interval, c.percent)
+
+	for {
+		select {
+		case <-ctx.Done():
+			<-consumerDone
+			return
+		case <-ticker.C:
+			if _, err := c.CheckOnce(ctx); err != nil {
+				logrus.Errorf("Consistency check failed: %v", err)
+			}
+		}
+	}
+}
+
+// observe 记录从Kafka读到的市场数据
+func (c *ConsistencyChecker) observe(d models.MarketData) error {
+	c.mutex.Lock()
+	c.seen[d.ID] = time.Now()
+	c.mutex.Unlock()
+	return nil
+}
+
+// CheckOnce 抽样上一个检查间隔内保存的数据，返回已保存到数据库但Kafka中没有读到的记录
+// 消费开始后grace内保存的数据不检查，消费组分配分区前发送的消息读不到
+func (c *ConsistencyChecker) CheckOnce(ctx context.Context) ([]models.MarketData, error) {
+	c.mutex.Lock()
+	from := min(c.interval+c.grace, time.Since(c.startedAt)-c.grace)
+	c.mutex.Unlock()
+	if from <= c.grace {
+		return nil, nil
+	}
+
+	sample, err := c.sampler.SampleRecentMarketData(ctx, from, c.grace, c.percent, c.limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample market data: %w", err)
+	}
+
+	now := time.Now()
+	var missing []models.MarketData
+	c.mutex.Lock()
+	for _, d := range sample {
+		if _, ok := c.seen[d.ID]; !ok {
+			missing = append(missing, d)
+		}
+	}
+	// 保存时间早于本次检查窗口的数据不会再被检查，读到时间超过两个窗口的ID不再需要
+	for id, seenAt := range c.seen {
+		if now.Sub(seenAt) > 2*(c.interval+c.grace) {
+			delete(c.seen, id)
+		}
+	}
+	c.recordStats(now, len(sample), missing)
+	c.mutex.Unlock()
+
+	for _, d := range missing {
+		logrus.Warnf("Market data %s (%s from %s at %s) was saved to the database but not found in Kafka",
+			d.ID, d.Symbol, d.Source, d.Timestamp.Format(time.RFC3339))
+	}
+	if len(missing) > 0 {
+		logrus.Errorf("Consistency check: %d of %d sampled market data records are missing from Kafka", len(missing), len(sample))
+	} else {
+		logrus.Debugf("Consistency check: all %d sampled market data records found in Kafka", len(sample))
+	}
+	return missing, nil
+}
+
+// recordStats 累计检查结果，调用方持有mutex
+func (c *ConsistencyChecker) recordStats(at time.Time, sampled int, missing []models.MarketData) {
+	c.stats.Checks++
+	c.stats.Sampled += int64(sampled)
+	c.stats.Missing += int64(len(missing))
+	c.stats.LastCheckAt = &at
+	c.stats.LastSampled = sampled
+	c.stats.LastMissing = []string{}
+	for _, d := range missing[:min(len(missing), maxReportedMissing)] {
+		c.stats.LastMissing = append(c.stats.LastMissing, d.ID)
+	}
+}
+
+// Stats 返回累计的检查结果
+func (c *ConsistencyChecker) Stats() models.ConsistencyStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := c.stats
+	stats.LastMissing = append([]string{}, c.stats.LastMissing...)
+	return stats
+}
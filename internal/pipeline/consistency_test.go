@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"quant-data-engine/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleCall 记录的抽样参数
+type sampleCall struct {
+	from, to       time.Duration
+	percent, limit int
+}
+
+// fakeSampler 返回固定的抽样结果
+type fakeSampler struct {
+	data  []models.MarketData
+	err   error
+	calls []sampleCall
+}
+
+func (s *fakeSampler) SampleRecentMarketData(ctx context.Context, from, to time.Duration, percent, limit int) ([]models.MarketData, error) {
+	s.calls = append(s.calls, sampleCall{from, to, percent, limit})
+	return s.data, s.err
+}
+
+// fakeConsumer 依次交给handler的Kafka消息，之后阻塞到ctx被取消
+type fakeConsumer struct {
+	messages []models.MarketData
+	handled  sync.WaitGroup
+}
+
+func (c *fakeConsumer) Consume(ctx context.Context, handler func(models.MarketData) error) error {
+	for _, m := range c.messages {
+		if err := handler(m); err != nil {
+			return err
+		}
+	}
+	c.handled.Done()
+	<-ctx.Done()
+	return nil
+}
+
+// TestConsistencyChecker_FlagsMissing 测试已保存到数据库但Kafka中没有读到的记录被标记并计入统计
+func TestConsistencyChecker_FlagsMissing(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	saved := []models.MarketData{
+		{ID: "a", Symbol: "BTCUSDT", Price: 1, Timestamp: ts, Source: models.SourceBinance},
+		{ID: "b", Symbol: "ETHUSDT", Price: 2, Timestamp: ts, Source: models.SourceOKX},
+		{ID: "c", Symbol: "BNBUSDT", Price: 3, Timestamp: ts, Source: models.SourceBinance},
+	}
+	sampler := &fakeSampler{data: saved}
+	consumer := &fakeConsumer{messages: []models.MarketData{saved[0], saved[2]}}
+	consumer.handled.Add(1)
+
+	checker := NewConsistencyChecker(sampler, consumer, time.Minute, 10*time.Second, 25, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		checker.Run(ctx)
+	}()
+	consumer.handled.Wait()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// 刚开始消费时不检查
+	missing, err := checker.CheckOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+	assert.Empty(t, sampler.calls)
+
+	// 模拟已消费一段时间，抽样上一个间隔（加上等待发送的时间）内保存的数据
+	checker.mutex.Lock()
+	checker.startedAt = time.Now().Add(-time.Hour)
+	checker.mutex.Unlock()
+	missing, err = checker.CheckOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, "b", missing[0].ID)
+	assert.Equal(t, []sampleCall{{from: 70 * time.Second, to: 10 * time.Second, percent: 25, limit: 100}}, sampler.calls)
+
+	stats := checker.Stats()
+	assert.Equal(t, int64(1), stats.Checks)
+	assert.Equal(t, int64(3), stats.Sampled)
+	assert.Equal(t, int64(1), stats.Missing)
+	assert.Equal(t, 3, stats.LastSampled)
+	assert.Equal(t, []string{"b"}, stats.LastMissing)
+	assert.NotNil(t, stats.LastCheckAt)
+
+	// 全部读到时不标记，累计结果保留
+	require.NoError(t, checker.observe(saved[1]))
+	missing, err = checker.CheckOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+	stats = checker.Stats()
+	assert.Equal(t, int64(2), stats.Checks)
+	assert.Equal(t, int64(1), stats.Missing)
+	assert.Empty(t, stats.LastMissing)
+}
+
+// TestConsistencyChecker_SampleError 测试抽样失败时返回错误且不计入统计
+func TestConsistencyChecker_SampleError(t *testing.T) {
+	sampler := &fakeSampler{err: errors.New("connection refused")}
+	checker := NewConsistencyChecker(sampler, &fakeConsumer{}, time.Minute, 0, 10, 100)
+	checker.startedAt = time.Now().Add(-time.Hour)
+
+	_, err := checker.CheckOnce(context.Background())
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Equal(t, int64(0), checker.Stats().Checks)
+}
+
+// TestConsistencyChecker_PipelineSendPath 测试流水线保存并发送的数据通过检查，发送失败的数据被标记为缺失
+func TestConsistencyChecker_PipelineSendPath(t *testing.T) {
+	check := func(publisher MarketDataPublisher, sent func() []models.MarketData) ([]models.MarketData, []models.MarketData) {
+		store := &mockStore{}
+		p := NewPipeline(newTestFactory(), store, publisher, []string{"BTCUSDT", "ETHUSDT"}, time.Second, nil)
+		p.ProcessData()
+
+		checker := NewConsistencyChecker(&fakeSampler{data: store.saved}, &fakeConsumer{}, time.Minute, 0, 100, 100)
+		checker.startedAt = time.Now().Add(-time.Hour)
+		for _, d := range sent() {
+			require.NoError(t, checker.observe(d))
+		}
+		missing, err := checker.CheckOnce(context.Background())
+		require.NoError(t, err)
+		return store.saved, missing
+	}
+
+	publisher := &mockPublisher{}
+	saved, missing := check(publisher, func() []models.MarketData { return publisher.sent })
+	assert.Len(t, saved, 2)
+	assert.Empty(t, missing)
+
+	saved, missing = check(failingPublisher{}, func() []models.MarketData { return nil })
+	assert.Len(t, saved, 2)
+	assert.Equal(t, saved, missing)
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoadShedder 根据数据库保存延迟自适应地放慢数据处理节奏
+// 连续slowSaves次保存耗时超过threshold时，处理间隔倍数翻倍（不超过maxFactor）；
+// 保存恢复正常后倍数逐步减半，直到回到原始间隔
+type LoadShedder struct {
+	mutex       sync.Mutex
+	threshold   time.Duration
+	slowSaves   int
+	maxFactor   int
+	consecutive int
+	factor      int
+}
+
+// NewLoadShedder 创建负载削减器，threshold<=0时不生效
+func NewLoadShedder(threshold time.Duration, slowSaves, maxFactor int) *LoadShedder {
+	if slowSaves < 1 {
+		slowSaves = 1
+	}
+	if maxFactor < 1 {
+		maxFactor = 1
+	}
+	return &LoadShedder{
+		threshold: threshold,
+		slowSaves: slowSaves,
+		maxFactor: maxFactor,
+		factor:    1,
+	}
+}
+
+// Observe 记录一次数据库保存耗时
+func (l *LoadShedder) Observe(latency time.Duration) {
+	if l.threshold <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if latency > l.threshold {
+		l.consecutive++
+		if l.consecutive >= l.slowSaves && l.factor < l.maxFactor {
+			l.factor = min(l.factor*2, l.maxFactor)
+			l.consecutive = 0
+			logrus.Warnf("DB save latency %v exceeds %v, slowing data processing by %dx", latency, l.threshold, l.factor)
+		}
+		return
+	}
+
+	l.consecutive = 0
+	if l.factor > 1 {
+		l.factor /= 2
+		logrus.Infof("DB save latency back to normal, data processing slowdown reduced to %dx", l.factor)
+	}
+}
+
+// Factor 返回当前处理间隔倍数
+func (l *LoadShedder) Factor() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.factor
+}
+
+// Interval 根据当前倍数计算实际处理间隔
+func (l *LoadShedder) Interval(base time.Duration) time.Duration {
+	return base * time.Duration(l.Factor())
+}
@@ -0,0 +1,142 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"quant-data-engine/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 通知级别，从低到高
+const (
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// levelRanks 通知级别的高低顺序
+var levelRanks = map[string]int{
+	LevelInfo:    0,
+	LevelWarning: 1,
+	LevelError:   2,
+}
+
+// notifyTimeout 单次发送通知的最长等待时间，避免通知渠道故障拖慢数据处理
+const notifyTimeout = 5 * time.Second
+
+// Notifier 流水线错误通知渠道
+type Notifier interface {
+	Notify(ctx context.Context, level, message string) error
+}
+
+// NopNotifier 不发送任何通知，未配置通知渠道时使用
+type NopNotifier struct{}
+
+// Notify 忽略通知
+func (NopNotifier) Notify(ctx context.Context, level, message string) error {
+	return nil
+}
+
+// WebhookNotifier 将通知以JSON POST到指定URL，text字段兼容Slack Incoming Webhook
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier 创建Webhook通知渠道
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify 发送通知
+func (n *WebhookNotifier) Notify(ctx context.Context, level, message string) error {
+	return postJSON(ctx, n.client, n.url, models.Notification{
+		Level:     level,
+		Message:   message,
+		Text:      fmt.Sprintf("[%s] %s", strings.ToUpper(level), message),
+		Timestamp: time.Now(),
+	})
+}
+
+// ThrottledNotifier 过滤低于最低级别的通知，并在window内丢弃级别和内容相同的重复通知
+type ThrottledNotifier struct {
+	next     Notifier
+	minRank  int
+	window   time.Duration
+	now      func() time.Time
+	mutex    sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// NewThrottledNotifier 创建带级别过滤和限流的通知渠道，未知的minLevel按warning处理
+func NewThrottledNotifier(next Notifier, minLevel string, window time.Duration) *ThrottledNotifier {
+	minRank, ok := levelRanks[minLevel]
+	if !ok {
+		minRank = levelRanks[LevelWarning]
+	}
+	return &ThrottledNotifier{
+		next:     next,
+		minRank:  minRank,
+		window:   window,
+		now:      time.Now,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// Notify 级别足够且不在限流窗口内时发送通知，发送失败时不计入限流，下次仍会重试
+func (n *ThrottledNotifier) Notify(ctx context.Context, level, message string) error {
+	if levelRanks[level] < n.minRank {
+		return nil
+	}
+
+	key := level + "\x00" + message
+	now := n.now()
+	n.mutex.Lock()
+	if last, ok := n.lastSent[key]; ok && now.Sub(last) < n.window {
+		n.mutex.Unlock()
+		return nil
+	}
+	n.lastSent[key] = now
+	n.mutex.Unlock()
+
+	if err := n.next.Notify(ctx, level, message); err != nil {
+		n.mutex.Lock()
+		delete(n.lastSent, key)
+		n.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// postJSON 将v以JSON POST到url，非2xx响应视为失败
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier 记录收到的通知
+type recordingNotifier struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, level, message string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.messages = append(n.messages, level+": "+message)
+	return nil
+}
+
+// TestThrottledWebhookNotifier 测试通知发送到Webhook，重复通知被限流，低于最低级别的通知被过滤
+func TestThrottledWebhookNotifier(t *testing.T) {
+	var mutex sync.Mutex
+	var received []models.Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n models.Notification
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+		mutex.Lock()
+		received = append(received, n)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	notifier := NewThrottledNotifier(NewWebhookNotifier(server.URL), LevelWarning, time.Minute)
+	notifier.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, notifier.Notify(ctx, LevelError, "Symbol LUNAUSDT failing"))
+	require.NoError(t, notifier.Notify(ctx, LevelError, "Symbol LUNAUSDT failing"))
+	require.NoError(t, notifier.Notify(ctx, LevelInfo, "cycle completed"))
+	require.NoError(t, notifier.Notify(ctx, LevelWarning, "Data processing slowed"))
+
+	mutex.Lock()
+	require.Len(t, received, 2)
+	assert.Equal(t, LevelError, received[0].Level)
+	assert.Equal(t, "Symbol LUNAUSDT failing", received[0].Message)
+	assert.Equal(t, "[ERROR] Symbol LUNAUSDT failing", received[0].Text)
+	assert.Equal(t, LevelWarning, received[1].Level)
+	mutex.Unlock()
+
+	// 限流窗口过后再次发送
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, notifier.Notify(ctx, LevelError, "Symbol LUNAUSDT failing"))
+	mutex.Lock()
+	assert.Len(t, received, 3)
+	mutex.Unlock()
+}
+
+// TestThrottledNotifier_RetryAfterFailure 测试发送失败的通知不计入限流
+func TestThrottledNotifier_RetryAfterFailure(t *testing.T) {
+	status := http.StatusInternalServerError
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewThrottledNotifier(NewWebhookNotifier(server.URL), LevelWarning, time.Hour)
+	assert.Error(t, notifier.Notify(context.Background(), LevelError, "failing"))
+
+	status = http.StatusOK
+	assert.NoError(t, notifier.Notify(context.Background(), LevelError, "failing"))
+	assert.NoError(t, notifier.Notify(context.Background(), LevelError, "failing"))
+	assert.Equal(t, 2, calls)
+}
+
+// TestPipeline_NotifiesSustainedFailure 测试交易对连续失败达到阈值时只通知一次
+func TestPipeline_NotifiesSustainedFailure(t *testing.T) {
+	factory := datasource.NewDataSourceFactory()
+	factory.Register("binance", &failingSource{
+		MockDataSource: datasource.NewMockDataSource("binance"),
+		failSymbol:     "LUNAUSDT",
+	})
+	notifier := &recordingNotifier{}
+	p := NewPipeline(factory, &mockStore{}, nil, []string{"BTCUSDT", "LUNAUSDT"}, time.Second, nil,
+		WithNotifier(notifier, 2))
+
+	p.ProcessData()
+	assert.Empty(t, notifier.messages)
+
+	p.ProcessData()
+	p.ProcessData()
+	require.Len(t, notifier.messages, 1)
+	assert.Equal(t, "error: Symbol LUNAUSDT failed 2 consecutive times, last error from binance: symbol delisted", notifier.messages[0])
+}
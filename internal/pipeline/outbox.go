@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"quant-data-engine/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OutboxStore 发件箱转发器依赖的存储接口
+type OutboxStore interface {
+	GetUnsentOutbox(ctx context.Context, limit int) ([]models.OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, ids []int64) error
+}
+
+// OutboxRelay 事务性发件箱转发器
+// 定期读取未发送的发件箱消息并发送到Kafka，publisher确认整批投递成功（SendMarketData返回nil）后才标记为已发送；
+// 部分消息投递失败或状态未知时整批保留，下次重新发送；发送成功但标记失败时消息同样会被再次发送，因此投递语义为至少一次
+type OutboxRelay struct {
+	store     OutboxStore
+	publisher MarketDataPublisher
+	interval  time.Duration
+	batchSize int
+}
+
+// NewOutboxRelay 创建发件箱转发器
+func NewOutboxRelay(store OutboxStore, publisher MarketDataPublisher, interval time.Duration, batchSize int) *OutboxRelay {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run 定期转发发件箱消息，直到ctx被取消
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	logrus.Infof("Starting outbox relay with interval %v", r.interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 积压时连续转发，直到取到的消息不足一批
+			for ctx.Err() == nil {
+				n, err := r.RelayOnce(ctx)
+				if err != nil {
+					logrus.Errorf("Failed to relay outbox messages: %v", err)
+					break
+				}
+				if n < r.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RelayOnce 转发一批未发送的消息，返回本批处理的消息数
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	messages, err := r.store.GetUnsentOutbox(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, 0, len(messages))
+	var data []models.MarketData
+	for _, m := range messages {
+		ids = append(ids, m.ID)
+		switch m.EventType {
+		case models.OutboxEventMarketData:
+			var d models.MarketData
+			if err := json.Unmarshal(m.Payload, &d); err != nil {
+				// 无法解析的消息重试也不会成功，记录后跳过，避免阻塞后续消息
+				logrus.Errorf("Skipping outbox message %d: invalid market data payload: %v", m.ID, err)
+				continue
+			}
+			data = append(data, d)
+		default:
+			logrus.Errorf("Skipping outbox message %d: unknown event type %q", m.ID, m.EventType)
+		}
+	}
+
+	if len(data) > 0 {
+		if r.publisher == nil {
+			return 0, fmt.Errorf("no publisher configured, %d outbox messages left unsent", len(data))
+		}
+		if err := r.publisher.SendMarketData(data); err != nil {
+			return 0, fmt.Errorf("failed to publish outbox messages: %w", err)
+		}
+	}
+
+	if err := r.store.MarkOutboxSent(ctx, ids); err != nil {
+		return 0, err
+	}
+	logrus.Debugf("Relayed %d outbox messages", len(messages))
+	return len(messages), nil
+}
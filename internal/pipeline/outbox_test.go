@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"quant-data-engine/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOutbox 内存发件箱
+type memoryOutbox struct {
+	messages []models.OutboxMessage
+	sent     map[int64]bool
+}
+
+func (o *memoryOutbox) add(t *testing.T, eventType string, payload interface{}) {
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+	o.messages = append(o.messages, models.OutboxMessage{ID: int64(len(o.messages) + 1), EventType: eventType, Payload: body})
+}
+
+func (o *memoryOutbox) GetUnsentOutbox(ctx context.Context, limit int) ([]models.OutboxMessage, error) {
+	var unsent []models.OutboxMessage
+	for _, m := range o.messages {
+		if !o.sent[m.ID] && len(unsent) < limit {
+			unsent = append(unsent, m)
+		}
+	}
+	return unsent, nil
+}
+
+func (o *memoryOutbox) MarkOutboxSent(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		o.sent[id] = true
+	}
+	return nil
+}
+
+// failingPublisher 发送总是失败
+type failingPublisher struct{}
+
+func (failingPublisher) SendMarketData(data []models.MarketData) error {
+	return errors.New("kafka unavailable")
+}
+
+// TestOutboxRelay_MarksSent 测试转发器发送成功后标记消息已发送，发送失败时保留消息
+func TestOutboxRelay_MarksSent(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	outbox := &memoryOutbox{sent: make(map[int64]bool)}
+	outbox.add(t, models.OutboxEventMarketData, models.MarketData{ID: "a", Symbol: "BTCUSDT", Price: 1, Timestamp: ts, Source: models.SourceBinance})
+	outbox.add(t, models.OutboxEventMarketData, models.MarketData{ID: "b", Symbol: "ETHUSDT", Price: 2, Timestamp: ts, Source: models.SourceOKX})
+	outbox.add(t, "unknown", map[string]string{})
+	outbox.add(t, models.OutboxEventMarketData, models.MarketData{ID: "c", Symbol: "BNBUSDT", Price: 3, Timestamp: ts, Source: models.SourceBinance})
+
+	// 发送失败时不标记
+	n, err := NewOutboxRelay(outbox, failingPublisher{}, time.Second, 10).RelayOnce(context.Background())
+	assert.Error(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, outbox.sent)
+
+	// 没有可用的发送方时同样不标记
+	n, err = NewOutboxRelay(outbox, nil, time.Second, 10).RelayOnce(context.Background())
+	assert.Error(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, outbox.sent)
+
+	// 按批转发，未知类型的消息跳过但同样标记，避免阻塞
+	publisher := &mockPublisher{}
+	relay := NewOutboxRelay(outbox, publisher, time.Second, 3)
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	require.Len(t, publisher.sent, 2)
+	assert.Equal(t, "a", publisher.sent[0].ID)
+	assert.Equal(t, ts, publisher.sent[1].Timestamp)
+	assert.Equal(t, map[int64]bool{1: true, 2: true, 3: true}, outbox.sent)
+
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, publisher.sent, 3)
+
+	// 没有未发送的消息
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, publisher.sent, 3)
+}
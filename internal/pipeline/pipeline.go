@@ -0,0 +1,419 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"quant-data-engine/internal/storage"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MarketDataStore 数据处理流水线依赖的存储接口
+type MarketDataStore interface {
+	SaveMarketData(data []models.MarketData) error
+}
+
+// MarketDataPublisher 数据处理流水线依赖的消息发送接口
+type MarketDataPublisher interface {
+	SendMarketData(data []models.MarketData) error
+}
+
+// Pipeline 市场数据处理流水线：定时从各数据源拉取数据，保存到数据库并发送到Kafka
+type Pipeline struct {
+	factory  *datasource.DataSourceFactory
+	storage  MarketDataStore
+	producer MarketDataPublisher
+	symbols  []string
+	sources  []string
+	shedder  *LoadShedder
+	// interval 基础处理间隔（纳秒），可在运行时通过SetInterval修改
+	interval atomic.Int64
+	// intervalChanged SetInterval通知Run按新间隔重新计时
+	intervalChanged chan struct{}
+	// validate 保存前逐条校验数据，非法数据写入rejects，不影响同批的合法数据
+	validate func(models.MarketData) error
+	rejects  RejectSink
+	// symbolStats 每个交易对最近一次成功时间和最近一次错误
+	symbolStats *symbolTracker
+	// notifier 持续失败、处理降速和写入死信时发送通知；failureThreshold为触发通知的连续失败次数
+	notifier         Notifier
+	failureThreshold int
+
+	// saveRetries 保存遇到临时性数据库错误（storage.ErrDBTransient）时的重试次数，第n次重试前等待n*saveRetryDelay
+	saveRetries    int
+	saveRetryDelay time.Duration
+
+	// lastSuccess 最近一次成功处理周期的完成时间（UnixNano），供SLA看门狗读取
+	lastSuccess atomic.Int64
+	// pausedAt 暂停的时间（UnixNano），0表示未暂停；暂停期间Run跳过处理周期
+	pausedAt atomic.Int64
+
+	// symbolMap 获取数据前将统一交易对名称转换为各数据源的名称，为nil时不转换
+	symbolMap *SymbolMap
+	// unsupported 已记录过警告的数据源不支持的交易对，键为"数据源/交易对"
+	unsupported sync.Map
+	// buffer 写缓冲，为nil时每批数据直接保存
+	buffer *WriteBuffer
+}
+
+// 保存遇到临时性数据库错误时的默认重试次数和重试间隔
+const (
+	DefaultSaveRetries    = 2
+	DefaultSaveRetryDelay = 200 * time.Millisecond
+)
+
+// Option 数据处理流水线可选配置
+type Option func(*Pipeline)
+
+// WithRejectSink 设置非法数据的死信输出
+func WithRejectSink(sink RejectSink) Option {
+	return func(p *Pipeline) {
+		p.rejects = sink
+	}
+}
+
+// WithSymbolMap 设置交易对映射，获取数据时使用数据源的交易对名称，保存的数据仍使用统一名称
+func WithSymbolMap(symbolMap *SymbolMap) Option {
+	return func(p *Pipeline) {
+		p.symbolMap = symbolMap
+	}
+}
+
+// WithWriteBuffer 启用写缓冲：获取的数据先放入缓冲区，缓冲区刷新时通过流水线的存储保存（临时性错误同样重试），
+// 落库后才记录成功、观测保存耗时并发送到Kafka；buffer原有的存储和刷新结果接收方会被替换
+func WithWriteBuffer(buffer *WriteBuffer) Option {
+	return func(p *Pipeline) {
+		p.buffer = buffer
+		buffer.attach(marketDataStoreFunc(p.saveMarketData), p.flushed)
+	}
+}
+
+// marketDataStoreFunc 将保存函数适配为MarketDataStore
+type marketDataStoreFunc func(data []models.MarketData) error
+
+// SaveMarketData 实现MarketDataStore
+func (f marketDataStoreFunc) SaveMarketData(data []models.MarketData) error {
+	return f(data)
+}
+
+// DefaultFailureThreshold 交易对连续失败多少次后发送通知
+const DefaultFailureThreshold = 3
+
+// WithNotifier 设置错误通知渠道，交易对连续失败failureThreshold次时通知一次，failureThreshold<1时使用默认值
+func WithNotifier(notifier Notifier, failureThreshold int) Option {
+	return func(p *Pipeline) {
+		if failureThreshold < 1 {
+			failureThreshold = DefaultFailureThreshold
+		}
+		p.notifier = notifier
+		p.failureThreshold = failureThreshold
+	}
+}
+
+// NewPipeline 创建数据处理流水线，producer为nil时只保存不发送（启用事务性发件箱时）
+func NewPipeline(factory *datasource.DataSourceFactory, store MarketDataStore, producer MarketDataPublisher, symbols []string, interval time.Duration, shedder *LoadShedder, opts ...Option) *Pipeline {
+	p := &Pipeline{
+		factory:  factory,
+		storage:  store,
+		producer: producer,
+		symbols:  symbols,
+		sources:  []string{"binance", "okx"},
+		shedder:  shedder,
+		validate: storage.ValidateMarketData,
+
+		intervalChanged: make(chan struct{}, 1),
+
+		symbolStats:      newSymbolTracker(),
+		notifier:         NopNotifier{},
+		failureThreshold: DefaultFailureThreshold,
+
+		saveRetries:    DefaultSaveRetries,
+		saveRetryDelay: DefaultSaveRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.interval.Store(int64(interval))
+	// 启动时间作为初始值，从未成功时同样会触发SLA告警
+	p.lastSuccess.Store(time.Now().UnixNano())
+	return p
+}
+
+// LastSuccess 返回最近一次成功处理周期（至少保存了一批数据）的完成时间
+// 暂停期间返回当前时间，主动暂停不触发SLA告警
+func (p *Pipeline) LastSuccess() time.Time {
+	if p.Paused() {
+		return time.Now()
+	}
+	return time.Unix(0, p.lastSuccess.Load())
+}
+
+// Pause 暂停数据处理，Run在恢复前跳过每个处理周期，正在进行的周期不受影响；已暂停时返回false
+func (p *Pipeline) Pause() bool {
+	if !p.pausedAt.CompareAndSwap(0, time.Now().UnixNano()) {
+		return false
+	}
+	logrus.Warn("Data processing paused")
+	return true
+}
+
+// Resume 恢复数据处理，从下一个处理周期开始正常处理；未暂停时返回false
+// 恢复时重新开始计算SLA，暂停的时长不计入未成功处理的时间
+func (p *Pipeline) Resume() bool {
+	pausedAt := p.pausedAt.Swap(0)
+	if pausedAt == 0 {
+		return false
+	}
+	p.lastSuccess.Store(time.Now().UnixNano())
+	logrus.Infof("Data processing resumed after %v", time.Since(time.Unix(0, pausedAt)).Round(time.Second))
+	return true
+}
+
+// Paused 判断数据处理是否已暂停
+func (p *Pipeline) Paused() bool {
+	return p.pausedAt.Load() != 0
+}
+
+// Status 返回流水线的运行状态
+func (p *Pipeline) Status() models.PipelineStatus {
+	status := models.PipelineStatus{
+		Interval:    p.EffectiveInterval().String(),
+		LastSuccess: time.Unix(0, p.lastSuccess.Load()),
+		Symbols:     len(p.symbols),
+	}
+	if pausedAt := p.pausedAt.Load(); pausedAt != 0 {
+		at := time.Unix(0, pausedAt)
+		status.Paused = true
+		status.PausedAt = &at
+	}
+	return status
+}
+
+// SymbolStatuses 返回每个交易对的处理状态，用于定位持续失败的交易对
+func (p *Pipeline) SymbolStatuses() []models.SymbolStatus {
+	return p.symbolStats.snapshot(p.symbols)
+}
+
+// SetInterval 修改基础处理间隔，运行中的流水线立即按新间隔重新计时，interval<=0时忽略
+func (p *Pipeline) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if time.Duration(p.interval.Swap(int64(interval))) == interval {
+		return
+	}
+	select {
+	case p.intervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+// EffectiveInterval 返回考虑负载削减后的实际处理间隔
+func (p *Pipeline) EffectiveInterval() time.Duration {
+	interval := time.Duration(p.interval.Load())
+	if p.shedder == nil {
+		return interval
+	}
+	return p.shedder.Interval(interval)
+}
+
+// Run 启动数据处理，直到ctx被取消
+func (p *Pipeline) Run(ctx context.Context) {
+	interval := p.EffectiveInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	logrus.Infof("Starting data processing with interval %v", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Data processing context canceled, exiting...")
+			return
+		case <-timer.C:
+			if p.Paused() {
+				logrus.Info("Data processing is paused, skipping this cycle")
+				timer.Reset(interval)
+				continue
+			}
+			p.ProcessData()
+
+			next := p.EffectiveInterval()
+			if next != interval {
+				logrus.Warnf("Data processing interval changed from %v to %v", interval, next)
+				if next > interval {
+					p.notify(LevelWarning, fmt.Sprintf("Data processing slowed to every %v because database saves are slow", next))
+				}
+				interval = next
+			}
+			timer.Reset(interval)
+		case <-p.intervalChanged:
+			next := p.EffectiveInterval()
+			if next == interval {
+				continue
+			}
+			logrus.Infof("Data processing interval reconfigured from %v to %v", interval, next)
+			interval = next
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// ProcessData 处理数据
+func (p *Pipeline) ProcessData() {
+	logrus.Info("Processing market data...")
+
+	saved := 0
+	supported := p.supportedSymbols()
+	for _, symbol := range p.symbols {
+		// 从各个数据源获取数据
+		for _, sourceName := range p.sources {
+			source := p.factory.GetDataSource(sourceName)
+			if source == nil {
+				logrus.Warnf("DataSource %s not found", sourceName)
+				continue
+			}
+
+			// 获取市场数据，请求使用数据源的交易对名称，返回的数据改回统一名称，并按统一名称重新生成ID
+			exchangeSymbol := p.symbolMap.ToExchange(sourceName, symbol)
+			// 跳过数据源不支持的交易对，不计为失败
+			if set, ok := supported[sourceName]; ok && !set[symbolKey(exchangeSymbol)] {
+				p.warnUnsupported(sourceName, exchangeSymbol)
+				continue
+			}
+			data, err := source.GetMarketData(exchangeSymbol)
+			if err != nil {
+				logrus.Errorf("Failed to get market data from %s for %s: %v", sourceName, exchangeSymbol, err)
+				p.recordFailure(symbol, sourceName, err)
+				continue
+			}
+			if exchangeSymbol != symbol {
+				for i := range data {
+					data[i].Symbol = symbol
+					data[i].ID = models.MarketDataID(symbol, data[i].Source, data[i].Timestamp)
+				}
+			}
+
+			if len(data) == 0 {
+				logrus.Infof("No market data received from %s for %s", sourceName, symbol)
+				continue
+			}
+
+			// 非法数据写入死信输出，只保存合法的数据
+			data, rejected := splitMarketData(data, p.validate, time.Now())
+			if len(rejected) > 0 {
+				p.reject(rejected)
+			}
+			if len(data) == 0 {
+				continue
+			}
+
+			// 启用写缓冲时只放入缓冲区，保存结果在缓冲区刷新时由flushed处理
+			if p.buffer != nil {
+				p.buffer.Add(data)
+				continue
+			}
+
+			// 保存到数据库
+			start := time.Now()
+			err = p.saveMarketData(data)
+			if p.shedder != nil {
+				p.shedder.Observe(time.Since(start))
+			}
+			if err != nil {
+				logrus.Errorf("Failed to save market data to database: %v", err)
+				p.recordFailure(symbol, sourceName, fmt.Errorf("failed to save: %w", err))
+				continue
+			}
+			saved++
+			p.symbolStats.success(symbol, time.Now())
+			p.publish(data)
+		}
+	}
+
+	if saved > 0 {
+		p.lastSuccess.Store(time.Now().UnixNano())
+	}
+
+	logrus.Info("Market data processing completed")
+}
+
+// publish 发送已落库的数据到Kafka，producer为nil时由发件箱转发器负责发送
+func (p *Pipeline) publish(data []models.MarketData) {
+	if p.producer == nil {
+		return
+	}
+	if err := p.producer.SendMarketData(data); err != nil {
+		logrus.Errorf("Failed to send market data to Kafka: %v", err)
+		// 即使Kafka发送失败，也继续处理其他数据
+	}
+}
+
+// flushed 处理写缓冲的刷新结果：观测保存耗时，保存成功时记录各交易对成功并发送到Kafka，失败时记录各交易对失败
+// 在缓冲区刷新的goroutine中调用（处理周期或写缓冲的定时检查）
+func (p *Pipeline) flushed(batch []models.MarketData, elapsed time.Duration, err error) {
+	if p.shedder != nil {
+		p.shedder.Observe(elapsed)
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, d := range batch {
+		key := d.Symbol + "/" + d.Source
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err != nil {
+			p.recordFailure(d.Symbol, d.Source, fmt.Errorf("failed to save: %w", err))
+		} else {
+			p.symbolStats.success(d.Symbol, now)
+		}
+	}
+	if err != nil {
+		logrus.Errorf("Failed to save %d buffered market data records to database: %v", len(batch), err)
+		return
+	}
+
+	p.lastSuccess.Store(now.UnixNano())
+	p.publish(batch)
+}
+
+// saveMarketData 保存市场数据，临时性数据库错误（连接中断、序列化冲突等）时重试，约束冲突等错误直接返回
+func (p *Pipeline) saveMarketData(data []models.MarketData) error {
+	err := p.storage.SaveMarketData(data)
+	for attempt := 1; attempt <= p.saveRetries && errors.Is(err, storage.ErrDBTransient); attempt++ {
+		logrus.Warnf("Transient error saving %d market data records, retrying (%d/%d): %v", len(data), attempt, p.saveRetries, err)
+		time.Sleep(time.Duration(attempt) * p.saveRetryDelay)
+		err = p.storage.SaveMarketData(data)
+	}
+	return err
+}
+
+// recordFailure 记录交易对处理失败，连续失败次数达到阈值时发送一次通知
+func (p *Pipeline) recordFailure(symbol, source string, err error) {
+	if p.symbolStats.failure(symbol, source, err, time.Now()) == p.failureThreshold {
+		p.notify(LevelError, fmt.Sprintf("Symbol %s failed %d consecutive times, last error from %s: %v", symbol, p.failureThreshold, source, err))
+	}
+}
+
+// notify 发送通知，失败时只记录日志
+func (p *Pipeline) notify(level, message string) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := p.notifier.Notify(ctx, level, message); err != nil {
+		logrus.Errorf("Failed to send %s notification: %v", level, err)
+	}
+}
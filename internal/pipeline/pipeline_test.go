@@ -0,0 +1,359 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"quant-data-engine/internal/storage"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockStore 模拟存储，每次保存耗时delay，err不为nil时保存失败
+type mockStore struct {
+	delay time.Duration
+	err   error
+	saved []models.MarketData
+}
+
+func (m *mockStore) SaveMarketData(data []models.MarketData) error {
+	time.Sleep(m.delay)
+	if m.err != nil {
+		return m.err
+	}
+	m.saved = append(m.saved, data...)
+	return nil
+}
+
+// mockPublisher 模拟消息发送
+type mockPublisher struct {
+	sent []models.MarketData
+}
+
+func (m *mockPublisher) SendMarketData(data []models.MarketData) error {
+	m.sent = append(m.sent, data...)
+	return nil
+}
+
+func newTestFactory() *datasource.DataSourceFactory {
+	factory := datasource.NewDataSourceFactory()
+	factory.Register("binance", datasource.NewMockDataSource("binance"))
+	return factory
+}
+
+// TestLoadShedder 测试负载削减倍数的增长和恢复
+func TestLoadShedder(t *testing.T) {
+	shedder := NewLoadShedder(100*time.Millisecond, 2, 4)
+	base := 30 * time.Second
+
+	// 单次慢保存不触发
+	shedder.Observe(200 * time.Millisecond)
+	assert.Equal(t, base, shedder.Interval(base))
+
+	// 连续两次慢保存翻倍
+	shedder.Observe(200 * time.Millisecond)
+	assert.Equal(t, 2*base, shedder.Interval(base))
+
+	// 不超过最大倍数
+	for i := 0; i < 10; i++ {
+		shedder.Observe(time.Second)
+	}
+	assert.Equal(t, 4*base, shedder.Interval(base))
+
+	// 延迟恢复正常后逐步回落
+	shedder.Observe(10 * time.Millisecond)
+	assert.Equal(t, 2*base, shedder.Interval(base))
+	shedder.Observe(10 * time.Millisecond)
+	shedder.Observe(10 * time.Millisecond)
+	assert.Equal(t, base, shedder.Interval(base))
+
+	// 阈值为0时不生效
+	disabled := NewLoadShedder(0, 1, 8)
+	disabled.Observe(time.Hour)
+	assert.Equal(t, base, disabled.Interval(base))
+}
+
+// TestPipeline_SlowSavesIncreaseInterval 测试数据库保存变慢时流水线处理间隔增大
+func TestPipeline_SlowSavesIncreaseInterval(t *testing.T) {
+	store := &mockStore{delay: 5 * time.Millisecond}
+	publisher := &mockPublisher{}
+	base := time.Second
+	p := NewPipeline(newTestFactory(), store, publisher, []string{"BTCUSDT", "ETHUSDT"}, base,
+		NewLoadShedder(time.Millisecond, 2, 8))
+
+	assert.Equal(t, base, p.EffectiveInterval())
+
+	// 每个周期两次慢保存，间隔翻倍
+	p.ProcessData()
+	assert.Equal(t, 2*base, p.EffectiveInterval())
+	p.ProcessData()
+	assert.Equal(t, 4*base, p.EffectiveInterval())
+	assert.Len(t, store.saved, 4)
+	assert.Len(t, publisher.sent, 4)
+
+	// 保存恢复正常后间隔回落
+	store.delay = 0
+	p.shedder.threshold = time.Hour
+	p.ProcessData()
+	assert.Equal(t, base, p.EffectiveInterval())
+}
+
+// TestPipeline_NilProducer 测试未配置producer时流水线只保存不发送
+func TestPipeline_NilProducer(t *testing.T) {
+	store := &mockStore{}
+	p := NewPipeline(newTestFactory(), store, nil, []string{"BTCUSDT"}, time.Second, nil)
+	p.ProcessData()
+	assert.Len(t, store.saved, 1)
+}
+
+// countingStore 记录保存次数的并发安全存储
+type countingStore struct {
+	saves atomic.Int64
+}
+
+func (c *countingStore) SaveMarketData(data []models.MarketData) error {
+	c.saves.Add(1)
+	return nil
+}
+
+// TestPipeline_SetInterval 测试运行中修改处理间隔后立即按新间隔处理
+func TestPipeline_SetInterval(t *testing.T) {
+	store := &countingStore{}
+	p := NewPipeline(newTestFactory(), store, nil, []string{"BTCUSDT"}, time.Hour, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	p.SetInterval(10 * time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, p.EffectiveInterval())
+	assert.Eventually(t, func() bool { return store.saves.Load() >= 2 }, 2*time.Second, 5*time.Millisecond)
+
+	// 非法间隔被忽略
+	p.SetInterval(0)
+	assert.Equal(t, 10*time.Millisecond, p.EffectiveInterval())
+}
+
+// TestPipeline_PauseResume 测试暂停期间处理周期被跳过，恢复后继续处理
+func TestPipeline_PauseResume(t *testing.T) {
+	store := &countingStore{}
+	p := NewPipeline(newTestFactory(), store, nil, []string{"BTCUSDT"}, 10*time.Millisecond, nil)
+	p.lastSuccess.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	assert.True(t, p.Pause())
+	assert.False(t, p.Pause())
+	status := p.Status()
+	assert.True(t, status.Paused)
+	require.NotNil(t, status.PausedAt)
+	// 暂停期间不触发SLA告警
+	assert.WithinDuration(t, time.Now(), p.LastSuccess(), time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// 暂停期间经过多个处理周期也不获取和保存数据
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int64(0), store.saves.Load())
+
+	assert.True(t, p.Resume())
+	assert.False(t, p.Resume())
+	assert.False(t, p.Status().Paused)
+	assert.Nil(t, p.Status().PausedAt)
+	assert.Eventually(t, func() bool { return store.saves.Load() >= 2 }, 2*time.Second, 5*time.Millisecond)
+
+	// 再次暂停后停止处理
+	p.Pause()
+	time.Sleep(30 * time.Millisecond)
+	saves := store.saves.Load()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, saves, store.saves.Load())
+}
+
+// failingSource 模拟数据源，对指定交易对返回错误
+type failingSource struct {
+	*datasource.MockDataSource
+	failSymbol string
+}
+
+func (f *failingSource) GetMarketData(symbol string) ([]models.MarketData, error) {
+	if symbol == f.failSymbol {
+		return nil, errors.New("symbol delisted")
+	}
+	return f.MockDataSource.GetMarketData(symbol)
+}
+
+// SupportedSymbols 失败的交易对仍在支持列表中，获取数据时才返回错误
+func (f *failingSource) SupportedSymbols(ctx context.Context) ([]string, error) {
+	symbols, err := f.MockDataSource.SupportedSymbols(ctx)
+	return append(symbols, f.failSymbol), err
+}
+
+// TestPipeline_SymbolStatuses 测试失败的交易对记录最近错误，其他交易对记录成功时间
+func TestPipeline_SymbolStatuses(t *testing.T) {
+	factory := datasource.NewDataSourceFactory()
+	factory.Register("binance", &failingSource{
+		MockDataSource: datasource.NewMockDataSource("binance"),
+		failSymbol:     "LUNAUSDT",
+	})
+	p := NewPipeline(factory, &mockStore{}, nil, []string{"BTCUSDT", "LUNAUSDT", "ETHUSDT"}, time.Second, nil)
+
+	// 处理前只有交易对名称
+	statuses := p.SymbolStatuses()
+	require.Len(t, statuses, 3)
+	assert.Nil(t, statuses[1].LastSuccess)
+	assert.False(t, statuses[1].Failing)
+
+	p.ProcessData()
+	p.ProcessData()
+
+	statuses = p.SymbolStatuses()
+	require.Len(t, statuses, 3)
+	for _, i := range []int{0, 2} {
+		assert.NotNil(t, statuses[i].LastSuccess, statuses[i].Symbol)
+		assert.False(t, statuses[i].Failing, statuses[i].Symbol)
+		assert.Empty(t, statuses[i].LastError, statuses[i].Symbol)
+	}
+
+	failed := statuses[1]
+	assert.Equal(t, "LUNAUSDT", failed.Symbol)
+	assert.True(t, failed.Failing)
+	assert.Nil(t, failed.LastSuccess)
+	assert.Equal(t, "binance: symbol delisted", failed.LastError)
+	assert.NotNil(t, failed.LastErrorAt)
+	assert.Equal(t, 2, failed.ConsecutiveFailures)
+}
+
+// TestPipeline_WriteBuffer 测试启用写缓冲时数据落库后才记录成功并发送，临时性错误时数据保留到下次刷新
+func TestPipeline_WriteBuffer(t *testing.T) {
+	store := &mockStore{err: fmt.Errorf("%w: connection reset", storage.ErrDBTransient)}
+	publisher := &mockPublisher{}
+	buffer := NewWriteBuffer(nil, 100, time.Hour)
+	p := NewPipeline(newTestFactory(), store, publisher, []string{"BTCUSDT", "ETHUSDT"}, time.Second, nil, WithWriteBuffer(buffer))
+	p.saveRetries = 0
+	started := p.Status().LastSuccess
+
+	// 只放入缓冲区，不计为成功也不发送
+	p.ProcessData()
+	assert.Equal(t, 2, buffer.Pending())
+	assert.Empty(t, store.saved)
+	assert.Empty(t, publisher.sent)
+	assert.Equal(t, started, p.Status().LastSuccess)
+	for _, status := range p.SymbolStatuses() {
+		assert.Nil(t, status.LastSuccess, status.Symbol)
+	}
+
+	// 临时性错误时保留数据并记录失败
+	assert.ErrorIs(t, buffer.Flush(), storage.ErrDBTransient)
+	assert.Equal(t, 2, buffer.Pending())
+	assert.Empty(t, publisher.sent)
+	for _, status := range p.SymbolStatuses() {
+		assert.True(t, status.Failing, status.Symbol)
+	}
+
+	// 落库后记录成功并发送
+	store.err = nil
+	require.NoError(t, buffer.Flush())
+	assert.Len(t, store.saved, 2)
+	assert.Equal(t, store.saved, publisher.sent)
+	assert.True(t, p.Status().LastSuccess.After(started))
+	for _, status := range p.SymbolStatuses() {
+		assert.False(t, status.Failing, status.Symbol)
+		assert.NotNil(t, status.LastSuccess, status.Symbol)
+	}
+}
+
+// symbolsErrorSource 获取支持的交易对失败的数据源
+type symbolsErrorSource struct {
+	*recordingSource
+}
+
+func (s *symbolsErrorSource) SupportedSymbols(ctx context.Context) ([]string, error) {
+	return nil, errors.New("exchangeInfo unavailable")
+}
+
+// TestPipeline_SkipsUnsupportedSymbols 测试跳过数据源不支持的交易对且不计为失败，获取支持的交易对失败时照常请求
+func TestPipeline_SkipsUnsupportedSymbols(t *testing.T) {
+	binance := &recordingSource{MockDataSource: datasource.NewMockDataSource("binance")}
+	okx := &symbolsErrorSource{&recordingSource{MockDataSource: datasource.NewMockDataSource("okx")}}
+	factory := datasource.NewDataSourceFactory()
+	factory.Register("binance", binance)
+	factory.Register("okx", okx)
+
+	store := &mockStore{}
+	p := NewPipeline(factory, store, nil, []string{"BTCUSDT", "DOGEUSDT"}, time.Second, nil)
+	p.ProcessData()
+	p.ProcessData()
+
+	assert.Equal(t, []string{"BTCUSDT", "BTCUSDT"}, binance.requested)
+	assert.Equal(t, []string{"BTCUSDT", "DOGEUSDT", "BTCUSDT", "DOGEUSDT"}, okx.requested)
+	assert.Len(t, store.saved, 6)
+
+	for _, status := range p.SymbolStatuses() {
+		assert.False(t, status.Failing, status.Symbol)
+		assert.Empty(t, status.LastError, status.Symbol)
+	}
+}
+
+// flakyStore 前failures次保存返回failErr的存储
+type flakyStore struct {
+	mockStore
+	failErr  error
+	failures int
+	calls    int
+}
+
+func (f *flakyStore) SaveMarketData(data []models.MarketData) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.failErr
+	}
+	return f.mockStore.SaveMarketData(data)
+}
+
+// TestPipeline_SaveRetry 测试临时性数据库错误时重试保存，约束冲突不重试
+func TestPipeline_SaveRetry(t *testing.T) {
+	transient := fmt.Errorf("%w: connection reset", storage.ErrDBTransient)
+	store := &flakyStore{failErr: transient, failures: 2}
+	p := NewPipeline(newTestFactory(), store, nil, []string{"BTCUSDT"}, time.Second, nil)
+	p.saveRetryDelay = 0
+
+	p.ProcessData()
+	assert.Equal(t, 3, store.calls)
+	assert.Len(t, store.saved, 1)
+	assert.Empty(t, p.SymbolStatuses()[0].LastError)
+
+	// 重试次数用完后记录失败
+	store = &flakyStore{failErr: transient, failures: 10}
+	p = NewPipeline(newTestFactory(), store, nil, []string{"BTCUSDT"}, time.Second, nil)
+	p.saveRetryDelay = 0
+	p.ProcessData()
+	assert.Equal(t, 1+DefaultSaveRetries, store.calls)
+	assert.Contains(t, p.SymbolStatuses()[0].LastError, "transient database error")
+
+	// 约束冲突不重试
+	store = &flakyStore{failErr: fmt.Errorf("%w: duplicate key", storage.ErrDBConstraint), failures: 10}
+	p = NewPipeline(newTestFactory(), store, nil, []string{"BTCUSDT"}, time.Second, nil)
+	p.ProcessData()
+	assert.Equal(t, 1, store.calls)
+}
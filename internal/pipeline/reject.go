@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"fmt"
+	"quant-data-engine/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 被拒绝数据的死信输出方式
+const (
+	RejectSinkTable = "table" // 写入rejected_market_data表（默认）
+	RejectSinkKafka = "kafka" // 发送到死信主题
+	RejectSinkNone  = "none"  // 只记录日志
+)
+
+// RejectSink 被拒绝市场数据的死信输出
+type RejectSink interface {
+	Reject(records []models.RejectedMarketData) error
+}
+
+// RejectStore 死信表存储接口
+type RejectStore interface {
+	SaveRejectedMarketData(records []models.RejectedMarketData) error
+}
+
+// TableRejectSink 将被拒绝的数据写入死信表
+type TableRejectSink struct {
+	store RejectStore
+}
+
+// NewTableRejectSink 创建死信表输出
+func NewTableRejectSink(store RejectStore) *TableRejectSink {
+	return &TableRejectSink{store: store}
+}
+
+// Reject 保存被拒绝的数据
+func (s *TableRejectSink) Reject(records []models.RejectedMarketData) error {
+	return s.store.SaveRejectedMarketData(records)
+}
+
+// RejectPublisher 死信主题消息发送接口
+type RejectPublisher interface {
+	SendRejectedMarketData(topic string, records []models.RejectedMarketData) error
+}
+
+// KafkaRejectSink 将被拒绝的数据发送到Kafka死信主题
+type KafkaRejectSink struct {
+	publisher RejectPublisher
+	topic     string
+}
+
+// NewKafkaRejectSink 创建Kafka死信主题输出
+func NewKafkaRejectSink(publisher RejectPublisher, topic string) *KafkaRejectSink {
+	return &KafkaRejectSink{publisher: publisher, topic: topic}
+}
+
+// Reject 发送被拒绝的数据
+func (s *KafkaRejectSink) Reject(records []models.RejectedMarketData) error {
+	return s.publisher.SendRejectedMarketData(s.topic, records)
+}
+
+// splitMarketData 逐条校验市场数据，返回合法的数据和附带校验原因的非法数据
+func splitMarketData(data []models.MarketData, validate func(models.MarketData) error, now time.Time) ([]models.MarketData, []models.RejectedMarketData) {
+	valid := make([]models.MarketData, 0, len(data))
+	var rejected []models.RejectedMarketData
+	for _, d := range data {
+		if err := validate(d); err != nil {
+			rejected = append(rejected, models.RejectedMarketData{Data: d, Reason: err.Error(), RejectedAt: now})
+			continue
+		}
+		valid = append(valid, d)
+	}
+	return valid, rejected
+}
+
+// reject 将非法数据写入死信输出，未配置死信输出或写入失败时只记录日志
+func (p *Pipeline) reject(records []models.RejectedMarketData) {
+	for _, r := range records {
+		logrus.Warnf("Rejected market data %s from %s for %s: %s", r.Data.ID, r.Data.Source, r.Data.Symbol, r.Reason)
+	}
+	if p.rejects == nil {
+		return
+	}
+	if err := p.rejects.Reject(records); err != nil {
+		logrus.Errorf("Failed to write %d rejected market data records to dead-letter sink: %v", len(records), err)
+		return
+	}
+	// 消息不含条数和原因，同一数据源和交易对的重复通知由限流通知渠道合并
+	first := records[0].Data
+	p.notify(LevelWarning, fmt.Sprintf("Invalid market data from %s for %s written to dead-letter sink", first.Source, first.Symbol))
+}
@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"context"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSource 每次返回固定数据的数据源
+type staticSource struct {
+	data []models.MarketData
+}
+
+func (s *staticSource) GetMarketData(symbol string) ([]models.MarketData, error) {
+	return s.data, nil
+}
+
+func (s *staticSource) GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error) {
+	return nil, nil
+}
+
+func (s *staticSource) Name() string {
+	return models.SourceBinance
+}
+
+func (s *staticSource) SupportedSymbols(ctx context.Context) ([]string, error) {
+	return []string{"BTCUSDT"}, nil
+}
+
+// memoryRejectSink 内存死信输出
+type memoryRejectSink struct {
+	rejected []models.RejectedMarketData
+}
+
+func (s *memoryRejectSink) Reject(records []models.RejectedMarketData) error {
+	s.rejected = append(s.rejected, records...)
+	return nil
+}
+
+// TestPipeline_RejectsInvalidRecords 测试同批中的非法数据写入死信输出，合法数据正常保存和发送
+func TestPipeline_RejectsInvalidRecords(t *testing.T) {
+	now := time.Now()
+	factory := datasource.NewDataSourceFactory()
+	factory.Register(models.SourceBinance, &staticSource{data: []models.MarketData{
+		{ID: "good-1", Symbol: "BTCUSDT", Price: 100, Volume: 1, Timestamp: now, Source: models.SourceBinance},
+		{ID: "bad-price", Symbol: "BTCUSDT", Price: -1, Volume: 1, Timestamp: now, Source: models.SourceBinance},
+		{ID: "good-2", Symbol: "BTCUSDT", Price: 101, Volume: 1, Timestamp: now, Source: models.SourceBinance},
+		{ID: "bad-time", Symbol: "BTCUSDT", Price: 100, Volume: 1, Source: models.SourceBinance},
+	}})
+
+	store := &mockStore{}
+	publisher := &mockPublisher{}
+	sink := &memoryRejectSink{}
+	p := NewPipeline(factory, store, publisher, []string{"BTCUSDT"}, time.Second, nil, WithRejectSink(sink))
+	p.ProcessData()
+
+	require.Len(t, store.saved, 2)
+	assert.Equal(t, "good-1", store.saved[0].ID)
+	assert.Equal(t, "good-2", store.saved[1].ID)
+	assert.Len(t, publisher.sent, 2)
+
+	require.Len(t, sink.rejected, 2)
+	assert.Equal(t, "bad-price", sink.rejected[0].Data.ID)
+	assert.Contains(t, sink.rejected[0].Reason, "price")
+	assert.Equal(t, "bad-time", sink.rejected[1].Data.ID)
+	assert.Contains(t, sink.rejected[1].Reason, "timestamp")
+	assert.False(t, sink.rejected[0].RejectedAt.IsZero())
+}
+
+// TestPipeline_AllRecordsRejected 测试整批非法时不调用保存
+func TestPipeline_AllRecordsRejected(t *testing.T) {
+	factory := datasource.NewDataSourceFactory()
+	factory.Register(models.SourceBinance, &staticSource{data: []models.MarketData{
+		{ID: "bad", Symbol: "BTCUSDT", Price: 0, Timestamp: time.Now(), Source: models.SourceBinance},
+	}})
+
+	store := &mockStore{}
+	sink := &memoryRejectSink{}
+	notifier := &recordingNotifier{}
+	p := NewPipeline(factory, store, &mockPublisher{}, []string{"BTCUSDT"}, time.Second, nil,
+		WithRejectSink(sink), WithNotifier(notifier, 0))
+	before := p.LastSuccess()
+	p.ProcessData()
+
+	assert.Empty(t, store.saved)
+	assert.Len(t, sink.rejected, 1)
+	assert.Equal(t, before, p.LastSuccess())
+
+	// 写入死信时发送通知
+	assert.Equal(t, []string{"warning: Invalid market data from binance for BTCUSDT written to dead-letter sink"}, notifier.messages)
+}
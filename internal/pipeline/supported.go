@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// supportedSymbolsTimeout 每个处理周期获取一个数据源支持的交易对的超时时间，结果由数据源缓存，通常不发起请求
+const supportedSymbolsTimeout = 10 * time.Second
+
+// symbolKey 比较交易对时使用的形式：大写并去掉"-"，BTC-USDT与BTCUSDT视为同一交易对
+func symbolKey(symbol string) string {
+	return strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(symbol)), "-", "")
+}
+
+// supportedSymbols 返回各数据源支持的交易对集合
+// 获取失败的数据源不在结果中，该数据源的所有交易对照常请求
+func (p *Pipeline) supportedSymbols() map[string]map[string]bool {
+	supported := make(map[string]map[string]bool, len(p.sources))
+	for _, sourceName := range p.sources {
+		source := p.factory.GetDataSource(sourceName)
+		if source == nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), supportedSymbolsTimeout)
+		symbols, err := source.SupportedSymbols(ctx)
+		cancel()
+		if err != nil {
+			logrus.Warnf("Failed to get supported symbols of %s, requesting all symbols: %v", sourceName, err)
+			continue
+		}
+
+		set := make(map[string]bool, len(symbols))
+		for _, symbol := range symbols {
+			set[symbolKey(symbol)] = true
+		}
+		supported[sourceName] = set
+	}
+	return supported
+}
+
+// warnUnsupported 记录数据源不支持的交易对，每个数据源的每个交易对只记录一次
+func (p *Pipeline) warnUnsupported(source, symbol string) {
+	if _, warned := p.unsupported.LoadOrStore(source+"/"+symbol, struct{}{}); warned {
+		return
+	}
+	logrus.Warnf("DataSource %s does not support %s, skipping", source, symbol)
+}
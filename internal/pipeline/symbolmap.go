@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"quant-data-engine/internal/models"
+	"sort"
+)
+
+// SymbolMapStore 交易对映射依赖的存储接口
+type SymbolMapStore interface {
+	GetSymbolMappings(ctx context.Context) ([]models.SymbolMapping, error)
+}
+
+// SymbolMap 统一交易对名称与各数据源交易所名称之间的双向映射，创建后只读，可并发使用
+// 没有映射的交易对在两个方向上都保持原样
+type SymbolMap struct {
+	toExchange  map[string]map[string]string
+	toCanonical map[string]map[string]string
+}
+
+// NewSymbolMap 由映射列表创建交易对映射，数据源名称会被规范化为小写形式
+func NewSymbolMap(mappings []models.SymbolMapping) *SymbolMap {
+	m := &SymbolMap{
+		toExchange:  make(map[string]map[string]string),
+		toCanonical: make(map[string]map[string]string),
+	}
+	for _, mapping := range mappings {
+		source := models.CanonicalSource(mapping.Source)
+		if m.toExchange[source] == nil {
+			m.toExchange[source] = make(map[string]string)
+			m.toCanonical[source] = make(map[string]string)
+		}
+		m.toExchange[source][mapping.Canonical] = mapping.ExchangeSymbol
+		m.toCanonical[source][mapping.ExchangeSymbol] = mapping.Canonical
+	}
+	return m
+}
+
+// LoadSymbolMap 从存储加载交易对映射
+func LoadSymbolMap(ctx context.Context, store SymbolMapStore) (*SymbolMap, error) {
+	mappings, err := store.GetSymbolMappings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load symbol mappings: %w", err)
+	}
+	return NewSymbolMap(mappings), nil
+}
+
+// ToExchange 返回统一名称在数据源下的交易所名称，m为nil或没有映射时返回canonical
+func (m *SymbolMap) ToExchange(source, canonical string) string {
+	if m == nil {
+		return canonical
+	}
+	if symbol, ok := m.toExchange[models.CanonicalSource(source)][canonical]; ok {
+		return symbol
+	}
+	return canonical
+}
+
+// ToCanonical 返回数据源的交易所名称对应的统一名称，m为nil或没有映射时返回exchangeSymbol
+func (m *SymbolMap) ToCanonical(source, exchangeSymbol string) string {
+	if m == nil {
+		return exchangeSymbol
+	}
+	if symbol, ok := m.toCanonical[models.CanonicalSource(source)][exchangeSymbol]; ok {
+		return symbol
+	}
+	return exchangeSymbol
+}
+
+// ReadSymbolMapFile 读取交易对映射种子文件，格式为 {"数据源": {"统一名称": "交易所名称"}}，
+// 例如 {"okx": {"BTCUSDT": "BTC-USDT"}}；返回按数据源和统一名称排序的映射
+func ReadSymbolMapFile(path string) ([]models.SymbolMapping, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read symbol map file: %w", err)
+	}
+
+	var bySource map[string]map[string]string
+	if err := json.Unmarshal(content, &bySource); err != nil {
+		return nil, fmt.Errorf("failed to parse symbol map file %s: %w", path, err)
+	}
+
+	var mappings []models.SymbolMapping
+	for source, symbols := range bySource {
+		for canonical, exchangeSymbol := range symbols {
+			if canonical == "" || exchangeSymbol == "" {
+				return nil, fmt.Errorf("symbol map file %s: empty symbol for source %s", path, source)
+			}
+			mappings = append(mappings, models.SymbolMapping{
+				Canonical:      canonical,
+				Source:         models.CanonicalSource(source),
+				ExchangeSymbol: exchangeSymbol,
+			})
+		}
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		if mappings[i].Source != mappings[j].Source {
+			return mappings[i].Source < mappings[j].Source
+		}
+		return mappings[i].Canonical < mappings[j].Canonical
+	})
+	return mappings, nil
+}
@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSymbolMap 测试统一名称与交易所名称的双向查找，没有映射时保持原样
+func TestSymbolMap(t *testing.T) {
+	m := NewSymbolMap([]models.SymbolMapping{
+		{Canonical: "BTCUSDT", Source: "OKX", ExchangeSymbol: "BTC-USDT"},
+		{Canonical: "ETHUSDT", Source: "okx", ExchangeSymbol: "ETH-USDT"},
+		{Canonical: "BTCUSDT", Source: "coinbase", ExchangeSymbol: "BTC-USD"},
+	})
+
+	assert.Equal(t, "BTC-USDT", m.ToExchange("okx", "BTCUSDT"))
+	assert.Equal(t, "BTC-USDT", m.ToExchange(" OKX ", "BTCUSDT"))
+	assert.Equal(t, "BTC-USD", m.ToExchange("coinbase", "BTCUSDT"))
+	assert.Equal(t, "BTCUSDT", m.ToCanonical("coinbase", "BTC-USD"))
+	assert.Equal(t, "ETHUSDT", m.ToCanonical("okx", "ETH-USDT"))
+
+	// 没有映射的数据源和交易对
+	assert.Equal(t, "BTCUSDT", m.ToExchange("binance", "BTCUSDT"))
+	assert.Equal(t, "BNBUSDT", m.ToExchange("okx", "BNBUSDT"))
+	assert.Equal(t, "BTC-USDT", m.ToCanonical("binance", "BTC-USDT"))
+
+	// 未配置映射
+	var none *SymbolMap
+	assert.Equal(t, "BTCUSDT", none.ToExchange("okx", "BTCUSDT"))
+	assert.Equal(t, "BTC-USDT", none.ToCanonical("okx", "BTC-USDT"))
+}
+
+// mappingStore 返回固定映射的存储
+type mappingStore struct {
+	mappings []models.SymbolMapping
+	err      error
+}
+
+func (m *mappingStore) GetSymbolMappings(ctx context.Context) ([]models.SymbolMapping, error) {
+	return m.mappings, m.err
+}
+
+// TestLoadSymbolMap 测试从存储加载映射和存储错误
+func TestLoadSymbolMap(t *testing.T) {
+	m, err := LoadSymbolMap(context.Background(), &mappingStore{mappings: []models.SymbolMapping{
+		{Canonical: "BTCUSDT", Source: "okx", ExchangeSymbol: "BTC-USDT"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "BTC-USDT", m.ToExchange("okx", "BTCUSDT"))
+
+	_, err = LoadSymbolMap(context.Background(), &mappingStore{err: errors.New("database down")})
+	assert.ErrorContains(t, err, "database down")
+}
+
+// TestReadSymbolMapFile 测试读取种子文件并按数据源和统一名称排序
+func TestReadSymbolMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "symbols.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"OKX": {"ETHUSDT": "ETH-USDT", "BTCUSDT": "BTC-USDT"},
+		"coinbase": {"BTCUSDT": "BTC-USD"}
+	}`), 0o600))
+
+	mappings, err := ReadSymbolMapFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []models.SymbolMapping{
+		{Canonical: "BTCUSDT", Source: "coinbase", ExchangeSymbol: "BTC-USD"},
+		{Canonical: "BTCUSDT", Source: "okx", ExchangeSymbol: "BTC-USDT"},
+		{Canonical: "ETHUSDT", Source: "okx", ExchangeSymbol: "ETH-USDT"},
+	}, mappings)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"okx": {"BTCUSDT": ""}}`), 0o600))
+	_, err = ReadSymbolMapFile(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`not json`), 0o600))
+	_, err = ReadSymbolMapFile(path)
+	assert.Error(t, err)
+
+	_, err = ReadSymbolMapFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+// recordingSource 记录请求的交易对名称，并像真实交易所一样在返回数据中使用该名称
+type recordingSource struct {
+	*datasource.MockDataSource
+	requested []string
+}
+
+func (r *recordingSource) GetMarketData(symbol string) ([]models.MarketData, error) {
+	r.requested = append(r.requested, symbol)
+	return r.MockDataSource.GetMarketData(symbol)
+}
+
+// TestPipeline_SymbolMap 测试获取数据时使用数据源的交易对名称，保存的数据使用统一名称，ID也由统一名称生成
+func TestPipeline_SymbolMap(t *testing.T) {
+	binance := &recordingSource{MockDataSource: datasource.NewMockDataSource("binance")}
+	okx := &recordingSource{MockDataSource: datasource.NewMockDataSource("okx")}
+	factory := datasource.NewDataSourceFactory()
+	factory.Register("binance", binance)
+	factory.Register("okx", okx)
+
+	store := &mockStore{}
+	symbolMap := NewSymbolMap([]models.SymbolMapping{{Canonical: "BTCUSDT", Source: "okx", ExchangeSymbol: "BTC-USDT"}})
+	p := NewPipeline(factory, store, nil, []string{"BTCUSDT", "ETHUSDT"}, time.Second, nil, WithSymbolMap(symbolMap))
+	p.ProcessData()
+
+	assert.Equal(t, []string{"BTCUSDT", "ETHUSDT"}, binance.requested)
+	assert.Equal(t, []string{"BTC-USDT", "ETHUSDT"}, okx.requested)
+
+	require.Len(t, store.saved, 4)
+	for _, d := range store.saved {
+		assert.Contains(t, []string{"BTCUSDT", "ETHUSDT"}, d.Symbol, "source %s", d.Source)
+		assert.Equal(t, models.MarketDataID(d.Symbol, d.Source, d.Timestamp), d.ID, "source %s symbol %s", d.Source, d.Symbol)
+	}
+}
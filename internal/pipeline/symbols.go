@@ -0,0 +1,65 @@
+package pipeline
+
+import (
+	"fmt"
+	"quant-data-engine/internal/models"
+	"sync"
+	"time"
+)
+
+// symbolTracker 记录每个交易对最近一次成功时间和最近一次错误
+type symbolTracker struct {
+	mutex    sync.Mutex
+	statuses map[string]*models.SymbolStatus
+}
+
+func newSymbolTracker() *symbolTracker {
+	return &symbolTracker{statuses: make(map[string]*models.SymbolStatus)}
+}
+
+// status 返回交易对的状态记录，调用方需持有锁
+func (t *symbolTracker) status(symbol string) *models.SymbolStatus {
+	status, ok := t.statuses[symbol]
+	if !ok {
+		status = &models.SymbolStatus{Symbol: symbol}
+		t.statuses[symbol] = status
+	}
+	return status
+}
+
+// success 记录交易对从某个数据源成功保存了数据
+func (t *symbolTracker) success(symbol string, at time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	status := t.status(symbol)
+	status.LastSuccess = &at
+	status.ConsecutiveFailures = 0
+	status.Failing = false
+}
+
+// failure 记录交易对从某个数据源获取或保存失败，返回连续失败次数
+func (t *symbolTracker) failure(symbol, source string, err error, at time.Time) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	status := t.status(symbol)
+	status.LastError = fmt.Sprintf("%s: %v", source, err)
+	status.LastErrorAt = &at
+	status.ConsecutiveFailures++
+	status.Failing = true
+	return status.ConsecutiveFailures
+}
+
+// snapshot 按symbols的顺序返回状态副本，尚未处理过的交易对只包含名称
+func (t *symbolTracker) snapshot(symbols []string) []models.SymbolStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	statuses := make([]models.SymbolStatus, 0, len(symbols))
+	for _, symbol := range symbols {
+		if status, ok := t.statuses[symbol]; ok {
+			statuses = append(statuses, *status)
+			continue
+		}
+		statuses = append(statuses, models.SymbolStatus{Symbol: symbol})
+	}
+	return statuses
+}
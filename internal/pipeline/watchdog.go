@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"quant-data-engine/internal/models"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AlertHook SLA告警回调
+type AlertHook interface {
+	Alert(ctx context.Context, alert models.SLAAlert) error
+}
+
+// Watchdog 数据处理SLA看门狗
+// 定期检查最近一次成功处理周期距今的时间，超过threshold时调用告警回调；
+// 同一次停滞只告警一次，处理恢复后再次停滞才会重新告警
+type Watchdog struct {
+	lastSuccess func() time.Time
+	threshold   time.Duration
+	hooks       []AlertHook
+	now         func() time.Time
+
+	mutex      sync.Mutex
+	alertedFor time.Time
+}
+
+// NewWatchdog 创建SLA看门狗，lastSuccess返回最近一次成功处理的时间
+func NewWatchdog(lastSuccess func() time.Time, threshold time.Duration, hooks ...AlertHook) *Watchdog {
+	return &Watchdog{
+		lastSuccess: lastSuccess,
+		threshold:   threshold,
+		hooks:       hooks,
+		now:         time.Now,
+	}
+}
+
+// Run 定期检查SLA，直到ctx被取消
+func (w *Watchdog) Run(ctx context.Context) {
+	// 检查间隔取阈值的1/4，保证告警延迟不超过阈值的25%
+	interval := w.threshold / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logrus.Infof("Starting SLA watchdog with threshold %v", w.threshold)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
+
+// Check 检查一次SLA，超过阈值且本次停滞尚未告警时触发告警，返回是否触发了告警
+func (w *Watchdog) Check(ctx context.Context) bool {
+	lastSuccess := w.lastSuccess()
+	now := w.now()
+	gap := now.Sub(lastSuccess)
+	if gap <= w.threshold {
+		return false
+	}
+
+	w.mutex.Lock()
+	if w.alertedFor.Equal(lastSuccess) {
+		w.mutex.Unlock()
+		return false
+	}
+	w.alertedFor = lastSuccess
+	w.mutex.Unlock()
+
+	alert := models.SLAAlert{
+		Type:             "ingestion_sla",
+		LastSuccess:      lastSuccess,
+		GapSeconds:       gap.Seconds(),
+		ThresholdSeconds: w.threshold.Seconds(),
+		Message:          fmt.Sprintf("no successful data processing cycle for %v (threshold %v)", gap.Round(time.Second), w.threshold),
+		Timestamp:        now,
+	}
+	logrus.Warnf("SLA breached: %s", alert.Message)
+
+	for _, hook := range w.hooks {
+		if err := hook.Alert(ctx, alert); err != nil {
+			logrus.Errorf("Failed to send SLA alert: %v", err)
+		}
+	}
+	return true
+}
+
+// WebhookHook 将告警以JSON POST到指定URL
+type WebhookHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookHook 创建Webhook告警回调
+func NewWebhookHook(url string) *WebhookHook {
+	return &WebhookHook{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Alert 发送告警
+func (h *WebhookHook) Alert(ctx context.Context, alert models.SLAAlert) error {
+	if err := postJSON(ctx, h.client, h.url, alert); err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	return nil
+}
+
+// AlertPublisher 告警消息发送接口
+type AlertPublisher interface {
+	SendAlert(topic string, alert models.SLAAlert) error
+}
+
+// KafkaAlertHook 将告警发送到Kafka告警主题
+type KafkaAlertHook struct {
+	publisher AlertPublisher
+	topic     string
+}
+
+// NewKafkaAlertHook 创建Kafka告警回调
+func NewKafkaAlertHook(publisher AlertPublisher, topic string) *KafkaAlertHook {
+	return &KafkaAlertHook{publisher: publisher, topic: topic}
+}
+
+// Alert 发送告警
+func (h *KafkaAlertHook) Alert(ctx context.Context, alert models.SLAAlert) error {
+	return h.publisher.SendAlert(h.topic, alert)
+}
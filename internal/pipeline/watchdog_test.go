@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"quant-data-engine/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHook 记录收到的告警
+type recordingHook struct {
+	mutex  sync.Mutex
+	alerts []models.SLAAlert
+}
+
+func (h *recordingHook) Alert(ctx context.Context, alert models.SLAAlert) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.alerts = append(h.alerts, alert)
+	return nil
+}
+
+func (h *recordingHook) count() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.alerts)
+}
+
+// TestWatchdog_StalledPipeline 测试流水线停滞时告警只触发一次，恢复后再次停滞重新告警
+func TestWatchdog_StalledPipeline(t *testing.T) {
+	store := &mockStore{err: errors.New("database unavailable")}
+	p := NewPipeline(newTestFactory(), store, &mockPublisher{}, []string{"BTCUSDT"}, time.Second, nil)
+
+	hook := &recordingHook{}
+	w := NewWatchdog(p.LastSuccess, time.Minute, hook)
+	now := p.LastSuccess()
+	w.now = func() time.Time { return now }
+
+	// 阈值内不告警
+	now = now.Add(30 * time.Second)
+	p.ProcessData()
+	assert.False(t, w.Check(context.Background()))
+
+	// 保存持续失败，超过阈值后只告警一次
+	now = now.Add(time.Minute)
+	p.ProcessData()
+	assert.True(t, w.Check(context.Background()))
+	now = now.Add(time.Minute)
+	assert.False(t, w.Check(context.Background()))
+	assert.False(t, w.Check(context.Background()))
+	require.Equal(t, 1, hook.count())
+	assert.Equal(t, "ingestion_sla", hook.alerts[0].Type)
+	assert.Equal(t, 60.0, hook.alerts[0].ThresholdSeconds)
+	assert.Greater(t, hook.alerts[0].GapSeconds, 60.0)
+
+	// 恢复后不告警
+	store.err = nil
+	p.ProcessData()
+	now = p.LastSuccess()
+	assert.False(t, w.Check(context.Background()))
+
+	// 再次停滞时重新告警
+	now = now.Add(2 * time.Minute)
+	assert.True(t, w.Check(context.Background()))
+	assert.Equal(t, 2, hook.count())
+}
+
+// TestWebhookHook 测试Webhook告警发送JSON
+func TestWebhookHook(t *testing.T) {
+	var received models.SLAAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	hook := NewWebhookHook(server.URL)
+	err := hook.Alert(context.Background(), models.SLAAlert{Type: "ingestion_sla", GapSeconds: 120})
+	require.NoError(t, err)
+	assert.Equal(t, "ingestion_sla", received.Type)
+	assert.Equal(t, 120.0, received.GapSeconds)
+
+	// 非2xx响应返回错误
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	assert.Error(t, NewWebhookHook(failing.URL).Alert(context.Background(), models.SLAAlert{}))
+}
@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultDailyChunkSize 日线任务每次批量写入的默认条数
+const DefaultDailyChunkSize = 5000
+
+// dailyFields 日线任务向Tushare请求的字段
+var dailyFields = []string{
+	"ts_code", "trade_date", "open", "high", "low", "close", "pre_close", "change", "pct_chg", "vol", "amount",
+}
+
+// DailyStore 日线任务依赖的存储接口
+type DailyStore interface {
+	GetAllStockCodes() ([]string, error)
+	SaveDaily(data []models.Daily) error
+}
+
+// DailyJobResult 一次日线任务的执行结果
+type DailyJobResult struct {
+	Codes  int // 处理的股票数
+	Failed int // 拉取失败的股票数
+	Bars   int // 保存的日线条数
+	Chunks int // 批量写入次数（每次一个事务）
+}
+
+// DailyJob 日线定时任务：逐只股票拉取日线，先在内存中累积，每满chunkSize条调用一次SaveDaily批量写入
+// 相比每只股票单独写入，全市场一次运行的事务数从股票数降到 总条数/chunkSize
+type DailyJob struct {
+	tushareClient datasource.TushareClientInterface
+	storage       DailyStore
+	chunkSize     int
+}
+
+// NewDailyJob 创建日线定时任务，chunkSize<1时使用DefaultDailyChunkSize
+func NewDailyJob(tushareClient datasource.TushareClientInterface, storage DailyStore, chunkSize int) *DailyJob {
+	if chunkSize < 1 {
+		chunkSize = DefaultDailyChunkSize
+	}
+	return &DailyJob{
+		tushareClient: tushareClient,
+		storage:       storage,
+		chunkSize:     chunkSize,
+	}
+}
+
+// RunToday 拉取并保存当天的日线，用作定时任务
+func (j *DailyJob) RunToday(ctx context.Context) error {
+	tradeDate := time.Now().Format("20060102")
+	if _, err := j.Run(ctx, tradeDate, tradeDate); err != nil {
+		return fmt.Errorf("daily job for %s: %w", tradeDate, err)
+	}
+	return nil
+}
+
+// Run 拉取所有股票[startDate, endDate]内的日线（YYYYMMDD）并分批写入
+// 单只股票拉取失败时跳过并计入Failed；写入失败时停止并返回错误，已写入的批次保留
+func (j *DailyJob) Run(ctx context.Context, startDate, endDate string) (DailyJobResult, error) {
+	var result DailyJobResult
+	codes, err := j.storage.GetAllStockCodes()
+	if err != nil {
+		return result, fmt.Errorf("failed to get stock codes: %w", err)
+	}
+
+	buffer := make([]models.Daily, 0, j.chunkSize)
+	flush := func() error {
+		if len(buffer) == 0 {
+			return nil
+		}
+		if err := j.storage.SaveDaily(buffer); err != nil {
+			return fmt.Errorf("failed to save %d daily records: %w", len(buffer), err)
+		}
+		result.Bars += len(buffer)
+		result.Chunks++
+		buffer = make([]models.Daily, 0, j.chunkSize)
+		return nil
+	}
+
+	for _, code := range codes {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Codes++
+
+		bars, err := j.fetchDaily(ctx, code, startDate, endDate)
+		if err != nil {
+			logrus.Errorf("Failed to fetch daily for %s: %v", code, err)
+			result.Failed++
+			continue
+		}
+		buffer = append(buffer, bars...)
+		if len(buffer) >= j.chunkSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	// 写入最后不满一批的数据
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	logrus.Infof("Daily job saved %d bars for %d stocks in %d chunks (%d failed)",
+		result.Bars, result.Codes, result.Chunks, result.Failed)
+	return result, nil
+}
+
+// fetchDaily 拉取单只股票的日线
+func (j *DailyJob) fetchDaily(ctx context.Context, code, startDate, endDate string) ([]models.Daily, error) {
+	resp, err := j.tushareClient.GetDaily(ctx, &datasource.DailyRequest{
+		TSCode:    code,
+		StartDate: startDate,
+		EndDate:   endDate,
+	}, dailyFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var bars []models.Daily
+	if err := datasource.DecodeItems(resp, &bars); err != nil {
+		return nil, err
+	}
+	return bars, nil
+}
@@ -0,0 +1,37 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RawResponseStore 原始响应清理任务依赖的存储接口
+type RawResponseStore interface {
+	DeleteRawResponsesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RawResponseCleanupJob 原始响应清理任务，删除获取时间超过保留时长的原始响应
+type RawResponseCleanupJob struct {
+	store     RawResponseStore
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewRawResponseCleanupJob 创建原始响应清理任务
+func NewRawResponseCleanupJob(store RawResponseStore, retention time.Duration) *RawResponseCleanupJob {
+	return &RawResponseCleanupJob{store: store, retention: retention, now: time.Now}
+}
+
+// Run 删除获取时间早于 当前时间-retention 的原始响应，用作定时任务
+func (j *RawResponseCleanupJob) Run(ctx context.Context) error {
+	before := j.now().UTC().Add(-j.retention)
+	deleted, err := j.store.DeleteRawResponsesBefore(ctx, before)
+	if err != nil {
+		return fmt.Errorf("raw response cleanup: %w", err)
+	}
+	logrus.Infof("Deleted %d raw responses fetched before %s", deleted, before.Format(time.RFC3339))
+	return nil
+}
@@ -1,50 +1,184 @@
 package schedule
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"quant-data-engine/internal/datasource"
 	"quant-data-engine/internal/models"
-	"quant-data-engine/internal/storage"
+	"sync"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
 
-// Scheduler 定时任务调度器
+// 定时任务数据输出方式
+const (
+	SinkDB    = "db"    // 只保存到数据库（默认）
+	SinkKafka = "kafka" // 只发送到Kafka，不依赖数据库
+	SinkBoth  = "both"  // 保存到数据库并发送到Kafka
+)
+
+// StockBasicStore 定时任务依赖的存储接口
+type StockBasicStore interface {
+	SaveStockBasic(data []models.StockBasic) error
+}
+
+// StockBasicPublisher 定时任务依赖的消息发送接口
+type StockBasicPublisher interface {
+	SendStockBasic(data []models.StockBasic) error
+}
+
+// JobFunc 定时任务，ctx在Stop时取消
+type JobFunc func(ctx context.Context) error
+
+// Scheduler 定时任务调度器，通过AddJob按cron表达式注册任务
 type Scheduler struct {
-	tushareClient *datasource.TushareClient
-	storage       *storage.PostgresStorage
+	tushareClient datasource.TushareClientInterface
+	storage       StockBasicStore
+	producer      StockBasicPublisher
+	sinkMode      string
+
+	cron *cron.Cron
+	// ctx 所有任务运行时使用的上下文，Stop或Start传入的ctx取消时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// jobs 已注册的任务及其最近一次执行情况，按注册顺序
+	mutex sync.Mutex
+	jobs  []*jobState
+}
+
+// jobState 已注册任务的最近一次执行情况
+type jobState struct {
+	name         string
+	spec         string
+	entryID      cron.EntryID
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
 }
 
-// NewScheduler 创建定时任务调度器
-func NewScheduler(tushareClient *datasource.TushareClient, storage *storage.PostgresStorage) *Scheduler {
+// NewScheduler 创建定时任务调度器，sinkMode为kafka时storage可以为nil，为db时producer可以为nil
+func NewScheduler(tushareClient datasource.TushareClientInterface, storage StockBasicStore, producer StockBasicPublisher, sinkMode string) *Scheduler {
+	switch sinkMode {
+	case SinkDB, SinkKafka, SinkBoth:
+	default:
+		logrus.Warnf("Unknown scheduler sink mode %q, using %q", sinkMode, SinkDB)
+		sinkMode = SinkDB
+	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		tushareClient: tushareClient,
 		storage:       storage,
+		producer:      producer,
+		sinkMode:      sinkMode,
+		cron:          cron.New(cron.WithLogger(cron.PrintfLogger(logrus.StandardLogger()))),
+		ctx:           ctx,
+		cancel:        cancel,
 	}
 }
 
-// Start 启动定时任务
-func (s *Scheduler) Start() {
-	// 立即执行一次获取股票列表
-	s.fetchStockList()
+// AddJob 按cron表达式注册定时任务，支持标准5段表达式和 @every 1h、@daily 等描述符
+// 同一任务上一次尚未执行完时跳过本次执行；任务返回的错误只记录日志
+func (s *Scheduler) AddJob(name, spec string, fn JobFunc) error {
+	state := &jobState{name: name, spec: spec}
+	job := cron.NewChain(cron.SkipIfStillRunning(cron.DiscardLogger)).Then(cron.FuncJob(func() {
+		s.runJob(state, fn)
+	}))
+	id, err := s.cron.AddJob(spec, job)
+	if err != nil {
+		return fmt.Errorf("invalid cron spec %q for job %s: %w", spec, name, err)
+	}
+	state.entryID = id
+
+	s.mutex.Lock()
+	s.jobs = append(s.jobs, state)
+	s.mutex.Unlock()
+
+	logrus.Infof("Scheduled job %s registered with cron spec %q", name, spec)
+	return nil
+}
+
+// runJob 执行一次任务并记录执行时间、耗时和错误，调度器已停止时不执行
+func (s *Scheduler) runJob(state *jobState, fn JobFunc) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	start := time.Now()
+	err := fn(s.ctx)
+	duration := time.Since(start)
+
+	s.mutex.Lock()
+	state.lastRun = start
+	state.lastDuration = duration
+	state.lastErr = err
+	s.mutex.Unlock()
 
-	// 每30分钟执行一次
-	ticker := time.NewTicker(30 * time.Minute)
-	go func() {
-		for range ticker.C {
-			s.fetchStockList()
+	if err != nil {
+		logrus.Errorf("Scheduled job %s failed after %v: %v", state.name, duration, err)
+		return
+	}
+	logrus.Debugf("Scheduled job %s finished in %v", state.name, duration)
+}
+
+// JobStatuses 返回已注册任务的最近一次执行情况和下一次计划执行时间，按注册顺序
+func (s *Scheduler) JobStatuses() []models.ScheduledJobStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := make([]models.ScheduledJobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := models.ScheduledJobStatus{Name: job.name, Spec: job.spec}
+		if !job.lastRun.IsZero() {
+			lastRun := job.lastRun
+			status.LastRun = &lastRun
+			status.LastDuration = job.lastDuration.String()
+		}
+		if job.lastErr != nil {
+			status.LastError = job.lastErr.Error()
 		}
-	}()
+		// 调度器启动前没有下一次执行时间
+		if next := s.cron.Entry(job.entryID).Next; !next.IsZero() {
+			status.NextRun = &next
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
 
-	logrus.Info("Scheduler started, fetching stock list every 30 minutes")
+// Start 立即获取一次股票列表，然后按cron表达式执行已注册的任务
+// ctx取消时取消进行中的任务并不再执行新的任务，等待任务返回需要调用Stop
+func (s *Scheduler) Start(ctx context.Context) {
+	context.AfterFunc(ctx, s.cancel)
+
+	if err := s.FetchStockList(s.ctx); err != nil {
+		logrus.Errorf("Initial stock list fetch failed: %v", err)
+	}
+	s.cron.Start()
+
+	logrus.Infof("Scheduler started with %d jobs (sink: %s)", len(s.cron.Entries()), s.sinkMode)
+}
+
+// Stop 停止调度新的任务，取消进行中任务的ctx并等待它们返回
+// ctx到期时不再等待，返回ctx.Err()，任务在后台继续退出
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+	select {
+	case <-s.cron.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// fetchStockList 获取股票列表
-func (s *Scheduler) fetchStockList() {
+// FetchStockList 获取股票列表并按输出方式保存或发送到Kafka，用作定时任务
+func (s *Scheduler) FetchStockList(ctx context.Context) error {
 	logrus.Info("Starting to fetch stock list")
 
 	// 调用 Tushare API 获取股票基础信息
-	resp, err := s.tushareClient.GetStockBasic(&datasource.StockBasicRequest{
+	resp, err := s.tushareClient.GetStockBasic(ctx, &datasource.StockBasicRequest{
 		ListStatus: "L", // 只获取上市的股票
 	}, []string{
 		"ts_code", "symbol", "name", "area", "industry", "fullname", "enname", "cnspell",
@@ -53,105 +187,44 @@ func (s *Scheduler) fetchStockList() {
 	})
 
 	if err != nil {
-		logrus.Errorf("Failed to fetch stock list: %v", err)
-		return
-	}
-
-	// 解析响应数据
-	var stockList []models.StockBasic
-	if resp.Data != nil && len(resp.Data.Items) > 0 {
-		for _, item := range resp.Data.Items {
-			stock := models.StockBasic{}
-
-			// 解析字段
-			for i, field := range resp.Data.Fields {
-				if i < len(item) {
-					switch field {
-					case "ts_code":
-						if v, ok := item[i].(string); ok {
-							stock.TSCode = v
-						}
-					case "symbol":
-						if v, ok := item[i].(string); ok {
-							stock.Symbol = v
-						}
-					case "name":
-						if v, ok := item[i].(string); ok {
-							stock.Name = v
-						}
-					case "area":
-						if v, ok := item[i].(string); ok {
-							stock.Area = v
-						}
-					case "industry":
-						if v, ok := item[i].(string); ok {
-							stock.Industry = v
-						}
-					case "fullname":
-						if v, ok := item[i].(string); ok {
-							stock.Fullname = v
-						}
-					case "enname":
-						if v, ok := item[i].(string); ok {
-							stock.Enname = v
-						}
-					case "cnspell":
-						if v, ok := item[i].(string); ok {
-							stock.Cnspell = v
-						}
-					case "market":
-						if v, ok := item[i].(string); ok {
-							stock.Market = v
-						}
-					case "exchange":
-						if v, ok := item[i].(string); ok {
-							stock.Exchange = v
-						}
-					case "curr_type":
-						if v, ok := item[i].(string); ok {
-							stock.CurrType = v
-						}
-					case "list_status":
-						if v, ok := item[i].(string); ok {
-							stock.ListStatus = v
-						}
-					case "list_date":
-						if v, ok := item[i].(string); ok {
-							stock.ListDate = v
-						}
-					case "delist_date":
-						if v, ok := item[i].(string); ok {
-							stock.DelistDate = v
-						}
-					case "is_hs":
-						if v, ok := item[i].(string); ok {
-							stock.IsHS = v
-						}
-					case "act_name":
-						if v, ok := item[i].(string); ok {
-							stock.ActName = v
-						}
-					case "act_ent_type":
-						if v, ok := item[i].(string); ok {
-							stock.ActEntType = v
-						}
-					}
-				}
-			}
-
-			// 添加到列表
-			stockList = append(stockList, stock)
-		}
+		return fmt.Errorf("failed to fetch stock list: %w", err)
 	}
 
+	stockList := parseStockBasic(resp)
 	logrus.Infof("Fetched %d stocks from Tushare API", len(stockList))
+	if len(stockList) == 0 {
+		return nil
+	}
 
-	// 保存到数据库
-	if len(stockList) > 0 {
+	// 保存到数据库和发送到Kafka互不影响，失败时汇总返回
+	var errs []error
+	if s.sinkMode == SinkDB || s.sinkMode == SinkBoth {
 		if err := s.storage.SaveStockBasic(stockList); err != nil {
-			logrus.Errorf("Failed to save stock list: %v", err)
-			return
+			errs = append(errs, fmt.Errorf("failed to save stock list: %w", err))
+		} else {
+			logrus.Infof("Successfully saved %d stocks to database", len(stockList))
+		}
+	}
+
+	// 发送到Kafka
+	if s.sinkMode == SinkKafka || s.sinkMode == SinkBoth {
+		if err := s.producer.SendStockBasic(stockList); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send stock list to Kafka: %w", err))
+		} else {
+			logrus.Infof("Successfully sent %d stocks to Kafka", len(stockList))
 		}
-		logrus.Infof("Successfully saved %d stocks to database", len(stockList))
 	}
+	return errors.Join(errs...)
+}
+
+// parseStockBasic 解析股票基础信息响应
+func parseStockBasic(resp *datasource.TushareResponse) []models.StockBasic {
+	if resp == nil || resp.Data == nil {
+		return nil
+	}
+	stockList := make([]models.StockBasic, 0, len(resp.Data.Items))
+	for _, item := range resp.Data.Items {
+		stockList = append(stockList, models.StockBasicFromRow(resp.Data.Fields, item))
+	}
+	return stockList
 }
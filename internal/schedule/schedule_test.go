@@ -0,0 +1,328 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"quant-data-engine/internal/datasource"
+	"quant-data-engine/internal/models"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTushareClient 模拟Tushare客户端，返回两只股票
+type mockTushareClient struct{}
+
+func (m *mockTushareClient) GetStockBasic(ctx context.Context, req *datasource.StockBasicRequest, fields []string) (*datasource.TushareResponse, error) {
+	return &datasource.TushareResponse{
+		Data: &datasource.DataResult{
+			Fields: []string{"ts_code", "symbol", "name", "list_status"},
+			Items: [][]interface{}{
+				{"000001.SZ", "000001", "平安银行", "L"},
+				{"600000.SH", "600000", "浦发银行", "L"},
+			},
+		},
+	}, nil
+}
+
+func (m *mockTushareClient) GetTradeCal(ctx context.Context, req *datasource.TradeCalRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetNewShare(ctx context.Context, req *datasource.NewShareRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetStockCompany(ctx context.Context, req *datasource.StockCompanyRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetStkManagers(ctx context.Context, req *datasource.StkManagersRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetStkRewards(ctx context.Context, req *datasource.StkRewardsRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetDaily(ctx context.Context, req *datasource.DailyRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetProBar(ctx context.Context, req *datasource.ProBarRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetAdjFactor(ctx context.Context, req *datasource.AdjFactorRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+func (m *mockTushareClient) GetMoneyflow(ctx context.Context, req *datasource.MoneyflowRequest, fields []string) (*datasource.TushareResponse, error) {
+	return nil, nil
+}
+
+// mockStore 记录保存的股票
+type mockStore struct {
+	saved []models.StockBasic
+}
+
+func (m *mockStore) SaveStockBasic(data []models.StockBasic) error {
+	m.saved = append(m.saved, data...)
+	return nil
+}
+
+// mockPublisher 记录发送的股票
+type mockPublisher struct {
+	sent []models.StockBasic
+}
+
+func (m *mockPublisher) SendStockBasic(data []models.StockBasic) error {
+	m.sent = append(m.sent, data...)
+	return nil
+}
+
+// TestScheduler_SinkMode 测试不同输出方式下调用的数据输出目标
+func TestScheduler_SinkMode(t *testing.T) {
+	tests := []struct {
+		mode      string
+		wantSaved int
+		wantSent  int
+	}{
+		{SinkDB, 2, 0},
+		{SinkKafka, 0, 2},
+		{SinkBoth, 2, 2},
+		{"unknown", 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			store := &mockStore{}
+			publisher := &mockPublisher{}
+			s := NewScheduler(&mockTushareClient{}, store, publisher, tt.mode)
+
+			s.FetchStockList(context.Background())
+			assert.Len(t, store.saved, tt.wantSaved)
+			assert.Len(t, publisher.sent, tt.wantSent)
+		})
+	}
+}
+
+// TestScheduler_FetchStockListSaves 测试通过接口注入的模拟客户端和存储，股票列表解析后完整保存
+func TestScheduler_FetchStockListSaves(t *testing.T) {
+	store := &mockStore{}
+	s := NewScheduler(&mockTushareClient{}, store, nil, SinkDB)
+
+	require.NoError(t, s.FetchStockList(context.Background()))
+	assert.Equal(t, []models.StockBasic{
+		{TSCode: "000001.SZ", Symbol: "000001", Name: "平安银行", ListStatus: "L"},
+		{TSCode: "600000.SH", Symbol: "600000", Name: "浦发银行", ListStatus: "L"},
+	}, store.saved)
+}
+
+// TestScheduler_KafkaModeWithoutStorage 测试kafka模式不依赖数据库
+func TestScheduler_KafkaModeWithoutStorage(t *testing.T) {
+	publisher := &mockPublisher{}
+	s := NewScheduler(&mockTushareClient{}, nil, publisher, SinkKafka)
+
+	assert.NotPanics(t, func() { s.FetchStockList(context.Background()) })
+	if assert.Len(t, publisher.sent, 2) {
+		assert.Equal(t, "000001.SZ", publisher.sent[0].TSCode)
+		assert.Equal(t, "平安银行", publisher.sent[0].Name)
+	}
+}
+
+// dailyTushareClient 每只股票返回一条日线，failCode的请求返回错误
+type dailyTushareClient struct {
+	mockTushareClient
+	failCode string
+}
+
+func (m *dailyTushareClient) GetDaily(ctx context.Context, req *datasource.DailyRequest, fields []string) (*datasource.TushareResponse, error) {
+	if req.TSCode == m.failCode {
+		return nil, fmt.Errorf("tushare error")
+	}
+	return &datasource.TushareResponse{
+		Data: &datasource.DataResult{
+			Fields: []string{"ts_code", "trade_date", "open", "high", "low", "close", "vol"},
+			Items: [][]interface{}{
+				{req.TSCode, req.StartDate, 10.0, 11.0, 9.5, 10.5, 1000.0},
+			},
+		},
+	}, nil
+}
+
+// dailyStore 记录每次SaveDaily的条数
+type dailyStore struct {
+	codes  []string
+	chunks []int
+	saved  []models.Daily
+}
+
+func (m *dailyStore) GetAllStockCodes() ([]string, error) {
+	return m.codes, nil
+}
+
+func (m *dailyStore) SaveDaily(data []models.Daily) error {
+	m.chunks = append(m.chunks, len(data))
+	m.saved = append(m.saved, data...)
+	return nil
+}
+
+// TestDailyJob_BulkUpsert 测试N只股票的日线分批写入，事务数远少于N，最后不满一批的数据也会写入
+func TestDailyJob_BulkUpsert(t *testing.T) {
+	store := &dailyStore{}
+	for i := 0; i < 25; i++ {
+		store.codes = append(store.codes, fmt.Sprintf("%06d.SZ", i))
+	}
+	job := NewDailyJob(&dailyTushareClient{failCode: "000003.SZ"}, store, 10)
+
+	result, err := job.Run(context.Background(), "20240102", "20240102")
+	require.NoError(t, err)
+	assert.Equal(t, DailyJobResult{Codes: 25, Failed: 1, Bars: 24, Chunks: 3}, result)
+	assert.Equal(t, []int{10, 10, 4}, store.chunks)
+	require.Len(t, store.saved, 24)
+	assert.Equal(t, "000000.SZ", store.saved[0].TSCode)
+	assert.Equal(t, "20240102", store.saved[0].TradeDate)
+	assert.Equal(t, 10.5, store.saved[0].Close)
+	assert.Equal(t, "000024.SZ", store.saved[23].TSCode)
+
+	// 默认批量大小下一次写入全部股票
+	store.chunks, store.saved = nil, nil
+	result, err = NewDailyJob(&dailyTushareClient{}, store, 0).Run(context.Background(), "20240102", "20240102")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Chunks)
+	assert.Equal(t, []int{25}, store.chunks)
+}
+
+// TestScheduler_AddJob 测试按cron表达式注册的任务会执行，Stop取消任务的ctx并等待任务返回
+func TestScheduler_AddJob(t *testing.T) {
+	store := &mockStore{}
+	s := NewScheduler(&mockTushareClient{}, store, nil, SinkDB)
+
+	assert.Error(t, s.AddJob("invalid", "every minute", func(ctx context.Context) error { return nil }))
+
+	var runs atomic.Int32
+	var finished atomic.Bool
+	started := make(chan struct{})
+	require.NoError(t, s.AddJob("blocking", "@every 1s", func(ctx context.Context) error {
+		if runs.Add(1) == 1 {
+			close(started)
+		}
+		// 一直运行到Stop取消ctx
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+		return ctx.Err()
+	}))
+
+	s.Start(context.Background())
+	// 启动时立即获取一次股票列表
+	assert.Len(t, store.saved, 2)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected scheduled job to run")
+	}
+
+	require.NoError(t, s.Stop(context.Background()))
+	assert.True(t, finished.Load(), "Stop should wait for the running job")
+	// 上一次执行未结束时跳过后续执行
+	assert.Equal(t, int32(1), runs.Load())
+}
+
+// TestScheduler_Stop 测试Stop后不再执行任务，任务未在超时内返回时Stop返回超时错误
+func TestScheduler_Stop(t *testing.T) {
+	s := NewScheduler(&mockTushareClient{}, &mockStore{}, nil, SinkDB)
+	var runs atomic.Int32
+	require.NoError(t, s.AddJob("counter", "@every 1s", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}))
+	s.Start(context.Background())
+
+	assert.Eventually(t, func() bool { return runs.Load() >= 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, s.Stop(context.Background()))
+	stopped := runs.Load()
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load(), "no job should run after Stop")
+
+	// 任务不响应取消时按超时返回
+	s = NewScheduler(&mockTushareClient{}, &mockStore{}, nil, SinkDB)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	require.NoError(t, s.AddJob("stuck", "@every 1s", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+	s.Start(context.Background())
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected scheduled job to run")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Stop(ctx), context.DeadlineExceeded)
+}
+
+// TestScheduler_JobStatuses 测试记录每个任务最近一次执行的时间和错误，启动后提供下一次执行时间
+func TestScheduler_JobStatuses(t *testing.T) {
+	s := NewScheduler(&mockTushareClient{}, &mockStore{}, nil, SinkDB)
+	ok := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return fmt.Errorf("tushare unavailable") }
+	require.NoError(t, s.AddJob("stock_list", "@daily", ok))
+	require.NoError(t, s.AddJob("daily", "0 18 * * 1-5", failing))
+
+	statuses := s.JobStatuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "stock_list", statuses[0].Name)
+	assert.Equal(t, "@daily", statuses[0].Spec)
+	assert.Nil(t, statuses[0].LastRun)
+	assert.Nil(t, statuses[0].NextRun)
+
+	before := time.Now()
+	s.runJob(s.jobs[0], ok)
+	s.runJob(s.jobs[1], failing)
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	statuses = s.JobStatuses()
+	require.Len(t, statuses, 2)
+	require.NotNil(t, statuses[0].LastRun)
+	assert.False(t, statuses[0].LastRun.Before(before))
+	assert.NotEmpty(t, statuses[0].LastDuration)
+	assert.Empty(t, statuses[0].LastError)
+	assert.Equal(t, "tushare unavailable", statuses[1].LastError)
+	for _, status := range statuses {
+		if assert.NotNil(t, status.NextRun) {
+			assert.True(t, status.NextRun.After(before))
+		}
+	}
+}
+
+// rawResponseStore 记录清理时间点的原始响应存储
+type rawResponseStore struct {
+	before time.Time
+}
+
+func (m *rawResponseStore) DeleteRawResponsesBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.before = before
+	return 3, nil
+}
+
+// TestRawResponseCleanupJob 测试清理任务删除早于保留时长的原始响应
+func TestRawResponseCleanupJob(t *testing.T) {
+	store := &rawResponseStore{}
+	job := NewRawResponseCleanupJob(store, 72*time.Hour)
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	require.NoError(t, job.Run(context.Background()))
+	assert.Equal(t, time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC), store.before)
+}
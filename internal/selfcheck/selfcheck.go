@@ -0,0 +1,157 @@
+// Package selfcheck 启动自检：在引擎启动前检查数据库、Kafka和Tushare配置，汇总所有失败项
+package selfcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"quant-data-engine/internal/datasource"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultCheckTimeout 单个检查项的默认超时时间
+const DefaultCheckTimeout = 5 * time.Second
+
+// Check 一个启动检查项，Run返回nil表示通过
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Failure 未通过的检查项
+type Failure struct {
+	Name string
+	Err  error
+}
+
+// Report 启动自检报告
+type Report struct {
+	Passed   []string
+	Failures []Failure
+}
+
+// OK 所有检查项均通过时返回true
+func (r *Report) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// String 返回多行报告，每个失败项一行
+func (r *Report) String() string {
+	if r.OK() {
+		return fmt.Sprintf("startup self-check passed (%d checks)", len(r.Passed))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "startup self-check failed (%d of %d checks):", len(r.Failures), len(r.Failures)+len(r.Passed))
+	for _, f := range r.Failures {
+		fmt.Fprintf(&b, "\n  - %s: %v", f.Name, f.Err)
+	}
+	return b.String()
+}
+
+// Err 有失败项时返回包含完整报告的错误，否则返回nil
+func (r *Report) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return errors.New(r.String())
+}
+
+// RunSelfCheck 依次执行所有检查项，每项最多等待timeout，一项失败不影响后续检查项，返回汇总报告
+func RunSelfCheck(ctx context.Context, timeout time.Duration, checks ...Check) *Report {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	report := &Report{}
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := check.Run(checkCtx)
+		cancel()
+		if err != nil {
+			report.Failures = append(report.Failures, Failure{Name: check.Name, Err: err})
+			continue
+		}
+		logrus.Debugf("Self-check %s passed", check.Name)
+		report.Passed = append(report.Passed, check.Name)
+	}
+	return report
+}
+
+// DatabaseCheck 检查数据库连通性，ping为实际建立连接的函数（如storage.Ping）
+func DatabaseCheck(host, port, name string, ping func(ctx context.Context) error) Check {
+	return Check{
+		Name: "database",
+		Run: func(ctx context.Context) error {
+			if err := ping(ctx); err != nil {
+				return fmt.Errorf("cannot connect to %s:%s/%s (check DB_HOST, DB_PORT, DB_NAME, DB_USER, DB_PASSWORD): %w", host, port, name, err)
+			}
+			return nil
+		},
+	}
+}
+
+// KafkaCheck 检查逗号分隔的Kafka brokers中至少有一个可以建立TCP连接
+func KafkaCheck(brokers string) Check {
+	return Check{
+		Name: "kafka",
+		Run: func(ctx context.Context) error {
+			var errs []error
+			var dialer net.Dialer
+			for _, broker := range strings.Split(brokers, ",") {
+				broker = strings.TrimSpace(broker)
+				if broker == "" {
+					continue
+				}
+				conn, err := dialer.DialContext(ctx, "tcp", broker)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				conn.Close()
+				return nil
+			}
+			if len(errs) == 0 {
+				return fmt.Errorf("KAFKA_BROKERS is empty")
+			}
+			return fmt.Errorf("no reachable broker in KAFKA_BROKERS %q: %w", brokers, errors.Join(errs...))
+		},
+	}
+}
+
+// TradeCalendarClient Tushare鉴权探测使用的接口
+type TradeCalendarClient interface {
+	GetTradeCal(ctx context.Context, req *datasource.TradeCalRequest, fields []string) (*datasource.TushareResponse, error)
+}
+
+// TushareCheck 检查Tushare token已配置；probe为true时调用一次交易日历接口验证token有效（消耗一次调用额度）
+func TushareCheck(token string, probe bool, client TradeCalendarClient) Check {
+	return Check{
+		Name: "tushare",
+		Run: func(ctx context.Context) error {
+			if token == "" {
+				return fmt.Errorf("TUSHARE_API_TOKEN is empty")
+			}
+			if !probe {
+				return nil
+			}
+			today := time.Now().Format("20060102")
+			done := make(chan error, 1)
+			go func() {
+				_, err := client.GetTradeCal(ctx, &datasource.TradeCalRequest{Exchange: "SSE", StartDate: today, EndDate: today}, []string{"cal_date"})
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if err != nil {
+					return fmt.Errorf("auth probe failed (check TUSHARE_API_TOKEN): %w", err)
+				}
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("auth probe timed out: %w", ctx.Err())
+			}
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package selfcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"quant-data-engine/internal/datasource"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTushare 模拟Tushare客户端，记录调用次数
+type fakeTushare struct {
+	calls int
+	err   error
+}
+
+func (f *fakeTushare) GetTradeCal(ctx context.Context, req *datasource.TradeCalRequest, fields []string) (*datasource.TushareResponse, error) {
+	f.calls++
+	return &datasource.TushareResponse{}, f.err
+}
+
+// closedAddr 返回一个没有监听的本地地址
+func closedAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// TestRunSelfCheck_AggregatesFailures 测试多个依赖失败时汇总到同一份报告
+func TestRunSelfCheck_AggregatesFailures(t *testing.T) {
+	tushare := &fakeTushare{err: errors.New("token invalid")}
+	report := RunSelfCheck(context.Background(), time.Second,
+		DatabaseCheck("db.local", "5432", "wrong_db", func(ctx context.Context) error {
+			return errors.New(`database "wrong_db" does not exist`)
+		}),
+		KafkaCheck(closedAddr(t)),
+		TushareCheck("bad-token", true, tushare),
+	)
+
+	assert.False(t, report.OK())
+	assert.Empty(t, report.Passed)
+	require.Len(t, report.Failures, 3)
+	assert.Equal(t, "database", report.Failures[0].Name)
+	assert.Equal(t, "kafka", report.Failures[1].Name)
+	assert.Equal(t, "tushare", report.Failures[2].Name)
+	assert.Equal(t, 1, tushare.calls)
+
+	err := report.Err()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "3 of 3 checks")
+	assert.Contains(t, err.Error(), `database "wrong_db" does not exist`)
+	assert.Contains(t, err.Error(), "KAFKA_BROKERS")
+	assert.Contains(t, err.Error(), "token invalid")
+}
+
+// TestRunSelfCheck_Passed 测试所有检查项通过
+func TestRunSelfCheck_Passed(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	report := RunSelfCheck(context.Background(), time.Second,
+		DatabaseCheck("localhost", "5432", "quant_data", func(ctx context.Context) error { return nil }),
+		KafkaCheck(closedAddr(t)+", "+ln.Addr().String()),
+		TushareCheck("token", true, &fakeTushare{}),
+	)
+
+	assert.True(t, report.OK())
+	assert.NoError(t, report.Err())
+	assert.Equal(t, []string{"database", "kafka", "tushare"}, report.Passed)
+}
+
+// TestTushareCheck 测试未启用探测时只检查token，不调用接口
+func TestTushareCheck(t *testing.T) {
+	tushare := &fakeTushare{err: errors.New("should not be called")}
+
+	assert.NoError(t, TushareCheck("token", false, tushare).Run(context.Background()))
+	assert.ErrorContains(t, TushareCheck("", false, tushare).Run(context.Background()), "TUSHARE_API_TOKEN is empty")
+	assert.ErrorContains(t, TushareCheck("", true, tushare).Run(context.Background()), "TUSHARE_API_TOKEN is empty")
+	assert.Equal(t, 0, tushare.calls)
+}
+
+// TestRunSelfCheck_Timeout 测试检查项超时计为失败
+func TestRunSelfCheck_Timeout(t *testing.T) {
+	report := RunSelfCheck(context.Background(), 10*time.Millisecond, Check{
+		Name: "slow",
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	require.Len(t, report.Failures, 1)
+	assert.ErrorIs(t, report.Failures[0].Err, context.DeadlineExceeded)
+}
@@ -0,0 +1,65 @@
+// Package shutdown 按顺序优雅关闭各子系统
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Step 一个需要优雅关闭的子系统，Stop应在ctx到期时尽快返回
+type Step struct {
+	Name string
+	Stop func(ctx context.Context) error
+}
+
+// Run 按顺序关闭各子系统，每个子系统最多等待timeout，返回超时的子系统名称
+// Stop未在timeout内返回时不再等待，直接继续关闭下一个子系统
+func Run(timeout time.Duration, steps ...Step) []string {
+	var timedOut []string
+	for _, step := range steps {
+		if err := stop(step, timeout); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				logrus.Warnf("%s stopped forcefully after %v timeout: %v", step.Name, timeout, err)
+				timedOut = append(timedOut, step.Name)
+				continue
+			}
+			logrus.Errorf("Failed to stop %s: %v", step.Name, err)
+			continue
+		}
+		logrus.Infof("%s stopped gracefully", step.Name)
+	}
+	return timedOut
+}
+
+// stop 在timeout内执行一个子系统的关闭
+func stop(step Step, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- step.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitDone 返回等待done关闭的Stop函数，用于等待后台goroutine退出
+func WaitDone(done <-chan struct{}) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRun_RespectsTimeout 测试每个子系统最多等待配置的超时，并返回超时的子系统
+func TestRun_RespectsTimeout(t *testing.T) {
+	timeout := 50 * time.Millisecond
+	done := make(chan struct{})
+	close(done)
+
+	start := time.Now()
+	timedOut := Run(timeout,
+		Step{Name: "data processing", Stop: WaitDone(done)},
+		// 忽略ctx的子系统也不会阻塞关闭流程
+		Step{Name: "http", Stop: func(ctx context.Context) error {
+			time.Sleep(time.Hour)
+			return nil
+		}},
+		Step{Name: "kafka", Stop: func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(timeout), deadline, timeout)
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		Step{Name: "failing", Stop: func(ctx context.Context) error {
+			return errors.New("boom")
+		}},
+	)
+	elapsed := time.Since(start)
+
+	assert.Equal(t, []string{"http", "kafka"}, timedOut)
+	assert.GreaterOrEqual(t, elapsed, 2*timeout)
+	assert.Less(t, elapsed, 2*timeout+time.Second)
+}
+
+// TestWaitDone 测试等待后台goroutine退出
+func TestWaitDone(t *testing.T) {
+	done := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, WaitDone(done)(ctx), context.DeadlineExceeded)
+
+	close(done)
+	assert.NoError(t, WaitDone(done)(context.Background()))
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"quant-data-engine/internal/models"
+)
+
+// stockBasicSelect stock_basic表的查询列，由models.StockBasic的db标签生成，查询列和扫描字段不会不一致
+var stockBasicSelect = selectColumns(reflect.TypeOf(models.StockBasic{}))
+
+// stkManagersSelect stk_managers表的查询列，由models.StkManagers的db标签生成
+var stkManagersSelect = selectColumns(reflect.TypeOf(models.StkManagers{}))
+
+// dbFields 按字段顺序返回结构体中带db标签的字段下标，db标签为空或"-"的字段被忽略
+func dbFields(t reflect.Type) []int {
+	var indexes []int
+	for i := 0; i < t.NumField(); i++ {
+		if dbTag(t.Field(i)) != "" {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// dbTag 返回字段的db列名
+func dbTag(field reflect.StructField) string {
+	column, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+	if column == "-" {
+		return ""
+	}
+	return column
+}
+
+// dbColumns 按字段顺序返回结构体的db列名
+func dbColumns(t reflect.Type) []string {
+	var columns []string
+	for _, i := range dbFields(t) {
+		columns = append(columns, dbTag(t.Field(i)))
+	}
+	return columns
+}
+
+// selectColumns 生成与dbColumns顺序一致的SELECT列表，字符串列为NULL时返回空字符串
+func selectColumns(t reflect.Type) string {
+	var columns []string
+	for _, i := range dbFields(t) {
+		field := t.Field(i)
+		column := dbTag(field)
+		if field.Type.Kind() == reflect.String {
+			column = fmt.Sprintf("COALESCE(%s, '') AS %s", column, column)
+		}
+		columns = append(columns, column)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// scanTargets 按dbColumns的顺序返回dest（结构体指针）中各字段的指针，用作rows.Scan的参数
+func scanTargets(dest interface{}) []interface{} {
+	v := reflect.ValueOf(dest).Elem()
+	var targets []interface{}
+	for _, i := range dbFields(v.Type()) {
+		targets = append(targets, v.Field(i).Addr().Interface())
+	}
+	return targets
+}
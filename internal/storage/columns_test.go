@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"quant-data-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStockBasicColumns 测试生成的列名与models.StockBasic的db标签一致
+func TestStockBasicColumns(t *testing.T) {
+	typ := reflect.TypeOf(models.StockBasic{})
+	var tags []string
+	for i := 0; i < typ.NumField(); i++ {
+		tags = append(tags, typ.Field(i).Tag.Get("db"))
+	}
+	assert.Equal(t, tags, dbColumns(typ))
+
+	// 字符串列使用COALESCE，时间列直接查询
+	assert.True(t, strings.HasPrefix(stockBasicSelect, "COALESCE(ts_code, '') AS ts_code, "))
+	assert.True(t, strings.HasSuffix(stockBasicSelect, ", created_at, updated_at"))
+}
+
+// TestScanTargets 测试扫描目标与列顺序一致，按列写入的值回填到对应字段
+func TestScanTargets(t *testing.T) {
+	columns := dbColumns(reflect.TypeOf(models.StockBasic{}))
+	var d models.StockBasic
+	targets := scanTargets(&d)
+	require.Len(t, targets, len(columns))
+
+	// 模拟rows.Scan：按列名写入值
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, column := range columns {
+		switch target := targets[i].(type) {
+		case *string:
+			*target = column
+		case *time.Time:
+			*target = now
+		default:
+			t.Fatalf("unexpected scan target %T for %s", target, column)
+		}
+	}
+	assert.Equal(t, "ts_code", d.TSCode)
+	assert.Equal(t, "curr_type", d.CurrType)
+	assert.Equal(t, "act_ent_type", d.ActEntType)
+	assert.Equal(t, now, d.UpdatedAt)
+}
+
+// TestDBColumns_SkipsUntagged 测试忽略没有db标签和db标签为"-"的字段
+func TestDBColumns_SkipsUntagged(t *testing.T) {
+	type row struct {
+		ID      string `db:"id"`
+		Note    string
+		Ignored string `db:"-"`
+		Count   int    `db:"count,omitempty"`
+	}
+	assert.Equal(t, []string{"id", "count"}, dbColumns(reflect.TypeOf(row{})))
+	assert.Equal(t, "COALESCE(id, '') AS id, count", selectColumns(reflect.TypeOf(row{})))
+
+	var r row
+	targets := scanTargets(&r)
+	require.Len(t, targets, 2)
+	*targets[1].(*int) = 3
+	assert.Equal(t, 3, r.Count)
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DBErrorKind 数据库错误的分类，用于判断是否可以重试
+type DBErrorKind int
+
+// 数据库错误分类
+const (
+	// DBErrorNone 没有错误
+	DBErrorNone DBErrorKind = iota
+	// DBErrorOther 其他错误，如SQL错误、类型不匹配、上下文取消，重试不会成功
+	DBErrorOther
+	// DBErrorTransient 临时性错误，如连接失败、序列化冲突、死锁，稍后重试可能成功
+	DBErrorTransient
+	// DBErrorConstraint 违反约束，如唯一键、外键、非空、检查约束，重试不会成功
+	DBErrorConstraint
+)
+
+// String 返回错误分类名称
+func (k DBErrorKind) String() string {
+	switch k {
+	case DBErrorNone:
+		return "none"
+	case DBErrorTransient:
+		return "transient"
+	case DBErrorConstraint:
+		return "constraint"
+	default:
+		return "other"
+	}
+}
+
+// ErrDBTransient 临时性数据库错误，稍后重试可能成功
+var ErrDBTransient = errors.New("transient database error")
+
+// ErrDBConstraint 数据库约束冲突，重试不会成功
+var ErrDBConstraint = errors.New("database constraint violation")
+
+// transientSQLStates 可重试的SQLSTATE错误码，08类（连接异常）按前缀单独判断
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// classifyDBError 根据pgx/pgconn错误判断数据库错误的分类
+func classifyDBError(err error) DBErrorKind {
+	if err == nil {
+		return DBErrorNone
+	}
+	// 调用方取消或超时不是数据库本身的问题
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return DBErrorOther
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case strings.HasPrefix(pgErr.Code, "23"):
+			return DBErrorConstraint
+		case strings.HasPrefix(pgErr.Code, "08"), transientSQLStates[pgErr.Code]:
+			return DBErrorTransient
+		default:
+			return DBErrorOther
+		}
+	}
+
+	// 连接建立失败、连接中断和未发送到服务器的请求
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || pgconn.SafeToRetry(err) {
+		return DBErrorTransient
+	}
+	return DBErrorOther
+}
+
+// wrapDBError 为临时性错误和约束冲突附加ErrDBTransient或ErrDBConstraint，使调用方可以用errors.Is判断，原始错误链保持不变
+func wrapDBError(err error) error {
+	switch classifyDBError(err) {
+	case DBErrorTransient:
+		return fmt.Errorf("%w: %w", ErrDBTransient, err)
+	case DBErrorConstraint:
+		return fmt.Errorf("%w: %w", ErrDBConstraint, err)
+	default:
+		return err
+	}
+}
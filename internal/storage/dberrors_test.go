@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClassifyDBError 测试代表性的pgconn错误映射到对应的错误分类
+func TestClassifyDBError(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	cases := []struct {
+		name string
+		err  error
+		want DBErrorKind
+	}{
+		{"nil", nil, DBErrorNone},
+		{"unique_violation", &pgconn.PgError{Code: "23505"}, DBErrorConstraint},
+		{"foreign_key_violation", &pgconn.PgError{Code: "23503"}, DBErrorConstraint},
+		{"not_null_violation", &pgconn.PgError{Code: "23502"}, DBErrorConstraint},
+		{"serialization_failure", &pgconn.PgError{Code: "40001"}, DBErrorTransient},
+		{"deadlock_detected", &pgconn.PgError{Code: "40P01"}, DBErrorTransient},
+		{"too_many_connections", &pgconn.PgError{Code: "53300"}, DBErrorTransient},
+		{"admin_shutdown", &pgconn.PgError{Code: "57P01"}, DBErrorTransient},
+		{"connection_failure", &pgconn.PgError{Code: "08006"}, DBErrorTransient},
+		{"syntax_error", &pgconn.PgError{Code: "42601"}, DBErrorOther},
+		{"wrapped unique_violation", fmt.Errorf("failed to insert: %w", &pgconn.PgError{Code: "23505"}), DBErrorConstraint},
+		{"dial error", fmt.Errorf("failed to begin transaction: %w", dialErr), DBErrorTransient},
+		{"unexpected eof", fmt.Errorf("failed to commit: %w", io.ErrUnexpectedEOF), DBErrorTransient},
+		{"context canceled", fmt.Errorf("failed to insert: %w", context.Canceled), DBErrorOther},
+		{"plain error", errors.New("invalid market data"), DBErrorOther},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, classifyDBError(tc.err), tc.want.String())
+		})
+	}
+}
+
+// TestWrapDBError 测试包装后可用errors.Is判断分类，且保留原始错误链
+func TestWrapDBError(t *testing.T) {
+	pgErr := &pgconn.PgError{Code: "23505", Message: "duplicate key"}
+	err := wrapDBError(fmt.Errorf("failed to insert market data: %w", pgErr))
+	assert.ErrorIs(t, err, ErrDBConstraint)
+	assert.NotErrorIs(t, err, ErrDBTransient)
+	var got *pgconn.PgError
+	assert.ErrorAs(t, err, &got)
+	assert.Equal(t, "23505", got.Code)
+
+	err = wrapDBError(&pgconn.PgError{Code: "40001"})
+	assert.ErrorIs(t, err, ErrDBTransient)
+
+	plain := errors.New("invalid market data")
+	assert.Same(t, plain, wrapDBError(plain))
+	assert.NoError(t, wrapDBError(nil))
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SaveStkManagers 保存上市公司管理层，同一公司同一公告日期的同名管理层已存在时更新
+func (s *PostgresStorage) SaveStkManagers(data []models.StkManagers) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	tx, err := s.pool.Begin(context.Background())
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(context.Background())
+
+	query := `
+		INSERT INTO stk_managers (
+			ts_code, ann_date, name, gender, lev, title, edu, national,
+			birthday, begin_date, end_date, resume, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		) ON CONFLICT (ts_code, ann_date, name) DO UPDATE SET
+			gender = $4, lev = $5, title = $6, edu = $7, national = $8,
+			birthday = $9, begin_date = $10, end_date = $11, resume = $12,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	for _, d := range data {
+		_, err := tx.Exec(context.Background(), query,
+			d.TSCode, d.AnnDate, d.Name, d.Gender, d.Lev, d.Title, d.Edu, d.National,
+			d.Birthday, d.BeginDate, d.EndDate, d.Resume)
+		if err != nil {
+			return errx.Wrap(err, "failed to insert stk managers")
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+
+	logrus.Debugf("Saved %d stk managers records", len(data))
+	return nil
+}
+
+// validateAnnDateRange 校验公告日期范围，start和end都可以为空，不为空时必须是YYYYMMDD，且end不能早于start
+func validateAnnDateRange(start, end string) error {
+	switch {
+	case start != "" && end != "":
+		return validateDateRange(start, end)
+	case start != "":
+		if _, err := time.Parse("20060102", start); err != nil {
+			return fmt.Errorf("%w: start %q must be YYYYMMDD", ErrInvalidDateRange, start)
+		}
+	case end != "":
+		if _, err := time.Parse("20060102", end); err != nil {
+			return fmt.Errorf("%w: end %q must be YYYYMMDD", ErrInvalidDateRange, end)
+		}
+	}
+	return nil
+}
+
+// buildStkManagersQuery 生成按公司和公告日期过滤、分页的管理层查询，空字符串和非正数的limit/offset表示不限制
+// 结果按公告日期倒序、姓名升序，同一页内和翻页时顺序稳定
+func buildStkManagersQuery(tsCode, startAnnDate, endAnnDate string, limit, offset int) (string, []any) {
+	var args []any
+	var conditions []string
+	addCondition := func(expr, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(expr, len(args)))
+	}
+	addCondition("ts_code = $%d", tsCode)
+	addCondition("ann_date >= $%d", startAnnDate)
+	addCondition("ann_date <= $%d", endAnnDate)
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + stkManagersSelect + " FROM stk_managers")
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+	sb.WriteString(" ORDER BY ann_date DESC, name ASC")
+	if limit > 0 {
+		args = append(args, limit)
+		fmt.Fprintf(&sb, " LIMIT $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		fmt.Fprintf(&sb, " OFFSET $%d", len(args))
+	}
+	return sb.String(), args
+}
+
+// GetStkManagersFiltered 获取公司在公告日期范围[startAnnDate, endAnnDate]内的管理层，按公告日期倒序、姓名升序分页返回
+// 日期格式为YYYYMMDD，为空时不限制；日期格式错误或end早于start时返回ErrInvalidDateRange
+func (s *PostgresStorage) GetStkManagersFiltered(ctx context.Context, tsCode string, startAnnDate, endAnnDate string, limit, offset int) ([]models.StkManagers, error) {
+	if err := validateAnnDateRange(startAnnDate, endAnnDate); err != nil {
+		return nil, err
+	}
+
+	query, args := buildStkManagersQuery(tsCode, startAnnDate, endAnnDate, limit, offset)
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query stk managers")
+	}
+	defer rows.Close()
+
+	data := []models.StkManagers{}
+	for rows.Next() {
+		var d models.StkManagers
+		if err := rows.Scan(scanTargets(&d)...); err != nil {
+			return nil, errx.Wrap(err, "failed to scan stk managers")
+		}
+		data = append(data, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating stk managers rows")
+	}
+	return data, nil
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"quant-data-engine/internal/errx"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 市场数据的去重键（MARKET_DATA_KEY），决定SaveMarketData的ON CONFLICT冲突目标
+const (
+	// MarketDataKeyID 按id去重（默认），id随机生成时重新获取的相同数据会重复保存
+	MarketDataKeyID = "id"
+	// MarketDataKeyNatural 按(symbol, timestamp, source)去重，同一数据源同一时刻的数据只保存一次
+	MarketDataKeyNatural = "natural"
+)
+
+// 去重键对应的冲突目标，natural使用唯一索引marketDataNaturalIndex
+const (
+	marketDataIDConflict      = "id"
+	marketDataNaturalConflict = "symbol, timestamp, source"
+	marketDataNaturalIndex    = "uq_market_data_symbol_timestamp_source"
+)
+
+// marketDataConflictTarget 返回去重键对应的ON CONFLICT冲突目标，空字符串按id去重
+func marketDataConflictTarget(key string) (string, error) {
+	switch key {
+	case MarketDataKeyID, "":
+		return marketDataIDConflict, nil
+	case MarketDataKeyNatural:
+		return marketDataNaturalConflict, nil
+	default:
+		return "", fmt.Errorf("invalid MARKET_DATA_KEY %q: must be %q or %q", key, MarketDataKeyID, MarketDataKeyNatural)
+	}
+}
+
+// marketDataNaturalIndexExists 判断(symbol, timestamp, source)唯一索引是否已创建
+func (s *PostgresStorage) marketDataNaturalIndexExists(ctx context.Context) (bool, error) {
+	var exists bool
+	if err := s.pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", marketDataNaturalIndex).Scan(&exists); err != nil {
+		return false, errx.Wrap(err, "failed to check market_data natural key index")
+	}
+	return exists, nil
+}
+
+// migrateMarketDataKey 按去重键调整market_data的唯一索引，索引已符合去重键时不做任何操作
+// natural时若唯一索引不存在，先删除(symbol, timestamp, source)重复的数据（每组保留id最小的一行，按交易对和数据源记录删除的条数），再创建唯一索引；
+// id时若唯一索引存在，dropIndex为true才删除该索引（否则按id去重的插入仍会因唯一索引冲突而失败），为false时返回错误，避免切换配置时静默删除约束
+func (s *PostgresStorage) migrateMarketDataKey(ctx context.Context, conflict string, dropIndex bool) error {
+	exists, err := s.marketDataNaturalIndexExists(ctx)
+	if err != nil {
+		return err
+	}
+
+	if conflict != marketDataNaturalConflict {
+		if !exists {
+			return nil
+		}
+		if !dropIndex {
+			return fmt.Errorf("market_data has the natural key index %s but MARKET_DATA_KEY is %q; set MARKET_DATA_KEY_DROP_INDEX=true to drop it",
+				marketDataNaturalIndex, MarketDataKeyID)
+		}
+		if _, err := s.pool.Exec(ctx, "DROP INDEX IF EXISTS "+marketDataNaturalIndex); err != nil {
+			return errx.Wrap(err, "failed to drop market_data natural key index")
+		}
+		logrus.Warnf("Dropped market_data natural key index %s, market data is now deduplicated by id only", marketDataNaturalIndex)
+		return nil
+	}
+	if exists {
+		return nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		WITH deleted AS (
+			DELETE FROM market_data a
+			USING market_data b
+			WHERE a.symbol = b.symbol AND a.timestamp = b.timestamp AND a.source = b.source AND a.id > b.id
+			RETURNING a.symbol, a.source
+		)
+		SELECT symbol, source, COUNT(*) FROM deleted GROUP BY symbol, source ORDER BY symbol, source
+	`)
+	if err != nil {
+		return errx.Wrap(err, "failed to delete duplicate market data")
+	}
+	var deleted int64
+	for rows.Next() {
+		var symbol, source string
+		var count int64
+		if err := rows.Scan(&symbol, &source, &count); err != nil {
+			rows.Close()
+			return errx.Wrap(err, "failed to scan deleted market data")
+		}
+		logrus.Warnf("Deleting %d duplicate market data rows for %s from %s", count, symbol, source)
+		deleted += count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errx.Wrap(err, "failed to delete duplicate market data")
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(
+		"CREATE UNIQUE INDEX %s ON market_data(%s)", marketDataNaturalIndex, marketDataNaturalConflict)); err != nil {
+		return errx.Wrap(err, "failed to create market_data natural key index")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+
+	logrus.Infof("Created market_data natural key index %s after deleting %d duplicate rows", marketDataNaturalIndex, deleted)
+	return nil
+}
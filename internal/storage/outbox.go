@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execer 可执行SQL语句的对象，pgx.Tx和pgxpool.Pool都满足该接口
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// insertMarketData 在事务中逐条插入市场数据，与conflict（冲突目标，见marketDataConflictTarget）冲突的数据跳过
+// outbox为true时为每条新插入的数据写入发件箱
+func insertMarketData(ctx context.Context, tx execer, data []models.MarketData, conflict string, outbox bool) error {
+	// 直接执行SQL语句，不使用预处理语句
+	query := fmt.Sprintf(`
+		INSERT INTO market_data (id, symbol, price, volume, timestamp, source)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (%s) DO NOTHING
+	`, conflict)
+
+	for _, d := range data {
+		tag, err := tx.Exec(ctx, query, d.ID, d.Symbol, d.Price, d.Volume, d.Timestamp, d.Source)
+		if err != nil {
+			return errx.Wrap(err, "failed to insert market data")
+		}
+		// 只为新插入的行写发件箱，重复数据不会被再次发送
+		if outbox && tag.RowsAffected() > 0 {
+			if err := insertOutbox(ctx, tx, models.OutboxEventMarketData, d.Symbol, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copier 支持COPY和查询的事务，pgx.Tx满足该接口
+type copier interface {
+	execer
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// copyMarketDataThreshold 达到该条数的批次使用COPY写入，更小的批次逐条插入，避免每批创建临时表的开销
+const copyMarketDataThreshold = 100
+
+// copyMarketData 在事务中用COPY将市场数据写入临时表，再插入market_data，与conflict冲突的数据跳过（与insertMarketData结果一致）
+// 同一批内conflict重复的数据只保留第一条；outbox为true时按原始顺序为每条新插入的数据写入发件箱
+func copyMarketData(ctx context.Context, tx copier, data []models.MarketData, conflict string, outbox bool) error {
+	// 临时表在事务结束时删除
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE market_data_staging (
+			seq INT NOT NULL,
+			id VARCHAR(36) NOT NULL,
+			symbol VARCHAR(20) NOT NULL,
+			price DOUBLE PRECISION NOT NULL,
+			volume DOUBLE PRECISION NOT NULL,
+			timestamp TIMESTAMP NOT NULL,
+			source VARCHAR(50) NOT NULL
+		) ON COMMIT DROP
+	`); err != nil {
+		return errx.Wrap(err, "failed to create market data staging table")
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"market_data_staging"},
+		[]string{"seq", "id", "symbol", "price", "volume", "timestamp", "source"},
+		pgx.CopyFromSlice(len(data), func(i int) ([]any, error) {
+			d := data[i]
+			return []any{i, d.ID, d.Symbol, d.Price, d.Volume, d.Timestamp, d.Source}, nil
+		}),
+	); err != nil {
+		return errx.Wrap(err, "failed to copy market data")
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		INSERT INTO market_data (id, symbol, price, volume, timestamp, source)
+		SELECT DISTINCT ON (%[1]s) id, symbol, price, volume, timestamp, source
+		FROM market_data_staging
+		ORDER BY %[1]s, seq
+		ON CONFLICT (%[1]s) DO NOTHING
+		RETURNING id
+	`, conflict))
+	if err != nil {
+		return errx.Wrap(err, "failed to insert market data")
+	}
+	inserted := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return errx.Wrap(err, "failed to scan inserted market data id")
+		}
+		inserted[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errx.Wrap(err, "failed to insert market data")
+	}
+
+	if !outbox {
+		return nil
+	}
+	// 只为新插入的行写发件箱，重复数据不会被再次发送
+	for _, d := range data {
+		if !inserted[d.ID] {
+			continue
+		}
+		delete(inserted, d.ID)
+		if err := insertOutbox(ctx, tx, models.OutboxEventMarketData, d.Symbol, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertOutbox 写入一条发件箱消息，payload序列化为JSON
+func insertOutbox(ctx context.Context, tx execer, eventType, key string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errx.Wrap(err, "failed to marshal outbox payload")
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO outbox (event_type, key, payload)
+		VALUES ($1, $2, $3)
+	`, eventType, key, body); err != nil {
+		return errx.Wrap(err, "failed to insert outbox message")
+	}
+	return nil
+}
+
+// GetUnsentOutbox 按写入顺序获取最多limit条未发送的发件箱消息
+func (s *PostgresStorage) GetUnsentOutbox(ctx context.Context, limit int) ([]models.OutboxMessage, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, event_type, key, payload, created_at
+		FROM outbox
+		WHERE sent_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query outbox")
+	}
+	defer rows.Close()
+
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var m models.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.EventType, &m.Key, &m.Payload, &m.CreatedAt); err != nil {
+			return nil, errx.Wrap(err, "failed to scan outbox message")
+		}
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating outbox rows")
+	}
+
+	return messages, nil
+}
+
+// MarkOutboxSent 将发件箱消息标记为已发送
+func (s *PostgresStorage) MarkOutboxSent(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := s.pool.Exec(ctx, `
+		UPDATE outbox SET sent_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1) AND sent_at IS NULL
+	`, ids); err != nil {
+		return errx.Wrap(err, "failed to mark outbox messages sent")
+	}
+	return nil
+}
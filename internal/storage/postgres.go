@@ -3,11 +3,17 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"quant-data-engine/internal/config"
+	"quant-data-engine/internal/errx"
 	"quant-data-engine/internal/models"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
@@ -16,73 +22,156 @@ import (
 type StorageInterface interface {
 	SaveStockBasic(data []models.StockBasic) error
 	GetStockBasic(limit int) ([]models.StockBasic, error)
+	QueryStockBasic(ctx context.Context, q StockBasicQuery) ([]models.StockBasic, error)
+	CountStockBasic(ctx context.Context, q StockBasicQuery) (int64, error)
+	GetStockNames(ctx context.Context) (map[string]string, error)
 	SaveMarketData(data []models.MarketData) error
 	SaveBacktestData(data models.BacktestData) error
-	GetMarketData(symbol string, limit int) ([]models.MarketData, error)
-	GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error)
+	SaveBacktestDataBatch(data []models.BacktestData) error
+	UpsertBacktestDataReturning(ctx context.Context, data models.BacktestData) (models.BacktestData, error)
+	GetMarketData(ctx context.Context, symbol string, limit int) ([]models.MarketData, error)
+	QueryMarketData(ctx context.Context, q MarketDataQuery) ([]models.MarketData, error)
+	CountMarketData(ctx context.Context, q MarketDataQuery) (int64, error)
+	GetHistoricalData(symbol string, startTime, endTime string, limit, offset int) (*models.HistoricalDataResult, error)
 	SaveOHLCVDailyQFQ(data []models.OHLCVDailyQFQ) error
 	GetOHLCVCountBySymbol(tsCode string) (int64, error)
 	GetExistingDateRangeForSymbol(tsCode string) (string, string, error)
 	GetAllStockCodes() ([]string, error)
 	GetStockListDate(symbol string) (string, error)
 	SaveTradeCalendar(data []models.TradeCal) error
+	SaveTradeCal(data []models.TradeCal) error
+	GetTradeCal(exchange string, startDate, endDate string) ([]models.TradeCal, error)
 	UpsertTradeCal(exchange, calDate, isOpen, preTradeDate string) error
+	SaveBackfillProgress(progress models.BackfillProgress) error
+	GetBackfillProgress(id string) (*models.BackfillProgress, error)
+	GetSourceFreshness(ctx context.Context) (map[string]time.Time, error)
+	GetCrossSourcePrices(ctx context.Context, symbol string, at time.Time, tolerance time.Duration) (map[string]float64, error)
+	GetVWAP(ctx context.Context, symbol string, start, end time.Time) (float64, error)
+	GetReturnCorrelation(ctx context.Context, symbolA, symbolB string, start, end time.Time) (float64, int, error)
+	GetVolumeProfile(ctx context.Context, symbol string, start, end time.Time, buckets int) ([]models.PriceVolumeBucket, error)
+	GetOHLCV(symbol string, interval time.Duration, startTime, endTime time.Time) ([]models.Candle, error)
+	GetDownsampledMarketData(ctx context.Context, symbol string, bucket time.Duration, start, end time.Time) ([]models.MarketData, error)
+	GetOpenTradeDates(ctx context.Context, exchange, start, end string) ([]string, error)
+	SaveAdjFactors(data []models.AdjFactor) error
+	GetBacktestDataByIDs(ctx context.Context, ids []string) ([]models.BacktestData, error)
+	GetBacktestData(symbol, strategy string, limit, offset int) ([]models.BacktestData, error)
+	CountBacktestData(symbol, strategy string) (int64, error)
+	SaveDaily(data []models.Daily) error
+	GetDaily(tsCode, startDate, endDate string) ([]models.Daily, error)
+	GetStoredTradeDates(ctx context.Context, tsCode string) ([]string, error)
+	FindMissingTradingDays(ctx context.Context, tsCode, exchange, start, end string) ([]string, error)
+	GetSplitAdjustedDaily(ctx context.Context, tsCode, start, end string) ([]models.Daily, error)
+	GetRollingHighLow(ctx context.Context, tsCode string, window int, asOf string) (high, low float64, err error)
+	RecomputeDailyChanges(ctx context.Context, tsCode string) (int64, error)
+	GetDailyColumns(ctx context.Context, tsCodes []string, cols []string, start, end string) (*models.DailyColumns, error)
+	SaveMoneyflow(data []models.Moneyflow) error
+	GetMoneyflow(ctx context.Context, tsCode, start, end string) ([]models.Moneyflow, error)
+	GetStkManagersFiltered(ctx context.Context, tsCode string, startAnnDate, endAnnDate string, limit, offset int) ([]models.StkManagers, error)
 	Close()
 }
 
 // PostgresStorage PostgreSQL存储实现
 type PostgresStorage struct {
-	pool *pgxpool.Pool
+	pool              *pgxpool.Pool
+	maxHistoricalRows int
+	// outbox 为true时SaveMarketData在同一事务中写入发件箱
+	outbox bool
+	// marketDataConflict SaveMarketData的ON CONFLICT冲突目标，由MARKET_DATA_KEY决定
+	marketDataConflict string
+	// dailySaved SaveDaily提交后以本批日线调用，为nil时不通知
+	dailySaved func([]models.Daily)
+}
+
+// OnDailySaved 设置日线保存后的通知，例如发布到事件总线供实时推送；fn不应阻塞
+func (s *PostgresStorage) OnDailySaved(fn func([]models.Daily)) {
+	s.dailySaved = fn
 }
 
 // NewPostgresStorage 创建PostgreSQL存储
 func NewPostgresStorage() (*PostgresStorage, error) {
 	cfg := config.AppConfig
 
-	// 构建数据库连接字符串
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
-
 	// 创建连接池配置
-	poolConfig, err := pgxpool.ParseConfig(connStr)
+	poolConfig, err := pgxpool.ParseConfig(connString(cfg))
 	if err != nil {
-		logrus.Errorf("Failed to parse database config: %v", err)
-		return nil, err
+		return nil, errx.Wrap(err, "failed to parse database config")
 	}
 
 	// 设置连接池参数
-	poolConfig.MaxConns = int32(cfg.DBMaxConns)
-	poolConfig.MinConns = int32(cfg.DBMaxConns / 2)
+	minConns, maxConns, err := poolSizing(cfg.DBMaxConns)
+	if err != nil {
+		return nil, err
+	}
+	conflict, err := marketDataConflictTarget(cfg.MarketDataKey)
+	if err != nil {
+		return nil, err
+	}
+	poolConfig.MaxConns = maxConns
+	poolConfig.MinConns = minConns
 	poolConfig.MaxConnLifetime = 1 * time.Hour
 	poolConfig.MaxConnIdleTime = 30 * time.Minute
 
 	// 创建连接池
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		logrus.Errorf("Failed to connect to database: %v", err)
-		return nil, err
+		return nil, errx.Wrap(err, "failed to connect to database")
 	}
 
 	// 测试连接
 	if err := pool.Ping(context.Background()); err != nil {
-		logrus.Errorf("Failed to ping database: %v", err)
-		return nil, err
+		return nil, errx.Wrap(err, "failed to ping database")
 	}
 
 	storage := &PostgresStorage{
-		pool: pool,
+		pool:              pool,
+		maxHistoricalRows: cfg.MaxHistoricalRows,
+		outbox:            cfg.OutboxEnabled,
+
+		marketDataConflict: conflict,
 	}
 
 	// 初始化表结构
 	if err := storage.initTables(); err != nil {
-		logrus.Errorf("Failed to initialize tables: %v", err)
-		return nil, err
+		return nil, errx.Wrap(err, "failed to initialize tables")
+	}
+	if err := storage.migrateMarketDataKey(context.Background(), conflict, cfg.MarketDataKeyDropIndex); err != nil {
+		return nil, errx.Wrap(err, "failed to migrate market_data key")
 	}
 
-	logrus.Info("Connected to PostgreSQL database successfully")
+	logrus.Infof("Connected to PostgreSQL database successfully (pool min_conns=%d, max_conns=%d)", minConns, maxConns)
 	return storage, nil
 }
 
+// connString 根据配置构建数据库连接字符串
+func connString(cfg *config.Config) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+}
+
+// Ping 使用当前配置建立一次数据库连接并检查连通性，不创建连接池和表结构，用于启动自检
+func Ping(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, connString(config.AppConfig))
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	return conn.Ping(ctx)
+}
+
+// poolSizing 根据DB_MAX_CONNS计算连接池大小，MinConns为MaxConns的一半，MaxConns>=2时至少为1
+func poolSizing(dbMaxConns int) (minConns, maxConns int32, err error) {
+	if dbMaxConns < 1 {
+		return 0, 0, fmt.Errorf("invalid DB_MAX_CONNS %d: must be at least 1", dbMaxConns)
+	}
+
+	maxConns = int32(dbMaxConns)
+	minConns = maxConns / 2
+	if maxConns >= 2 && minConns < 1 {
+		minConns = 1
+	}
+	return minConns, maxConns, nil
+}
+
 // initTables 初始化表结构
 func (s *PostgresStorage) initTables() error {
 	// 创建市场数据表
@@ -286,6 +375,47 @@ func (s *PostgresStorage) initTables() error {
 	CREATE INDEX IF NOT EXISTS idx_daily_pct_chg ON daily(pct_chg);
 	`
 
+	// 创建A股复权因子表
+	adjFactorTableSQL := `
+	CREATE TABLE IF NOT EXISTS adj_factor (
+		ts_code VARCHAR(20) NOT NULL,
+		trade_date VARCHAR(10) NOT NULL,
+		adj_factor DOUBLE PRECISION NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (ts_code, trade_date)
+	);
+	`
+
+	// 创建A股个股资金流向表
+	moneyflowTableSQL := `
+	CREATE TABLE IF NOT EXISTS moneyflow (
+		ts_code VARCHAR(20) NOT NULL,
+		trade_date VARCHAR(10) NOT NULL,
+		buy_sm_vol BIGINT,
+		buy_sm_amount DOUBLE PRECISION,
+		sell_sm_vol BIGINT,
+		sell_sm_amount DOUBLE PRECISION,
+		buy_md_vol BIGINT,
+		buy_md_amount DOUBLE PRECISION,
+		sell_md_vol BIGINT,
+		sell_md_amount DOUBLE PRECISION,
+		buy_lg_vol BIGINT,
+		buy_lg_amount DOUBLE PRECISION,
+		sell_lg_vol BIGINT,
+		sell_lg_amount DOUBLE PRECISION,
+		buy_elg_vol BIGINT,
+		buy_elg_amount DOUBLE PRECISION,
+		sell_elg_vol BIGINT,
+		sell_elg_amount DOUBLE PRECISION,
+		net_mf_vol BIGINT,
+		net_mf_amount DOUBLE PRECISION,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (ts_code, trade_date)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_moneyflow_trade_date ON moneyflow(trade_date);
+	`
+
 	// 创建A股日线前复权行情表（ohlcv_daily_qfq已存在，使用现有schema）
 	// 现有表结构: symbol, trade_date, open, high, low, close, volume, turnover, trade_days, created_at
 
@@ -300,45 +430,144 @@ func (s *PostgresStorage) initTables() error {
 	CREATE INDEX IF NOT EXISTS idx_trade_calendar_is_trading ON trade_calendar(is_trading_day);
 	`
 
+	// 创建全市场回补进度表
+	backfillProgressTableSQL := `
+	CREATE TABLE IF NOT EXISTS backfill_progress (
+		id VARCHAR(36) PRIMARY KEY,
+		start_date VARCHAR(10) NOT NULL,
+		end_date VARCHAR(10) NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		total INTEGER NOT NULL DEFAULT 0,
+		done INTEGER NOT NULL DEFAULT 0,
+		current_code VARCHAR(20),
+		last_code VARCHAR(20),
+		errors INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	// 创建被拒绝市场数据的死信表，payload为原始记录
+	rejectedMarketDataTableSQL := `
+	CREATE TABLE IF NOT EXISTS rejected_market_data (
+		id BIGSERIAL PRIMARY KEY,
+		symbol VARCHAR(20),
+		source VARCHAR(50),
+		reason TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		rejected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rejected_market_data_rejected_at ON rejected_market_data(rejected_at);
+	`
+
+	// 创建事务性发件箱表，sent_at为空表示尚未发送
+	outboxTableSQL := `
+	CREATE TABLE IF NOT EXISTS outbox (
+		id BIGSERIAL PRIMARY KEY,
+		event_type VARCHAR(50) NOT NULL,
+		key VARCHAR(100) NOT NULL,
+		payload JSONB NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_outbox_unsent ON outbox(id) WHERE sent_at IS NULL;
+	`
+
+	// 创建交易对映射表，canonical为系统内统一的交易对名称，exchange_symbol为数据源使用的名称
+	symbolMapTableSQL := `
+	CREATE TABLE IF NOT EXISTS symbol_map (
+		canonical VARCHAR(20) NOT NULL,
+		source VARCHAR(50) NOT NULL,
+		exchange_symbol VARCHAR(50) NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (canonical, source),
+		UNIQUE (source, exchange_symbol)
+	);
+	`
+
+	// 创建数据源原始响应表，body为gzip压缩的响应体，按fetched_at定期清理
+	rawResponsesTableSQL := `
+	CREATE TABLE IF NOT EXISTS raw_responses (
+		id BIGSERIAL PRIMARY KEY,
+		source VARCHAR(50) NOT NULL,
+		endpoint TEXT NOT NULL,
+		body BYTEA NOT NULL,
+		fetched_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_raw_responses_fetched_at ON raw_responses(fetched_at);
+	`
+
 	// 执行SQL语句
 	if _, err := s.pool.Exec(context.Background(), marketDataTableSQL); err != nil {
-		return fmt.Errorf("failed to create market_data table: %w", err)
+		return errx.Wrap(err, "failed to create market_data table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), backtestDataTableSQL); err != nil {
-		return fmt.Errorf("failed to create backtest_data table: %w", err)
+		return errx.Wrap(err, "failed to create backtest_data table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), stockBasicTableSQL); err != nil {
-		return fmt.Errorf("failed to create stock_basic table: %w", err)
+		return errx.Wrap(err, "failed to create stock_basic table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), tradeCalTableSQL); err != nil {
-		return fmt.Errorf("failed to create trade_cal table: %w", err)
+		return errx.Wrap(err, "failed to create trade_cal table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), newShareTableSQL); err != nil {
-		return fmt.Errorf("failed to create new_share table: %w", err)
+		return errx.Wrap(err, "failed to create new_share table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), stockCompanyTableSQL); err != nil {
-		return fmt.Errorf("failed to create stock_company table: %w", err)
+		return errx.Wrap(err, "failed to create stock_company table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), stkManagersTableSQL); err != nil {
-		return fmt.Errorf("failed to create stk_managers table: %w", err)
+		return errx.Wrap(err, "failed to create stk_managers table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), stkRewardsTableSQL); err != nil {
-		return fmt.Errorf("failed to create stk_rewards table: %w", err)
+		return errx.Wrap(err, "failed to create stk_rewards table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), dailyTableSQL); err != nil {
-		return fmt.Errorf("failed to create daily table: %w", err)
+		return errx.Wrap(err, "failed to create daily table")
+	}
+
+	if _, err := s.pool.Exec(context.Background(), adjFactorTableSQL); err != nil {
+		return errx.Wrap(err, "failed to create adj_factor table")
+	}
+
+	if _, err := s.pool.Exec(context.Background(), moneyflowTableSQL); err != nil {
+		return errx.Wrap(err, "failed to create moneyflow table")
 	}
 
 	if _, err := s.pool.Exec(context.Background(), tradeCalendarTableSQL); err != nil {
-		return fmt.Errorf("failed to create trade_calendar table: %w", err)
+		return errx.Wrap(err, "failed to create trade_calendar table")
+	}
+
+	if _, err := s.pool.Exec(context.Background(), backfillProgressTableSQL); err != nil {
+		return errx.Wrap(err, "failed to create backfill_progress table")
+	}
+
+	if _, err := s.pool.Exec(context.Background(), rejectedMarketDataTableSQL); err != nil {
+		return errx.Wrap(err, "failed to create rejected_market_data table")
+	}
+
+	if _, err := s.pool.Exec(context.Background(), outboxTableSQL); err != nil {
+		return errx.Wrap(err, "failed to create outbox table")
+	}
+
+	if _, err := s.pool.Exec(context.Background(), symbolMapTableSQL); err != nil {
+		return errx.Wrap(err, "failed to create symbol_map table")
+	}
+
+	if _, err := s.pool.Exec(context.Background(), rawResponsesTableSQL); err != nil {
+		return errx.Wrap(err, "failed to create raw_responses table")
 	}
 
 	return nil
@@ -352,42 +581,71 @@ func (s *PostgresStorage) SaveMarketData(data []models.MarketData) error {
 
 	// 验证数据
 	for i, d := range data {
-		if err := validateMarketData(d); err != nil {
-			return fmt.Errorf("invalid market data at index %d: %w", i, err)
+		if err := ValidateMarketData(d); err != nil {
+			return errx.WrapF(err, "invalid market data at index %d", i)
 		}
 	}
 
-	// 使用批量插入
+	// 使用批量插入，数据库错误附加ErrDBTransient/ErrDBConstraint供调用方判断是否重试
 	tx, err := s.pool.Begin(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return wrapDBError(errx.Wrap(err, "failed to begin transaction"))
 	}
 	defer tx.Rollback(context.Background())
 
-	// 直接执行SQL语句，不使用预处理语句
-	query := `
-		INSERT INTO market_data (id, symbol, price, volume, timestamp, source)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (id) DO NOTHING
-	`
+	// 大批量使用COPY写入，小批量逐条插入
+	if len(data) >= copyMarketDataThreshold {
+		err = copyMarketData(context.Background(), tx, data, s.marketDataConflict, s.outbox)
+	} else {
+		err = insertMarketData(context.Background(), tx, data, s.marketDataConflict, s.outbox)
+	}
+	if err != nil {
+		return wrapDBError(err)
+	}
 
-	for _, d := range data {
-		_, err := tx.Exec(context.Background(), query, d.ID, d.Symbol, d.Price, d.Volume, d.Timestamp, d.Source)
+	if err := tx.Commit(context.Background()); err != nil {
+		return wrapDBError(errx.Wrap(err, "failed to commit transaction"))
+	}
+
+	logrus.Infof("Saved %d market data records", len(data))
+	return nil
+}
+
+// SaveRejectedMarketData 保存被拒绝的市场数据到死信表
+func (s *PostgresStorage) SaveRejectedMarketData(records []models.RejectedMarketData) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := s.pool.Begin(context.Background())
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(context.Background())
+
+	for _, r := range records {
+		payload, err := json.Marshal(r.Data)
 		if err != nil {
-			return fmt.Errorf("failed to insert market data: %w", err)
+			return errx.Wrap(err, "failed to marshal rejected market data")
+		}
+		if _, err := tx.Exec(context.Background(), `
+			INSERT INTO rejected_market_data (symbol, source, reason, payload, rejected_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, r.Data.Symbol, r.Data.Source, r.Reason, payload, r.RejectedAt); err != nil {
+			return errx.Wrap(err, "failed to insert rejected market data")
 		}
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return errx.Wrap(err, "failed to commit transaction")
 	}
 
-	logrus.Infof("Saved %d market data records", len(data))
+	logrus.Infof("Saved %d rejected market data records", len(records))
 	return nil
 }
 
-// validateMarketData 验证市场数据
-func validateMarketData(data models.MarketData) error {
+// ValidateMarketData 验证市场数据，SaveMarketData拒绝包含非法记录的批次
+func ValidateMarketData(data models.MarketData) error {
 	if data.ID == "" {
 		return fmt.Errorf("id is required")
 	}
@@ -406,20 +664,23 @@ func validateMarketData(data models.MarketData) error {
 	if data.Source == "" {
 		return fmt.Errorf("source is required")
 	}
+	if !models.IsKnownSource(data.Source) {
+		return fmt.Errorf("unknown source %q", data.Source)
+	}
 	return nil
 }
 
 // SaveBacktestData 保存回测数据
 func (s *PostgresStorage) SaveBacktestData(data models.BacktestData) error {
 	// 验证数据
-	if err := validateBacktestData(data); err != nil {
-		return fmt.Errorf("invalid backtest data: %w", err)
+	if err := ValidateBacktestData(data); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBacktestData, err)
 	}
 
 	// 使用事务
 	tx, err := s.pool.Begin(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return errx.Wrap(err, "failed to begin transaction")
 	}
 	defer tx.Rollback(context.Background())
 
@@ -436,329 +697,1959 @@ func (s *PostgresStorage) SaveBacktestData(data models.BacktestData) error {
 	`, data.ID, data.Symbol, data.Strategy, data.StartDate, data.EndDate, data.Results, data.Timestamp)
 
 	if err != nil {
-		return fmt.Errorf("failed to save backtest data: %w", err)
+		return errx.Wrap(err, "failed to save backtest data")
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return errx.Wrap(err, "failed to commit transaction")
 	}
 
 	logrus.Infof("Saved backtest data for symbol %s", data.Symbol)
 	return nil
 }
 
-// validateBacktestData 验证回测数据
-func validateBacktestData(data models.BacktestData) error {
-	if data.ID == "" {
-		return fmt.Errorf("id is required")
-	}
-	if data.Symbol == "" {
-		return fmt.Errorf("symbol is required")
+// ErrInvalidBacktestData 回测数据校验失败
+var ErrInvalidBacktestData = errors.New("invalid backtest data")
+
+// SaveBacktestDataBatch 在一个事务中批量插入或更新回测数据（按id），任一条校验失败时整批不保存
+func (s *PostgresStorage) SaveBacktestDataBatch(data []models.BacktestData) error {
+	if len(data) == 0 {
+		return nil
 	}
-	if data.Strategy == "" {
-		return fmt.Errorf("strategy is required")
+	for i, d := range data {
+		if err := ValidateBacktestData(d); err != nil {
+			return fmt.Errorf("%w at index %d: %w", ErrInvalidBacktestData, i, err)
+		}
 	}
-	if data.StartDate.IsZero() {
-		return fmt.Errorf("start_date is required")
+
+	tx, err := s.pool.Begin(context.Background())
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
 	}
-	if data.EndDate.IsZero() {
-		return fmt.Errorf("end_date is required")
+	defer tx.Rollback(context.Background())
+
+	query := `
+		INSERT INTO backtest_data (id, symbol, strategy, start_date, end_date, results, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			symbol = EXCLUDED.symbol,
+			strategy = EXCLUDED.strategy,
+			start_date = EXCLUDED.start_date,
+			end_date = EXCLUDED.end_date,
+			results = EXCLUDED.results,
+			timestamp = EXCLUDED.timestamp
+	`
+	for _, d := range data {
+		if _, err := tx.Exec(context.Background(), query,
+			d.ID, d.Symbol, d.Strategy, d.StartDate, d.EndDate, d.Results, d.Timestamp,
+		); err != nil {
+			return errx.WrapF(err, "failed to save backtest data %s", d.ID)
+		}
 	}
-	if data.EndDate.Before(data.StartDate) {
-		return fmt.Errorf("end_date must be after start_date")
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
 	}
-	if data.Results == "" {
-		return fmt.Errorf("results is required")
+
+	logrus.Infof("Saved %d backtest data records", len(data))
+	return nil
+}
+
+// UpsertBacktestDataReturning 插入或更新回测数据，返回持久化后的行（包括数据库生成的created_at）
+// 更新已有回测时created_at保持首次写入的时间
+func (s *PostgresStorage) UpsertBacktestDataReturning(ctx context.Context, data models.BacktestData) (models.BacktestData, error) {
+	if err := ValidateBacktestData(data); err != nil {
+		return models.BacktestData{}, fmt.Errorf("%w: %w", ErrInvalidBacktestData, err)
 	}
-	if data.Timestamp.IsZero() {
-		return fmt.Errorf("timestamp is required")
+
+	var stored models.BacktestData
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO backtest_data (id, symbol, strategy, start_date, end_date, results, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			symbol = EXCLUDED.symbol,
+			strategy = EXCLUDED.strategy,
+			start_date = EXCLUDED.start_date,
+			end_date = EXCLUDED.end_date,
+			results = EXCLUDED.results,
+			timestamp = EXCLUDED.timestamp
+		RETURNING id, symbol, strategy, start_date, end_date, results::text, timestamp, created_at
+	`, data.ID, data.Symbol, data.Strategy, data.StartDate, data.EndDate, data.Results, data.Timestamp).Scan(
+		&stored.ID, &stored.Symbol, &stored.Strategy, &stored.StartDate, &stored.EndDate, &stored.Results, &stored.Timestamp, &stored.CreatedAt)
+	if err != nil {
+		return models.BacktestData{}, errx.Wrap(err, "failed to upsert backtest data")
 	}
-	return nil
+
+	logrus.Infof("Upserted backtest data %s for symbol %s", stored.ID, stored.Symbol)
+	return stored, nil
 }
 
-// GetMarketData 获取市场数据
-func (s *PostgresStorage) GetMarketData(symbol string, limit int) ([]models.MarketData, error) {
-	rows, err := s.pool.Query(context.Background(), `
-		SELECT id, symbol, price, volume, timestamp, source
-		FROM market_data
-		WHERE symbol = $1
-		ORDER BY timestamp DESC
-		LIMIT $2
-	`, symbol, limit)
+// GetBacktestDataByIDs 按ID批量获取回测数据，不存在的ID直接忽略，结果不保证顺序
+func (s *PostgresStorage) GetBacktestDataByIDs(ctx context.Context, ids []string) ([]models.BacktestData, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, symbol, strategy, start_date, end_date, results::text, timestamp
+		FROM backtest_data
+		WHERE id = ANY($1)
+	`, ids)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query market data: %w", err)
+		return nil, errx.Wrap(err, "failed to query backtest data")
 	}
 	defer rows.Close()
 
-	var data []models.MarketData
+	var data []models.BacktestData
 	for rows.Next() {
-		var d models.MarketData
-		if err := rows.Scan(&d.ID, &d.Symbol, &d.Price, &d.Volume, &d.Timestamp, &d.Source); err != nil {
-			return nil, fmt.Errorf("failed to scan market data: %w", err)
+		var d models.BacktestData
+		if err := rows.Scan(&d.ID, &d.Symbol, &d.Strategy, &d.StartDate, &d.EndDate, &d.Results, &d.Timestamp); err != nil {
+			return nil, errx.Wrap(err, "failed to scan backtest data")
 		}
 		data = append(data, d)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating market data rows: %w", err)
+		return nil, errx.Wrap(err, "error iterating backtest data rows")
 	}
 
 	return data, nil
 }
 
-// GetHistoricalData 获取历史数据
-func (s *PostgresStorage) GetHistoricalData(symbol string, startTime, endTime string) ([]models.MarketData, error) {
-	rows, err := s.pool.Query(context.Background(), `
-		SELECT id, symbol, price, volume, timestamp, source
-		FROM market_data
-		WHERE symbol = $1 AND timestamp BETWEEN $2 AND $3
-		ORDER BY timestamp ASC
-	`, symbol, startTime, endTime)
+// backtestDataFilter 生成按交易对和策略过滤回测数据的WHERE子句和参数，strategy为空时不按策略过滤
+func backtestDataFilter(symbol, strategy string) (string, []interface{}) {
+	where := " WHERE symbol = $1"
+	args := []interface{}{symbol}
+	if strategy != "" {
+		args = append(args, strategy)
+		where += fmt.Sprintf(" AND strategy = $%d", len(args))
+	}
+	return where, args
+}
+
+// GetBacktestData 获取交易对的回测数据，strategy为空时不按策略过滤，按时间戳倒序跳过offset条，limit<=0时不限制条数
+func (s *PostgresStorage) GetBacktestData(symbol, strategy string, limit, offset int) ([]models.BacktestData, error) {
+	where, args := backtestDataFilter(symbol, strategy)
+	query := "SELECT id, symbol, strategy, start_date, end_date, results::text, timestamp, created_at FROM backtest_data" + where
+	// 同一时间戳按id排序，保证分页稳定
+	query += " ORDER BY timestamp DESC, id ASC"
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.pool.Query(context.Background(), query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query historical data: %w", err)
+		return nil, errx.Wrap(err, "failed to query backtest data")
 	}
 	defer rows.Close()
 
-	var data []models.MarketData
+	var data []models.BacktestData
 	for rows.Next() {
-		var d models.MarketData
-		if err := rows.Scan(&d.ID, &d.Symbol, &d.Price, &d.Volume, &d.Timestamp, &d.Source); err != nil {
-			return nil, fmt.Errorf("failed to scan historical data: %w", err)
+		var d models.BacktestData
+		if err := rows.Scan(&d.ID, &d.Symbol, &d.Strategy, &d.StartDate, &d.EndDate, &d.Results, &d.Timestamp, &d.CreatedAt); err != nil {
+			return nil, errx.Wrap(err, "failed to scan backtest data")
 		}
 		data = append(data, d)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating historical data rows: %w", err)
+		return nil, errx.Wrap(err, "error iterating backtest data rows")
 	}
 
 	return data, nil
 }
 
-// Close 关闭存储
-func (s *PostgresStorage) Close() {
-	if s.pool != nil {
-		s.pool.Close()
-		logrus.Info("PostgreSQL connection pool closed")
+// CountBacktestData 统计交易对的回测数据条数，strategy为空时不按策略过滤
+func (s *PostgresStorage) CountBacktestData(symbol, strategy string) (int64, error) {
+	where, args := backtestDataFilter(symbol, strategy)
+	var count int64
+	if err := s.pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM backtest_data"+where, args...).Scan(&count); err != nil {
+		return 0, errx.Wrap(err, "failed to count backtest data")
 	}
+	return count, nil
 }
 
-// SaveStockBasic 保存股票基础信息
-func (s *PostgresStorage) SaveStockBasic(data []models.StockBasic) error {
-	if len(data) == 0 {
-		return nil
+// ValidateBacktestData 验证回测数据，保存回测数据的方法拒绝非法数据
+func ValidateBacktestData(data models.BacktestData) error {
+	if data.ID == "" {
+		return fmt.Errorf("id is required")
 	}
-
-	// 使用批量插入
-	tx, err := s.pool.Begin(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+	if data.Symbol == "" {
+		return fmt.Errorf("symbol is required")
 	}
-	defer tx.Rollback(context.Background())
-
-	// 直接执行SQL语句
-	query := `
-		INSERT INTO stock_basic (
-			ts_code, symbol, name, area, industry, fullname, enname, cnspell, 
-			market, exchange, curr_type, list_status, list_date, delist_date, is_hs, 
-			act_name, act_ent_type, updated_at
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, CURRENT_TIMESTAMP
-		) ON CONFLICT (ts_code) DO UPDATE SET
-			symbol = $2, name = $3, area = $4, industry = $5, fullname = $6, enname = $7, cnspell = $8, 
-			market = $9, exchange = $10, curr_type = $11, list_status = $12, list_date = $13, delist_date = $14, is_hs = $15, 
-			act_name = $16, act_ent_type = $17, updated_at = CURRENT_TIMESTAMP
-	`
-
-	for _, d := range data {
-		_, err := tx.Exec(context.Background(), query,
-			d.TSCode, d.Symbol, d.Name, d.Area, d.Industry, d.Fullname, d.Enname, d.Cnspell,
-			d.Market, d.Exchange, d.CurrType, d.ListStatus, d.ListDate, d.DelistDate, d.IsHS,
-			d.ActName, d.ActEntType,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to insert stock basic data: %w", err)
-		}
+	if data.Strategy == "" {
+		return fmt.Errorf("strategy is required")
 	}
-
-	if err := tx.Commit(context.Background()); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if data.StartDate.IsZero() {
+		return fmt.Errorf("start_date is required")
+	}
+	if data.EndDate.IsZero() {
+		return fmt.Errorf("end_date is required")
+	}
+	if data.EndDate.Before(data.StartDate) {
+		return fmt.Errorf("end_date must be after start_date")
+	}
+	if data.Results == "" {
+		return fmt.Errorf("results is required")
+	}
+	if !json.Valid([]byte(data.Results)) {
+		return fmt.Errorf("results must be valid JSON")
+	}
+	if data.Timestamp.IsZero() {
+		return fmt.Errorf("timestamp is required")
 	}
-
-	logrus.Infof("Saved %d stock basic data records", len(data))
 	return nil
 }
 
-// GetStockBasic 获取股票基础信息
-func (s *PostgresStorage) GetStockBasic(limit int) ([]models.StockBasic, error) {
-	rows, err := s.pool.Query(context.Background(), `
-		SELECT ts_code, symbol, name, area, industry, fullname, enname, cnspell, 
-			market, exchange, curr_type, list_status, list_date, delist_date, is_hs, 
-			act_name, act_ent_type, created_at, updated_at
-		FROM stock_basic
-		ORDER BY ts_code ASC
-		LIMIT $1
-	`, limit)
+// GetStockNames 获取全部股票代码到名称的映射
+func (s *PostgresStorage) GetStockNames(ctx context.Context) (map[string]string, error) {
+	rows, err := s.pool.Query(ctx, `SELECT ts_code, COALESCE(name, '') FROM stock_basic`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query stock basic data: %w", err)
+		return nil, errx.Wrap(err, "failed to query stock names")
 	}
 	defer rows.Close()
 
-	var data []models.StockBasic
+	names := make(map[string]string)
 	for rows.Next() {
-		var d models.StockBasic
-		if err := rows.Scan(
-			&d.TSCode, &d.Symbol, &d.Name, &d.Area, &d.Industry, &d.Fullname, &d.Enname, &d.Cnspell,
-			&d.Market, &d.Exchange, &d.CurrType, &d.ListStatus, &d.ListDate, &d.DelistDate, &d.IsHS,
-			&d.ActName, &d.ActEntType, &d.CreatedAt, &d.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan stock basic data: %w", err)
+		var code, name string
+		if err := rows.Scan(&code, &name); err != nil {
+			return nil, errx.Wrap(err, "failed to scan stock name")
 		}
-		data = append(data, d)
+		names[code] = name
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating stock basic data rows: %w", err)
+		return nil, errx.Wrap(err, "error iterating stock name rows")
 	}
 
-	return data, nil
+	return names, nil
 }
 
-// SaveOHLCVDailyQFQ 保存前复权日线行情数据（匹配现有ohlcv_daily_qfq表schema）
-func (s *PostgresStorage) SaveOHLCVDailyQFQ(data []models.OHLCVDailyQFQ) error {
-	if len(data) == 0 {
-		return nil
-	}
+// GetMarketData 获取指定交易对最新的limit条市场数据
+//
+// Deprecated: 使用QueryMarketData
+func (s *PostgresStorage) GetMarketData(ctx context.Context, symbol string, limit int) ([]models.MarketData, error) {
+	return s.QueryMarketData(ctx, MarketDataQuery{Symbol: symbol, Limit: limit})
+}
 
-	tx, err := s.pool.Begin(context.Background())
+// 市场数据查询的排序方向（按时间）
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// ErrInvalidMarketDataQuery 市场数据查询选项不合法
+var ErrInvalidMarketDataQuery = errors.New("invalid market data query")
+
+// MarketDataQuery 市场数据查询选项，Symbol必填，其余零值字段表示不限制
+// 时间范围为[Since, Until)，Order为空时按时间倒序；Offset为按排序跳过的条数，在数据库中跳过
+type MarketDataQuery struct {
+	Symbol string
+	Source string
+	Limit  int
+	Offset int
+	Since  time.Time
+	Until  time.Time
+	Order  string
+}
+
+// Validate 校验查询选项
+func (q MarketDataQuery) Validate() error {
+	if q.Symbol == "" {
+		return fmt.Errorf("%w: symbol is required", ErrInvalidMarketDataQuery)
+	}
+	if q.Source != "" && !models.IsKnownSource(q.Source) {
+		return fmt.Errorf("%w: unknown source %q", ErrInvalidMarketDataQuery, q.Source)
+	}
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: limit cannot be negative", ErrInvalidMarketDataQuery)
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("%w: offset cannot be negative", ErrInvalidMarketDataQuery)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since) {
+		return fmt.Errorf("%w: until must be after since", ErrInvalidMarketDataQuery)
+	}
+	if q.Order != "" && q.Order != OrderAsc && q.Order != OrderDesc {
+		return fmt.Errorf("%w: order must be %q or %q", ErrInvalidMarketDataQuery, OrderAsc, OrderDesc)
+	}
+	return nil
+}
+
+// marketDataFilter 根据查询选项的过滤条件生成WHERE子句和参数，调用方需先校验查询选项
+func marketDataFilter(q MarketDataQuery) (string, []any) {
+	args := []any{q.Symbol}
+	conditions := []string{"symbol = $1"}
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if q.Source != "" {
+		addCondition("source = $%d", q.Source)
+	}
+	if !q.Since.IsZero() {
+		addCondition("timestamp >= $%d", q.Since)
+	}
+	if !q.Until.IsZero() {
+		addCondition("timestamp < $%d", q.Until)
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// buildMarketDataQuery 根据查询选项生成参数化SQL和参数，选项中的值只通过参数传递
+func buildMarketDataQuery(q MarketDataQuery) (string, []any, error) {
+	if err := q.Validate(); err != nil {
+		return "", nil, err
+	}
+	where, args := marketDataFilter(q)
+
+	order := "DESC"
+	if q.Order == OrderAsc {
+		order = "ASC"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT id, symbol, price, volume, timestamp, source FROM market_data" + where)
+	sb.WriteString(" ORDER BY timestamp " + order)
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		fmt.Fprintf(&sb, " LIMIT $%d", len(args))
+	}
+	if q.Offset > 0 {
+		args = append(args, q.Offset)
+		fmt.Fprintf(&sb, " OFFSET $%d", len(args))
+	}
+	return sb.String(), args, nil
+}
+
+// QueryMarketData 按查询选项获取市场数据
+func (s *PostgresStorage) QueryMarketData(ctx context.Context, q MarketDataQuery) ([]models.MarketData, error) {
+	query, args, err := buildMarketDataQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query market data")
+	}
+
+	return scanMarketData(ctx, rows)
+}
+
+// CountMarketData 统计符合过滤条件的市场数据条数，忽略Limit、Offset和Order
+func (s *PostgresStorage) CountMarketData(ctx context.Context, q MarketDataQuery) (int64, error) {
+	if err := q.Validate(); err != nil {
+		return 0, err
+	}
+	where, args := marketDataFilter(q)
+	var count int64
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM market_data"+where, args...).Scan(&count); err != nil {
+		return 0, errx.Wrap(err, "failed to count market data")
+	}
+	return count, nil
+}
+
+// MaxDownsampleBuckets 降采样查询最多返回的时间桶数
+const MaxDownsampleBuckets = 10000
+
+// validateDownsample 校验降采样参数：桶至少1秒，end不早于start，桶数不超过MaxDownsampleBuckets
+func validateDownsample(bucket time.Duration, start, end time.Time) error {
+	if bucket < time.Second {
+		return fmt.Errorf("%w: bucket must be at least 1s", ErrInvalidMarketDataQuery)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("%w: end must be after start", ErrInvalidMarketDataQuery)
+	}
+	if buckets := int64(end.Sub(start)/bucket) + 1; buckets > MaxDownsampleBuckets {
+		return fmt.Errorf("%w: %d buckets exceeds maximum %d, use a larger bucket", ErrInvalidMarketDataQuery, buckets, MaxDownsampleBuckets)
+	}
+	return nil
+}
+
+// GetDownsampledMarketData 按bucket将[start, end]内的市场数据分桶（从start开始对齐），每个桶返回时间最晚的一条，按时间升序
+func (s *PostgresStorage) GetDownsampledMarketData(ctx context.Context, symbol string, bucket time.Duration, start, end time.Time) ([]models.MarketData, error) {
+	if err := validateDownsample(bucket, start, end); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, symbol, price, volume, timestamp, source
+		FROM (
+			SELECT DISTINCT ON (bucket) id, symbol, price, volume, timestamp, source, bucket
+			FROM (
+				SELECT id, symbol, price, volume, timestamp, source,
+					date_bin($2 * INTERVAL '1 microsecond', timestamp, $3) AS bucket
+				FROM market_data
+				WHERE symbol = $1 AND timestamp BETWEEN $3 AND $4
+			) binned
+			ORDER BY bucket, timestamp DESC, id DESC
+		) latest
+		ORDER BY timestamp ASC
+	`, symbol, bucket.Microseconds(), start, end)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query downsampled market data")
+	}
+
+	return scanMarketData(ctx, rows)
+}
+
+// scanMarketData 读取市场数据查询结果并关闭rows
+// 每行扫描前检查ctx，客户端断开或请求被取消时立即停止，不再读取剩余的行
+func scanMarketData(ctx context.Context, rows pgx.Rows) ([]models.MarketData, error) {
+	defer rows.Close()
+
+	var data []models.MarketData
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, errx.Wrap(err, "market data scan aborted")
+		}
+
+		var d models.MarketData
+		if err := rows.Scan(&d.ID, &d.Symbol, &d.Price, &d.Volume, &d.Timestamp, &d.Source); err != nil {
+			return nil, errx.Wrap(err, "failed to scan market data")
+		}
+		data = append(data, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating market data rows")
+	}
+
+	return data, nil
+}
+
+// GetHistoricalData 获取历史数据，按时间升序分页，跳过前offset行
+// 每页最多limit行，limit<=0或超过maxHistoricalRows时使用maxHistoricalRows；后面还有数据时标记HasMore并给出下一页的offset和起始时间
+func (s *PostgresStorage) GetHistoricalData(symbol string, startTime, endTime string, limit, offset int) (*models.HistoricalDataResult, error) {
+	// 同一时间戳按id排序，保证分页稳定
+	query := `
+		SELECT id, symbol, price, volume, timestamp, source
+		FROM market_data
+		WHERE symbol = $1 AND timestamp BETWEEN $2 AND $3
+		ORDER BY timestamp ASC, id ASC
+	`
+	args := []interface{}{symbol, startTime, endTime}
+	pageSize := historicalPageSize(limit, s.maxHistoricalRows)
+	if pageSize > 0 {
+		// 多取一行用于判断后面是否还有数据
+		args = append(args, pageSize+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query historical data")
+	}
+	defer rows.Close()
+
+	var data []models.MarketData
+	for rows.Next() {
+		var d models.MarketData
+		if err := rows.Scan(&d.ID, &d.Symbol, &d.Price, &d.Volume, &d.Timestamp, &d.Source); err != nil {
+			return nil, errx.Wrap(err, "failed to scan historical data")
+		}
+		data = append(data, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating historical data rows")
+	}
+
+	return truncateHistoricalData(data, pageSize, offset), nil
+}
+
+// historicalPageSize 返回历史数据每页的行数，limit不超过maxRows，maxRows<=0表示不限制，返回0表示不分页
+func historicalPageSize(limit, maxRows int) int {
+	if maxRows <= 0 {
+		return max(limit, 0)
+	}
+	if limit <= 0 || limit > maxRows {
+		return maxRows
+	}
+	return limit
+}
+
+// GetSourceFreshness 获取每个数据源最新一条市场数据的时间
+func (s *PostgresStorage) GetSourceFreshness(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT source, MAX(timestamp)
+		FROM market_data
+		GROUP BY source
+	`)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query source freshness")
+	}
+	defer rows.Close()
+
+	freshness := make(map[string]time.Time)
+	for rows.Next() {
+		var source string
+		var lastSeen time.Time
+		if err := rows.Scan(&source, &lastSeen); err != nil {
+			return nil, errx.Wrap(err, "failed to scan source freshness")
+		}
+		freshness[source] = lastSeen
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating source freshness rows")
+	}
+
+	return freshness, nil
+}
+
+// SampleRecentMarketData 随机抽样保存时间（created_at）在from之前到to之前之间的市场数据，每条被抽中的概率为percent%，最多返回limit条
+// 时间按数据库当前时间计算，与created_at的默认值一致，不受应用与数据库时钟偏差影响
+func (s *PostgresStorage) SampleRecentMarketData(ctx context.Context, from, to time.Duration, percent, limit int) ([]models.MarketData, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, symbol, price, volume, timestamp, source
+		FROM market_data
+		WHERE created_at >= LOCALTIMESTAMP - make_interval(secs => $1)
+			AND created_at < LOCALTIMESTAMP - make_interval(secs => $2)
+			AND random() * 100 < $3
+		LIMIT $4
+	`, from.Seconds(), to.Seconds(), percent, limit)
+	if err != nil {
+		return nil, errx.Wrap(wrapDBError(err), "failed to sample market data")
+	}
+	defer rows.Close()
+
+	var data []models.MarketData
+	for rows.Next() {
+		var d models.MarketData
+		if err := rows.Scan(&d.ID, &d.Symbol, &d.Price, &d.Volume, &d.Timestamp, &d.Source); err != nil {
+			return nil, errx.Wrap(err, "failed to scan sampled market data")
+		}
+		data = append(data, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating sampled market data rows")
+	}
+	return data, nil
+}
+
+// GetCrossSourcePrices 获取各数据源在at前后tolerance范围内距at最近的价格，按数据源返回
+// 与at距离相同时取较早的数据，范围内没有数据的数据源不出现在结果中
+func (s *PostgresStorage) GetCrossSourcePrices(ctx context.Context, symbol string, at time.Time, tolerance time.Duration) (map[string]float64, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT ON (source) source, price
+		FROM market_data
+		WHERE symbol = $1 AND timestamp BETWEEN $2 AND $3
+		ORDER BY source, ABS(EXTRACT(EPOCH FROM (timestamp - $4))), timestamp
+	`, symbol, at.Add(-tolerance), at.Add(tolerance), at)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query cross-source prices")
+	}
+	defer rows.Close()
+
+	prices := make(map[string]float64)
+	for rows.Next() {
+		var source string
+		var price float64
+		if err := rows.Scan(&source, &price); err != nil {
+			return nil, errx.Wrap(err, "failed to scan cross-source price")
+		}
+		prices[source] = price
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating cross-source price rows")
+	}
+
+	return prices, nil
+}
+
+// ErrZeroVolume 时间范围内成交量为0（或没有数据），无法计算VWAP
+var ErrZeroVolume = errors.New("total volume is zero")
+
+// GetVWAP 计算[start, end]内的成交量加权平均价 SUM(price*volume)/SUM(volume)
+func (s *PostgresStorage) GetVWAP(ctx context.Context, symbol string, start, end time.Time) (float64, error) {
+	var notional, volume float64
+	err := s.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(price * volume), 0), COALESCE(SUM(volume), 0)
+		FROM market_data
+		WHERE symbol = $1 AND timestamp BETWEEN $2 AND $3
+	`, symbol, start, end).Scan(&notional, &volume)
+	if err != nil {
+		return 0, errx.Wrap(err, "failed to query vwap")
+	}
+	return vwap(notional, volume)
+}
+
+// vwap 由成交额和成交量计算VWAP，成交量为0时返回ErrZeroVolume
+func vwap(notional, volume float64) (float64, error) {
+	if volume <= 0 {
+		return 0, ErrZeroVolume
+	}
+	return notional / volume, nil
+}
+
+// 成交量分布的价格区间数范围
+const (
+	MinVolumeProfileBuckets = 1
+	MaxVolumeProfileBuckets = 500
+)
+
+// GetVolumeProfile 统计交易对在[start, end]内的成交量分布：将[最低价, 最高价]等分为buckets个价格区间，
+// 用width_bucket将每条数据归入所在区间并汇总成交量，按价格升序返回全部区间（没有成交的区间成交量为0）
+// 范围内没有数据时返回空切片；所有价格相同时只返回一个区间
+func (s *PostgresStorage) GetVolumeProfile(ctx context.Context, symbol string, start, end time.Time, buckets int) ([]models.PriceVolumeBucket, error) {
+	if buckets < MinVolumeProfileBuckets || buckets > MaxVolumeProfileBuckets {
+		return nil, fmt.Errorf("%w: buckets must be between %d and %d", ErrInvalidMarketDataQuery, MinVolumeProfileBuckets, MaxVolumeProfileBuckets)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalidMarketDataQuery)
+	}
+
+	// 最高价的width_bucket为buckets+1，计入最后一个区间；价格范围为0时width_bucket报错，全部计入第一个区间
+	rows, err := s.pool.Query(ctx, `
+		WITH ticks AS (
+			SELECT price, volume
+			FROM market_data
+			WHERE symbol = $1 AND timestamp BETWEEN $2 AND $3
+		), bounds AS (
+			SELECT MIN(price) AS lo, MAX(price) AS hi FROM ticks
+		)
+		SELECT b.lo, b.hi,
+			CASE WHEN b.hi > b.lo THEN LEAST(width_bucket(t.price, b.lo, b.hi, $4::int), $4::int) ELSE 1 END AS bucket,
+			SUM(t.volume), COUNT(*)
+		FROM ticks t CROSS JOIN bounds b
+		GROUP BY b.lo, b.hi, bucket
+		ORDER BY bucket
+	`, symbol, start, end, buckets)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query volume profile")
+	}
+	defer rows.Close()
+
+	var lo, hi float64
+	var counts []volumeBucketCount
+	for rows.Next() {
+		var c volumeBucketCount
+		if err := rows.Scan(&lo, &hi, &c.bucket, &c.volume, &c.ticks); err != nil {
+			return nil, errx.Wrap(err, "failed to scan volume profile")
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating volume profile rows")
+	}
+	return buildVolumeProfile(lo, hi, buckets, counts), nil
+}
+
+// volumeBucketCount 一个价格区间（从1开始编号）的成交量和数据条数
+type volumeBucketCount struct {
+	bucket int
+	volume float64
+	ticks  int
+}
+
+// buildVolumeProfile 由各区间的汇总结果生成[lo, hi]内等宽的buckets个价格区间，counts为空时返回空切片
+func buildVolumeProfile(lo, hi float64, buckets int, counts []volumeBucketCount) []models.PriceVolumeBucket {
+	if len(counts) == 0 {
+		return []models.PriceVolumeBucket{}
+	}
+	if hi <= lo {
+		buckets = 1
+	}
+
+	profile := make([]models.PriceVolumeBucket, buckets)
+	width := (hi - lo) / float64(buckets)
+	for i := range profile {
+		profile[i].PriceLow = lo + float64(i)*width
+		profile[i].PriceHigh = lo + float64(i+1)*width
+	}
+	profile[buckets-1].PriceHigh = hi
+
+	for _, c := range counts {
+		i := min(max(c.bucket, 1), buckets) - 1
+		profile[i].Volume += c.volume
+		profile[i].Ticks += c.ticks
+	}
+	return profile
+}
+
+// CorrelationBucket 计算收益率相关系数时对齐两个交易对价格序列的时间桶
+const CorrelationBucket = time.Minute
+
+// MinCorrelationPoints 计算相关系数至少需要的重叠收益率点数
+const MinCorrelationPoints = 3
+
+// ErrInsufficientOverlap 两个交易对在时间范围内重叠的数据点不足，无法计算相关系数
+var ErrInsufficientOverlap = errors.New("insufficient overlapping data")
+
+// ErrZeroVariance 收益率没有波动（价格不变），相关系数没有定义
+var ErrZeroVariance = errors.New("returns have zero variance")
+
+// GetReturnCorrelation 计算两个交易对在[start, end]内收益率的皮尔逊相关系数，返回相关系数和重叠的收益率点数
+// 两个价格序列按CorrelationBucket分桶（从start开始对齐），每个桶取最晚的价格，只保留两者都有价格的桶，
+// 收益率为相邻共同桶之间的价格变化率
+func (s *PostgresStorage) GetReturnCorrelation(ctx context.Context, symbolA, symbolB string, start, end time.Time) (float64, int, error) {
+	if end.Before(start) {
+		return 0, 0, fmt.Errorf("%w: end must be after start", ErrInvalidMarketDataQuery)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		WITH binned AS (
+			SELECT DISTINCT ON (symbol, bucket) symbol, price, bucket
+			FROM (
+				SELECT id, symbol, price, timestamp,
+					date_bin($3 * INTERVAL '1 microsecond', timestamp, $4) AS bucket
+				FROM market_data
+				WHERE symbol IN ($1, $2) AND timestamp BETWEEN $4 AND $5
+			) raw
+			ORDER BY symbol, bucket, timestamp DESC, id DESC
+		)
+		SELECT a.price, b.price
+		FROM binned a
+		JOIN binned b ON b.bucket = a.bucket AND b.symbol = $2
+		WHERE a.symbol = $1
+		ORDER BY a.bucket
+	`, symbolA, symbolB, CorrelationBucket.Microseconds(), start, end)
+	if err != nil {
+		return 0, 0, errx.Wrap(err, "failed to query aligned prices")
+	}
+	defer rows.Close()
+
+	var pricesA, pricesB []float64
+	for rows.Next() {
+		var a, b float64
+		if err := rows.Scan(&a, &b); err != nil {
+			return 0, 0, errx.Wrap(err, "failed to scan aligned prices")
+		}
+		pricesA = append(pricesA, a)
+		pricesB = append(pricesB, b)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, errx.Wrap(err, "error iterating aligned prices")
+	}
+
+	return returnCorrelation(pricesA, pricesB)
+}
+
+// returnCorrelation 由两个对齐的价格序列计算收益率的皮尔逊相关系数和收益率点数
+func returnCorrelation(pricesA, pricesB []float64) (float64, int, error) {
+	returnsA := priceReturns(pricesA)
+	returnsB := priceReturns(pricesB)
+	points := len(returnsA)
+	if points < MinCorrelationPoints {
+		return 0, points, fmt.Errorf("%w: %d overlapping returns, need at least %d", ErrInsufficientOverlap, points, MinCorrelationPoints)
+	}
+
+	var meanA, meanB float64
+	for i := range returnsA {
+		meanA += returnsA[i]
+		meanB += returnsB[i]
+	}
+	meanA /= float64(points)
+	meanB /= float64(points)
+
+	var cov, varA, varB float64
+	for i := range returnsA {
+		da, db := returnsA[i]-meanA, returnsB[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0, points, ErrZeroVariance
+	}
+	return cov / math.Sqrt(varA*varB), points, nil
+}
+
+// priceReturns 计算相邻价格的变化率，前一个价格为0时记为0
+func priceReturns(prices []float64) []float64 {
+	if len(prices) < 2 {
+		return nil
+	}
+	returns := make([]float64, len(prices)-1)
+	for i := 1; i < len(prices); i++ {
+		if prices[i-1] != 0 {
+			returns[i-1] = prices[i]/prices[i-1] - 1
+		}
+	}
+	return returns
+}
+
+// CandleIntervals GetOHLCV支持的K线周期
+var CandleIntervals = []time.Duration{time.Minute, 5 * time.Minute, time.Hour, 24 * time.Hour}
+
+// validateCandleInterval 校验K线周期是否受支持
+func validateCandleInterval(interval time.Duration) error {
+	for _, supported := range CandleIntervals {
+		if interval == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unsupported candle interval %v, use 1m, 5m, 1h or 1d", ErrInvalidMarketDataQuery, interval)
+}
+
+// GetOHLCV 将[startTime, endTime]内的市场数据按interval聚合为K线，按开盘时间升序
+// 时间窗口按UTC纪元对齐，开盘价和收盘价分别为窗口内最早和最晚的成交价，成交量为窗口内合计
+func (s *PostgresStorage) GetOHLCV(symbol string, interval time.Duration, startTime, endTime time.Time) ([]models.Candle, error) {
+	if err := validateCandleInterval(interval); err != nil {
+		return nil, err
+	}
+	if endTime.Before(startTime) {
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalidMarketDataQuery)
+	}
+
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT to_timestamp(bucket::double precision) AT TIME ZONE 'UTC' AS open_time,
+			(array_agg(price ORDER BY timestamp ASC, id ASC))[1] AS open,
+			MAX(price) AS high,
+			MIN(price) AS low,
+			(array_agg(price ORDER BY timestamp DESC, id DESC))[1] AS close,
+			SUM(volume) AS volume
+		FROM (
+			SELECT id, price, volume, timestamp,
+				floor(extract(epoch FROM timestamp) / $2::bigint) * $2::bigint AS bucket
+			FROM market_data
+			WHERE symbol = $1 AND timestamp BETWEEN $3 AND $4
+		) ticks
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`, symbol, int64(interval/time.Second), startTime, endTime)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query ohlcv")
+	}
+	defer rows.Close()
+
+	candles := []models.Candle{}
+	for rows.Next() {
+		var c models.Candle
+		if err := rows.Scan(&c.OpenTime, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume); err != nil {
+			return nil, errx.Wrap(err, "failed to scan ohlcv")
+		}
+		candles = append(candles, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating ohlcv rows")
+	}
+	return candles, nil
+}
+
+// truncateHistoricalData 将从offset开始的一页结果截断到maxRows行，maxRows<=0表示不限制
+func truncateHistoricalData(data []models.MarketData, maxRows, offset int) *models.HistoricalDataResult {
+	if maxRows <= 0 || len(data) <= maxRows {
+		return &models.HistoricalDataResult{Data: data}
+	}
+
+	nextStart := data[maxRows].Timestamp
+	nextOffset := offset + maxRows
+	return &models.HistoricalDataResult{
+		Data:       data[:maxRows],
+		HasMore:    true,
+		NextOffset: &nextOffset,
+		Truncated:  true,
+		NextStart:  &nextStart,
+	}
+}
+
+// PingPool 从连接池获取一个连接并检查数据库连通性
+func (s *PostgresStorage) PingPool(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// PoolStats 返回连接池的连接数和获取连接的统计
+func (s *PostgresStorage) PoolStats() models.DBPoolStats {
+	stat := s.pool.Stat()
+	return models.DBPoolStats{
+		TotalConns:        stat.TotalConns(),
+		IdleConns:         stat.IdleConns(),
+		AcquiredConns:     stat.AcquiredConns(),
+		MaxConns:          stat.MaxConns(),
+		AcquireCount:      stat.AcquireCount(),
+		EmptyAcquireCount: stat.EmptyAcquireCount(),
+		AcquireDuration:   stat.AcquireDuration().String(),
+	}
+}
+
+// Close 关闭存储
+func (s *PostgresStorage) Close() {
+	if s.pool != nil {
+		s.pool.Close()
+		logrus.Info("PostgreSQL connection pool closed")
+	}
+}
+
+// SaveStockBasic 保存股票基础信息
+func (s *PostgresStorage) SaveStockBasic(data []models.StockBasic) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	// 使用批量插入
+	tx, err := s.pool.Begin(context.Background())
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(context.Background())
+
+	// 只在字段实际变化时更新，重复保存相同数据（如重试）不会改变updated_at，保证按updated_at检测变更有效
+	query := `
+		INSERT INTO stock_basic (
+			ts_code, symbol, name, area, industry, fullname, enname, cnspell, 
+			market, exchange, curr_type, list_status, list_date, delist_date, is_hs, 
+			act_name, act_ent_type, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, CURRENT_TIMESTAMP
+		) ON CONFLICT (ts_code) DO UPDATE SET
+			symbol = $2, name = $3, area = $4, industry = $5, fullname = $6, enname = $7, cnspell = $8, 
+			market = $9, exchange = $10, curr_type = $11, list_status = $12, list_date = $13, delist_date = $14, is_hs = $15, 
+			act_name = $16, act_ent_type = $17, updated_at = CURRENT_TIMESTAMP
+		WHERE (
+			stock_basic.symbol, stock_basic.name, stock_basic.area, stock_basic.industry, stock_basic.fullname,
+			stock_basic.enname, stock_basic.cnspell, stock_basic.market, stock_basic.exchange, stock_basic.curr_type,
+			stock_basic.list_status, stock_basic.list_date, stock_basic.delist_date, stock_basic.is_hs,
+			stock_basic.act_name, stock_basic.act_ent_type
+		) IS DISTINCT FROM (
+			EXCLUDED.symbol, EXCLUDED.name, EXCLUDED.area, EXCLUDED.industry, EXCLUDED.fullname,
+			EXCLUDED.enname, EXCLUDED.cnspell, EXCLUDED.market, EXCLUDED.exchange, EXCLUDED.curr_type,
+			EXCLUDED.list_status, EXCLUDED.list_date, EXCLUDED.delist_date, EXCLUDED.is_hs,
+			EXCLUDED.act_name, EXCLUDED.act_ent_type
+		)
+	`
+
+	var changed int64
+	for _, d := range data {
+		tag, err := tx.Exec(context.Background(), query,
+			d.TSCode, d.Symbol, d.Name, d.Area, d.Industry, d.Fullname, d.Enname, d.Cnspell,
+			d.Market, d.Exchange, d.CurrType, d.ListStatus, d.ListDate, d.DelistDate, d.IsHS,
+			d.ActName, d.ActEntType,
+		)
+		if err != nil {
+			return errx.Wrap(err, "failed to insert stock basic data")
+		}
+		changed += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+
+	logrus.Infof("Saved %d stock basic data records (%d inserted or changed)", len(data), changed)
+	return nil
+}
+
+// GetStockBasic 获取股票基础信息
+func (s *PostgresStorage) GetStockBasic(limit int) ([]models.StockBasic, error) {
+	return s.QueryStockBasic(context.Background(), StockBasicQuery{Limit: limit})
+}
+
+// StockBasicQuery 股票基础信息查询选项，零值字段表示不限制，结果按ts_code升序
+type StockBasicQuery struct {
+	Market     string
+	Exchange   string
+	ListStatus string
+	Limit      int
+	Offset     int
+}
+
+// stockBasicFilter 根据查询选项的过滤条件生成WHERE子句和参数，没有过滤条件时WHERE子句为空
+func stockBasicFilter(q StockBasicQuery) (string, []any) {
+	var args []any
+	var conditions []string
+	addCondition := func(column, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	addCondition("market", q.Market)
+	addCondition("exchange", q.Exchange)
+	addCondition("list_status", q.ListStatus)
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// buildStockBasicQuery 根据查询选项生成参数化SQL和参数，选项中的值只通过参数传递
+func buildStockBasicQuery(q StockBasicQuery) (string, []any) {
+	where, args := stockBasicFilter(q)
+
+	// 查询列和扫描字段都由models.StockBasic的db标签生成
+	var sb strings.Builder
+	sb.WriteString("SELECT " + stockBasicSelect + " FROM stock_basic" + where)
+	sb.WriteString(" ORDER BY ts_code ASC")
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		fmt.Fprintf(&sb, " LIMIT $%d", len(args))
+	}
+	if q.Offset > 0 {
+		args = append(args, q.Offset)
+		fmt.Fprintf(&sb, " OFFSET $%d", len(args))
+	}
+	return sb.String(), args
+}
+
+// CountStockBasic 统计符合过滤条件的股票数，忽略Limit和Offset
+func (s *PostgresStorage) CountStockBasic(ctx context.Context, q StockBasicQuery) (int64, error) {
+	where, args := stockBasicFilter(q)
+	var count int64
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM stock_basic"+where, args...).Scan(&count); err != nil {
+		return 0, errx.Wrap(err, "failed to count stock basic data")
+	}
+	return count, nil
+}
+
+// QueryStockBasic 按市场、交易所和上市状态获取股票基础信息
+func (s *PostgresStorage) QueryStockBasic(ctx context.Context, q StockBasicQuery) ([]models.StockBasic, error) {
+	query, args := buildStockBasicQuery(q)
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query stock basic data")
+	}
+	defer rows.Close()
+
+	var data []models.StockBasic
+	for rows.Next() {
+		var d models.StockBasic
+		if err := rows.Scan(scanTargets(&d)...); err != nil {
+			return nil, errx.Wrap(err, "failed to scan stock basic data")
+		}
+		data = append(data, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating stock basic data rows")
+	}
+
+	return data, nil
+}
+
+// SaveOHLCVDailyQFQ 保存前复权日线行情数据（匹配现有ohlcv_daily_qfq表schema）
+func (s *PostgresStorage) SaveOHLCVDailyQFQ(data []models.OHLCVDailyQFQ) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	tx, err := s.pool.Begin(context.Background())
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(context.Background())
+
+	query := `
+		INSERT INTO ohlcv_daily_qfq (symbol, trade_date, open, high, low, close, volume, turnover, trade_days, created_at)
+		VALUES ($1, $2::date, $3, $4, $5, $6, $7, $8, 0, CURRENT_TIMESTAMP)
+		ON CONFLICT (symbol, trade_date) DO NOTHING
+	`
+
+	for _, d := range data {
+		_, err := tx.Exec(context.Background(), query,
+			d.Symbol, d.TradeDate, d.Open, d.High, d.Low, d.Close, d.Volume, d.Turnover,
+		)
+		if err != nil {
+			return errx.Wrap(err, "failed to insert ohlcv_daily_qfq")
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+
+	logrus.Debugf("Saved %d ohlcv_daily_qfq records", len(data))
+	return nil
+}
+
+// GetOHLCVCountBySymbol 获取指定股票已存在的OHLCV记录数
+func (s *PostgresStorage) GetOHLCVCountBySymbol(tsCode string) (int64, error) {
+	var count int64
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM ohlcv_daily_qfq WHERE symbol = $1
+	`, tsCode).Scan(&count)
+	if err != nil {
+		return 0, errx.Wrap(err, "failed to count ohlcv_daily_qfq")
+	}
+	return count, nil
+}
+
+// GetExistingDateRangeForSymbol 获取指定股票已存在的日期范围
+func (s *PostgresStorage) GetExistingDateRangeForSymbol(tsCode string) (string, string, error) {
+	var minDate, maxDate sql.NullString
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT MIN(trade_date::text), MAX(trade_date::text) FROM ohlcv_daily_qfq WHERE symbol = $1
+	`, tsCode).Scan(&minDate, &maxDate)
+	if err != nil {
+		return "", "", nil
+	}
+	if !minDate.Valid {
+		return "", "", nil
+	}
+	return minDate.String, maxDate.String, nil
+}
+
+// GetAllStockCodes 获取所有股票的symbol（从stocks表）
+func (s *PostgresStorage) GetAllStockCodes() ([]string, error) {
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT symbol FROM stocks ORDER BY symbol
+	`)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query stock codes")
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, errx.Wrap(err, "failed to scan stock code")
+		}
+		codes = append(codes, code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating stock codes")
+	}
+	return codes, nil
+}
+
+// GetStockListDate 获取股票上市日期
+func (s *PostgresStorage) GetStockListDate(symbol string) (string, error) {
+	var listDate sql.NullString
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT list_date::text FROM stocks WHERE symbol = $1
+	`, symbol).Scan(&listDate)
+	if err != nil {
+		return "", nil
+	}
+	if !listDate.Valid {
+		return "", nil
+	}
+	return listDate.String, nil
+}
+
+// DefaultExchange 未指定交易所时使用的交易所，与Tushare trade_cal接口的默认值一致
+const DefaultExchange = "SSE"
+
+// ErrInvalidDateRange 日期范围不合法
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+// validateDateRange 校验YYYYMMDD格式的日期范围，end不能早于start
+func validateDateRange(start, end string) error {
+	startDate, err := time.Parse("20060102", start)
+	if err != nil {
+		return fmt.Errorf("%w: start %q must be YYYYMMDD", ErrInvalidDateRange, start)
+	}
+	endDate, err := time.Parse("20060102", end)
+	if err != nil {
+		return fmt.Errorf("%w: end %q must be YYYYMMDD", ErrInvalidDateRange, end)
+	}
+	if endDate.Before(startDate) {
+		return fmt.Errorf("%w: end must not be before start", ErrInvalidDateRange)
+	}
+	return nil
+}
+
+// GetOpenTradeDates 获取交易所在[start, end]内的交易日（YYYYMMDD），按日期升序，exchange为空时使用SSE
+func (s *PostgresStorage) GetOpenTradeDates(ctx context.Context, exchange, start, end string) ([]string, error) {
+	if err := validateDateRange(start, end); err != nil {
+		return nil, err
+	}
+	if exchange == "" {
+		exchange = DefaultExchange
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT cal_date
+		FROM trade_cal
+		WHERE exchange = $1 AND cal_date BETWEEN $2 AND $3 AND is_open = '1'
+		ORDER BY cal_date ASC
+	`, exchange, start, end)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query open trade dates")
+	}
+	defer rows.Close()
+
+	dates := []string{}
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, errx.Wrap(err, "failed to scan trade date")
+		}
+		dates = append(dates, date)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating trade date rows")
+	}
+	return dates, nil
+}
+
+// ErrInvalidTradeCal 交易日历数据不合法
+var ErrInvalidTradeCal = errors.New("invalid trade calendar")
+
+// validateTradeCal 校验交易日历：cal_date为8位数字，is_open为"0"或"1"
+func validateTradeCal(d models.TradeCal) error {
+	if len(d.CalDate) != 8 || strings.Trim(d.CalDate, "0123456789") != "" {
+		return fmt.Errorf("%w: cal_date %q must be 8 digits", ErrInvalidTradeCal, d.CalDate)
+	}
+	if d.IsOpen != "0" && d.IsOpen != "1" {
+		return fmt.Errorf("%w: is_open %q must be \"0\" or \"1\" for %s", ErrInvalidTradeCal, d.IsOpen, d.CalDate)
+	}
+	return nil
+}
+
+// SaveTradeCalendar 批量保存交易日历，同时写入trade_calendar和按交易所区分的trade_cal
+func (s *PostgresStorage) SaveTradeCalendar(data []models.TradeCal) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	// is_open按TradeCal.Open规范化为"1"或"0"
+	normalized := make([]models.TradeCal, len(data))
+	for i, d := range data {
+		if d.Open() {
+			d.IsOpen = "1"
+		} else {
+			d.IsOpen = "0"
+		}
+		if err := validateTradeCal(d); err != nil {
+			return err
+		}
+		normalized[i] = d
+	}
+
+	tx, err := s.pool.Begin(context.Background())
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(context.Background())
+
+	query := `
+		INSERT INTO trade_calendar (trade_date, is_trading_day, created_at)
+		VALUES ($1::date, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (trade_date) DO UPDATE SET is_trading_day = $2
+	`
+
+	for _, d := range normalized {
+		_, err := tx.Exec(context.Background(), query, d.CalDate, d.Open())
+		if err != nil {
+			return errx.Wrap(err, "failed to insert trade_calendar")
+		}
+	}
+	if err := saveTradeCal(context.Background(), tx, normalized); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+
+	logrus.Infof("Saved %d trade_calendar records", len(data))
+	return nil
+}
+
+// SaveTradeCal 批量保存按交易所区分的交易日历，重复保存同一日期范围时更新已有记录，exchange为空时使用SSE
+func (s *PostgresStorage) SaveTradeCal(data []models.TradeCal) error {
+	if len(data) == 0 {
+		return nil
+	}
+	for _, d := range data {
+		if err := validateTradeCal(d); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.pool.Begin(context.Background())
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := saveTradeCal(context.Background(), tx, data); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+
+	logrus.Infof("Saved %d trade_cal records", len(data))
+	return nil
+}
+
+// saveTradeCal 在事务中写入trade_cal，调用方需先校验数据
+func saveTradeCal(ctx context.Context, tx pgx.Tx, data []models.TradeCal) error {
+	query := `
+		INSERT INTO trade_cal (exchange, cal_date, is_open, pre_trade_date, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (exchange, cal_date) DO UPDATE SET
+			is_open = $3, pre_trade_date = $4, updated_at = CURRENT_TIMESTAMP
+	`
+	for _, d := range data {
+		exchange := d.Exchange
+		if exchange == "" {
+			exchange = DefaultExchange
+		}
+		if _, err := tx.Exec(ctx, query, exchange, d.CalDate, d.IsOpen, d.PreTradeDate); err != nil {
+			return errx.Wrap(err, "failed to insert trade_cal")
+		}
+	}
+	return nil
+}
+
+// GetTradeCal 获取交易所在[startDate, endDate]内的交易日历（YYYYMMDD），按日期升序，exchange为空时使用SSE
+func (s *PostgresStorage) GetTradeCal(exchange string, startDate, endDate string) ([]models.TradeCal, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	if exchange == "" {
+		exchange = DefaultExchange
+	}
+
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT exchange, cal_date, is_open, COALESCE(pre_trade_date, ''), created_at, updated_at
+		FROM trade_cal
+		WHERE exchange = $1 AND cal_date BETWEEN $2 AND $3
+		ORDER BY cal_date ASC
+	`, exchange, startDate, endDate)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query trade_cal")
+	}
+	defer rows.Close()
+
+	result := []models.TradeCal{}
+	for rows.Next() {
+		var d models.TradeCal
+		if err := rows.Scan(&d.Exchange, &d.CalDate, &d.IsOpen, &d.PreTradeDate, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, errx.Wrap(err, "failed to scan trade_cal")
+		}
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating trade_cal rows")
+	}
+	return result, nil
+}
+
+// SaveAdjFactors 保存复权因子
+func (s *PostgresStorage) SaveAdjFactors(data []models.AdjFactor) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	tx, err := s.pool.Begin(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return errx.Wrap(err, "failed to begin transaction")
 	}
 	defer tx.Rollback(context.Background())
 
 	query := `
-		INSERT INTO ohlcv_daily_qfq (symbol, trade_date, open, high, low, close, volume, turnover, trade_days, created_at)
-		VALUES ($1, $2::date, $3, $4, $5, $6, $7, $8, 0, CURRENT_TIMESTAMP)
-		ON CONFLICT (symbol, trade_date) DO NOTHING
+		INSERT INTO adj_factor (ts_code, trade_date, adj_factor, created_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (ts_code, trade_date) DO UPDATE SET adj_factor = $3
 	`
 
 	for _, d := range data {
-		_, err := tx.Exec(context.Background(), query,
-			d.Symbol, d.TradeDate, d.Open, d.High, d.Low, d.Close, d.Volume, d.Turnover,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to insert ohlcv_daily_qfq: %w", err)
+		if _, err := tx.Exec(context.Background(), query, d.TSCode, d.TradeDate, d.AdjFactor); err != nil {
+			return errx.Wrap(err, "failed to insert adj_factor")
 		}
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return errx.Wrap(err, "failed to commit transaction")
 	}
 
-	logrus.Debugf("Saved %d ohlcv_daily_qfq records", len(data))
+	logrus.Debugf("Saved %d adj_factor records", len(data))
 	return nil
 }
 
-// GetOHLCVCountBySymbol 获取指定股票已存在的OHLCV记录数
-func (s *PostgresStorage) GetOHLCVCountBySymbol(tsCode string) (int64, error) {
-	var count int64
-	err := s.pool.QueryRow(context.Background(), `
-		SELECT COUNT(*) FROM ohlcv_daily_qfq WHERE symbol = $1
-	`, tsCode).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count ohlcv_daily_qfq: %w", err)
+// SaveMoneyflow 保存个股资金流向
+func (s *PostgresStorage) SaveMoneyflow(data []models.Moneyflow) error {
+	if len(data) == 0 {
+		return nil
 	}
-	return count, nil
-}
 
-// GetExistingDateRangeForSymbol 获取指定股票已存在的日期范围
-func (s *PostgresStorage) GetExistingDateRangeForSymbol(tsCode string) (string, string, error) {
-	var minDate, maxDate sql.NullString
-	err := s.pool.QueryRow(context.Background(), `
-		SELECT MIN(trade_date::text), MAX(trade_date::text) FROM ohlcv_daily_qfq WHERE symbol = $1
-	`, tsCode).Scan(&minDate, &maxDate)
+	tx, err := s.pool.Begin(context.Background())
 	if err != nil {
-		return "", "", nil
+		return errx.Wrap(err, "failed to begin transaction")
 	}
-	if !minDate.Valid {
-		return "", "", nil
+	defer tx.Rollback(context.Background())
+
+	query := `
+		INSERT INTO moneyflow (
+			ts_code, trade_date,
+			buy_sm_vol, buy_sm_amount, sell_sm_vol, sell_sm_amount,
+			buy_md_vol, buy_md_amount, sell_md_vol, sell_md_amount,
+			buy_lg_vol, buy_lg_amount, sell_lg_vol, sell_lg_amount,
+			buy_elg_vol, buy_elg_amount, sell_elg_vol, sell_elg_amount,
+			net_mf_vol, net_mf_amount, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, CURRENT_TIMESTAMP
+		) ON CONFLICT (ts_code, trade_date) DO UPDATE SET
+			buy_sm_vol = $3, buy_sm_amount = $4, sell_sm_vol = $5, sell_sm_amount = $6,
+			buy_md_vol = $7, buy_md_amount = $8, sell_md_vol = $9, sell_md_amount = $10,
+			buy_lg_vol = $11, buy_lg_amount = $12, sell_lg_vol = $13, sell_lg_amount = $14,
+			buy_elg_vol = $15, buy_elg_amount = $16, sell_elg_vol = $17, sell_elg_amount = $18,
+			net_mf_vol = $19, net_mf_amount = $20
+	`
+
+	for _, d := range data {
+		_, err := tx.Exec(context.Background(), query,
+			d.TSCode, d.TradeDate,
+			d.BuySmVol, d.BuySmAmount, d.SellSmVol, d.SellSmAmount,
+			d.BuyMdVol, d.BuyMdAmount, d.SellMdVol, d.SellMdAmount,
+			d.BuyLgVol, d.BuyLgAmount, d.SellLgVol, d.SellLgAmount,
+			d.BuyElgVol, d.BuyElgAmount, d.SellElgVol, d.SellElgAmount,
+			d.NetMfVol, d.NetMfAmount)
+		if err != nil {
+			return errx.Wrap(err, "failed to insert moneyflow")
+		}
 	}
-	return minDate.String, maxDate.String, nil
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+
+	logrus.Debugf("Saved %d moneyflow records", len(data))
+	return nil
 }
 
-// GetAllStockCodes 获取所有股票的symbol（从stocks表）
-func (s *PostgresStorage) GetAllStockCodes() ([]string, error) {
-	rows, err := s.pool.Query(context.Background(), `
-		SELECT symbol FROM stocks ORDER BY symbol
-	`)
+// GetMoneyflow 获取单只股票在日期范围内的资金流向，按交易日升序返回，日期格式为YYYYMMDD
+func (s *PostgresStorage) GetMoneyflow(ctx context.Context, tsCode, start, end string) ([]models.Moneyflow, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT ts_code, trade_date,
+			COALESCE(buy_sm_vol, 0), COALESCE(buy_sm_amount, 0), COALESCE(sell_sm_vol, 0), COALESCE(sell_sm_amount, 0),
+			COALESCE(buy_md_vol, 0), COALESCE(buy_md_amount, 0), COALESCE(sell_md_vol, 0), COALESCE(sell_md_amount, 0),
+			COALESCE(buy_lg_vol, 0), COALESCE(buy_lg_amount, 0), COALESCE(sell_lg_vol, 0), COALESCE(sell_lg_amount, 0),
+			COALESCE(buy_elg_vol, 0), COALESCE(buy_elg_amount, 0), COALESCE(sell_elg_vol, 0), COALESCE(sell_elg_amount, 0),
+			COALESCE(net_mf_vol, 0), COALESCE(net_mf_amount, 0), created_at
+		FROM moneyflow
+		WHERE ts_code = $1 AND trade_date BETWEEN $2 AND $3
+		ORDER BY trade_date ASC
+	`, tsCode, start, end)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query stock codes: %w", err)
+		return nil, errx.Wrap(err, "failed to query moneyflow")
 	}
 	defer rows.Close()
 
-	var codes []string
+	var data []models.Moneyflow
 	for rows.Next() {
-		var code string
-		if err := rows.Scan(&code); err != nil {
-			return nil, fmt.Errorf("failed to scan stock code: %w", err)
+		var d models.Moneyflow
+		if err := rows.Scan(&d.TSCode, &d.TradeDate,
+			&d.BuySmVol, &d.BuySmAmount, &d.SellSmVol, &d.SellSmAmount,
+			&d.BuyMdVol, &d.BuyMdAmount, &d.SellMdVol, &d.SellMdAmount,
+			&d.BuyLgVol, &d.BuyLgAmount, &d.SellLgVol, &d.SellLgAmount,
+			&d.BuyElgVol, &d.BuyElgAmount, &d.SellElgVol, &d.SellElgAmount,
+			&d.NetMfVol, &d.NetMfAmount, &d.CreatedAt); err != nil {
+			return nil, errx.Wrap(err, "failed to scan moneyflow")
 		}
-		codes = append(codes, code)
+		data = append(data, d)
 	}
+
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating stock codes: %w", err)
+		return nil, errx.Wrap(err, "error iterating moneyflow rows")
 	}
-	return codes, nil
+
+	return data, nil
 }
 
-// GetStockListDate 获取股票上市日期
-func (s *PostgresStorage) GetStockListDate(symbol string) (string, error) {
-	var listDate sql.NullString
-	err := s.pool.QueryRow(context.Background(), `
-		SELECT list_date::text FROM stocks WHERE symbol = $1
-	`, symbol).Scan(&listDate)
-	if err != nil {
-		return "", nil
+// ErrInvalidDaily 日线数据不合法
+var ErrInvalidDaily = errors.New("invalid daily bar")
+
+// validateDaily 校验日线：代码和日期不能为空，开高低收不能为负，最高价不能低于最低价
+func validateDaily(d models.Daily) error {
+	if d.TSCode == "" || d.TradeDate == "" {
+		return fmt.Errorf("%w: ts_code and trade_date are required", ErrInvalidDaily)
 	}
-	if !listDate.Valid {
-		return "", nil
+	if d.Open < 0 || d.High < 0 || d.Low < 0 || d.Close < 0 {
+		return fmt.Errorf("%w: negative price for %s %s", ErrInvalidDaily, d.TSCode, d.TradeDate)
 	}
-	return listDate.String, nil
+	if d.High < d.Low {
+		return fmt.Errorf("%w: high %v below low %v for %s %s", ErrInvalidDaily, d.High, d.Low, d.TSCode, d.TradeDate)
+	}
+	return nil
 }
 
-// SaveTradeCalendar 批量保存交易日历
-func (s *PostgresStorage) SaveTradeCalendar(data []models.TradeCal) error {
+// SaveDaily 批量保存A股日线行情，重复保存同一股票同一交易日时更新已有记录
+// 任一条数据校验失败时整批不保存
+func (s *PostgresStorage) SaveDaily(data []models.Daily) error {
 	if len(data) == 0 {
 		return nil
 	}
+	for _, d := range data {
+		if err := validateDaily(d); err != nil {
+			return err
+		}
+	}
 
 	tx, err := s.pool.Begin(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return errx.Wrap(err, "failed to begin transaction")
 	}
 	defer tx.Rollback(context.Background())
 
 	query := `
-		INSERT INTO trade_calendar (trade_date, is_trading_day, created_at)
-		VALUES ($1::date, $2, CURRENT_TIMESTAMP)
-		ON CONFLICT (trade_date) DO UPDATE SET is_trading_day = $2
+		INSERT INTO daily (
+			ts_code, trade_date, open, high, low, close, pre_close, change, pct_chg, vol, amount, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CURRENT_TIMESTAMP
+		) ON CONFLICT (ts_code, trade_date) DO UPDATE SET
+			open = $3, high = $4, low = $5, close = $6, pre_close = $7, change = $8, pct_chg = $9,
+			vol = $10, amount = $11, updated_at = CURRENT_TIMESTAMP
 	`
 
 	for _, d := range data {
-		isTrading := d.IsOpen == "1" || d.IsOpen == "true"
-		_, err := tx.Exec(context.Background(), query, d.CalDate, isTrading)
+		_, err := tx.Exec(context.Background(), query,
+			d.TSCode, d.TradeDate, d.Open, d.High, d.Low, d.Close, d.PreClose, d.Change, d.PctChg, d.Vol, d.Amount,
+		)
 		if err != nil {
-			return fmt.Errorf("failed to insert trade_calendar: %w", err)
+			return errx.Wrap(err, "failed to insert daily")
 		}
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return errx.Wrap(err, "failed to commit transaction")
 	}
 
-	logrus.Infof("Saved %d trade_calendar records", len(data))
+	logrus.Infof("Saved %d daily records", len(data))
+	if s.dailySaved != nil {
+		s.dailySaved(data)
+	}
 	return nil
 }
 
+// GetDaily 获取单只股票[startDate, endDate]内的日线行情（YYYYMMDD），按交易日升序
+func (s *PostgresStorage) GetDaily(tsCode, startDate, endDate string) ([]models.Daily, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT ts_code, trade_date,
+			COALESCE(open, 0), COALESCE(high, 0), COALESCE(low, 0), COALESCE(close, 0),
+			COALESCE(pre_close, 0), COALESCE(change, 0), COALESCE(pct_chg, 0),
+			COALESCE(vol, 0), COALESCE(amount, 0), created_at, updated_at
+		FROM daily
+		WHERE ts_code = $1 AND trade_date BETWEEN $2 AND $3
+		ORDER BY trade_date ASC
+	`, tsCode, startDate, endDate)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query daily")
+	}
+	defer rows.Close()
+
+	bars := []models.Daily{}
+	for rows.Next() {
+		var d models.Daily
+		if err := rows.Scan(&d.TSCode, &d.TradeDate, &d.Open, &d.High, &d.Low, &d.Close,
+			&d.PreClose, &d.Change, &d.PctChg, &d.Vol, &d.Amount, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, errx.Wrap(err, "failed to scan daily")
+		}
+		bars = append(bars, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating daily rows")
+	}
+	return bars, nil
+}
+
+// GetStoredTradeDates 获取单只股票已保存日线的交易日（YYYYMMDD），去重后按日期升序，用于和交易日历对比找出缺失的日线
+func (s *PostgresStorage) GetStoredTradeDates(ctx context.Context, tsCode string) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT trade_date
+		FROM daily
+		WHERE ts_code = $1
+		ORDER BY trade_date ASC
+	`, tsCode)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query stored trade dates")
+	}
+	defer rows.Close()
+
+	dates := []string{}
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, errx.Wrap(err, "failed to scan stored trade date")
+		}
+		dates = append(dates, date)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating stored trade date rows")
+	}
+	return dates, nil
+}
+
+// FindMissingTradingDays 获取交易所在[start, end]内开市、但该股票没有日线的交易日（YYYYMMDD），按日期升序，exchange为空时使用SSE
+// 用于回补时只拉取缺失的日期；交易日历未同步的日期不会被视为缺失
+func (s *PostgresStorage) FindMissingTradingDays(ctx context.Context, tsCode, exchange, start, end string) ([]string, error) {
+	if err := validateDateRange(start, end); err != nil {
+		return nil, err
+	}
+	if exchange == "" {
+		exchange = DefaultExchange
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT c.cal_date
+		FROM trade_cal c
+		WHERE c.exchange = $1 AND c.cal_date BETWEEN $2 AND $3 AND c.is_open = '1'
+			AND NOT EXISTS (
+				SELECT 1 FROM daily d WHERE d.ts_code = $4 AND d.trade_date = c.cal_date
+			)
+		ORDER BY c.cal_date ASC
+	`, exchange, start, end, tsCode)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query missing trading days")
+	}
+	defer rows.Close()
+
+	dates := []string{}
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, errx.Wrap(err, "failed to scan missing trading day")
+		}
+		dates = append(dates, date)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating missing trading day rows")
+	}
+	return dates, nil
+}
+
+// ErrInvalidWindow 滚动窗口交易日数不合法
+var ErrInvalidWindow = errors.New("window must be positive")
+
+// ErrNoDailyData 指定条件下没有日线数据
+var ErrNoDailyData = errors.New("no daily data")
+
+// GetRollingHighLow 获取截至asOf（含，YYYYMMDD）最近window个交易日的最高价和最低价，asOf为空时截至最新交易日
+func (s *PostgresStorage) GetRollingHighLow(ctx context.Context, tsCode string, window int, asOf string) (high, low float64, err error) {
+	if window <= 0 {
+		return 0, 0, ErrInvalidWindow
+	}
+	if asOf != "" {
+		if _, err := time.Parse("20060102", asOf); err != nil {
+			return 0, 0, fmt.Errorf("%w: as_of %q must be YYYYMMDD", ErrInvalidDateRange, asOf)
+		}
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT ts_code, trade_date, high, low
+		FROM daily
+		WHERE ts_code = $1 AND ($2 = '' OR trade_date <= $2)
+			AND high IS NOT NULL AND low IS NOT NULL
+		ORDER BY trade_date DESC
+		LIMIT $3
+	`, tsCode, asOf, window)
+	if err != nil {
+		return 0, 0, errx.Wrap(err, "failed to query daily high/low")
+	}
+	defer rows.Close()
+
+	var bars []models.Daily
+	for rows.Next() {
+		var d models.Daily
+		if err := rows.Scan(&d.TSCode, &d.TradeDate, &d.High, &d.Low); err != nil {
+			return 0, 0, errx.Wrap(err, "failed to scan daily high/low")
+		}
+		bars = append(bars, d)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, errx.Wrap(err, "error iterating daily high/low rows")
+	}
+
+	return rollingHighLow(bars)
+}
+
+// rollingHighLow 计算一组日线的最高价和最低价，没有数据时返回ErrNoDailyData
+func rollingHighLow(bars []models.Daily) (high, low float64, err error) {
+	if len(bars) == 0 {
+		return 0, 0, ErrNoDailyData
+	}
+	high, low = bars[0].High, bars[0].Low
+	for _, bar := range bars[1:] {
+		high = math.Max(high, bar.High)
+		low = math.Min(low, bar.Low)
+	}
+	return high, low, nil
+}
+
+// GetSplitAdjustedDaily 获取按复权因子调整后的日线行情，日期格式为YYYYMMDD
+// 价格以区间内最新的复权因子为基准进行前复权，拆股/送转前后的价格序列保持连续
+func (s *PostgresStorage) GetSplitAdjustedDaily(ctx context.Context, tsCode, start, end string) ([]models.Daily, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT d.ts_code, d.trade_date,
+			COALESCE(d.open, 0), COALESCE(d.high, 0), COALESCE(d.low, 0), COALESCE(d.close, 0),
+			COALESCE(d.pre_close, 0), COALESCE(d.change, 0), COALESCE(d.pct_chg, 0),
+			COALESCE(d.vol, 0), COALESCE(d.amount, 0), a.adj_factor
+		FROM daily d
+		LEFT JOIN adj_factor a ON a.ts_code = d.ts_code AND a.trade_date = d.trade_date
+		WHERE d.ts_code = $1 AND d.trade_date BETWEEN $2 AND $3
+		ORDER BY d.trade_date ASC
+	`, tsCode, start, end)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query split adjusted daily")
+	}
+	defer rows.Close()
+
+	var bars []models.Daily
+	var factors []float64
+	for rows.Next() {
+		var d models.Daily
+		var factor sql.NullFloat64
+		if err := rows.Scan(&d.TSCode, &d.TradeDate, &d.Open, &d.High, &d.Low, &d.Close,
+			&d.PreClose, &d.Change, &d.PctChg, &d.Vol, &d.Amount, &factor); err != nil {
+			return nil, errx.Wrap(err, "failed to scan split adjusted daily")
+		}
+		bars = append(bars, d)
+		factors = append(factors, factor.Float64)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating split adjusted daily rows")
+	}
+
+	return splitAdjustDaily(bars, factors), nil
+}
+
+// splitAdjustDaily 按复权因子调整日线价格：adjusted = price * factor / latestFactor
+// factors与bars一一对应，0表示缺失，缺失的因子沿用前一个交易日的因子（开头缺失时使用第一个已知因子）；
+// 成交量和成交额不做调整，涨跌幅是比例不受影响
+func splitAdjustDaily(bars []models.Daily, factors []float64) []models.Daily {
+	var first float64
+	for _, f := range factors {
+		if f > 0 {
+			first = f
+			break
+		}
+	}
+	if first == 0 {
+		// 没有任何复权因子，原样返回
+		return bars
+	}
+
+	filled := make([]float64, len(factors))
+	last := first
+	for i, f := range factors {
+		if f > 0 {
+			last = f
+		}
+		filled[i] = last
+	}
+
+	latest := filled[len(filled)-1]
+	adjusted := make([]models.Daily, len(bars))
+	for i, bar := range bars {
+		scale := filled[i] / latest
+		bar.Open *= scale
+		bar.High *= scale
+		bar.Low *= scale
+		bar.Close *= scale
+		bar.PreClose *= scale
+		bar.Change *= scale
+		adjusted[i] = bar
+	}
+	return adjusted
+}
+
+// RecomputeDailyChanges 按交易日顺序重新计算单只股票日线的昨收、涨跌额和涨跌幅，返回更新的行数
+// 只更新pre_close/change/pct_chg为空或为0的行（如CSV导入的日线），每只股票的第一个交易日没有前收盘价，不做处理
+func (s *PostgresStorage) RecomputeDailyChanges(ctx context.Context, tsCode string) (int64, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT ts_code, trade_date, COALESCE(close, 0),
+			COALESCE(pre_close, 0), COALESCE(change, 0), COALESCE(pct_chg, 0)
+		FROM daily
+		WHERE ts_code = $1
+		ORDER BY trade_date ASC
+	`, tsCode)
+	if err != nil {
+		return 0, errx.Wrap(err, "failed to query daily")
+	}
+
+	var bars []models.Daily
+	for rows.Next() {
+		var d models.Daily
+		if err := rows.Scan(&d.TSCode, &d.TradeDate, &d.Close, &d.PreClose, &d.Change, &d.PctChg); err != nil {
+			rows.Close()
+			return 0, errx.Wrap(err, "failed to scan daily")
+		}
+		bars = append(bars, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, errx.Wrap(err, "error iterating daily rows")
+	}
+
+	updates := recomputeDailyChanges(bars)
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var updated int64
+	for _, d := range updates {
+		tag, err := tx.Exec(ctx, `
+			UPDATE daily SET pre_close = $3, change = $4, pct_chg = $5, updated_at = CURRENT_TIMESTAMP
+			WHERE ts_code = $1 AND trade_date = $2
+		`, d.TSCode, d.TradeDate, d.PreClose, d.Change, d.PctChg)
+		if err != nil {
+			return 0, errx.Wrap(err, "failed to update daily changes")
+		}
+		updated += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, errx.Wrap(err, "failed to commit transaction")
+	}
+
+	logrus.Infof("Recomputed change/pct_chg for %d daily rows of %s", updated, tsCode)
+	return updated, nil
+}
+
+// recomputeDailyChanges 根据按交易日升序排列的日线计算缺失的昨收、涨跌额和涨跌幅，返回需要更新的行
+// pre_close取前一交易日收盘价，change = close - pre_close，pct_chg = change / pre_close * 100；
+// 三个字段都非0的行保持不变，前一交易日收盘价为0时无法计算，跳过
+func recomputeDailyChanges(bars []models.Daily) []models.Daily {
+	var updates []models.Daily
+	for i := 1; i < len(bars); i++ {
+		bar := bars[i]
+		if bar.PreClose != 0 && bar.Change != 0 && bar.PctChg != 0 {
+			continue
+		}
+
+		prevClose := bars[i-1].Close
+		if prevClose == 0 {
+			continue
+		}
+
+		change := bar.Close - prevClose
+		pctChg := change / prevClose * 100
+		if bar.PreClose == prevClose && bar.Change == change && bar.PctChg == pctChg {
+			continue
+		}
+
+		bar.PreClose = prevClose
+		bar.Change = change
+		bar.PctChg = pctChg
+		updates = append(updates, bar)
+	}
+	return updates
+}
+
+// DailyColumnNames 可按列查询的日线数值字段
+var DailyColumnNames = []string{"open", "high", "low", "close", "pre_close", "change", "pct_chg", "vol", "amount"}
+
+// ErrInvalidDailyColumn 请求的日线列不在允许列表中
+var ErrInvalidDailyColumn = errors.New("invalid daily column")
+
+// ValidateDailyColumns 校验请求的日线列，去除重复列并保持请求顺序
+func ValidateDailyColumns(cols []string) ([]string, error) {
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("%w: at least one column is required", ErrInvalidDailyColumn)
+	}
+
+	allowed := make(map[string]bool, len(DailyColumnNames))
+	for _, name := range DailyColumnNames {
+		allowed[name] = true
+	}
+
+	seen := make(map[string]bool, len(cols))
+	var valid []string
+	for _, col := range cols {
+		if !allowed[col] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDailyColumn, col)
+		}
+		if seen[col] {
+			continue
+		}
+		seen[col] = true
+		valid = append(valid, col)
+	}
+	return valid, nil
+}
+
+// GetDailyColumns 批量获取多只股票指定列的日线数据，按股票代码和交易日升序返回列式结果，日期格式为YYYYMMDD
+// 列名必须在DailyColumnNames中，否则返回ErrInvalidDailyColumn；空值返回0
+func (s *PostgresStorage) GetDailyColumns(ctx context.Context, tsCodes []string, cols []string, start, end string) (*models.DailyColumns, error) {
+	cols, err := ValidateDailyColumns(cols)
+	if err != nil {
+		return nil, err
+	}
+
+	// 列名已经过允许列表校验，可以安全地拼接到SQL中
+	selects := make([]string, len(cols))
+	for i, col := range cols {
+		selects[i] = fmt.Sprintf("COALESCE(%s, 0)", col)
+	}
+	query := fmt.Sprintf(`
+		SELECT ts_code, trade_date, %s
+		FROM daily
+		WHERE ts_code = ANY($1) AND trade_date BETWEEN $2 AND $3
+		ORDER BY ts_code ASC, trade_date ASC
+	`, strings.Join(selects, ", "))
+
+	rows, err := s.pool.Query(ctx, query, tsCodes, start, end)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query daily columns")
+	}
+	defer rows.Close()
+
+	result := newDailyColumns(cols)
+	values := make([]float64, len(cols))
+	dest := make([]interface{}, len(cols)+2)
+	var tsCode, tradeDate string
+	dest[0], dest[1] = &tsCode, &tradeDate
+	for i := range values {
+		dest[i+2] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, errx.Wrap(err, "failed to scan daily columns")
+		}
+		appendDailyColumnsRow(result, cols, tsCode, tradeDate, values)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating daily columns rows")
+	}
+
+	return result, nil
+}
+
+// newDailyColumns 创建空的列式结果，每个请求列都初始化为空切片，JSON中输出[]而不是null
+func newDailyColumns(cols []string) *models.DailyColumns {
+	result := &models.DailyColumns{
+		TSCode:    []string{},
+		TradeDate: []string{},
+		Columns:   make(map[string][]float64, len(cols)),
+	}
+	for _, col := range cols {
+		result.Columns[col] = []float64{}
+	}
+	return result
+}
+
+// appendDailyColumnsRow 向列式结果追加一行数据，values与cols一一对应
+func appendDailyColumnsRow(result *models.DailyColumns, cols []string, tsCode, tradeDate string, values []float64) {
+	result.TSCode = append(result.TSCode, tsCode)
+	result.TradeDate = append(result.TradeDate, tradeDate)
+	for i, col := range cols {
+		result.Columns[col] = append(result.Columns[col], values[i])
+	}
+}
+
 // UpsertTradeCal 单条保存或更新交易日历
 func (s *PostgresStorage) UpsertTradeCal(exchange, calDate, isOpen, preTradeDate string) error {
 	_, err := s.pool.Exec(context.Background(), `
@@ -767,7 +2658,48 @@ func (s *PostgresStorage) UpsertTradeCal(exchange, calDate, isOpen, preTradeDate
 		ON CONFLICT (trade_date) DO UPDATE SET is_trading_day = $2
 	`, calDate, isOpen == "1")
 	if err != nil {
-		return fmt.Errorf("failed to upsert trade_cal: %w", err)
+		return errx.Wrap(err, "failed to upsert trade_cal")
+	}
+	return nil
+}
+
+// SaveBackfillProgress 保存或更新回补任务进度
+func (s *PostgresStorage) SaveBackfillProgress(progress models.BackfillProgress) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO backfill_progress (
+			id, start_date, end_date, status, total, done, current_code, last_code, errors, last_error, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP
+		) ON CONFLICT (id) DO UPDATE SET
+			status = $4, total = $5, done = $6, current_code = $7, last_code = $8, errors = $9, last_error = $10,
+			updated_at = CURRENT_TIMESTAMP
+	`, progress.ID, progress.StartDate, progress.EndDate, progress.Status, progress.Total, progress.Done,
+		progress.CurrentCode, progress.LastCode, progress.Errors, progress.LastError)
+	if err != nil {
+		return errx.Wrap(err, "failed to save backfill progress")
 	}
 	return nil
 }
+
+// GetBackfillProgress 获取回补任务进度，任务不存在时返回nil
+func (s *PostgresStorage) GetBackfillProgress(id string) (*models.BackfillProgress, error) {
+	var p models.BackfillProgress
+	var currentCode, lastCode, lastError sql.NullString
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT id, start_date, end_date, status, total, done, current_code, last_code, errors, last_error,
+			created_at, updated_at
+		FROM backfill_progress
+		WHERE id = $1
+	`, id).Scan(&p.ID, &p.StartDate, &p.EndDate, &p.Status, &p.Total, &p.Done, &currentCode, &lastCode,
+		&p.Errors, &lastError, &p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query backfill progress")
+	}
+	p.CurrentCode = currentCode.String
+	p.LastCode = lastCode.String
+	p.LastError = lastError.String
+	return &p, nil
+}
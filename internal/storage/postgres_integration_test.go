@@ -0,0 +1,1191 @@
+//go:build integration
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"quant-data-engine/internal/config"
+	"quant-data-engine/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIntegrationStorage 按环境变量配置连接测试数据库
+func newIntegrationStorage(t testing.TB) *PostgresStorage {
+	require.NoError(t, config.LoadConfig())
+	s, err := NewPostgresStorage()
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// TestPostgresStorage_PoolStats 测试连接池连通性检查和统计
+func TestPostgresStorage_PoolStats(t *testing.T) {
+	s := newIntegrationStorage(t)
+
+	require.NoError(t, s.PingPool(context.Background()))
+	stats := s.PoolStats()
+	assert.Equal(t, int32(config.AppConfig.DBMaxConns), stats.MaxConns)
+	assert.GreaterOrEqual(t, stats.TotalConns, int32(1))
+	assert.Positive(t, stats.AcquireCount)
+}
+
+// TestPostgresStorage_GetSourceFreshness 测试按数据源返回最新数据时间
+func TestPostgresStorage_GetSourceFreshness(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	// 使用远未来的时间戳，保证合成数据是各数据源的最新数据
+	base := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data []models.MarketData
+	var ids []string
+	for i := 0; i < 5; i++ {
+		data = append(data,
+			models.MarketData{ID: uuid.New().String(), Symbol: "BTCUSDT", Price: 1, Volume: 1, Timestamp: base.Add(time.Duration(i) * time.Minute), Source: models.SourceBinance},
+			models.MarketData{ID: uuid.New().String(), Symbol: "ETHUSDT", Price: 1, Volume: 1, Timestamp: base.Add(time.Duration(i) * time.Hour), Source: models.SourceOKX},
+		)
+	}
+	for _, d := range data {
+		ids = append(ids, d.ID)
+	}
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE id = ANY($1)", ids)
+	})
+	require.NoError(t, s.SaveMarketData(data))
+
+	freshness, err := s.GetSourceFreshness(ctx)
+	require.NoError(t, err)
+	assert.True(t, freshness[models.SourceBinance].Equal(base.Add(4*time.Minute)))
+	assert.True(t, freshness[models.SourceOKX].Equal(base.Add(4*time.Hour)))
+}
+
+// TestPostgresStorage_GetBacktestDataByIDs 测试按ID批量获取回测数据，不存在的ID被忽略
+func TestPostgresStorage_GetBacktestDataByIDs(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		d := models.BacktestData{
+			ID:        uuid.New().String(),
+			Symbol:    "BTCUSDT",
+			Strategy:  "MA Cross",
+			StartDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Results:   `{"profit": 12.5}`,
+			Timestamp: time.Now().UTC(),
+		}
+		require.NoError(t, s.SaveBacktestData(d))
+		ids = append(ids, d.ID)
+	}
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM backtest_data WHERE id = ANY($1)", ids)
+	})
+
+	data, err := s.GetBacktestDataByIDs(ctx, append([]string{uuid.New().String()}, ids...))
+	require.NoError(t, err)
+
+	var got []string
+	for _, d := range data {
+		got = append(got, d.ID)
+		assert.JSONEq(t, `{"profit": 12.5}`, d.Results)
+	}
+	assert.ElementsMatch(t, ids, got)
+}
+
+// TestPostgresStorage_GetBacktestData 测试按交易对和策略获取回测数据，按时间戳倒序并限制条数
+func TestPostgresStorage_GetBacktestData(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "BT" + uuid.New().String()[:8]
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for i, strategy := range []string{"MA Cross", "RSI", "MA Cross"} {
+		d := models.BacktestData{
+			ID:        uuid.New().String(),
+			Symbol:    symbol,
+			Strategy:  strategy,
+			StartDate: base.AddDate(-1, 0, 0),
+			EndDate:   base,
+			Results:   `{"profit": 12.5}`,
+			Timestamp: base.Add(time.Duration(i) * time.Hour),
+		}
+		require.NoError(t, s.SaveBacktestData(d))
+		ids = append(ids, d.ID)
+	}
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM backtest_data WHERE id = ANY($1)", ids)
+	})
+
+	data, err := s.GetBacktestData(symbol, "", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, data, 3)
+	assert.Equal(t, []string{ids[2], ids[1], ids[0]}, []string{data[0].ID, data[1].ID, data[2].ID})
+	assert.NotNil(t, data[0].CreatedAt)
+
+	data, err = s.GetBacktestData(symbol, "MA Cross", 1, 0)
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	assert.Equal(t, ids[2], data[0].ID)
+
+	data, err = s.GetBacktestData(symbol, "MA Cross", 1, 1)
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	assert.Equal(t, ids[0], data[0].ID)
+
+	data, err = s.GetBacktestData(symbol, "Unknown", 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	count, err := s.CountBacktestData(symbol, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	count, err = s.CountBacktestData(symbol, "MA Cross")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+// TestPostgresStorage_SaveBacktestDataBatch 测试批量保存回测数据，重复保存时更新已有记录，含非法数据时整批不保存
+func TestPostgresStorage_SaveBacktestDataBatch(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data []models.BacktestData
+	var ids []string
+	for i := 0; i < 3; i++ {
+		d := models.BacktestData{
+			ID:        uuid.New().String(),
+			Symbol:    "BTCUSDT",
+			Strategy:  "MA Cross",
+			StartDate: start,
+			EndDate:   start.AddDate(1, 0, 0),
+			Results:   fmt.Sprintf(`{"profit": %d}`, i),
+			Timestamp: start.AddDate(1, 0, 1),
+		}
+		data = append(data, d)
+		ids = append(ids, d.ID)
+	}
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM backtest_data WHERE id = ANY($1)", ids)
+	})
+	require.NoError(t, s.SaveBacktestDataBatch(data))
+
+	data[0].Strategy = "RSI"
+	require.NoError(t, s.SaveBacktestDataBatch(data[:1]))
+
+	stored, err := s.GetBacktestDataByIDs(ctx, ids)
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+	for _, d := range stored {
+		if d.ID == ids[0] {
+			assert.Equal(t, "RSI", d.Strategy)
+		}
+	}
+
+	// 含非法数据时整批不保存
+	invalid := data[1]
+	invalid.ID = uuid.New().String()
+	invalid.Results = "not json"
+	valid := data[2]
+	valid.ID = uuid.New().String()
+	ids = append(ids, invalid.ID, valid.ID)
+	err = s.SaveBacktestDataBatch([]models.BacktestData{valid, invalid})
+	assert.ErrorIs(t, err, ErrInvalidBacktestData)
+	stored, err = s.GetBacktestDataByIDs(ctx, []string{valid.ID})
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
+
+// TestPostgresStorage_Seed 测试使用合成数据写入后按查询方法读回
+func TestPostgresStorage_Seed(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "SEEDTEST"
+	tsCode := "999999.SZ"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+		_, _ = s.pool.Exec(ctx, "DELETE FROM daily WHERE ts_code = $1", tsCode)
+	})
+
+	require.NoError(t, s.SeedMarketData(ctx, 10, symbol))
+	// 重复写入不产生重复数据
+	require.NoError(t, s.SeedMarketData(ctx, 10, symbol))
+
+	data, err := s.GetMarketData(ctx, symbol, 3)
+	require.NoError(t, err)
+	require.Len(t, data, 3)
+	assert.Equal(t, 109.0, data[0].Price)
+	assert.Equal(t, 107.0, data[2].Price)
+
+	data, err = s.GetMarketData(ctx, symbol, 100)
+	require.NoError(t, err)
+	assert.Len(t, data, 10)
+
+	// 5个交易日从2000-01-03（周一）开始，跳过周末
+	require.NoError(t, s.SeedDaily(ctx, tsCode, 5))
+	columns, err := s.GetDailyColumns(ctx, []string{tsCode}, []string{"close", "pre_close"}, "20000101", "20000131")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20000103", "20000104", "20000105", "20000106", "20000107"}, columns.TradeDate)
+	assert.InDeltaSlice(t, []float64{10.0, 10.1, 10.2, 10.3, 10.4}, columns.Columns["close"], 1e-9)
+	assert.InDelta(t, 10.3, columns.Columns["pre_close"][4], 1e-9)
+
+	// 合成数据的涨跌幅已完整，无需重新计算
+	updated, err := s.RecomputeDailyChanges(ctx, tsCode)
+	require.NoError(t, err)
+	assert.Zero(t, updated)
+}
+
+// TestPostgresStorage_SaveMarketDataCopy 测试大批量保存使用COPY时已存在的id跳过、同批重复的id保留第一条
+func TestPostgresStorage_SaveMarketDataCopy(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "COPYTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+
+	base := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	bar := func(i int, price float64) models.MarketData {
+		return models.MarketData{ID: uuid.New().String(), Symbol: symbol, Price: price, Volume: 1, Timestamp: base.Add(time.Duration(i) * time.Second), Source: models.SourceBinance}
+	}
+	existing := bar(0, 1)
+	require.NoError(t, s.SaveMarketData([]models.MarketData{existing}))
+
+	data := make([]models.MarketData, 0, copyMarketDataThreshold+2)
+	for i := 1; len(data) < copyMarketDataThreshold; i++ {
+		data = append(data, bar(i, 100))
+	}
+	conflict := existing
+	conflict.Price = 2
+	inBatch := data[0]
+	inBatch.Price = 200
+	data = append(data, conflict, inBatch)
+	require.NoError(t, s.SaveMarketData(data))
+
+	var count int
+	require.NoError(t, s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM market_data WHERE symbol = $1", symbol).Scan(&count))
+	assert.Equal(t, copyMarketDataThreshold+1, count)
+
+	var price float64
+	require.NoError(t, s.pool.QueryRow(ctx, "SELECT price FROM market_data WHERE id = $1", existing.ID).Scan(&price))
+	assert.Equal(t, 1.0, price)
+	require.NoError(t, s.pool.QueryRow(ctx, "SELECT price FROM market_data WHERE id = $1", inBatch.ID).Scan(&price))
+	assert.Equal(t, 100.0, price)
+}
+
+// TestPostgresStorage_SaveMarketDataDeterministicID 测试由交易对、数据源和时间生成ID时，重复获取的相同数据按id去重只保存一行
+func TestPostgresStorage_SaveMarketDataDeterministicID(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "DETIDTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+
+	ts := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	poll := func(price float64) models.MarketData {
+		return models.MarketData{ID: models.MarketDataID(symbol, models.SourceBinance, ts), Symbol: symbol, Price: price, Volume: 1, Timestamp: ts, Source: models.SourceBinance}
+	}
+	first, second := poll(100), poll(101)
+	require.Equal(t, first.ID, second.ID)
+
+	require.NoError(t, s.SaveMarketData([]models.MarketData{first}))
+	require.NoError(t, s.SaveMarketData([]models.MarketData{second}))
+
+	var count int
+	require.NoError(t, s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM market_data WHERE symbol = $1", symbol).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+// TestPostgresStorage_MarketDataNaturalKey 测试按(symbol, timestamp, source)去重时，重新保存相同的数据（新的id）不产生重复行
+func TestPostgresStorage_MarketDataNaturalKey(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "NATKEYTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+		// 恢复默认的按id去重，删除唯一索引
+		_ = s.migrateMarketDataKey(ctx, marketDataIDConflict, true)
+	})
+
+	base := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := func(i int) models.MarketData {
+		return models.MarketData{ID: uuid.New().String(), Symbol: symbol, Price: 100, Volume: 1, Timestamp: base.Add(time.Duration(i) * time.Second), Source: models.SourceBinance}
+	}
+	count := func() int {
+		var n int
+		require.NoError(t, s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM market_data WHERE symbol = $1", symbol).Scan(&n))
+		return n
+	}
+
+	// 迁移前已存在的重复数据只保留一行
+	require.NoError(t, s.SaveMarketData([]models.MarketData{tick(0), tick(0)}))
+	require.Equal(t, 2, count())
+	require.NoError(t, s.migrateMarketDataKey(ctx, marketDataNaturalConflict, false))
+	s.marketDataConflict = marketDataNaturalConflict
+	assert.Equal(t, 1, count())
+
+	// 索引已存在时再次迁移不做任何操作；切换回id时不允许删除索引则返回错误并保留索引
+	require.NoError(t, s.migrateMarketDataKey(ctx, marketDataNaturalConflict, false))
+	assert.Error(t, s.migrateMarketDataKey(ctx, marketDataIDConflict, false))
+	exists, err := s.marketDataNaturalIndexExists(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// 逐条插入和COPY重新保存相同的数据都不产生重复行，不同数据源的同一时刻数据分别保存
+	require.NoError(t, s.SaveMarketData([]models.MarketData{tick(0), tick(1)}))
+	assert.Equal(t, 2, count())
+	require.NoError(t, s.SaveMarketData([]models.MarketData{tick(1)}))
+	assert.Equal(t, 2, count())
+	other := tick(1)
+	other.Source = models.SourceOKX
+	require.NoError(t, s.SaveMarketData([]models.MarketData{other}))
+	assert.Equal(t, 3, count())
+
+	batch := make([]models.MarketData, 0, copyMarketDataThreshold)
+	for i := 0; len(batch) < copyMarketDataThreshold; i++ {
+		batch = append(batch, tick(i%50))
+	}
+	require.NoError(t, s.SaveMarketData(batch))
+	assert.Equal(t, 51, count())
+}
+
+// benchmarkInsertMarketData 在回滚的事务中写入10000条市场数据，对比逐条插入和COPY
+func benchmarkInsertMarketData(b *testing.B, insert func(ctx context.Context, tx pgx.Tx, data []models.MarketData) error) {
+	s := newIntegrationStorage(b)
+	ctx := context.Background()
+
+	base := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]models.MarketData, 10000)
+	for i := range data {
+		data[i] = models.MarketData{ID: uuid.New().String(), Symbol: "BENCHTEST", Price: float64(i), Volume: 1, Timestamp: base.Add(time.Duration(i) * time.Second), Source: models.SourceBinance}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx, err := s.pool.Begin(ctx)
+		require.NoError(b, err)
+		require.NoError(b, insert(ctx, tx, data))
+		require.NoError(b, tx.Rollback(ctx))
+	}
+}
+
+func BenchmarkInsertMarketData_Loop(b *testing.B) {
+	benchmarkInsertMarketData(b, func(ctx context.Context, tx pgx.Tx, data []models.MarketData) error {
+		return insertMarketData(ctx, tx, data, marketDataIDConflict, false)
+	})
+}
+
+func BenchmarkInsertMarketData_CopyFrom(b *testing.B) {
+	benchmarkInsertMarketData(b, func(ctx context.Context, tx pgx.Tx, data []models.MarketData) error {
+		return copyMarketData(ctx, tx, data, marketDataIDConflict, false)
+	})
+}
+
+// TestPostgresStorage_Outbox 测试启用发件箱时保存市场数据写入发件箱，标记后不再返回
+func TestPostgresStorage_Outbox(t *testing.T) {
+	s := newIntegrationStorage(t)
+	s.outbox = true
+	ctx := context.Background()
+
+	symbol := "OUTBOXTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+		_, _ = s.pool.Exec(ctx, "DELETE FROM outbox WHERE key = $1", symbol)
+	})
+
+	d := models.MarketData{ID: uuid.New().String(), Symbol: symbol, Price: 1, Volume: 1, Timestamp: time.Now().UTC(), Source: models.SourceBinance}
+	require.NoError(t, s.SaveMarketData([]models.MarketData{d}))
+	// 重复保存不产生新的发件箱消息
+	require.NoError(t, s.SaveMarketData([]models.MarketData{d}))
+
+	unsent := func() []models.OutboxMessage {
+		messages, err := s.GetUnsentOutbox(ctx, 10000)
+		require.NoError(t, err)
+		var mine []models.OutboxMessage
+		for _, m := range messages {
+			if m.Key == symbol {
+				mine = append(mine, m)
+			}
+		}
+		return mine
+	}
+
+	messages := unsent()
+	require.Len(t, messages, 1)
+	assert.Equal(t, models.OutboxEventMarketData, messages[0].EventType)
+	assert.Contains(t, string(messages[0].Payload), d.ID)
+
+	require.NoError(t, s.MarkOutboxSent(ctx, []int64{messages[0].ID}))
+	assert.Empty(t, unsent())
+}
+
+// TestPostgresStorage_GetCrossSourcePrices 测试在多数据源合成数据上按数据源选择距离最近的价格
+func TestPostgresStorage_GetCrossSourcePrices(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "CMPTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+
+	at := time.Date(2100, 1, 1, 12, 0, 0, 0, time.UTC)
+	bar := func(source string, offset time.Duration, price float64) models.MarketData {
+		return models.MarketData{ID: uuid.New().String(), Symbol: symbol, Price: price, Volume: 1, Timestamp: at.Add(offset), Source: source}
+	}
+	require.NoError(t, s.SaveMarketData([]models.MarketData{
+		// binance: 5秒后的数据比20秒前的更近
+		bar(models.SourceBinance, -20*time.Second, 100),
+		bar(models.SourceBinance, 5*time.Second, 101),
+		bar(models.SourceBinance, 2*time.Minute, 150),
+		// okx: 距离相同时取较早的数据
+		bar(models.SourceOKX, -10*time.Second, 102),
+		bar(models.SourceOKX, 10*time.Second, 103),
+	}))
+
+	prices, err := s.GetCrossSourcePrices(ctx, symbol, at, 30*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{models.SourceBinance: 101, models.SourceOKX: 102}, prices)
+
+	// 范围内没有数据的数据源不返回
+	prices, err = s.GetCrossSourcePrices(ctx, symbol, at.Add(2*time.Minute), 30*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{models.SourceBinance: 150}, prices)
+}
+
+// TestPostgresStorage_QueryMarketData 测试按时间范围和排序方向查询合成数据
+func TestPostgresStorage_QueryMarketData(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "QUERYTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+	require.NoError(t, s.SeedMarketData(ctx, 10, symbol))
+
+	// 合成数据从seedBase开始每分钟一条，价格为100+i
+	data, err := s.QueryMarketData(ctx, MarketDataQuery{
+		Symbol: symbol,
+		Source: models.SourceBinance,
+		Since:  seedBase.Add(2 * time.Minute),
+		Until:  seedBase.Add(6 * time.Minute),
+		Order:  OrderAsc,
+		Limit:  3,
+	})
+	require.NoError(t, err)
+	require.Len(t, data, 3)
+	assert.Equal(t, []float64{102, 103, 104}, []float64{data[0].Price, data[1].Price, data[2].Price})
+
+	// 倒序跳过最新的8条
+	data, err = s.QueryMarketData(ctx, MarketDataQuery{Symbol: symbol, Source: models.SourceBinance, Limit: 5, Offset: 8})
+	require.NoError(t, err)
+	require.Len(t, data, 2)
+	assert.Equal(t, []float64{101, 100}, []float64{data[0].Price, data[1].Price})
+
+	data, err = s.QueryMarketData(ctx, MarketDataQuery{Symbol: symbol, Source: models.SourceOKX})
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	// 总数使用相同的过滤条件，忽略limit和offset
+	count, err := s.CountMarketData(ctx, MarketDataQuery{Symbol: symbol, Source: models.SourceBinance, Limit: 5, Offset: 8})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), count)
+	count, err = s.CountMarketData(ctx, MarketDataQuery{Symbol: symbol, Since: seedBase.Add(2 * time.Minute), Until: seedBase.Add(6 * time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
+
+// TestPostgresStorage_GetHistoricalDataPaging 测试历史数据按limit和offset分页
+func TestPostgresStorage_GetHistoricalDataPaging(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "HISTPAGETEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+	require.NoError(t, s.SeedMarketData(ctx, 5, symbol))
+
+	start := seedBase.Format(time.RFC3339)
+	end := seedBase.Add(time.Hour).Format(time.RFC3339)
+	result, err := s.GetHistoricalData(symbol, start, end, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, result.Data, 2)
+	assert.True(t, result.HasMore)
+	require.NotNil(t, result.NextOffset)
+	assert.Equal(t, 2, *result.NextOffset)
+
+	result, err = s.GetHistoricalData(symbol, start, end, 2, 4)
+	require.NoError(t, err)
+	require.Len(t, result.Data, 1)
+	assert.Equal(t, 104.0, result.Data[0].Price)
+	assert.False(t, result.HasMore)
+	assert.Nil(t, result.NextOffset)
+}
+
+// TestPostgresStorage_GetVWAP 测试按时间范围计算成交量加权平均价
+func TestPostgresStorage_GetVWAP(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "VWAPTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+	require.NoError(t, s.SeedMarketData(ctx, 5, symbol))
+
+	// 合成数据第i条价格为100+i、成交量为10+i，取i=1..3：(101*11 + 102*12 + 103*13) / 36
+	got, err := s.GetVWAP(ctx, symbol, seedBase.Add(time.Minute), seedBase.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.InDelta(t, (101.0*11+102*12+103*13)/36, got, 1e-9)
+
+	_, err = s.GetVWAP(ctx, symbol, seedBase.Add(time.Hour), seedBase.Add(2*time.Hour))
+	assert.ErrorIs(t, err, ErrZeroVolume)
+}
+
+// TestPostgresStorage_GetReturnCorrelation 测试按分钟对齐两个交易对的价格后计算收益率相关系数
+func TestPostgresStorage_GetReturnCorrelation(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbolA, symbolB := "CORRTESTA", "CORRTESTB"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol IN ($1, $2)", symbolA, symbolB)
+	})
+	require.NoError(t, s.SeedMarketData(ctx, 6, symbolA))
+	require.NoError(t, s.SeedMarketData(ctx, 4, symbolB))
+
+	// 合成数据每分钟一条、价格同为100+i，只有前4分钟重叠，得到3个相同的收益率
+	corr, points, err := s.GetReturnCorrelation(ctx, symbolA, symbolB, seedBase, seedBase.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 3, points)
+	assert.InDelta(t, 1, corr, 1e-9)
+
+	_, _, err = s.GetReturnCorrelation(ctx, symbolA, symbolB, seedBase.Add(2*time.Minute), seedBase.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrInsufficientOverlap)
+}
+
+// TestPostgresStorage_GetVolumeProfile 测试width_bucket将合成数据的成交量分配到价格区间
+func TestPostgresStorage_GetVolumeProfile(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "VPTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+	// 价格100+i、成交量i+1，i=0..9，分为3个区间[100,103) [103,106) [106,109]
+	require.NoError(t, s.SeedMarketData(ctx, 10, symbol))
+	_, err := s.pool.Exec(ctx, "UPDATE market_data SET volume = price - 99 WHERE symbol = $1", symbol)
+	require.NoError(t, err)
+
+	profile, err := s.GetVolumeProfile(ctx, symbol, seedBase, seedBase.Add(time.Hour), 3)
+	require.NoError(t, err)
+	require.Len(t, profile, 3)
+	assert.Equal(t, models.PriceVolumeBucket{PriceLow: 100, PriceHigh: 103, Volume: 1 + 2 + 3, Ticks: 3}, profile[0])
+	assert.Equal(t, models.PriceVolumeBucket{PriceLow: 103, PriceHigh: 106, Volume: 4 + 5 + 6, Ticks: 3}, profile[1])
+	assert.Equal(t, models.PriceVolumeBucket{PriceLow: 106, PriceHigh: 109, Volume: 7 + 8 + 9 + 10, Ticks: 4}, profile[2])
+
+	// 只有一条数据时只有一个区间
+	profile, err = s.GetVolumeProfile(ctx, symbol, seedBase, seedBase, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []models.PriceVolumeBucket{{PriceLow: 100, PriceHigh: 100, Volume: 1, Ticks: 1}}, profile)
+
+	profile, err = s.GetVolumeProfile(ctx, "VPNODATA", seedBase, seedBase.Add(time.Hour), 3)
+	require.NoError(t, err)
+	assert.Empty(t, profile)
+
+	_, err = s.GetVolumeProfile(ctx, symbol, seedBase, seedBase.Add(time.Hour), 0)
+	assert.ErrorIs(t, err, ErrInvalidMarketDataQuery)
+}
+
+// TestPostgresStorage_SymbolMappings 测试交易对映射的保存、更新和双向查找
+func TestPostgresStorage_SymbolMappings(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	source := "symmaptest"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM symbol_map WHERE source = $1", source)
+	})
+	require.NoError(t, s.SaveSymbolMappings(ctx, []models.SymbolMapping{
+		{Canonical: "BTCUSDT", Source: source, ExchangeSymbol: "BTC-USD"},
+		{Canonical: "ETHUSDT", Source: source, ExchangeSymbol: "ETH-USD"},
+	}))
+	// 已存在的映射被更新
+	require.NoError(t, s.SaveSymbolMappings(ctx, []models.SymbolMapping{
+		{Canonical: "BTCUSDT", Source: source, ExchangeSymbol: "XBT-USD"},
+	}))
+
+	exchangeSymbol, err := s.LookupExchangeSymbol(ctx, "BTCUSDT", source)
+	require.NoError(t, err)
+	assert.Equal(t, "XBT-USD", exchangeSymbol)
+	canonical, err := s.LookupCanonicalSymbol(ctx, source, "ETH-USD")
+	require.NoError(t, err)
+	assert.Equal(t, "ETHUSDT", canonical)
+
+	_, err = s.LookupExchangeSymbol(ctx, "BNBUSDT", source)
+	assert.ErrorIs(t, err, ErrSymbolMappingNotFound)
+	_, err = s.LookupCanonicalSymbol(ctx, source, "BTC-USD")
+	assert.ErrorIs(t, err, ErrSymbolMappingNotFound)
+
+	mappings, err := s.GetSymbolMappings(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, mappings, models.SymbolMapping{Canonical: "BTCUSDT", Source: source, ExchangeSymbol: "XBT-USD"})
+
+	assert.Error(t, s.SaveSymbolMappings(ctx, []models.SymbolMapping{{Canonical: "BTCUSDT", Source: source}}))
+}
+
+// TestPostgresStorage_RawResponses 测试原始响应的保存和按获取时间清理
+func TestPostgresStorage_RawResponses(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	source := "rawtest"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM raw_responses WHERE source = $1", source)
+	})
+	require.NoError(t, s.SaveRawResponse(ctx, models.RawResponse{Source: source, Endpoint: "daily", Body: []byte{0x1f, 0x8b}, FetchedAt: seedBase}))
+	require.NoError(t, s.SaveRawResponse(ctx, models.RawResponse{Source: source, Endpoint: "daily", Body: []byte{0x1f, 0x8b}, FetchedAt: seedBase.Add(time.Hour)}))
+
+	var body []byte
+	require.NoError(t, s.pool.QueryRow(ctx, "SELECT body FROM raw_responses WHERE source = $1 ORDER BY fetched_at LIMIT 1", source).Scan(&body))
+	assert.Equal(t, []byte{0x1f, 0x8b}, body)
+
+	deleted, err := s.DeleteRawResponsesBefore(ctx, seedBase.Add(time.Minute))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, deleted, int64(1))
+
+	var remaining int
+	require.NoError(t, s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM raw_responses WHERE source = $1", source).Scan(&remaining))
+	assert.Equal(t, 1, remaining)
+}
+
+// TestPostgresStorage_SaveRejectedMarketData 测试被拒绝的数据连同原因写入死信表
+func TestPostgresStorage_SaveRejectedMarketData(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "REJECTTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM rejected_market_data WHERE symbol = $1", symbol)
+	})
+
+	d := models.MarketData{ID: uuid.New().String(), Symbol: symbol, Price: -1, Source: models.SourceBinance}
+	reason := ValidateMarketData(d).Error()
+	require.NoError(t, s.SaveRejectedMarketData([]models.RejectedMarketData{{Data: d, Reason: reason, RejectedAt: time.Now()}}))
+
+	var gotReason, gotID string
+	require.NoError(t, s.pool.QueryRow(ctx,
+		"SELECT reason, payload->>'id' FROM rejected_market_data WHERE symbol = $1", symbol).Scan(&gotReason, &gotID))
+	assert.Equal(t, reason, gotReason)
+	assert.Equal(t, d.ID, gotID)
+}
+
+// TestPostgresStorage_UpsertBacktestDataReturning 测试返回的行与数据库中保存的一致，更新时保留created_at
+func TestPostgresStorage_UpsertBacktestDataReturning(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	id := uuid.New().String()
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM backtest_data WHERE id = $1", id)
+	})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := models.BacktestData{
+		ID:        id,
+		Symbol:    "BTCUSDT",
+		Strategy:  "MA Cross",
+		StartDate: start,
+		EndDate:   start.AddDate(0, 1, 0),
+		Results:   `{"profit": 12.5}`,
+		Timestamp: start.AddDate(0, 1, 1),
+	}
+	returned, err := s.UpsertBacktestDataReturning(ctx, data)
+	require.NoError(t, err)
+	require.NotNil(t, returned.CreatedAt)
+	assert.Equal(t, id, returned.ID)
+	assert.JSONEq(t, data.Results, returned.Results)
+
+	stored, err := s.GetBacktestDataByIDs(ctx, []string{id})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, stored[0].Symbol, returned.Symbol)
+	assert.Equal(t, stored[0].Strategy, returned.Strategy)
+	assert.True(t, stored[0].StartDate.Equal(returned.StartDate))
+	assert.True(t, stored[0].Timestamp.Equal(returned.Timestamp))
+	assert.Equal(t, stored[0].Results, returned.Results)
+
+	// 更新已有回测时返回新值，created_at不变
+	data.Strategy = "RSI"
+	updated, err := s.UpsertBacktestDataReturning(ctx, data)
+	require.NoError(t, err)
+	assert.Equal(t, "RSI", updated.Strategy)
+	require.NotNil(t, updated.CreatedAt)
+	assert.True(t, returned.CreatedAt.Equal(*updated.CreatedAt))
+
+	_, err = s.UpsertBacktestDataReturning(ctx, models.BacktestData{ID: id})
+	assert.ErrorIs(t, err, ErrInvalidBacktestData)
+}
+
+// TestPostgresStorage_SaveStockBasicIdempotent 测试重复保存相同的股票列表不改变updated_at，字段变化时才更新
+func TestPostgresStorage_SaveStockBasicIdempotent(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	tsCode := "IDEMTEST.SZ"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM stock_basic WHERE ts_code = $1", tsCode)
+	})
+	updatedAt := func() time.Time {
+		var at time.Time
+		require.NoError(t, s.pool.QueryRow(ctx, "SELECT updated_at FROM stock_basic WHERE ts_code = $1", tsCode).Scan(&at))
+		return at
+	}
+
+	stock := models.StockBasic{TSCode: tsCode, Symbol: "IDEMTEST", Name: "幂等测试", Market: "主板", ListStatus: "L", ListDate: "20000103"}
+	require.NoError(t, s.SaveStockBasic([]models.StockBasic{stock}))
+	first := updatedAt()
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, s.SaveStockBasic([]models.StockBasic{stock}))
+	assert.True(t, first.Equal(updatedAt()), "updated_at changed on identical save")
+
+	time.Sleep(10 * time.Millisecond)
+	stock.Name = "幂等测试B"
+	require.NoError(t, s.SaveStockBasic([]models.StockBasic{stock}))
+	assert.True(t, updatedAt().After(first), "updated_at not bumped on change")
+}
+
+// TestPostgresStorage_GetStockBasicRoundTrip 测试保存后读取的字段与写入一致，可为空的列为NULL时读取为空字符串
+func TestPostgresStorage_GetStockBasicRoundTrip(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	// 代码排在真实股票之前，保证在LIMIT范围内
+	saved := models.StockBasic{
+		TSCode: "000000.RT", Symbol: "000000", Name: "往返测试", Area: "深圳", Industry: "银行",
+		Market: "主板", Exchange: "SZSE", CurrType: "CNY", ListStatus: "L", ListDate: "19910403", IsHS: "S",
+	}
+	nullable := "000000.RN"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM stock_basic WHERE ts_code IN ($1, $2)", saved.TSCode, nullable)
+	})
+	require.NoError(t, s.SaveStockBasic([]models.StockBasic{saved}))
+	_, err := s.pool.Exec(ctx, "INSERT INTO stock_basic (ts_code, symbol, name) VALUES ($1, '000000', 'NULL列')", nullable)
+	require.NoError(t, err)
+
+	data, err := s.GetStockBasic(2)
+	require.NoError(t, err)
+	require.Len(t, data, 2)
+
+	assert.Equal(t, nullable, data[0].TSCode)
+	assert.Empty(t, data[0].Area)
+	assert.Empty(t, data[0].ActEntType)
+
+	got := data[1]
+	assert.False(t, got.CreatedAt.IsZero())
+	got.CreatedAt, got.UpdatedAt = time.Time{}, time.Time{}
+	assert.Equal(t, saved, got)
+
+	// 偏移和总数
+	data, err = s.QueryStockBasic(ctx, StockBasicQuery{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	assert.Equal(t, saved.TSCode, data[0].TSCode)
+	count, err := s.CountStockBasic(ctx, StockBasicQuery{Market: "主板", Exchange: "SZSE", Limit: 1})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, count, int64(1))
+}
+
+// TestPostgresStorage_GetOpenTradeDates 测试只返回开市日期并按日期升序
+func TestPostgresStorage_GetOpenTradeDates(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	exchange := "CALTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM trade_cal WHERE exchange = $1", exchange)
+		_, _ = s.pool.Exec(ctx, "DELETE FROM trade_calendar WHERE trade_date BETWEEN $1 AND $2", seedBase, seedBase.AddDate(0, 0, 13))
+	})
+
+	// 2000-01-03起两周，1月7日（周五）为节假日，周末休市；乱序写入
+	var calendar []models.TradeCal
+	for i := 13; i >= 0; i-- {
+		date := seedBase.AddDate(0, 0, i)
+		isOpen := "1"
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday || date.Day() == 7 {
+			isOpen = "0"
+		}
+		calendar = append(calendar, models.TradeCal{Exchange: exchange, CalDate: date.Format("20060102"), IsOpen: isOpen})
+	}
+	require.NoError(t, s.SaveTradeCalendar(calendar))
+
+	dates, err := s.GetOpenTradeDates(ctx, exchange, "20000101", "20000111")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20000103", "20000104", "20000105", "20000106", "20000110", "20000111"}, dates)
+
+	dates, err = s.GetOpenTradeDates(ctx, exchange, "20000108", "20000109")
+	require.NoError(t, err)
+	assert.Empty(t, dates)
+
+	_, err = s.GetOpenTradeDates(ctx, exchange, "20000111", "20000101")
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+}
+
+// TestPostgresStorage_SaveTradeCal 测试重复保存同一日期范围时更新已有记录，按日期升序读取
+func TestPostgresStorage_SaveTradeCal(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	exchange := "CALSAVE"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM trade_cal WHERE exchange = $1", exchange)
+	})
+
+	calendar := []models.TradeCal{
+		{Exchange: exchange, CalDate: "20000104", IsOpen: "1", PreTradeDate: "20000103"},
+		{Exchange: exchange, CalDate: "20000103", IsOpen: "1"},
+		{Exchange: exchange, CalDate: "20000108", IsOpen: "0", PreTradeDate: "20000104"},
+	}
+	require.NoError(t, s.SaveTradeCal(calendar))
+
+	// 重新拉取同一范围，20000104改为休市
+	calendar[0].IsOpen = "0"
+	require.NoError(t, s.SaveTradeCal(calendar))
+
+	got, err := s.GetTradeCal(exchange, "20000101", "20000131")
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, "20000103", got[0].CalDate)
+	assert.Equal(t, "", got[0].PreTradeDate)
+	assert.Equal(t, "20000104", got[1].CalDate)
+	assert.Equal(t, "0", got[1].IsOpen)
+	assert.Equal(t, "20000103", got[1].PreTradeDate)
+	assert.Equal(t, "20000108", got[2].CalDate)
+
+	// 非法数据整批拒绝
+	err = s.SaveTradeCal([]models.TradeCal{
+		{Exchange: exchange, CalDate: "20000110", IsOpen: "1"},
+		{Exchange: exchange, CalDate: "2000-01-11", IsOpen: "1"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidTradeCal)
+	got, err = s.GetTradeCal(exchange, "20000101", "20000131")
+	require.NoError(t, err)
+	assert.Len(t, got, 3)
+}
+
+// TestPostgresStorage_SaveDaily 测试重复保存同一股票同一交易日时只保留一行并更新为新值
+func TestPostgresStorage_SaveDaily(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	code := "DAILYTEST.SZ"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM daily WHERE ts_code = $1", code)
+	})
+
+	bars := []models.Daily{
+		{TSCode: code, TradeDate: "20000104", Open: 10, High: 11, Low: 9, Close: 10.5, Vol: 100},
+		{TSCode: code, TradeDate: "20000103", Open: 9, High: 10, Low: 8, Close: 9.5, Vol: 200},
+	}
+	require.NoError(t, s.SaveDaily(bars))
+
+	bars[0].Close, bars[0].Vol = 10.8, 150
+	bars[1].Close = 9.7
+	require.NoError(t, s.SaveDaily(bars))
+
+	var count int
+	require.NoError(t, s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM daily WHERE ts_code = $1", code).Scan(&count))
+	assert.Equal(t, 2, count)
+
+	got, err := s.GetDaily(code, "20000101", "20000131")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "20000103", got[0].TradeDate)
+	assert.Equal(t, 9.7, got[0].Close)
+	assert.Equal(t, "20000104", got[1].TradeDate)
+	assert.Equal(t, 10.8, got[1].Close)
+	assert.Equal(t, 150.0, got[1].Vol)
+
+	// 非法数据整批拒绝
+	err = s.SaveDaily([]models.Daily{
+		{TSCode: code, TradeDate: "20000105", Open: 10, High: 11, Low: 9, Close: 10},
+		{TSCode: code, TradeDate: "20000106", Open: 10, High: 9, Low: 11, Close: 10},
+	})
+	assert.ErrorIs(t, err, ErrInvalidDaily)
+	got, err = s.GetDaily(code, "20000101", "20000131")
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+}
+
+// TestPostgresStorage_GetStoredTradeDates 测试返回去重并升序的已保存交易日，只包含指定股票
+func TestPostgresStorage_GetStoredTradeDates(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	code, other := "DATESTEST.SZ", "DATESOTHER.SZ"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM daily WHERE ts_code IN ($1, $2)", code, other)
+	})
+
+	require.NoError(t, s.SaveDaily([]models.Daily{
+		{TSCode: code, TradeDate: "20000106", Open: 10, High: 11, Low: 9, Close: 10},
+		{TSCode: code, TradeDate: "20000103", Open: 10, High: 11, Low: 9, Close: 10},
+		{TSCode: code, TradeDate: "20000104", Open: 10, High: 11, Low: 9, Close: 10},
+		{TSCode: other, TradeDate: "20000105", Open: 10, High: 11, Low: 9, Close: 10},
+	}))
+	// 重复保存同一交易日不产生重复日期
+	require.NoError(t, s.SaveDaily([]models.Daily{
+		{TSCode: code, TradeDate: "20000104", Open: 10, High: 12, Low: 9, Close: 11},
+	}))
+
+	dates, err := s.GetStoredTradeDates(ctx, code)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20000103", "20000104", "20000106"}, dates)
+
+	dates, err = s.GetStoredTradeDates(ctx, "MISSING.SZ")
+	require.NoError(t, err)
+	assert.Empty(t, dates)
+	assert.NotNil(t, dates)
+}
+
+// TestPostgresStorage_FindMissingTradingDays 测试返回交易日历中开市但没有日线的日期，休市日和其他股票的日线不影响结果
+func TestPostgresStorage_FindMissingTradingDays(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	exchange, code, other := "GAPTEST", "GAPTEST.SZ", "GAPOTHER.SZ"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM trade_cal WHERE exchange = $1", exchange)
+		_, _ = s.pool.Exec(ctx, "DELETE FROM trade_calendar WHERE trade_date BETWEEN $1 AND $2", seedBase, seedBase.AddDate(0, 0, 13))
+		_, _ = s.pool.Exec(ctx, "DELETE FROM daily WHERE ts_code IN ($1, $2)", code, other)
+	})
+
+	// 2000-01-03起两周，1月7日（周五）为节假日，周末休市
+	var calendar []models.TradeCal
+	for i := 0; i < 14; i++ {
+		date := seedBase.AddDate(0, 0, i)
+		isOpen := "1"
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday || date.Day() == 7 {
+			isOpen = "0"
+		}
+		calendar = append(calendar, models.TradeCal{Exchange: exchange, CalDate: date.Format("20060102"), IsOpen: isOpen})
+	}
+	require.NoError(t, s.SaveTradeCalendar(calendar))
+
+	require.NoError(t, s.SaveDaily([]models.Daily{
+		{TSCode: code, TradeDate: "20000103", Open: 10, High: 11, Low: 9, Close: 10},
+		{TSCode: code, TradeDate: "20000105", Open: 10, High: 11, Low: 9, Close: 10},
+		{TSCode: code, TradeDate: "20000111", Open: 10, High: 11, Low: 9, Close: 10},
+		// 休市日的日线和其他股票的日线不影响结果
+		{TSCode: code, TradeDate: "20000108", Open: 10, High: 11, Low: 9, Close: 10},
+		{TSCode: other, TradeDate: "20000104", Open: 10, High: 11, Low: 9, Close: 10},
+	}))
+
+	dates, err := s.FindMissingTradingDays(ctx, code, exchange, "20000101", "20000112")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20000104", "20000106", "20000110", "20000112"}, dates)
+
+	// 没有缺失时返回空数组
+	dates, err = s.FindMissingTradingDays(ctx, code, exchange, "20000103", "20000103")
+	require.NoError(t, err)
+	assert.Empty(t, dates)
+	assert.NotNil(t, dates)
+
+	_, err = s.FindMissingTradingDays(ctx, code, exchange, "20000112", "20000101")
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+}
+
+// TestPostgresStorage_SampleRecentMarketData 测试按保存时间窗口和抽样比例抽取市场数据
+func TestPostgresStorage_SampleRecentMarketData(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "SAMPLETEST"
+	require.NoError(t, s.SeedMarketData(ctx, 20, symbol))
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+	count := func(data []models.MarketData) int {
+		n := 0
+		for _, d := range data {
+			if d.Symbol == symbol {
+				n++
+			}
+		}
+		return n
+	}
+
+	// 刚保存的数据在窗口内，100%抽样时全部返回，limit限制条数
+	data, err := s.SampleRecentMarketData(ctx, time.Hour, 0, 100, 100000)
+	require.NoError(t, err)
+	assert.Equal(t, 20, count(data))
+
+	data, err = s.SampleRecentMarketData(ctx, time.Hour, 0, 100, 5)
+	require.NoError(t, err)
+	assert.Len(t, data, 5)
+
+	// 保存不到一分钟的数据不在窗口内
+	data, err = s.SampleRecentMarketData(ctx, time.Hour, time.Minute, 100, 100000)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count(data))
+}
+
+// TestPostgresStorage_GetStkManagersFiltered 测试管理层的公告日期过滤、排序和分页
+func TestPostgresStorage_GetStkManagersFiltered(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	code, other := "MGRTEST.SZ", "MGROTHER.SZ"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM stk_managers WHERE ts_code IN ($1, $2)", code, other)
+	})
+	require.NoError(t, s.SaveStkManagers([]models.StkManagers{
+		{TSCode: code, AnnDate: "20220101", Name: "李四", Title: "监事"},
+		{TSCode: code, AnnDate: "20240301", Name: "赵六", Title: "董事"},
+		{TSCode: code, AnnDate: "20230601", Name: "张三", Title: "总经理"},
+		{TSCode: code, AnnDate: "20240301", Name: "王五", Title: "董事长"},
+		{TSCode: other, AnnDate: "20240301", Name: "其他", Title: "董事"},
+	}))
+	// 重复保存时更新
+	require.NoError(t, s.SaveStkManagers([]models.StkManagers{{TSCode: code, AnnDate: "20230601", Name: "张三", Title: "董事长"}}))
+
+	names := func(data []models.StkManagers) []string {
+		var out []string
+		for _, d := range data {
+			out = append(out, d.AnnDate+" "+d.Name)
+		}
+		return out
+	}
+
+	all, err := s.GetStkManagersFiltered(ctx, code, "", "", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20240301 王五", "20240301 赵六", "20230601 张三", "20220101 李四"}, names(all))
+	assert.Equal(t, "董事长", all[2].Title)
+
+	page, err := s.GetStkManagersFiltered(ctx, code, "", "", 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20240301 赵六", "20230601 张三"}, names(page))
+
+	filtered, err := s.GetStkManagersFiltered(ctx, code, "20230101", "20231231", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20230601 张三"}, names(filtered))
+
+	empty, err := s.GetStkManagersFiltered(ctx, code, "", "", 10, 10)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = s.GetStkManagersFiltered(ctx, code, "20231231", "20230101", 10, 0)
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+}
+
+// TestPostgresStorage_GetRollingHighLow 测试只统计截至as_of的最近window个交易日
+func TestPostgresStorage_GetRollingHighLow(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	code := "HIGHLOW.SZ"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM daily WHERE ts_code = $1", code)
+	})
+	require.NoError(t, s.SaveDaily([]models.Daily{
+		{TSCode: code, TradeDate: "20000103", Open: 10, High: 12.0, Low: 9.5, Close: 10},
+		{TSCode: code, TradeDate: "20000104", Open: 10, High: 10.5, Low: 9.9, Close: 10},
+		{TSCode: code, TradeDate: "20000105", Open: 10, High: 10.9, Low: 9.7, Close: 10},
+		{TSCode: code, TradeDate: "20000106", Open: 10, High: 11.6, Low: 10.4, Close: 11},
+		{TSCode: code, TradeDate: "20000107", Open: 11, High: 13.0, Low: 10.8, Close: 12},
+	}))
+
+	high, low, err := s.GetRollingHighLow(ctx, code, 3, "20000106")
+	require.NoError(t, err)
+	assert.Equal(t, 11.6, high)
+	assert.Equal(t, 9.7, low)
+
+	// 未指定as_of时截至最新交易日
+	high, low, err = s.GetRollingHighLow(ctx, code, 2, "")
+	require.NoError(t, err)
+	assert.Equal(t, 13.0, high)
+	assert.Equal(t, 10.4, low)
+
+	_, _, err = s.GetRollingHighLow(ctx, code, 0, "20000106")
+	assert.ErrorIs(t, err, ErrInvalidWindow)
+	_, _, err = s.GetRollingHighLow(ctx, code, 5, "19991231")
+	assert.ErrorIs(t, err, ErrNoDailyData)
+}
+
+// TestPostgresStorage_GetOHLCV 测试按时间窗口聚合开高低收和成交量
+func TestPostgresStorage_GetOHLCV(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "OHLCVTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+	// 每分钟一条、价格为100+i、成交量为10+i
+	require.NoError(t, s.SeedMarketData(ctx, 12, symbol))
+
+	// 5分钟K线：[0,5) [5,10) [10,12)
+	candles, err := s.GetOHLCV(symbol, 5*time.Minute, seedBase, seedBase.Add(11*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, candles, 3)
+	assert.True(t, seedBase.Equal(candles[0].OpenTime))
+	assert.Equal(t, models.Candle{OpenTime: candles[0].OpenTime, Open: 100, High: 104, Low: 100, Close: 104, Volume: 10 + 11 + 12 + 13 + 14}, candles[0])
+	assert.True(t, seedBase.Add(5*time.Minute).Equal(candles[1].OpenTime))
+	assert.Equal(t, 105.0, candles[1].Open)
+	assert.Equal(t, 109.0, candles[1].Close)
+	assert.True(t, seedBase.Add(10*time.Minute).Equal(candles[2].OpenTime))
+	assert.Equal(t, 110.0, candles[2].Open)
+	assert.Equal(t, 111.0, candles[2].Close)
+	assert.Equal(t, 43.0, candles[2].Volume)
+
+	// 1小时K线合并为一根
+	candles, err = s.GetOHLCV(symbol, time.Hour, seedBase, seedBase.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, candles, 1)
+	assert.Equal(t, 100.0, candles[0].Low)
+	assert.Equal(t, 111.0, candles[0].High)
+
+	_, err = s.GetOHLCV(symbol, 15*time.Minute, seedBase, seedBase.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidMarketDataQuery)
+}
+
+// TestPostgresStorage_GetDownsampledMarketData 测试每个时间桶只返回桶内最晚的一条数据
+func TestPostgresStorage_GetDownsampledMarketData(t *testing.T) {
+	s := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	symbol := "DOWNTEST"
+	t.Cleanup(func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM market_data WHERE symbol = $1", symbol)
+	})
+	require.NoError(t, s.SeedMarketData(ctx, 10, symbol))
+
+	// 每分钟一条、价格为100+i，按3分钟分桶：[0,3) [3,6) [6,9) [9,12)
+	data, err := s.GetDownsampledMarketData(ctx, symbol, 3*time.Minute, seedBase, seedBase.Add(9*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, data, 4)
+	for i, want := range []float64{102, 105, 108, 109} {
+		assert.Equal(t, want, data[i].Price)
+		assert.True(t, seedBase.Add(time.Duration(want-100)*time.Minute).Equal(data[i].Timestamp))
+	}
+
+	_, err = s.GetDownsampledMarketData(ctx, symbol, time.Millisecond, seedBase, seedBase.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrInvalidMarketDataQuery)
+}
@@ -1,13 +1,19 @@
 package storage
 
 import (
+	"context"
+	"encoding/json"
 	"quant-data-engine/internal/models"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockPostgresStorage 模拟PostgresStorage
@@ -26,7 +32,7 @@ func TestValidateMarketData(t *testing.T) {
 		Timestamp: time.Now(),
 		Source:    "binance",
 	}
-	err := validateMarketData(validData)
+	err := ValidateMarketData(validData)
 	assert.NoError(t, err)
 
 	// 测试无效数据
@@ -38,25 +44,37 @@ func TestValidateMarketData(t *testing.T) {
 		Timestamp: time.Time{},
 		Source:    "",
 	}
-	err = validateMarketData(invalidData)
+	err = ValidateMarketData(invalidData)
 	assert.Error(t, err)
 
 	// 测试价格为负数
 	negativePriceData := validData
 	negativePriceData.Price = -10000.0
-	err = validateMarketData(negativePriceData)
+	err = ValidateMarketData(negativePriceData)
 	assert.Error(t, err)
 
 	// 测试交易量为负数
 	negativeVolumeData := validData
 	negativeVolumeData.Volume = -100.0
-	err = validateMarketData(negativeVolumeData)
+	err = ValidateMarketData(negativeVolumeData)
 	assert.Error(t, err)
 
 	// 测试时间戳为零值
 	zeroTimestampData := validData
 	zeroTimestampData.Timestamp = time.Time{}
-	err = validateMarketData(zeroTimestampData)
+	err = ValidateMarketData(zeroTimestampData)
+	assert.Error(t, err)
+
+	// 测试未规范化的数据源名称
+	nonCanonicalSourceData := validData
+	nonCanonicalSourceData.Source = "Binance"
+	err = ValidateMarketData(nonCanonicalSourceData)
+	assert.Error(t, err)
+
+	// 测试未知数据源
+	unknownSourceData := validData
+	unknownSourceData.Source = "coinbase"
+	err = ValidateMarketData(unknownSourceData)
 	assert.Error(t, err)
 }
 
@@ -72,7 +90,7 @@ func TestValidateBacktestData(t *testing.T) {
 		Results:   `{"profit": 12.5, "drawdown": 5.2}`,
 		Timestamp: time.Now(),
 	}
-	err := validateBacktestData(validData)
+	err := ValidateBacktestData(validData)
 	assert.NoError(t, err)
 
 	// 测试无效数据
@@ -85,13 +103,19 @@ func TestValidateBacktestData(t *testing.T) {
 		Results:   "",
 		Timestamp: time.Time{},
 	}
-	err = validateBacktestData(invalidData)
+	err = ValidateBacktestData(invalidData)
 	assert.Error(t, err)
 
 	// 测试结束日期早于开始日期
 	wrongDateData := validData
 	wrongDateData.EndDate = validData.StartDate.AddDate(0, -1, 0)
-	err = validateBacktestData(wrongDateData)
+	err = ValidateBacktestData(wrongDateData)
+	assert.Error(t, err)
+
+	// 测试结果不是合法JSON
+	invalidResultsData := validData
+	invalidResultsData.Results = "profit=12.5"
+	err = ValidateBacktestData(invalidResultsData)
 	assert.Error(t, err)
 }
 
@@ -138,3 +162,741 @@ func TestSaveBacktestData(t *testing.T) {
 	err := (&PostgresStorage{}).SaveBacktestData(invalidData)
 	assert.Error(t, err)
 }
+
+// TestTruncateHistoricalData 测试历史数据超出行数上限时的截断
+func TestTruncateHistoricalData(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data []models.MarketData
+	for i := 0; i < 5; i++ {
+		data = append(data, models.MarketData{
+			ID:        uuid.New().String(),
+			Symbol:    "BTCUSDT",
+			Price:     100,
+			Volume:    1,
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Source:    "binance",
+		})
+	}
+
+	// 超出上限：截断并返回下一条记录的时间戳
+	result := truncateHistoricalData(data, 3, 0)
+	assert.True(t, result.Truncated)
+	assert.True(t, result.HasMore)
+	assert.Len(t, result.Data, 3)
+	if assert.NotNil(t, result.NextStart) {
+		assert.Equal(t, base.Add(3*time.Minute), *result.NextStart)
+	}
+	if assert.NotNil(t, result.NextOffset) {
+		assert.Equal(t, 3, *result.NextOffset)
+	}
+
+	// 下一页的offset从当前offset累加
+	result = truncateHistoricalData(data, 2, 6)
+	if assert.NotNil(t, result.NextOffset) {
+		assert.Equal(t, 8, *result.NextOffset)
+	}
+
+	// 恰好等于上限：不截断
+	result = truncateHistoricalData(data, 5, 0)
+	assert.False(t, result.Truncated)
+	assert.False(t, result.HasMore)
+	assert.Len(t, result.Data, 5)
+	assert.Nil(t, result.NextStart)
+	assert.Nil(t, result.NextOffset)
+
+	// 上限为0表示不限制
+	result = truncateHistoricalData(data, 0, 0)
+	assert.False(t, result.Truncated)
+	assert.Len(t, result.Data, 5)
+}
+
+// TestHistoricalPageSize 测试每页行数不超过上限，上限为0时按limit分页或不分页
+func TestHistoricalPageSize(t *testing.T) {
+	assert.Equal(t, 100, historicalPageSize(0, 100))
+	assert.Equal(t, 100, historicalPageSize(-1, 100))
+	assert.Equal(t, 20, historicalPageSize(20, 100))
+	assert.Equal(t, 100, historicalPageSize(500, 100))
+	assert.Equal(t, 500, historicalPageSize(500, 0))
+	assert.Equal(t, 0, historicalPageSize(0, 0))
+}
+
+// TestVWAP 测试由合成成交数据的成交额和成交量计算VWAP
+func TestVWAP(t *testing.T) {
+	ticks := []models.MarketData{
+		{Price: 100, Volume: 1},
+		{Price: 102, Volume: 3},
+		{Price: 110, Volume: 0},
+		{Price: 96, Volume: 4},
+	}
+	var notional, volume float64
+	for _, tick := range ticks {
+		notional += tick.Price * tick.Volume
+		volume += tick.Volume
+	}
+
+	// (100*1 + 102*3 + 96*4) / 8 = 98.75，成交量为0的数据不影响结果
+	got, err := vwap(notional, volume)
+	require.NoError(t, err)
+	assert.InDelta(t, 98.75, got, 1e-9)
+
+	_, err = vwap(0, 0)
+	assert.ErrorIs(t, err, ErrZeroVolume)
+}
+
+// TestBuildVolumeProfile 测试由各区间的汇总结果生成等宽价格区间，最高价计入最后一个区间
+func TestBuildVolumeProfile(t *testing.T) {
+	// 价格100到110分为4个区间，宽2.5：第3个区间没有成交
+	profile := buildVolumeProfile(100, 110, 4, []volumeBucketCount{
+		{bucket: 1, volume: 3, ticks: 2},
+		{bucket: 2, volume: 1.5, ticks: 1},
+		{bucket: 4, volume: 6, ticks: 3},
+	})
+	assert.Equal(t, []models.PriceVolumeBucket{
+		{PriceLow: 100, PriceHigh: 102.5, Volume: 3, Ticks: 2},
+		{PriceLow: 102.5, PriceHigh: 105, Volume: 1.5, Ticks: 1},
+		{PriceLow: 105, PriceHigh: 107.5},
+		{PriceLow: 107.5, PriceHigh: 110, Volume: 6, Ticks: 3},
+	}, profile)
+
+	// 所有价格相同时只有一个区间
+	profile = buildVolumeProfile(100, 100, 10, []volumeBucketCount{{bucket: 1, volume: 5, ticks: 4}})
+	assert.Equal(t, []models.PriceVolumeBucket{{PriceLow: 100, PriceHigh: 100, Volume: 5, Ticks: 4}}, profile)
+
+	// 没有数据
+	assert.Empty(t, buildVolumeProfile(0, 0, 10, nil))
+}
+
+// TestReturnCorrelation 测试由两个合成价格序列计算已知的收益率相关系数
+func TestReturnCorrelation(t *testing.T) {
+	// 由收益率序列生成从100开始的价格序列
+	pricesFrom := func(returns ...float64) []float64 {
+		prices := []float64{100}
+		for _, r := range returns {
+			prices = append(prices, prices[len(prices)-1]*(1+r))
+		}
+		return prices
+	}
+
+	// 收益率x=[1,2,3,4]%、y=[1,3,2,4]%：协方差4，方差均为5，相关系数0.8
+	a := pricesFrom(0.01, 0.02, 0.03, 0.04)
+	b := pricesFrom(0.01, 0.03, 0.02, 0.04)
+	corr, points, err := returnCorrelation(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, 4, points)
+	assert.InDelta(t, 0.8, corr, 1e-9)
+
+	// 收益率线性相关和反向
+	corr, _, err = returnCorrelation(a, pricesFrom(0.03, 0.05, 0.07, 0.09))
+	require.NoError(t, err)
+	assert.InDelta(t, 1, corr, 1e-9)
+	corr, _, err = returnCorrelation(a, pricesFrom(-0.01, -0.02, -0.03, -0.04))
+	require.NoError(t, err)
+	assert.InDelta(t, -1, corr, 1e-9)
+
+	// 重叠点数不足
+	_, points, err = returnCorrelation(a[:3], b[:3])
+	assert.ErrorIs(t, err, ErrInsufficientOverlap)
+	assert.Equal(t, 2, points)
+	_, _, err = returnCorrelation(nil, nil)
+	assert.ErrorIs(t, err, ErrInsufficientOverlap)
+
+	// 价格不变
+	_, _, err = returnCorrelation(a, []float64{5, 5, 5, 5, 5})
+	assert.ErrorIs(t, err, ErrZeroVariance)
+}
+
+// TestValidateCandleInterval 测试只支持1m、5m、1h、1d的K线周期
+func TestValidateCandleInterval(t *testing.T) {
+	for _, interval := range []time.Duration{time.Minute, 5 * time.Minute, time.Hour, 24 * time.Hour} {
+		assert.NoError(t, validateCandleInterval(interval), interval.String())
+	}
+	for _, interval := range []time.Duration{0, time.Second, 15 * time.Minute, 4 * time.Hour} {
+		assert.ErrorIs(t, validateCandleInterval(interval), ErrInvalidMarketDataQuery, interval.String())
+	}
+}
+
+// TestValidateDownsample 测试降采样参数校验
+func TestValidateDownsample(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, validateDownsample(time.Minute, start, start.Add(24*time.Hour)))
+	assert.NoError(t, validateDownsample(time.Second, start, start))
+	assert.ErrorIs(t, validateDownsample(time.Millisecond, start, start.Add(time.Hour)), ErrInvalidMarketDataQuery)
+	assert.ErrorIs(t, validateDownsample(time.Minute, start, start.Add(-time.Hour)), ErrInvalidMarketDataQuery)
+	assert.ErrorIs(t, validateDownsample(time.Second, start, start.Add(MaxDownsampleBuckets*time.Second)), ErrInvalidMarketDataQuery)
+}
+
+// TestValidateTradeCal 测试交易日历日期和开市标志校验
+func TestValidateTradeCal(t *testing.T) {
+	assert.NoError(t, validateTradeCal(models.TradeCal{CalDate: "20240102", IsOpen: "1"}))
+	assert.NoError(t, validateTradeCal(models.TradeCal{CalDate: "20240106", IsOpen: "0"}))
+	for _, d := range []models.TradeCal{
+		{CalDate: "", IsOpen: "1"},
+		{CalDate: "2024-01-02", IsOpen: "1"},
+		{CalDate: "2024010a", IsOpen: "1"},
+		{CalDate: "20240102", IsOpen: ""},
+		{CalDate: "20240102", IsOpen: "true"},
+	} {
+		assert.ErrorIs(t, validateTradeCal(d), ErrInvalidTradeCal, "%+v", d)
+	}
+}
+
+// TestValidateDaily 测试日线价格校验
+func TestValidateDaily(t *testing.T) {
+	valid := models.Daily{TSCode: "000001.SZ", TradeDate: "20240102", Open: 10, High: 11, Low: 9, Close: 10.5}
+	assert.NoError(t, validateDaily(valid))
+
+	// 停牌时开高低收可以相同
+	flat := valid
+	flat.Open, flat.High, flat.Low, flat.Close = 10, 10, 10, 10
+	assert.NoError(t, validateDaily(flat))
+
+	for name, mutate := range map[string]func(d *models.Daily){
+		"missing code":  func(d *models.Daily) { d.TSCode = "" },
+		"missing date":  func(d *models.Daily) { d.TradeDate = "" },
+		"negative open": func(d *models.Daily) { d.Open = -1 },
+		"negative low":  func(d *models.Daily) { d.Low = -0.01 },
+		"high below low": func(d *models.Daily) {
+			d.High, d.Low = 9, 11
+		},
+	} {
+		d := valid
+		mutate(&d)
+		assert.ErrorIs(t, validateDaily(d), ErrInvalidDaily, name)
+	}
+}
+
+// TestRollingHighLow 测试在已知日线序列上计算滚动最高最低价
+func TestRollingHighLow(t *testing.T) {
+	// 按交易日倒序，与GetRollingHighLow的查询顺序一致
+	bars := []models.Daily{
+		{TradeDate: "20240108", High: 10.8, Low: 10.1},
+		{TradeDate: "20240105", High: 11.6, Low: 10.4},
+		{TradeDate: "20240104", High: 10.9, Low: 9.7},
+		{TradeDate: "20240103", High: 10.5, Low: 9.9},
+		{TradeDate: "20240102", High: 12.0, Low: 9.5},
+	}
+
+	// 最近3个交易日
+	high, low, err := rollingHighLow(bars[:3])
+	require.NoError(t, err)
+	assert.Equal(t, 11.6, high)
+	assert.Equal(t, 9.7, low)
+
+	// 全部5个交易日
+	high, low, err = rollingHighLow(bars)
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, high)
+	assert.Equal(t, 9.5, low)
+
+	_, _, err = rollingHighLow(nil)
+	assert.ErrorIs(t, err, ErrNoDailyData)
+}
+
+// TestValidateDateRange 测试YYYYMMDD日期范围校验
+func TestValidateDateRange(t *testing.T) {
+	assert.NoError(t, validateDateRange("20240101", "20240131"))
+	assert.NoError(t, validateDateRange("20240101", "20240101"))
+	assert.ErrorIs(t, validateDateRange("2024-01-01", "20240131"), ErrInvalidDateRange)
+	assert.ErrorIs(t, validateDateRange("20240101", ""), ErrInvalidDateRange)
+	assert.ErrorIs(t, validateDateRange("20240131", "20240101"), ErrInvalidDateRange)
+}
+
+// TestSplitAdjustDaily 测试按复权因子调整后拆股前后价格连续
+func TestSplitAdjustDaily(t *testing.T) {
+	// 第三个交易日10送10（1拆2），复权因子由1变为2，原始价格减半
+	bars := []models.Daily{
+		{TradeDate: "20240102", Open: 9.8, High: 10.1, Low: 9.7, Close: 10.0, PreClose: 9.8, Change: 0.2},
+		{TradeDate: "20240103", Open: 10.0, High: 10.3, Low: 9.9, Close: 10.2, PreClose: 10.0, Change: 0.2},
+		{TradeDate: "20240104", Open: 5.1, High: 5.2, Low: 5.0, Close: 5.1, PreClose: 5.1, Change: 0},
+		{TradeDate: "20240105", Open: 5.1, High: 5.3, Low: 5.1, Close: 5.2, PreClose: 5.1, Change: 0.1},
+	}
+	factors := []float64{1, 1, 2, 2}
+
+	adjusted := splitAdjustDaily(bars, factors)
+	assert.Len(t, adjusted, 4)
+	assert.InDelta(t, 5.0, adjusted[0].Close, 1e-9)
+	assert.InDelta(t, 5.1, adjusted[1].Close, 1e-9)
+	assert.InDelta(t, 5.1, adjusted[2].Close, 1e-9)
+	assert.InDelta(t, 5.2, adjusted[3].Close, 1e-9)
+	assert.InDelta(t, 4.9, adjusted[0].PreClose, 1e-9)
+	assert.InDelta(t, 0.1, adjusted[0].Change, 1e-9)
+
+	// 拆股日前收盘与拆股日昨收一致，序列连续
+	assert.InDelta(t, adjusted[1].Close, adjusted[2].PreClose, 1e-9)
+
+	// 原始数据不被修改
+	assert.Equal(t, 10.0, bars[0].Close)
+
+	// 缺失的因子沿用前一个交易日，开头缺失时使用第一个已知因子
+	adjusted = splitAdjustDaily(bars, []float64{0, 1, 0, 2})
+	assert.InDelta(t, 5.0, adjusted[0].Close, 1e-9)
+	assert.InDelta(t, 2.55, adjusted[2].Close, 1e-9)
+
+	// 没有任何因子时原样返回
+	adjusted = splitAdjustDaily(bars, []float64{0, 0, 0, 0})
+	assert.Equal(t, bars, adjusted)
+}
+
+// TestRecomputeDailyChanges 测试按交易日顺序计算昨收、涨跌额和涨跌幅
+func TestRecomputeDailyChanges(t *testing.T) {
+	bars := []models.Daily{
+		{TSCode: "000001.SZ", TradeDate: "20240102", Close: 10.0},
+		{TSCode: "000001.SZ", TradeDate: "20240103", Close: 10.5},
+		{TSCode: "000001.SZ", TradeDate: "20240104", Close: 9.45},
+		// 已有完整数据的行不更新
+		{TSCode: "000001.SZ", TradeDate: "20240105", Close: 9.5, PreClose: 9.45, Change: 0.05, PctChg: 0.5291},
+		// 平盘且数据已正确的行不更新
+		{TSCode: "000001.SZ", TradeDate: "20240108", Close: 9.5, PreClose: 9.5},
+		{TSCode: "000001.SZ", TradeDate: "20240109", Close: 9.0},
+	}
+
+	updates := recomputeDailyChanges(bars)
+	require.Len(t, updates, 3)
+
+	// 第一个交易日没有前收盘价，不处理
+	assert.Equal(t, "20240103", updates[0].TradeDate)
+	assert.InDelta(t, 10.0, updates[0].PreClose, 1e-9)
+	assert.InDelta(t, 0.5, updates[0].Change, 1e-9)
+	assert.InDelta(t, 5.0, updates[0].PctChg, 1e-9)
+
+	assert.Equal(t, "20240104", updates[1].TradeDate)
+	assert.InDelta(t, 10.5, updates[1].PreClose, 1e-9)
+	assert.InDelta(t, -1.05, updates[1].Change, 1e-9)
+	assert.InDelta(t, -10.0, updates[1].PctChg, 1e-9)
+
+	assert.Equal(t, "20240109", updates[2].TradeDate)
+	assert.InDelta(t, 9.5, updates[2].PreClose, 1e-9)
+	assert.InDelta(t, -0.5, updates[2].Change, 1e-9)
+	assert.InDelta(t, -5.263157894736842, updates[2].PctChg, 1e-9)
+
+	// 原始数据不被修改
+	assert.Equal(t, 0.0, bars[1].PreClose)
+
+	assert.Empty(t, recomputeDailyChanges(bars[:1]))
+	assert.Empty(t, recomputeDailyChanges(nil))
+}
+
+// TestValidateDailyColumns 测试日线列允许列表校验
+func TestValidateDailyColumns(t *testing.T) {
+	cols, err := ValidateDailyColumns([]string{"close", "open", "close", "pct_chg"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"close", "open", "pct_chg"}, cols)
+
+	for _, invalid := range [][]string{
+		nil,
+		{"close", "ts_code"},
+		{"close; DROP TABLE daily"},
+		{"CLOSE"},
+	} {
+		_, err := ValidateDailyColumns(invalid)
+		assert.ErrorIs(t, err, ErrInvalidDailyColumn, "cols %v", invalid)
+	}
+}
+
+// TestDailyColumnsShape 测试列式结果中各列与ts_code、trade_date一一对应
+func TestDailyColumnsShape(t *testing.T) {
+	cols := []string{"open", "close"}
+	result := newDailyColumns(cols)
+	assert.Empty(t, result.TradeDate)
+	assert.NotNil(t, result.Columns["close"])
+
+	appendDailyColumnsRow(result, cols, "000001.SZ", "20240102", []float64{9.8, 10.0})
+	appendDailyColumnsRow(result, cols, "000001.SZ", "20240103", []float64{10.0, 10.2})
+	appendDailyColumnsRow(result, cols, "600000.SH", "20240102", []float64{7.1, 7.2})
+
+	assert.Equal(t, []string{"000001.SZ", "000001.SZ", "600000.SH"}, result.TSCode)
+	assert.Equal(t, []string{"20240102", "20240103", "20240102"}, result.TradeDate)
+	assert.Equal(t, []float64{9.8, 10.0, 7.1}, result.Columns["open"])
+	assert.Equal(t, []float64{10.0, 10.2, 7.2}, result.Columns["close"])
+	assert.Len(t, result.Columns, 2)
+}
+
+// TestPoolSizing 测试连接池大小计算和非法配置
+func TestPoolSizing(t *testing.T) {
+	tests := []struct {
+		maxConns    int
+		expectedMin int32
+		expectedMax int32
+		expectErr   bool
+	}{
+		{0, 0, 0, true},
+		{-1, 0, 0, true},
+		{1, 0, 1, false},
+		{2, 1, 2, false},
+		{3, 1, 3, false},
+		{10, 5, 10, false},
+	}
+
+	for _, tt := range tests {
+		minConns, maxConns, err := poolSizing(tt.maxConns)
+		if tt.expectErr {
+			assert.Error(t, err, "DB_MAX_CONNS=%d", tt.maxConns)
+			continue
+		}
+		require.NoError(t, err, "DB_MAX_CONNS=%d", tt.maxConns)
+		assert.Equal(t, tt.expectedMin, minConns, "DB_MAX_CONNS=%d", tt.maxConns)
+		assert.Equal(t, tt.expectedMax, maxConns, "DB_MAX_CONNS=%d", tt.maxConns)
+	}
+}
+
+// fakeRows 模拟pgx.Rows，返回n行市场数据，afterScan在每次Scan后调用
+type fakeRows struct {
+	n         int
+	next      int
+	scanned   int
+	closed    bool
+	afterScan func()
+}
+
+func (r *fakeRows) Close()                                       { r.closed = true }
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) Values() ([]any, error)                       { return nil, nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	if r.closed || r.next >= r.n {
+		return false
+	}
+	r.next++
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	*dest[0].(*string) = uuid.New().String()
+	*dest[1].(*string) = "BTCUSDT"
+	*dest[2].(*float64) = float64(r.next)
+	*dest[3].(*float64) = 1
+	*dest[4].(*time.Time) = time.Now()
+	*dest[5].(*string) = models.SourceBinance
+	r.scanned++
+	if r.afterScan != nil {
+		r.afterScan()
+	}
+	return nil
+}
+
+// TestScanMarketData_ContextCanceled 测试读取第一行后取消ctx，扫描提前停止并关闭rows
+func TestScanMarketData_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rows := &fakeRows{n: 1000, afterScan: cancel}
+	data, err := scanMarketData(ctx, rows)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, data)
+	assert.Equal(t, 1, rows.scanned)
+	assert.Less(t, rows.next, rows.n)
+	assert.True(t, rows.closed)
+
+	// 未取消时读取全部行
+	rows = &fakeRows{n: 5}
+	data, err = scanMarketData(context.Background(), rows)
+	require.NoError(t, err)
+	assert.Len(t, data, 5)
+	assert.True(t, rows.closed)
+}
+
+// fakeTx 模拟事务，记录执行的SQL和参数，existing中的id视为已存在（ON CONFLICT不插入）
+type fakeTx struct {
+	existing map[string]bool
+	queries  []string
+	args     [][]any
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	tx.queries = append(tx.queries, sql)
+	tx.args = append(tx.args, arguments)
+	if strings.Contains(sql, "INSERT INTO market_data") && tx.existing[arguments[0].(string)] {
+		return pgconn.NewCommandTag("INSERT 0 0"), nil
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+// TestInsertMarketData_Outbox 测试发件箱消息与市场数据在同一事务中写入，重复数据不写发件箱
+func TestInsertMarketData_Outbox(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := []models.MarketData{
+		{ID: "new-1", Symbol: "BTCUSDT", Price: 1, Volume: 2, Timestamp: ts, Source: models.SourceBinance},
+		{ID: "dup", Symbol: "ETHUSDT", Price: 3, Volume: 4, Timestamp: ts, Source: models.SourceOKX},
+	}
+
+	tx := &fakeTx{existing: map[string]bool{"dup": true}}
+	require.NoError(t, insertMarketData(context.Background(), tx, data, marketDataIDConflict, true))
+
+	// 新数据之后紧跟一条发件箱消息，已存在的数据没有发件箱消息
+	require.Len(t, tx.queries, 3)
+	assert.Contains(t, tx.queries[0], "INSERT INTO market_data")
+	assert.Contains(t, tx.queries[1], "INSERT INTO outbox")
+	assert.Contains(t, tx.queries[2], "INSERT INTO market_data")
+	assert.Equal(t, models.OutboxEventMarketData, tx.args[1][0])
+	assert.Equal(t, "BTCUSDT", tx.args[1][1])
+
+	var payload models.MarketData
+	require.NoError(t, json.Unmarshal(tx.args[1][2].([]byte), &payload))
+	assert.Equal(t, data[0], payload)
+
+	// 未启用发件箱时只写市场数据
+	tx = &fakeTx{}
+	require.NoError(t, insertMarketData(context.Background(), tx, data, marketDataIDConflict, false))
+	assert.Len(t, tx.queries, 2)
+	for _, q := range tx.queries {
+		assert.NotContains(t, q, "outbox")
+	}
+}
+
+// TestMarketDataConflictTarget 测试去重键对应的冲突目标，natural时插入语句按(symbol, timestamp, source)去重
+func TestMarketDataConflictTarget(t *testing.T) {
+	for key, want := range map[string]string{"": "id", MarketDataKeyID: "id", MarketDataKeyNatural: "symbol, timestamp, source"} {
+		conflict, err := marketDataConflictTarget(key)
+		require.NoError(t, err)
+		assert.Equal(t, want, conflict)
+	}
+	_, err := marketDataConflictTarget("uuid")
+	assert.ErrorContains(t, err, "MARKET_DATA_KEY")
+
+	data := []models.MarketData{{ID: "a", Symbol: "BTCUSDT", Price: 1, Volume: 1, Timestamp: time.Now(), Source: models.SourceBinance}}
+	tx := &fakeTx{}
+	require.NoError(t, insertMarketData(context.Background(), tx, data, marketDataNaturalConflict, false))
+	assert.Contains(t, tx.queries[0], "ON CONFLICT (symbol, timestamp, source) DO NOTHING")
+
+	copyTx := &fakeCopyTx{}
+	require.NoError(t, copyMarketData(context.Background(), copyTx, data, marketDataNaturalConflict, false))
+	assert.Contains(t, copyTx.queries[1], "SELECT DISTINCT ON (symbol, timestamp, source)")
+	assert.Contains(t, copyTx.queries[1], "ORDER BY symbol, timestamp, source, seq")
+	assert.Contains(t, copyTx.queries[1], "ON CONFLICT (symbol, timestamp, source) DO NOTHING")
+}
+
+// idRows 模拟只有一列id的pgx.Rows
+type idRows struct {
+	fakeRows
+	ids []string
+}
+
+func (r *idRows) Next() bool {
+	if r.closed || r.next >= len(r.ids) {
+		return false
+	}
+	r.next++
+	return true
+}
+
+func (r *idRows) Scan(dest ...any) error {
+	*dest[0].(*string) = r.ids[r.next-1]
+	return nil
+}
+
+// fakeCopyTx 模拟支持COPY的事务，Query按INSERT ... SELECT DISTINCT ON (id) ... ON CONFLICT DO NOTHING的语义返回新插入的id
+type fakeCopyTx struct {
+	fakeTx
+	copied [][]any
+}
+
+func (tx *fakeCopyTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return 0, err
+		}
+		tx.copied = append(tx.copied, values)
+	}
+	return int64(len(tx.copied)), rowSrc.Err()
+}
+
+func (tx *fakeCopyTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx.queries = append(tx.queries, sql)
+	tx.args = append(tx.args, args)
+	seen := make(map[string]bool)
+	var ids []string
+	for _, row := range tx.copied {
+		id := row[1].(string)
+		if seen[id] || tx.existing[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return &idRows{ids: ids}, nil
+}
+
+// TestCopyMarketData_Outbox 测试COPY写入时已存在和同批重复的id不写发件箱，发件箱消息按原始顺序写入
+func TestCopyMarketData_Outbox(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := []models.MarketData{
+		{ID: "new-1", Symbol: "BTCUSDT", Price: 1, Volume: 2, Timestamp: ts, Source: models.SourceBinance},
+		{ID: "dup", Symbol: "ETHUSDT", Price: 3, Volume: 4, Timestamp: ts, Source: models.SourceOKX},
+		{ID: "new-2", Symbol: "BNBUSDT", Price: 5, Volume: 6, Timestamp: ts, Source: models.SourceOKX},
+		{ID: "new-1", Symbol: "BTCUSDT", Price: 7, Volume: 8, Timestamp: ts, Source: models.SourceBinance},
+	}
+
+	tx := &fakeCopyTx{fakeTx: fakeTx{existing: map[string]bool{"dup": true}}}
+	require.NoError(t, copyMarketData(context.Background(), tx, data, marketDataIDConflict, true))
+
+	// 所有数据按原始顺序写入临时表
+	require.Len(t, tx.copied, 4)
+	for i, row := range tx.copied {
+		assert.Equal(t, i, row[0])
+		assert.Equal(t, data[i].ID, row[1])
+	}
+
+	// 创建临时表、插入、两条发件箱消息
+	require.Len(t, tx.queries, 4)
+	assert.Contains(t, tx.queries[0], "CREATE TEMP TABLE market_data_staging")
+	assert.Contains(t, tx.queries[1], "ON CONFLICT (id) DO NOTHING")
+	var payloads []models.MarketData
+	for i := 2; i < 4; i++ {
+		assert.Contains(t, tx.queries[i], "INSERT INTO outbox")
+		var payload models.MarketData
+		require.NoError(t, json.Unmarshal(tx.args[i][2].([]byte), &payload))
+		payloads = append(payloads, payload)
+	}
+	assert.Equal(t, []models.MarketData{data[0], data[2]}, payloads)
+
+	// 未启用发件箱时只写市场数据
+	tx = &fakeCopyTx{}
+	require.NoError(t, copyMarketData(context.Background(), tx, data, marketDataIDConflict, false))
+	assert.Len(t, tx.queries, 2)
+}
+
+// TestBuildMarketDataQuery 测试不同查询选项组合生成的SQL和参数
+func TestBuildMarketDataQuery(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		query     MarketDataQuery
+		where     string
+		order     string
+		limit     string
+		expectArg []any
+	}{
+		{
+			name:      "symbol only",
+			query:     MarketDataQuery{Symbol: "BTCUSDT"},
+			where:     "WHERE symbol = $1 ORDER",
+			order:     "ORDER BY timestamp DESC",
+			expectArg: []any{"BTCUSDT"},
+		},
+		{
+			name:      "legacy limit",
+			query:     MarketDataQuery{Symbol: "BTCUSDT", Limit: 10},
+			where:     "WHERE symbol = $1 ORDER",
+			order:     "ORDER BY timestamp DESC",
+			limit:     "LIMIT $2",
+			expectArg: []any{"BTCUSDT", 10},
+		},
+		{
+			name:      "source and time range ascending",
+			query:     MarketDataQuery{Symbol: "BTCUSDT", Source: models.SourceOKX, Since: since, Until: until, Order: OrderAsc, Limit: 5},
+			where:     "WHERE symbol = $1 AND source = $2 AND timestamp >= $3 AND timestamp < $4 ORDER",
+			order:     "ORDER BY timestamp ASC",
+			limit:     "LIMIT $5",
+			expectArg: []any{"BTCUSDT", models.SourceOKX, since, until, 5},
+		},
+		{
+			name:      "limit and offset",
+			query:     MarketDataQuery{Symbol: "BTCUSDT", Limit: 10, Offset: 1000000},
+			where:     "WHERE symbol = $1 ORDER",
+			order:     "ORDER BY timestamp DESC",
+			limit:     "LIMIT $2 OFFSET $3",
+			expectArg: []any{"BTCUSDT", 10, 1000000},
+		},
+		{
+			name:      "until only descending",
+			query:     MarketDataQuery{Symbol: "ETHUSDT", Until: until, Order: OrderDesc},
+			where:     "WHERE symbol = $1 AND timestamp < $2 ORDER",
+			order:     "ORDER BY timestamp DESC",
+			expectArg: []any{"ETHUSDT", until},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := buildMarketDataQuery(tt.query)
+			require.NoError(t, err)
+			assert.Contains(t, query, tt.where)
+			assert.Contains(t, query, tt.order)
+			if tt.limit != "" {
+				assert.True(t, strings.HasSuffix(query, tt.limit), query)
+			} else {
+				assert.NotContains(t, query, "LIMIT")
+			}
+			assert.Equal(t, tt.expectArg, args)
+		})
+	}
+}
+
+// TestBuildStockBasicQuery 测试股票基础信息过滤条件生成的SQL和参数
+func TestBuildStockBasicQuery(t *testing.T) {
+	query, args := buildStockBasicQuery(StockBasicQuery{Limit: 100})
+	assert.NotContains(t, query, "WHERE")
+	assert.True(t, strings.HasSuffix(query, "FROM stock_basic ORDER BY ts_code ASC LIMIT $1"), query)
+	assert.Equal(t, []any{100}, args)
+
+	query, args = buildStockBasicQuery(StockBasicQuery{Exchange: "SSE", ListStatus: "L", Limit: 10})
+	assert.Contains(t, query, "WHERE exchange = $1 AND list_status = $2 ORDER BY ts_code ASC LIMIT $3")
+	assert.Equal(t, []any{"SSE", "L", 10}, args)
+
+	query, args = buildStockBasicQuery(StockBasicQuery{ListStatus: "L", Limit: 10, Offset: 20})
+	assert.Contains(t, query, "WHERE list_status = $1 ORDER BY ts_code ASC LIMIT $2 OFFSET $3")
+	assert.Equal(t, []any{"L", 10, 20}, args)
+
+	// 过滤值只通过参数传递
+	query, args = buildStockBasicQuery(StockBasicQuery{Market: "主板'; DROP TABLE stock_basic; --"})
+	assert.Contains(t, query, "WHERE market = $1 ORDER BY ts_code ASC")
+	assert.NotContains(t, query, "DROP")
+	assert.NotContains(t, query, "LIMIT")
+	assert.Len(t, args, 1)
+}
+
+// TestBuildStkManagersQuery 测试管理层查询的过滤条件、排序和分页参数
+func TestBuildStkManagersQuery(t *testing.T) {
+	query, args := buildStkManagersQuery("000001.SZ", "", "", 0, 0)
+	assert.True(t, strings.HasSuffix(query, "FROM stk_managers WHERE ts_code = $1 ORDER BY ann_date DESC, name ASC"), query)
+	assert.Equal(t, []any{"000001.SZ"}, args)
+
+	query, args = buildStkManagersQuery("000001.SZ", "20230101", "20231231", 20, 40)
+	assert.Contains(t, query, "WHERE ts_code = $1 AND ann_date >= $2 AND ann_date <= $3 ORDER BY ann_date DESC, name ASC LIMIT $4 OFFSET $5")
+	assert.Equal(t, []any{"000001.SZ", "20230101", "20231231", 20, 40}, args)
+
+	// 只有结束日期和偏移量时参数编号连续
+	query, args = buildStkManagersQuery("000001.SZ", "", "20231231", 0, 10)
+	assert.Contains(t, query, "WHERE ts_code = $1 AND ann_date <= $2 ORDER BY ann_date DESC, name ASC OFFSET $3")
+	assert.NotContains(t, query, "LIMIT")
+	assert.Equal(t, []any{"000001.SZ", "20231231", 10}, args)
+
+	// 查询列由模型的db标签生成
+	assert.True(t, strings.HasPrefix(query, "SELECT COALESCE(ts_code, '') AS ts_code, "), query)
+}
+
+// TestValidateAnnDateRange 测试公告日期范围的可选边界
+func TestValidateAnnDateRange(t *testing.T) {
+	assert.NoError(t, validateAnnDateRange("", ""))
+	assert.NoError(t, validateAnnDateRange("20230101", ""))
+	assert.NoError(t, validateAnnDateRange("", "20231231"))
+	assert.NoError(t, validateAnnDateRange("20230101", "20230101"))
+	assert.ErrorIs(t, validateAnnDateRange("2023-01-01", ""), ErrInvalidDateRange)
+	assert.ErrorIs(t, validateAnnDateRange("", "2023"), ErrInvalidDateRange)
+	assert.ErrorIs(t, validateAnnDateRange("20231231", "20230101"), ErrInvalidDateRange)
+}
+
+// TestMarketDataQuery_Validate 测试非法的查询选项
+func TestMarketDataQuery_Validate(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	invalid := []MarketDataQuery{
+		{},
+		{Symbol: "BTCUSDT", Order: "sideways"},
+		{Symbol: "BTCUSDT", Order: "ASC; DROP TABLE market_data"},
+		{Symbol: "BTCUSDT", Source: "unknown"},
+		{Symbol: "BTCUSDT", Limit: -1},
+		{Symbol: "BTCUSDT", Since: since, Until: since},
+	}
+	for _, q := range invalid {
+		_, _, err := buildMarketDataQuery(q)
+		assert.ErrorIs(t, err, ErrInvalidMarketDataQuery, "%+v", q)
+	}
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
+	"time"
+)
+
+// SaveRawResponse 保存一条数据源原始响应
+func (s *PostgresStorage) SaveRawResponse(ctx context.Context, r models.RawResponse) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO raw_responses (source, endpoint, body, fetched_at)
+		VALUES ($1, $2, $3, $4)
+	`, r.Source, r.Endpoint, r.Body, r.FetchedAt)
+	if err != nil {
+		return errx.Wrap(wrapDBError(err), "failed to save raw response")
+	}
+	return nil
+}
+
+// DeleteRawResponsesBefore 删除fetched_at早于before的原始响应，返回删除的行数
+func (s *PostgresStorage) DeleteRawResponsesBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM raw_responses WHERE fetched_at < $1`, before)
+	if err != nil {
+		return 0, errx.Wrap(wrapDBError(err), "failed to delete raw responses")
+	}
+	return tag.RowsAffected(), nil
+}
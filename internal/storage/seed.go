@@ -0,0 +1,83 @@
+//go:build integration
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// seedBase 合成数据的起始时间，早于真实数据，避免影响数据源新鲜度等取最新数据的查询
+var seedBase = time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC)
+
+// SeedMarketData 写入n条确定性的合成市场数据，仅用于集成测试
+// 第i条数据的ID由symbol和i生成，时间为seedBase+i分钟，价格为100+i，重复写入时覆盖
+func (s *PostgresStorage) SeedMarketData(ctx context.Context, n int, symbol string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	for i := 0; i < n; i++ {
+		id := uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("seed:%s:%d", symbol, i))).String()
+		_, err := tx.Exec(ctx, `
+			INSERT INTO market_data (id, symbol, price, volume, timestamp, source)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (id) DO UPDATE SET price = $3, volume = $4, timestamp = $5, source = $6
+		`, id, symbol, 100+float64(i), 10+float64(i), seedBase.Add(time.Duration(i)*time.Minute), models.SourceBinance)
+		if err != nil {
+			return errx.Wrap(err, "failed to seed market_data")
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+// SeedDaily 为tsCode写入n个交易日（跳过周末）的确定性合成日线，仅用于集成测试
+// 第i个交易日收盘价为10+0.1*i，pre_close/change/pct_chg与前一交易日一致，重复写入时覆盖
+func (s *PostgresStorage) SeedDaily(ctx context.Context, tsCode string, n int) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	date := seedBase
+	preClose := 10.0
+	for i := 0; i < n; i++ {
+		for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			date = date.AddDate(0, 0, 1)
+		}
+
+		closePrice := math.Round((10+0.1*float64(i))*100) / 100
+		change := closePrice - preClose
+		_, err := tx.Exec(ctx, `
+			INSERT INTO daily (ts_code, trade_date, open, high, low, close, pre_close, change, pct_chg, vol, amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (ts_code, trade_date) DO UPDATE SET
+				open = $3, high = $4, low = $5, close = $6, pre_close = $7, change = $8, pct_chg = $9, vol = $10, amount = $11
+		`, tsCode, date.Format("20060102"), preClose, closePrice+0.05, preClose-0.05, closePrice,
+			preClose, change, change/preClose*100, 1000+float64(i), (1000+float64(i))*closePrice)
+		if err != nil {
+			return errx.Wrap(err, "failed to seed daily")
+		}
+
+		preClose = closePrice
+		date = date.AddDate(0, 0, 1)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errx.Wrap(err, "failed to commit transaction")
+	}
+	return nil
+}
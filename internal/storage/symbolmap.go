@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quant-data-engine/internal/errx"
+	"quant-data-engine/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrSymbolMappingNotFound 交易对在数据源下没有映射
+var ErrSymbolMappingNotFound = errors.New("symbol mapping not found")
+
+// SaveSymbolMappings 保存交易对映射，已存在的(canonical, source)更新为新的交易所名称，所有映射在一个事务中写入
+func (s *PostgresStorage) SaveSymbolMappings(ctx context.Context, mappings []models.SymbolMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	for i, m := range mappings {
+		if m.Canonical == "" || m.Source == "" || m.ExchangeSymbol == "" {
+			return fmt.Errorf("invalid symbol mapping at index %d: canonical, source and exchange_symbol are required", i)
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return errx.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	for _, m := range mappings {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO symbol_map (canonical, source, exchange_symbol, updated_at)
+			VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+			ON CONFLICT (canonical, source) DO UPDATE SET
+				exchange_symbol = EXCLUDED.exchange_symbol,
+				updated_at = CURRENT_TIMESTAMP
+		`, m.Canonical, models.CanonicalSource(m.Source), m.ExchangeSymbol)
+		if err != nil {
+			return errx.WrapF(wrapDBError(err), "failed to save symbol mapping %s/%s", m.Source, m.Canonical)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errx.Wrap(err, "failed to commit symbol mappings")
+	}
+	return nil
+}
+
+// GetSymbolMappings 获取所有交易对映射，按数据源和统一名称排序
+func (s *PostgresStorage) GetSymbolMappings(ctx context.Context) ([]models.SymbolMapping, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT canonical, source, exchange_symbol
+		FROM symbol_map
+		ORDER BY source, canonical
+	`)
+	if err != nil {
+		return nil, errx.Wrap(err, "failed to query symbol mappings")
+	}
+	defer rows.Close()
+
+	var mappings []models.SymbolMapping
+	for rows.Next() {
+		var m models.SymbolMapping
+		if err := rows.Scan(&m.Canonical, &m.Source, &m.ExchangeSymbol); err != nil {
+			return nil, errx.Wrap(err, "failed to scan symbol mapping")
+		}
+		mappings = append(mappings, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errx.Wrap(err, "error iterating symbol mapping rows")
+	}
+	return mappings, nil
+}
+
+// LookupExchangeSymbol 查询统一名称在数据源下的交易所名称，没有映射时返回ErrSymbolMappingNotFound
+func (s *PostgresStorage) LookupExchangeSymbol(ctx context.Context, canonical, source string) (string, error) {
+	var exchangeSymbol string
+	err := s.pool.QueryRow(ctx, `
+		SELECT exchange_symbol FROM symbol_map WHERE canonical = $1 AND source = $2
+	`, canonical, models.CanonicalSource(source)).Scan(&exchangeSymbol)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s on %s", ErrSymbolMappingNotFound, canonical, source)
+	}
+	if err != nil {
+		return "", errx.Wrap(err, "failed to lookup symbol mapping")
+	}
+	return exchangeSymbol, nil
+}
+
+// LookupCanonicalSymbol 查询数据源的交易所名称对应的统一名称，没有映射时返回ErrSymbolMappingNotFound
+func (s *PostgresStorage) LookupCanonicalSymbol(ctx context.Context, source, exchangeSymbol string) (string, error) {
+	var canonical string
+	err := s.pool.QueryRow(ctx, `
+		SELECT canonical FROM symbol_map WHERE source = $1 AND exchange_symbol = $2
+	`, models.CanonicalSource(source), exchangeSymbol).Scan(&canonical)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s on %s", ErrSymbolMappingNotFound, exchangeSymbol, source)
+	}
+	if err != nil {
+		return "", errx.Wrap(err, "failed to lookup symbol mapping")
+	}
+	return canonical, nil
+}